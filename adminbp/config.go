@@ -0,0 +1,56 @@
+package adminbp
+
+// DefaultAllowedNetworks are the networks allowed to access the admin server
+// when Config.AllowedNetworks is empty.
+var DefaultAllowedNetworks = []string{
+	"127.0.0.0/8",
+	"::1/128",
+}
+
+// Config is the config of the admin server.
+//
+// Can be deserialized from YAML.
+type Config struct {
+	// Addr is the local address to run the admin server on,
+	// in the same format as baseplate.Config.Addr.
+	//
+	// It must be different from the address of the service.
+	// When it's empty, the admin server is disabled.
+	Addr string `yaml:"addr"`
+
+	// AllowedNetworks are the networks in CIDR notation
+	// (e.g. "10.0.0.0/8", "::1/128") that are allowed to access the admin
+	// server.
+	//
+	// Requests from other remote addresses are rejected with 403.
+	// When it's empty, DefaultAllowedNetworks (loopback only) is used.
+	// Use "0.0.0.0/0" and "::/0" to allow all the remote addresses.
+	AllowedNetworks []string `yaml:"allowedNetworks"`
+
+	// TLS is the optional TLS config of the admin server,
+	// independent from the TLS config of the service.
+	TLS TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig is the TLS config of the admin server.
+//
+// Can be deserialized from YAML.
+type TLSConfig struct {
+	// CertFile and KeyFile are the paths to the PEM encoded certificate and
+	// private key.
+	//
+	// When both are empty, the admin server serves plain HTTP.
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+
+	// ClientCAFile is the optional path to the PEM encoded CA certificates.
+	//
+	// When it's set, clients are required to present certificates signed by one
+	// of the CAs.
+	ClientCAFile string `yaml:"clientCAFile"`
+}
+
+// Enabled returns true if the TLS config is set.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
@@ -0,0 +1,22 @@
+// Package adminbp provides an admin HTTP server,
+// to serve Prometheus metrics, pprof and other admin endpoints on a dedicated
+// listener that's separated from the one serving the actual service traffic.
+//
+// It's usually started by baseplate.New when the admin section of the
+// baseplate config is set:
+//
+//     admin:
+//       addr: ":6060"
+//       allowedNetworks:
+//         - "10.0.0.0/8"
+//         - "127.0.0.1/32"
+//       tls:
+//         certFile: "/path/to/cert.pem"
+//         keyFile: "/path/to/key.pem"
+//         clientCAFile: "/path/to/ca.pem"
+//
+// When started by baseplate.New, it also serves the effective config (with the
+// sensitive values redacted), the metadata of the secrets,
+// and an endpoint to trigger the graceful shutdown (see ConfigPath,
+// SecretsPath and DrainPath).
+package adminbp
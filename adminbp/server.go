@@ -0,0 +1,235 @@
+package adminbp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/reddit/baseplate.go/healthbp"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// DefaultShutdownTimeout is the timeout used by Server.Close to wait for the
+// in-flight requests.
+const DefaultShutdownTimeout = time.Second * 5
+
+// Paths of the endpoints registered by default.
+const (
+	MetricsPath    = "/metrics"
+	PprofPath      = "/debug/pprof/"
+	LogLevelPath   = "/log/level"
+	RecentLogsPath = "/log/recent"
+	HealthPath     = "/health"
+	ExpvarPath     = "/debug/vars"
+)
+
+// Server is the admin HTTP server.
+//
+// It comes with the following endpoints registered:
+//
+// - MetricsPath: Prometheus metrics exposition of prometheus.DefaultGatherer.
+//
+// - PprofPath: net/http/pprof endpoints.
+//
+// - LogLevelPath: log.LevelHandler.
+//
+// - RecentLogsPath: log.RecentLogsHandler.
+//
+// - HealthPath: healthbp.Handler of healthbp.DefaultRegistry.
+//
+// - ExpvarPath: expvar.Handler.
+//
+// Additional endpoints can be registered via Handle.
+type Server struct {
+	mux      *http.ServeMux
+	server   *http.Server
+	listener net.Listener
+}
+
+// New creates a Server from cfg and starts listening on cfg.Addr.
+//
+// The returned Server doesn't serve any requests until Serve is called.
+// Use Start instead to create a Server and serve it in the background.
+func New(cfg Config) (*Server, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("adminbp.New: empty addr")
+	}
+
+	allowed := cfg.AllowedNetworks
+	if len(allowed) == 0 {
+		allowed = DefaultAllowedNetworks
+	}
+	networks, err := parseNetworks(allowed)
+	if err != nil {
+		return nil, fmt.Errorf("adminbp.New: %w", err)
+	}
+
+	var tlsConfig *tls.Config
+	if cfg.TLS.Enabled() {
+		tlsConfig, err = loadTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("adminbp.New: %w", err)
+		}
+	}
+
+	s := &Server{
+		mux: http.NewServeMux(),
+	}
+	s.mux.Handle(MetricsPath, promhttp.Handler())
+	s.mux.HandleFunc(PprofPath, pprof.Index)
+	s.mux.HandleFunc(PprofPath+"cmdline", pprof.Cmdline)
+	s.mux.HandleFunc(PprofPath+"profile", pprof.Profile)
+	s.mux.HandleFunc(PprofPath+"symbol", pprof.Symbol)
+	s.mux.HandleFunc(PprofPath+"trace", pprof.Trace)
+	s.mux.Handle(LogLevelPath, log.LevelHandler())
+	s.mux.Handle(RecentLogsPath, log.RecentLogsHandler())
+	s.mux.Handle(HealthPath, healthbp.Handler(healthbp.DefaultRegistry))
+	s.mux.Handle(ExpvarPath, expvar.Handler())
+
+	s.server = &http.Server{
+		Handler:   allowNetworks(networks, s.mux),
+		TLSConfig: tlsConfig,
+	}
+
+	s.listener, err = net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("adminbp.New: failed to listen on %q: %w", cfg.Addr, err)
+	}
+	if tlsConfig != nil {
+		s.listener = tls.NewListener(s.listener, tlsConfig)
+	}
+	return s, nil
+}
+
+// Start creates a Server via New, and serves it in a background goroutine.
+//
+// Errors from Serve are logged.
+func Start(cfg Config) (*Server, error) {
+	s, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := s.Serve(); err != nil {
+			log.Errorw("adminbp: admin server stopped", "err", err)
+		}
+	}()
+	log.Infow("adminbp: admin server started", "addr", s.Addr())
+	return s, nil
+}
+
+// Paths of the endpoints registered by baseplate.New,
+// see ConfigHandler, SecretsHandler and DrainHandler.
+const (
+	ConfigPath  = "/config"
+	SecretsPath = "/secrets"
+	DrainPath   = "/drain"
+)
+
+// Handle registers the handler for the given pattern,
+// in the same way as http.ServeMux.Handle.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Addr returns the address the Server is listening on.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Serve serves the requests until Close is called.
+//
+// It returns nil when the Server is closed.
+func (s *Server) Serve() error {
+	if err := s.server.Serve(s.listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Close shuts down the Server gracefully,
+// waiting for the in-flight requests up to DefaultShutdownTimeout.
+//
+// The listener is closed even if Serve was never called.
+func (s *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("adminbp: failed to shutdown admin server: %w", err)
+	}
+	// Shutdown only closes the listener passed to Serve.
+	if err := s.listener.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return fmt.Errorf("adminbp: failed to close admin server listener: %w", err)
+	}
+	return nil
+}
+
+func parseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed network %q: %w", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func allowNetworks(networks []*net.IPNet, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			for _, network := range networks {
+				if network.Contains(ip) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+		log.Audit(
+			r.Context(),
+			"adminbp: request from disallowed network denied",
+			"remoteAddr", r.RemoteAddr,
+			"path", r.URL.Path,
+		)
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	})
+}
+
+func loadTLSConfig(cfg TLSConfig) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tls key pair: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in client ca file %q", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,162 @@
+package adminbp_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/reddit/baseplate.go/adminbp"
+)
+
+func startServer(t *testing.T, cfg adminbp.Config) string {
+	t.Helper()
+
+	server, err := adminbp.Start(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := server.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return fmt.Sprintf("http://%s", server.Addr())
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestServer(t *testing.T) {
+	base := startServer(t, adminbp.Config{
+		Addr: "127.0.0.1:0",
+	})
+
+	for _, path := range []string{
+		adminbp.MetricsPath,
+		adminbp.PprofPath,
+		adminbp.PprofPath + "cmdline",
+		adminbp.LogLevelPath,
+		adminbp.HealthPath,
+		adminbp.ExpvarPath,
+	} {
+		t.Run(path, func(t *testing.T) {
+			code, body := get(t, base+path)
+			if code != http.StatusOK {
+				t.Errorf("Expected %d, got %d: %s", http.StatusOK, code, body)
+			}
+		})
+	}
+
+	t.Run("metrics-content", func(t *testing.T) {
+		_, body := get(t, base+adminbp.MetricsPath)
+		if !strings.Contains(body, "go_goroutines") {
+			t.Errorf("Expected go_goroutines in metrics, got %q", body)
+		}
+	})
+}
+
+func TestServerAllowedNetworks(t *testing.T) {
+	t.Run("allowed", func(t *testing.T) {
+		base := startServer(t, adminbp.Config{
+			Addr:            "127.0.0.1:0",
+			AllowedNetworks: []string{"10.0.0.0/8", "127.0.0.0/8"},
+		})
+		if code, body := get(t, base+adminbp.MetricsPath); code != http.StatusOK {
+			t.Errorf("Expected %d, got %d: %s", http.StatusOK, code, body)
+		}
+	})
+
+	t.Run("default", func(t *testing.T) {
+		defer func(networks []string) {
+			adminbp.DefaultAllowedNetworks = networks
+		}(adminbp.DefaultAllowedNetworks)
+		adminbp.DefaultAllowedNetworks = []string{"10.0.0.0/8"}
+
+		base := startServer(t, adminbp.Config{
+			Addr: "127.0.0.1:0",
+		})
+		if code, body := get(t, base+adminbp.MetricsPath); code != http.StatusForbidden {
+			t.Errorf("Expected %d, got %d: %s", http.StatusForbidden, code, body)
+		}
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		base := startServer(t, adminbp.Config{
+			Addr:            "127.0.0.1:0",
+			AllowedNetworks: []string{"10.0.0.0/8"},
+		})
+		if code, body := get(t, base+adminbp.MetricsPath); code != http.StatusForbidden {
+			t.Errorf("Expected %d, got %d: %s", http.StatusForbidden, code, body)
+		}
+	})
+}
+
+func TestCloseWithoutServe(t *testing.T) {
+	server, err := adminbp.New(adminbp.Config{
+		Addr: "127.0.0.1:0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := server.Addr().String()
+	if err := server.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Expected the listener to be closed, got %v", err)
+	}
+	ln.Close()
+}
+
+func TestNewErrors(t *testing.T) {
+	for _, c := range []struct {
+		label string
+		cfg   adminbp.Config
+	}{
+		{
+			label: "empty-addr",
+			cfg:   adminbp.Config{},
+		},
+		{
+			label: "invalid-network",
+			cfg: adminbp.Config{
+				Addr:            "127.0.0.1:0",
+				AllowedNetworks: []string{"foo"},
+			},
+		},
+		{
+			label: "missing-tls-files",
+			cfg: adminbp.Config{
+				Addr: "127.0.0.1:0",
+				TLS: adminbp.TLSConfig{
+					CertFile: "/path/to/nowhere/cert.pem",
+					KeyFile:  "/path/to/nowhere/key.pem",
+				},
+			},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			if server, err := adminbp.New(c.cfg); err == nil {
+				server.Close()
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package backgroundbp
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/ecinterface"
+)
+
+// Detach returns a new context carrying the edge context and the span of ctx,
+// but not its deadline and cancellation.
+//
+// It's useful to continue the work of a request after the request finished.
+// Other values of ctx are not carried over,
+// as they are usually bound to the request (e.g. the thrift headers).
+//
+// If ecImpl is nil, the global one from ecinterface.Get will be used instead.
+func Detach(ctx context.Context, ecImpl ecinterface.Interface) context.Context {
+	if ecImpl == nil {
+		ecImpl = ecinterface.Get()
+	}
+	return detach(context.Background(), ctx, ecImpl)
+}
+
+// detach carries the values of from over to base.
+func detach(base, from context.Context, ecImpl ecinterface.Interface) context.Context {
+	ctx := base
+	if header, ok := ecImpl.ContextToHeader(from); ok {
+		// The header was just serialized from a valid edge context,
+		// so failing to parse it back is unexpected and the edge context is
+		// dropped in that case.
+		if detached, err := ecImpl.HeaderToContext(ctx, header); err == nil {
+			ctx = detached
+		}
+	}
+	if span := opentracing.SpanFromContext(from); span != nil {
+		ctx = opentracing.ContextWithSpan(ctx, span)
+	}
+	return ctx
+}
@@ -0,0 +1,17 @@
+// Package backgroundbp provides a managed runner of the background tasks
+// launched from the request handlers.
+//
+// Using a bare goroutine for the work that outlives the request (e.g.
+// invalidating caches, sending notifications) has a few pitfalls:
+// the request context is canceled when the request finishes,
+// the number of the goroutines is unbounded under load,
+// the work is invisible to tracing and metrics,
+// and it's killed halfway through when the service shuts down.
+//
+// Runner addresses them by detaching the values of the request context (the
+// edge context and the span) into a new context (see Detach),
+// bounding the concurrency and the duration of the tasks,
+// recording a span and the metrics for every task,
+// and draining the outstanding tasks when it's closed during graceful
+// shutdown.
+package backgroundbp
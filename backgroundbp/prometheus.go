@@ -0,0 +1,49 @@
+package backgroundbp
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus label names used by the Runner metrics.
+const (
+	PrometheusRunnerLabel = "backgroundbp_runner"
+	PrometheusTaskLabel   = "backgroundbp_task"
+	PrometheusResultLabel = "backgroundbp_result"
+)
+
+// Values of PrometheusResultLabel.
+const (
+	resultSuccess  = "success"
+	resultError    = "error"
+	resultTimeout  = "timeout"
+	resultPanic    = "panic"
+	resultRejected = "rejected"
+)
+
+var (
+	tasksCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "backgroundbp_tasks_total",
+		Help: "Total number of the background tasks launched via backgroundbp.Runner, by result",
+	}, []string{
+		PrometheusRunnerLabel,
+		PrometheusTaskLabel,
+		PrometheusResultLabel,
+	})
+
+	taskDurationHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "backgroundbp_task_duration_seconds",
+		Help:    "Duration of the background tasks run by backgroundbp.Runner",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+	}, []string{
+		PrometheusRunnerLabel,
+		PrometheusTaskLabel,
+	})
+
+	activeTasksGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "backgroundbp_active_tasks",
+		Help: "Number of the background tasks currently running in backgroundbp.Runner",
+	}, []string{
+		PrometheusRunnerLabel,
+	})
+)
@@ -0,0 +1,243 @@
+package backgroundbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// Default values of Config.
+const (
+	DefaultMaxConcurrency = 100
+	DefaultTimeout        = 30 * time.Second
+)
+
+// Errors returned by Runner.Go.
+var (
+	// ErrTooManyTasks is returned when MaxConcurrency tasks are already
+	// running.
+	ErrTooManyTasks = errors.New("backgroundbp: too many tasks running")
+
+	// ErrRunnerClosed is returned after the Runner is closed.
+	ErrRunnerClosed = errors.New("backgroundbp: runner closed")
+)
+
+// Task is the background work run by a Runner.
+//
+// ctx is detached from the request context (see Detach),
+// and is canceled when the task times out,
+// or when the Runner fails to drain it in time.
+type Task func(ctx context.Context) error
+
+// Config is the config of a Runner.
+type Config struct {
+	// Name of the Runner, used in the metrics.
+	Name string
+
+	// The max number of the tasks running at the same time,
+	// <=0 means DefaultMaxConcurrency.
+	MaxConcurrency int
+
+	// The timeout of every task,
+	// 0 means DefaultTimeout, and <0 means no timeout.
+	Timeout time.Duration
+
+	// How long Close waits for the outstanding tasks before canceling them,
+	// <=0 means Close waits for them to finish (or time out).
+	DrainTimeout time.Duration
+
+	// The edge context implementation used to detach the edge context from the
+	// request context.
+	//
+	// If it's nil, the global one from ecinterface.Get will be used instead.
+	EdgeContextImpl ecinterface.Interface
+
+	// Logger is used to log the tasks failed or panicked.
+	//
+	// Optional, nil means log.DefaultWrapper.
+	Logger log.Wrapper
+}
+
+// Runner runs the background tasks.
+//
+// It's safe for concurrent use.
+type Runner struct {
+	cfg Config
+	sem chan struct{}
+
+	// The base of the contexts of the tasks, canceled by Drain.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lock   sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewRunner creates a Runner.
+func NewRunner(cfg Config) *Runner {
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = DefaultMaxConcurrency
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	r := &Runner{
+		cfg: cfg,
+		sem: make(chan struct{}, cfg.MaxConcurrency),
+	}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	return r
+}
+
+// Go runs task named name in the background.
+//
+// The edge context and the span of ctx are detached into the context of the
+// task, and the task is run in a local span named "background.<name>"
+// as a child of the span of ctx.
+//
+// It never blocks.
+// When MaxConcurrency tasks are already running it returns ErrTooManyTasks,
+// and after the Runner is closed it returns ErrRunnerClosed,
+// without running task.
+func (r *Runner) Go(ctx context.Context, name string, task Task) error {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if r.closed {
+		r.report(name, resultRejected)
+		return ErrRunnerClosed
+	}
+	select {
+	case r.sem <- struct{}{}:
+	default:
+		r.report(name, resultRejected)
+		return ErrTooManyTasks
+	}
+
+	taskCtx := detach(r.ctx, ctx, r.ecImpl())
+	r.wg.Add(1)
+	activeTasksGauge.With(prometheus.Labels{
+		PrometheusRunnerLabel: r.cfg.Name,
+	}).Inc()
+	go r.run(taskCtx, name, task)
+	return nil
+}
+
+func (r *Runner) run(ctx context.Context, name string, task Task) {
+	start := time.Now()
+	defer func() {
+		<-r.sem
+		activeTasksGauge.With(prometheus.Labels{
+			PrometheusRunnerLabel: r.cfg.Name,
+		}).Dec()
+		taskDurationHistogram.With(prometheus.Labels{
+			PrometheusRunnerLabel: r.cfg.Name,
+			PrometheusTaskLabel:   name,
+		}).Observe(time.Since(start).Seconds())
+		r.wg.Done()
+	}()
+
+	if r.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.Timeout)
+		defer cancel()
+	}
+
+	otSpan, ctx := opentracing.StartSpanFromContext(
+		ctx,
+		"background."+name,
+		tracing.SpanTypeOption{Type: tracing.SpanTypeLocal},
+	)
+	span := tracing.AsSpan(otSpan)
+
+	var err error
+	result := resultSuccess
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("backgroundbp: task %q panicked: %v", name, p)
+			result = resultPanic
+		}
+		span.FinishWithOptions(tracing.FinishOptions{
+			Ctx: ctx,
+			Err: err,
+		}.Convert())
+		if err != nil {
+			r.cfg.Logger.Log(ctx, fmt.Sprintf("backgroundbp: task %q failed: %v", name, err))
+		}
+		r.report(name, result)
+	}()
+
+	err = task(ctx)
+	if err != nil {
+		result = resultError
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			result = resultTimeout
+		}
+	}
+}
+
+func (r *Runner) report(name, result string) {
+	tasksCounter.With(prometheus.Labels{
+		PrometheusRunnerLabel: r.cfg.Name,
+		PrometheusTaskLabel:   name,
+		PrometheusResultLabel: result,
+	}).Inc()
+}
+
+func (r *Runner) ecImpl() ecinterface.Interface {
+	if r.cfg.EdgeContextImpl != nil {
+		return r.cfg.EdgeContextImpl
+	}
+	return ecinterface.Get()
+}
+
+// Drain stops accepting new tasks, and waits for the outstanding tasks to
+// finish.
+//
+// When ctx is done before that, the outstanding tasks are canceled and
+// ctx.Err() is returned.
+func (r *Runner) Drain(ctx context.Context) error {
+	r.lock.Lock()
+	r.closed = true
+	r.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		r.cancel()
+		return nil
+	case <-ctx.Done():
+		r.cancel()
+		return ctx.Err()
+	}
+}
+
+// Close implements io.Closer.
+//
+// It calls Drain with Config.DrainTimeout.
+// It's usually registered to the graceful shutdown after the servers are
+// closed (e.g. as a PostShutdown closer of baseplate.Serve,
+// or to runtimebp.ShutdownGroupDrain+1 of a runtimebp.ShutdownManager),
+// so no more tasks are launched by the requests.
+func (r *Runner) Close() error {
+	ctx := context.Background()
+	if r.cfg.DrainTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.DrainTimeout)
+		defer cancel()
+	}
+	return r.Drain(ctx)
+}
@@ -0,0 +1,199 @@
+package backgroundbp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+func TestDetach(t *testing.T) {
+	ecImpl := ecinterface.Mock()
+	ctx, err := ecImpl.HeaderToContext(context.Background(), "edge-context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, span := tracing.StartTopLevelServerSpan(ctx, "request")
+	defer span.Finish()
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	detached := Detach(ctx, ecImpl)
+	if err := detached.Err(); err != nil {
+		t.Errorf("Expected detached context not canceled, got %v", err)
+	}
+	if header, ok := ecImpl.ContextToHeader(detached); !ok || header != "edge-context" {
+		t.Errorf("Expected edge context %q, got %q, %v", "edge-context", header, ok)
+	}
+	if got := opentracing.SpanFromContext(detached); got != span {
+		t.Errorf("Expected span %v, got %v", span, got)
+	}
+}
+
+func TestRunner(t *testing.T) {
+	const name = "TestRunner"
+	ecImpl := ecinterface.Mock()
+	runner := NewRunner(Config{
+		Name:            name,
+		MaxConcurrency:  1,
+		Timeout:         -1,
+		EdgeContextImpl: ecImpl,
+		Logger:          nopLogger,
+	})
+	results := map[string]string{
+		"block":    resultSuccess,
+		"rejected": resultRejected,
+		"closed":   resultRejected,
+	}
+	before := make(map[string]float64, len(results))
+	for task, result := range results {
+		before[task] = taskCount(name, task, result)
+	}
+
+	ctx, err := ecImpl.HeaderToContext(context.Background(), "edge-context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	if err := runner.Go(ctx, "block", func(ctx context.Context) error {
+		<-release
+		if header, ok := ecImpl.ContextToHeader(ctx); !ok || header != "edge-context" {
+			done <- errors.New("edge context not detached")
+			return nil
+		}
+		done <- ctx.Err()
+		return nil
+	}); err != nil {
+		t.Fatalf("Go returned %v", err)
+	}
+	// The request finished.
+	cancel()
+
+	if err := runner.Go(context.Background(), "rejected", func(ctx context.Context) error {
+		t.Error("Expected the task not run")
+		return nil
+	}); !errors.Is(err, ErrTooManyTasks) {
+		t.Errorf("Expected ErrTooManyTasks, got %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Task got %v", err)
+	}
+	if err := runner.Close(); err != nil {
+		t.Errorf("Close returned %v", err)
+	}
+
+	if err := runner.Go(context.Background(), "closed", func(ctx context.Context) error {
+		t.Error("Expected the task not run")
+		return nil
+	}); !errors.Is(err, ErrRunnerClosed) {
+		t.Errorf("Expected ErrRunnerClosed, got %v", err)
+	}
+
+	for task, result := range results {
+		if got := taskCount(name, task, result) - before[task]; got != 1 {
+			t.Errorf("Expected 1 %s task %q, got %v", result, task, got)
+		}
+	}
+}
+
+func TestRunnerResults(t *testing.T) {
+	const name = "TestRunnerResults"
+	runner := NewRunner(Config{
+		Name:            name,
+		Timeout:         time.Millisecond,
+		EdgeContextImpl: ecinterface.Mock(),
+		Logger:          nopLogger,
+	})
+	results := []string{resultError, resultTimeout, resultPanic}
+	before := make(map[string]float64, len(results))
+	for _, result := range results {
+		before[result] = taskCount(name, result, result)
+	}
+	for _, task := range []struct {
+		name string
+		task Task
+	}{
+		{
+			name: "error",
+			task: func(ctx context.Context) error {
+				return errors.New("failed")
+			},
+		},
+		{
+			name: "timeout",
+			task: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+		{
+			name: "panic",
+			task: func(ctx context.Context) error {
+				panic("oops")
+			},
+		},
+	} {
+		if err := runner.Go(context.Background(), task.name, task.task); err != nil {
+			t.Fatalf("Go %q returned %v", task.name, err)
+		}
+	}
+	if err := runner.Close(); err != nil {
+		t.Fatalf("Close returned %v", err)
+	}
+	for _, result := range results {
+		if got := taskCount(name, result, result) - before[result]; got != 1 {
+			t.Errorf("Expected 1 %s task, got %v", result, got)
+		}
+	}
+}
+
+func TestRunnerDrainTimeout(t *testing.T) {
+	runner := NewRunner(Config{
+		Name:            "TestRunnerDrainTimeout",
+		Timeout:         -1,
+		DrainTimeout:    time.Millisecond,
+		EdgeContextImpl: ecinterface.Mock(),
+		Logger:          nopLogger,
+	})
+	done := make(chan error, 1)
+	if err := runner.Go(context.Background(), "forever", func(ctx context.Context) error {
+		<-ctx.Done()
+		done <- ctx.Err()
+		return nil
+	}); err != nil {
+		t.Fatalf("Go returned %v", err)
+	}
+	if err := runner.Close(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Close to return context.DeadlineExceeded, got %v", err)
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the task canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("The task was not canceled")
+	}
+}
+
+func taskCount(runner, task, result string) float64 {
+	return testutil.ToFloat64(tasksCounter.With(prometheus.Labels{
+		PrometheusRunnerLabel: runner,
+		PrometheusTaskLabel:   task,
+		PrometheusResultLabel: result,
+	}))
+}
+
+func nopLogger(context.Context, string) {}
@@ -5,13 +5,20 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/reddit/baseplate.go/adminbp"
 	"github.com/reddit/baseplate.go/batchcloser"
 	"github.com/reddit/baseplate.go/configbp"
 	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/errorsbp"
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/healthbp"
 	"github.com/reddit/baseplate.go/log"
 	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/profilebp"
 	"github.com/reddit/baseplate.go/runtimebp"
 	"github.com/reddit/baseplate.go/secrets"
 	"github.com/reddit/baseplate.go/tracing"
@@ -46,12 +53,38 @@ type Config struct {
 	// If this is less than 0, then no timeout will be set on the Stop command.
 	StopTimeout time.Duration `yaml:"stopTimeout"`
 
-	Log     log.Config       `yaml:"log"`
-	Metrics metricsbp.Config `yaml:"metrics"`
-	Runtime runtimebp.Config `yaml:"runtime"`
-	Secrets secrets.Config   `yaml:"secrets"`
-	Sentry  log.SentryConfig `yaml:"sentry"`
-	Tracing tracing.Config   `yaml:"tracing"`
+	// StartupTimeout is the max time Serve waits for the dependencies
+	// required from the StartupGate of the Baseplate (see StartupGateProvider)
+	// to be initialized, before giving up with the error naming the ones still
+	// pending.
+	//
+	// If this is not set, then DefaultStartupTimeout will be used.
+	// If this is less than 0, then Serve will wait indefinitely.
+	StartupTimeout time.Duration `yaml:"startupTimeout"`
+
+	// Admin is the config of the admin server serving Prometheus metrics,
+	// pprof and other admin endpoints on a dedicated listener.
+	//
+	// When Admin.Addr is set, New starts the admin server,
+	// with the following endpoints registered in addition to the default ones
+	// of adminbp.Server:
+	//
+	// - adminbp.ConfigPath: the effective config with the sensitive values
+	// redacted, see adminbp.RedactConfig.
+	//
+	// - adminbp.SecretsPath: the metadata of the secrets, without their values.
+	//
+	// - adminbp.DrainPath: POST to start the graceful shutdown of Serve,
+	// the same as receiving SIGTERM.
+	Admin adminbp.Config `yaml:"admin"`
+
+	Log       log.Config       `yaml:"log"`
+	Metrics   metricsbp.Config `yaml:"metrics"`
+	Profiling profilebp.Config `yaml:"profiling"`
+	Runtime   runtimebp.Config `yaml:"runtime"`
+	Secrets   secrets.Config   `yaml:"secrets"`
+	Sentry    log.SentryConfig `yaml:"sentry"`
+	Tracing   tracing.Config   `yaml:"tracing"`
 }
 
 // GetConfig implements Configer.
@@ -69,6 +102,62 @@ type Baseplate interface {
 	Secrets() *secrets.Store
 }
 
+// LifecycleProvider is an optional interface a Baseplate can implement to
+// provide the Lifecycle hooks run by Serve.
+//
+// The Baseplate returned by New implements it, use a type assertion to access
+// it:
+//
+//     if provider, ok := bp.(baseplate.LifecycleProvider); ok {
+//         provider.Lifecycle().Register(baseplate.PhasePreDrain, "name", hook)
+//     }
+//
+// Serve runs no hooks for the Baseplates not implementing it.
+type LifecycleProvider interface {
+	// Lifecycle returns the registry of the hooks to be run by Serve in the
+	// lifecycle phases of the service.
+	Lifecycle() *Lifecycle
+}
+
+// StartupGateProvider is an optional interface a Baseplate can implement to
+// provide the healthbp.StartupGate Serve waits for before starting the Server.
+//
+// The Baseplate returned by New implements it, and fails the readiness probes
+// of healthbp.DefaultRegistry until its StartupGate is ready.
+// Use a type assertion to access it:
+//
+//     if provider, ok := bp.(baseplate.StartupGateProvider); ok {
+//         cfg.StartupSignal = provider.StartupGate().Require("name")
+//     }
+//
+// Serve doesn't wait for the Baseplates not implementing it.
+type StartupGateProvider interface {
+	// StartupGate returns the gate of the dependencies required to be
+	// initialized before the service takes traffic.
+	StartupGate() *healthbp.StartupGate
+}
+
+// DefaultStartupTimeout is the default value of Config.StartupTimeout.
+const DefaultStartupTimeout = time.Minute
+
+// ConfigChangeNotifier is an optional interface a Baseplate can implement to
+// notify the changes of the config file.
+//
+// The Baseplate returned by New implements it, use a type assertion to access
+// it:
+//
+//     if notifier, ok := bp.(baseplate.ConfigChangeNotifier); ok {
+//         notifier.OnConfigChange(hook)
+//     }
+type ConfigChangeNotifier interface {
+	// OnConfigChange registers a hook to be called when the config file changes.
+	//
+	// It's only effective when the Baseplate is created by New with
+	// NewArgs.WatchConfig set to true, otherwise the hooks are never called.
+	// See ConfigWatcher.OnConfigChange for more details.
+	OnConfigChange(hook ConfigChangeHook)
+}
+
 // Server is the primary interface for baseplate servers.
 type Server interface {
 	// Close should stop the server gracefully and only return after the server has
@@ -108,16 +197,29 @@ type ServeArgs struct {
 
 // Serve runs the given Server until it is given an external shutdown signal.
 //
-// It uses runtimebp.HandleShutdown to handle the signal and gracefully shut
-// down, in order:
+// Before starting the Server, it runs the PhasePostInit hooks,
+// waits for the dependencies required from the StartupGate of the Server's
+// Baseplate to be initialized (see StartupGateProvider and
+// Config.StartupTimeout),
+// and runs the PhasePreServe hooks registered into the Lifecycle of the
+// Server's Baseplate (see LifecycleProvider),
+// and returns the error without starting the Server if any of them fails.
+//
+// It uses runtimebp.HandleShutdown to handle the signal
+// (or the drain endpoint of the admin server, see Config.Admin) and gracefully
+// shut down, in order:
+//
+// * the PhasePreDrain hooks,
 //
 // * any provided PreShutdown closers,
 //
-// * the Server, and
+// * the Server,
+//
+// * any provided PostShutdown closers, and
 //
-// * any provided PostShutdown closers.
+// * the PhasePostShutdown hooks.
 //
-// Returns the (possibly nil) error returned by "Close", or
+// Returns the (possibly nil) errors returned by the hooks and "Close", or
 // context.DeadlineExceeded if it times out.
 //
 // If a StopTimeout is configured, Serve will wait for that duration for the
@@ -127,19 +229,48 @@ type ServeArgs struct {
 // server.Start/Stop directly.
 func Serve(ctx context.Context, args ServeArgs) error {
 	server := args.Server
+	// A nil *Lifecycle has no hooks to run.
+	var lifecycle *Lifecycle
+	if provider, ok := server.Baseplate().(LifecycleProvider); ok {
+		lifecycle = provider.Lifecycle()
+	}
+	// A nil *healthbp.StartupGate has nothing to wait for.
+	var startup *healthbp.StartupGate
+	if provider, ok := server.Baseplate().(StartupGateProvider); ok {
+		startup = provider.StartupGate()
+	}
+
+	// Run the startup hooks before listening for the shutdown signal,
+	// so a failed startup returns directly.
+	if err := lifecycle.Run(ctx, PhasePostInit); err != nil {
+		return err
+	}
+	if err := waitStartup(ctx, startup, server.Baseplate().GetConfig().StartupTimeout); err != nil {
+		return fmt.Errorf("baseplate.Serve: dependencies not initialized: %w", err)
+	}
+	if err := lifecycle.Run(ctx, PhasePreServe); err != nil {
+		return err
+	}
 
 	// Initialize a channel to return the response from server.Close() as our
 	// return value.
-	shutdownChannel := make(chan error)
+	//
+	// It's buffered with size 1 as the shutdown could also be triggered by the
+	// server failing, in which case we only read from it after shutdown returns.
+	shutdownChannel := make(chan error, 1)
 
-	// Listen for a shutdown command.
+	// shutdown gracefully shuts down the server and the closers.
 	//
-	// This is a blocking call so it is in a separate goroutine.  It will exit
-	// either when it is triggered via a shutdown command or if the context passed
-	// in is cancelled.
-	go runtimebp.HandleShutdown(
-		ctx,
-		func(signal os.Signal) {
+	// It's triggered either by a shutdown command or by the server failing,
+	// whichever comes first, and only runs once.
+	var (
+		shutdownOnce sync.Once
+		shuttingDown int32
+	)
+	shutdown := func(reason ...interface{}) {
+		shutdownOnce.Do(func() {
+			atomic.StoreInt32(&shuttingDown, 1)
+
 			// Check if the server has a StopTimeout configured.
 			//
 			// If one is set, we will only wait for that duration for the server to
@@ -166,14 +297,19 @@ func Serve(ctx context.Context, args ServeArgs) error {
 			// It's buffered with size 1 to avoid blocking the goroutine forever.
 			closeChannel := make(chan error, 1)
 
-			// Tell the server and any provided closers to close.
+			// Tell the server and any provided closers to close,
+			// surrounded by the shutdown hooks.
 			//
 			// This is a blocking call, so it is called in a separate goroutine.
 			go func() {
+				var batch errorsbp.Batch
+				batch.Add(lifecycle.Run(ctx, PhasePreDrain))
 				bc := batchcloser.New(args.PreShutdown...)
 				bc.Add(server)
 				bc.Add(args.PostShutdown...)
-				closeChannel <- bc.Close()
+				batch.Add(bc.Close())
+				batch.Add(lifecycle.Run(ctx, PhasePostShutdown))
+				closeChannel <- batch.Compile()
 			}()
 
 			// Declare the error variable we will use later here so we can set it to
@@ -194,19 +330,57 @@ func Serve(ctx context.Context, args ServeArgs) error {
 
 			log.Infow(
 				"graceful shutdown",
-				"signal", signal,
-				"close error", err,
+				append(reason, "close error", err)...,
 			)
 
 			// Pass the final, potentially nil, error to shutdownChannel.
 			shutdownChannel <- err
+		})
+	}
+
+	// Listen for a shutdown command.
+	//
+	// This is a blocking call so it is in a separate goroutine.  It will exit
+	// either when it is triggered via a shutdown command or if the context passed
+	// in is cancelled (including when Serve returns).
+	signalCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go runtimebp.HandleShutdown(
+		signalCtx,
+		func(signal os.Signal) {
+			shutdown("signal", signal)
 		},
 	)
+	if bp, ok := server.Baseplate().(interface{ drainTriggered() <-chan struct{} }); ok {
+		go func() {
+			select {
+			case <-bp.drainTriggered():
+				shutdown("admin", "drain")
+			case <-signalCtx.Done():
+			}
+		}()
+	}
 
 	// Start the server.
 	//
 	// This is a blocking command and will run until the server is closed.
-	log.Info(server.Serve())
+	if err := server.Serve(); err != nil {
+		if atomic.LoadInt32(&shuttingDown) != 0 {
+			// The error happened while closing the server,
+			// which will be reported by shutdown.
+			log.Info(err)
+			return <-shutdownChannel
+		}
+
+		// The server failed on its own (e.g. one of the servers in a
+		// MultiServer failed), shut down instead of waiting for a shutdown command
+		// that may never come.
+		log.Errorw("baseplate: server stopped with error", "err", err)
+		shutdown("serve error", err)
+		var batch errorsbp.Batch
+		batch.Add(err, <-shutdownChannel)
+		return batch.Compile()
+	}
 
 	// Return the error passed via shutdownChannel to the caller.
 	//
@@ -272,6 +446,20 @@ type NewArgs struct {
 	//
 	// The factory to be used to create edge context implementation.
 	EdgeContextFactory ecinterface.Factory
+
+	// Optional. When true, New watches the config file at
+	// $BASEPLATE_CONFIG_PATH (see ParseConfigYAML) for changes,
+	// and calls the hooks registered via ConfigChangeNotifier.OnConfigChange
+	// of the returned Baseplate with the updated config.
+	//
+	// Config must be the same type ParseConfigYAML parsed the file into,
+	// and the Baseplate's GetConfig returns the latest config.
+	//
+	// New also registers a hook updating the log levels on changes,
+	// unless the log levels are from Log.LevelFile.
+	// The other subsystems initialized by New (e.g. secrets, tracing) are not
+	// reinitialized on changes.
+	WatchConfig bool
 }
 
 // New initializes Baseplate libraries with the given config,
@@ -281,7 +469,13 @@ type NewArgs struct {
 // The returned context will be cancelled when the Baseplate is closed.
 func New(ctx context.Context, args NewArgs) (context.Context, Baseplate, error) {
 	cfg := args.Config.GetConfig()
-	bp := impl{cfg: cfg, closers: batchcloser.New()}
+	bp := impl{
+		cfg:       cfg,
+		closers:   batchcloser.New(),
+		lifecycle: new(Lifecycle),
+		startup:   new(healthbp.StartupGate),
+		drain:     newDrainTrigger(),
+	}
 
 	runtimebp.InitFromConfig(cfg.Runtime)
 
@@ -289,6 +483,54 @@ func New(ctx context.Context, args NewArgs) (context.Context, Baseplate, error)
 	bp.closers.Add(batchcloser.WrapCancel(cancel))
 
 	log.InitFromConfig(cfg.Log)
+	if cfg.Log.LevelFile != "" {
+		closer, err := watchLogLevel(ctx, cfg.Log.LevelFile)
+		if err != nil {
+			bp.Close()
+			return nil, nil, fmt.Errorf(
+				"baseplate.New: failed to watch log level file: %w (config: %#v)",
+				err,
+				cfg.Log,
+			)
+		}
+		bp.closers.Add(closer)
+	}
+	if args.WatchConfig {
+		watcher, err := watchConfig(ctx, args.Config)
+		if err != nil {
+			bp.Close()
+			return nil, nil, fmt.Errorf(
+				"baseplate.New: failed to watch config file: %w (path: %q)",
+				err,
+				configbp.BaseplateConfigPath,
+			)
+		}
+		bp.closers.Add(watcher)
+		bp.watcher = watcher
+		if cfg.Log.LevelFile == "" {
+			watcher.OnConfigChange(logLevelHook)
+		}
+	}
+	otlpCloser, err := log.InitOTLP(cfg.Log.OTLP)
+	if err != nil {
+		bp.Close()
+		return nil, nil, fmt.Errorf(
+			"baseplate.New: failed to init otlp log export: %w (config: %#v)",
+			err,
+			cfg.Log.OTLP,
+		)
+	}
+	bp.closers.Add(otlpCloser)
+	auditCloser, err := log.InitAudit(cfg.Log.Audit)
+	if err != nil {
+		bp.Close()
+		return nil, nil, fmt.Errorf(
+			"baseplate.New: failed to init audit log: %w (config: %#v)",
+			err,
+			cfg.Log.Audit,
+		)
+	}
+	bp.closers.Add(auditCloser)
 	bp.closers.Add(metricsbp.InitFromConfig(ctx, cfg.Metrics))
 
 	closer, err := log.InitSentry(cfg.Sentry)
@@ -312,6 +554,11 @@ func New(ctx context.Context, args NewArgs) (context.Context, Baseplate, error)
 		)
 	}
 	bp.closers.Add(bp.secrets)
+	bp.closers.Add(batchcloser.WrapCancel(healthbp.Add(
+		healthbp.SecretsCheckerName,
+		bp.secrets.CheckExpiration,
+		healthbp.CheckerOptions{},
+	)))
 
 	closer, err = tracing.InitFromConfig(cfg.Tracing)
 	if err != nil {
@@ -324,6 +571,51 @@ func New(ctx context.Context, args NewArgs) (context.Context, Baseplate, error)
 	}
 	bp.closers.Add(closer)
 
+	closer, err = profilebp.InitFromConfig(ctx, cfg.Profiling)
+	if err != nil {
+		bp.Close()
+		return nil, nil, fmt.Errorf(
+			"baseplate.New: failed to init profiling: %w (config: %#v)",
+			err,
+			cfg.Profiling,
+		)
+	}
+	bp.closers.Add(closer)
+
+	if cfg.Admin.Addr != "" {
+		admin, err := adminbp.Start(cfg.Admin)
+		if err != nil {
+			bp.Close()
+			return nil, nil, fmt.Errorf(
+				"baseplate.New: failed to start admin server: %w (config: %#v)",
+				err,
+				cfg.Admin,
+			)
+		}
+		bp.closers.Add(admin)
+
+		admin.Handle(adminbp.ConfigPath, adminbp.ConfigHandler(func() interface{} {
+			if bp.watcher != nil {
+				return bp.watcher.Get()
+			}
+			return args.Config
+		}))
+		admin.Handle(adminbp.SecretsPath, adminbp.SecretsHandler(bp.secrets))
+		admin.Handle(adminbp.DrainPath, adminbp.DrainHandler(func(context.Context) {
+			bp.drain.trigger()
+		}))
+	}
+
+	// Fail the readiness probes until the dependencies are initialized.
+	bp.closers.Add(batchcloser.WrapCancel(healthbp.Add(
+		healthbp.StartupCheckerName,
+		bp.startup.Check,
+		healthbp.CheckerOptions{
+			Criticality: healthbp.CriticalityReadiness,
+			CacheTTL:    -1,
+		},
+	)))
+
 	bp.ecImpl, err = args.EdgeContextFactory(ecinterface.FactoryArgs{
 		Store: bp.secrets,
 	})
@@ -338,17 +630,114 @@ func New(ctx context.Context, args NewArgs) (context.Context, Baseplate, error)
 	return ctx, bp, nil
 }
 
+// waitStartup waits for gate with timeout, see Config.StartupTimeout.
+func waitStartup(ctx context.Context, gate *healthbp.StartupGate, timeout time.Duration) error {
+	if gate == nil {
+		return nil
+	}
+	if timeout == 0 {
+		timeout = DefaultStartupTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return gate.Wait(ctx)
+}
+
+func watchLogLevel(ctx context.Context, path string) (io.Closer, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	fw, err := filewatcher.New(ctx, filewatcher.Config{
+		Path:   path,
+		Parser: log.LevelFileParser,
+		Logger: log.ErrorWithSentryWrapper(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batchcloser.Wrap(func() error {
+		fw.Stop()
+		return nil
+	}), nil
+}
+
+func watchConfig(ctx context.Context, cfg Configer) (*ConfigWatcher, error) {
+	if configbp.BaseplateConfigPath == "" {
+		return nil, fmt.Errorf("no $BASEPLATE_CONFIG_PATH specified, cannot watch config")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	return WatchConfig(ctx, configbp.BaseplateConfigPath, cfg, log.ErrorWithSentryWrapper())
+}
+
 type impl struct {
-	closers *batchcloser.BatchCloser
-	cfg     Config
-	ecImpl  ecinterface.Interface
-	secrets *secrets.Store
+	closers   *batchcloser.BatchCloser
+	cfg       Config
+	ecImpl    ecinterface.Interface
+	secrets   *secrets.Store
+	watcher   *ConfigWatcher
+	lifecycle *Lifecycle
+	startup   *healthbp.StartupGate
+	drain     *drainTrigger
+}
+
+// drainTrigger is used by the drain endpoint of the admin server to start the
+// graceful shutdown of Serve.
+type drainTrigger struct {
+	once sync.Once
+	ch   chan struct{}
+}
+
+func newDrainTrigger() *drainTrigger {
+	return &drainTrigger{
+		ch: make(chan struct{}),
+	}
+}
+
+func (d *drainTrigger) trigger() {
+	d.once.Do(func() {
+		close(d.ch)
+	})
+}
+
+// drainTriggered returns the channel closed when the drain is triggered.
+//
+// It's used by Serve via type assertion,
+// a nil channel (from NewTestBaseplate or other Baseplate implementations)
+// never triggers.
+func (bp impl) drainTriggered() <-chan struct{} {
+	if bp.drain == nil {
+		return nil
+	}
+	return bp.drain.ch
 }
 
 func (bp impl) GetConfig() Config {
+	if bp.watcher != nil {
+		return bp.watcher.Get().GetConfig()
+	}
 	return bp.cfg
 }
 
+func (bp impl) OnConfigChange(hook ConfigChangeHook) {
+	if bp.watcher != nil {
+		bp.watcher.OnConfigChange(hook)
+	}
+}
+
+func (bp impl) Lifecycle() *Lifecycle {
+	return bp.lifecycle
+}
+
+func (bp impl) StartupGate() *healthbp.StartupGate {
+	return bp.startup
+}
+
 func (bp impl) Secrets() *secrets.Store {
 	return bp.secrets
 }
@@ -382,14 +771,19 @@ type NewTestBaseplateArgs struct {
 // the monitoring or logging frameworks.
 func NewTestBaseplate(args NewTestBaseplateArgs) Baseplate {
 	return &impl{
-		cfg:     args.Config,
-		secrets: args.Store,
-		ecImpl:  args.EdgeContextImpl,
-		closers: batchcloser.New(),
+		cfg:       args.Config,
+		secrets:   args.Store,
+		ecImpl:    args.EdgeContextImpl,
+		closers:   batchcloser.New(),
+		lifecycle: new(Lifecycle),
+		startup:   new(healthbp.StartupGate),
 	}
 }
 
 var (
-	_ Baseplate = impl{}
-	_ Baseplate = (*impl)(nil)
+	_ Baseplate            = impl{}
+	_ Baseplate            = (*impl)(nil)
+	_ ConfigChangeNotifier = impl{}
+	_ LifecycleProvider    = impl{}
+	_ StartupGateProvider  = impl{}
 )
@@ -7,12 +7,14 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"sync"
 	"syscall"
 	"testing"
 	"time"
 
 	"github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/adminbp"
 	"github.com/reddit/baseplate.go/configbp"
 	"github.com/reddit/baseplate.go/ecinterface"
 	"github.com/reddit/baseplate.go/log"
@@ -345,3 +347,22 @@ anotherbackend:
 		}
 	})
 }
+
+func TestConfigRedactable(t *testing.T) {
+	// The admin server dumps the config via adminbp.RedactConfig,
+	// make sure all the sections of Config can be encoded.
+	data, err := adminbp.RedactConfig(serviceConfig{
+		Config: baseplate.Config{
+			Addr: ":8080",
+			Secrets: secrets.Config{
+				Path: "/tmp/secrets.json",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "path: /tmp/secrets.json") {
+		t.Errorf("Expected secrets path in config dump, got:\n%s", data)
+	}
+}
@@ -0,0 +1,28 @@
+// Package baseplatetest provides an in-process harness to write black-box
+// tests of baseplate services.
+//
+// New constructs a Baseplate with fake secrets and the mock edge context
+// implementation, starts the thrift, HTTP and gRPC servers configured, and
+// returns the clients connected to them.
+// The spans and the metrics reported while handling the requests are recorded
+// in memory, so the tests can assert on them,
+// for example to verify the behavior of the middlewares in a few lines:
+//
+//	h := baseplatetest.New(t, baseplatetest.Config{
+//		HTTPEndpoints: map[httpbp.Pattern]httpbp.Endpoint{
+//			"/hello": {
+//				Name:    "hello",
+//				Methods: []string{http.MethodGet},
+//				Handle:  hello,
+//			},
+//		},
+//	})
+//	resp, err := h.HTTPClient.Get(h.HTTPURL + "/hello")
+//	// check resp and err
+//	span := h.WaitForSpan(t, "hello")
+//	// check the tags of the span
+//
+// The tracer (tracing.InitGlobalTracer), the statsd metrics (metricsbp.M) and
+// the span hooks are global states,
+// so the tests using the harness must not run in parallel.
+package baseplatetest
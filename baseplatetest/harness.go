@@ -0,0 +1,304 @@
+package baseplatetest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/errorsbp"
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/grpcbp"
+	"github.com/reddit/baseplate.go/httpbp"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/secrets"
+	"github.com/reddit/baseplate.go/thriftbp"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// Default values used by New.
+const (
+	// DefaultServiceSlug is used as the ServiceSlug of the clients when
+	// Config.ServiceSlug is not set.
+	DefaultServiceSlug = "testing"
+
+	// DefaultClientTimeout is used as the connect and socket timeouts of the
+	// thrift client.
+	//
+	// We use a relatively large number as the default timeout because we often
+	// run tests from virtual environments with very limited resources.
+	DefaultClientTimeout = 500 * time.Millisecond
+
+	// DefaultWaitTimeout is the timeout of WaitForSpan.
+	DefaultWaitTimeout = time.Second
+
+	loopbackAddr = "127.0.0.1:0"
+
+	// The buffer size of the in-memory gRPC listener.
+	grpcBufferSize = 1024 * 1024
+)
+
+// Config is the config used by New.
+//
+// All the fields are optional,
+// the servers are only started for the protocols configured.
+type Config struct {
+	// The config of the Baseplate.
+	//
+	// Addr will always be replaced with the address of the thrift server.
+	Baseplate baseplate.Config
+
+	// The secrets in the fake secrets store,
+	// see secrets.NewTestSecrets for more details.
+	Secrets map[string]secrets.GenericSecret
+
+	// The edge context implementation,
+	// if it's not set, ecinterface.Mock() will be used instead.
+	EdgeContextImpl ecinterface.Interface
+
+	// The name of the service the clients use,
+	// DefaultServiceSlug will be used when it's empty.
+	ServiceSlug string
+
+	// The processor of the thrift server,
+	// the thrift server is started when it's non-nil.
+	ThriftProcessor thrift.TProcessor
+
+	// The additional ProcessorMiddlewares to wrap the thrift server with,
+	// after BaseplateDefaultProcessorMiddlewares.
+	ThriftMiddlewares []thrift.ProcessorMiddleware
+
+	// The ErrorSpanSuppressor used by the InjectServerSpan middleware of the
+	// thrift server.
+	ErrorSpanSuppressor errorsbp.Suppressor
+
+	// The endpoints of the HTTP server,
+	// the HTTP server is started when it's non-empty.
+	HTTPEndpoints map[httpbp.Pattern]httpbp.Endpoint
+
+	// The additional Middlewares to wrap the HTTP endpoints with,
+	// after httpbp.DefaultMiddleware.
+	HTTPMiddlewares []httpbp.Middleware
+
+	// The function to register the services to the gRPC server,
+	// the gRPC server is started on an in-memory listener when it's non-nil.
+	GRPCRegister func(s *grpc.Server)
+
+	// The additional interceptors of the gRPC server,
+	// after the ones injecting the server span and the edge context.
+	GRPCInterceptors []grpc.UnaryServerInterceptor
+}
+
+// Harness is the in-process test harness returned by New.
+type Harness struct {
+	// Baseplate is the Baseplate the servers are built on.
+	Baseplate baseplate.Baseplate
+
+	// SecretsWatcher can be used to update the secrets in
+	// Baseplate.Secrets().
+	SecretsWatcher *filewatcher.MockFileWatcher
+
+	// ThriftClient is the ClientPool connected to the thrift server,
+	// nil when Config.ThriftProcessor is nil.
+	ThriftClient thriftbp.ClientPool
+
+	// HTTPURL is the base URL of the HTTP server in the form of
+	// "http://ip:port", and HTTPClient is the client to be used with it.
+	//
+	// They are zero values when Config.HTTPEndpoints is empty.
+	HTTPURL    string
+	HTTPClient *http.Client
+
+	// GRPCConn is the connection to the gRPC server,
+	// nil when Config.GRPCRegister is nil.
+	GRPCConn *grpc.ClientConn
+
+	spans   *spanRecorder
+	metrics *metricsRecorder
+}
+
+// New creates a Harness and starts the configured servers.
+//
+// Everything is cleaned up via tb.Cleanup when the test finishes,
+// including restoring the global states it replaced.
+// It calls tb.Fatal when it fails to start anything.
+func New(tb testing.TB, cfg Config) *Harness {
+	tb.Helper()
+
+	if cfg.EdgeContextImpl == nil {
+		cfg.EdgeContextImpl = ecinterface.Mock()
+	}
+	if cfg.ServiceSlug == "" {
+		cfg.ServiceSlug = DefaultServiceSlug
+	}
+
+	h := &Harness{
+		spans:   newSpanRecorder(),
+		metrics: newMetricsRecorder(tb),
+	}
+
+	// Replace the global states, in the reverse order of the restorations.
+	prevStatsd := metricsbp.M
+	metricsbp.M = h.metrics.statsd
+	tracing.ResetHooks()
+	tracing.RegisterCreateServerSpanHooks(metricsbp.CreateServerSpanHook{Metrics: metricsbp.M})
+	tb.Cleanup(func() {
+		tracing.ResetHooks()
+		metricsbp.M = prevStatsd
+	})
+	logger, startFailing := tracing.TestWrapper(tb)
+	if err := tracing.InitGlobalTracer(tracing.Config{
+		SampleRate:               1,
+		Logger:                   logger,
+		TestOnlyMockMessageQueue: h.spans,
+	}); err != nil {
+		tb.Fatalf("baseplatetest: failed to init tracer: %v", err)
+	}
+	startFailing()
+	tb.Cleanup(func() {
+		tracing.InitGlobalTracer(tracing.Config{})
+	})
+
+	store, watcher, err := secrets.NewTestSecrets(context.Background(), cfg.Secrets)
+	if err != nil {
+		tb.Fatalf("baseplatetest: failed to create secrets: %v", err)
+	}
+	h.SecretsWatcher = watcher
+
+	var socket *thrift.TServerSocket
+	if cfg.ThriftProcessor != nil {
+		socket, err = thrift.NewTServerSocket(loopbackAddr)
+		if err != nil {
+			tb.Fatalf("baseplatetest: failed to create thrift socket: %v", err)
+		}
+		// Call listen to reserve a port before starting the server.
+		if err := socket.Listen(); err != nil {
+			tb.Fatalf("baseplatetest: failed to listen: %v", err)
+		}
+		cfg.Baseplate.Addr = socket.Addr().String()
+	}
+
+	h.Baseplate = baseplate.NewTestBaseplate(baseplate.NewTestBaseplateArgs{
+		Config:          cfg.Baseplate,
+		Store:           store,
+		EdgeContextImpl: cfg.EdgeContextImpl,
+	})
+	tb.Cleanup(func() {
+		h.Baseplate.Close()
+	})
+
+	if socket != nil {
+		h.startThrift(tb, cfg, socket)
+	}
+	if len(cfg.HTTPEndpoints) > 0 {
+		h.startHTTP(tb, cfg)
+	}
+	if cfg.GRPCRegister != nil {
+		h.startGRPC(tb, cfg)
+	}
+	return h
+}
+
+func (h *Harness) startThrift(tb testing.TB, cfg Config, socket *thrift.TServerSocket) {
+	tb.Helper()
+
+	middlewares := thriftbp.BaseplateDefaultProcessorMiddlewares(
+		thriftbp.DefaultProcessorMiddlewaresArgs{
+			EdgeContextImpl:     cfg.EdgeContextImpl,
+			ErrorSpanSuppressor: cfg.ErrorSpanSuppressor,
+		},
+	)
+	srv, err := thriftbp.NewServer(thriftbp.ServerConfig{
+		Socket:      socket,
+		Logger:      thrift.NopLogger,
+		Processor:   cfg.ThriftProcessor,
+		Middlewares: append(middlewares, cfg.ThriftMiddlewares...),
+	})
+	if err != nil {
+		tb.Fatalf("baseplatetest: failed to create thrift server: %v", err)
+	}
+	server := thriftbp.ApplyBaseplate(h.Baseplate, srv)
+	go server.Serve()
+	tb.Cleanup(func() {
+		server.Close()
+	})
+
+	pool, err := thriftbp.NewBaseplateClientPool(thriftbp.ClientPoolConfig{
+		ServiceSlug:     cfg.ServiceSlug,
+		Addr:            socket.Addr().String(),
+		EdgeContextImpl: cfg.EdgeContextImpl,
+		MaxConnections:  10,
+		ConnectTimeout:  DefaultClientTimeout,
+		SocketTimeout:   DefaultClientTimeout,
+	})
+	if err != nil {
+		tb.Fatalf("baseplatetest: failed to create thrift client pool: %v", err)
+	}
+	h.ThriftClient = pool
+	tb.Cleanup(func() {
+		pool.Close()
+	})
+}
+
+func (h *Harness) startHTTP(tb testing.TB, cfg Config) {
+	tb.Helper()
+
+	server, ts, err := httpbp.NewTestBaseplateServer(httpbp.ServerArgs{
+		Baseplate:   h.Baseplate,
+		Endpoints:   cfg.HTTPEndpoints,
+		Middlewares: cfg.HTTPMiddlewares,
+	})
+	if err != nil {
+		tb.Fatalf("baseplatetest: failed to create HTTP server: %v", err)
+	}
+	h.HTTPURL = ts.URL
+	h.HTTPClient = ts.Client()
+	tb.Cleanup(func() {
+		server.Close()
+	})
+}
+
+func (h *Harness) startGRPC(tb testing.TB, cfg Config) {
+	tb.Helper()
+
+	interceptors := []grpc.UnaryServerInterceptor{
+		grpcbp.InjectServerSpanInterceptorUnary(),
+		grpcbp.InjectEdgeContextInterceptorUnary(cfg.EdgeContextImpl),
+	}
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(append(interceptors, cfg.GRPCInterceptors...)...))
+	cfg.GRPCRegister(server)
+
+	l := bufconn.Listen(grpcBufferSize)
+	go server.Serve(l)
+	tb.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithInsecure(),
+		grpc.WithUserAgent(cfg.ServiceSlug),
+		grpc.WithChainUnaryInterceptor(
+			grpcbp.MonitorInterceptorUnary(grpcbp.MonitorInterceptorArgs{
+				ServiceSlug: cfg.ServiceSlug,
+			}),
+			grpcbp.ForwardEdgeContextUnary(cfg.EdgeContextImpl),
+		),
+	)
+	if err != nil {
+		tb.Fatalf("baseplatetest: failed to dial gRPC server: %v", err)
+	}
+	h.GRPCConn = conn
+	tb.Cleanup(func() {
+		conn.Close()
+	})
+}
@@ -0,0 +1,138 @@
+package baseplatetest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	pb "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+
+	"github.com/reddit/baseplate.go/baseplatetest"
+	"github.com/reddit/baseplate.go/faults"
+	"github.com/reddit/baseplate.go/httpbp"
+	baseplatethrift "github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+type healthService struct{}
+
+func (healthService) IsHealthy(ctx context.Context, req *baseplatethrift.IsHealthyRequest) (bool, error) {
+	return true, nil
+}
+
+type pingService struct {
+	pb.UnimplementedTestServiceServer
+}
+
+func (pingService) Ping(ctx context.Context, req *pb.PingRequest) (*pb.PingResponse, error) {
+	return &pb.PingResponse{Value: req.Value}, nil
+}
+
+func TestHarness(t *testing.T) {
+	injector, err := faults.NewInjector(faults.Config{
+		Rules: []faults.Rule{
+			{
+				Name:       "teapot",
+				Methods:    []string{"teapot"},
+				Percentage: 100,
+				Codes:      map[faults.Protocol]int{faults.ProtocolHTTP: http.StatusTeapot},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}
+
+	h := baseplatetest.New(t, baseplatetest.Config{
+		ThriftProcessor: baseplatethrift.NewBaseplateServiceV2Processor(healthService{}),
+		HTTPEndpoints: map[httpbp.Pattern]httpbp.Endpoint{
+			"/hello": {
+				Name:    "hello",
+				Methods: []string{http.MethodGet},
+				Handle:  handle,
+			},
+			"/teapot": {
+				Name:    "teapot",
+				Methods: []string{http.MethodGet},
+				Handle:  handle,
+			},
+		},
+		HTTPMiddlewares: []httpbp.Middleware{httpbp.InjectFaults(injector)},
+		GRPCRegister: func(s *grpc.Server) {
+			pb.RegisterTestServiceServer(s, &pingService{})
+		},
+	})
+	ctx := context.Background()
+
+	t.Run("thrift", func(t *testing.T) {
+		client := baseplatethrift.NewBaseplateServiceV2Client(h.ThriftClient.TClient())
+		healthy, err := client.IsHealthy(ctx, &baseplatethrift.IsHealthyRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !healthy {
+			t.Error("Expected healthy")
+		}
+		h.WaitForSpan(t, "is_healthy")
+	})
+
+	t.Run("http", func(t *testing.T) {
+		resp, err := h.HTTPClient.Get(h.HTTPURL + "/hello")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		span := h.WaitForSpan(t, "hello")
+		if v, ok := baseplatetest.SpanTag(span, tracing.ZipkinBinaryAnnotationKeyError); ok {
+			t.Errorf("Expected no error tag, got %v", v)
+		}
+	})
+
+	t.Run("http-middleware", func(t *testing.T) {
+		resp, err := h.HTTPClient.Get(h.HTTPURL + "/teapot")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusTeapot {
+			t.Errorf("Expected status %d, got %d", http.StatusTeapot, resp.StatusCode)
+		}
+		span := h.WaitForSpan(t, "teapot")
+		if _, ok := baseplatetest.SpanTag(span, tracing.ZipkinBinaryAnnotationKeyError); !ok {
+			t.Error("Expected error tag")
+		}
+		if got := h.PrometheusValue(t, "faults_injected_total", prometheus.Labels{
+			faults.PrometheusProtocolLabel: string(faults.ProtocolHTTP),
+			faults.PrometheusRuleLabel:     "teapot",
+		}); got != 1 {
+			t.Errorf("Expected 1 fault injected, got %v", got)
+		}
+	})
+
+	t.Run("grpc", func(t *testing.T) {
+		client := pb.NewTestServiceClient(h.GRPCConn)
+		resp, err := client.Ping(ctx, &pb.PingRequest{Value: "foo"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Value != "foo" {
+			t.Errorf("Expected %q, got %q", "foo", resp.Value)
+		}
+		h.WaitForSpan(t, "Ping")
+		h.WaitForSpan(t, baseplatetest.DefaultServiceSlug+".Ping")
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		if got, want := h.StatsdCounter(t, "baseplate.server.rate"), 4.0; got != want {
+			t.Errorf("Expected %v server spans counted, got %v", want, got)
+		}
+	})
+}
@@ -0,0 +1,228 @@
+package baseplatetest
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/mqsend"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// spanRecorder is the mqsend.MessageQueue used by the tracer to record the
+// spans in memory.
+type spanRecorder struct {
+	lock  sync.Mutex
+	spans []tracing.ZipkinSpan
+	err   error
+}
+
+var _ mqsend.MessageQueue = (*spanRecorder)(nil)
+
+func newSpanRecorder() *spanRecorder {
+	return new(spanRecorder)
+}
+
+func (r *spanRecorder) Send(_ context.Context, data []byte) error {
+	var span tracing.ZipkinSpan
+	err := json.Unmarshal(data, &span)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err != nil {
+		r.err = err
+		return err
+	}
+	r.spans = append(r.spans, span)
+	return nil
+}
+
+func (r *spanRecorder) Close() error {
+	return nil
+}
+
+func (r *spanRecorder) snapshot() ([]tracing.ZipkinSpan, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]tracing.ZipkinSpan(nil), r.spans...), r.err
+}
+
+// Spans returns all the spans recorded so far, in the order they finished.
+//
+// Please note that the server spans are finished after the responses are sent
+// to the clients,
+// so use WaitForSpan instead to avoid flaky tests.
+func (h *Harness) Spans() []tracing.ZipkinSpan {
+	spans, _ := h.spans.snapshot()
+	return spans
+}
+
+// WaitForSpan waits for the span with the name to be recorded and returns it.
+//
+// It calls tb.Fatal if no such span is recorded within DefaultWaitTimeout.
+func (h *Harness) WaitForSpan(tb testing.TB, name string) tracing.ZipkinSpan {
+	tb.Helper()
+
+	deadline := time.Now().Add(DefaultWaitTimeout)
+	for {
+		spans, err := h.spans.snapshot()
+		if err != nil {
+			tb.Fatalf("baseplatetest: failed to decode span: %v", err)
+		}
+		for _, span := range spans {
+			if span.Name == name {
+				return span
+			}
+		}
+		if time.Now().After(deadline) {
+			names := make([]string, len(spans))
+			for i, span := range spans {
+				names[i] = span.Name
+			}
+			tb.Fatalf("baseplatetest: span %q not recorded in %v, got %q", name, DefaultWaitTimeout, names)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// SpanTag returns the value of the binary annotation (tag) key of span.
+func SpanTag(span tracing.ZipkinSpan, key string) (interface{}, bool) {
+	for _, annotation := range span.BinaryAnnotations {
+		if annotation.Key == key {
+			return annotation.Value, true
+		}
+	}
+	return nil, false
+}
+
+// metricsRecorder records the statsd metrics in memory,
+// and the values of the prometheus metrics when the Harness is created.
+type metricsRecorder struct {
+	statsd *metricsbp.Statsd
+
+	lock     sync.Mutex
+	counters map[string]float64
+
+	baseline map[string]float64
+}
+
+func newMetricsRecorder(tb testing.TB) *metricsRecorder {
+	tb.Helper()
+	baseline, err := gatherPrometheus()
+	if err != nil {
+		tb.Fatalf("baseplatetest: failed to gather prometheus metrics: %v", err)
+	}
+	// Without Endpoint the statsd metrics are only buffered in memory.
+	return &metricsRecorder{
+		statsd:   metricsbp.NewStatsd(context.Background(), metricsbp.Config{}),
+		counters: make(map[string]float64),
+		baseline: baseline,
+	}
+}
+
+// StatsdCounter returns the sum of the statsd counter with the name reported
+// so far, of all the tag combinations.
+func (h *Harness) StatsdCounter(tb testing.TB, name string) float64 {
+	tb.Helper()
+
+	r := h.metrics
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// WriteTo flushes (and resets) the buffered counters,
+	// so they are accumulated into r.counters.
+	var sb strings.Builder
+	if _, err := r.statsd.WriteTo(&sb); err != nil {
+		tb.Fatalf("baseplatetest: failed to flush statsd metrics: %v", err)
+	}
+	for _, line := range strings.Split(sb.String(), "\n") {
+		// The lines are in the format of "name[,tags]:value|c".
+		if !strings.HasSuffix(line, "|c") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(line[i+1:len(line)-len("|c")], 64)
+		if err != nil {
+			tb.Fatalf("baseplatetest: failed to parse statsd line %q: %v", line, err)
+		}
+		key := line[:i]
+		if j := strings.IndexByte(key, ','); j >= 0 {
+			key = key[:j]
+		}
+		r.counters[key] += value
+	}
+	return r.counters[name]
+}
+
+// PrometheusValue returns the change of the value of the prometheus metric
+// with the name and labels since the Harness was created.
+//
+// For counters and gauges it's the value of the metric,
+// for histograms and summaries it's the number of the observations.
+// The metrics must be registered to prometheus.DefaultRegisterer
+// (e.g. via promauto).
+func (h *Harness) PrometheusValue(tb testing.TB, name string, labels prometheus.Labels) float64 {
+	tb.Helper()
+
+	values, err := gatherPrometheus()
+	if err != nil {
+		tb.Fatalf("baseplatetest: failed to gather prometheus metrics: %v", err)
+	}
+	key := prometheusKey(name, labels)
+	return values[key] - h.metrics.baseline[key]
+}
+
+func gatherPrometheus() (map[string]float64, error) {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := make(prometheus.Labels, len(metric.GetLabel()))
+			for _, pair := range metric.GetLabel() {
+				labels[pair.GetName()] = pair.GetValue()
+			}
+			values[prometheusKey(family.GetName(), labels)] = prometheusValue(metric)
+		}
+	}
+	return values, nil
+}
+
+func prometheusValue(metric *dto.Metric) float64 {
+	switch {
+	case metric.Counter != nil:
+		return metric.Counter.GetValue()
+	case metric.Gauge != nil:
+		return metric.Gauge.GetValue()
+	case metric.Histogram != nil:
+		return float64(metric.Histogram.GetSampleCount())
+	case metric.Summary != nil:
+		return float64(metric.Summary.GetSampleCount())
+	case metric.Untyped != nil:
+		return metric.Untyped.GetValue()
+	}
+	return 0
+}
+
+func prometheusKey(name string, labels prometheus.Labels) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+strconv.Quote(v))
+	}
+	sort.Strings(pairs)
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
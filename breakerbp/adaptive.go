@@ -0,0 +1,228 @@
+package breakerbp
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Default values of AdaptiveLimiterConfig.
+const (
+	DefaultInitialLimit = 20
+	DefaultMinLimit     = 1
+	DefaultMaxLimit     = 1000
+	DefaultTolerance    = 1.5
+	DefaultSmoothing    = 0.2
+	DefaultShortWindow  = 10
+	DefaultLongWindow   = 600
+)
+
+// ErrConcurrencyLimitExceeded is returned by AdaptiveLimiter.Execute when the
+// number of the in-flight requests already reached the limit.
+var ErrConcurrencyLimitExceeded = errors.New("breakerbp: concurrency limit exceeded")
+
+// AdaptiveLimiterConfig is the config of an AdaptiveLimiter.
+//
+// Can be deserialized from YAML.
+type AdaptiveLimiterConfig struct {
+	// Name of the limiter, used as the label of the metrics.
+	Name string `yaml:"name"`
+
+	// Optional. The limit before any latency is observed.
+	// Defaults to DefaultInitialLimit.
+	InitialLimit int `yaml:"initialLimit"`
+
+	// Optional. The bounds of the limit.
+	// Default to DefaultMinLimit and DefaultMaxLimit.
+	MinLimit int `yaml:"minLimit"`
+	MaxLimit int `yaml:"maxLimit"`
+
+	// Optional. How much the recent latency can exceed the long term latency
+	// before the limit starts to decrease, e.g. 1.5 means 50% higher.
+	// Defaults to DefaultTolerance.
+	Tolerance float64 `yaml:"tolerance"`
+
+	// Optional. In (0, 1], how fast the limit moves towards the newly
+	// calculated one. Defaults to DefaultSmoothing.
+	Smoothing float64 `yaml:"smoothing"`
+
+	// Optional. The number of the samples the recent and the long term
+	// latencies are averaged over.
+	// Default to DefaultShortWindow and DefaultLongWindow.
+	ShortWindow int `yaml:"shortWindow"`
+	LongWindow  int `yaml:"longWindow"`
+}
+
+// AdaptiveLimiter is a CircuitBreaker limiting the concurrent in-flight
+// requests to a downstream,
+// with the limit adjusted based on the observed latency.
+//
+// It implements the gradient algorithm:
+// The latency of the requests is tracked by a recent and a long term moving
+// average,
+// the limit grows (by the square root of itself) while the recent latency stays
+// within Tolerance of the long term one,
+// and shrinks proportionally when the recent latency grows beyond that,
+// which is the sign of requests queuing up in the downstream.
+//
+// The requests exceeding the limit are rejected immediately with
+// ErrConcurrencyLimitExceeded,
+// instead of adding to the load of an already overloaded downstream.
+//
+// Use a separate AdaptiveLimiter for each downstream.
+type AdaptiveLimiter struct {
+	cfg AdaptiveLimiterConfig
+	now func() time.Time
+
+	lock     sync.Mutex
+	limit    float64
+	inFlight int
+	shortRTT float64
+	longRTT  float64
+
+	limitGauge      prometheus.Gauge
+	inFlightGauge   prometheus.Gauge
+	rejectedCounter prometheus.Counter
+}
+
+// NewAdaptiveLimiter creates an AdaptiveLimiter.
+func NewAdaptiveLimiter(cfg AdaptiveLimiterConfig) *AdaptiveLimiter {
+	if cfg.InitialLimit <= 0 {
+		cfg.InitialLimit = DefaultInitialLimit
+	}
+	if cfg.MinLimit <= 0 {
+		cfg.MinLimit = DefaultMinLimit
+	}
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = DefaultMaxLimit
+	}
+	if cfg.Tolerance <= 0 {
+		cfg.Tolerance = DefaultTolerance
+	}
+	if cfg.Smoothing <= 0 || cfg.Smoothing > 1 {
+		cfg.Smoothing = DefaultSmoothing
+	}
+	if cfg.ShortWindow <= 0 {
+		cfg.ShortWindow = DefaultShortWindow
+	}
+	if cfg.LongWindow <= 0 {
+		cfg.LongWindow = DefaultLongWindow
+	}
+	l := &AdaptiveLimiter{
+		cfg:             cfg,
+		now:             time.Now,
+		limitGauge:      concurrencyLimitGauge.WithLabelValues(cfg.Name),
+		inFlightGauge:   concurrencyInFlightGauge.WithLabelValues(cfg.Name),
+		rejectedCounter: concurrencyRejectedCounter.WithLabelValues(cfg.Name),
+	}
+	l.setLimit(float64(cfg.InitialLimit))
+	return l
+}
+
+// Limit returns the current limit.
+func (l *AdaptiveLimiter) Limit() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return int(l.limit)
+}
+
+// Execute calls fn if the number of the in-flight requests is below the limit,
+// and updates the limit with the latency of fn.
+//
+// Otherwise it returns ErrConcurrencyLimitExceeded without calling fn.
+func (l *AdaptiveLimiter) Execute(fn func() (interface{}, error)) (interface{}, error) {
+	if !l.acquire() {
+		l.rejectedCounter.Inc()
+		return nil, ErrConcurrencyLimitExceeded
+	}
+	start := l.now()
+	defer func() {
+		l.release(l.now().Sub(start))
+	}()
+	return fn()
+}
+
+// ThriftMiddleware is a thrift.ClientMiddleware that handles concurrency
+// limiting.
+func (l *AdaptiveLimiter) ThriftMiddleware(next thrift.TClient) thrift.TClient {
+	return thrift.WrappedTClient{
+		Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
+			m, err := l.Execute(func() (interface{}, error) {
+				return next.Call(ctx, method, args, result)
+			})
+			meta, _ := m.(thrift.ResponseMeta)
+			return meta, err
+		},
+	}
+}
+
+func (l *AdaptiveLimiter) acquire() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.inFlight >= int(l.limit) {
+		return false
+	}
+	l.inFlight++
+	l.inFlightGauge.Set(float64(l.inFlight))
+	return true
+}
+
+func (l *AdaptiveLimiter) release(rtt time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	inFlight := l.inFlight
+	l.inFlight--
+	l.inFlightGauge.Set(float64(l.inFlight))
+	l.sample(rtt.Seconds(), inFlight)
+}
+
+// sample updates the limit with the latency of a request,
+// finished when there were inFlight requests in flight.
+//
+// It must be called with the lock held.
+func (l *AdaptiveLimiter) sample(rtt float64, inFlight int) {
+	if rtt <= 0 {
+		return
+	}
+	if l.longRTT == 0 {
+		l.shortRTT = rtt
+		l.longRTT = rtt
+		return
+	}
+	l.shortRTT = ema(l.shortRTT, rtt, l.cfg.ShortWindow)
+	l.longRTT = ema(l.longRTT, rtt, l.cfg.LongWindow)
+	if l.longRTT > 2*l.shortRTT {
+		// The downstream recovered from a long period of high latency,
+		// let the long term latency catch up faster.
+		l.longRTT *= 0.95
+	}
+	if float64(inFlight) < l.limit/2 {
+		// The limit isn't what's limiting the requests,
+		// the latency says nothing about whether it's too high or too low.
+		return
+	}
+
+	gradient := math.Max(0.5, math.Min(1, l.cfg.Tolerance*l.longRTT/l.shortRTT))
+	newLimit := l.limit*gradient + math.Sqrt(l.limit)
+	l.setLimit(l.limit*(1-l.cfg.Smoothing) + newLimit*l.cfg.Smoothing)
+}
+
+// setLimit must be called with the lock held.
+func (l *AdaptiveLimiter) setLimit(limit float64) {
+	limit = math.Max(float64(l.cfg.MinLimit), math.Min(float64(l.cfg.MaxLimit), limit))
+	l.limit = limit
+	l.limitGauge.Set(math.Floor(limit))
+}
+
+// ema returns the exponential moving average over window samples.
+func ema(avg, sample float64, window int) float64 {
+	factor := 2 / float64(window+1)
+	return avg*(1-factor) + sample*factor
+}
+
+var _ CircuitBreaker = (*AdaptiveLimiter)(nil)
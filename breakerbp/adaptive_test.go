@@ -0,0 +1,111 @@
+package breakerbp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLimiterExecute(t *testing.T) {
+	l := NewAdaptiveLimiter(AdaptiveLimiterConfig{
+		Name:         "test-execute",
+		InitialLimit: 1,
+	})
+
+	var innerErr error
+	result, err := l.Execute(func() (interface{}, error) {
+		_, innerErr = l.Execute(func() (interface{}, error) {
+			t.Error("Expected the request exceeding the limit not to be executed")
+			return nil, nil
+		})
+		return "result", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "result" {
+		t.Errorf("Expected result %q, got %v", "result", result)
+	}
+	if !errors.Is(innerErr, ErrConcurrencyLimitExceeded) {
+		t.Errorf("Expected ErrConcurrencyLimitExceeded, got %v", innerErr)
+	}
+
+	// The slot is released after the request finished, even on error.
+	fnErr := errors.New("failed")
+	if _, err := l.Execute(func() (interface{}, error) {
+		return nil, fnErr
+	}); !errors.Is(err, fnErr) {
+		t.Errorf("Expected %v, got %v", fnErr, err)
+	}
+	if _, err := l.Execute(func() (interface{}, error) {
+		return nil, nil
+	}); err != nil {
+		t.Errorf("Expected no error after the requests finished, got %v", err)
+	}
+}
+
+func TestAdaptiveLimiterGradient(t *testing.T) {
+	const rtt = time.Millisecond * 10
+
+	newLimiter := func() *AdaptiveLimiter {
+		l := NewAdaptiveLimiter(AdaptiveLimiterConfig{
+			Name:         "test-gradient",
+			InitialLimit: 20,
+			MaxLimit:     100,
+			MinLimit:     5,
+		})
+		// Establish the baseline latency.
+		l.sample(rtt.Seconds(), 20)
+		return l
+	}
+
+	t.Run("grows-when-saturated", func(t *testing.T) {
+		l := newLimiter()
+		for i := 0; i < 1000; i++ {
+			l.sample(rtt.Seconds(), l.Limit())
+		}
+		if limit := l.Limit(); limit != 100 {
+			t.Errorf("Expected limit to grow to MaxLimit 100, got %d", limit)
+		}
+	})
+
+	t.Run("unchanged-when-not-saturated", func(t *testing.T) {
+		l := newLimiter()
+		for i := 0; i < 100; i++ {
+			l.sample(rtt.Seconds(), 1)
+		}
+		if limit := l.Limit(); limit != 20 {
+			t.Errorf("Expected limit to stay 20, got %d", limit)
+		}
+	})
+
+	t.Run("shrinks-on-high-latency", func(t *testing.T) {
+		l := newLimiter()
+		for i := 0; i < 100; i++ {
+			l.sample(rtt.Seconds(), l.Limit())
+		}
+		before := l.Limit()
+		for i := 0; i < 10; i++ {
+			l.sample((rtt * 5).Seconds(), l.Limit())
+		}
+		if limit := l.Limit(); limit >= before {
+			t.Errorf("Expected limit to shrink from %d, got %d", before, limit)
+		}
+		for i := 0; i < 100; i++ {
+			l.sample((rtt * 5).Seconds(), l.Limit())
+		}
+		if limit := l.Limit(); limit > before/4 {
+			t.Errorf("Expected limit to shrink to at most %d, got %d", before/4, limit)
+		}
+	})
+
+	t.Run("tolerated-latency", func(t *testing.T) {
+		l := newLimiter()
+		for i := 0; i < 100; i++ {
+			l.sample((rtt * 5 / 4).Seconds(), l.Limit())
+		}
+		if limit := l.Limit(); limit <= 20 {
+			t.Errorf("Expected limit to grow within the tolerance, got %d", limit)
+		}
+	})
+}
@@ -1,3 +1,4 @@
 // Package breakerbp integrates with https://github.com/sony/gobreaker and
-// provides a thrift compatible circuit breaker implementation.
+// provides a thrift compatible circuit breaker implementation,
+// and an adaptive concurrency limiter.
 package breakerbp
@@ -2,6 +2,7 @@ package breakerbp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -21,6 +22,8 @@ type FailureRatioBreaker struct {
 	minRequestsToTrip int
 	failureThreshold  float64
 	logger            log.Wrapper
+	onStateChange     func(name string, from, to gobreaker.State)
+	metrics           breakerMetrics
 }
 
 // Config represents the configuration for a FailureRatioBreaker.
@@ -52,17 +55,32 @@ type Config struct {
 
 	// Timeout is the duration of the 'Open' state. After an 'Open' timeout duration has passed, the breaker enters 'half-open' state.
 	Timeout time.Duration `yaml:"timeout"`
+
+	// OnStateChange is called after the breaker changes states (after Logger),
+	// e.g. to alert when the breaker trips.
+	//
+	// It's called synchronously with the breaker locked,
+	// so it must not block or call the breaker.
+	OnStateChange func(name string, from, to gobreaker.State) `yaml:"-"`
 }
 
 // NewFailureRatioBreaker creates a new FailureRatioBreaker with the provided configuration. Creates a new goroutine to emit
 // breaker state metrics if EmitStatusMetrics is set to true. This goroutine is stopped when metricsbp.M.Ctx() is done().
+//
+// The state, trips and rejections of the breaker are also reported as
+// Prometheus metrics labeled by the name of the breaker.
 func NewFailureRatioBreaker(config Config) FailureRatioBreaker {
+	return newFailureRatioBreaker(config, newBreakerMetrics(config.Name, true))
+}
 
+func newFailureRatioBreaker(config Config, metrics breakerMetrics) FailureRatioBreaker {
 	failureBreaker := FailureRatioBreaker{
 		name:              config.Name,
 		minRequestsToTrip: config.MinRequestsToTrip,
 		failureThreshold:  config.FailureThreshold,
 		logger:            config.Logger,
+		onStateChange:     config.OnStateChange,
+		metrics:           metrics,
 	}
 	settings := gobreaker.Settings{
 		Name:          config.Name,
@@ -102,7 +120,11 @@ func (cb FailureRatioBreaker) runStatsProducer() {
 // Execute wraps the given function call in circuit breaker logic and returns
 // the result.
 func (cb FailureRatioBreaker) Execute(fn func() (interface{}, error)) (interface{}, error) {
-	return cb.goBreaker.Execute(fn)
+	result, err := cb.goBreaker.Execute(fn)
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		cb.metrics.rejected.Inc()
+	}
+	return result, err
 }
 
 // State returns the current state of the breaker.
@@ -139,6 +161,10 @@ func (cb FailureRatioBreaker) shouldTrip(counts gobreaker.Counts) bool {
 func (cb FailureRatioBreaker) stateChanged(name string, from gobreaker.State, to gobreaker.State) {
 	message := fmt.Sprintf("circuit breaker %v state changed from %v to %v", name, from, to)
 	cb.logger.Log(context.Background(), message)
+	cb.metrics.stateChanged(to)
+	if cb.onStateChange != nil {
+		cb.onStateChange(name, from, to)
+	}
 }
 
 var (
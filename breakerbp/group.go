@@ -0,0 +1,182 @@
+package breakerbp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/sony/gobreaker"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+)
+
+// DefaultIdleTTL is the default value of GroupConfig.IdleTTL.
+const DefaultIdleTTL = time.Minute * 10
+
+// GroupConfig is the config of a BreakerGroup.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//	name: downstream
+//	minRequestsToTrip: 10
+//	failureThreshold: 0.5
+//	timeout: 10s
+//	idleTTL: 30m
+type GroupConfig struct {
+	// The config shared by all the breakers in the group.
+	//
+	// EmitStatusMetrics is ignored,
+	// the group reports the aggregated metrics instead.
+	Config `yaml:",inline"`
+
+	// Optional. The breakers not used for IdleTTL are removed from the group,
+	// which resets their state.
+	// Defaults to DefaultIdleTTL.
+	IdleTTL time.Duration `yaml:"idleTTL"`
+}
+
+// BreakerGroup fans a single logical breaker config out to independent
+// FailureRatioBreakers keyed by destination host and/or method,
+// so that a single misbehaving host or method doesn't trip the breaker for
+// all of them.
+//
+// The breakers are created lazily on first use,
+// and removed after being idle for IdleTTL.
+//
+// The number of the breakers in each state is reported via the
+// breakerbp_group_breakers Prometheus gauge,
+// and the trips and rejections of all the breakers are aggregated,
+// labeled by the name of the group.
+type BreakerGroup struct {
+	cfg GroupConfig
+	now func() time.Time
+
+	lock       sync.Mutex
+	breakers   map[string]*groupEntry
+	lastSweep  time.Time
+	lastReport time.Time
+}
+
+type groupEntry struct {
+	breaker  FailureRatioBreaker
+	lastUsed time.Time
+}
+
+// NewBreakerGroup creates a BreakerGroup.
+func NewBreakerGroup(cfg GroupConfig) *BreakerGroup {
+	if cfg.IdleTTL <= 0 {
+		cfg.IdleTTL = DefaultIdleTTL
+	}
+	cfg.EmitStatusMetrics = false
+	g := &BreakerGroup{
+		cfg:      cfg,
+		now:      time.Now,
+		breakers: make(map[string]*groupEntry),
+	}
+	g.lastSweep = g.now()
+	g.lastReport = g.lastSweep
+	return g
+}
+
+// Breaker returns the breaker of key, creating it if needed.
+//
+// The breakers are named "<group name>:<key>" in the logs.
+func (g *BreakerGroup) Breaker(key string) FailureRatioBreaker {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	now := g.now()
+	if now.Sub(g.lastSweep) >= g.cfg.IdleTTL/2 {
+		g.sweep(now)
+	}
+	entry := g.breakers[key]
+	if entry == nil {
+		cfg := g.cfg.Config
+		cfg.Name = g.cfg.Name + ":" + key
+		entry = &groupEntry{
+			// The trips and rejections of the breakers are aggregated by the name of
+			// the group.
+			breaker: newFailureRatioBreaker(cfg, newBreakerMetrics(g.cfg.Name, false)),
+		}
+		g.breakers[key] = entry
+		g.reportStates(now)
+	} else if now.Sub(g.lastReport) >= metricsbp.SysStatsTickerInterval {
+		g.reportStates(now)
+	}
+	entry.lastUsed = now
+	return entry.breaker
+}
+
+// Execute wraps the given function call in the circuit breaker logic of the
+// breaker of key, and returns the result.
+func (g *BreakerGroup) Execute(key string, fn func() (interface{}, error)) (interface{}, error) {
+	return g.Breaker(key).Execute(fn)
+}
+
+// Len returns the number of the breakers in the group.
+func (g *BreakerGroup) Len() int {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return len(g.breakers)
+}
+
+// ThriftMiddleware returns a thrift.ClientMiddleware that handles circuit
+// breaking with the breaker of the key returned by key for each call.
+//
+// To key the breakers by method, use MethodKey.
+// To key them by host as well, use a separate BreakerGroup for each host
+// (or key function prefixing the host of the client).
+func (g *BreakerGroup) ThriftMiddleware(key func(ctx context.Context, method string) string) thrift.ClientMiddleware {
+	return func(next thrift.TClient) thrift.TClient {
+		return thrift.WrappedTClient{
+			Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
+				m, err := g.Execute(key(ctx, method), func() (interface{}, error) {
+					return next.Call(ctx, method, args, result)
+				})
+				meta, _ := m.(thrift.ResponseMeta)
+				return meta, err
+			},
+		}
+	}
+}
+
+// MethodKey is the key function for BreakerGroup.ThriftMiddleware that keys
+// the breakers by the thrift method.
+func MethodKey(_ context.Context, method string) string {
+	return method
+}
+
+// sweep removes the idle breakers.
+//
+// It must be called with the lock held.
+func (g *BreakerGroup) sweep(now time.Time) {
+	g.lastSweep = now
+	for key, entry := range g.breakers {
+		if now.Sub(entry.lastUsed) >= g.cfg.IdleTTL {
+			delete(g.breakers, key)
+			groupEvictedCounter.WithLabelValues(g.cfg.Name).Inc()
+		}
+	}
+	g.reportStates(now)
+}
+
+// reportStates reports the number of the breakers in each state.
+//
+// As the states are only counted when the breakers are accessed,
+// the reported numbers can lag behind by up to
+// metricsbp.SysStatsTickerInterval.
+//
+// It must be called with the lock held.
+func (g *BreakerGroup) reportStates(now time.Time) {
+	g.lastReport = now
+	counts := make(map[gobreaker.State]int, len(breakerStates))
+	for _, entry := range g.breakers {
+		counts[entry.breaker.State()]++
+	}
+	for state, label := range breakerStates {
+		groupBreakersGauge.WithLabelValues(g.cfg.Name, label).Set(float64(counts[state]))
+	}
+}
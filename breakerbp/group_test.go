@@ -0,0 +1,66 @@
+package breakerbp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sony/gobreaker"
+)
+
+func TestBreakerGroup(t *testing.T) {
+	const name = "test-group"
+	g := NewBreakerGroup(GroupConfig{
+		Config: Config{
+			Name:              name,
+			MinRequestsToTrip: 2,
+			FailureThreshold:  1,
+			Timeout:           time.Hour,
+		},
+		IdleTTL: time.Minute,
+	})
+	now := time.Now()
+	g.now = func() time.Time {
+		return now
+	}
+
+	fail := func() (interface{}, error) {
+		return nil, errors.New("failed")
+	}
+	for i := 0; i < 2; i++ {
+		g.Execute("a", fail)
+	}
+	if _, err := g.Execute("a", fail); !errors.Is(err, gobreaker.ErrOpenState) {
+		t.Errorf("Expected breaker a to be open, got %v", err)
+	}
+	if _, err := g.Execute("b", fail); errors.Is(err, gobreaker.ErrOpenState) {
+		t.Errorf("Expected breaker b not to be affected by a, got %v", err)
+	}
+	if n := g.Len(); n != 2 {
+		t.Errorf("Expected 2 breakers, got %d", n)
+	}
+
+	// Force the report.
+	now = now.Add(time.Second * 11)
+	g.Breaker("b")
+	for state, expected := range map[string]float64{
+		"closed":    1,
+		"open":      1,
+		"half_open": 0,
+	} {
+		if actual := testutil.ToFloat64(groupBreakersGauge.WithLabelValues(name, state)); actual != expected {
+			t.Errorf("Expected %v breakers in state %q, got %v", expected, state, actual)
+		}
+	}
+
+	// a becomes idle and is removed, which resets its state.
+	now = now.Add(time.Second * 50)
+	g.Breaker("b")
+	if n := g.Len(); n != 1 {
+		t.Errorf("Expected idle breaker to be removed, got %d breakers", n)
+	}
+	if _, err := g.Execute("a", fail); errors.Is(err, gobreaker.ErrOpenState) {
+		t.Errorf("Expected recreated breaker a to be closed, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package breakerbp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/sony/gobreaker"
+
+	"github.com/reddit/baseplate.go/breakerbp"
+	"github.com/reddit/baseplate.go/thriftbp/thrifttest"
+)
+
+func TestBreakerGroupThriftMiddleware(t *testing.T) {
+	g := breakerbp.NewBreakerGroup(breakerbp.GroupConfig{
+		Config: breakerbp.Config{
+			Name:              "test-group-thrift",
+			MinRequestsToTrip: 1,
+			FailureThreshold:  1,
+			Timeout:           time.Hour,
+		},
+	})
+	mock := &thrifttest.MockClient{}
+	mock.AddMockCall("fail", func(_ context.Context, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
+		return thrift.ResponseMeta{}, errors.New("backend down")
+	})
+	mock.AddNopMockCalls("ok")
+	client := thrift.WrapClient(mock, g.ThriftMiddleware(breakerbp.MethodKey))
+
+	ctx := context.Background()
+	client.Call(ctx, "fail", nil, nil)
+	if _, err := client.Call(ctx, "fail", nil, nil); !errors.Is(err, gobreaker.ErrOpenState) {
+		t.Errorf("Expected the breaker of fail to be open, got %v", err)
+	}
+	if _, err := client.Call(ctx, "ok", nil, nil); err != nil {
+		t.Errorf("Expected the breaker of ok not to be affected, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package breakerbp
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sony/gobreaker"
+)
+
+// Label names of the Prometheus metrics reported by breakerbp.
+const (
+	PrometheusBreakerNameLabel  = "breaker_name"
+	PrometheusBreakerStateLabel = "breaker_state"
+)
+
+// breakerStates are the values of PrometheusBreakerStateLabel.
+var breakerStates = map[gobreaker.State]string{
+	gobreaker.StateClosed:   "closed",
+	gobreaker.StateHalfOpen: "half_open",
+	gobreaker.StateOpen:     "open",
+}
+
+var (
+	stateGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "breakerbp_state",
+		Help: "1 for the current state of the circuit breakers, 0 for the other states",
+	}, []string{
+		PrometheusBreakerNameLabel,
+		PrometheusBreakerStateLabel,
+	})
+
+	tripsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "breakerbp_trips_total",
+		Help: "Number of the times the circuit breakers changed to the open state",
+	}, []string{
+		PrometheusBreakerNameLabel,
+	})
+
+	rejectedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "breakerbp_rejected_total",
+		Help: "Number of the requests rejected by the open or half-open circuit breakers",
+	}, []string{
+		PrometheusBreakerNameLabel,
+	})
+
+	concurrencyLimitGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "breakerbp_concurrency_limit",
+		Help: "The current limit of the in-flight requests of the adaptive concurrency limiters",
+	}, []string{
+		PrometheusBreakerNameLabel,
+	})
+
+	concurrencyInFlightGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "breakerbp_concurrency_in_flight_requests",
+		Help: "The number of the in-flight requests of the adaptive concurrency limiters",
+	}, []string{
+		PrometheusBreakerNameLabel,
+	})
+
+	concurrencyRejectedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "breakerbp_concurrency_rejected_total",
+		Help: "Number of the requests rejected by the adaptive concurrency limiters",
+	}, []string{
+		PrometheusBreakerNameLabel,
+	})
+
+	groupBreakersGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "breakerbp_group_breakers",
+		Help: "Number of the breakers in each state in the breaker groups",
+	}, []string{
+		PrometheusBreakerNameLabel,
+		PrometheusBreakerStateLabel,
+	})
+
+	groupEvictedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "breakerbp_group_breakers_evicted_total",
+		Help: "Number of the idle breakers removed from the breaker groups",
+	}, []string{
+		PrometheusBreakerNameLabel,
+	})
+)
+
+// breakerMetrics are the Prometheus metrics of a FailureRatioBreaker.
+type breakerMetrics struct {
+	name string
+	// Whether to report the state,
+	// which is reported by the BreakerGroup for the breakers in groups instead.
+	reportState bool
+
+	trips    prometheus.Counter
+	rejected prometheus.Counter
+}
+
+func newBreakerMetrics(name string, reportState bool) breakerMetrics {
+	m := breakerMetrics{
+		name:        name,
+		reportState: reportState,
+		trips:       tripsCounter.WithLabelValues(name),
+		rejected:    rejectedCounter.WithLabelValues(name),
+	}
+	m.setState(gobreaker.StateClosed)
+	return m
+}
+
+func (m breakerMetrics) stateChanged(to gobreaker.State) {
+	if to == gobreaker.StateOpen {
+		m.trips.Inc()
+	}
+	m.setState(to)
+}
+
+func (m breakerMetrics) setState(current gobreaker.State) {
+	if !m.reportState {
+		return
+	}
+	for state, label := range breakerStates {
+		var value float64
+		if state == current {
+			value = 1
+		}
+		stateGauge.WithLabelValues(m.name, label).Set(value)
+	}
+}
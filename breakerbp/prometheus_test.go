@@ -0,0 +1,87 @@
+package breakerbp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sony/gobreaker"
+)
+
+func TestFailureRatioBreakerMetrics(t *testing.T) {
+	const name = "test-metrics"
+	type change struct {
+		name     string
+		from, to gobreaker.State
+	}
+	var changes []change
+	cb := NewFailureRatioBreaker(Config{
+		Name:              name,
+		MinRequestsToTrip: 1,
+		FailureThreshold:  1,
+		Timeout:           time.Millisecond,
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			changes = append(changes, change{
+				name: name,
+				from: from,
+				to:   to,
+			})
+		},
+	})
+
+	checkState := func(t *testing.T, expected string) {
+		t.Helper()
+		for _, state := range breakerStates {
+			var value float64
+			if state == expected {
+				value = 1
+			}
+			if actual := testutil.ToFloat64(stateGauge.WithLabelValues(name, state)); actual != value {
+				t.Errorf("Expected state gauge of %q to be %v, got %v", state, value, actual)
+			}
+		}
+	}
+	checkState(t, "closed")
+
+	tripsBefore := testutil.ToFloat64(tripsCounter.WithLabelValues(name))
+	rejectedBefore := testutil.ToFloat64(rejectedCounter.WithLabelValues(name))
+
+	cb.Execute(func() (interface{}, error) {
+		return nil, errors.New("failed")
+	})
+	if _, err := cb.Execute(func() (interface{}, error) {
+		return nil, nil
+	}); !errors.Is(err, gobreaker.ErrOpenState) {
+		t.Fatalf("Expected breaker to be open, got %v", err)
+	}
+	checkState(t, "open")
+	if delta := testutil.ToFloat64(tripsCounter.WithLabelValues(name)) - tripsBefore; delta != 1 {
+		t.Errorf("Expected 1 trip, got %v", delta)
+	}
+	if delta := testutil.ToFloat64(rejectedCounter.WithLabelValues(name)) - rejectedBefore; delta != 1 {
+		t.Errorf("Expected 1 rejection, got %v", delta)
+	}
+
+	time.Sleep(time.Millisecond * 5)
+	if _, err := cb.Execute(func() (interface{}, error) {
+		return nil, nil
+	}); err != nil {
+		t.Fatalf("Expected the half-open breaker to allow the request, got %v", err)
+	}
+	checkState(t, "closed")
+
+	expected := []change{
+		{name: name, from: gobreaker.StateClosed, to: gobreaker.StateOpen},
+		{name: name, from: gobreaker.StateOpen, to: gobreaker.StateHalfOpen},
+		{name: name, from: gobreaker.StateHalfOpen, to: gobreaker.StateClosed},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected state changes %v, got %v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Expected state change #%d to be %v, got %v", i, expected[i], changes[i])
+		}
+	}
+}
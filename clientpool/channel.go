@@ -6,6 +6,9 @@ import (
 )
 
 type channelPool struct {
+	// accessed atomically, keep it first for 64-bit alignment.
+	exhaustions uint64
+
 	pool           chan Client
 	opener         ClientOpener
 	numActive      int32
@@ -13,8 +16,11 @@ type channelPool struct {
 	maxClients     int
 }
 
-// Make sure channelPool implements Pool interface.
-var _ Pool = (*channelPool)(nil)
+// Make sure channelPool implements Pool and StatsReporter interfaces.
+var (
+	_ Pool          = (*channelPool)(nil)
+	_ StatsReporter = (*channelPool)(nil)
+)
 
 // NewChannelPool creates a new client pool implemented via channel.
 func NewChannelPool(initialClients, maxClients int, opener ClientOpener) (Pool, error) {
@@ -64,6 +70,7 @@ func (cp *channelPool) Get() (client Client, err error) {
 	}
 
 	if cp.IsExhausted() {
+		atomic.AddUint64(&cp.exhaustions, 1)
 		err = ErrExhausted
 		return
 	}
@@ -127,3 +134,12 @@ func (cp *channelPool) NumAllocated() int32 {
 func (cp *channelPool) IsExhausted() bool {
 	return cp.NumActiveClients() >= int32(cp.maxClients)
 }
+
+// Stats implements StatsReporter.
+func (cp *channelPool) Stats() Stats {
+	return Stats{
+		Active:      int64(cp.NumActiveClients()),
+		Idle:        int64(cp.NumAllocated()),
+		Exhaustions: atomic.LoadUint64(&cp.exhaustions),
+	}
+}
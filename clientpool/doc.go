@@ -11,6 +11,10 @@
 //     PASS
 //     ok  	github.com/reddit/baseplate.go/clientpool	2.495s
 //
+// TypedPool is the generic implementation giving out clients of a concrete
+// type, with Get waiting for the clients to be released (prioritized by
+// WithPriority) when the pool is exhausted.
+//
 // This package is considered low level and should not be used directly in most
 // cases.
 // A thrift-specific wrapping is available in thriftbp package.
@@ -0,0 +1,207 @@
+package clientpool
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Names of the standardized Prometheus metrics of client pools.
+//
+// They are shared by all the client pools (thrift, http, redis, etc.) so that
+// the dashboards can be built across protocols without per-protocol metric name
+// mappings.
+const (
+	// Gauge: the number of clients/connections currently in use.
+	PrometheusActiveName = "clientpool_active_clients"
+
+	// Gauge: the number of clients/connections allocated but not in use.
+	PrometheusIdleName = "clientpool_idle_clients"
+
+	// Gauge: the number of callers currently waiting for a client/connection.
+	PrometheusWaitersName = "clientpool_waiters"
+
+	// Counter: the number of times a caller couldn't get a client/connection
+	// right away because the pool was exhausted.
+	PrometheusExhaustionsName = "clientpool_exhaustions_total"
+
+	// Counter: the number of times an idle client/connection was reused.
+	PrometheusHitsName = "clientpool_hits_total"
+
+	// Counter: the number of times a new client/connection was opened because
+	// there was no idle one.
+	PrometheusMissesName = "clientpool_misses_total"
+
+	// Counter: the number of stale (e.g. expired) idle clients/connections
+	// closed by the pool.
+	PrometheusStaleName = "clientpool_stale_closed_total"
+)
+
+// Label names of the standardized Prometheus metrics of client pools.
+const (
+	// The protocol of the pool, e.g. "thrift", "http", "redis".
+	PrometheusProtocolLabel = "protocol"
+
+	// The name of the pool, usually the slug of the service it connects to.
+	PrometheusPoolLabel = "pool"
+)
+
+// Stats are the standardized stats of a client pool.
+type Stats struct {
+	// The number of clients currently in use.
+	Active int64
+
+	// The number of clients allocated but not in use.
+	Idle int64
+
+	// The number of callers currently waiting for a client.
+	//
+	// Pools that never wait (e.g. the channel pool, which returns ErrExhausted
+	// immediately) always report 0.
+	Waiters int64
+
+	// The accumulated number of times a caller couldn't get a client right away
+	// because the pool was exhausted.
+	Exhaustions uint64
+
+	// The accumulated numbers of times an idle client was reused (Hits),
+	// or a new client was opened because there was no idle one (Misses),
+	// and of the stale idle clients closed by the pool (Stale).
+	//
+	// Pools that don't track them (e.g. the channel pool) always report 0.
+	Hits   uint64
+	Misses uint64
+	Stale  uint64
+}
+
+// StatsReporter is the optional interface a Pool can implement to report
+// Stats.
+//
+// The Pool returned by NewChannelPool implements it.
+type StatsReporter interface {
+	Stats() Stats
+}
+
+// PoolStats returns the Stats of pool.
+//
+// If pool doesn't implement StatsReporter,
+// the Stats are derived from NumActiveClients and NumAllocated,
+// with Waiters and Exhaustions being 0.
+func PoolStats(pool Pool) Stats {
+	if r, ok := pool.(StatsReporter); ok {
+		return r.Stats()
+	}
+	return Stats{
+		Active: int64(pool.NumActiveClients()),
+		Idle:   int64(pool.NumAllocated()),
+	}
+}
+
+// StatsCollector is a prometheus.Collector reporting the Stats of a pool with
+// the standardized metric names.
+type StatsCollector struct {
+	stats func() Stats
+
+	active      *prometheus.Desc
+	idle        *prometheus.Desc
+	waiters     *prometheus.Desc
+	exhaustions *prometheus.Desc
+	hits        *prometheus.Desc
+	misses      *prometheus.Desc
+	stale       *prometheus.Desc
+}
+
+// NewStatsCollector creates a StatsCollector.
+//
+// protocol and pool are reported as the labels of the metrics,
+// stats is called every time the metrics are collected.
+func NewStatsCollector(protocol, pool string, stats func() Stats) *StatsCollector {
+	labels := prometheus.Labels{
+		PrometheusProtocolLabel: protocol,
+		PrometheusPoolLabel:     pool,
+	}
+	return &StatsCollector{
+		stats: stats,
+
+		active: prometheus.NewDesc(
+			PrometheusActiveName,
+			"The number of clients currently in use.",
+			nil,
+			labels,
+		),
+		idle: prometheus.NewDesc(
+			PrometheusIdleName,
+			"The number of clients allocated but not in use.",
+			nil,
+			labels,
+		),
+		waiters: prometheus.NewDesc(
+			PrometheusWaitersName,
+			"The number of callers currently waiting for a client.",
+			nil,
+			labels,
+		),
+		exhaustions: prometheus.NewDesc(
+			PrometheusExhaustionsName,
+			"The number of times a caller couldn't get a client right away because the pool was exhausted.",
+			nil,
+			labels,
+		),
+		hits: prometheus.NewDesc(
+			PrometheusHitsName,
+			"The number of times an idle client was reused.",
+			nil,
+			labels,
+		),
+		misses: prometheus.NewDesc(
+			PrometheusMissesName,
+			"The number of times a new client was opened because there was no idle one.",
+			nil,
+			labels,
+		),
+		stale: prometheus.NewDesc(
+			PrometheusStaleName,
+			"The number of stale idle clients closed by the pool.",
+			nil,
+			labels,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *StatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.active
+	ch <- c.idle
+	ch <- c.waiters
+	ch <- c.exhaustions
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.stale
+}
+
+// Collect implements prometheus.Collector.
+func (c *StatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.active, prometheus.GaugeValue, float64(stats.Active))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waiters, prometheus.GaugeValue, float64(stats.Waiters))
+	ch <- prometheus.MustNewConstMetric(c.exhaustions, prometheus.CounterValue, float64(stats.Exhaustions))
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.stale, prometheus.CounterValue, float64(stats.Stale))
+}
+
+// RegisterStats creates a StatsCollector and registers it to
+// prometheus.DefaultRegisterer.
+//
+// It returns an error if there's already a StatsCollector registered with the
+// same protocol and pool.
+// The returned StatsCollector can be used to unregister it via
+// prometheus.Unregister when the pool is closed.
+func RegisterStats(protocol, pool string, stats func() Stats) (*StatsCollector, error) {
+	c := NewStatsCollector(protocol, pool, stats)
+	if err := prometheus.Register(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+var _ prometheus.Collector = (*StatsCollector)(nil)
@@ -0,0 +1,119 @@
+package clientpool_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/reddit/baseplate.go/clientpool"
+)
+
+func TestChannelPoolStats(t *testing.T) {
+	opener := func() (clientpool.Client, error) {
+		return &testClient{}, nil
+	}
+	const min, max = 1, 2
+	pool, err := clientpool.NewChannelPool(min, max, opener)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	c1, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Get(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Get(); !errors.Is(err, clientpool.ErrExhausted) {
+		t.Fatalf("Expected ErrExhausted, got %v", err)
+	}
+	if err := pool.Release(c1); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := clientpool.Stats{
+		Active:      1,
+		Idle:        1,
+		Exhaustions: 1,
+	}
+	if got := clientpool.PoolStats(pool); got != expected {
+		t.Errorf("Expected stats %+v, got %+v", expected, got)
+	}
+}
+
+func TestStatsCollector(t *testing.T) {
+	stats := clientpool.Stats{
+		Active:      1,
+		Idle:        2,
+		Waiters:     3,
+		Exhaustions: 4,
+		Hits:        5,
+		Misses:      6,
+		Stale:       7,
+	}
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(clientpool.NewStatsCollector("thrift", "foo", func() clientpool.Stats {
+		return stats
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register(clientpool.NewStatsCollector("thrift", "foo", func() clientpool.Stats {
+		return stats
+	})); err == nil {
+		t.Error("Expected error registering the same pool twice, got nil")
+	}
+	if err := registry.Register(clientpool.NewStatsCollector("http", "foo", func() clientpool.Stats {
+		return stats
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]float64{
+		clientpool.PrometheusActiveName:      1,
+		clientpool.PrometheusIdleName:        2,
+		clientpool.PrometheusWaitersName:     3,
+		clientpool.PrometheusExhaustionsName: 4,
+		clientpool.PrometheusHitsName:        5,
+		clientpool.PrometheusMissesName:      6,
+		clientpool.PrometheusStaleName:       7,
+	}
+	if len(families) != len(expected) {
+		t.Fatalf("Expected %d metric families, got %d", len(expected), len(families))
+	}
+	for _, family := range families {
+		value, ok := expected[family.GetName()]
+		if !ok {
+			t.Errorf("Unexpected metric %q", family.GetName())
+			continue
+		}
+		if len(family.GetMetric()) != 2 {
+			t.Errorf("Expected 2 pools for %q, got %d", family.GetName(), len(family.GetMetric()))
+		}
+		for _, m := range family.GetMetric() {
+			var got float64
+			switch {
+			case m.GetGauge() != nil:
+				got = m.GetGauge().GetValue()
+			case m.GetCounter() != nil:
+				got = m.GetCounter().GetValue()
+			}
+			if got != value {
+				t.Errorf("Expected %q to be %v, got %v", family.GetName(), value, got)
+			}
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels[clientpool.PrometheusPoolLabel] != "foo" {
+				t.Errorf("Expected pool label %q, got %v", "foo", labels)
+			}
+		}
+	}
+}
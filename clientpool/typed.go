@@ -0,0 +1,388 @@
+package clientpool
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is the error returned by Get of a TypedPool after it's closed.
+var ErrPoolClosed = errors.New("clientpool: pool closed")
+
+type priorityContextKey struct{}
+
+// WithPriority attaches the priority to use when the Get of a TypedPool needs
+// to wait for a client.
+//
+// When a client becomes available, it's given to the waiter with the highest
+// priority, and the waiters with the same priority are served in the order
+// they started waiting.
+// The default priority is 0, negative priorities are allowed.
+func WithPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, priorityContextKey{}, priority)
+}
+
+func getPriority(ctx context.Context) int {
+	priority, _ := ctx.Value(priorityContextKey{}).(int)
+	return priority
+}
+
+// TypedPoolConfig is the configuration of a TypedPool.
+type TypedPoolConfig struct {
+	// The number of clients to open when creating the pool.
+	InitialClients int
+
+	// The max number of clients the pool gives out at the same time.
+	MaxClients int
+
+	// MaxWait is the max time Get waits for a client to be released when the
+	// pool is exhausted, after which it returns ErrExhausted.
+	//
+	// Get never waits longer than the deadline of its context.
+	//
+	// Optional, <=0 means Get returns ErrExhausted right away without waiting,
+	// the same as the pool returned by NewChannelPool.
+	MaxWait time.Duration
+}
+
+// TypedPool is a client pool giving out clients of type T,
+// so the callers don't need to do type assertions on the clients they get.
+//
+// It's safe to be used concurrently.
+type TypedPool[T Client] struct {
+	opener  func() (T, error)
+	max     int
+	maxWait time.Duration
+
+	lock        sync.Mutex
+	idle        []T
+	active      int
+	waiters     waiterHeap[T]
+	seq         uint64
+	exhaustions uint64
+	closed      bool
+}
+
+// Make sure TypedPool implements StatsReporter.
+var _ StatsReporter = (*TypedPool[Client])(nil)
+
+// NewTypedPool creates a new TypedPool.
+func NewTypedPool[T Client](cfg TypedPoolConfig, opener func() (T, error)) (*TypedPool[T], error) {
+	if cfg.InitialClients > cfg.MaxClients {
+		return nil, &ConfigError{
+			InitialClients: cfg.InitialClients,
+			MaxClients:     cfg.MaxClients,
+		}
+	}
+
+	idle := make([]T, 0, cfg.MaxClients)
+	for i := 0; i < cfg.InitialClients; i++ {
+		c, err := opener()
+		if err != nil {
+			for _, c := range idle {
+				c.Close()
+			}
+			return nil, fmt.Errorf(
+				"error creating client #%d/%d: %w",
+				i,
+				cfg.InitialClients,
+				err,
+			)
+		}
+		idle = append(idle, c)
+	}
+
+	return &TypedPool[T]{
+		opener:  opener,
+		max:     cfg.MaxClients,
+		maxWait: cfg.MaxWait,
+		idle:    idle,
+	}, nil
+}
+
+// WarmUp opens new clients into the pool until there are n idle clients,
+// or the pool is full or closed.
+//
+// It returns the error from the opener, if any,
+// and the clients opened before the error are kept in the pool.
+func (p *TypedPool[T]) WarmUp(n int) error {
+	for {
+		p.lock.Lock()
+		full := p.closed || len(p.idle) >= n || len(p.idle)+p.active >= p.max
+		p.lock.Unlock()
+		if full {
+			return nil
+		}
+
+		c, err := p.opener()
+		if err != nil {
+			return err
+		}
+
+		p.lock.Lock()
+		if p.closed || len(p.idle)+p.active >= p.max {
+			p.lock.Unlock()
+			return c.Close()
+		}
+		p.idle = append(p.idle, c)
+		p.lock.Unlock()
+	}
+}
+
+// Get returns a client from the pool.
+//
+// When the pool is exhausted, Get waits for a client to be released for up to
+// MaxWait (see TypedPoolConfig) or until ctx is done, whichever comes first.
+// It returns ErrExhausted when MaxWait is reached,
+// and an error wrapping ctx.Err() when ctx is done.
+//
+// The waiters are served by their priorities, see WithPriority.
+func (p *TypedPool[T]) Get(ctx context.Context) (client T, err error) {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		err = ErrPoolClosed
+		return
+	}
+	for len(p.idle) > 0 {
+		c := p.idle[0]
+		p.idle = p.idle[1:]
+		if c.IsOpen() {
+			p.active++
+			p.lock.Unlock()
+			return c, nil
+		}
+	}
+	if p.active < p.max {
+		p.active++
+		p.lock.Unlock()
+		return p.open()
+	}
+
+	p.exhaustions++
+	if p.maxWait <= 0 {
+		p.lock.Unlock()
+		err = ErrExhausted
+		return
+	}
+	w := &waiter[T]{
+		priority: getPriority(ctx),
+		seq:      p.seq,
+		ch:       make(chan grant[T], 1),
+	}
+	p.seq++
+	heap.Push(&p.waiters, w)
+	p.lock.Unlock()
+
+	timer := time.NewTimer(p.maxWait)
+	defer timer.Stop()
+	select {
+	case g := <-w.ch:
+		return p.granted(g)
+	case <-timer.C:
+		err = ErrExhausted
+	case <-ctx.Done():
+		err = fmt.Errorf("clientpool: context done while waiting for a client: %w", ctx.Err())
+	}
+
+	p.lock.Lock()
+	if w.index >= 0 {
+		heap.Remove(&p.waiters, w.index)
+		p.lock.Unlock()
+		return
+	}
+	p.lock.Unlock()
+	// The grant was sent right before we gave up waiting, take it instead of
+	// leaking it.
+	return p.granted(<-w.ch)
+}
+
+// granted handles the grant received by a waiter.
+func (p *TypedPool[T]) granted(g grant[T]) (client T, err error) {
+	if g.err != nil {
+		err = g.err
+		return
+	}
+	if g.client != nil {
+		return *g.client, nil
+	}
+	return p.open()
+}
+
+// open opens a new client on the slot already reserved by the caller,
+// and gives the slot away if it fails.
+func (p *TypedPool[T]) open() (client T, err error) {
+	client, err = p.opener()
+	if err != nil {
+		p.releaseSlot()
+	}
+	return
+}
+
+// releaseSlot gives away the slot of an active client without a client to go
+// with it, to the first waiter (who will open a new client) if any.
+func (p *TypedPool[T]) releaseSlot() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.active--
+	if !p.closed && p.waiters.Len() > 0 {
+		w := heap.Pop(&p.waiters).(*waiter[T])
+		p.active++
+		w.ch <- grant[T]{}
+	}
+}
+
+// Release releases a client back to the pool.
+//
+// If the client is no longer open, a new client will be opened to replace it.
+// If the pool is full or closed, the client will be closed instead.
+func (p *TypedPool[T]) Release(c T) error {
+	if any(c) == nil {
+		return nil
+	}
+
+	if !c.IsOpen() {
+		newC, err := p.opener()
+		if err != nil {
+			p.releaseSlot()
+			return err
+		}
+		c = newC
+	}
+
+	p.lock.Lock()
+	p.active--
+	if p.closed {
+		p.lock.Unlock()
+		return c.Close()
+	}
+	if p.waiters.Len() > 0 {
+		w := heap.Pop(&p.waiters).(*waiter[T])
+		p.active++
+		w.ch <- grant[T]{client: &c}
+		p.lock.Unlock()
+		return nil
+	}
+	if len(p.idle) < p.max {
+		p.idle = append(p.idle, c)
+		p.lock.Unlock()
+		return nil
+	}
+	p.lock.Unlock()
+	// Pool is full, just close it instead.
+	return c.Close()
+}
+
+// Close closes the pool, and all allocated clients.
+//
+// The callers waiting in Get will get ErrPoolClosed,
+// and the clients released after Close will be closed.
+func (p *TypedPool[T]) Close() error {
+	p.lock.Lock()
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	for p.waiters.Len() > 0 {
+		w := heap.Pop(&p.waiters).(*waiter[T])
+		w.ch <- grant[T]{err: ErrPoolClosed}
+	}
+	p.lock.Unlock()
+
+	var lastErr error
+	for _, c := range idle {
+		if err := c.Close(); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// NumActiveClients returns the number of clients curently given out for use.
+func (p *TypedPool[T]) NumActiveClients() int32 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return int32(p.active)
+}
+
+// NumAllocated returns the number of allocated clients in internal pool.
+func (p *TypedPool[T]) NumAllocated() int32 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return int32(len(p.idle))
+}
+
+// IsExhausted returns true when NumActiveClients >= max capacity.
+func (p *TypedPool[T]) IsExhausted() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.active >= p.max
+}
+
+// Stats implements StatsReporter.
+func (p *TypedPool[T]) Stats() Stats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return Stats{
+		Active:      int64(p.active),
+		Idle:        int64(len(p.idle)),
+		Waiters:     int64(p.waiters.Len()),
+		Exhaustions: p.exhaustions,
+	}
+}
+
+// grant is what a waiter gets when it's woken up:
+// a released client, an error, or neither,
+// which means it's given a slot to open a new client.
+type grant[T Client] struct {
+	client *T
+	err    error
+}
+
+type waiter[T Client] struct {
+	priority int
+	seq      uint64
+	ch       chan grant[T]
+
+	// index in the heap, -1 when it's no longer in the heap.
+	index int
+}
+
+// waiterHeap implements heap.Interface,
+// with the highest priority and earliest waiter at the top.
+type waiterHeap[T Client] []*waiter[T]
+
+func (h waiterHeap[T]) Len() int {
+	return len(h)
+}
+
+func (h waiterHeap[T]) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h waiterHeap[T]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *waiterHeap[T]) Push(x interface{}) {
+	w := x.(*waiter[T])
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+
+func (h *waiterHeap[T]) Pop() interface{} {
+	old := *h
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*h = old[:n-1]
+	return w
+}
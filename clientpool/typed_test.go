@@ -0,0 +1,334 @@
+package clientpool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/clientpool"
+)
+
+func newTypedPool(t *testing.T, cfg clientpool.TypedPoolConfig) *clientpool.TypedPool[*testClient] {
+	t.Helper()
+	pool, err := clientpool.NewTypedPool(cfg, func() (*testClient, error) {
+		return &testClient{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		pool.Close()
+	})
+	return pool
+}
+
+func waitForWaiters(t *testing.T, pool *clientpool.TypedPool[*testClient], n int64) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for pool.Stats().Waiters != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d waiters, got %d", n, pool.Stats().Waiters)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTypedPoolInvalidConfig(t *testing.T) {
+	_, err := clientpool.NewTypedPool(
+		clientpool.TypedPoolConfig{InitialClients: 5, MaxClients: 1},
+		func() (*testClient, error) {
+			return &testClient{}, nil
+		},
+	)
+	var ce *clientpool.ConfigError
+	if !errors.As(err, &ce) {
+		t.Errorf("Expected *ConfigError, got %v", err)
+	}
+}
+
+func TestTypedPool(t *testing.T) {
+	ctx := context.Background()
+	pool := newTypedPool(t, clientpool.TypedPoolConfig{
+		InitialClients: 1,
+		MaxClients:     2,
+	})
+
+	c1, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1 == c2 {
+		t.Error("Expected different clients")
+	}
+	if !pool.IsExhausted() {
+		t.Error("Expected pool to be exhausted")
+	}
+
+	if _, err := pool.Get(ctx); !errors.Is(err, clientpool.ErrExhausted) {
+		t.Errorf("Expected ErrExhausted, got %v", err)
+	}
+	expected := clientpool.Stats{Active: 2, Exhaustions: 1}
+	if stats := pool.Stats(); stats != expected {
+		t.Errorf("Expected stats %+v, got %+v", expected, stats)
+	}
+
+	if err := pool.Release(c1); err != nil {
+		t.Fatal(err)
+	}
+	c2.Close()
+	if err := pool.Release(c2); err != nil {
+		t.Fatal(err)
+	}
+	expected = clientpool.Stats{Active: 0, Idle: 2, Exhaustions: 1}
+	if stats := pool.Stats(); stats != expected {
+		t.Errorf("Expected stats %+v, got %+v", expected, stats)
+	}
+
+	c, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != c1 {
+		t.Error("Expected to get the idle client released first")
+	}
+	c, err = pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.IsOpen() {
+		t.Error("Expected the closed client to be replaced by an open one")
+	}
+}
+
+func TestTypedPoolWarmUp(t *testing.T) {
+	ctx := context.Background()
+	fail := true
+	pool, err := clientpool.NewTypedPool(
+		clientpool.TypedPoolConfig{MaxClients: 3},
+		func() (*testClient, error) {
+			if fail {
+				return nil, errors.New("failed")
+			}
+			return &testClient{}, nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	if err := pool.WarmUp(2); err == nil {
+		t.Error("Expected the error from the opener, got nil")
+	}
+
+	fail = false
+	c, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.WarmUp(5); err != nil {
+		t.Fatal(err)
+	}
+	// Capped by MaxClients, including the active one.
+	expected := clientpool.Stats{Active: 1, Idle: 2}
+	if stats := pool.Stats(); stats != expected {
+		t.Errorf("Expected stats %+v, got %+v", expected, stats)
+	}
+
+	if err := pool.Release(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.WarmUp(2); err != nil {
+		t.Fatal(err)
+	}
+	expected = clientpool.Stats{Idle: 3}
+	if stats := pool.Stats(); stats != expected {
+		t.Errorf("Expected stats %+v, got %+v", expected, stats)
+	}
+}
+
+func TestTypedPoolWait(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("released", func(t *testing.T) {
+		pool := newTypedPool(t, clientpool.TypedPoolConfig{
+			MaxClients: 1,
+			MaxWait:    time.Second,
+		})
+		c, err := pool.Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ch := make(chan *testClient)
+		go func() {
+			c, err := pool.Get(ctx)
+			if err != nil {
+				t.Error(err)
+			}
+			ch <- c
+		}()
+		waitForWaiters(t, pool, 1)
+		if err := pool.Release(c); err != nil {
+			t.Fatal(err)
+		}
+		if got := <-ch; got != c {
+			t.Error("Expected the waiter to get the released client")
+		}
+		if active := pool.NumActiveClients(); active != 1 {
+			t.Errorf("Expected 1 active client, got %d", active)
+		}
+	})
+
+	t.Run("max-wait", func(t *testing.T) {
+		pool := newTypedPool(t, clientpool.TypedPoolConfig{
+			MaxClients: 1,
+			MaxWait:    time.Millisecond * 10,
+		})
+		if _, err := pool.Get(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pool.Get(ctx); !errors.Is(err, clientpool.ErrExhausted) {
+			t.Errorf("Expected ErrExhausted, got %v", err)
+		}
+		if waiters := pool.Stats().Waiters; waiters != 0 {
+			t.Errorf("Expected no waiters left, got %d", waiters)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		pool := newTypedPool(t, clientpool.TypedPoolConfig{
+			MaxClients: 1,
+			MaxWait:    time.Minute,
+		})
+		if _, err := pool.Get(ctx); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+		defer cancel()
+		if _, err := pool.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+
+	t.Run("opener-failure", func(t *testing.T) {
+		errOpen := errors.New("open failed")
+		var fail bool
+		pool, err := clientpool.NewTypedPool(
+			clientpool.TypedPoolConfig{MaxClients: 1, MaxWait: time.Second},
+			func() (*testClient, error) {
+				if fail {
+					return nil, errOpen
+				}
+				return &testClient{}, nil
+			},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Close()
+		c, err := pool.Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ch := make(chan error)
+		go func() {
+			_, err := pool.Get(ctx)
+			ch <- err
+		}()
+		waitForWaiters(t, pool, 1)
+		// The replacement of the closed client fails to open,
+		// the waiter gets the slot and fails to open as well.
+		fail = true
+		c.Close()
+		if err := pool.Release(c); !errors.Is(err, errOpen) {
+			t.Errorf("Expected Release error %v, got %v", errOpen, err)
+		}
+		if err := <-ch; !errors.Is(err, errOpen) {
+			t.Errorf("Expected Get error %v, got %v", errOpen, err)
+		}
+		if active := pool.NumActiveClients(); active != 0 {
+			t.Errorf("Expected 0 active clients, got %d", active)
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		pool := newTypedPool(t, clientpool.TypedPoolConfig{
+			MaxClients: 1,
+			MaxWait:    time.Minute,
+		})
+		c, err := pool.Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ch := make(chan error)
+		go func() {
+			_, err := pool.Get(ctx)
+			ch <- err
+		}()
+		waitForWaiters(t, pool, 1)
+		pool.Close()
+		if err := <-ch; !errors.Is(err, clientpool.ErrPoolClosed) {
+			t.Errorf("Expected ErrPoolClosed, got %v", err)
+		}
+		if err := pool.Release(c); err != nil {
+			t.Fatal(err)
+		}
+		if c.IsOpen() {
+			t.Error("Expected client released after Close to be closed")
+		}
+		if _, err := pool.Get(ctx); !errors.Is(err, clientpool.ErrPoolClosed) {
+			t.Errorf("Expected ErrPoolClosed, got %v", err)
+		}
+	})
+}
+
+func TestTypedPoolPriority(t *testing.T) {
+	ctx := context.Background()
+	pool := newTypedPool(t, clientpool.TypedPoolConfig{
+		MaxClients: 1,
+		MaxWait:    time.Second,
+	})
+	c, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan string, 4)
+	for i, w := range []struct {
+		name     string
+		priority int
+	}{
+		{name: "low", priority: -1},
+		{name: "default-1", priority: 0},
+		{name: "high", priority: 10},
+		{name: "default-2", priority: 0},
+	} {
+		go func(name string, priority int) {
+			c, err := pool.Get(clientpool.WithPriority(ctx, priority))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			order <- name
+			pool.Release(c)
+		}(w.name, w.priority)
+		waitForWaiters(t, pool, int64(i+1))
+	}
+
+	if err := pool.Release(c); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"high", "default-1", "default-2", "low"} {
+		if got := <-order; got != want {
+			t.Errorf("Expected %q to get the client, got %q", want, got)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package baseplate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/reddit/baseplate.go/configbp"
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// ConfigChangeHook is the type of the callbacks called by ConfigWatcher when
+// the config file is changed.
+//
+// old and new are the GetConfig results of the previous and the updated
+// configs.
+// Use ConfigWatcher.Get to access the customized configurations embedding
+// Config.
+type ConfigChangeHook func(old, new Config)
+
+// ConfigWatcher watches a YAML config file and calls the registered
+// ConfigChangeHooks whenever its content changes,
+// so that subsystems like log level, sampling rates and rate limits can be
+// updated without restarting the service.
+//
+// It's usually created by New when NewArgs.WatchConfig is true,
+// in which case the hooks should be registered via
+// ConfigChangeNotifier.OnConfigChange of the Baseplate instead.
+type ConfigWatcher struct {
+	fw filewatcher.FileWatcher
+
+	lock    sync.Mutex
+	current Configer
+	hooks   []ConfigChangeHook
+}
+
+// WatchConfig creates a ConfigWatcher watching the YAML config file at path.
+//
+// The file is parsed the same way as ParseConfigYAML,
+// into a new value of the same type as cfg on every change
+// (e.g. if cfg is a *myServiceConfig, Get always returns a *myServiceConfig).
+// If the updated file fails to parse, the error is logged via logger and the
+// previous config is kept.
+//
+// It's necessary to call Stop on the returned ConfigWatcher when it's no
+// longer used.
+func WatchConfig(ctx context.Context, path string, cfg Configer, logger log.Wrapper) (*ConfigWatcher, error) {
+	if cfg == nil {
+		return nil, errors.New("baseplate.WatchConfig: cfg must not be nil")
+	}
+	typ := reflect.TypeOf(cfg)
+	w := new(ConfigWatcher)
+	fw, err := filewatcher.New(ctx, filewatcher.Config{
+		Path: path,
+		Parser: func(r io.Reader) (interface{}, error) {
+			cfg, err := parseConfigAs(r, typ)
+			if err != nil {
+				return nil, err
+			}
+			w.update(cfg)
+			return cfg, nil
+		},
+		Logger: logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.fw = fw
+	return w, nil
+}
+
+// OnConfigChange registers hook to be called when the config file changes.
+//
+// The hooks are called in the order they are registered,
+// from the goroutine watching the file,
+// so they should return quickly.
+// They are not called if the content changed without changing the parsed
+// config (e.g. only the comments changed).
+func (w *ConfigWatcher) OnConfigChange(hook ConfigChangeHook) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.hooks = append(w.hooks, hook)
+}
+
+// Get returns the latest parsed config.
+func (w *ConfigWatcher) Get() Configer {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.current
+}
+
+// Stop stops watching the config file.
+//
+// After Stop is called the hooks are no longer called,
+// but Get still returns the last config before stopping.
+func (w *ConfigWatcher) Stop() {
+	w.fw.Stop()
+}
+
+// Close implements io.Closer by calling Stop.
+func (w *ConfigWatcher) Close() error {
+	w.Stop()
+	return nil
+}
+
+func (w *ConfigWatcher) update(cfg Configer) {
+	w.lock.Lock()
+	old := w.current
+	w.current = cfg
+	hooks := make([]ConfigChangeHook, len(w.hooks))
+	copy(hooks, w.hooks)
+	w.lock.Unlock()
+
+	if old == nil || reflect.DeepEqual(old, cfg) {
+		return
+	}
+	oldCfg, newCfg := old.GetConfig(), cfg.GetConfig()
+	for _, hook := range hooks {
+		hook(oldCfg, newCfg)
+	}
+}
+
+// parseConfigAs strictly parses the YAML config from r into a new value of typ,
+// which could either be a type implementing Configer or a pointer to it.
+func parseConfigAs(r io.Reader, typ reflect.Type) (Configer, error) {
+	isPtr := typ.Kind() == reflect.Ptr
+	if isPtr {
+		typ = typ.Elem()
+	}
+	ptr := reflect.New(typ)
+	if err := configbp.ParseStrictYAML(r, ptr.Interface()); err != nil {
+		return nil, fmt.Errorf("baseplate: failed to parse config: %w", err)
+	}
+	if isPtr {
+		return ptr.Interface().(Configer), nil
+	}
+	return ptr.Elem().Interface().(Configer), nil
+}
+
+// logLevelHook is the ConfigChangeHook updating the log levels,
+// registered by New when the log levels are not from Log.LevelFile.
+func logLevelHook(old, new Config) {
+	if new.Log.Level != old.Log.Level {
+		level := new.Log.Level
+		if level == "" {
+			level = log.InfoLevel
+		}
+		log.SetLevel(level)
+	}
+	if !reflect.DeepEqual(new.Log.Levels, old.Log.Levels) {
+		log.SetNamedLevels(new.Log.Levels)
+	}
+}
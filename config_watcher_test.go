@@ -0,0 +1,163 @@
+package baseplate_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/log"
+)
+
+type watchedConfig struct {
+	baseplate.Config `yaml:",inline"`
+
+	Limit int `yaml:"limit"`
+}
+
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	// Write to a temp file then rename to trigger a single CREATE event.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, `
+addr: :8080
+log:
+  level: info
+limit: 1
+`)
+
+	logged := make(chan string, 10)
+	w, err := baseplate.WatchConfig(
+		context.Background(),
+		path,
+		new(watchedConfig),
+		func(_ context.Context, msg string) {
+			logged <- msg
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(w.Stop)
+
+	cfg, ok := w.Get().(*watchedConfig)
+	if !ok {
+		t.Fatalf("Expected *watchedConfig, got %T", w.Get())
+	}
+	if cfg.Addr != ":8080" || cfg.Limit != 1 {
+		t.Errorf("Unexpected initial config: %#v", cfg)
+	}
+
+	type change struct {
+		old, new baseplate.Config
+	}
+	changes := make(chan change, 10)
+	w.OnConfigChange(func(old, new baseplate.Config) {
+		changes <- change{old: old, new: new}
+	})
+
+	const timeout = time.Second
+	writeConfigFile(t, path, `
+addr: :8080
+log:
+  level: debug
+limit: 1
+`)
+	select {
+	case c := <-changes:
+		if c.old.Log.Level != log.InfoLevel {
+			t.Errorf("Expected old log level %q, got %q", log.InfoLevel, c.old.Log.Level)
+		}
+		if c.new.Log.Level != log.DebugLevel {
+			t.Errorf("Expected new log level %q, got %q", log.DebugLevel, c.new.Log.Level)
+		}
+	case <-time.After(timeout):
+		t.Fatal("Hook not called after the config changed")
+	}
+
+	t.Run("customized-configs", func(t *testing.T) {
+		writeConfigFile(t, path, `
+addr: :8080
+log:
+  level: debug
+limit: 2
+`)
+		select {
+		case <-changes:
+			if limit := w.Get().(*watchedConfig).Limit; limit != 2 {
+				t.Errorf("Expected limit 2, got %d", limit)
+			}
+		case <-time.After(timeout):
+			t.Fatal("Hook not called after the config changed")
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		writeConfigFile(t, path, `
+# comment
+addr: :8080
+log:
+  level: debug
+limit: 2
+`)
+		select {
+		case c := <-changes:
+			t.Errorf("Hook called without actual changes: %#v", c)
+		case <-time.After(timeout / 2):
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		writeConfigFile(t, path, `
+addr: :8080
+unknown: foo
+`)
+		select {
+		case c := <-changes:
+			t.Errorf("Hook called with invalid config: %#v", c)
+		case <-logged:
+		case <-time.After(timeout):
+			t.Error("Parser error not logged")
+		}
+		if limit := w.Get().(*watchedConfig).Limit; limit != 2 {
+			t.Errorf("Expected the previous config to be kept with limit 2, got %d", limit)
+		}
+	})
+}
+
+func TestWatchConfigValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, "addr: :8080\n")
+
+	w, err := baseplate.WatchConfig(
+		context.Background(),
+		path,
+		baseplate.Config{},
+		log.TestWrapper(t),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(w.Stop)
+
+	cfg, ok := w.Get().(baseplate.Config)
+	if !ok {
+		t.Fatalf("Expected baseplate.Config, got %T", w.Get())
+	}
+	if cfg.Addr != ":8080" {
+		t.Errorf("Expected addr %q, got %q", ":8080", cfg.Addr)
+	}
+}
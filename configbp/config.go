@@ -8,11 +8,13 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v2"
 
+	"github.com/reddit/baseplate.go/errorsbp"
 	"github.com/reddit/baseplate.go/internal/limitopen"
 	"github.com/reddit/baseplate.go/log"
 )
@@ -66,6 +68,11 @@ func ParseStrictFile(path string, ptr interface{}) error {
 //
 // Environment variables (e.g. $FOO and ${FOO}) are substituted from the environment before parsing.
 // The configuration is parsed into each of the targets, which will typically be pointers to structs.
+//
+// All the fields in the YAML not matching any field of the targets are
+// reported with their YAML paths (see ErrUnknownField),
+// and the parsed configuration is validated by Validate.
+// The errors are aggregated into an errorsbp.Batch of *FieldErrors.
 func ParseStrictYAML(reader io.Reader, ptr interface{}) error {
 	reader = &envsubstReader{
 		lines: bufio.NewScanner(reader),
@@ -76,7 +83,21 @@ func ParseStrictYAML(reader io.Reader, ptr interface{}) error {
 		reader = io.TeeReader(reader, &debugOutput)
 	}
 
-	dec := yaml.NewDecoder(reader)
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("reading YAML: %w", err)
+	}
+
+	var node interface{}
+	if err := yaml.Unmarshal(data, &node); err == nil {
+		var batch errorsbp.Batch
+		checkUnknownFields(&batch, "", node, reflect.TypeOf(ptr))
+		if err := batch.Compile(); err != nil {
+			return fmt.Errorf("parsing YAML into %T: %w", ptr, err)
+		}
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
 	dec.SetStrict(true)
 	if err := dec.Decode(ptr); err != nil {
 		// Print out the partial configuration to aid in debugging decode errors now that the file isn't used literally
@@ -91,5 +112,8 @@ func ParseStrictYAML(reader io.Reader, ptr interface{}) error {
 		log.Debugf("Parsed configuration as %T:\n%s", ptr, debugOutput.String())
 	}
 
+	if err := Validate(ptr); err != nil {
+		return fmt.Errorf("validating %T: %w", ptr, err)
+	}
 	return nil
 }
@@ -0,0 +1,299 @@
+package configbp
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/reddit/baseplate.go/errorsbp"
+)
+
+// ErrUnknownField is the error reported for the fields in the YAML that don't
+// match any field of the struct they are decoded into,
+// usually caused by typos like "adddr".
+var ErrUnknownField = errors.New("unknown field")
+
+// ErrRequired is the error to be reported by Validators for the required
+// fields missing from the config.
+var ErrRequired = errors.New("required field is missing")
+
+// FieldError is the error of a field in the config.
+//
+// The errors returned by ParseStrictYAML and Validate are errorsbp.Batch of
+// FieldErrors.
+type FieldError struct {
+	// Path is the YAML path to the field, e.g. "log.level",
+	// "tracing.sampler.rules[0].name".
+	//
+	// It's relative to the section when returned by a Validator.
+	Path string
+
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	if e.Path == "" {
+		return "configbp: " + e.Err.Error()
+	}
+	return fmt.Sprintf("configbp: %s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Validator is the interface the config sections can implement to validate
+// their values after being decoded, e.g. required fields and value ranges.
+//
+// ValidateConfig can return a *FieldError or an errorsbp.Batch of
+// *FieldErrors with Paths relative to the section to report the exact fields.
+// Other errors are reported on the section itself,
+// so they should name the fields in their messages,
+// e.g. "sampleRate: must be in [0, 1]".
+//
+// Unlike the Validate methods of the configs (e.g.
+// thriftbp.ClientPoolConfig.Validate) that are checked after the defaults and
+// the values from the code are filled,
+// ValidateConfig should only reject the values that are always invalid in the
+// YAML.
+type Validator interface {
+	ValidateConfig() error
+}
+
+// Validate validates cfg by calling ValidateConfig on every section
+// implementing Validator, including cfg itself.
+//
+// The sections with zero values (e.g. omitted in the YAML) are skipped,
+// except cfg itself.
+//
+// The returned error, if non-nil, is an errorsbp.Batch of *FieldErrors from
+// all the sections.
+func Validate(cfg interface{}) error {
+	var batch errorsbp.Batch
+	validate(&batch, "", reflect.ValueOf(cfg), true)
+	return batch.Compile()
+}
+
+func validate(batch *errorsbp.Batch, path string, v reflect.Value, root bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() || !v.CanInterface() || (!root && v.IsZero()) {
+		return
+	}
+
+	validatee := v.Interface()
+	if v.CanAddr() {
+		validatee = v.Addr().Interface()
+	}
+	if validator, ok := validatee.(Validator); ok {
+		addFieldErrors(batch, path, validator.ValidateConfig())
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, f := range yamlFields(v.Type()) {
+			fieldPath := path
+			if !f.inline {
+				fieldPath = joinPath(path, f.name)
+			}
+			validate(batch, fieldPath, v.Field(f.index), false)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		for _, key := range keys {
+			validate(batch, joinPath(path, fmt.Sprint(key)), v.MapIndex(key), false)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validate(batch, indexPath(path, i), v.Index(i), false)
+		}
+	}
+}
+
+func addFieldErrors(batch *errorsbp.Batch, path string, err error) {
+	if err == nil {
+		return
+	}
+	if errs, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range errs.Unwrap() {
+			addFieldErrors(batch, path, err)
+		}
+		return
+	}
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		batch.Add(&FieldError{
+			Path: joinPath(path, fe.Path),
+			Err:  fe.Err,
+		})
+		return
+	}
+	batch.Add(&FieldError{
+		Path: path,
+		Err:  err,
+	})
+}
+
+// checkUnknownFields reports the keys in node, decoded from YAML into an
+// interface{}, that don't match any field of typ.
+func checkUnknownFields(batch *errorsbp.Batch, path string, node interface{}, typ reflect.Type) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if customUnmarshaler(typ) {
+		// The shape of the YAML is decided by the type itself.
+		return
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		m, ok := node.(map[interface{}]interface{})
+		if !ok {
+			// Leave the type errors to the decoder.
+			return
+		}
+		fields := make(map[string]reflect.Type)
+		var inlineMap bool
+		collectYAMLFields(typ, fields, &inlineMap)
+		for _, key := range sortedKeys(m) {
+			name := fmt.Sprint(key)
+			fieldType, ok := fields[name]
+			if !ok {
+				if !inlineMap {
+					batch.Add(&FieldError{
+						Path: joinPath(path, name),
+						Err:  ErrUnknownField,
+					})
+				}
+				continue
+			}
+			checkUnknownFields(batch, joinPath(path, name), m[key], fieldType)
+		}
+	case reflect.Map:
+		m, ok := node.(map[interface{}]interface{})
+		if !ok {
+			return
+		}
+		for _, key := range sortedKeys(m) {
+			checkUnknownFields(batch, joinPath(path, fmt.Sprint(key)), m[key], typ.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		s, ok := node.([]interface{})
+		if !ok {
+			return
+		}
+		for i, elem := range s {
+			checkUnknownFields(batch, indexPath(path, i), elem, typ.Elem())
+		}
+	}
+}
+
+// collectYAMLFields collects the field names and types of struct typ
+// following the rules of yaml.v2, including the fields of the inlined structs.
+//
+// inlineMap is set to true when typ has an inlined map catching all the
+// unknown fields.
+func collectYAMLFields(typ reflect.Type, fields map[string]reflect.Type, inlineMap *bool) {
+	for _, f := range yamlFields(typ) {
+		field := typ.Field(f.index)
+		if !f.inline {
+			fields[f.name] = field.Type
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.Map:
+			*inlineMap = true
+		case reflect.Struct:
+			collectYAMLFields(field.Type, fields, inlineMap)
+		}
+	}
+}
+
+type yamlField struct {
+	index  int
+	name   string
+	inline bool
+}
+
+// yamlFields returns the fields of struct typ decoded by yaml.v2.
+func yamlFields(typ reflect.Type) []yamlField {
+	fields := make([]yamlField, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			// unexported
+			continue
+		}
+		tag := field.Tag.Get("yaml")
+		if tag == "" && !strings.Contains(string(field.Tag), ":") {
+			// yaml.v2 also accepts the whole tag as the yaml tag.
+			tag = string(field.Tag)
+		}
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		f := yamlField{
+			index: i,
+			name:  parts[0],
+		}
+		for _, flag := range parts[1:] {
+			if flag == "inline" {
+				f.inline = true
+			}
+		}
+		if f.name == "" {
+			f.name = strings.ToLower(field.Name)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+var (
+	yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func customUnmarshaler(typ reflect.Type) bool {
+	ptr := reflect.PtrTo(typ)
+	return ptr.Implements(yamlUnmarshalerType) || ptr.Implements(textUnmarshalerType)
+}
+
+func sortedKeys(m map[interface{}]interface{}) []interface{} {
+	keys := make([]interface{}, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+	return keys
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	if name == "" {
+		return path
+	}
+	return path + "." + name
+}
+
+func indexPath(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
@@ -0,0 +1,183 @@
+package configbp_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/configbp"
+	"github.com/reddit/baseplate.go/errorsbp"
+)
+
+type validatedSection struct {
+	Name  string `yaml:"name"`
+	Count int    `yaml:"count"`
+}
+
+func (s validatedSection) ValidateConfig() error {
+	var batch errorsbp.Batch
+	if s.Name == "" {
+		batch.Add(&configbp.FieldError{
+			Path: "name",
+			Err:  configbp.ErrRequired,
+		})
+	}
+	if s.Count > 10 {
+		batch.Add(errors.New("count: must not be greater than 10"))
+	}
+	return batch.Compile()
+}
+
+type serviceConfig struct {
+	baseplate.Config `yaml:",inline"`
+
+	Section  validatedSection            `yaml:"section"`
+	Sections []validatedSection          `yaml:"sections"`
+	Named    map[string]validatedSection `yaml:"named"`
+}
+
+// fieldErrors returns the messages of all the *FieldErrors in err.
+func fieldErrors(t *testing.T, err error) []string {
+	t.Helper()
+
+	var errs []error
+	var batch errorsbp.Batch
+	if errors.As(err, &batch) {
+		errs = batch.GetErrors()
+	} else {
+		errs = []error{err}
+	}
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		var fe *configbp.FieldError
+		if !errors.As(err, &fe) {
+			t.Errorf("Expected *configbp.FieldError, got %#v", err)
+			continue
+		}
+		msgs = append(msgs, fe.Error())
+	}
+	return msgs
+}
+
+func TestParseStrictYAMLValidation(t *testing.T) {
+	for _, c := range []struct {
+		label   string
+		content string
+		want    []string
+	}{
+		{
+			label: "valid",
+			content: `
+addr: :8080
+log:
+  level: debug
+section:
+  name: foo
+sections:
+  - name: bar
+named:
+  baz:
+    name: baz
+`,
+		},
+		{
+			label: "unknown-fields",
+			content: `
+adddr: :8080
+log:
+  levl: debug
+sections:
+  - name: bar
+    cuont: 1
+named:
+  baz:
+    nmae: baz
+`,
+			want: []string{
+				"configbp: adddr: unknown field",
+				"configbp: log.levl: unknown field",
+				"configbp: named.baz.nmae: unknown field",
+				"configbp: sections[0].cuont: unknown field",
+			},
+		},
+		{
+			label: "sections",
+			content: `
+log:
+  level: verbose
+runtime:
+  numProcesses:
+    min: 4
+    max: 2
+secrets:
+  redactFromLogs: true
+tracing:
+  sampleRate: 10
+`,
+			want: []string{
+				`configbp: log: level: unknown log level "verbose"`,
+				"configbp: runtime: numProcesses.min: must not be greater than numProcesses.max (2), got 4",
+				"configbp: secrets: path: required field is missing",
+				"configbp: tracing: sampleRate: must be in [0, 1], got 10",
+			},
+		},
+		{
+			label: "custom-validators",
+			content: `
+section:
+  count: 20
+sections:
+  - name: foo
+  - count: 1
+named:
+  foo:
+    count: 1
+`,
+			want: []string{
+				"configbp: section.name: required field is missing",
+				"configbp: section: count: must not be greater than 10",
+				"configbp: sections[1].name: required field is missing",
+				"configbp: named.foo.name: required field is missing",
+			},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var cfg serviceConfig
+			err := configbp.ParseStrictYAML(strings.NewReader(c.content), &cfg)
+			if len(c.want) == 0 {
+				if err != nil {
+					t.Fatalf("ParseStrictYAML returned error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if diff := cmp.Diff(fieldErrors(t, err), c.want); diff != "" {
+				t.Errorf("Errors mismatch (-got +want):\n%s\nerror: %v", diff, err)
+			}
+		})
+	}
+}
+
+func TestUnknownFieldIs(t *testing.T) {
+	var cfg baseplate.Config
+	err := configbp.ParseStrictYAML(strings.NewReader("adddr: :8080\n"), &cfg)
+	if !errors.Is(err, configbp.ErrUnknownField) {
+		t.Errorf("Expected error to be ErrUnknownField, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := configbp.Validate(&validatedSection{Name: "foo"}); err != nil {
+		t.Errorf("Validate returned error on valid config: %v", err)
+	}
+	// The root is validated even when it's zero.
+	err := configbp.Validate(validatedSection{})
+	if !errors.Is(err, configbp.ErrRequired) {
+		t.Errorf("Expected error to be ErrRequired, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package ecinterface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Errors regarding the custom claims.
+var (
+	// ErrClaimNotAllowed is the error returned by Claims.Validate for the claims
+	// not in the allowlist.
+	ErrClaimNotAllowed = errors.New("ecinterface: claim not allowed")
+
+	// ErrClaimsNotSupported is the error returned by WithClaims when the
+	// implementation doesn't implement Claimer.
+	ErrClaimsNotSupported = errors.New("ecinterface: custom claims not supported by the implementation")
+)
+
+// Claims are the service-defined custom claims carried by the edge context,
+// keyed by the claim names.
+//
+// They are propagated along with the edge context to the downstream services,
+// so they should be small and must not contain PII.
+type Claims map[string]string
+
+// Validate returns an error wrapping ErrClaimNotAllowed if any of the claims
+// is not in allowlist.
+//
+// Implementations should call it before attaching the claims to the edge
+// context, and drop the claims not in the allowlist when parsing the headers,
+// so services can't smuggle arbitrary data through the edge context.
+func (c Claims) Validate(allowlist []string) error {
+	allowed := make(map[string]bool, len(allowlist))
+	for _, name := range allowlist {
+		allowed[name] = true
+	}
+	var disallowed []string
+	for name := range c {
+		if !allowed[name] {
+			disallowed = append(disallowed, name)
+		}
+	}
+	if len(disallowed) == 0 {
+		return nil
+	}
+	sort.Strings(disallowed)
+	return fmt.Errorf("%w: %q", ErrClaimNotAllowed, disallowed)
+}
+
+// Claimer is an optional interface edgecontext implementations can implement
+// to support the custom claims.
+type Claimer interface {
+	// Claims returns the custom claims from the edge context attached to ctx.
+	//
+	// It shall return false when there's no edge context attached to ctx.
+	// The returned Claims shall not be modified by the caller.
+	Claims(ctx context.Context) (Claims, bool)
+
+	// WithClaims returns a copy of ctx with the custom claims added to the edge
+	// context attached to ctx, replacing the existing claims with the same names.
+	//
+	// It shall return an error wrapping ErrClaimNotAllowed if any of the claims
+	// is not allowed by the implementation.
+	WithClaims(ctx context.Context, claims Claims) (context.Context, error)
+}
+
+// GetClaims returns the custom claims from the edge context attached to ctx,
+// if impl implements Claimer.
+func GetClaims(ctx context.Context, impl Interface) (Claims, bool) {
+	if claimer, ok := impl.(Claimer); ok {
+		return claimer.Claims(ctx)
+	}
+	return nil, false
+}
+
+// GetClaim returns a single custom claim from the edge context attached to
+// ctx, if impl implements Claimer.
+func GetClaim(ctx context.Context, impl Interface, name string) (string, bool) {
+	claims, ok := GetClaims(ctx, impl)
+	if !ok {
+		return "", false
+	}
+	value, ok := claims[name]
+	return value, ok
+}
+
+// WithClaims adds the custom claims to the edge context attached to ctx,
+// if impl implements Claimer.
+//
+// It returns ctx intact with ErrClaimsNotSupported if impl doesn't implement
+// Claimer.
+func WithClaims(ctx context.Context, impl Interface, claims Claims) (context.Context, error) {
+	if claimer, ok := impl.(Claimer); ok {
+		return claimer.WithClaims(ctx, claims)
+	}
+	return ctx, ErrClaimsNotSupported
+}
@@ -0,0 +1,100 @@
+package ecinterface_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/reddit/baseplate.go/ecinterface"
+)
+
+// noClaimer is an Interface not implementing Claimer.
+type noClaimer struct {
+	ecinterface.Interface
+}
+
+func TestClaimsValidate(t *testing.T) {
+	for _, c := range []struct {
+		label     string
+		claims    ecinterface.Claims
+		allowlist []string
+		expected  string
+	}{
+		{
+			label:     "allowed",
+			claims:    ecinterface.Claims{"foo": "1", "bar": "2"},
+			allowlist: []string{"bar", "foo", "fizz"},
+		},
+		{
+			label:     "empty",
+			allowlist: nil,
+		},
+		{
+			label:     "disallowed",
+			claims:    ecinterface.Claims{"foo": "1", "zoo": "2", "bar": "3", "baz": "4"},
+			allowlist: []string{"foo"},
+			expected:  `ecinterface: claim not allowed: ["bar" "baz" "zoo"]`,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			err := c.claims.Validate(c.allowlist)
+			if c.expected == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ecinterface.ErrClaimNotAllowed) {
+				t.Errorf("Expected error to wrap %v, got %v", ecinterface.ErrClaimNotAllowed, err)
+			}
+			if err != nil && err.Error() != c.expected {
+				t.Errorf("Expected error %q, got %q", c.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestClaims(t *testing.T) {
+	impl := ecinterface.Mock()
+	ctx := context.Background()
+
+	if claims, ok := ecinterface.GetClaims(ctx, impl); ok {
+		t.Errorf("Expected no claims, got %v", claims)
+	}
+	if value, ok := ecinterface.GetClaim(ctx, impl, "foo"); ok {
+		t.Errorf("Expected no claim, got %q", value)
+	}
+
+	ctx, err := ecinterface.WithClaims(ctx, impl, ecinterface.Claims{"foo": "1", "bar": "2"})
+	if err != nil {
+		t.Fatalf("WithClaims returned error: %v", err)
+	}
+	ctx, err = ecinterface.WithClaims(ctx, impl, ecinterface.Claims{"foo": "3"})
+	if err != nil {
+		t.Fatalf("WithClaims returned error: %v", err)
+	}
+	for name, expected := range map[string]string{"foo": "3", "bar": "2"} {
+		if value, ok := ecinterface.GetClaim(ctx, impl, name); !ok || value != expected {
+			t.Errorf("Expected claim %q to be %q, got %q, %v", name, expected, value, ok)
+		}
+	}
+	if value, ok := ecinterface.GetClaim(ctx, impl, "fizz"); ok {
+		t.Errorf("Expected no claim %q, got %q", "fizz", value)
+	}
+}
+
+func TestClaimsNotSupported(t *testing.T) {
+	impl := noClaimer{ecinterface.Mock()}
+	ctx := context.Background()
+
+	got, err := ecinterface.WithClaims(ctx, impl, ecinterface.Claims{"foo": "1"})
+	if !errors.Is(err, ecinterface.ErrClaimsNotSupported) {
+		t.Errorf("Expected error %v, got %v", ecinterface.ErrClaimsNotSupported, err)
+	}
+	if got != ctx {
+		t.Error("Expected ctx to be returned intact")
+	}
+	if value, ok := ecinterface.GetClaim(ctx, impl, "foo"); ok {
+		t.Errorf("Expected no claim, got %q", value)
+	}
+}
@@ -0,0 +1,79 @@
+package ecinterface
+
+import (
+	"context"
+	"time"
+)
+
+// DeviceKind is the kind of the device the request is from.
+//
+// It's a string type so the kinds added in the future are carried through
+// intact by the services built with the older versions of Baseplate.go.
+type DeviceKind string
+
+// Known DeviceKind values.
+const (
+	DeviceKindUnknown DeviceKind = ""
+	DeviceKindIOS     DeviceKind = "ios"
+	DeviceKindAndroid DeviceKind = "android"
+	DeviceKindDesktop DeviceKind = "desktop"
+	DeviceKindMobile  DeviceKind = "mobile"
+)
+
+// Known returns true if k is one of the DeviceKind values known to this
+// version of Baseplate.go.
+func (k DeviceKind) Known() bool {
+	switch k {
+	default:
+		return false
+	case DeviceKindIOS, DeviceKindAndroid, DeviceKindDesktop, DeviceKindMobile:
+		return true
+	}
+}
+
+// Fields are the standard edge context fields with typed accessors.
+//
+// The zero value of each field means it's unknown.
+// Implementations shall keep the values they don't recognize
+// (e.g. a DeviceKind added in the future) as-is instead of dropping them,
+// and ignore the fields they don't recognize when parsing the headers,
+// so the services can be upgraded independently.
+type Fields struct {
+	// The kind of the device the request is from.
+	DeviceKind DeviceKind
+
+	// The ISO 3166-1 alpha-2 country code of the origin of the request.
+	OriginCountry string
+
+	// The time the account of the logged in user was created,
+	// zero when the user is not logged in.
+	AccountCreatedAt time.Time
+}
+
+// AccountAge returns the age of the account of the logged in user at now.
+//
+// It returns false when AccountCreatedAt is unknown.
+func (f Fields) AccountAge(now time.Time) (time.Duration, bool) {
+	if f.AccountCreatedAt.IsZero() {
+		return 0, false
+	}
+	return now.Sub(f.AccountCreatedAt), true
+}
+
+// Fielder is an optional interface edgecontext implementations can implement
+// to expose the standard fields with types.
+type Fielder interface {
+	// Fields returns the standard fields from the edge context attached to ctx.
+	//
+	// It shall return false when there's no edge context attached to ctx.
+	Fields(ctx context.Context) (Fields, bool)
+}
+
+// GetFields returns the standard fields from the edge context attached to ctx,
+// if impl implements Fielder.
+func GetFields(ctx context.Context, impl Interface) (Fields, bool) {
+	if fielder, ok := impl.(Fielder); ok {
+		return fielder.Fields(ctx)
+	}
+	return Fields{}, false
+}
@@ -0,0 +1,32 @@
+package ecinterface_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/ecinterface"
+)
+
+func TestFieldsAccountAge(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("unknown", func(t *testing.T) {
+		age, ok := ecinterface.Fields{}.AccountAge(now)
+		if ok {
+			t.Errorf("Expected unknown account age, got %v", age)
+		}
+	})
+
+	t.Run("known", func(t *testing.T) {
+		f := ecinterface.Fields{
+			AccountCreatedAt: now.Add(-time.Hour * 48),
+		}
+		age, ok := f.AccountAge(now)
+		if !ok {
+			t.Fatal("Expected known account age")
+		}
+		if age != time.Hour*48 {
+			t.Errorf("Expected account age %v, got %v", time.Hour*48, age)
+		}
+	})
+}
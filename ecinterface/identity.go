@@ -0,0 +1,28 @@
+package ecinterface
+
+import (
+	"context"
+)
+
+// Identifier is an optional interface edgecontext implementations can implement
+// to expose the authenticated caller of the request,
+// e.g. for per-user quotas.
+type Identifier interface {
+	// AuthenticatedID returns the id of the authenticated user of the edge
+	// context attached to ctx,
+	// or the id of the authenticated oauth client when there's no logged in
+	// user.
+	//
+	// It shall return false when the request is not authenticated,
+	// or there's no edge context attached to ctx.
+	AuthenticatedID(ctx context.Context) (string, bool)
+}
+
+// GetAuthenticatedID returns the id of the authenticated caller from the edge
+// context attached to ctx, if impl implements Identifier.
+func GetAuthenticatedID(ctx context.Context, impl Interface) (string, bool) {
+	if identifier, ok := impl.(Identifier); ok {
+		return identifier.AuthenticatedID(ctx)
+	}
+	return "", false
+}
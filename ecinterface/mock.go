@@ -6,6 +6,8 @@ import (
 
 type ecKey struct{}
 
+type claimsKey struct{}
+
 type ecImpl struct{}
 
 func (ecImpl) ContextToHeader(ctx context.Context) (string, bool) {
@@ -20,7 +22,28 @@ func (ecImpl) HeaderToContext(ctx context.Context, header string) (context.Conte
 	return context.WithValue(ctx, ecKey{}, header), nil
 }
 
+func (ecImpl) Claims(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+	return claims, ok
+}
+
+func (impl ecImpl) WithClaims(ctx context.Context, claims Claims) (context.Context, error) {
+	existing, _ := impl.Claims(ctx)
+	merged := make(Claims, len(existing)+len(claims))
+	for name, value := range existing {
+		merged[name] = value
+	}
+	for name, value := range claims {
+		merged[name] = value
+	}
+	return context.WithValue(ctx, claimsKey{}, merged), nil
+}
+
 // Mock creates a mocked Interface.
+//
+// The returned Interface also implements Claimer, allowing all the claims.
 func Mock() Interface {
 	return ecImpl{}
 }
+
+var _ Claimer = ecImpl{}
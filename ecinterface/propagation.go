@@ -0,0 +1,86 @@
+package ecinterface
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoHeader is the error returned by Carrier.Get when there's no edge
+// context header in the carrier.
+var ErrNoHeader = errors.New("ecinterface: no edge context header")
+
+// Carrier is the transport-specific storage of the edge context header,
+// used by Inject and Extract.
+//
+// The implementations are provided by the transport packages,
+// e.g. thriftbp.EdgeContextCarrier, httpbp.EdgeContextCarrier and
+// grpcbp.EdgeContextCarrier.
+// They take care of the header names and the encodings used by the transport,
+// so the header passed in and out of Carrier is always the raw header used by
+// Interface.
+type Carrier interface {
+	// Get returns the raw edge context header of the incoming request.
+	//
+	// It shall return ErrNoHeader when there's no edge context header,
+	// and other errors when the header is malformed
+	// (e.g. failed to decode from the transport encoding).
+	Get(ctx context.Context) (header string, err error)
+
+	// Set sets the raw edge context header to be sent with the outgoing
+	// requests.
+	//
+	// Carriers based on context (e.g. thrift headers) return the new context,
+	// others return ctx intact.
+	Set(ctx context.Context, header string) context.Context
+
+	// Unset removes the edge context header from the outgoing requests,
+	// so the incoming edge context header isn't forwarded.
+	Unset(ctx context.Context) context.Context
+}
+
+// Inject injects the edge context attached to ctx into carrier,
+// to be propagated to the outgoing requests.
+//
+// When there's no edge context attached to ctx,
+// the header is unset from carrier instead.
+//
+// If impl is nil, the global one from Get will be used instead.
+func Inject(ctx context.Context, impl Interface, carrier Carrier) context.Context {
+	if impl == nil {
+		impl = Get()
+	}
+	header, ok := impl.ContextToHeader(ctx)
+	if !ok {
+		return carrier.Unset(ctx)
+	}
+	return carrier.Set(ctx, header)
+}
+
+// Extract parses the edge context header from carrier and attaches the edge
+// context to ctx, and sets the sentry tags of it (see SetSentryTags).
+//
+// When there's no edge context header in carrier,
+// it returns ctx intact with nil error.
+// When the header is malformed,
+// it returns ctx intact with the error.
+//
+// If impl is nil, the global one from Get will be used instead.
+func Extract(ctx context.Context, impl Interface, carrier Carrier) (context.Context, error) {
+	header, err := carrier.Get(ctx)
+	if errors.Is(err, ErrNoHeader) {
+		return ctx, nil
+	}
+	if err != nil {
+		return ctx, err
+	}
+
+	if impl == nil {
+		impl = Get()
+	}
+	ecCtx, err := impl.HeaderToContext(ctx, header)
+	if err != nil {
+		return ctx, err
+	}
+	SetSentryTags(ecCtx, impl)
+	return ecCtx, nil
+}
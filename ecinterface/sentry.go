@@ -0,0 +1,30 @@
+package ecinterface
+
+import (
+	"context"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+// SentryTagger is an optional interface edgecontext implementations can
+// implement to report some of the edge context fields as sentry tags.
+//
+// Implementations must only return non-PII fields
+// (e.g. whether the user is logged in),
+// and hash the fields that are only useful for correlation
+// (e.g. device id, via log.HashSentryTag).
+type SentryTagger interface {
+	// SentryTags returns the tags from the edge context attached to ctx.
+	SentryTags(ctx context.Context) map[string]string
+}
+
+// SetSentryTags sets the tags from the edge context attached to ctx to the
+// sentry hub attached to ctx, if impl implements SentryTagger.
+//
+// It's called by thriftbp and httpbp after the edge context is initialized from
+// the request headers.
+func SetSentryTags(ctx context.Context, impl Interface) {
+	if tagger, ok := impl.(SentryTagger); ok {
+		log.SetSentryTags(ctx, tagger.SentryTags(ctx))
+	}
+}
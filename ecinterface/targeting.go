@@ -0,0 +1,40 @@
+package ecinterface
+
+import (
+	"context"
+)
+
+// TargetingAttributes are the edge context fields experiments can target on.
+type TargetingAttributes struct {
+	// The ISO 3166-1 alpha-2 country code of the origin of the request,
+	// empty if unknown.
+	CountryCode string
+
+	// Whether the user is logged in.
+	LoggedIn bool
+
+	// The kind of the device the request is from (e.g. "ios", "android",
+	// "desktop"), empty if unknown.
+	DeviceKind string
+}
+
+// Targeter is an optional interface edgecontext implementations can implement
+// to support targeting experiments on the edge context fields.
+//
+// Implementations must only return non-PII fields.
+type Targeter interface {
+	// TargetingAttributes returns the attributes from the edge context attached
+	// to ctx.
+	//
+	// It shall return false when there's no edge context attached to ctx.
+	TargetingAttributes(ctx context.Context) (TargetingAttributes, bool)
+}
+
+// GetTargetingAttributes returns the targeting attributes from the edge context
+// attached to ctx, if impl implements Targeter.
+func GetTargetingAttributes(ctx context.Context, impl Interface) (TargetingAttributes, bool) {
+	if targeter, ok := impl.(Targeter); ok {
+		return targeter.TargetingAttributes(ctx)
+	}
+	return TargetingAttributes{}, false
+}
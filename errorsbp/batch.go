@@ -16,10 +16,23 @@ var (
 //
 // The zero value of Batch is valid (with no errors) and ready to use.
 //
+// Batch also implements the Unwrap() []error interface of multi-errors
+// introduced in go 1.20, so it works with any library that supports errors
+// created by errors.Join.
+//
+// By default a Batch keeps all the errors added to it.
+// To avoid huge batches producing huge error messages,
+// use SetDeduplicate and/or SetMaxSize.
+//
 // This type is not thread-safe.
 // The same batch should not be operated on different goroutines concurrently.
 type Batch struct {
 	errors []error
+
+	deduplicate bool
+	seen        map[string]struct{}
+	maxSize     int
+	omitted     int
 }
 
 func (be Batch) Error() string {
@@ -27,7 +40,7 @@ func (be Batch) Error() string {
 	fmt.Fprintf(
 		&sb,
 		"errorsbp.Batch: total %d error(s) in this batch",
-		len(be.errors),
+		len(be.errors)+be.omitted,
 	)
 	for i, err := range be.errors {
 		if i == 0 {
@@ -37,9 +50,44 @@ func (be Batch) Error() string {
 		}
 		fmt.Fprintf(&sb, "%+v", err)
 	}
+	if be.omitted > 0 {
+		fmt.Fprintf(&sb, "; and %d more error(s) omitted", be.omitted)
+	}
 	return sb.String()
 }
 
+// Unwrap returns the errors in the batch,
+// implementing the multi-error interface of go 1.20+.
+//
+// The errors omitted because of SetMaxSize are not included.
+func (be Batch) Unwrap() []error {
+	return be.GetErrors()
+}
+
+// SetDeduplicate sets whether the errors with the same message as an error
+// already in the batch are skipped by Add and AddPrefix.
+//
+// It only affects the errors added after it's called.
+func (be *Batch) SetDeduplicate(deduplicate bool) {
+	be.deduplicate = deduplicate
+}
+
+// SetMaxSize sets the max number of the errors kept in the batch.
+//
+// The errors added after the batch is full are omitted,
+// only counted in the error message as "and N more error(s) omitted".
+// Non-positive size means no limit, which is the default.
+//
+// It only affects the errors added after it's called.
+func (be *Batch) SetMaxSize(size int) {
+	be.maxSize = size
+}
+
+// Omitted returns the number of the errors omitted because of SetMaxSize.
+func (be Batch) Omitted() int {
+	return be.omitted
+}
+
 // As implements helper interface for errors.As.
 //
 // If v is pointer to either Batch or *Batch,
@@ -86,7 +134,30 @@ func (be Batch) Is(target error) bool {
 }
 
 func (be *Batch) addBatch(batch Batch) {
-	be.errors = append(be.errors, batch.errors...)
+	for _, err := range batch.errors {
+		be.addSingle(err)
+	}
+	be.omitted += batch.omitted
+}
+
+// addSingle adds a single non-nil, non-Batch error into the batch,
+// honoring the deduplicate and max size settings.
+func (be *Batch) addSingle(err error) {
+	if be.deduplicate {
+		msg := err.Error()
+		if _, ok := be.seen[msg]; ok {
+			return
+		}
+		if be.seen == nil {
+			be.seen = make(map[string]struct{})
+		}
+		be.seen[msg] = struct{}{}
+	}
+	if be.maxSize > 0 && len(be.errors) >= be.maxSize {
+		be.omitted++
+		return
+	}
+	be.errors = append(be.errors, err)
 }
 
 // Add adds errors into the batch.
@@ -105,7 +176,7 @@ func (be *Batch) Add(errs ...error) {
 		if errors.As(err, &batch) {
 			be.addBatch(batch)
 		} else {
-			be.errors = append(be.errors, err)
+			be.addSingle(err)
 		}
 	}
 }
@@ -130,7 +201,7 @@ func (be *Batch) AddPrefix(prefix string, errs ...error) {
 	}
 
 	appendSingle := func(err error) {
-		be.errors = append(be.errors, prefixError(prefix, err))
+		be.addSingle(prefixError(prefix, err))
 	}
 
 	for _, err := range errs {
@@ -143,6 +214,7 @@ func (be *Batch) AddPrefix(prefix string, errs ...error) {
 			for _, err := range batch.errors {
 				appendSingle(err)
 			}
+			be.omitted += batch.omitted
 		} else {
 			appendSingle(err)
 		}
@@ -153,15 +225,15 @@ func (be *Batch) AddPrefix(prefix string, errs ...error) {
 //
 // If the batch contains zero errors, Compile returns nil.
 //
-// If the batch contains exactly one error,
+// If the batch contains exactly one error (and none omitted),
 // that underlying error will be returned.
 //
 // Otherwise, the batch itself will be returned.
 func (be Batch) Compile() error {
-	switch len(be.errors) {
-	case 0:
+	switch {
+	case len(be.errors) == 0 && be.omitted == 0:
 		return nil
-	case 1:
+	case len(be.errors) == 1 && be.omitted == 0:
 		return be.errors[0]
 	default:
 		return be
@@ -169,8 +241,12 @@ func (be Batch) Compile() error {
 }
 
 // Clear clears the batch.
+//
+// The deduplicate and max size settings are kept.
 func (be *Batch) Clear() {
 	be.errors = nil
+	be.seen = nil
+	be.omitted = 0
 }
 
 // GetErrors returns a copy of the underlying error(s).
@@ -265,3 +265,83 @@ func TestAddPrefix(t *testing.T) {
 		}
 	}
 }
+
+type multiError interface {
+	Unwrap() []error
+}
+
+func TestUnwrapMulti(t *testing.T) {
+	err0 := errors.New("foo")
+	err1 := errors.New("bar")
+	var batch errorsbp.Batch
+	batch.Add(err0, err1)
+
+	var me multiError
+	if !errors.As(batch.Compile(), &me) {
+		t.Fatalf("Expected Batch to implement Unwrap() []error")
+	}
+	if got, expected := me.Unwrap(), []error{err0, err1}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestDeduplicate(t *testing.T) {
+	var batch errorsbp.Batch
+	batch.SetDeduplicate(true)
+	batch.Add(errors.New("foo"), errors.New("bar"), errors.New("foo"))
+	batch.AddPrefix("prefix", errors.New("foo"), errors.New("foo"))
+
+	var another errorsbp.Batch
+	another.Add(errors.New("bar"), errors.New("fizz"))
+	batch.Add(another)
+
+	expected := "errorsbp.Batch: total 4 error(s) in this batch: foo; bar; prefix: foo; fizz"
+	if got := batch.Error(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	batch.Clear()
+	batch.Add(errors.New("foo"), errors.New("foo"))
+	if got := len(batch.GetErrors()); got != 1 {
+		t.Errorf("Expected deduplication to be kept after Clear, got %d errors", got)
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	var batch errorsbp.Batch
+	batch.SetMaxSize(2)
+	batch.Add(errors.New("foo"), errors.New("bar"), errors.New("fizz"))
+	batch.AddPrefix("prefix", errors.New("buzz"))
+
+	if got := len(batch.GetErrors()); got != 2 {
+		t.Errorf("Expected 2 errors kept, got %d", got)
+	}
+	if got := batch.Omitted(); got != 2 {
+		t.Errorf("Expected 2 errors omitted, got %d", got)
+	}
+	expected := "errorsbp.Batch: total 4 error(s) in this batch: foo; bar; and 2 more error(s) omitted"
+	if got := batch.Error(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	// The omitted errors carry over when adding the batch into another one.
+	var another errorsbp.Batch
+	another.Add(batch)
+	if got := another.Omitted(); got != 2 {
+		t.Errorf("Expected 2 errors omitted, got %d", got)
+	}
+
+	// Compile doesn't lose the omitted errors.
+	var single errorsbp.Batch
+	single.SetMaxSize(1)
+	single.Add(errors.New("foo"), errors.New("bar"))
+	var b errorsbp.Batch
+	if !errors.As(single.Compile(), &b) {
+		t.Errorf("Expected Compile to return the batch with omitted errors, got %v", single.Compile())
+	}
+
+	batch.Clear()
+	if err := batch.Compile(); err != nil {
+		t.Errorf("Expected nil after Clear, got %v", err)
+	}
+}
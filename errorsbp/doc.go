@@ -53,4 +53,16 @@
 //     func MyThriftSuppressor(err error) bool {
 //         return errors.As(err, new(*mythrift.MyThriftErrorType))
 //     }
+//
+// Registry
+//
+// Registry maps errors to categories (retryable, client fault, server fault,
+// resource exhausted), so that the classification of the errors can be defined
+// once per service, and consulted by retrybp filters (retrybp.RegistryFilter),
+// Suppressors (Registry.Suppressor), and other middlewares:
+//
+//     func init() {
+//         errorsbp.RegisterType(new(*mythrift.InvalidRequest), errorsbp.CategoryClientFault)
+//         errorsbp.RegisterError(ErrOverloaded, errorsbp.CategoryResourceExhausted)
+//     }
 package errorsbp
@@ -0,0 +1,176 @@
+package errorsbp
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// Category is the category of an error, used to decide how to handle it
+// (retry it, suppress it from the span errors, etc.).
+type Category int
+
+// Category values.
+const (
+	// CategoryUnknown means no Classifier can decide the category of the error.
+	CategoryUnknown Category = iota
+
+	// CategoryRetryable is a transient error, the same request could succeed
+	// when retried.
+	CategoryRetryable
+
+	// CategoryClientFault is an error caused by the caller,
+	// e.g. an invalid request, retrying it won't help.
+	CategoryClientFault
+
+	// CategoryServerFault is an error caused by the server,
+	// retrying it is not known to be safe or helpful.
+	CategoryServerFault
+
+	// CategoryResourceExhausted is an error caused by running out of some
+	// resource, e.g. rate limited or overloaded, retrying it without backing off
+	// makes it worse.
+	CategoryResourceExhausted
+)
+
+func (c Category) String() string {
+	switch c {
+	default:
+		return "unknown"
+	case CategoryRetryable:
+		return "retryable"
+	case CategoryClientFault:
+		return "client_fault"
+	case CategoryServerFault:
+		return "server_fault"
+	case CategoryResourceExhausted:
+		return "resource_exhausted"
+	}
+}
+
+// Classifier defines a type of function that returns the Category of an error.
+//
+// The implementation shall return CategoryUnknown on the errors it doesn't
+// know about.
+type Classifier func(err error) Category
+
+// Registry is a list of Classifiers consulted in the order they are registered.
+//
+// It allows the classification of the errors to be defined once per service,
+// and used by retrybp filters (retrybp.RegistryFilter),
+// Suppressors (Registry.Suppressor), and other middlewares.
+//
+// Registry is safe to be used concurrently.
+// The zero value is an empty Registry ready to use.
+type Registry struct {
+	lock        sync.RWMutex
+	classifiers []Classifier
+}
+
+// DefaultRegistry is the Registry used by the package level Register* and
+// Classify functions.
+var DefaultRegistry = new(Registry)
+
+// Register adds a Classifier to the registry.
+func (r *Registry) Register(c Classifier) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.classifiers = append(r.classifiers, c)
+}
+
+// RegisterError classifies the errors matching target via errors.Is as
+// category.
+func (r *Registry) RegisterError(target error, category Category) {
+	r.Register(func(err error) Category {
+		if errors.Is(err, target) {
+			return category
+		}
+		return CategoryUnknown
+	})
+}
+
+// RegisterType classifies the errors matching the type of target via
+// errors.As as category.
+//
+// Like the second arg of errors.As,
+// target must be a non-nil pointer to a type implementing error or to any
+// interface type, e.g. new(*MyError), otherwise RegisterType panics.
+func (r *Registry) RegisterType(target interface{}, category Category) {
+	typ := reflect.TypeOf(target)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		panic("errorsbp: RegisterType target must be a non-nil pointer")
+	}
+	elem := typ.Elem()
+	// Check target is valid for errors.As upfront instead of on the first
+	// classification.
+	errors.As(errors.New(""), reflect.New(elem).Interface())
+	r.Register(func(err error) Category {
+		// Use a new target for every call to be safe for concurrent use.
+		if errors.As(err, reflect.New(elem).Interface()) {
+			return category
+		}
+		return CategoryUnknown
+	})
+}
+
+// Classify returns the category decided by the first Classifier that doesn't
+// return CategoryUnknown on err.
+//
+// It returns CategoryUnknown if err is nil or none of them can decide.
+//
+// Classify is nil-safe, a nil *Registry classifies all errors as
+// CategoryUnknown.
+func (r *Registry) Classify(err error) Category {
+	if r == nil || err == nil {
+		return CategoryUnknown
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for _, c := range r.classifiers {
+		if category := c(err); category != CategoryUnknown {
+			return category
+		}
+	}
+	return CategoryUnknown
+}
+
+// Suppressor returns a Suppressor that suppresses the errors classified as any
+// of the given categories.
+//
+// For example, to not treat the errors caused by the callers as span errors:
+//
+//	registry.Suppressor(errorsbp.CategoryClientFault)
+func (r *Registry) Suppressor(categories ...Category) Suppressor {
+	return func(err error) bool {
+		category := r.Classify(err)
+		if category == CategoryUnknown {
+			return false
+		}
+		for _, c := range categories {
+			if c == category {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Register adds a Classifier to DefaultRegistry.
+func Register(c Classifier) {
+	DefaultRegistry.Register(c)
+}
+
+// RegisterError calls RegisterError of DefaultRegistry.
+func RegisterError(target error, category Category) {
+	DefaultRegistry.RegisterError(target, category)
+}
+
+// RegisterType calls RegisterType of DefaultRegistry.
+func RegisterType(target interface{}, category Category) {
+	DefaultRegistry.RegisterType(target, category)
+}
+
+// Classify classifies err with DefaultRegistry.
+func Classify(err error) Category {
+	return DefaultRegistry.Classify(err)
+}
@@ -0,0 +1,116 @@
+package errorsbp_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/reddit/baseplate.go/errorsbp"
+)
+
+type clientError struct{}
+
+func (*clientError) Error() string {
+	return "client error"
+}
+
+var errOverloaded = errors.New("overloaded")
+
+func TestRegistry(t *testing.T) {
+	var r errorsbp.Registry
+	r.RegisterError(errOverloaded, errorsbp.CategoryResourceExhausted)
+	r.RegisterType(new(*clientError), errorsbp.CategoryClientFault)
+	r.Register(func(err error) errorsbp.Category {
+		if err.Error() == "flaky" {
+			return errorsbp.CategoryRetryable
+		}
+		return errorsbp.CategoryUnknown
+	})
+
+	for _, c := range []struct {
+		label    string
+		err      error
+		expected errorsbp.Category
+	}{
+		{
+			label:    "nil",
+			err:      nil,
+			expected: errorsbp.CategoryUnknown,
+		},
+		{
+			label:    "unknown",
+			err:      errors.New("foo"),
+			expected: errorsbp.CategoryUnknown,
+		},
+		{
+			label:    "error",
+			err:      errOverloaded,
+			expected: errorsbp.CategoryResourceExhausted,
+		},
+		{
+			label:    "wrapped-error",
+			err:      fmt.Errorf("foo: %w", errOverloaded),
+			expected: errorsbp.CategoryResourceExhausted,
+		},
+		{
+			label:    "type",
+			err:      fmt.Errorf("foo: %w", new(clientError)),
+			expected: errorsbp.CategoryClientFault,
+		},
+		{
+			label:    "classifier",
+			err:      errors.New("flaky"),
+			expected: errorsbp.CategoryRetryable,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			if got := r.Classify(c.err); got != c.expected {
+				t.Errorf("Expected %v, got %v", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestRegistryOrder(t *testing.T) {
+	var r errorsbp.Registry
+	r.RegisterError(errOverloaded, errorsbp.CategoryResourceExhausted)
+	r.RegisterError(errOverloaded, errorsbp.CategoryRetryable)
+
+	if got, expected := r.Classify(errOverloaded), errorsbp.CategoryResourceExhausted; got != expected {
+		t.Errorf("Expected the first registered category %v, got %v", expected, got)
+	}
+}
+
+func TestRegistryNil(t *testing.T) {
+	var r *errorsbp.Registry
+	if got := r.Classify(errOverloaded); got != errorsbp.CategoryUnknown {
+		t.Errorf("Expected %v, got %v", errorsbp.CategoryUnknown, got)
+	}
+}
+
+func TestRegisterTypeInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected RegisterType to panic")
+		}
+	}()
+	var r errorsbp.Registry
+	r.RegisterType(clientError{}, errorsbp.CategoryClientFault)
+}
+
+func TestRegistrySuppressor(t *testing.T) {
+	var r errorsbp.Registry
+	r.RegisterType(new(*clientError), errorsbp.CategoryClientFault)
+	r.RegisterError(errOverloaded, errorsbp.CategoryResourceExhausted)
+	s := r.Suppressor(errorsbp.CategoryClientFault)
+
+	if !s.Suppress(new(clientError)) {
+		t.Error("Expected client fault to be suppressed")
+	}
+	if s.Suppress(errOverloaded) {
+		t.Error("Expected resource exhausted not to be suppressed")
+	}
+	if s.Suppress(errors.New("foo")) {
+		t.Error("Expected unknown error not to be suppressed")
+	}
+}
@@ -0,0 +1,110 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/reddit/baseplate.go/retrybp"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+// CollectorUserAgent is the User-Agent header used by CollectorSender.
+const CollectorUserAgent = "baseplate.go-event-publisher/1.0"
+
+// Signer signs the uncompressed payload of a batch,
+// and returns the value of the X-Signature header.
+type Signer func(payload []byte) (string, error)
+
+// HMACSigner returns a Signer signing the payloads with HMAC-SHA256,
+// using the current version of the versioned secret at path in store.
+//
+// The signatures are in the format of "key=<keyName>, mac=<hex digest>",
+// expected by the event collector.
+func HMACSigner(store *secrets.Store, keyName, path string) Signer {
+	return func(payload []byte) (string, error) {
+		secret, err := store.GetVersionedSecret(path)
+		if err != nil {
+			return "", err
+		}
+		mac := hmac.New(sha256.New, secret.Current)
+		mac.Write(payload)
+		return fmt.Sprintf("key=%s, mac=%s", keyName, hex.EncodeToString(mac.Sum(nil))), nil
+	}
+}
+
+// CollectorSender is a Sender sending the batches to the event collector over
+// HTTP.
+type CollectorSender struct {
+	// Required. The URL of the event collector endpoint.
+	URL string
+
+	// Optional. Defaults to an http.Client with 5s timeout.
+	Client *http.Client
+}
+
+var defaultCollectorClient = &http.Client{
+	Timeout: time.Second * 5,
+}
+
+// CollectorError is the error returned by CollectorSender when the event
+// collector responded with a non-2xx status code.
+type CollectorError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e CollectorError) Error() string {
+	return fmt.Sprintf("events: collector returned status code %d: %s", e.StatusCode, e.Body)
+}
+
+// Send implements Sender.
+//
+// The 4xx errors (other than 429) are not retried.
+func (s CollectorSender) Send(ctx context.Context, batch Batch) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(batch.Payload))
+	if err != nil {
+		return retrybp.Unrecoverable(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", CollectorUserAgent)
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	if batch.Encoding != "" {
+		req.Header.Set("Content-Encoding", batch.Encoding)
+	}
+	if batch.Signature != "" {
+		req.Header.Set("X-Signature", batch.Signature)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = defaultCollectorClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = CollectorError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+	}
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return retrybp.Unrecoverable(err)
+	}
+	return err
+}
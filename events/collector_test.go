@@ -0,0 +1,105 @@
+package events_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/reddit/baseplate.go/events"
+	"github.com/reddit/baseplate.go/retrybp"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+func TestHMACSigner(t *testing.T) {
+	const path = "secret/event-publisher/key"
+	store, _, err := secrets.NewTestSecrets(context.Background(), map[string]secrets.GenericSecret{
+		path: {
+			Type:     "versioned",
+			Current:  "aGVsbG8=",
+			Encoding: secrets.Base64Encoding,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := events.HMACSigner(store, "EventKey", path)
+	signature, err := signer([]byte("[]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, []byte("hello"))
+	mac.Write([]byte("[]"))
+	expected := "key=EventKey, mac=" + hex.EncodeToString(mac.Sum(nil))
+	if signature != expected {
+		t.Errorf("Expected signature %q, got %q", expected, signature)
+	}
+
+	if _, err := events.HMACSigner(store, "EventKey", "secret/nope")(nil); err == nil {
+		t.Error("Expected error for missing secret, got nil")
+	}
+}
+
+func TestCollectorSender(t *testing.T) {
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		for header, expected := range map[string]string{
+			"Content-Type":     "application/json",
+			"Content-Encoding": "gzip",
+			"X-Signature":      "key=k, mac=m",
+			"User-Agent":       events.CollectorUserAgent,
+		} {
+			if got := r.Header.Get(header); got != expected {
+				t.Errorf("Expected %s header %q, got %q", header, expected, got)
+			}
+		}
+		if string(body) != "payload" {
+			t.Errorf("Expected body %q, got %q", "payload", body)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sender := events.CollectorSender{URL: server.URL}
+	batch := events.Batch{
+		Payload:   []byte("payload"),
+		Encoding:  "gzip",
+		Signature: "key=k, mac=m",
+		Count:     1,
+	}
+	for _, c := range []struct {
+		status      int
+		err         bool
+		unretryable bool
+	}{
+		{status: http.StatusNoContent},
+		{status: http.StatusBadRequest, err: true, unretryable: true},
+		{status: http.StatusTooManyRequests, err: true},
+		{status: http.StatusServiceUnavailable, err: true},
+	} {
+		t.Run(http.StatusText(c.status), func(t *testing.T) {
+			status = c.status
+			err := sender.Send(context.Background(), batch)
+			if !c.err {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var ce events.CollectorError
+			if !errors.As(err, &ce) || ce.StatusCode != c.status {
+				t.Errorf("Expected CollectorError with status %d, got %v", c.status, err)
+			}
+			var re retrybp.RetryableError
+			if unretryable := errors.As(err, &re) && re.Retryable() < 0; unretryable != c.unretryable {
+				t.Errorf("Expected unretryable %v, got %v", c.unretryable, unretryable)
+			}
+		})
+	}
+}
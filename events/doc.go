@@ -1,6 +1,10 @@
 // Package events implements event publisher per baseplate spec.
 //
-// This package is mainly just the serialization part.
+// Queue is mainly just the serialization part.
 // The actual publishing part is handled by sidecar implemented by baseplate.py,
 // and communicated via mqsend package.
+//
+// Publisher publishes the events directly from the service in batches,
+// with compression and retries, without the need of the sidecar.
+// Use it with CollectorSender to publish the events to the event collector.
 package events
@@ -56,6 +56,12 @@ type Config struct {
 	// If it <=0 or > MaxQueueSize (the constant, 10000),
 	// MaxQueueSize constant will be used instead.
 	MaxQueueSize int64 `yaml:"maxQueueSize"`
+
+	// Transport chooses how the events are sent to the sidecar,
+	// for the platforms without posix message queues (e.g. macOS).
+	//
+	// Optional, defaults to posix message queue.
+	Transport mqsend.TransportConfig `yaml:"transport"`
 }
 
 // V2 initializes a new v2 event queue with default configurations.
@@ -76,6 +82,7 @@ func V2WithConfig(cfg Config) (*Queue, error) {
 		Name:           QueueNamePrefix + name,
 		MaxQueueSize:   cfg.MaxQueueSize,
 		MaxMessageSize: MaxEventSize,
+		Transport:      cfg.Transport,
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,171 @@
+package events
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Label names of the Prometheus metrics reported by Publisher.
+const (
+	PrometheusQueueLabel       = "events_queue"
+	PrometheusDropReasonLabel  = "events_drop_reason"
+	PrometheusSuccessLabel     = "events_success"
+	PrometheusEvictReasonLabel = "events_evict_reason"
+	PrometheusTypeLabel        = "events_type"
+)
+
+// Values of the PrometheusDropReasonLabel label.
+const (
+	dropReasonQueueFull     = "queue_full"
+	dropReasonTooLarge      = "too_large"
+	dropReasonPublishFailed = "publish_failed"
+)
+
+// Values of the PrometheusEvictReasonLabel label.
+const (
+	evictReasonAge  = "age"
+	evictReasonSize = "size"
+)
+
+var (
+	queueDepthGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "events_queue_depth",
+		Help: "Number of the events put but not yet published or dropped",
+	}, []string{
+		PrometheusQueueLabel,
+	})
+
+	droppedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_dropped_total",
+		Help: "Number of the dropped events",
+	}, []string{
+		PrometheusQueueLabel,
+		PrometheusDropReasonLabel,
+	})
+
+	sampledOutCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_sampled_out_total",
+		Help: "Number of the events not published because of sampling",
+	}, []string{
+		PrometheusQueueLabel,
+		PrometheusTypeLabel,
+	})
+
+	publishedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_published_total",
+		Help: "Number of the published events",
+	}, []string{
+		PrometheusQueueLabel,
+	})
+
+	batchesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_batches_total",
+		Help: "Number of the batches published (or failed to be published)",
+	}, []string{
+		PrometheusQueueLabel,
+		PrometheusSuccessLabel,
+	})
+
+	publishLatencyHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "events_publish_latency_seconds",
+		Help:    "Latency of publishing the batches, including the retries",
+		Buckets: prometheus.DefBuckets,
+	}, []string{
+		PrometheusQueueLabel,
+		PrometheusSuccessLabel,
+	})
+
+	spooledCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_spooled_total",
+		Help: "Number of the events persisted in the spool after failing to be published",
+	}, []string{
+		PrometheusQueueLabel,
+	})
+
+	spoolRecoveredCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_spool_recovered_total",
+		Help: "Number of the spooled events published on replay",
+	}, []string{
+		PrometheusQueueLabel,
+	})
+
+	spoolExpiredCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_spool_expired_total",
+		Help: "Number of the spooled events evicted before being replayed",
+	}, []string{
+		PrometheusQueueLabel,
+		PrometheusEvictReasonLabel,
+	})
+
+	spoolEventsGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "events_spool_events",
+		Help: "Number of the events in the spool",
+	}, []string{
+		PrometheusQueueLabel,
+	})
+
+	spoolBytesGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "events_spool_bytes",
+		Help: "Total size of the batches in the spool",
+	}, []string{
+		PrometheusQueueLabel,
+	})
+)
+
+type publisherMetrics struct {
+	name      string
+	depth     prometheus.Gauge
+	published prometheus.Counter
+}
+
+func newPublisherMetrics(name string) publisherMetrics {
+	return publisherMetrics{
+		name:      name,
+		depth:     queueDepthGauge.WithLabelValues(name),
+		published: publishedCounter.WithLabelValues(name),
+	}
+}
+
+func (m publisherMetrics) dropped(reason string, n int) {
+	droppedCounter.WithLabelValues(m.name, reason).Add(float64(n))
+}
+
+func (m publisherMetrics) sampledOut(eventType string) {
+	sampledOutCounter.WithLabelValues(m.name, eventType).Inc()
+}
+
+func (m publisherMetrics) batch(latency time.Duration, success bool) {
+	labels := []string{m.name, strconv.FormatBool(success)}
+	batchesCounter.WithLabelValues(labels...).Inc()
+	publishLatencyHistogram.WithLabelValues(labels...).Observe(latency.Seconds())
+}
+
+type spoolMetrics struct {
+	name      string
+	spooled   prometheus.Counter
+	recovered prometheus.Counter
+	events    prometheus.Gauge
+	bytes     prometheus.Gauge
+}
+
+func newSpoolMetrics(name string) spoolMetrics {
+	return spoolMetrics{
+		name:      name,
+		spooled:   spooledCounter.WithLabelValues(name),
+		recovered: spoolRecoveredCounter.WithLabelValues(name),
+		events:    spoolEventsGauge.WithLabelValues(name),
+		bytes:     spoolBytesGauge.WithLabelValues(name),
+	}
+}
+
+func (m spoolMetrics) add(events int, bytes int64) {
+	m.events.Add(float64(events))
+	m.bytes.Add(float64(bytes))
+}
+
+func (m spoolMetrics) expired(reason string, n int) {
+	spoolExpiredCounter.WithLabelValues(m.name, reason).Add(float64(n))
+}
@@ -0,0 +1,543 @@
+package events
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/avast/retry-go"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/randbp"
+	"github.com/reddit/baseplate.go/retrybp"
+)
+
+// Default values of PublisherConfig.
+const (
+	DefaultMaxBatchBytes   = 500 * 1024
+	DefaultFlushInterval   = time.Second
+	DefaultMaxAttempts     = 5
+	DefaultRetryBackoff    = time.Millisecond * 100
+	DefaultMaxRetryBackoff = time.Second * 5
+)
+
+// Allowed Compression values
+const (
+	CompressionGzip = "gzip"
+	CompressionNone = "none"
+)
+
+var (
+	// ErrPublisherClosed is returned by Publisher.Put after Close is called.
+	ErrPublisherClosed = errors.New("events: publisher is closed")
+
+	// ErrQueueFull is returned by Publisher.Put when the event is dropped
+	// because the queue is full.
+	ErrQueueFull = errors.New("events: queue is full")
+
+	// ErrEventTooLarge is returned by Publisher.Put when the serialized event
+	// is larger than MaxEventSize.
+	ErrEventTooLarge = errors.New("events: event is too large")
+
+	// ErrCompressionInvalid is returned by NewPublisher when an invalid
+	// compression is specified.
+	ErrCompressionInvalid = errors.New("events: Compression is invalid")
+)
+
+// PublisherConfig is the config used to initialize a Publisher.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//	events:
+//	  name: v2
+//	  maxPutTimeout: 10ms
+//	  maxBatchBytes: 512000
+//	  flushInterval: 1s
+//	  compression: gzip
+//	  maxAttempts: 5
+//	  spool:
+//	    dir: /var/spool/events
+//	    maxBytes: 104857600
+//	    maxAge: 24h
+//	  sampling:
+//	    rates:
+//	      ScreenviewEvent: 0.01
+type PublisherConfig struct {
+	// Optional. The name of the publisher, used in the metrics.
+	// Defaults to "v2".
+	Name string `yaml:"name"`
+
+	// Optional. The max number of the events buffered in memory waiting to be
+	// published. Defaults to MaxQueueSize (the constant, 10000).
+	MaxQueueSize int `yaml:"maxQueueSize"`
+
+	// Optional. The max time Put waits for the space in a full queue.
+	//
+	// If the passed in context object already has an earlier deadline set,
+	// that deadline will be respected instead.
+	//
+	// If MaxPutTimeout <= 0,
+	// Put function would run in non-blocking mode,
+	// that it fails immediately if the queue is full.
+	MaxPutTimeout time.Duration `yaml:"maxPutTimeout"`
+
+	// Optional. The max size of the uncompressed payload of a batch in bytes.
+	// Defaults to DefaultMaxBatchBytes.
+	//
+	// A batch always contains at least one event,
+	// so a batch with a single event could be larger than it.
+	MaxBatchBytes int `yaml:"maxBatchBytes"`
+
+	// Optional. The max time an event waits in a batch before the batch is
+	// published. Defaults to DefaultFlushInterval.
+	FlushInterval time.Duration `yaml:"flushInterval"`
+
+	// Optional. Defaults to "gzip". Valid values are "gzip" and "none".
+	Compression string `yaml:"compression"`
+
+	// Optional. The max number of attempts to publish a batch,
+	// after that the events in the batch are dropped
+	// (or spooled, if Spool is enabled).
+	// Defaults to DefaultMaxAttempts.
+	MaxAttempts int `yaml:"maxAttempts"`
+
+	// Optional. The initial and max backoff between the attempts to publish a
+	// batch, the backoff doubles after each attempt.
+	// Default to DefaultRetryBackoff and DefaultMaxRetryBackoff.
+	RetryBackoff    time.Duration `yaml:"retryBackoff"`
+	MaxRetryBackoff time.Duration `yaml:"maxRetryBackoff"`
+
+	// Optional. Used to sign the batches.
+	Signer Signer `yaml:"-"`
+
+	// Optional. The on-disk spool for the batches failed to be published,
+	// disabled by default.
+	Spool SpoolConfig `yaml:"spool"`
+
+	// Optional. The per event type sample rates, all the events are published
+	// by default.
+	Sampling SamplingConfig `yaml:"sampling"`
+
+	// Optional. When set, the sample rates are read from it instead of
+	// Sampling, so they can be updated without restarting the service.
+	//
+	// Its Get must return SamplingConfig, e.g. the one returned by
+	// WatchSampling.
+	SamplingWatcher filewatcher.FileWatcher `yaml:"-"`
+
+	// Optional. Returns the type of the events for sampling.
+	// Defaults to EventType.
+	EventType func(event thrift.TStruct) string `yaml:"-"`
+}
+
+// Batch is a batch of serialized events to be sent by a Sender.
+type Batch struct {
+	// Payload is the JSON array of the serialized events,
+	// compressed with Encoding.
+	Payload []byte
+
+	// Encoding is the compression of Payload, e.g. "gzip".
+	// It's empty when Payload is not compressed.
+	Encoding string
+
+	// Signature is the signature of the uncompressed Payload,
+	// or empty if PublisherConfig.Signer is nil.
+	Signature string
+
+	// Count is the number of the events in the batch.
+	Count int
+}
+
+// Sender sends the batches of events to the downstream pipeline.
+//
+// Errors returned by Send are retried with backoff, unless they are wrapped by
+// retrybp.Unrecoverable.
+type Sender interface {
+	Send(ctx context.Context, batch Batch) error
+}
+
+// Publisher publishes events in batches.
+//
+// Put only serializes the event and puts it into an in-memory queue.
+// The events in the queue are published by a background goroutine in batches,
+// with the failed publishes retried with backoff.
+//
+// The following Prometheus metrics are reported with "events_queue" label:
+//
+// - events_queue_depth: number of the events put but not yet published,
+// spooled or dropped.
+//
+// - events_dropped_total: number of the dropped events, by
+// "events_drop_reason".
+//
+// - events_published_total: number of the published events.
+//
+// - events_sampled_out_total: number of the events not published because of
+// sampling, by "events_type".
+//
+// - events_batches_total and events_publish_latency_seconds: number and
+// latency (including retries) of the published batches, by "events_success".
+//
+// With the spool enabled (see SpoolConfig), the following metrics are also
+// reported:
+//
+// - events_spooled_total: number of the events persisted in the spool.
+//
+// - events_spool_recovered_total: number of the spooled events published on
+// replay.
+//
+// - events_spool_expired_total: number of the spooled events evicted, by
+// "events_evict_reason" ("age" or "size").
+//
+// - events_spool_events and events_spool_bytes: number and total size of the
+// events in the spool.
+type Publisher struct {
+	cfg    PublisherConfig
+	sender Sender
+	spool  *spool
+
+	events chan []byte
+	lock   sync.RWMutex
+	closed bool
+	done   chan struct{}
+
+	metrics publisherMetrics
+}
+
+// NewPublisher creates a Publisher publishing the events via sender.
+//
+// It's necessary to call Close on the publisher to flush the queued events
+// before it goes out of scope.
+func NewPublisher(cfg PublisherConfig, sender Sender) (*Publisher, error) {
+	if cfg.Name == "" {
+		cfg.Name = DefaultV2Name
+	}
+	if cfg.MaxQueueSize <= 0 {
+		cfg.MaxQueueSize = MaxQueueSize
+	}
+	if cfg.MaxBatchBytes <= 0 {
+		cfg.MaxBatchBytes = DefaultMaxBatchBytes
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+	switch cfg.Compression {
+	case "":
+		cfg.Compression = CompressionGzip
+	case CompressionGzip, CompressionNone:
+	default:
+		return nil, ErrCompressionInvalid
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = DefaultRetryBackoff
+	}
+	if cfg.MaxRetryBackoff <= 0 {
+		cfg.MaxRetryBackoff = DefaultMaxRetryBackoff
+	}
+	if cfg.EventType == nil {
+		cfg.EventType = EventType
+	}
+
+	p := &Publisher{
+		cfg:     cfg,
+		sender:  sender,
+		events:  make(chan []byte, cfg.MaxQueueSize),
+		done:    make(chan struct{}),
+		metrics: newPublisherMetrics(cfg.Name),
+	}
+	if cfg.Spool.Dir != "" {
+		var err error
+		p.spool, err = openSpool(cfg.Spool, cfg.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	go p.run()
+	return p, nil
+}
+
+// Put serializes and puts an event into the queue to be published.
+//
+// If the type of the event is sampled (see SamplingConfig),
+// the events not in the sample are silently skipped,
+// and the ones in the sample have their sample rate set if they implement
+// SampleRateSetter.
+func (p *Publisher) Put(ctx context.Context, event thrift.TStruct) error {
+	eventType := p.cfg.EventType(event)
+	if rate := p.sampleRate(eventType); rate < 1 {
+		if !randbp.ShouldSampleWithRate(rate) {
+			p.metrics.sampledOut(eventType)
+			return nil
+		}
+		if setter, ok := event.(SampleRateSetter); ok {
+			setter.SetSampleRate(rate)
+		}
+	}
+
+	data, err := serializerPool.Write(ctx, event)
+	if err != nil {
+		return err
+	}
+	if len(data) > MaxEventSize {
+		p.metrics.dropped(dropReasonTooLarge, 1)
+		return fmt.Errorf("%w: %d > %d", ErrEventTooLarge, len(data), MaxEventSize)
+	}
+
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.closed {
+		return ErrPublisherClosed
+	}
+
+	if p.cfg.MaxPutTimeout <= 0 {
+		select {
+		case p.events <- data:
+			p.metrics.depth.Inc()
+			return nil
+		default:
+			p.metrics.dropped(dropReasonQueueFull, 1)
+			return ErrQueueFull
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.MaxPutTimeout)
+	defer cancel()
+	select {
+	case p.events <- data:
+		p.metrics.depth.Inc()
+		return nil
+	case <-ctx.Done():
+		p.metrics.dropped(dropReasonQueueFull, 1)
+		return fmt.Errorf("%w: %v", ErrQueueFull, ctx.Err())
+	}
+}
+
+// Close stops accepting new events,
+// and publishes the events already in the queue before returning.
+//
+// It's OK to call Close multiple times.
+// Calls after the first one return immediately.
+func (p *Publisher) Close() error {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.events)
+	p.lock.Unlock()
+
+	<-p.done
+	return nil
+}
+
+func (p *Publisher) run() {
+	defer close(p.done)
+
+	var (
+		buf    bytes.Buffer
+		count  int
+		timer  *time.Timer
+		flushC <-chan time.Time
+	)
+	var replayC <-chan time.Time
+	if p.spool != nil {
+		ticker := time.NewTicker(p.spool.cfg.ReplayInterval)
+		defer ticker.Stop()
+		replayC = ticker.C
+	}
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, flushC = nil, nil
+		}
+		if count == 0 {
+			return
+		}
+		buf.WriteByte(']')
+		payload := make([]byte, buf.Len())
+		copy(payload, buf.Bytes())
+		p.publish(payload, count)
+		buf.Reset()
+		count = 0
+	}
+
+	for {
+		select {
+		case data, ok := <-p.events:
+			if !ok {
+				flush()
+				return
+			}
+			// +2 for the separator and the closing bracket.
+			if count > 0 && buf.Len()+len(data)+2 > p.cfg.MaxBatchBytes {
+				flush()
+			}
+			if count == 0 {
+				buf.WriteByte('[')
+				timer = time.NewTimer(p.cfg.FlushInterval)
+				flushC = timer.C
+			} else {
+				buf.WriteByte(',')
+			}
+			buf.Write(data)
+			count++
+		case <-flushC:
+			flush()
+		case <-replayC:
+			p.replay()
+		}
+	}
+}
+
+// publish publishes a batch with the uncompressed payload containing count
+// events.
+func (p *Publisher) publish(payload []byte, count int) {
+	defer p.metrics.depth.Sub(float64(count))
+
+	batch, err := p.newBatch(payload, count)
+	if err != nil {
+		log.Errorw(
+			"events: failed to prepare the batch, dropping",
+			"events", count,
+			"err", err,
+		)
+		p.metrics.dropped(dropReasonPublishFailed, count)
+		return
+	}
+
+	if p.spool != nil && !p.spool.empty() {
+		// Keep the order with the batches already in the spool.
+		p.spoolBatch(batch)
+		return
+	}
+
+	start := time.Now()
+	err = p.send(batch)
+	p.metrics.batch(time.Since(start), err == nil)
+	if err != nil {
+		if p.spool != nil && !isUnrecoverable(err) {
+			p.spoolBatch(batch)
+			return
+		}
+		log.Errorw(
+			"events: failed to publish the batch, dropping",
+			"events", count,
+			"err", err,
+		)
+		p.metrics.dropped(dropReasonPublishFailed, count)
+		return
+	}
+	p.metrics.published.Add(float64(count))
+}
+
+// send sends batch with retries.
+func (p *Publisher) send(batch Batch) error {
+	ctx := context.Background()
+	return retrybp.Do(
+		ctx,
+		func() error {
+			return p.sender.Send(ctx, batch)
+		},
+		retry.Attempts(uint(p.cfg.MaxAttempts)),
+		retrybp.CappedExponentialBackoff(retrybp.CappedExponentialBackoffArgs{
+			InitialDelay: p.cfg.RetryBackoff,
+			MaxDelay:     p.cfg.MaxRetryBackoff,
+			MaxJitter:    p.cfg.RetryBackoff,
+		}),
+		retrybp.Filters(
+			retrybp.RetryableErrorFilter,
+			retrybp.ContextErrorFilter,
+			retryAllFilter,
+		),
+	)
+}
+
+func (p *Publisher) spoolBatch(batch Batch) {
+	if err := p.spool.push(batch, time.Now()); err != nil {
+		log.Errorw(
+			"events: failed to spool the batch, dropping",
+			"events", batch.Count,
+			"err", err,
+		)
+		p.metrics.dropped(dropReasonPublishFailed, batch.Count)
+	}
+}
+
+// replay publishes the spooled batches in order,
+// until the spool is empty or a batch fails to be published.
+//
+// Each batch is only tried once, to be retried on the next replay.
+func (p *Publisher) replay() {
+	p.spool.expire(time.Now())
+	for !p.spool.empty() {
+		batch, err := p.spool.peek()
+		if err == nil {
+			err = p.sender.Send(context.Background(), batch)
+			if err != nil && !isUnrecoverable(err) {
+				return
+			}
+		}
+		count := p.spool.files[0].count
+		p.spool.pop()
+		if err != nil {
+			log.Errorw(
+				"events: failed to replay the spooled batch, dropping",
+				"events", count,
+				"err", err,
+			)
+			p.metrics.dropped(dropReasonPublishFailed, count)
+			continue
+		}
+		p.metrics.published.Add(float64(count))
+		p.spool.metrics.recovered.Add(float64(count))
+	}
+}
+
+func isUnrecoverable(err error) bool {
+	var re retrybp.RetryableError
+	return errors.As(err, &re) && re.Retryable() < 0
+}
+
+func (p *Publisher) newBatch(payload []byte, count int) (Batch, error) {
+	batch := Batch{
+		Payload: payload,
+		Count:   count,
+	}
+	if p.cfg.Signer != nil {
+		signature, err := p.cfg.Signer(payload)
+		if err != nil {
+			return Batch{}, fmt.Errorf("events: failed to sign the batch: %w", err)
+		}
+		batch.Signature = signature
+	}
+	if p.cfg.Compression == CompressionGzip {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(payload); err != nil {
+			return Batch{}, fmt.Errorf("events: failed to compress the batch: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return Batch{}, fmt.Errorf("events: failed to compress the batch: %w", err)
+		}
+		batch.Payload = buf.Bytes()
+		batch.Encoding = CompressionGzip
+	}
+	return batch, nil
+}
+
+// retryAllFilter is a retrybp.Filter retrying all the errors that reach it.
+func retryAllFilter(_ error, _ retry.RetryIfFunc) bool {
+	return true
+}
@@ -0,0 +1,259 @@
+package events
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/reddit/baseplate.go/retrybp"
+)
+
+type fakeSender struct {
+	lock    sync.Mutex
+	batches []Batch
+	// errors to be returned by the next Send calls.
+	errs []error
+}
+
+func (s *fakeSender) Send(_ context.Context, batch Batch) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return err
+	}
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+func (s *fakeSender) getBatches() []Batch {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Batch(nil), s.batches...)
+}
+
+func decodeBatch(t *testing.T, batch Batch) []json.RawMessage {
+	t.Helper()
+	var r io.Reader = bytes.NewReader(batch.Payload)
+	if batch.Encoding == CompressionGzip {
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gr
+	}
+	var events []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&events); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != batch.Count {
+		t.Errorf("Expected %d events in the batch, got %d", batch.Count, len(events))
+	}
+	return events
+}
+
+func TestPublisherBatching(t *testing.T) {
+	const expected = `[1,"mock",1,0]`
+	published := publishedCounter.WithLabelValues("test-batching")
+	before := testutil.ToFloat64(published)
+	sender := &fakeSender{}
+	p, err := NewPublisher(PublisherConfig{
+		Name: "test-batching",
+		// Fits 3 events.
+		MaxBatchBytes: len(expected)*3 + 4,
+		FlushInterval: time.Hour,
+	}, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 7; i++ {
+		if err := p.Put(ctx, mockTStruct{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Put(ctx, mockTStruct{}); !errors.Is(err, ErrPublisherClosed) {
+		t.Errorf("Expected %v after Close, got %v", ErrPublisherClosed, err)
+	}
+
+	batches := sender.getBatches()
+	counts := []int{3, 3, 1}
+	if len(batches) != len(counts) {
+		t.Fatalf("Expected %d batches, got %d", len(counts), len(batches))
+	}
+	for i, batch := range batches {
+		if batch.Count != counts[i] {
+			t.Errorf("Expected %d events in batch %d, got %d", counts[i], i, batch.Count)
+		}
+		if batch.Encoding != CompressionGzip {
+			t.Errorf("Expected gzip encoding, got %q", batch.Encoding)
+		}
+		for _, event := range decodeBatch(t, batch) {
+			if string(event) != expected {
+				t.Errorf("Expected event %s, got %s", expected, event)
+			}
+		}
+	}
+	if v := testutil.ToFloat64(published) - before; v != 7 {
+		t.Errorf("Expected 7 events published, got %v", v)
+	}
+	if v := testutil.ToFloat64(queueDepthGauge.WithLabelValues("test-batching")); v != 0 {
+		t.Errorf("Expected queue depth 0, got %v", v)
+	}
+}
+
+func TestPublisherFlushInterval(t *testing.T) {
+	sender := &fakeSender{}
+	p, err := NewPublisher(PublisherConfig{
+		Name:          "test-flush",
+		FlushInterval: time.Millisecond * 10,
+		Compression:   CompressionNone,
+		Signer: func(payload []byte) (string, error) {
+			return "signed", nil
+		},
+	}, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if err := p.Put(context.Background(), mockTStruct{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 50)
+	batches := sender.getBatches()
+	if len(batches) != 1 {
+		t.Fatalf("Expected 1 batch flushed by interval, got %d", len(batches))
+	}
+	if batches[0].Encoding != "" {
+		t.Errorf("Expected no encoding, got %q", batches[0].Encoding)
+	}
+	if batches[0].Signature != "signed" {
+		t.Errorf("Expected signature %q, got %q", "signed", batches[0].Signature)
+	}
+	decodeBatch(t, batches[0])
+}
+
+func TestPublisherRetry(t *testing.T) {
+	for _, c := range []struct {
+		label     string
+		errs      []error
+		published int
+		dropped   int
+	}{
+		{
+			label:     "recovered",
+			errs:      []error{errors.New("foo"), errors.New("bar")},
+			published: 1,
+		},
+		{
+			label:   "exhausted",
+			errs:    []error{errors.New("foo"), errors.New("bar"), errors.New("baz")},
+			dropped: 1,
+		},
+		{
+			label:   "unrecoverable",
+			errs:    []error{retrybp.Unrecoverable(errors.New("bad request"))},
+			dropped: 1,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			name := "test-retry-" + c.label
+			dropped := droppedCounter.WithLabelValues(name, dropReasonPublishFailed)
+			before := testutil.ToFloat64(dropped)
+			sender := &fakeSender{errs: c.errs}
+			p, err := NewPublisher(PublisherConfig{
+				Name:         name,
+				MaxAttempts:  3,
+				RetryBackoff: time.Millisecond,
+			}, sender)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Put(context.Background(), mockTStruct{}); err != nil {
+				t.Fatal(err)
+			}
+			p.Close()
+
+			if n := len(sender.getBatches()); n != c.published {
+				t.Errorf("Expected %d batches published, got %d", c.published, n)
+			}
+			if v := testutil.ToFloat64(dropped) - before; v != float64(c.dropped) {
+				t.Errorf("Expected %d events dropped, got %v", c.dropped, v)
+			}
+		})
+	}
+}
+
+// blockingSender blocks Send until unblock is closed.
+type blockingSender struct {
+	fakeSender
+	unblock chan struct{}
+}
+
+func (s *blockingSender) Send(ctx context.Context, batch Batch) error {
+	<-s.unblock
+	return s.fakeSender.Send(ctx, batch)
+}
+
+func TestPublisherQueueFull(t *testing.T) {
+	dropped := droppedCounter.WithLabelValues("test-full", dropReasonQueueFull)
+	published := publishedCounter.WithLabelValues("test-full")
+	droppedBefore := testutil.ToFloat64(dropped)
+	publishedBefore := testutil.ToFloat64(published)
+	sender := &blockingSender{unblock: make(chan struct{})}
+	p, err := NewPublisher(PublisherConfig{
+		Name:          "test-full",
+		MaxQueueSize:  2,
+		FlushInterval: time.Millisecond,
+	}, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	// The first one is taken by the blocked publishing.
+	if err := p.Put(ctx, mockTStruct{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 10)
+	for i := 0; i < 2; i++ {
+		if err := p.Put(ctx, mockTStruct{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Put(ctx, mockTStruct{}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected %v, got %v", ErrQueueFull, err)
+	}
+	if v := testutil.ToFloat64(queueDepthGauge.WithLabelValues("test-full")); v != 3 {
+		t.Errorf("Expected queue depth 3, got %v", v)
+	}
+	if v := testutil.ToFloat64(dropped) - droppedBefore; v != 1 {
+		t.Errorf("Expected 1 event dropped, got %v", v)
+	}
+
+	close(sender.unblock)
+	p.Close()
+	if v := testutil.ToFloat64(published) - publishedBefore; v != 3 {
+		t.Errorf("Expected 3 events published, got %v", v)
+	}
+}
+
+func TestPublisherInvalidCompression(t *testing.T) {
+	if _, err := NewPublisher(PublisherConfig{Compression: "zip"}, &fakeSender{}); !errors.Is(err, ErrCompressionInvalid) {
+		t.Errorf("Expected %v, got %v", ErrCompressionInvalid, err)
+	}
+}
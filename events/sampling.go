@@ -0,0 +1,113 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"gopkg.in/yaml.v2"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// SamplingConfig is the config of the per event type sampling of a Publisher.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//	rates:
+//	  ScreenviewEvent: 0.01
+//	  ClickEvent: 0.1
+type SamplingConfig struct {
+	// Rates maps the event types (see EventTyper) to their sample rates,
+	// in [0, 1].
+	//
+	// The events of the types not in Rates are always published.
+	Rates map[string]float64 `yaml:"rates"`
+}
+
+// Rate returns the sample rate of eventType.
+func (c SamplingConfig) Rate(eventType string) float64 {
+	rate, ok := c.Rates[eventType]
+	if !ok || rate > 1 {
+		return 1
+	}
+	if rate < 0 {
+		return 0
+	}
+	return rate
+}
+
+// EventTyper is an optional interface the events can implement to specify
+// their type for sampling.
+//
+// The events not implementing it use the name of their go type (e.g.
+// "ScreenviewEvent" for *ScreenviewEvent).
+type EventTyper interface {
+	EventType() string
+}
+
+// SampleRateSetter is an optional interface the events can implement to
+// record the sample rate they were sampled at,
+// so that the downstream can scale the counts of the sampled events.
+//
+// SetSampleRate is called before the event is serialized,
+// only when the event is sampled at a rate lower than 1.
+type SampleRateSetter interface {
+	SetSampleRate(rate float64)
+}
+
+// EventType returns the type of event used for sampling.
+//
+// See EventTyper for more details.
+func EventType(event thrift.TStruct) string {
+	if typer, ok := event.(EventTyper); ok {
+		return typer.EventType()
+	}
+	t := reflect.TypeOf(event)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return ""
+	}
+	return t.Name()
+}
+
+// ParseSamplingConfig is the filewatcher.Parser of SamplingConfig in YAML.
+func ParseSamplingConfig(r io.Reader) (interface{}, error) {
+	var cfg SamplingConfig
+	if err := yaml.NewDecoder(r).Decode(&cfg); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("events: failed to parse sampling config: %w", err)
+	}
+	return cfg, nil
+}
+
+// WatchSampling watches the SamplingConfig in the YAML file at path,
+// to be used as PublisherConfig.SamplingWatcher,
+// so that the sample rates can be updated without restarting the service.
+//
+// It's necessary to call Stop on the returned FileWatcher when it's no longer
+// used.
+func WatchSampling(ctx context.Context, path string, logger log.Wrapper) (filewatcher.FileWatcher, error) {
+	return filewatcher.New(ctx, filewatcher.Config{
+		Path:   path,
+		Parser: ParseSamplingConfig,
+		Logger: logger,
+	})
+}
+
+// sampleRate returns the sample rate of eventType in the current sampling
+// config of the publisher.
+func (p *Publisher) sampleRate(eventType string) float64 {
+	if p.cfg.SamplingWatcher != nil {
+		if cfg, ok := p.cfg.SamplingWatcher.Get().(SamplingConfig); ok {
+			return cfg.Rate(eventType)
+		}
+	}
+	return p.cfg.Sampling.Rate(eventType)
+}
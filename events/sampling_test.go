@@ -0,0 +1,156 @@
+package events
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+)
+
+type typedEvent struct {
+	mockTStruct
+
+	sampleRate float64
+}
+
+func (*typedEvent) EventType() string {
+	return "typed"
+}
+
+func (e *typedEvent) SetSampleRate(rate float64) {
+	e.sampleRate = rate
+}
+
+func TestEventType(t *testing.T) {
+	if got := EventType(mockTStruct{}); got != "mockTStruct" {
+		t.Errorf("Expected %q, got %q", "mockTStruct", got)
+	}
+	if got := EventType(&mockTStruct{}); got != "mockTStruct" {
+		t.Errorf("Expected %q, got %q", "mockTStruct", got)
+	}
+	if got := EventType(&typedEvent{}); got != "typed" {
+		t.Errorf("Expected %q, got %q", "typed", got)
+	}
+}
+
+func TestSamplingConfigRate(t *testing.T) {
+	cfg := SamplingConfig{
+		Rates: map[string]float64{
+			"half":     0.5,
+			"none":     0,
+			"negative": -1,
+			"over":     2,
+		},
+	}
+	for eventType, expected := range map[string]float64{
+		"half":     0.5,
+		"none":     0,
+		"negative": 0,
+		"over":     1,
+		"unknown":  1,
+	} {
+		if got := cfg.Rate(eventType); got != expected {
+			t.Errorf("%s: Expected %v, got %v", eventType, expected, got)
+		}
+	}
+}
+
+func TestPublisherSampling(t *testing.T) {
+	const (
+		name = "test-sampling"
+		n    = 1000
+	)
+	sampledOut := sampledOutCounter.WithLabelValues(name, "typed")
+	before := testutil.ToFloat64(sampledOut)
+	sender := &fakeSender{}
+	p, err := NewPublisher(PublisherConfig{
+		Name:          name,
+		FlushInterval: time.Hour,
+		Sampling: SamplingConfig{
+			Rates: map[string]float64{
+				"typed": 0.5,
+			},
+		},
+	}, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	var rateSet int
+	for i := 0; i < n; i++ {
+		event := &typedEvent{}
+		if err := p.Put(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+		if event.sampleRate == 0.5 {
+			rateSet++
+		}
+		// Not sampled.
+		if err := p.Put(ctx, mockTStruct{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var published int
+	for _, batch := range sender.getBatches() {
+		published += batch.Count
+	}
+	dropped := int(testutil.ToFloat64(sampledOut) - before)
+	if published+dropped != 2*n {
+		t.Errorf("Expected %d events published or sampled out, got %d + %d", 2*n, published, dropped)
+	}
+	if published-n != rateSet {
+		t.Errorf("Expected the sample rate set on all %d sampled events, got %d", published-n, rateSet)
+	}
+	if dropped < n*2/5 || dropped > n*3/5 {
+		t.Errorf("Expected about half of %d events sampled out, got %d", n, dropped)
+	}
+}
+
+func TestPublisherSamplingWatcher(t *testing.T) {
+	watcher, err := filewatcher.NewMockFilewatcher(
+		strings.NewReader("rates:\n  mockTStruct: 0\n"),
+		ParseSamplingConfig,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := &fakeSender{}
+	p, err := NewPublisher(PublisherConfig{
+		Name:            "test-sampling-watcher",
+		FlushInterval:   time.Hour,
+		SamplingWatcher: watcher,
+	}, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := p.Put(ctx, mockTStruct{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := watcher.Update(strings.NewReader("rates: {}\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Put(ctx, mockTStruct{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var published int
+	for _, batch := range sender.getBatches() {
+		published += batch.Count
+	}
+	if published != 1 {
+		t.Errorf("Expected only the event after the update published, got %d", published)
+	}
+}
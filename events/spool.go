@@ -0,0 +1,261 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default values of SpoolConfig.
+const (
+	DefaultSpoolMaxBytes       = 100 * 1024 * 1024
+	DefaultSpoolMaxAge         = time.Hour * 24
+	DefaultSpoolReplayInterval = time.Second * 5
+)
+
+// ErrSpoolBatchTooLarge is returned when a batch is larger than
+// SpoolConfig.MaxBytes.
+var ErrSpoolBatchTooLarge = errors.New("events: batch is too large for the spool")
+
+// SpoolConfig configures the on-disk spool of a Publisher.
+//
+// When the batches fail to be published after all the attempts,
+// they are persisted in the spool instead of being dropped,
+// and replayed in order when the downstream pipeline recovers.
+// While there are batches in the spool,
+// the new batches are appended to the spool to preserve the order.
+//
+// The spooled batches survive restarts, so the same Dir should not be used by
+// more than one Publisher at the same time.
+//
+// Can be deserialized from YAML.
+type SpoolConfig struct {
+	// Optional. The directory to store the spooled batches.
+	// When it's empty, the spool is disabled.
+	Dir string `yaml:"dir"`
+
+	// Optional. The max total size of the spooled batches in bytes,
+	// the oldest batches are evicted to make room for the new ones.
+	// Defaults to DefaultSpoolMaxBytes.
+	MaxBytes int64 `yaml:"maxBytes"`
+
+	// Optional. The spooled batches older than MaxAge are evicted.
+	// Defaults to DefaultSpoolMaxAge.
+	MaxAge time.Duration `yaml:"maxAge"`
+
+	// Optional. The interval to try to replay the spooled batches.
+	// Defaults to DefaultSpoolReplayInterval.
+	ReplayInterval time.Duration `yaml:"replayInterval"`
+}
+
+const spoolFileSuffix = ".batch"
+
+// spoolFile is a batch persisted in the spool.
+//
+// The files are named "<created unix nano>-<seq>-<count>.batch" so that the
+// lexical order is the order they are spooled.
+type spoolFile struct {
+	name    string
+	size    int64
+	count   int
+	created time.Time
+}
+
+func parseSpoolFile(name string, size int64) (spoolFile, bool) {
+	parts := strings.Split(strings.TrimSuffix(name, spoolFileSuffix), "-")
+	if len(parts) != 3 || !strings.HasSuffix(name, spoolFileSuffix) {
+		return spoolFile{}, false
+	}
+	created, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return spoolFile{}, false
+	}
+	count, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return spoolFile{}, false
+	}
+	return spoolFile{
+		name:    name,
+		size:    size,
+		count:   count,
+		created: time.Unix(0, created),
+	}, true
+}
+
+// spoolHeader is the first line of the spool files, followed by the payload.
+type spoolHeader struct {
+	Encoding  string `json:"encoding,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// spool is the on-disk FIFO of the batches.
+//
+// It's not thread-safe, and only used by the goroutine publishing the batches.
+type spool struct {
+	cfg     SpoolConfig
+	metrics spoolMetrics
+
+	files []spoolFile // oldest first
+	bytes int64
+	seq   uint64
+}
+
+// openSpool opens the spool at cfg.Dir with the batches left by the previous
+// runs.
+func openSpool(cfg SpoolConfig, name string) (*spool, error) {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultSpoolMaxBytes
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = DefaultSpoolMaxAge
+	}
+	if cfg.ReplayInterval <= 0 {
+		cfg.ReplayInterval = DefaultSpoolReplayInterval
+	}
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("events: failed to create spool dir: %w", err)
+	}
+	entries, err := os.ReadDir(cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("events: failed to read spool dir: %w", err)
+	}
+
+	s := &spool{
+		cfg:     cfg,
+		metrics: newSpoolMetrics(name),
+	}
+	var events int
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		f, ok := parseSpoolFile(entry.Name(), info.Size())
+		if !ok {
+			// Including the temporary files from interrupted writes.
+			os.Remove(filepath.Join(cfg.Dir, entry.Name()))
+			continue
+		}
+		s.files = append(s.files, f)
+		s.bytes += f.size
+		events += f.count
+	}
+	s.metrics.events.Set(float64(events))
+	s.metrics.bytes.Set(float64(s.bytes))
+	sort.Slice(s.files, func(i, j int) bool {
+		return s.files[i].name < s.files[j].name
+	})
+	return s, nil
+}
+
+func (s *spool) empty() bool {
+	return len(s.files) == 0
+}
+
+// push persists batch to the end of the spool,
+// evicting the oldest batches if needed.
+func (s *spool) push(batch Batch, now time.Time) error {
+	header, err := json.Marshal(spoolHeader{
+		Encoding:  batch.Encoding,
+		Signature: batch.Signature,
+	})
+	if err != nil {
+		return err
+	}
+	size := int64(len(header) + 1 + len(batch.Payload))
+	if size > s.cfg.MaxBytes {
+		return fmt.Errorf("%w: %d > %d", ErrSpoolBatchTooLarge, size, s.cfg.MaxBytes)
+	}
+	for s.bytes+size > s.cfg.MaxBytes && !s.empty() {
+		s.metrics.expired(evictReasonSize, s.files[0].count)
+		s.pop()
+	}
+
+	s.seq++
+	f := spoolFile{
+		name:    fmt.Sprintf("%020d-%010d-%d%s", now.UnixNano(), s.seq, batch.Count, spoolFileSuffix),
+		size:    size,
+		count:   batch.Count,
+		created: now,
+	}
+	path := filepath.Join(s.cfg.Dir, f.name)
+	// Write to a temporary file first,
+	// so that interrupted writes are never replayed.
+	tmp := path + ".tmp"
+	data := make([]byte, 0, size)
+	data = append(data, header...)
+	data = append(data, '\n')
+	data = append(data, batch.Payload...)
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	s.files = append(s.files, f)
+	s.bytes += size
+	s.metrics.add(f.count, f.size)
+	s.metrics.spooled.Add(float64(f.count))
+	return nil
+}
+
+// peek reads the oldest batch in the spool.
+func (s *spool) peek() (Batch, error) {
+	f := s.files[0]
+	file, err := os.Open(filepath.Join(s.cfg.Dir, f.name))
+	if err != nil {
+		return Batch{}, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return Batch{}, fmt.Errorf("events: failed to read spooled batch %q: %w", f.name, err)
+	}
+	var header spoolHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return Batch{}, fmt.Errorf("events: failed to parse spooled batch %q: %w", f.name, err)
+	}
+	payload, err := io.ReadAll(r)
+	if err != nil {
+		return Batch{}, fmt.Errorf("events: failed to read spooled batch %q: %w", f.name, err)
+	}
+	return Batch{
+		Payload:   payload,
+		Encoding:  header.Encoding,
+		Signature: header.Signature,
+		Count:     f.count,
+	}, nil
+}
+
+// pop removes the oldest batch from the spool.
+func (s *spool) pop() {
+	f := s.files[0]
+	os.Remove(filepath.Join(s.cfg.Dir, f.name))
+	s.files = s.files[1:]
+	s.bytes -= f.size
+	s.metrics.add(-f.count, -f.size)
+}
+
+// expire evicts the batches older than MaxAge.
+func (s *spool) expire(now time.Time) {
+	for !s.empty() && now.Sub(s.files[0].created) > s.cfg.MaxAge {
+		s.metrics.expired(evictReasonAge, s.files[0].count)
+		s.pop()
+	}
+}
@@ -0,0 +1,192 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// outageSender fails all the Send calls while down.
+type outageSender struct {
+	fakeSender
+
+	downLock sync.Mutex
+	down     bool
+}
+
+func (s *outageSender) setDown(down bool) {
+	s.downLock.Lock()
+	defer s.downLock.Unlock()
+	s.down = down
+}
+
+func (s *outageSender) Send(ctx context.Context, batch Batch) error {
+	s.downLock.Lock()
+	down := s.down
+	s.downLock.Unlock()
+	if down {
+		return errors.New("pipeline unavailable")
+	}
+	return s.fakeSender.Send(ctx, batch)
+}
+
+// counterSigner signs the batches with increasing numbers,
+// to verify the order of the batches.
+func counterSigner() Signer {
+	var n int64
+	return func(_ []byte) (string, error) {
+		return strconv.FormatInt(atomic.AddInt64(&n, 1), 10), nil
+	}
+}
+
+func TestPublisherSpool(t *testing.T) {
+	const name = "test-spool"
+	dir := t.TempDir()
+	spooled := spooledCounter.WithLabelValues(name)
+	recovered := spoolRecoveredCounter.WithLabelValues(name)
+	spooledBefore := testutil.ToFloat64(spooled)
+	recoveredBefore := testutil.ToFloat64(recovered)
+
+	sender := &outageSender{}
+	sender.setDown(true)
+	cfg := PublisherConfig{
+		Name: name,
+		// One event per batch.
+		MaxBatchBytes: len(`[1,"mock",1,0]`),
+		FlushInterval: time.Millisecond,
+		MaxAttempts:   1,
+		Signer:        counterSigner(),
+		Spool: SpoolConfig{
+			Dir:            dir,
+			ReplayInterval: time.Millisecond * 10,
+		},
+	}
+	p, err := NewPublisher(cfg, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	put := func(p *Publisher, n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			if err := p.Put(ctx, mockTStruct{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	put(p, 3)
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if v := testutil.ToFloat64(spooled) - spooledBefore; v != 3 {
+		t.Errorf("Expected 3 events spooled, got %v", v)
+	}
+	if v := testutil.ToFloat64(spoolEventsGauge.WithLabelValues(name)); v != 3 {
+		t.Errorf("Expected 3 events in the spool, got %v", v)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("Expected 3 spooled files, got %d", len(entries))
+	}
+
+	// The spooled batches survive the restart,
+	// and the new batches are kept after them while the pipeline is down.
+	p, err = NewPublisher(cfg, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	put(p, 2)
+	time.Sleep(time.Millisecond * 20)
+
+	sender.setDown(false)
+	time.Sleep(time.Millisecond * 50)
+	put(p, 1)
+	time.Sleep(time.Millisecond * 20)
+
+	batches := sender.getBatches()
+	if len(batches) != 6 {
+		t.Fatalf("Expected 6 batches published, got %d", len(batches))
+	}
+	for i, batch := range batches {
+		if expected := strconv.Itoa(i + 1); batch.Signature != expected {
+			t.Errorf("Expected batch %s at %d, got %s", expected, i, batch.Signature)
+		}
+		decodeBatch(t, batch)
+	}
+	if v := testutil.ToFloat64(recovered) - recoveredBefore; v != 5 {
+		t.Errorf("Expected 5 events recovered, got %v", v)
+	}
+	if v := testutil.ToFloat64(spoolBytesGauge.WithLabelValues(name)); v != 0 {
+		t.Errorf("Expected empty spool, got %v bytes", v)
+	}
+}
+
+func TestSpoolEviction(t *testing.T) {
+	const name = "test-spool-eviction"
+	expired := func(reason string) float64 {
+		return testutil.ToFloat64(spoolExpiredCounter.WithLabelValues(name, reason))
+	}
+	sizeBefore := expired(evictReasonSize)
+	ageBefore := expired(evictReasonAge)
+
+	batch := func(payload string) Batch {
+		return Batch{
+			Payload: []byte(payload),
+			Count:   2,
+		}
+	}
+	// Each batch takes 3 bytes for the empty header "{}\n" plus the payload.
+	s, err := openSpool(SpoolConfig{
+		Dir:      t.TempDir(),
+		MaxBytes: 20,
+		MaxAge:   time.Minute,
+	}, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for _, payload := range []string{"aaaaa", "bbbbb", "ccccc"} {
+		if err := s.push(batch(payload), now); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Second)
+	}
+	if len(s.files) != 2 {
+		t.Fatalf("Expected 2 batches in the spool, got %d", len(s.files))
+	}
+	if got, err := s.peek(); err != nil || string(got.Payload) != "bbbbb" {
+		t.Errorf("Expected the oldest batch evicted, got %q, %v", got.Payload, err)
+	}
+	if v := expired(evictReasonSize) - sizeBefore; v != 2 {
+		t.Errorf("Expected 2 events evicted by size, got %v", v)
+	}
+
+	if err := s.push(batch("too large for the spool"), now); !errors.Is(err, ErrSpoolBatchTooLarge) {
+		t.Errorf("Expected %v, got %v", ErrSpoolBatchTooLarge, err)
+	}
+
+	s.expire(now.Add(time.Minute - time.Millisecond*1500))
+	if len(s.files) != 1 {
+		t.Fatalf("Expected 1 batch in the spool, got %d", len(s.files))
+	}
+	if v := expired(evictReasonAge) - ageBefore; v != 2 {
+		t.Errorf("Expected 2 events evicted by age, got %v", v)
+	}
+	s.pop()
+	if !s.empty() {
+		t.Error("Expected the spool to be empty")
+	}
+}
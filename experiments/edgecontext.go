@@ -0,0 +1,56 @@
+package experiments
+
+import (
+	"context"
+	"strings"
+
+	"github.com/reddit/baseplate.go/ecinterface"
+)
+
+// The targeting fields populated from the edge context by VariantWithContext.
+const (
+	TargetingFieldCountryCode = "country_code"
+	TargetingFieldLoggedIn    = "logged_in"
+	TargetingFieldDeviceKind  = "device_kind"
+)
+
+// EdgeContextArgs returns a copy of args with the targeting fields from the
+// edge context attached to ctx added,
+// if the edgecontext implementation implements ecinterface.Targeter.
+//
+// The fields already in args are not overwritten.
+// When impl is nil, ecinterface.Get will be used instead.
+func EdgeContextArgs(ctx context.Context, impl ecinterface.Interface, args map[string]interface{}) map[string]interface{} {
+	if impl == nil {
+		impl = ecinterface.Get()
+	}
+	attrs, ok := ecinterface.GetTargetingAttributes(ctx, impl)
+	if !ok {
+		return args
+	}
+
+	merged := map[string]interface{}{
+		TargetingFieldLoggedIn: attrs.LoggedIn,
+	}
+	if attrs.CountryCode != "" {
+		merged[TargetingFieldCountryCode] = strings.ToUpper(attrs.CountryCode)
+	}
+	if attrs.DeviceKind != "" {
+		merged[TargetingFieldDeviceKind] = strings.ToLower(attrs.DeviceKind)
+	}
+	for key, value := range args {
+		// Targeting is case-insensitive on the field names.
+		delete(merged, strings.ToLower(key))
+		merged[key] = value
+	}
+	return merged
+}
+
+// VariantWithContext is Variant with the targeting fields from the edge
+// context attached to ctx (see EdgeContextArgs) added to args,
+// so that the experiments can target on the country of the origin,
+// the logged in state and the device kind of the request without the
+// service populating them.
+func (e *Experiments) VariantWithContext(ctx context.Context, name string, args map[string]interface{}, bucketingEventOverride bool) (string, error) {
+	return e.Variant(name, EdgeContextArgs(ctx, nil, args), bucketingEventOverride)
+}
@@ -0,0 +1,193 @@
+package experiments
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/reddit/baseplate.go/ecinterface"
+)
+
+type targeterKey struct{}
+
+type fakeTargeter struct {
+	ecinterface.Interface
+}
+
+func (fakeTargeter) TargetingAttributes(ctx context.Context) (ecinterface.TargetingAttributes, bool) {
+	attrs, ok := ctx.Value(targeterKey{}).(ecinterface.TargetingAttributes)
+	return attrs, ok
+}
+
+func TestEdgeContextArgs(t *testing.T) {
+	t.Parallel()
+
+	impl := fakeTargeter{Interface: ecinterface.Mock()}
+	ctx := context.WithValue(context.Background(), targeterKey{}, ecinterface.TargetingAttributes{
+		CountryCode: "us",
+		LoggedIn:    true,
+		DeviceKind:  "IOS",
+	})
+
+	for _, c := range []struct {
+		label    string
+		ctx      context.Context
+		impl     ecinterface.Interface
+		args     map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			label: "added",
+			ctx:   ctx,
+			impl:  impl,
+			args:  map[string]interface{}{"user_id": "t2_1"},
+			expected: map[string]interface{}{
+				"user_id":      "t2_1",
+				"country_code": "US",
+				"logged_in":    true,
+				"device_kind":  "ios",
+			},
+		},
+		{
+			label: "not-overwritten",
+			ctx:   ctx,
+			impl:  impl,
+			args:  map[string]interface{}{"Logged_In": false},
+			expected: map[string]interface{}{
+				"Logged_In":    false,
+				"country_code": "US",
+				"device_kind":  "ios",
+			},
+		},
+		{
+			label: "unknown-fields-omitted",
+			ctx: context.WithValue(context.Background(), targeterKey{}, ecinterface.TargetingAttributes{
+				LoggedIn: false,
+			}),
+			impl: impl,
+			args: map[string]interface{}{"user_id": "t2_1"},
+			expected: map[string]interface{}{
+				"user_id":   "t2_1",
+				"logged_in": false,
+			},
+		},
+		{
+			label:    "no-edge-context",
+			ctx:      context.Background(),
+			impl:     impl,
+			args:     map[string]interface{}{"user_id": "t2_1"},
+			expected: map[string]interface{}{"user_id": "t2_1"},
+		},
+		{
+			label:    "not-targeter",
+			ctx:      ctx,
+			impl:     ecinterface.Mock(),
+			args:     map[string]interface{}{"user_id": "t2_1"},
+			expected: map[string]interface{}{"user_id": "t2_1"},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			args := EdgeContextArgs(c.ctx, c.impl, c.args)
+			if diff := cmp.Diff(c.expected, args); diff != "" {
+				t.Errorf("args mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEdgeContextTargeting(t *testing.T) {
+	t.Parallel()
+
+	targeting, err := json.Marshal(map[string]interface{}{
+		"ALL": []interface{}{
+			map[string]interface{}{
+				"EQ": map[string]interface{}{
+					"field":  TargetingFieldCountryCode,
+					"values": []string{"US", "CA"},
+				},
+			},
+			map[string]interface{}{
+				"EQ": map[string]interface{}{
+					"field": TargetingFieldLoggedIn,
+					"value": true,
+				},
+			},
+			map[string]interface{}{
+				"EQ": map[string]interface{}{
+					"field": TargetingFieldDeviceKind,
+					"value": "ios",
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := *simpleConfig
+	config.Type = "feature_rollout"
+	config.Experiment.Targeting = targeting
+	config.Experiment.Variants = []Variant{
+		{
+			Name: "variant_1",
+			Size: 1,
+		},
+	}
+	experiment, err := NewSimpleExperiment(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	impl := fakeTargeter{Interface: ecinterface.Mock()}
+	for _, c := range []struct {
+		label    string
+		attrs    ecinterface.TargetingAttributes
+		expected string
+	}{
+		{
+			label: "targeted",
+			attrs: ecinterface.TargetingAttributes{
+				CountryCode: "ca",
+				LoggedIn:    true,
+				DeviceKind:  "ios",
+			},
+			expected: "variant_1",
+		},
+		{
+			label: "country",
+			attrs: ecinterface.TargetingAttributes{
+				CountryCode: "FR",
+				LoggedIn:    true,
+				DeviceKind:  "ios",
+			},
+		},
+		{
+			label: "logged-out",
+			attrs: ecinterface.TargetingAttributes{
+				CountryCode: "US",
+				DeviceKind:  "ios",
+			},
+		},
+		{
+			label: "device",
+			attrs: ecinterface.TargetingAttributes{
+				CountryCode: "US",
+				LoggedIn:    true,
+				DeviceKind:  "android",
+			},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), targeterKey{}, c.attrs)
+			args := EdgeContextArgs(ctx, impl, map[string]interface{}{"user_id": "t2_1"})
+			variant, err := experiment.Variant(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if variant != c.expected {
+				t.Errorf("expected variant %q, got %q", c.expected, variant)
+			}
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/gofrs/uuid"
 
 	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/healthbp"
 	"github.com/reddit/baseplate.go/log"
 	"github.com/reddit/baseplate.go/timebp"
 )
@@ -50,6 +51,10 @@ func (e MissingBucketKeyError) Error() string {
 type Experiments struct {
 	watcher     filewatcher.FileWatcher
 	eventLogger EventLogger
+
+	// overrides is the optional local overrides file,
+	// see NewExperimentsWithOverrides.
+	overrides filewatcher.FileWatcher
 }
 
 // NewExperiments returns a new instance of the experiments clients. The path
@@ -58,6 +63,37 @@ type Experiments struct {
 // Context should come with a timeout otherwise this might block forever, i.e.
 // if the path never becomes available.
 func NewExperiments(ctx context.Context, path string, eventLogger EventLogger, logger log.Wrapper) (*Experiments, error) {
+	return NewExperimentsFromConfig(ctx, Config{Path: path}, eventLogger, logger)
+}
+
+// Config is the config of NewExperimentsFromConfig.
+//
+// Can be deserialized from YAML.
+type Config struct {
+	// Path is the path to the experiments file, required.
+	Path string `yaml:"path"`
+
+	// Optional. When > 0, instead of failing when the experiments file is not
+	// available within MaxWait, NewExperimentsFromConfig returns the
+	// Experiments without any experiments, and keeps waiting for the file in
+	// the background, see filewatcher.Config.Default.
+	//
+	// Variant returns UnknownExperimentError until the file is
+	// loaded, so it's usually used with StartupSignal.
+	MaxWait time.Duration `yaml:"maxWait"`
+
+	// Optional. The signal reported ready once the experiments file is loaded,
+	// usually required from the StartupGate of the Baseplate,
+	// so the service only takes traffic after the experiments are loaded.
+	StartupSignal *healthbp.StartupSignal `yaml:"-"`
+}
+
+// NewExperimentsFromConfig is NewExperiments with the experiments file loaded
+// as configured by cfg.
+//
+// Context should come with a timeout otherwise this might block forever, i.e.
+// if the path never becomes available and cfg.MaxWait is not set.
+func NewExperimentsFromConfig(ctx context.Context, cfg Config, eventLogger EventLogger, logger log.Wrapper) (*Experiments, error) {
 	parser := func(r io.Reader) (interface{}, error) {
 		var doc document
 		err := json.NewDecoder(r).Decode(&doc)
@@ -66,14 +102,17 @@ func NewExperiments(ctx context.Context, path string, eventLogger EventLogger, l
 		}
 		return doc, nil
 	}
-	result, err := filewatcher.New(
-		ctx,
-		filewatcher.Config{
-			Path:   path,
-			Parser: parser,
-			Logger: logger,
-		},
-	)
+	fwCfg := filewatcher.Config{
+		Path:          cfg.Path,
+		Parser:        parser,
+		Logger:        logger,
+		StartupSignal: cfg.StartupSignal,
+	}
+	if cfg.MaxWait > 0 {
+		fwCfg.MaxWait = cfg.MaxWait
+		fwCfg.Default = document{}
+	}
+	result, err := filewatcher.New(ctx, fwCfg)
 	if err != nil {
 		return nil, err
 	}
@@ -95,7 +134,13 @@ func NewExperiments(ctx context.Context, path string, eventLogger EventLogger, l
 // This function might return MissingBucketKeyError as the error.
 // Caller usually want to check for that and handle it differently from other
 // errors. See its documentation for more details.
+//
+// The variants forced by the local overrides file,
+// if any (see NewExperimentsWithOverrides), take precedence.
 func (e *Experiments) Variant(name string, args map[string]interface{}, bucketingEventOverride bool) (string, error) {
+	if variant, ok := e.overrideVariant(name, args); ok {
+		return variant, nil
+	}
 	experiment, err := e.experiment(name)
 	if err != nil {
 		return "", err
@@ -221,7 +266,7 @@ type SimpleExperiment struct {
 func NewSimpleExperiment(experiment *ExperimentConfig) (*SimpleExperiment, error) {
 	bucketVal := experiment.Experiment.BucketVal
 	if bucketVal == "" {
-		bucketVal = "user_id"
+		bucketVal = defaultBucketVal
 	}
 	enabled := true
 	if experiment.Enabled != nil {
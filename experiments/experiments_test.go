@@ -1,13 +1,17 @@
 package experiments
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/reddit/baseplate.go/healthbp"
+	"github.com/reddit/baseplate.go/log"
 	"github.com/reddit/baseplate.go/timebp"
 )
 
@@ -633,3 +637,45 @@ func roundTo(num float64, digits int) float64 {
 	shift := math.Pow(10, float64(digits))
 	return math.Round(num*shift) / shift
 }
+
+func TestNewExperimentsFromConfigMaxWait(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experiments.json")
+	gate := new(healthbp.StartupGate)
+	e, err := NewExperimentsFromConfig(
+		context.Background(),
+		Config{
+			Path:          path,
+			MaxWait:       time.Millisecond,
+			StartupSignal: gate.Require("experiments"),
+		},
+		nil,
+		log.TestWrapper(t),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.watcher.Stop()
+
+	args := map[string]interface{}{"user_id": "t2_1"}
+	var unknown UnknownExperimentError
+	if _, err := e.Variant("test_experiment", args, false); !errors.As(err, &unknown) {
+		t.Errorf("Expected UnknownExperimentError before the file is loaded, got %v", err)
+	}
+	if err := gate.Check(context.Background()); err == nil {
+		t.Error("Expected the startup signal to be pending before the file is loaded")
+	}
+
+	doc, err := json.Marshal(document{"test_experiment": overridesConfig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, path, string(doc))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := gate.Wait(ctx); err != nil {
+		t.Fatalf("Expected the startup signal to be ready after the file is loaded, got %v", err)
+	}
+	if _, err := e.Variant("test_experiment", args, false); err != nil {
+		t.Errorf("Expected no error after the file is loaded, got %v", err)
+	}
+}
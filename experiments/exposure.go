@@ -0,0 +1,283 @@
+package experiments
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+// Default values of ExposureConfig.
+const (
+	DefaultMaxDedupEntries = 100000
+	DefaultFlushInterval   = time.Second
+	DefaultMaxQueueSize    = 10000
+)
+
+// ErrExposureQueueFull is returned by ExposureLogger.Log when the exposure
+// event is dropped because there are already ExposureConfig.MaxQueueSize
+// events waiting to be logged.
+var ErrExposureQueueFull = errors.New("experiments: exposure queue is full")
+
+// BatchEventLogger is an optional interface an EventLogger can implement to
+// log the batched exposure events of an ExposureLogger in a single call.
+type BatchEventLogger interface {
+	LogBatch(ctx context.Context, events []ExperimentEvent) error
+}
+
+// ExposureConfig configures an ExposureLogger.
+//
+// Can be deserialized from YAML.
+type ExposureConfig struct {
+	// Optional. The exposure events of the same user (or the same device for
+	// the logged out users) to the same variant of the same experiment are only
+	// logged once within DedupWindow.
+	//
+	// When it's <=0, the deduplication is disabled.
+	DedupWindow time.Duration `yaml:"dedupWindow"`
+
+	// Optional. The max number of the (user, experiment) pairs remembered for
+	// the deduplication in a DedupWindow,
+	// the oldest ones are forgotten early when it's exceeded.
+	//
+	// Defaults to DefaultMaxDedupEntries.
+	MaxDedupEntries int `yaml:"maxDedupEntries"`
+
+	// Optional. When it's >1, the exposure events are logged asynchronously in
+	// batches of up to BatchSize events.
+	//
+	// When the EventLogger implements BatchEventLogger,
+	// each batch is logged with a single LogBatch call.
+	BatchSize int `yaml:"batchSize"`
+
+	// Optional. The max time an exposure event is held before its batch is
+	// logged. Defaults to DefaultFlushInterval.
+	FlushInterval time.Duration `yaml:"flushInterval"`
+
+	// Optional. The max number of the exposure events waiting to be logged,
+	// the new events are dropped when it's exceeded.
+	//
+	// Defaults to DefaultMaxQueueSize.
+	MaxQueueSize int `yaml:"maxQueueSize"`
+
+	// Optional. Used to log the errors returned by the EventLogger when
+	// batching.
+	Logger log.Wrapper `yaml:"logger"`
+}
+
+// ExposureLogger is an EventLogger wrapping another EventLogger,
+// deduplicating and batching the exposure events.
+//
+// It's usually passed into NewExperiments,
+// so that the hot code paths can call Expose on every variant lookup without
+// flooding the event pipeline.
+type ExposureLogger struct {
+	logger EventLogger
+	cfg    ExposureConfig
+
+	now func() time.Time
+
+	dedupLock sync.Mutex
+	// The exposures seen in the current and the previous generations,
+	// the generations are rotated every DedupWindow (or when current is full),
+	// so that the memory is bounded without tracking each entry.
+	current   map[exposureKey]exposure
+	previous  map[exposureKey]exposure
+	rotatedAt time.Time
+
+	queueLock sync.Mutex
+	queue     []ExperimentEvent
+
+	flush chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+type exposureKey struct {
+	experiment string
+	user       string
+}
+
+type exposure struct {
+	variant string
+	at      time.Time
+}
+
+// NewExposureLogger creates an ExposureLogger wrapping logger.
+//
+// When batching is enabled, Close must be called to log the pending events
+// and release the resources.
+func NewExposureLogger(logger EventLogger, cfg ExposureConfig) *ExposureLogger {
+	if cfg.MaxDedupEntries <= 0 {
+		cfg.MaxDedupEntries = DefaultMaxDedupEntries
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+	if cfg.MaxQueueSize <= 0 {
+		cfg.MaxQueueSize = DefaultMaxQueueSize
+	}
+	l := &ExposureLogger{
+		logger:  logger,
+		cfg:     cfg,
+		now:     time.Now,
+		current: make(map[exposureKey]exposure),
+		flush:   make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	l.rotatedAt = l.now()
+	if l.batching() {
+		go l.run()
+	} else {
+		close(l.done)
+	}
+	return l
+}
+
+func (l *ExposureLogger) batching() bool {
+	return l.cfg.BatchSize > 1
+}
+
+// Log implements EventLogger.
+//
+// When batching is enabled,
+// the events are logged asynchronously with a background context,
+// and only the errors of the events dropped because the queue is full are
+// returned.
+func (l *ExposureLogger) Log(ctx context.Context, event ExperimentEvent) error {
+	if l.deduplicated(event) {
+		exposuresCounter.WithLabelValues(exposureResultDeduplicated).Inc()
+		return nil
+	}
+	if !l.batching() {
+		exposuresCounter.WithLabelValues(exposureResultLogged).Inc()
+		return l.logger.Log(ctx, event)
+	}
+
+	l.queueLock.Lock()
+	if len(l.queue) >= l.cfg.MaxQueueSize {
+		l.queueLock.Unlock()
+		exposuresCounter.WithLabelValues(exposureResultDropped).Inc()
+		return ErrExposureQueueFull
+	}
+	l.queue = append(l.queue, event)
+	full := len(l.queue) >= l.cfg.BatchSize
+	l.queueLock.Unlock()
+
+	if full {
+		select {
+		case l.flush <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// deduplicated returns true if the same exposure was seen within the
+// DedupWindow, otherwise it remembers the exposure and returns false.
+func (l *ExposureLogger) deduplicated(event ExperimentEvent) bool {
+	if l.cfg.DedupWindow <= 0 || event.Experiment == nil {
+		return false
+	}
+	user := event.UserID
+	if user == "" {
+		if event.DeviceID == uuid.Nil {
+			return false
+		}
+		user = event.DeviceID.String()
+	}
+	key := exposureKey{
+		experiment: event.Experiment.Name,
+		user:       user,
+	}
+
+	now := l.now()
+	l.dedupLock.Lock()
+	defer l.dedupLock.Unlock()
+
+	if now.Sub(l.rotatedAt) >= l.cfg.DedupWindow || len(l.current) >= l.cfg.MaxDedupEntries {
+		l.previous = l.current
+		l.current = make(map[exposureKey]exposure)
+		l.rotatedAt = now
+	}
+	seen, ok := l.current[key]
+	if !ok {
+		seen, ok = l.previous[key]
+	}
+	if ok && seen.variant == event.VariantName && now.Sub(seen.at) < l.cfg.DedupWindow {
+		return true
+	}
+	l.current[key] = exposure{
+		variant: event.VariantName,
+		at:      now,
+	}
+	return false
+}
+
+func (l *ExposureLogger) run() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			l.logQueued()
+			return
+		case <-ticker.C:
+			l.logQueued()
+		case <-l.flush:
+			l.logQueued()
+		}
+	}
+}
+
+// logQueued logs all the queued events in batches of up to BatchSize.
+func (l *ExposureLogger) logQueued() {
+	l.queueLock.Lock()
+	events := l.queue
+	l.queue = nil
+	l.queueLock.Unlock()
+
+	for len(events) > 0 {
+		n := l.cfg.BatchSize
+		if n > len(events) {
+			n = len(events)
+		}
+		l.logBatch(events[:n])
+		events = events[n:]
+	}
+}
+
+func (l *ExposureLogger) logBatch(events []ExperimentEvent) {
+	ctx := context.Background()
+	exposuresCounter.WithLabelValues(exposureResultLogged).Add(float64(len(events)))
+	if batchLogger, ok := l.logger.(BatchEventLogger); ok {
+		if err := batchLogger.LogBatch(ctx, events); err != nil {
+			l.cfg.Logger.Log(ctx, "experiments: failed to log exposure events: "+err.Error())
+		}
+		return
+	}
+	for _, event := range events {
+		if err := l.logger.Log(ctx, event); err != nil {
+			l.cfg.Logger.Log(ctx, "experiments: failed to log exposure event: "+err.Error())
+		}
+	}
+}
+
+// Close logs the pending events and stops the background goroutine, if any.
+//
+// It's not safe to call Log after Close.
+func (l *ExposureLogger) Close() error {
+	if l.batching() {
+		close(l.stop)
+	}
+	<-l.done
+	return nil
+}
+
+var _ EventLogger = (*ExposureLogger)(nil)
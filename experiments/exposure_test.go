@@ -0,0 +1,256 @@
+package experiments
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type recordingEventLogger struct {
+	lock    sync.Mutex
+	events  []ExperimentEvent
+	batches int
+}
+
+func (l *recordingEventLogger) Log(_ context.Context, event ExperimentEvent) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.events = append(l.events, event)
+	return nil
+}
+
+func (l *recordingEventLogger) logged() []ExperimentEvent {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]ExperimentEvent(nil), l.events...)
+}
+
+type recordingBatchEventLogger struct {
+	recordingEventLogger
+}
+
+func (l *recordingBatchEventLogger) LogBatch(_ context.Context, events []ExperimentEvent) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.events = append(l.events, events...)
+	l.batches++
+	return nil
+}
+
+func exposureEvent(experiment, user, variant string) ExperimentEvent {
+	return ExperimentEvent{
+		Experiment:  &ExperimentConfig{Name: experiment},
+		UserID:      user,
+		VariantName: variant,
+	}
+}
+
+func TestExposureLoggerDedup(t *testing.T) {
+	t.Parallel()
+
+	recorder := &recordingEventLogger{}
+	l := NewExposureLogger(recorder, ExposureConfig{
+		DedupWindow: time.Minute,
+	})
+	defer l.Close()
+	now := time.Now()
+	l.now = func() time.Time {
+		return now
+	}
+
+	device := uuid.Must(uuid.NewV4())
+	ctx := context.Background()
+	for _, c := range []struct {
+		label    string
+		event    ExperimentEvent
+		advance  time.Duration
+		expected bool
+	}{
+		{
+			label:    "first",
+			event:    exposureEvent("exp", "t2_1", "variant_1"),
+			expected: true,
+		},
+		{
+			label:    "duplicate",
+			event:    exposureEvent("exp", "t2_1", "variant_1"),
+			advance:  time.Second * 30,
+			expected: false,
+		},
+		{
+			label:    "another-user",
+			event:    exposureEvent("exp", "t2_2", "variant_1"),
+			expected: true,
+		},
+		{
+			label:    "another-experiment",
+			event:    exposureEvent("exp2", "t2_1", "variant_1"),
+			expected: true,
+		},
+		{
+			label:    "another-variant",
+			event:    exposureEvent("exp", "t2_2", "variant_2"),
+			expected: true,
+		},
+		{
+			label:    "duplicate-across-generations",
+			event:    exposureEvent("exp", "t2_2", "variant_2"),
+			advance:  time.Second * 40,
+			expected: false,
+		},
+		{
+			label:    "expired",
+			event:    exposureEvent("exp", "t2_1", "variant_1"),
+			advance:  time.Second * 40,
+			expected: true,
+		},
+		{
+			label: "device",
+			event: ExperimentEvent{
+				Experiment:  &ExperimentConfig{Name: "exp"},
+				DeviceID:    device,
+				VariantName: "variant_1",
+			},
+			expected: true,
+		},
+		{
+			label: "device-duplicate",
+			event: ExperimentEvent{
+				Experiment:  &ExperimentConfig{Name: "exp"},
+				DeviceID:    device,
+				VariantName: "variant_1",
+			},
+			expected: false,
+		},
+		{
+			label:    "anonymous",
+			event:    exposureEvent("exp", "", "variant_1"),
+			expected: true,
+		},
+		{
+			label:    "anonymous-not-deduplicated",
+			event:    exposureEvent("exp", "", "variant_1"),
+			expected: true,
+		},
+	} {
+		now = now.Add(c.advance)
+		before := len(recorder.logged())
+		if err := l.Log(ctx, c.event); err != nil {
+			t.Fatalf("%s: %v", c.label, err)
+		}
+		if logged := len(recorder.logged()) > before; logged != c.expected {
+			t.Errorf("%s: expected logged %v, got %v", c.label, c.expected, logged)
+		}
+	}
+}
+
+func TestExposureLoggerMaxDedupEntries(t *testing.T) {
+	t.Parallel()
+
+	recorder := &recordingEventLogger{}
+	l := NewExposureLogger(recorder, ExposureConfig{
+		DedupWindow:     time.Minute,
+		MaxDedupEntries: 2,
+	})
+	defer l.Close()
+
+	ctx := context.Background()
+	for _, user := range []string{"t2_1", "t2_2", "t2_3", "t2_4", "t2_1"} {
+		if err := l.Log(ctx, exposureEvent("exp", user, "variant_1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// t2_1 was forgotten after 2 rotations.
+	if got := len(recorder.logged()); got != 5 {
+		t.Errorf("expected 5 logged events, got %d", got)
+	}
+}
+
+func TestExposureLoggerBatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	t.Run("batch-logger", func(t *testing.T) {
+		recorder := &recordingBatchEventLogger{}
+		l := NewExposureLogger(recorder, ExposureConfig{
+			BatchSize:     3,
+			FlushInterval: time.Hour,
+		})
+		for _, user := range []string{"t2_1", "t2_2", "t2_3"} {
+			if err := l.Log(ctx, exposureEvent("exp", user, "variant_1")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// A full batch is logged without waiting for FlushInterval.
+		deadline := time.Now().Add(time.Second * 5)
+		for len(recorder.logged()) < 3 {
+			if time.Now().After(deadline) {
+				t.Fatal("full batch not logged")
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+
+		if err := l.Log(ctx, exposureEvent("exp", "t2_4", "variant_1")); err != nil {
+			t.Fatal(err)
+		}
+		// Close logs the partial batch.
+		l.Close()
+		events := recorder.logged()
+		if len(events) != 4 {
+			t.Fatalf("expected 4 logged events, got %d", len(events))
+		}
+		for i, user := range []string{"t2_1", "t2_2", "t2_3", "t2_4"} {
+			if events[i].UserID != user {
+				t.Errorf("expected event #%d of user %q, got %q", i, user, events[i].UserID)
+			}
+		}
+		if recorder.batches != 2 {
+			t.Errorf("expected 2 batches, got %d", recorder.batches)
+		}
+	})
+
+	t.Run("flush-interval", func(t *testing.T) {
+		recorder := &recordingEventLogger{}
+		l := NewExposureLogger(recorder, ExposureConfig{
+			BatchSize:     100,
+			FlushInterval: time.Millisecond * 10,
+		})
+		defer l.Close()
+		if err := l.Log(ctx, exposureEvent("exp", "t2_1", "variant_1")); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(time.Second * 5)
+		for len(recorder.logged()) < 1 {
+			if time.Now().After(deadline) {
+				t.Fatal("event not logged after FlushInterval")
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+	})
+
+	t.Run("queue-full", func(t *testing.T) {
+		recorder := &recordingEventLogger{}
+		l := NewExposureLogger(recorder, ExposureConfig{
+			BatchSize:     10,
+			MaxQueueSize:  2,
+			FlushInterval: time.Hour,
+		})
+		for _, user := range []string{"t2_1", "t2_2"} {
+			if err := l.Log(ctx, exposureEvent("exp", user, "variant_1")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := l.Log(ctx, exposureEvent("exp", "t2_3", "variant_1"))
+		if !errors.Is(err, ErrExposureQueueFull) {
+			t.Errorf("expected ErrExposureQueueFull, got %v", err)
+		}
+		l.Close()
+		if got := len(recorder.logged()); got != 2 {
+			t.Errorf("expected 2 logged events, got %d", got)
+		}
+	})
+}
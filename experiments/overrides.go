@@ -0,0 +1,116 @@
+package experiments
+
+import (
+	"context"
+	"io"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// defaultBucketVal is the bucket_val used when the experiment doesn't specify
+// one.
+const defaultBucketVal = "user_id"
+
+// Override forces the variant of an experiment, regardless of its bucketing,
+// targeting and enabled state.
+//
+// Can be deserialized from YAML.
+type Override struct {
+	// Optional. The variant forced for everyone not matched by Values.
+	//
+	// For feature rollouts, set it to the name of the rollout variant to force
+	// the feature flag on.
+	Variant string `yaml:"variant"`
+
+	// Optional. The variants forced for the specific values of the bucket_val
+	// argument of the experiment ("user_id" by default),
+	// e.g. user ID -> variant.
+	Values map[string]string `yaml:"values"`
+}
+
+// variant returns the variant forced by o for args, if any.
+//
+// args must be already lowered.
+func (o Override) variant(bucketVal string, args map[string]interface{}) (string, bool) {
+	if value, ok := args[bucketVal].(string); ok {
+		if variant, ok := o.Values[value]; ok {
+			return variant, true
+		}
+	}
+	if o.Variant != "" {
+		return o.Variant, true
+	}
+	return "", false
+}
+
+// Overrides is the content of a local overrides file,
+// mapping experiment names to their overrides.
+//
+// Example:
+//
+//	my_experiment:
+//	  values:
+//	    t2_1: variant_1
+//	    t2_2: control_1
+//	my_feature_flag:
+//	  variant: enabled
+type Overrides map[string]Override
+
+func parseOverrides(r io.Reader) (interface{}, error) {
+	var overrides Overrides
+	if err := yaml.NewDecoder(r).Decode(&overrides); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// NewExperimentsWithOverrides is NewExperiments with a local overrides file
+// layered over the experiments file.
+//
+// The overrides file is in the YAML format of Overrides,
+// and it's automatically reloaded when changed,
+// so developers can exercise the variants deterministically in development
+// and tests.
+// The experiments only in the overrides file but not in the experiments file
+// are also supported by Variant.
+//
+// It should never be used in production.
+func NewExperimentsWithOverrides(ctx context.Context, path, overridesPath string, eventLogger EventLogger, logger log.Wrapper) (*Experiments, error) {
+	e, err := NewExperiments(ctx, path, eventLogger, logger)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := filewatcher.New(
+		ctx,
+		filewatcher.Config{
+			Path:   overridesPath,
+			Parser: parseOverrides,
+			Logger: logger,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	e.overrides = overrides
+	return e, nil
+}
+
+// overrideVariant returns the variant forced by the overrides file for the
+// experiment, if any.
+func (e *Experiments) overrideVariant(name string, args map[string]interface{}) (string, bool) {
+	if e.overrides == nil {
+		return "", false
+	}
+	override, ok := e.overrides.Get().(Overrides)[name]
+	if !ok {
+		return "", false
+	}
+	bucketVal := defaultBucketVal
+	if experiment, ok := e.watcher.Get().(document)[name]; ok && experiment.Experiment.BucketVal != "" {
+		bucketVal = experiment.Experiment.BucketVal
+	}
+	return override.variant(bucketVal, lowerArguments(args))
+}
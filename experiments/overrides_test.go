@@ -0,0 +1,211 @@
+package experiments
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	// Write to a temporary file and rename, so the watcher never sees a
+	// partially written file.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// overridesConfig is simpleConfig with explicit targeting,
+// so that it survives the round trip through the experiments file.
+var overridesConfig = func() *ExperimentConfig {
+	config := *simpleConfig
+	config.Experiment.Targeting = json.RawMessage(targetAllOverride)
+	return &config
+}()
+
+func TestOverrides(t *testing.T) {
+	t.Parallel()
+
+	doc, err := json.Marshal(document{
+		"test_experiment": overridesConfig,
+		"device_experiment": func() *ExperimentConfig {
+			config := *overridesConfig
+			config.Experiment.BucketVal = "device_id"
+			return &config
+		}(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher, err := filewatcher.NewMockFilewatcher(strings.NewReader(string(doc)), func(r io.Reader) (interface{}, error) {
+		var doc document
+		err := json.NewDecoder(r).Decode(&doc)
+		return doc, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overrides, err := filewatcher.NewMockFilewatcher(strings.NewReader(`
+test_experiment:
+  values:
+    t2_1: variant_2
+device_experiment:
+  variant: variant_1
+  values:
+    device_1: variant_2
+only_overridden:
+  variant: enabled
+`), parseOverrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &Experiments{
+		watcher:   watcher,
+		overrides: overrides,
+	}
+
+	for _, c := range []struct {
+		label      string
+		experiment string
+		args       map[string]interface{}
+		expected   string
+	}{
+		{
+			label:      "user",
+			experiment: "test_experiment",
+			args:       map[string]interface{}{"user_id": "t2_1"},
+			expected:   "variant_2",
+		},
+		{
+			label:      "user-case-insensitive-key",
+			experiment: "test_experiment",
+			args:       map[string]interface{}{"User_ID": "t2_1"},
+			expected:   "variant_2",
+		},
+		{
+			label:      "device",
+			experiment: "device_experiment",
+			args:       map[string]interface{}{"user_id": "t2_1", "device_id": "device_1"},
+			expected:   "variant_2",
+		},
+		{
+			label:      "everyone",
+			experiment: "device_experiment",
+			args:       map[string]interface{}{"device_id": "device_2"},
+			expected:   "variant_1",
+		},
+		{
+			label:      "not-in-experiments-file",
+			experiment: "only_overridden",
+			args:       map[string]interface{}{},
+			expected:   "enabled",
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			variant, err := e.Variant(c.experiment, c.args, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if variant != c.expected {
+				t.Errorf("expected variant %q, got %q", c.expected, variant)
+			}
+		})
+	}
+
+	t.Run("not-overridden", func(t *testing.T) {
+		args := map[string]interface{}{"user_id": "t2_2"}
+		experiment, err := NewSimpleExperiment(overridesConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := experiment.Variant(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		variant, err := e.Variant("test_experiment", args, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if variant != expected {
+			t.Errorf("expected variant %q, got %q", expected, variant)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := e.Variant("unknown", map[string]interface{}{"user_id": "t2_1"}, false)
+		if _, ok := err.(UnknownExperimentError); !ok {
+			t.Errorf("expected UnknownExperimentError, got %v", err)
+		}
+	})
+}
+
+func TestNewExperimentsWithOverrides(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "experiments.json")
+	overridesPath := filepath.Join(dir, "overrides.yaml")
+
+	doc, err := json.Marshal(document{"test_experiment": overridesConfig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, path, string(doc))
+	// Empty overrides file.
+	writeFile(t, overridesPath, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	e, err := NewExperimentsWithOverrides(ctx, path, overridesPath, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.watcher.Stop()
+	defer e.overrides.Stop()
+
+	args := map[string]interface{}{"user_id": "t2_1"}
+	experiment, err := NewSimpleExperiment(overridesConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := experiment.Variant(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	variant, err := e.Variant("test_experiment", args, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variant != expected {
+		t.Errorf("expected variant %q before overriding, got %q", expected, variant)
+	}
+
+	writeFile(t, overridesPath, `
+test_experiment:
+  values:
+    t2_1: forced
+`)
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		variant, err = e.Variant("test_experiment", args, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if variant == "forced" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("overrides file not reloaded, variant %q", variant)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
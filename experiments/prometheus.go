@@ -0,0 +1,24 @@
+package experiments
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// PrometheusExposureResultLabel is the label name of the Prometheus metrics
+// reported by ExposureLogger.
+const PrometheusExposureResultLabel = "experiments_exposure_result"
+
+// Values of the PrometheusExposureResultLabel label.
+const (
+	exposureResultLogged       = "logged"
+	exposureResultDeduplicated = "deduplicated"
+	exposureResultDropped      = "dropped"
+)
+
+var exposuresCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "experiments_exposures_total",
+	Help: "Number of the exposure events passed into ExposureLogger",
+}, []string{
+	PrometheusExposureResultLabel,
+})
@@ -0,0 +1,207 @@
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/reddit/baseplate.go/errorsbp"
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+// Protocol is the protocol of the requests a Rule applies to.
+type Protocol string
+
+// Protocol values.
+const (
+	ProtocolThrift Protocol = "thrift"
+	ProtocolHTTP   Protocol = "http"
+	ProtocolGRPC   Protocol = "grpc"
+)
+
+// Config is the fault injection config.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//	rules:
+//	  - name: slow-search
+//	    protocols: [thrift, grpc]
+//	    methods: ["search*", "/reddit.search.Search/*"]
+//	    percentage: 10
+//	    delay: 200ms
+//	  - name: flaky-frontend
+//	    callers: ["frontend-*"]
+//	    percentage: 1
+//	    codes:
+//	      thrift: 1
+//	      http: 503
+//	      grpc: 14
+//	  - name: drop-connections
+//	    protocols: [thrift]
+//	    percentage: 0.1
+//	    abort: true
+type Config struct {
+	// Rules are the fault injection rules.
+	//
+	// Only the first rule matching the request is considered,
+	// so the more specific rules should be put before the more general ones.
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule describes the requests to inject the faults into, and the faults to
+// inject.
+//
+// A request matches the rule when it matches all of Protocols, Methods and
+// Callers (empty matches everything),
+// and the faults are then injected into Percentage% of the matched requests.
+type Rule struct {
+	// Name of the rule, required.
+	//
+	// It's used in the error messages and the metrics.
+	Name string `yaml:"name"`
+
+	// Protocols the rule applies to, empty means all the protocols.
+	Protocols []Protocol `yaml:"protocols"`
+
+	// Methods are the path.Match patterns of the methods the rule applies to,
+	// empty means all the methods.
+	//
+	// The methods are:
+	//
+	// - thrift: the name of the endpoint, e.g. "getUser".
+	//
+	// - http: the name of the endpoint passed to httpbp.Wrap,
+	// usually the route, e.g. "/users".
+	//
+	// - grpc: the full method, e.g. "/reddit.users.Users/GetUser".
+	Methods []string `yaml:"methods"`
+
+	// Callers are the path.Match patterns of the callers the rule applies to,
+	// empty means all the callers.
+	//
+	// The caller is from the "User-Agent" (transport.HeaderUserAgent) header,
+	// which is usually the name of the calling service.
+	Callers []string `yaml:"callers"`
+
+	// The percentage of the matched requests to inject the faults into,
+	// in [0, 100].
+	//
+	// 0 (the default) disables the rule.
+	Percentage float64 `yaml:"percentage"`
+
+	// Optional. The delay before the request is handled
+	// (or rejected by Codes or Abort).
+	Delay timebp.Duration `yaml:"delay"`
+
+	// Optional. The error codes to reject the requests with, by protocol:
+	//
+	// - thrift: the type of the TApplicationException.
+	//
+	// - http: the HTTP status code, in [400, 599].
+	//
+	// - grpc: the gRPC status code, in [1, 16].
+	//
+	// The requests of the protocols not in Codes are not rejected.
+	Codes map[Protocol]int `yaml:"codes"`
+
+	// Optional. When true, the connection is dropped without a response,
+	// taking precedence over Codes.
+	//
+	// For gRPC, which doesn't expose the connection to the interceptors,
+	// the requests are rejected with Unavailable instead.
+	Abort bool `yaml:"abort"`
+}
+
+// Errors returned by Config.Validate.
+var (
+	ErrNoName            = errors.New("faults: rule name is required")
+	ErrInvalidPercentage = errors.New("faults: percentage must be in [0, 100]")
+	ErrUnknownProtocol   = errors.New("faults: unknown protocol")
+	ErrInvalidCode       = errors.New("faults: invalid error code")
+)
+
+// Validate checks the rules in the config.
+func (c Config) Validate() error {
+	var batch errorsbp.Batch
+	for i, rule := range c.Rules {
+		if err := rule.Validate(); err != nil {
+			batch.Add(fmt.Errorf("faults: rule #%d %q: %w", i, rule.Name, err))
+		}
+	}
+	return batch.Compile()
+}
+
+// Validate checks the rule.
+func (r Rule) Validate() error {
+	var batch errorsbp.Batch
+	if r.Name == "" {
+		batch.Add(ErrNoName)
+	}
+	if !(r.Percentage >= 0 && r.Percentage <= 100) {
+		batch.Add(fmt.Errorf("%w: %v", ErrInvalidPercentage, r.Percentage))
+	}
+	batch.Add(r.Delay.Validate(0, 0))
+	for _, p := range r.Protocols {
+		if !p.valid() {
+			batch.Add(fmt.Errorf("%w: %q", ErrUnknownProtocol, p))
+		}
+	}
+	for _, patterns := range [][]string{r.Methods, r.Callers} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				batch.Add(fmt.Errorf("faults: invalid pattern %q: %w", pattern, err))
+			}
+		}
+	}
+	for p, code := range r.Codes {
+		if !p.valid() {
+			batch.Add(fmt.Errorf("%w: %q", ErrUnknownProtocol, p))
+			continue
+		}
+		if !p.validCode(code) {
+			batch.Add(fmt.Errorf("%w: %d for %s", ErrInvalidCode, code, p))
+		}
+	}
+	return batch.Compile()
+}
+
+func (p Protocol) valid() bool {
+	switch p {
+	case ProtocolThrift, ProtocolHTTP, ProtocolGRPC:
+		return true
+	}
+	return false
+}
+
+func (p Protocol) validCode(code int) bool {
+	switch p {
+	case ProtocolHTTP:
+		return code >= 400 && code <= 599
+	case ProtocolGRPC:
+		// codes.OK is not an error, and codes.Unauthenticated is the last one.
+		return code >= 1 && code <= 16
+	default:
+		return code >= 0
+	}
+}
+
+// ParseConfig is the filewatcher.Parser of Config in YAML.
+//
+// The parsed Config is validated,
+// so invalid changes to the watched file are rejected
+// (and the previous Config is kept) instead of being partially applied.
+func ParseConfig(r io.Reader) (interface{}, error) {
+	var cfg Config
+	if err := yaml.NewDecoder(r).Decode(&cfg); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("faults: failed to parse config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
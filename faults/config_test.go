@@ -0,0 +1,151 @@
+package faults
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+func TestRuleValidate(t *testing.T) {
+	for _, c := range []struct {
+		label string
+		rule  Rule
+		want  error
+	}{
+		{
+			label: "valid",
+			rule: Rule{
+				Name:       "rule",
+				Protocols:  []Protocol{ProtocolThrift, ProtocolHTTP, ProtocolGRPC},
+				Methods:    []string{"get*", "/pkg.Service/*"},
+				Callers:    []string{"frontend-?"},
+				Percentage: 100,
+				Delay:      timebp.Duration(time.Second),
+				Codes: map[Protocol]int{
+					ProtocolThrift: 0,
+					ProtocolHTTP:   503,
+					ProtocolGRPC:   14,
+				},
+			},
+		},
+		{
+			label: "no-name",
+			rule:  Rule{Percentage: 1},
+			want:  ErrNoName,
+		},
+		{
+			label: "percentage",
+			rule:  Rule{Name: "rule", Percentage: 101},
+			want:  ErrInvalidPercentage,
+		},
+		{
+			label: "protocol",
+			rule:  Rule{Name: "rule", Protocols: []Protocol{"smtp"}},
+			want:  ErrUnknownProtocol,
+		},
+		{
+			label: "codes-protocol",
+			rule:  Rule{Name: "rule", Codes: map[Protocol]int{"smtp": 1}},
+			want:  ErrUnknownProtocol,
+		},
+		{
+			label: "http-code",
+			rule:  Rule{Name: "rule", Codes: map[Protocol]int{ProtocolHTTP: 200}},
+			want:  ErrInvalidCode,
+		},
+		{
+			label: "grpc-code",
+			rule:  Rule{Name: "rule", Codes: map[Protocol]int{ProtocolGRPC: 0}},
+			want:  ErrInvalidCode,
+		},
+		{
+			label: "delay",
+			rule:  Rule{Name: "rule", Delay: timebp.Duration(-time.Second)},
+			want:  timebp.DurationOutOfRangeError{},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			err := c.rule.Validate()
+			switch want := c.want.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("Validate() returned %v", err)
+				}
+			case timebp.DurationOutOfRangeError:
+				if !errors.As(err, &want) {
+					t.Errorf("Expected DurationOutOfRangeError, got %v", err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Errorf("Expected %v, got %v", want, err)
+				}
+			}
+		})
+	}
+
+	t.Run("pattern", func(t *testing.T) {
+		err := Rule{Name: "rule", Methods: []string{"[a-"}}.Validate()
+		if err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+			t.Errorf("Expected invalid pattern error, got %v", err)
+		}
+	})
+}
+
+func TestParseConfig(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		const content = `
+rules:
+  - name: slow-search
+    protocols: [thrift, grpc]
+    methods: ["search*"]
+    percentage: 10
+    delay: 200ms
+  - name: flaky
+    percentage: 1.5
+    codes:
+      http: 503
+      grpc: 14
+    abort: true
+`
+		v, err := ParseConfig(strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := v.(Config)
+		if len(cfg.Rules) != 2 {
+			t.Fatalf("Expected 2 rules, got %#v", cfg.Rules)
+		}
+		if got, want := cfg.Rules[0].Delay.ToDuration(), 200*time.Millisecond; got != want {
+			t.Errorf("Rules[0].Delay got %v, want %v", got, want)
+		}
+		if got, want := cfg.Rules[1].Codes[ProtocolHTTP], 503; got != want {
+			t.Errorf("Rules[1].Codes[http] got %d, want %d", got, want)
+		}
+		if !cfg.Rules[1].Abort {
+			t.Error("Expected Rules[1].Abort to be true")
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		v, err := ParseConfig(strings.NewReader(""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rules := v.(Config).Rules; len(rules) != 0 {
+			t.Errorf("Expected no rules, got %#v", rules)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		const content = `
+rules:
+  - percentage: 10
+`
+		if _, err := ParseConfig(strings.NewReader(content)); !errors.Is(err, ErrNoName) {
+			t.Errorf("Expected ErrNoName, got %v", err)
+		}
+	})
+}
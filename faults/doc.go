@@ -0,0 +1,15 @@
+// Package faults provides the fault injection config shared by the thrift,
+// HTTP and gRPC servers, to run chaos experiments.
+//
+// A single YAML file (see Config) describes the rules of the faults to inject,
+// matched by protocol, method, caller and percentage of the requests,
+// and the actions to take (delay, error code and abort).
+// The file is watched by WatchInjector so the experiments can be started and
+// stopped without restarting the services,
+// and the same file can be deployed fleet-wide regardless of the protocols the
+// services speak.
+//
+// The Injector is used by the fault injection middlewares:
+// thriftbp.InjectFaults, httpbp.InjectFaults and
+// grpcbp.InjectFaultsInterceptorUnary.
+package faults
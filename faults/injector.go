@@ -0,0 +1,219 @@
+package faults
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/randbp"
+)
+
+// Request describes the request to be matched against the rules.
+type Request struct {
+	Protocol Protocol
+
+	// Method is the method of the request, see Rule.Methods for the format.
+	Method string
+
+	// Caller is the "User-Agent" header of the request, might be empty.
+	Caller string
+}
+
+// Fault is the fault to be injected into a request,
+// returned by Injector.Match.
+type Fault struct {
+	// Rule is the name of the rule the fault comes from.
+	Rule string
+
+	// Delay is the delay before the request is handled or rejected,
+	// see Wait.
+	Delay time.Duration
+
+	// Code is the error code to reject the request with,
+	// only valid when HasCode is true.
+	Code    int
+	HasCode bool
+
+	// Abort means the connection should be dropped without a response.
+	Abort bool
+}
+
+// Wait blocks for the Delay of the fault,
+// or until ctx is done, in which case ctx.Err() is returned.
+func (f Fault) Wait(ctx context.Context) error {
+	if f.Delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(f.Delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Injector decides the faults to be injected into the requests based on the
+// rules of the Config.
+//
+// A nil *Injector never injects anything,
+// so the middlewares can be wired in unconditionally.
+type Injector struct {
+	// Exactly one of them is set.
+	cfg     atomic.Value // Config
+	watcher filewatcher.FileWatcher
+
+	// It's randbp.R.Float64 when unset,
+	// overridden in tests to get deterministic results.
+	randFloat func() float64
+}
+
+// NewInjector creates an Injector with a static Config.
+//
+// The Config can be changed later via Update.
+func NewInjector(cfg Config) (*Injector, error) {
+	i := new(Injector)
+	if err := i.Update(cfg); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// WatchInjector creates an Injector with the Config in the YAML file at path,
+// the file is reloaded when changed.
+//
+// The invalid changes to the file are logged via logger and ignored,
+// see ParseConfig.
+//
+// It's necessary to call Stop on the returned Injector when it's no longer
+// used.
+func WatchInjector(ctx context.Context, path string, logger log.Wrapper) (*Injector, error) {
+	watcher, err := filewatcher.New(ctx, filewatcher.Config{
+		Path:   path,
+		Parser: ParseConfig,
+		Logger: logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Injector{watcher: watcher}, nil
+}
+
+// ErrWatchedConfig is the error returned by Injector.Update on the Injectors
+// created by WatchInjector.
+var ErrWatchedConfig = errors.New("faults: config of the injector comes from the watched file")
+
+// Update validates and replaces the Config of an Injector created by
+// NewInjector.
+//
+// It returns ErrWatchedConfig on the Injectors created by WatchInjector,
+// as their Config comes from the watched file.
+func (i *Injector) Update(cfg Config) error {
+	if i.watcher != nil {
+		return ErrWatchedConfig
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	i.cfg.Store(cfg)
+	return nil
+}
+
+// Stop stops watching the file of the Injectors created by WatchInjector.
+//
+// It's a no-op on the Injectors created by NewInjector.
+func (i *Injector) Stop() {
+	if i != nil && i.watcher != nil {
+		i.watcher.Stop()
+	}
+}
+
+// Config returns the current Config of the Injector.
+func (i *Injector) Config() Config {
+	if i == nil {
+		return Config{}
+	}
+	if i.watcher != nil {
+		cfg, _ := i.watcher.Get().(Config)
+		return cfg
+	}
+	cfg, _ := i.cfg.Load().(Config)
+	return cfg
+}
+
+// Match returns the fault to be injected into req, if any.
+//
+// Only the first rule matching req is considered,
+// and the fault is returned with the Percentage of the rule as the
+// probability.
+// The returned Fault could be a no-op for req (e.g. a rule without Delay or
+// Abort, with Codes only for the other protocols).
+func (i *Injector) Match(req Request) (Fault, bool) {
+	if i == nil {
+		return Fault{}, false
+	}
+	for _, rule := range i.Config().Rules {
+		if !rule.matches(req) {
+			continue
+		}
+		if i.random()*100 >= rule.Percentage {
+			return Fault{}, false
+		}
+		fault := Fault{
+			Rule:  rule.Name,
+			Delay: rule.Delay.ToDuration(),
+			Abort: rule.Abort,
+		}
+		fault.Code, fault.HasCode = rule.Codes[req.Protocol]
+		injectedCounter.With(prometheus.Labels{
+			PrometheusProtocolLabel: string(req.Protocol),
+			PrometheusRuleLabel:     rule.Name,
+		}).Inc()
+		return fault, true
+	}
+	return Fault{}, false
+}
+
+func (i *Injector) random() float64 {
+	if i.randFloat != nil {
+		return i.randFloat()
+	}
+	return randbp.R.Float64()
+}
+
+func (r Rule) matches(req Request) bool {
+	if len(r.Protocols) > 0 {
+		var found bool
+		for _, p := range r.Protocols {
+			if p == req.Protocol {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return matchAny(r.Methods, req.Method) && matchAny(r.Callers, req.Caller)
+}
+
+// matchAny returns true when patterns is empty, or s matches any of them.
+func matchAny(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		// The patterns are already validated by Rule.Validate.
+		if matched, _ := path.Match(pattern, s); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package faults
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+func TestInjectorMatch(t *testing.T) {
+	injector, err := NewInjector(Config{
+		Rules: []Rule{
+			{
+				Name:       "thrift-only",
+				Protocols:  []Protocol{ProtocolThrift},
+				Methods:    []string{"get*"},
+				Percentage: 100,
+				Delay:      timebp.Duration(time.Millisecond),
+				Codes:      map[Protocol]int{ProtocolThrift: 1},
+			},
+			{
+				Name:       "frontend",
+				Callers:    []string{"frontend-*"},
+				Percentage: 50,
+				Abort:      true,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var roll float64
+	injector.randFloat = func() float64 {
+		return roll
+	}
+
+	for _, c := range []struct {
+		label string
+		roll  float64
+		req   Request
+		want  Fault
+		ok    bool
+	}{
+		{
+			label: "first-rule",
+			req:   Request{Protocol: ProtocolThrift, Method: "getUser", Caller: "frontend-1"},
+			want: Fault{
+				Rule:    "thrift-only",
+				Delay:   time.Millisecond,
+				Code:    1,
+				HasCode: true,
+			},
+			ok: true,
+		},
+		{
+			label: "protocol-mismatch",
+			req:   Request{Protocol: ProtocolHTTP, Method: "getUser", Caller: "frontend-1"},
+			want: Fault{
+				Rule:  "frontend",
+				Abort: true,
+			},
+			ok: true,
+		},
+		{
+			label: "percentage",
+			roll:  0.5,
+			req:   Request{Protocol: ProtocolHTTP, Method: "getUser", Caller: "frontend-1"},
+		},
+		{
+			label: "first-rule-only",
+			roll:  0.99,
+			req:   Request{Protocol: ProtocolThrift, Method: "getUser", Caller: "frontend-1"},
+			want: Fault{
+				Rule:    "thrift-only",
+				Delay:   time.Millisecond,
+				Code:    1,
+				HasCode: true,
+			},
+			ok: true,
+		},
+		{
+			label: "no-match",
+			req:   Request{Protocol: ProtocolGRPC, Method: "/pkg.Service/Get", Caller: "backend"},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			roll = c.roll
+			fault, ok := injector.Match(c.req)
+			if ok != c.ok {
+				t.Errorf("Match() ok got %v, want %v", ok, c.ok)
+			}
+			if fault != c.want {
+				t.Errorf("Match() got %#v, want %#v", fault, c.want)
+			}
+		})
+	}
+
+	t.Run("update", func(t *testing.T) {
+		roll = 0
+		if err := injector.Update(Config{Rules: []Rule{{Percentage: 100}}}); !errors.Is(err, ErrNoName) {
+			t.Errorf("Expected ErrNoName, got %v", err)
+		}
+		if err := injector.Update(Config{}); err != nil {
+			t.Fatal(err)
+		}
+		if fault, ok := injector.Match(Request{Protocol: ProtocolThrift, Method: "getUser"}); ok {
+			t.Errorf("Expected no fault after update, got %#v", fault)
+		}
+	})
+}
+
+func TestInjectorNil(t *testing.T) {
+	var injector *Injector
+	if fault, ok := injector.Match(Request{Protocol: ProtocolHTTP}); ok {
+		t.Errorf("Expected no fault from nil Injector, got %#v", fault)
+	}
+	injector.Stop()
+}
+
+func TestInjectorWatcher(t *testing.T) {
+	watcher, err := filewatcher.NewMockFilewatcher(strings.NewReader(`
+rules:
+  - name: all
+    percentage: 100
+`), ParseConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	injector := &Injector{watcher: watcher}
+	defer injector.Stop()
+
+	req := Request{Protocol: ProtocolGRPC, Method: "/pkg.Service/Get"}
+	if _, ok := injector.Match(req); !ok {
+		t.Error("Expected fault from the initial config")
+	}
+
+	if err := watcher.Update(strings.NewReader("rules: []")); err != nil {
+		t.Fatal(err)
+	}
+	if fault, ok := injector.Match(req); ok {
+		t.Errorf("Expected no fault after reload, got %#v", fault)
+	}
+
+	if err := injector.Update(Config{Rules: []Rule{{Name: "all", Percentage: 100}}}); !errors.Is(err, ErrWatchedConfig) {
+		t.Errorf("Expected ErrWatchedConfig, got %v", err)
+	}
+	if fault, ok := injector.Match(req); ok {
+		t.Errorf("Expected no fault after rejected update, got %#v", fault)
+	}
+}
+
+func TestFaultWait(t *testing.T) {
+	t.Run("delay", func(t *testing.T) {
+		const delay = 10 * time.Millisecond
+		start := time.Now()
+		if err := (Fault{Delay: delay}).Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed < delay {
+			t.Errorf("Wait returned after %v, want at least %v", elapsed, delay)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := (Fault{Delay: time.Minute}).Wait(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
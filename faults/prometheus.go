@@ -0,0 +1,20 @@
+package faults
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus labels.
+const (
+	PrometheusProtocolLabel = "faults_protocol"
+	PrometheusRuleLabel     = "faults_rule"
+)
+
+var injectedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "faults_injected_total",
+	Help: "Total number of the requests the faults were injected into",
+}, []string{
+	PrometheusProtocolLabel,
+	PrometheusRuleLabel,
+})
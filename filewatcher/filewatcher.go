@@ -1,17 +1,22 @@
 package filewatcher
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"gopkg.in/fsnotify.v1"
 
+	"github.com/reddit/baseplate.go/healthbp"
+	"github.com/reddit/baseplate.go/internal/fswatch"
 	"github.com/reddit/baseplate.go/internal/limitopen"
 	"github.com/reddit/baseplate.go/log"
 )
@@ -57,6 +62,7 @@ type Parser func(f io.Reader) (data interface{}, err error)
 // Result is the return type of New. Use Get function to get the actual data.
 type Result struct {
 	data atomic.Value
+	file *watchedFile
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -70,6 +76,30 @@ func (r *Result) Get() interface{} {
 	return r.data.Load()
 }
 
+// LastReload returns the last time the file was loaded successfully,
+// or the zero time if it's never loaded (see Config.Default).
+//
+// For the files skipped by Config.SkipUnchanged, and the URLs not modified
+// with NewHTTP, the time they are confirmed unchanged counts as a successful
+// load.
+func (r *Result) LastReload() time.Time {
+	return r.file.lastReloadTime()
+}
+
+// LastError returns the error of the last attempt to load the file,
+// or nil if it succeeded.
+//
+// It can be used with LastReload by the health checks,
+// e.g. to fail when the file has been unparseable for longer than a
+// threshold:
+//
+//	if err := result.LastError(); err != nil && time.Since(result.LastReload()) > threshold {
+//		return fmt.Errorf("failed to load the file for %v: %w", threshold, err)
+//	}
+func (r *Result) LastError() error {
+	return r.file.lastError()
+}
+
 // Stop stops the file watcher.
 //
 // After Stop is called you won't get any updates on the file content,
@@ -81,25 +111,136 @@ func (r *Result) Stop() {
 	r.cancel()
 }
 
-func (r *Result) watcherLoop(
-	watcher *fsnotify.Watcher,
-	path string,
-	parser Parser,
-	softLimit, hardLimit int64,
+// watchedFile is a file watched by the watcher loop.
+type watchedFile struct {
+	path                 string
+	parser               Parser
+	softLimit, hardLimit int64
+	skipUnchanged        bool
+	data                 *atomic.Value
+
+	// The checksum of the content last parsed successfully with skipUnchanged,
+	// only accessed by the goroutine parsing the file.
+	checksum [sha256.Size]byte
+	parsed   bool
+
+	lastReload int64 // unix nanoseconds, accessed atomically
+	errLock    sync.Mutex
+	lastErr    error
+}
+
+// succeeded records a successful load of the file.
+func (f *watchedFile) succeeded() {
+	now := time.Now()
+	atomic.StoreInt64(&f.lastReload, now.UnixNano())
+	f.errLock.Lock()
+	f.lastErr = nil
+	f.errLock.Unlock()
+	lastReloadGauge.WithLabelValues(f.path).Set(float64(now.UnixNano()) / float64(time.Second))
+}
+
+// failed records a failure to load the file.
+func (f *watchedFile) failed(err error) {
+	f.errLock.Lock()
+	defer f.errLock.Unlock()
+	f.lastErr = err
+}
+
+func (f *watchedFile) lastReloadTime() time.Time {
+	nanos := atomic.LoadInt64(&f.lastReload)
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+func (f *watchedFile) lastError() error {
+	f.errLock.Lock()
+	defer f.errLock.Unlock()
+	return f.lastErr
+}
+
+// reload reads and parses the file,
+// and stores the parsed data when it succeeds.
+func (f *watchedFile) reload(logger log.Wrapper) {
+	r, err := limitopen.OpenWithLimit(f.path, f.softLimit, f.hardLimit)
+	if err != nil {
+		f.failed(err)
+		logger.Log(context.Background(), "filewatcher: I/O error: "+err.Error())
+		return
+	}
+	defer r.Close()
+	if err := f.parse(r); err != nil {
+		logger.Log(context.Background(), "filewatcher: parser error: "+err.Error())
+	}
+}
+
+// parse parses the content of the file read from r and stores the parsed
+// data,
+// or skips it when the content is the same as the last one parsed with
+// skipUnchanged.
+func (f *watchedFile) parse(r io.Reader) error {
+	if err := f.doParse(r); err != nil {
+		f.failed(err)
+		return err
+	}
+	f.succeeded()
+	return nil
+}
+
+func (f *watchedFile) doParse(r io.Reader) error {
+	if !f.skipUnchanged {
+		d, err := f.parser(r)
+		if err != nil {
+			parseFailuresCounter.WithLabelValues(f.path).Inc()
+			return err
+		}
+		f.data.Store(d)
+		return nil
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	checksum := sha256.Sum256(content)
+	if f.parsed && checksum == f.checksum {
+		return nil
+	}
+	d, err := f.parser(bytes.NewReader(content))
+	if err != nil {
+		parseFailuresCounter.WithLabelValues(f.path).Inc()
+		return err
+	}
+	f.data.Store(d)
+	f.checksum = checksum
+	f.parsed = true
+	return nil
+}
+
+// watcherLoop reloads the files on the events of their paths until ctx is
+// done.
+//
+// files are keyed by filepath.Clean of their paths, which is the same as the
+// names of the events when watching their parent directories.
+func watcherLoop(
+	ctx context.Context,
+	watcher fswatch.Watcher,
+	files map[string]*watchedFile,
 	logger log.Wrapper,
 ) {
-	file := filepath.Base(path)
 	for {
 		select {
-		case <-r.ctx.Done():
+		case <-ctx.Done():
 			watcher.Close()
 			return
 
-		case err := <-watcher.Errors:
+		case err := <-watcher.Errors():
 			logger.Log(context.Background(), "filewatcher: watcher error: "+err.Error())
 
-		case ev := <-watcher.Events:
-			if filepath.Base(ev.Name) != file {
+		case ev := <-watcher.Events():
+			f := files[filepath.Clean(ev.Name)]
+			if f == nil {
 				continue
 			}
 
@@ -107,21 +248,7 @@ func (r *Result) watcherLoop(
 			default:
 				// Ignore uninterested events.
 			case fsnotify.Create, fsnotify.Write:
-				// Wrap with an anonymous function to make sure that defer works.
-				func() {
-					f, err := limitopen.OpenWithLimit(path, softLimit, hardLimit)
-					if err != nil {
-						logger.Log(context.Background(), "filewatcher: I/O error: "+err.Error())
-						return
-					}
-					defer f.Close()
-					d, err := parser(f)
-					if err != nil {
-						logger.Log(context.Background(), "filewatcher: parser error: "+err.Error())
-					} else {
-						r.data.Store(d)
-					}
-				}()
+				f.reload(logger)
 			}
 		}
 	}
@@ -160,74 +287,214 @@ type Config struct {
 	// If the hard limit is violated,
 	// The loading of the file will fail immediately.
 	MaxFileSize int64 `yaml:"maxFileSize"`
+
+	// Optional. The interval to poll the file when fsnotify is not available,
+	// or not supported by the file system of the file (e.g. NFS).
+	// <=0 means 1 second.
+	PollInterval time.Duration `yaml:"pollInterval"`
+
+	// Optional. When true, the file is always polled instead of watched by
+	// fsnotify, for the file systems not supporting inotify but not detected
+	// automatically (e.g. some container overlays).
+	ForcePolling bool `yaml:"forcePolling"`
+
+	// Optional. When true, the file is only parsed again when its content
+	// changed (as compared by its SHA-256 checksum) since the last time it was
+	// parsed successfully,
+	// so the writers touching the file without changing it (e.g. only
+	// updating the mtime) don't cause needless calls to Parser.
+	//
+	// It requires the whole content to be read into memory before calling
+	// Parser.
+	SkipUnchanged bool `yaml:"skipUnchanged"`
+
+	// Optional. The initial and max backoff between the attempts to open the
+	// file when it doesn't exist yet, the backoff doubles after each attempt.
+	// Default to InitialReadInterval and InitialReadBackoff (no doubling).
+	InitialReadBackoff    time.Duration `yaml:"initialReadBackoff"`
+	MaxInitialReadBackoff time.Duration `yaml:"maxInitialReadBackoff"`
+
+	// Optional. The max duration New waits for the file to become available,
+	// in addition to the deadline of the context passed into New.
+	// <=0 means New waits until the context is cancelled,
+	// or doesn't wait at all with Default.
+	MaxWait time.Duration `yaml:"maxWait"`
+
+	// Optional. When non-nil, instead of failing when the file is not
+	// available within MaxWait, New returns a Result with Default as the
+	// data, and keeps waiting for the file in the background (until Stop is
+	// called) to load and watch it,
+	// so the services can start before the file is written by a sidecar.
+	//
+	// The failures to load the file in the background are logged via Logger
+	// and retried every MaxInitialReadBackoff.
+	//
+	// It must be of the same type returned by Parser.
+	Default interface{} `yaml:"-"`
+
+	// Optional. The signal reported ready once the file is loaded
+	// (with Default, once it's loaded in the background),
+	// usually required from the StartupGate of the Baseplate,
+	// so the service only takes traffic after the file is loaded.
+	StartupSignal *healthbp.StartupSignal `yaml:"-"`
+}
+
+// readBackoff returns the initial and max backoff to open the file.
+func (cfg Config) readBackoff() (initial, max time.Duration) {
+	initial = cfg.InitialReadBackoff
+	if initial <= 0 {
+		initial = InitialReadInterval
+	}
+	max = cfg.MaxInitialReadBackoff
+	if max < initial {
+		max = initial
+	}
+	return initial, max
 }
 
 // New creates a new file watcher.
 //
 // If the path is not available at the time of calling,
-// it blocks until the file becomes available, or context is cancelled,
-// whichever comes first.
+// it blocks until the file becomes available, cfg.MaxWait passes,
+// or context is cancelled, whichever comes first,
+// see Config.Default to start without the file instead of failing.
 //
 // When logger is non-nil, it will be used to log errors,
 // either returned by parser or by the underlying file system watcher.
 // Please note that this does not include errors returned by the first parser
 // call, which will be returned directly.
 func New(ctx context.Context, cfg Config) (*Result, error) {
-	limit := cfg.MaxFileSize
-	if limit <= 0 {
-		limit = DefaultMaxFileSize
+	limit, hardLimit := limits(cfg.MaxFileSize)
+	waitCtx := ctx
+	if cfg.MaxWait > 0 || cfg.Default != nil {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, cfg.MaxWait)
+		defer cancel()
 	}
-	hardLimit := limit * HardLimitMultiplier
-
-	var f io.ReadCloser
 
-	for {
-		select {
-		default:
-		case <-ctx.Done():
-			return nil, fmt.Errorf("filewatcher: context cancelled while waiting for file under %q to load. %w", cfg.Path, ctx.Err())
-		}
+	res := &Result{}
+	res.ctx, res.cancel = context.WithCancel(context.Background())
+	file := &watchedFile{
+		path:          cfg.Path,
+		parser:        cfg.Parser,
+		softLimit:     limit,
+		hardLimit:     hardLimit,
+		skipUnchanged: cfg.SkipUnchanged,
+		data:          &res.data,
+	}
+	res.file = file
 
-		var err error
-		f, err = limitopen.OpenWithLimit(cfg.Path, limit, hardLimit)
-		if errors.Is(err, os.ErrNotExist) {
-			time.Sleep(InitialReadInterval)
-			continue
-		}
-		if err != nil {
+	f, err := openWhenAvailable(waitCtx, cfg, limit, hardLimit)
+	if err != nil {
+		if cfg.Default == nil || waitCtx.Err() == nil {
+			res.cancel()
+			cfg.StartupSignal.Fail(err)
 			return nil, err
 		}
-		break
+		res.data.Store(cfg.Default)
+		go res.loadLazily(cfg, file)
+		return res, nil
 	}
-
 	defer f.Close()
 
-	var watcher *fsnotify.Watcher
-	watcher, err := fsnotify.NewWatcher()
+	watcher, err := watchFile(cfg, f, file)
 	if err != nil {
+		res.cancel()
+		cfg.StartupSignal.Fail(err)
 		return nil, err
 	}
+	cfg.StartupSignal.Ready()
+	go watcherLoop(res.ctx, watcher, map[string]*watchedFile{filepath.Clean(cfg.Path): file}, cfg.Logger)
+
+	return res, nil
+}
+
+// watchFile starts watching the parent directory of the file,
+// and parses the file opened as f.
+func watchFile(cfg Config, f io.Reader, file *watchedFile) (fswatch.Watcher, error) {
+	watcher := fswatch.New(fswatch.Config{
+		PollInterval: cfg.PollInterval,
+		ForcePolling: cfg.ForcePolling,
+	})
 	// Note: We need to watch the parent directory instead of the file itself,
 	// because only watching the file won't give us CREATE events,
 	// which will happen with atomic renames.
-	err = watcher.Add(filepath.Dir(cfg.Path))
-	if err != nil {
+	if err := watcher.Add(filepath.Dir(cfg.Path)); err != nil {
+		watcher.Close()
 		return nil, err
 	}
 
-	var d interface{}
-	d, err = cfg.Parser(f)
-	if err != nil {
+	if err := file.parse(f); err != nil {
 		watcher.Close()
 		return nil, err
 	}
-	res := &Result{}
-	res.data.Store(d)
-	res.ctx, res.cancel = context.WithCancel(context.Background())
+	return watcher, nil
+}
+
+// loadLazily waits for the file to become available to load and watch it,
+// for the Result created with Config.Default.
+func (r *Result) loadLazily(cfg Config, file *watchedFile) {
+	_, maxBackoff := cfg.readBackoff()
+	for {
+		watcher, err := func() (fswatch.Watcher, error) {
+			f, err := openWhenAvailable(r.ctx, cfg, file.softLimit, file.hardLimit)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			return watchFile(cfg, f, file)
+		}()
+		if err == nil {
+			cfg.StartupSignal.Ready()
+			watcherLoop(r.ctx, watcher, map[string]*watchedFile{filepath.Clean(cfg.Path): file}, cfg.Logger)
+			return
+		}
+		if r.ctx.Err() != nil {
+			return
+		}
+		file.failed(err)
+		cfg.Logger.Log(context.Background(), "filewatcher: failed to load file: "+err.Error())
 
-	go res.watcherLoop(watcher, cfg.Path, cfg.Parser, limit, hardLimit, cfg.Logger)
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-time.After(maxBackoff):
+		}
+	}
+}
 
-	return res, nil
+// limits returns the soft and hard limits of the MaxFileSize.
+func limits(maxFileSize int64) (soft, hard int64) {
+	if maxFileSize <= 0 {
+		maxFileSize = DefaultMaxFileSize
+	}
+	return maxFileSize, maxFileSize * HardLimitMultiplier
+}
+
+// openWhenAvailable opens the path of cfg with the limits,
+// retrying with the backoff of cfg while the path doesn't exist until ctx is
+// done.
+func openWhenAvailable(ctx context.Context, cfg Config, limit, hardLimit int64) (io.ReadCloser, error) {
+	backoff, maxBackoff := cfg.readBackoff()
+	for {
+		f, err := limitopen.OpenWithLimit(cfg.Path, limit, hardLimit)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("filewatcher: context cancelled while waiting for file under %q to load. %w", cfg.Path, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
 }
 
 // NewMockFilewatcher returns a pointer to a new MockFileWatcher object
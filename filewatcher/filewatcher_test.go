@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/healthbp"
 	"github.com/reddit/baseplate.go/log"
 )
 
@@ -186,7 +188,13 @@ func TestFileWatcherRename(t *testing.T) {
 		}
 	}()
 	// Give it some time to handle the file content change
-	time.Sleep(interval * 10)
+	deadline := time.Now().Add(timeout)
+	for {
+		if b, _ := data.Get().([]byte); bytes.Equal(b, payload2) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(interval)
+	}
 	compareBytesData(t, data.Get(), payload2)
 }
 
@@ -482,3 +490,259 @@ func TestMockFileWatcher(t *testing.T) {
 		},
 	)
 }
+
+func TestFileWatcherPolling(t *testing.T) {
+	payload1 := []byte("Hello, world!")
+	payload2 := []byte("Bye, world!")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "foo")
+	if err := os.WriteFile(path, payload1, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := filewatcher.New(
+		context.Background(),
+		filewatcher.Config{
+			Path:         path,
+			Parser:       parser,
+			Logger:       log.TestWrapper(t),
+			PollInterval: time.Millisecond * 10,
+			ForcePolling: true,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Stop()
+	compareBytesData(t, data.Get(), payload1)
+
+	newpath := path + ".bar"
+	if err := os.WriteFile(newpath, payload2, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(newpath, path); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for !bytes.Equal(data.Get().([]byte), payload2) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %q after polling, got %q", payload2, data.Get())
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestFileWatcherMaxWait(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo")
+	const maxWait = time.Millisecond * 50
+
+	start := time.Now()
+	_, err := filewatcher.New(context.Background(), filewatcher.Config{
+		Path:                  path,
+		Parser:                parser,
+		InitialReadBackoff:    time.Millisecond,
+		MaxInitialReadBackoff: time.Millisecond * 10,
+		MaxWait:               maxWait,
+		Logger:                log.TestWrapper(t),
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if duration := time.Since(start); duration < maxWait || duration > maxWait*10 {
+		t.Errorf("Expected to wait for %v, took %v", maxWait, duration)
+	}
+}
+
+func TestFileWatcherDefault(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dir")
+	path := filepath.Join(dir, "foo")
+	defaultPayload := []byte("default")
+	gate := new(healthbp.StartupGate)
+
+	data, err := filewatcher.New(context.Background(), filewatcher.Config{
+		Path:                  path,
+		Parser:                parser,
+		InitialReadBackoff:    time.Millisecond,
+		MaxInitialReadBackoff: time.Millisecond * 10,
+		Default:               defaultPayload,
+		StartupSignal:         gate.Require("foo"),
+		Logger:                log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Stop()
+	compareBytesData(t, data.Get(), defaultPayload)
+	if err := gate.Check(context.Background()); err == nil {
+		t.Error("Expected the startup signal to be pending before the file is loaded")
+	}
+
+	// The parent directory doesn't exist when New returns.
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	waitFor := func(t *testing.T, payload []byte) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for {
+			if b, _ := data.Get().([]byte); bytes.Equal(b, payload) {
+				return
+			}
+			if time.Now().After(deadline) {
+				compareBytesData(t, data.Get(), payload)
+				return
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+	}
+	if err := os.WriteFile(path, []byte("Hello, world!"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, []byte("Hello, world!"))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := gate.Wait(ctx); err != nil {
+		t.Errorf("Expected the startup signal to be ready after the file is loaded, got %v", err)
+	}
+
+	// The file is watched after loaded.
+	if err := os.WriteFile(path, []byte("Bye, world!"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, []byte("Bye, world!"))
+}
+
+func TestFileWatcherSkipUnchanged(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		t.Run(fmt.Sprintf("%v", skip), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "foo")
+			if err := os.WriteFile(path, []byte("foo"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			var calls int64
+			data, err := filewatcher.New(context.Background(), filewatcher.Config{
+				Path: path,
+				Parser: func(f io.Reader) (interface{}, error) {
+					atomic.AddInt64(&calls, 1)
+					return io.ReadAll(f)
+				},
+				SkipUnchanged: skip,
+				Logger:        log.TestWrapper(t),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer data.Stop()
+
+			// Replace the file with the same content, touch it,
+			// then replace it with a different content.
+			// The files are replaced by renaming to avoid parsing the
+			// truncated file.
+			replace := func(content string) {
+				t.Helper()
+				tmp := path + ".tmp"
+				if err := os.WriteFile(tmp, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Rename(tmp, path); err != nil {
+					t.Fatal(err)
+				}
+			}
+			replace("foo")
+			time.Sleep(time.Millisecond * 50)
+			now := time.Now()
+			if err := os.Chtimes(path, now, now); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(time.Millisecond * 50)
+			replace("bar")
+			deadline := time.Now().Add(time.Second)
+			for {
+				if b, _ := data.Get().([]byte); string(b) == "bar" {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("File not reloaded, got %q", data.Get())
+				}
+				time.Sleep(time.Millisecond * 10)
+			}
+
+			// The initial parse, and the one of "bar",
+			// plus at least one of "foo" without skip.
+			got := atomic.LoadInt64(&calls)
+			if skip && got != 2 {
+				t.Errorf("Expected 2 parser calls, got %d", got)
+			}
+			if !skip && got <= 2 {
+				t.Errorf("Expected more than 2 parser calls, got %d", got)
+			}
+		})
+	}
+}
+
+func TestFileWatcherLastReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo")
+	if err := os.WriteFile(path, []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	errParser := errors.New("parser failed")
+	start := time.Now()
+	data, err := filewatcher.New(context.Background(), filewatcher.Config{
+		Path: path,
+		Parser: func(f io.Reader) (interface{}, error) {
+			b, err := io.ReadAll(f)
+			if string(b) == "invalid" {
+				return nil, errParser
+			}
+			return b, err
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Stop()
+
+	initial := data.LastReload()
+	if initial.Before(start) || initial.After(time.Now()) {
+		t.Errorf("LastReload() got %v, want after %v", initial, start)
+	}
+	if err := data.LastError(); err != nil {
+		t.Errorf("LastError() got %v, want nil", err)
+	}
+
+	replace := func(content string) {
+		t.Helper()
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor := func(t *testing.T, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("Condition not met, LastReload: %v, LastError: %v", data.LastReload(), data.LastError())
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+	}
+
+	replace("invalid")
+	waitFor(t, func() bool {
+		return errors.Is(data.LastError(), errParser)
+	})
+	if got := data.LastReload(); !got.Equal(initial) {
+		t.Errorf("LastReload() changed to %v after the failure", got)
+	}
+
+	replace("bar")
+	waitFor(t, func() bool {
+		return data.LastError() == nil && data.LastReload().After(initial)
+	})
+}
@@ -0,0 +1,195 @@
+package filewatcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+// Default values of HTTPConfig.
+const (
+	DefaultHTTPPollInterval = time.Minute
+	DefaultHTTPTimeout      = 10 * time.Second
+)
+
+// HTTPConfig defines the config to be used in NewHTTP function.
+//
+// Can be deserialized from YAML.
+type HTTPConfig struct {
+	// The HTTP(S) URL to be polled, required.
+	URL string `yaml:"url"`
+
+	// The parser to parse the response body, required.
+	Parser Parser `yaml:"-"`
+
+	// Optional. When non-nil, it will be used to log errors,
+	// either returned by parser or by the requests.
+	// Please note that this does not include errors of the first request,
+	// which will be returned directly.
+	Logger log.Wrapper `yaml:"logger"`
+
+	// Optional. The interval to poll the URL,
+	// <=0 means DefaultHTTPPollInterval.
+	PollInterval time.Duration `yaml:"pollInterval"`
+
+	// Optional. The timeout of every request,
+	// <=0 means DefaultHTTPTimeout.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// Optional. When <=0 DefaultMaxFileSize will be used instead.
+	//
+	// Unlike Config.MaxFileSize, only the hard limit (see HardLimitMultiplier)
+	// is enforced on the response body.
+	MaxFileSize int64 `yaml:"maxFileSize"`
+
+	// Optional, see Config.SkipUnchanged.
+	// It's useful when the server doesn't support ETag or Last-Modified.
+	SkipUnchanged bool `yaml:"skipUnchanged"`
+
+	// Optional. The additional headers of the requests, e.g. Authorization.
+	Header http.Header `yaml:"-"`
+
+	// Optional. Used to make the requests,
+	// nil means a new http.Client with Timeout.
+	HTTPClient *http.Client `yaml:"-"`
+}
+
+// Validate validates the HTTPConfig.
+func (cfg HTTPConfig) Validate() error {
+	if cfg.URL == "" {
+		return errors.New("url: required field is missing")
+	}
+	if cfg.Parser == nil {
+		return errors.New("parser: required field is missing")
+	}
+	return nil
+}
+
+// httpSource polls the URL of the HTTPConfig.
+type httpSource struct {
+	cfg  HTTPConfig
+	file *watchedFile
+
+	// The validators of the last response parsed successfully.
+	etag         string
+	lastModified string
+}
+
+// NewHTTP creates a new file watcher polling an HTTP(S) URL instead of
+// watching a file,
+// with the response body parsed by cfg.Parser in the same way as the content
+// of a file.
+//
+// The URL is requested for the first time with ctx before returning,
+// and the error is returned directly if it fails.
+// After that it's polled every cfg.PollInterval until Stop is called,
+// with the ETag and Last-Modified headers of the last response (if any)
+// sent back as If-None-Match and If-Modified-Since,
+// so the body is only parsed again when the server doesn't respond with
+// 304 Not Modified.
+func NewHTTP(ctx context.Context, cfg HTTPConfig) (*Result, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("filewatcher.NewHTTP: %w", err)
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultHTTPPollInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultHTTPTimeout
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: cfg.Timeout}
+	}
+
+	res := &Result{}
+	_, hardLimit := limits(cfg.MaxFileSize)
+	s := &httpSource{
+		cfg: cfg,
+		file: &watchedFile{
+			path:          cfg.URL,
+			parser:        cfg.Parser,
+			hardLimit:     hardLimit,
+			skipUnchanged: cfg.SkipUnchanged,
+			data:          &res.data,
+		},
+	}
+	res.file = s.file
+	if err := s.poll(ctx); err != nil {
+		return nil, fmt.Errorf("filewatcher.NewHTTP: %w", err)
+	}
+
+	res.ctx, res.cancel = context.WithCancel(context.Background())
+	go s.loop(res.ctx)
+	return res, nil
+}
+
+func (s *httpSource) loop(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.poll(ctx); err != nil && ctx.Err() == nil {
+				s.file.failed(err)
+				s.cfg.Logger.Log(context.Background(), "filewatcher: "+err.Error())
+			}
+		}
+	}
+}
+
+// poll requests the URL, and parses the response body unless it's not
+// modified.
+func (s *httpSource) poll(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.URL, nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range s.cfg.Header {
+		req.Header[key] = values
+	}
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	if s.lastModified != "" {
+		req.Header.Set("If-Modified-Since", s.lastModified)
+	}
+
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("http request for %q failed: %w", s.cfg.URL, err)
+	}
+	defer func() {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+	if resp.StatusCode == http.StatusNotModified {
+		s.file.succeeded()
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("http request for %q failed with status %q", s.cfg.URL, resp.Status)
+	}
+
+	// Read one more byte than the limit to tell whether it's exceeded.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, s.file.hardLimit+1))
+	if err != nil {
+		return fmt.Errorf("failed to read http response of %q: %w", s.cfg.URL, err)
+	}
+	if int64(len(body)) > s.file.hardLimit {
+		return fmt.Errorf("http response of %q exceeds the size limit %d", s.cfg.URL, s.file.hardLimit)
+	}
+	if err := s.file.parse(bytes.NewReader(body)); err != nil {
+		return fmt.Errorf("parser error: %w", err)
+	}
+	s.etag = resp.Header.Get("ETag")
+	s.lastModified = resp.Header.Get("Last-Modified")
+	return nil
+}
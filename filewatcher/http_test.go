@@ -0,0 +1,156 @@
+package filewatcher_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// configServer is a fake config service supporting ETag.
+type configServer struct {
+	lock sync.Mutex
+	body string
+	etag string
+
+	requests    int64
+	notModified int64
+}
+
+func (s *configServer) set(body, etag string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.body = body
+	s.etag = etag
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&s.requests, 1)
+	if r.Header.Get("Authorization") != "token" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.lock.Lock()
+	body, etag := s.body, s.etag
+	s.lock.Unlock()
+	if r.Header.Get("If-None-Match") == etag {
+		atomic.AddInt64(&s.notModified, 1)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	io.WriteString(w, body)
+}
+
+func TestNewHTTP(t *testing.T) {
+	config := &configServer{body: "foo", etag: `"1"`}
+	server := httptest.NewServer(config)
+	defer server.Close()
+
+	var parses int64
+	data, err := filewatcher.NewHTTP(context.Background(), filewatcher.HTTPConfig{
+		URL: server.URL,
+		Parser: func(f io.Reader) (interface{}, error) {
+			atomic.AddInt64(&parses, 1)
+			return io.ReadAll(f)
+		},
+		PollInterval: time.Millisecond * 10,
+		Header:       http.Header{"Authorization": {"token"}},
+		Logger:       log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Stop()
+	compareBytesData(t, data.Get(), []byte("foo"))
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt64(&config.notModified) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("No request with If-None-Match")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if got := atomic.LoadInt64(&parses); got != 1 {
+		t.Errorf("Expected the not modified response not parsed, got %d parses", got)
+	}
+
+	config.set("bar", `"2"`)
+	deadline = time.Now().Add(time.Second)
+	for {
+		if b, _ := data.Get().([]byte); string(b) == "bar" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Not reloaded, got %q", data.Get())
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	data.Stop()
+	// Give the in-flight request (if any) some time to finish.
+	time.Sleep(time.Millisecond * 20)
+	requests := atomic.LoadInt64(&config.requests)
+	time.Sleep(time.Millisecond * 50)
+	if got := atomic.LoadInt64(&config.requests); got != requests {
+		t.Errorf("Expected no requests after Stop, got %d more", got-requests)
+	}
+}
+
+func TestNewHTTPErrors(t *testing.T) {
+	config := &configServer{body: strings.Repeat("a", 11), etag: `"1"`}
+	server := httptest.NewServer(config)
+	defer server.Close()
+
+	for _, c := range []struct {
+		label string
+		cfg   filewatcher.HTTPConfig
+	}{
+		{
+			label: "no-url",
+			cfg:   filewatcher.HTTPConfig{Parser: parser},
+		},
+		{
+			label: "status",
+			cfg:   filewatcher.HTTPConfig{URL: server.URL, Parser: parser},
+		},
+		{
+			label: "size",
+			cfg: filewatcher.HTTPConfig{
+				URL:         server.URL,
+				Parser:      parser,
+				Header:      http.Header{"Authorization": {"token"}},
+				MaxFileSize: 1,
+			},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			if _, err := filewatcher.NewHTTP(context.Background(), c.cfg); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+
+	t.Run("parser", func(t *testing.T) {
+		errParser := errors.New("parser failed")
+		_, err := filewatcher.NewHTTP(context.Background(), filewatcher.HTTPConfig{
+			URL: server.URL,
+			Parser: func(io.Reader) (interface{}, error) {
+				return nil, errParser
+			},
+			Header: http.Header{"Authorization": {"token"}},
+		})
+		if !errors.Is(err, errParser) {
+			t.Errorf("Expected the parser error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package filewatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/reddit/baseplate.go/internal/fswatch"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// MultiConfig defines the config to be used in NewMulti function.
+type MultiConfig struct {
+	// The files to be watched, required.
+	//
+	// Only the Path, Parser, MaxFileSize and SkipUnchanged of every file are
+	// used,
+	// and the paths must be unique.
+	Files []Config
+
+	// Optional. When non-nil, it will be used to log errors,
+	// either returned by the parsers or by the underlying file system watcher.
+	// Please note that this does not include errors returned by the first
+	// parser calls, which will be returned directly.
+	Logger log.Wrapper
+
+	// Optional. The interval to poll the files when fsnotify is not available,
+	// or not supported by the file systems of the files (e.g. NFS).
+	// <=0 means 1 second.
+	PollInterval time.Duration
+
+	// Optional. When true, the files are always polled instead of watched by
+	// fsnotify.
+	ForcePolling bool
+}
+
+// Multi is the return type of NewMulti.
+// Use Get function to get the actual data of every file.
+type Multi struct {
+	files  map[string]*watchedFile // keyed by the paths in MultiConfig.Files
+	cancel context.CancelFunc
+}
+
+// Get returns the latest parsed data of the file at path,
+// or nil if path is not one of the paths in MultiConfig.Files.
+//
+// Although the type is interface{},
+// it's guaranteed to be whatever actual type is implemented inside the Parser
+// of the file.
+func (m *Multi) Get(path string) interface{} {
+	f := m.files[path]
+	if f == nil {
+		return nil
+	}
+	return f.data.Load()
+}
+
+// LastReload returns the last time the file at path was loaded successfully,
+// see Result.LastReload.
+//
+// It returns the zero time if path is not one of the paths in
+// MultiConfig.Files.
+func (m *Multi) LastReload(path string) time.Time {
+	f := m.files[path]
+	if f == nil {
+		return time.Time{}
+	}
+	return f.lastReloadTime()
+}
+
+// LastError returns the error of the last attempt to load the file at path,
+// see Result.LastError.
+//
+// It returns nil if path is not one of the paths in MultiConfig.Files.
+func (m *Multi) LastError(path string) error {
+	f := m.files[path]
+	if f == nil {
+		return nil
+	}
+	return f.lastError()
+}
+
+// Stop stops the file watcher.
+//
+// After Stop is called you won't get any updates on the contents of the
+// files, but you can still call Get to get the last contents before stopping.
+//
+// It's OK to call Stop multiple times.
+// Calls after the first one are essentially no-op.
+func (m *Multi) Stop() {
+	m.cancel()
+}
+
+// NewMulti creates a new file watcher watching multiple files,
+// with a single underlying file system watcher and goroutine shared by all the
+// files.
+//
+// If some of the paths are not available at the time of calling,
+// it blocks until all the files become available, or context is cancelled,
+// whichever comes first.
+func NewMulti(ctx context.Context, cfg MultiConfig) (*Multi, error) {
+	if len(cfg.Files) == 0 {
+		return nil, errors.New("filewatcher: no files to watch")
+	}
+
+	m := &Multi{
+		files: make(map[string]*watchedFile, len(cfg.Files)),
+	}
+	files := make(map[string]*watchedFile, len(cfg.Files))
+	dirs := make(map[string]bool)
+	for _, fc := range cfg.Files {
+		key := filepath.Clean(fc.Path)
+		if files[key] != nil {
+			return nil, fmt.Errorf("filewatcher: duplicate path %q", fc.Path)
+		}
+		limit, hardLimit := limits(fc.MaxFileSize)
+		f := &watchedFile{
+			path:          fc.Path,
+			parser:        fc.Parser,
+			softLimit:     limit,
+			hardLimit:     hardLimit,
+			skipUnchanged: fc.SkipUnchanged,
+			data:          new(atomic.Value),
+		}
+		files[key] = f
+		m.files[fc.Path] = f
+		dirs[filepath.Dir(key)] = true
+	}
+
+	readers := make(map[*watchedFile]io.ReadCloser, len(files))
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+	for _, f := range files {
+		r, err := openWhenAvailable(ctx, Config{Path: f.path}, f.softLimit, f.hardLimit)
+		if err != nil {
+			return nil, err
+		}
+		readers[f] = r
+	}
+
+	watcher := fswatch.New(fswatch.Config{
+		PollInterval: cfg.PollInterval,
+		ForcePolling: cfg.ForcePolling,
+	})
+	// Note: Like New, we need to watch the parent directories instead of the
+	// files themselves to get the CREATE events of atomic renames.
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	for f, r := range readers {
+		if err := f.parse(r); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+
+	var loopCtx context.Context
+	loopCtx, m.cancel = context.WithCancel(context.Background())
+	go watcherLoop(loopCtx, watcher, files, cfg.Logger)
+
+	return m, nil
+}
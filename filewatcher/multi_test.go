@@ -0,0 +1,139 @@
+package filewatcher_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/log"
+)
+
+func upperParser(f io.Reader) (interface{}, error) {
+	b, err := io.ReadAll(f)
+	return strings.ToUpper(string(b)), err
+}
+
+func TestMulti(t *testing.T) {
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+	foo := filepath.Join(dir1, "foo")
+	bar := filepath.Join(dir1, "bar")
+	baz := filepath.Join(dir2, "baz")
+	for path, content := range map[string]string{
+		foo: "foo",
+		bar: "bar",
+		baz: "baz",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := filewatcher.NewMulti(context.Background(), filewatcher.MultiConfig{
+		Files: []filewatcher.Config{
+			{Path: foo, Parser: parser},
+			{Path: bar, Parser: upperParser},
+			{Path: baz, Parser: parser},
+		},
+		Logger: log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop()
+
+	compareBytesData(t, m.Get(foo), []byte("foo"))
+	if got, want := m.Get(bar), "BAR"; got != want {
+		t.Errorf("Get(%q) got %#v, want %q", bar, got, want)
+	}
+	compareBytesData(t, m.Get(baz), []byte("baz"))
+	if got := m.Get(filepath.Join(dir1, "other")); got != nil {
+		t.Errorf("Expected nil for the path not watched, got %#v", got)
+	}
+
+	// Replace bar atomically, and write baz in place.
+	tmp := filepath.Join(dir1, "bar.tmp")
+	if err := os.WriteFile(tmp, []byte("new bar"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, bar); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(baz, []byte("new baz"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		gotBar := m.Get(bar)
+		gotBaz, _ := m.Get(baz).([]byte)
+		if gotBar == "NEW BAR" && string(gotBaz) == "new baz" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Files not reloaded, got %#v and %q", gotBar, gotBaz)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	compareBytesData(t, m.Get(foo), []byte("foo"))
+}
+
+func TestMultiErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "foo")
+	if err := os.WriteFile(path, []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		if _, err := filewatcher.NewMulti(context.Background(), filewatcher.MultiConfig{}); err == nil {
+			t.Error("Expected error for no files")
+		}
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		_, err := filewatcher.NewMulti(context.Background(), filewatcher.MultiConfig{
+			Files: []filewatcher.Config{
+				{Path: path, Parser: parser},
+				{Path: filepath.Join(dir, ".", "foo"), Parser: parser},
+			},
+		})
+		if err == nil {
+			t.Error("Expected error for duplicate paths")
+		}
+	})
+
+	t.Run("parser", func(t *testing.T) {
+		errParser := errors.New("parser failed")
+		_, err := filewatcher.NewMulti(context.Background(), filewatcher.MultiConfig{
+			Files: []filewatcher.Config{
+				{Path: path, Parser: func(io.Reader) (interface{}, error) {
+					return nil, errParser
+				}},
+			},
+		})
+		if !errors.Is(err, errParser) {
+			t.Errorf("Expected the parser error, got %v", err)
+		}
+	})
+
+	t.Run("not-exist", func(t *testing.T) {
+		filewatcher.InitialReadInterval = time.Millisecond
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+		defer cancel()
+		_, err := filewatcher.NewMulti(ctx, filewatcher.MultiConfig{
+			Files: []filewatcher.Config{
+				{Path: path, Parser: parser},
+				{Path: filepath.Join(dir, "not-exist"), Parser: parser},
+			},
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the context error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,26 @@
+package filewatcher
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// PrometheusPathLabel is the label name of the paths (or the URLs with
+// NewHTTP) of the watched files.
+const PrometheusPathLabel = "filewatcher_path"
+
+var (
+	parseFailuresCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "filewatcher_parse_failures_total",
+		Help: "Total number of the failures of the parsers of the watched files, including the initial loads",
+	}, []string{
+		PrometheusPathLabel,
+	})
+
+	lastReloadGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "filewatcher_last_reload_timestamp_seconds",
+		Help: "The unix timestamp of the last successful load of the watched file",
+	}, []string{
+		PrometheusPathLabel,
+	})
+)
@@ -0,0 +1,57 @@
+package filewatcher
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo")
+	if err := os.WriteFile(path, []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	parser := func(f io.Reader) (interface{}, error) {
+		b, err := io.ReadAll(f)
+		if string(b) == "invalid" {
+			return nil, errors.New("invalid")
+		}
+		return b, err
+	}
+	start := time.Now()
+	data, err := New(context.Background(), Config{
+		Path:   path,
+		Parser: parser,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Stop()
+
+	lastReload := testutil.ToFloat64(lastReloadGauge.WithLabelValues(path))
+	if lastReload < float64(start.Unix()) {
+		t.Errorf("Expected last reload timestamp after %v, got %v", start.Unix(), lastReload)
+	}
+
+	failures := parseFailuresCounter.WithLabelValues(path)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte("invalid"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for testutil.ToFloat64(failures) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Parse failure not counted")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
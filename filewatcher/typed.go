@@ -0,0 +1,137 @@
+package filewatcher
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/reddit/baseplate.go/healthbp"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// TypedParser is the Parser of a TypedFileWatcher returning T.
+type TypedParser[T any] func(f io.Reader) (data T, err error)
+
+// TypedFileWatcher is the FileWatcher with Get returning T instead of
+// interface{}.
+type TypedFileWatcher[T any] interface {
+	// Get returns the latest, parsed data from the TypedFileWatcher.
+	Get() T
+
+	// Stop stops the TypedFileWatcher, see FileWatcher.Stop.
+	Stop()
+}
+
+// TypedConfig defines the config to be used in NewTyped function.
+//
+// It's the same as Config, except the Parser returns T.
+//
+// Can be deserialized from YAML.
+type TypedConfig[T any] struct {
+	// The path to the file to be watched, required.
+	Path string `yaml:"path"`
+
+	// The parser to parse the data load, required.
+	//
+	// Unlike Parser, it's OK for it to return the zero value of T with nil
+	// error (e.g. a nil pointer).
+	Parser TypedParser[T] `yaml:"-"`
+
+	// Optional, see Config.Logger.
+	Logger log.Wrapper `yaml:"logger"`
+
+	// Optional, see Config.MaxFileSize.
+	MaxFileSize int64 `yaml:"maxFileSize"`
+
+	// Optional, see Config.PollInterval.
+	PollInterval time.Duration `yaml:"pollInterval"`
+
+	// Optional, see Config.ForcePolling.
+	ForcePolling bool `yaml:"forcePolling"`
+
+	// Optional, see Config.SkipUnchanged.
+	SkipUnchanged bool `yaml:"skipUnchanged"`
+
+	// Optional, see Config.InitialReadBackoff and Config.MaxInitialReadBackoff.
+	InitialReadBackoff    time.Duration `yaml:"initialReadBackoff"`
+	MaxInitialReadBackoff time.Duration `yaml:"maxInitialReadBackoff"`
+
+	// Optional, see Config.MaxWait.
+	MaxWait time.Duration `yaml:"maxWait"`
+
+	// Optional, see Config.Default.
+	Default *T `yaml:"-"`
+
+	// Optional, see Config.StartupSignal.
+	StartupSignal *healthbp.StartupSignal `yaml:"-"`
+}
+
+// Typed is the return type of NewTyped. Use Get function to get the actual
+// data.
+type Typed[T any] struct {
+	result *Result
+}
+
+var _ TypedFileWatcher[any] = (*Typed[any])(nil)
+
+// typedData wraps the data of Typed, so it's always the same type stored in
+// the atomic.Value of Result even if T is an interface type.
+type typedData[T any] struct {
+	data T
+}
+
+// NewTyped creates a new file watcher with Get returning T,
+// it's otherwise the same as New.
+func NewTyped[T any](ctx context.Context, cfg TypedConfig[T]) (*Typed[T], error) {
+	var def interface{}
+	if cfg.Default != nil {
+		def = typedData[T]{data: *cfg.Default}
+	}
+	result, err := New(ctx, Config{
+		Path: cfg.Path,
+		Parser: func(f io.Reader) (interface{}, error) {
+			data, err := cfg.Parser(f)
+			if err != nil {
+				return nil, err
+			}
+			return typedData[T]{data: data}, nil
+		},
+		Logger:       cfg.Logger,
+		MaxFileSize:  cfg.MaxFileSize,
+		PollInterval: cfg.PollInterval,
+		ForcePolling: cfg.ForcePolling,
+
+		SkipUnchanged:         cfg.SkipUnchanged,
+		InitialReadBackoff:    cfg.InitialReadBackoff,
+		MaxInitialReadBackoff: cfg.MaxInitialReadBackoff,
+		MaxWait:               cfg.MaxWait,
+		Default:               def,
+		StartupSignal:         cfg.StartupSignal,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Typed[T]{result: result}, nil
+}
+
+// Get returns the latest parsed data from the file watcher.
+func (t *Typed[T]) Get() T {
+	return t.result.Get().(typedData[T]).data
+}
+
+// LastReload returns the last time the file was loaded successfully,
+// see Result.LastReload.
+func (t *Typed[T]) LastReload() time.Time {
+	return t.result.LastReload()
+}
+
+// LastError returns the error of the last attempt to load the file,
+// see Result.LastError.
+func (t *Typed[T]) LastError() error {
+	return t.result.LastError()
+}
+
+// Stop stops the file watcher, see Result.Stop.
+func (t *Typed[T]) Stop() {
+	t.result.Stop()
+}
@@ -0,0 +1,129 @@
+package filewatcher_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/log"
+)
+
+type lines []string
+
+func parseLines(f io.Reader) (lines, error) {
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		// The nil value is allowed with TypedParser.
+		return nil, nil
+	}
+	return strings.Split(string(b), "\n"), nil
+}
+
+func TestNewTyped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo")
+	if err := os.WriteFile(path, []byte("foo\nbar"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var fw filewatcher.TypedFileWatcher[lines]
+	fw, err := filewatcher.NewTyped(context.Background(), filewatcher.TypedConfig[lines]{
+		Path:   path,
+		Parser: parseLines,
+		Logger: log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.Stop()
+
+	if got := fw.Get(); len(got) != 2 || got[0] != "foo" || got[1] != "bar" {
+		t.Errorf("Get() got %q, want [foo bar]", got)
+	}
+
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for fw.Get() != nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected nil after truncating the file, got %q", fw.Get())
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestNewTypedInterface(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo")
+	if err := os.WriteFile(path, []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The data of different types can be stored with an interface T.
+	fw, err := filewatcher.NewTyped(context.Background(), filewatcher.TypedConfig[interface{}]{
+		Path: path,
+		Parser: func(f io.Reader) (interface{}, error) {
+			b, err := io.ReadAll(f)
+			if string(b) == "int" {
+				return 1, err
+			}
+			return string(b), err
+		},
+		Logger: log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.Stop()
+	if got := fw.Get(); got != "foo" {
+		t.Errorf("Get() got %#v, want %q", got, "foo")
+	}
+
+	if err := os.WriteFile(path, []byte("int"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for fw.Get() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 1 after writing the file, got %#v", fw.Get())
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestNewTypedDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo")
+	def := lines{"default"}
+	fw, err := filewatcher.NewTyped(context.Background(), filewatcher.TypedConfig[lines]{
+		Path:               path,
+		Parser:             parseLines,
+		InitialReadBackoff: time.Millisecond,
+		Default:            &def,
+		Logger:             log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.Stop()
+	if got := fw.Get(); len(got) != 1 || got[0] != "default" {
+		t.Errorf("Get() got %q, want the default", got)
+	}
+
+	if err := os.WriteFile(path, []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for got := fw.Get(); len(got) != 1 || got[0] != "foo"; got = fw.Get() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected [foo] after writing the file, got %q", got)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
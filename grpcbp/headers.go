@@ -9,28 +9,53 @@ import (
 	"github.com/reddit/baseplate.go/transport"
 )
 
-// AttachEdgeRequestContext returns a context that has the header of the edge
-// context attached to ctx object set to forward using the "Edge-Request"
-// header on any gRPC calls made with that context object.
-func AttachEdgeRequestContext(ctx context.Context, ecImpl ecinterface.Interface) context.Context {
-	if ecImpl == nil {
-		ecImpl = ecinterface.Get()
+// EdgeContextCarrier is the ecinterface.Carrier of the "Edge-Request" gRPC
+// metadata.
+//
+// Get reads the header from the incoming metadata,
+// Set and Unset update the outgoing metadata of the gRPC calls.
+type EdgeContextCarrier struct{}
+
+var _ ecinterface.Carrier = EdgeContextCarrier{}
+
+// Get implements ecinterface.Carrier.
+func (EdgeContextCarrier) Get(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", ecinterface.ErrNoHeader
 	}
-	header, ok := ecImpl.ContextToHeader(ctx)
+	header, ok := GetHeader(md, transport.HeaderEdgeRequest)
 	if !ok {
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return ctx
-		}
-		md.Delete(transport.HeaderEdgeRequest)
-		return metadata.NewOutgoingContext(ctx, md)
+		return "", ecinterface.ErrNoHeader
 	}
+	return header, nil
+}
+
+// Set implements ecinterface.Carrier.
+func (EdgeContextCarrier) Set(ctx context.Context, header string) context.Context {
 	return metadata.AppendToOutgoingContext(
 		ctx,
 		transport.HeaderEdgeRequest, header,
 	)
 }
 
+// Unset implements ecinterface.Carrier.
+func (EdgeContextCarrier) Unset(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	md.Delete(transport.HeaderEdgeRequest)
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// AttachEdgeRequestContext returns a context that has the header of the edge
+// context attached to ctx object set to forward using the "Edge-Request"
+// header on any gRPC calls made with that context object.
+func AttachEdgeRequestContext(ctx context.Context, ecImpl ecinterface.Interface) context.Context {
+	return ecinterface.Inject(ctx, ecImpl, EdgeContextCarrier{})
+}
+
 // GetHeader retrieves the header value for a given key. Since metadata.MD
 // headers are mapped to a list of strings this function checks if there is at
 // least one value present.
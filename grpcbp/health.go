@@ -0,0 +1,49 @@
+package grpcbp
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/reddit/baseplate.go/healthbp"
+	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+)
+
+// HealthServer returns a gRPC health server reading from registry.
+//
+// The service name of the requests is used as the probe
+// ("readiness", "liveness" or "startup", case insensitive),
+// the empty service name is the readiness probe.
+// Other service names are responded with NOT_FOUND status.
+//
+// Watch is not implemented.
+func HealthServer(registry *healthbp.Registry) healthpb.HealthServer {
+	return healthServer{registry: registry}
+}
+
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+
+	registry *healthbp.Registry
+}
+
+func (s healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	probe := baseplate.IsHealthyProbe_READINESS
+	if service := req.GetService(); service != "" {
+		var err error
+		probe, err = baseplate.IsHealthyProbeFromString(strings.ToUpper(service))
+		if err != nil {
+			return nil, status.Errorf(codes.NotFound, "grpcbp: unknown health check service %q", service)
+		}
+	}
+	resp := &healthpb.HealthCheckResponse{
+		Status: healthpb.HealthCheckResponse_SERVING,
+	}
+	if !s.registry.IsHealthy(ctx, int64(probe)) {
+		resp.Status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	return resp, nil
+}
@@ -0,0 +1,69 @@
+package grpcbp
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/reddit/baseplate.go/healthbp"
+)
+
+type fakeHealthChecker bool
+
+func (hc fakeHealthChecker) IsHealthy(context.Context) bool {
+	return bool(hc)
+}
+
+func TestHealthServer(t *testing.T) {
+	var registry healthbp.Registry
+	registry.Register(
+		"dependency",
+		healthbp.FromHealthChecker(fakeHealthChecker(false)),
+		healthbp.CheckerOptions{
+			Criticality: healthbp.CriticalityReadiness,
+		},
+	)
+	server := HealthServer(&registry)
+
+	for _, c := range []struct {
+		service string
+		want    healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{
+			service: "",
+			want:    healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+		{
+			service: "readiness",
+			want:    healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+		{
+			service: "LIVENESS",
+			want:    healthpb.HealthCheckResponse_SERVING,
+		},
+	} {
+		t.Run(c.service, func(t *testing.T) {
+			resp, err := server.Check(context.Background(), &healthpb.HealthCheckRequest{
+				Service: c.service,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.GetStatus() != c.want {
+				t.Errorf("Expected status %v, got %v", c.want, resp.GetStatus())
+			}
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := server.Check(context.Background(), &healthpb.HealthCheckRequest{
+			Service: "foo",
+		})
+		if code := status.Code(err); code != codes.NotFound {
+			t.Errorf("Expected NotFound, got %v (%v)", code, err)
+		}
+	})
+}
@@ -3,12 +3,17 @@ package grpcbp
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/faults"
 	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/ratelimit"
 	"github.com/reddit/baseplate.go/tracing"
 	"github.com/reddit/baseplate.go/transport"
 )
@@ -80,16 +85,7 @@ func InjectEdgeContextInterceptorStreaming(impl ecinterface.Interface) grpc.Stre
 // headers set on the context onto the context and configures gRPC to forward
 // the edge requent context header on any gRPC calls made by the server.
 func InitializeEdgeContext(ctx context.Context, impl ecinterface.Interface) context.Context {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return ctx
-	}
-	value, ok := GetHeader(md, transport.HeaderEdgeRequest)
-	if !ok {
-		return ctx
-	}
-
-	ctx, err := impl.HeaderToContext(ctx, value)
+	ctx, err := ecinterface.Extract(ctx, impl, EdgeContextCarrier{})
 	if err != nil {
 		log.C(ctx).Errorw(
 			"Error while parsing EdgeRequestContext",
@@ -99,6 +95,88 @@ func InitializeEdgeContext(ctx context.Context, impl ecinterface.Interface) cont
 	return ctx
 }
 
+// RateLimitInterceptorUnary is a server middleware that rate limits the
+// requests via limiter.
+//
+// key is used to get the rate limit key of the request (e.g. the caller from
+// the edge context), it's prefixed by the method slug so that the limits of
+// different methods are separated.
+// If key is nil, all the requests to the same method share the same limit.
+//
+// Returns a ResourceExhausted error when the request is rate limited.
+// When limiter returns an error, the error is logged and the request is
+// allowed (fail open).
+func RateLimitInterceptorUnary(limiter ratelimit.Limiter, key func(ctx context.Context) string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
+		k := methodSlug(info.FullMethod)
+		if key != nil {
+			k += ":" + key(ctx)
+		}
+		result, err := ratelimit.Allow(ctx, limiter, k)
+		if err != nil {
+			log.C(ctx).Warnw(
+				"grpcbp.RateLimitInterceptorUnary: limiter failed, allowing the request",
+				"err", err,
+				"key", k,
+			)
+			return handler(ctx, req)
+		}
+		if !result.Allowed {
+			return nil, status.Errorf(
+				codes.ResourceExhausted,
+				"%q rate limited, retry after %v",
+				k,
+				result.RetryAfter,
+			)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// InjectFaultsInterceptorUnary is a server middleware that injects the faults
+// from injector into the matching requests, for chaos experiments.
+//
+// The caller of the request is read from the "User-Agent"
+// (transport.HeaderUserAgent) header,
+// set by the clients via grpc.WithUserAgent,
+// with the " grpc-go/<version>" suffix added by grpc-go removed.
+// The fault delays the request first (cut short when the context is done).
+// Then, if the fault aborts, an Unavailable error is returned,
+// as the connection is not accessible to the interceptors;
+// otherwise if the fault has an error code for gRPC,
+// an error of the code is returned without calling the handler.
+//
+// A nil injector never injects anything.
+func InjectFaultsInterceptorUnary(injector *faults.Injector) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
+		var caller string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			caller, _ = GetHeader(md, transport.HeaderUserAgent)
+			if i := strings.LastIndex(caller, " grpc-go/"); i >= 0 {
+				caller = caller[:i]
+			}
+		}
+		fault, ok := injector.Match(faults.Request{
+			Protocol: faults.ProtocolGRPC,
+			Method:   info.FullMethod,
+			Caller:   caller,
+		})
+		if !ok {
+			return handler(ctx, req)
+		}
+		if err := fault.Wait(ctx); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+		if fault.Abort {
+			return nil, status.Errorf(codes.Unavailable, "connection aborted by fault injection rule %q", fault.Rule)
+		}
+		if fault.HasCode {
+			return nil, status.Errorf(codes.Code(fault.Code), "fault injected by rule %q", fault.Rule)
+		}
+		return handler(ctx, req)
+	}
+}
+
 // StartSpanFromGRPCContext creates a server span from a gRPC context object.
 //
 // This span would usually be used as the span of the whole gRPC endpoint
@@ -136,7 +214,7 @@ func StartSpanFromGRPCContext(ctx context.Context, name string) (context.Context
 	}
 
 	if value, ok := GetHeader(md, transport.HeaderTracingSampled); ok {
-		sampled = value == transport.HeaderTracingSampled
+		sampled = value == transport.HeaderTracingSampledTrue
 		headers.Sampled = &sampled
 	}
 
@@ -9,10 +9,14 @@ import (
 
 	pb "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/faults"
 	"github.com/reddit/baseplate.go/mqsend"
+	"github.com/reddit/baseplate.go/ratelimit"
 	"github.com/reddit/baseplate.go/tracing"
 	"github.com/reddit/baseplate.go/transport"
 )
@@ -135,6 +139,85 @@ func TestInjectEdgeContextInterceptorUnary(t *testing.T) {
 	}
 }
 
+type fakeLimiter struct {
+	allowed int
+	keys    []string
+}
+
+func (l *fakeLimiter) AllowN(_ context.Context, key string, _ int64) (ratelimit.Result, error) {
+	l.keys = append(l.keys, key)
+	if len(l.keys) > l.allowed {
+		return ratelimit.Result{RetryAfter: time.Second}, nil
+	}
+	return ratelimit.Result{Allowed: true}, nil
+}
+
+func TestRateLimitInterceptorUnary(t *testing.T) {
+	limiter := &fakeLimiter{allowed: 1}
+	l, _ := setupServer(t, grpc.UnaryInterceptor(
+		RateLimitInterceptorUnary(limiter, func(ctx context.Context) string {
+			return "client"
+		}),
+	))
+	conn := setupClient(t, l)
+	client := pb.NewTestServiceClient(conn)
+
+	if _, err := client.Ping(context.Background(), &pb.PingRequest{}); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	_, err := client.Ping(context.Background(), &pb.PingRequest{})
+	if got := status.Code(err); got != codes.ResourceExhausted {
+		t.Errorf("Expected code %v, got %v (%v)", codes.ResourceExhausted, got, err)
+	}
+	for _, k := range limiter.keys {
+		if k != "Ping:client" {
+			t.Errorf("Expected key %q, got %q", "Ping:client", k)
+		}
+	}
+}
+
+func TestInjectFaultsInterceptorUnary(t *testing.T) {
+	injector, err := faults.NewInjector(faults.Config{
+		Rules: []faults.Rule{
+			{
+				Name:       "abort",
+				Callers:    []string{"abort*"},
+				Percentage: 100,
+				Abort:      true,
+			},
+			{
+				Name:       "error",
+				Methods:    []string{"/*/Ping"},
+				Callers:    []string{"frontend"},
+				Percentage: 100,
+				Codes:      map[faults.Protocol]int{faults.ProtocolGRPC: int(codes.Internal)},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, _ := setupServer(t, grpc.UnaryInterceptor(InjectFaultsInterceptorUnary(injector)))
+
+	for _, c := range []struct {
+		caller string
+		want   codes.Code
+	}{
+		{caller: "backend", want: codes.OK},
+		{caller: "frontend", want: codes.Internal},
+		{caller: "abort", want: codes.Unavailable},
+	} {
+		t.Run(c.caller, func(t *testing.T) {
+			conn := setupClient(t, l, grpc.WithUserAgent(c.caller))
+			client := pb.NewTestServiceClient(conn)
+			_, err := client.Ping(context.Background(), &pb.PingRequest{})
+			if got := status.Code(err); got != c.want {
+				t.Errorf("Expected code %v, got %v (%v)", c.want, got, err)
+			}
+		})
+	}
+}
+
 func initTracing(t *testing.T) *mqsend.MockMessageQueue {
 	t.Helper()
 
@@ -181,3 +264,33 @@ func (t *mockService) PingList(req *pb.PingRequest, c pb.TestService_PingListSer
 func (t *mockService) PingStream(c pb.TestService_PingStreamServer) error {
 	panic("not implemented")
 }
+
+func TestStartSpanFromGRPCContextSampled(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		value    string
+		expected bool
+	}{
+		{
+			label:    "true",
+			value:    transport.HeaderTracingSampledTrue,
+			expected: true,
+		},
+		{
+			label:    "false",
+			value:    "0",
+			expected: false,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(
+				context.Background(),
+				metadata.Pairs(transport.HeaderTracingSampled, c.value),
+			)
+			_, span := StartSpanFromGRPCContext(ctx, "test")
+			if span.Sampled() != c.expected {
+				t.Errorf("Expected sampled %v, got %v", c.expected, span.Sampled())
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// Package healthbp provides a central registry of the health checkers of the
+// dependencies of a service, read by all the health probes.
+//
+// The subsystems register named checkers with their criticality levels into a
+// Registry (usually DefaultRegistry),
+// and the health probes read from it:
+//
+// - thrift is_healthy endpoint: thriftbp.HealthCheck middleware,
+// or Registry.IsHealthy from the handler.
+//
+// - HTTP: httpbp.HealthCheckHandlerFunc.
+//
+// - gRPC: grpcbp.HealthServer.
+//
+// The admin server also serves the detailed per-checker results of
+// DefaultRegistry on adminbp.HealthPath for debugging.
+//
+// The following dependencies register their checkers into DefaultRegistry
+// when they are created, and remove them when they are closed:
+//
+// - thrift client pools, see thriftbp.ClientPoolConfig.HealthCriticality.
+//
+// - kafka consumers, see kafkabp.ConsumerConfig.HealthCriticality.
+//
+// - the secrets Store of baseplate.New, reporting the expired secrets,
+// see SecretsCheckerName.
+//
+// They default to CriticalityNone, so they are only reported unless
+// configured otherwise.
+// The other dependencies (e.g. Redis clients, with redisbp.HealthCheck) can be
+// registered manually, see the example below.
+//
+// A checker affects the probes decided by its Criticality,
+// or the probes explicitly listed in CheckerOptions.Probes.
+//
+// Every checker has its own timeout and cache TTL (see CheckerOptions),
+// so a slow or frequently probed dependency doesn't slow down or overload the
+// probes.
+//
+// The dependencies initialized in the background (e.g. kafka consumers,
+// experiments files and thrift client pools warming up) can also hold off
+// the traffic until they are initialized via StartupGate:
+// the StartupGate of the Baseplate returned by baseplate.New fails the
+// readiness probes and delays baseplate.Serve until all the StartupSignals
+// required from it are ready (see baseplate.StartupGateProvider).
+//
+// Example:
+//
+//	healthbp.Register(
+//		"redis",
+//		redisbp.HealthCheck(redisClient),
+//		healthbp.CheckerOptions{
+//			Criticality: healthbp.CriticalityReadiness,
+//			Timeout:     time.Millisecond * 100,
+//		},
+//	)
+package healthbp
@@ -0,0 +1,37 @@
+package healthbp
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ProbeQuery is the name of the HTTP query used by Handler to specify the
+// probe, the same as httpbp.HealthCheckProbeQuery.
+const ProbeQuery = "type"
+
+// Handler returns an http.Handler responding with the JSON encoded Report of
+// registry, for debugging the health checkers.
+//
+// The probe is specified by ProbeQuery, by name (e.g. "liveness") or by int
+// value, and defaults to readiness.
+//
+// Unlike httpbp.HealthCheckHandlerFunc, which is meant to be used as the
+// health probe, it always responds with 200 when the probe was run,
+// regardless of the health of the service.
+// It's registered into the admin server (see adminbp.HealthPath) for
+// DefaultRegistry.
+func Handler(registry *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probe := ProbeReadiness
+		if s := r.URL.Query().Get(ProbeQuery); s != "" {
+			var err error
+			probe, err = ParseProbe(s)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(registry.Check(r.Context(), probe))
+	})
+}
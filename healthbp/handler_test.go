@@ -0,0 +1,63 @@
+package healthbp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	var r Registry
+	r.Register("consumer", FromHealthChecker(fakeHealthChecker(false)), CheckerOptions{
+		Criticality: CriticalityReadiness,
+	})
+	handler := Handler(&r)
+
+	for _, c := range []struct {
+		query     string
+		code      int
+		probe     string
+		healthy   bool
+		affecting bool
+	}{
+		{query: "", code: http.StatusOK, probe: "readiness", healthy: false, affecting: true},
+		{query: "?type=liveness", code: http.StatusOK, probe: "liveness", healthy: true, affecting: false},
+		{query: "?type=foo", code: http.StatusBadRequest},
+	} {
+		t.Run(c.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health"+c.query, nil))
+			if w.Code != c.code {
+				t.Fatalf("Expected code %d, got %d: %s", c.code, w.Code, w.Body.String())
+			}
+			if c.code != http.StatusOK {
+				return
+			}
+
+			var report struct {
+				Probe    string `json:"probe"`
+				Healthy  bool   `json:"healthy"`
+				Checkers []struct {
+					Name        string `json:"name"`
+					Criticality string `json:"criticality"`
+					Affecting   bool   `json:"affecting"`
+					Error       string `json:"error"`
+				} `json:"checkers"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+				t.Fatal(err)
+			}
+			if report.Probe != c.probe || report.Healthy != c.healthy {
+				t.Errorf("Expected probe %q healthy %v, got %#v", c.probe, c.healthy, report)
+			}
+			if len(report.Checkers) != 1 {
+				t.Fatalf("Expected 1 checker, got %#v", report.Checkers)
+			}
+			checker := report.Checkers[0]
+			if checker.Name != "consumer" || checker.Criticality != "readiness" || checker.Affecting != c.affecting || checker.Error != ErrUnhealthy.Error() {
+				t.Errorf("Unexpected checker result: %#v", checker)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package healthbp
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Label names of the Prometheus metrics reported by healthbp.
+const (
+	PrometheusCheckerLabel     = "healthbp_checker"
+	PrometheusCriticalityLabel = "healthbp_criticality"
+)
+
+var (
+	checkerHealthyGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "healthbp_checker_healthy",
+		Help: "Whether the last run of the health checker reported healthy (1) or not (0)",
+	}, []string{
+		PrometheusCheckerLabel,
+		PrometheusCriticalityLabel,
+	})
+)
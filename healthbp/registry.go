@@ -0,0 +1,450 @@
+package healthbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+	"github.com/reddit/baseplate.go/internal/prometheusbpint"
+)
+
+// Default values of CheckerOptions.
+const (
+	DefaultTimeout  = time.Second
+	DefaultCacheTTL = time.Second
+)
+
+// SecretsCheckerName is the name baseplate.New registers the checker of its
+// secrets Store (secrets.Store.CheckExpiration) into DefaultRegistry with,
+// with CriticalityNone.
+const SecretsCheckerName = "secrets"
+
+// The probes defined in baseplate.thrift,
+// to be used in CheckerOptions.Probes and Registry.Check.
+const (
+	ProbeReadiness = int64(baseplate.IsHealthyProbe_READINESS)
+	ProbeLiveness  = int64(baseplate.IsHealthyProbe_LIVENESS)
+	ProbeStartup   = int64(baseplate.IsHealthyProbe_STARTUP)
+)
+
+// ProbeString returns the lowercase name of probe,
+// e.g. "readiness" for ProbeReadiness,
+// or the int value for the probes unknown to this version of Baseplate.go.
+func ProbeString(probe int64) string {
+	switch p := baseplate.IsHealthyProbe(probe); p {
+	default:
+		return strconv.FormatInt(probe, 10)
+	case baseplate.IsHealthyProbe_READINESS, baseplate.IsHealthyProbe_LIVENESS, baseplate.IsHealthyProbe_STARTUP:
+		return strings.ToLower(p.String())
+	}
+}
+
+// ParseProbe parses the probe from its name (case insensitive)
+// or its int value.
+func ParseProbe(s string) (int64, error) {
+	if probe, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return probe, nil
+	}
+	probe, err := baseplate.IsHealthyProbeFromString(strings.ToUpper(s))
+	if err != nil {
+		return 0, fmt.Errorf("healthbp: unknown probe %q", s)
+	}
+	return int64(probe), nil
+}
+
+// Errors reported by the checkers.
+var (
+	// ErrUnhealthy is the error reported by the checkers created by
+	// FromHealthChecker when IsHealthy returns false.
+	ErrUnhealthy = errors.New("healthbp: unhealthy")
+
+	// ErrTimeout is the error reported when a checker didn't return within its
+	// timeout.
+	ErrTimeout = errors.New("healthbp: checker timed out")
+)
+
+// Criticality decides which probes a checker affects.
+type Criticality int
+
+// Criticality values.
+const (
+	// CriticalityNone checkers are only reported, they never fail any probes.
+	//
+	// It's for the dependencies the service can run without,
+	// e.g. a cache with fallbacks.
+	CriticalityNone Criticality = iota
+
+	// CriticalityReadiness checkers fail the readiness and startup probes,
+	// so the service stops receiving traffic until they recover.
+	CriticalityReadiness
+
+	// CriticalityLiveness checkers fail all the probes, including the liveness
+	// probe, so the service gets restarted.
+	//
+	// It's for the failures the service can't recover from without a restart,
+	// e.g. a kafka consumer that stopped consuming.
+	CriticalityLiveness
+)
+
+func (c Criticality) String() string {
+	switch c {
+	default:
+		return fmt.Sprintf("Criticality(%d)", int(c))
+	case CriticalityNone:
+		return "none"
+	case CriticalityReadiness:
+		return "readiness"
+	case CriticalityLiveness:
+		return "liveness"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c Criticality) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler,
+// so Criticality can be deserialized from YAML as "none", "readiness" or
+// "liveness".
+func (c *Criticality) UnmarshalText(text []byte) error {
+	for _, v := range []Criticality{CriticalityNone, CriticalityReadiness, CriticalityLiveness} {
+		if strings.EqualFold(string(text), v.String()) {
+			*c = v
+			return nil
+		}
+	}
+	return fmt.Errorf("healthbp: unknown criticality %q", text)
+}
+
+// affects returns true if the failures of the checkers with criticality c
+// fail probe.
+func (c Criticality) affects(probe int64) bool {
+	if baseplate.IsHealthyProbe(probe) == baseplate.IsHealthyProbe_LIVENESS {
+		return c >= CriticalityLiveness
+	}
+	return c >= CriticalityReadiness
+}
+
+// CheckFunc checks the health of a dependency,
+// and returns a non-nil error when it's unhealthy.
+//
+// It should return promptly when ctx is done.
+type CheckFunc func(ctx context.Context) error
+
+// HealthChecker defines an interface to report healthy status.
+//
+// It's the same as baseplate.HealthChecker,
+// implemented by baseplate.Drainer and kafkabp.Consumer, etc.
+type HealthChecker interface {
+	IsHealthy(ctx context.Context) bool
+}
+
+// FromHealthChecker returns a CheckFunc reporting ErrUnhealthy when the
+// IsHealthy of hc returns false.
+func FromHealthChecker(hc HealthChecker) CheckFunc {
+	return func(ctx context.Context) error {
+		if !hc.IsHealthy(ctx) {
+			return ErrUnhealthy
+		}
+		return nil
+	}
+}
+
+// CheckerOptions are the options of a checker registered into a Registry.
+type CheckerOptions struct {
+	// Criticality decides which probes the checker affects.
+	//
+	// Optional, defaults to CriticalityNone.
+	Criticality Criticality
+
+	// Probes are the probes the checker affects (e.g. ProbeStartup only),
+	// for the checkers that don't fit in any of the Criticality levels.
+	//
+	// Optional, when it's non-empty it takes precedence over Criticality.
+	Probes []int64
+
+	// Timeout is the max time to wait for the checker,
+	// after which it's reported with ErrTimeout.
+	//
+	// Optional, <=0 means DefaultTimeout.
+	Timeout time.Duration
+
+	// CacheTTL is the time the result of the checker is reused for,
+	// to avoid overloading the dependencies with frequent probes.
+	//
+	// Optional, 0 means DefaultCacheTTL, <0 disables caching.
+	CacheTTL time.Duration
+}
+
+// affects returns true if the failures of the checker fail probe.
+func (o CheckerOptions) affects(probe int64) bool {
+	if len(o.Probes) == 0 {
+		return o.Criticality.affects(probe)
+	}
+	for _, p := range o.Probes {
+		if p == probe {
+			return true
+		}
+	}
+	return false
+}
+
+// Result is the result of a checker.
+type Result struct {
+	Name        string      `json:"name"`
+	Criticality Criticality `json:"criticality"`
+
+	// Affecting is true when the checker affects the probe of the Report.
+	//
+	// An unhealthy result fails the probe only when it's affecting.
+	Affecting bool `json:"affecting"`
+
+	// Err is the error returned by the checker, nil means healthy.
+	Err error `json:"-"`
+
+	// Error is the message of Err, for the JSON encoding.
+	Error string `json:"error,omitempty"`
+
+	// CheckedAt is the time the checker was run,
+	// which could be earlier than the Report when the result was cached.
+	CheckedAt time.Time `json:"checkedAt"`
+
+	// Duration is the time the checker took to run.
+	Duration time.Duration `json:"duration"`
+
+	// Cached is true when the result was reused from a previous run.
+	Cached bool `json:"cached"`
+}
+
+// Healthy returns true if the checker reported no error.
+func (r Result) Healthy() bool {
+	return r.Err == nil
+}
+
+// Report is the results of all the checkers for a probe.
+type Report struct {
+	// Probe is the name of the probe of the report, e.g. "readiness".
+	Probe string `json:"probe"`
+
+	// Healthy is true when none of the checkers affecting the probe failed.
+	Healthy bool `json:"healthy"`
+
+	// Results are the results of all the checkers registered,
+	// sorted by their names.
+	Results []Result `json:"checkers"`
+}
+
+type checker struct {
+	name  string
+	check CheckFunc
+	opts  CheckerOptions
+
+	lock   sync.Mutex
+	last   Result
+	cached bool
+}
+
+func (c *checker) run(ctx context.Context, now func() time.Time) Result {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	ttl := c.opts.CacheTTL
+	if ttl == 0 {
+		ttl = DefaultCacheTTL
+	}
+	if c.cached && ttl > 0 && now().Sub(c.last.CheckedAt) < ttl {
+		result := c.last
+		result.Cached = true
+		return result
+	}
+
+	timeout := c.opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := Result{
+		Name:        c.name,
+		Criticality: c.opts.Criticality,
+		CheckedAt:   now(),
+	}
+	// Run the checker in its own goroutine so a checker not honoring ctx can't
+	// block the probes.
+	errs := make(chan error, 1)
+	go func() {
+		errs <- c.check(ctx)
+	}()
+	select {
+	case err := <-errs:
+		result.Err = err
+	case <-ctx.Done():
+		result.Err = ErrTimeout
+	}
+	result.Duration = now().Sub(result.CheckedAt)
+	if result.Err != nil {
+		result.Error = result.Err.Error()
+	}
+	checkerHealthyGauge.WithLabelValues(c.name, c.opts.Criticality.String()).Set(prometheusbpint.BoolFloat(result.Err == nil))
+
+	c.last = result
+	c.cached = true
+	return result
+}
+
+// Registry is the central registry of the health checkers of the
+// dependencies of a service,
+// read by the health probes (e.g. the thrift is_healthy endpoint,
+// httpbp.HealthCheckHandlerFunc, and grpcbp.HealthServer).
+//
+// Registry is safe to be used concurrently.
+// The zero value is an empty Registry ready to use.
+type Registry struct {
+	lock     sync.RWMutex
+	checkers map[string]*checker
+
+	// for testing
+	now func() time.Time
+}
+
+// DefaultRegistry is the Registry used by the package level Register and
+// Check functions.
+var DefaultRegistry = new(Registry)
+
+// Register registers a checker with name,
+// replacing the existing one with the same name.
+func (r *Registry) Register(name string, check CheckFunc, opts CheckerOptions) {
+	r.Add(name, check, opts)
+}
+
+// Unregister removes the checker with name.
+func (r *Registry) Unregister(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.checkers, name)
+}
+
+// Add is the same as Register,
+// but returns the function to remove the checker added,
+// for the checkers registered by the dependencies when they are created and
+// removed when they are closed.
+//
+// Unlike Unregister, the function returned doesn't remove the checker with the
+// same name registered afterwards.
+func (r *Registry) Add(name string, check CheckFunc, opts CheckerOptions) (remove func()) {
+	c := &checker{
+		name:  name,
+		check: check,
+		opts:  opts,
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.checkers == nil {
+		r.checkers = make(map[string]*checker)
+	}
+	r.checkers[name] = c
+	return func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		if r.checkers[name] == c {
+			delete(r.checkers, name)
+		}
+	}
+}
+
+// Check runs all the checkers concurrently (or reuses their cached results),
+// and returns the Report for probe.
+//
+// probe is one of the values of the IsHealthyProbe enum defined in
+// baseplate.thrift (see also httpbp.GetHealthCheckProbe).
+// The liveness probe is only affected by the CriticalityLiveness checkers,
+// other probes (including the unknown ones) are affected by the
+// CriticalityReadiness and CriticalityLiveness checkers,
+// unless the checker explicitly lists the probes it affects in
+// CheckerOptions.Probes.
+func (r *Registry) Check(ctx context.Context, probe int64) Report {
+	r.lock.RLock()
+	checkers := make([]*checker, 0, len(r.checkers))
+	for _, c := range r.checkers {
+		checkers = append(checkers, c)
+	}
+	now := r.now
+	r.lock.RUnlock()
+	if now == nil {
+		now = time.Now
+	}
+
+	sort.Slice(checkers, func(i, j int) bool {
+		return checkers[i].name < checkers[j].name
+	})
+
+	report := Report{
+		Probe:   ProbeString(probe),
+		Healthy: true,
+		Results: make([]Result, len(checkers)),
+	}
+	var wg sync.WaitGroup
+	wg.Add(len(checkers))
+	for i, c := range checkers {
+		go func(i int, c *checker) {
+			defer wg.Done()
+			report.Results[i] = c.run(ctx, now)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for i, c := range checkers {
+		result := &report.Results[i]
+		result.Affecting = c.opts.affects(probe)
+		if result.Affecting && !result.Healthy() {
+			report.Healthy = false
+		}
+	}
+	return report
+}
+
+// IsHealthy returns whether the service passes probe,
+// it's the shorthand of Check(ctx, probe).Healthy.
+//
+// It can be used to implement the is_healthy endpoint of thrift services,
+// when not using thriftbp.HealthCheck:
+//
+//	func (h *Handler) IsHealthy(ctx context.Context, req *baseplate.IsHealthyRequest) (bool, error) {
+//		return healthbp.IsHealthy(ctx, int64(req.GetProbe())), nil
+//	}
+func (r *Registry) IsHealthy(ctx context.Context, probe int64) bool {
+	return r.Check(ctx, probe).Healthy
+}
+
+// Register registers a checker into DefaultRegistry.
+func Register(name string, check CheckFunc, opts CheckerOptions) {
+	DefaultRegistry.Register(name, check, opts)
+}
+
+// Unregister removes a checker from DefaultRegistry.
+func Unregister(name string) {
+	DefaultRegistry.Unregister(name)
+}
+
+// Add adds a checker into DefaultRegistry, see Registry.Add.
+func Add(name string, check CheckFunc, opts CheckerOptions) (remove func()) {
+	return DefaultRegistry.Add(name, check, opts)
+}
+
+// Check calls Check of DefaultRegistry.
+func Check(ctx context.Context, probe int64) Report {
+	return DefaultRegistry.Check(ctx, probe)
+}
+
+// IsHealthy calls IsHealthy of DefaultRegistry.
+func IsHealthy(ctx context.Context, probe int64) bool {
+	return DefaultRegistry.IsHealthy(ctx, probe)
+}
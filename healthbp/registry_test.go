@@ -0,0 +1,262 @@
+package healthbp
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	readiness = ProbeReadiness
+	liveness  = ProbeLiveness
+	startup   = ProbeStartup
+)
+
+type fakeHealthChecker bool
+
+func (hc fakeHealthChecker) IsHealthy(context.Context) bool {
+	return bool(hc)
+}
+
+func TestRegistryCriticality(t *testing.T) {
+	errDown := errors.New("down")
+	failing := func(context.Context) error {
+		return errDown
+	}
+
+	for _, c := range []struct {
+		label       string
+		criticality Criticality
+		want        map[int64]bool
+	}{
+		{
+			label:       "none",
+			criticality: CriticalityNone,
+			want: map[int64]bool{
+				readiness: true,
+				liveness:  true,
+				startup:   true,
+			},
+		},
+		{
+			label:       "readiness",
+			criticality: CriticalityReadiness,
+			want: map[int64]bool{
+				readiness: false,
+				liveness:  true,
+				startup:   false,
+			},
+		},
+		{
+			label:       "liveness",
+			criticality: CriticalityLiveness,
+			want: map[int64]bool{
+				readiness: false,
+				liveness:  false,
+				startup:   false,
+			},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var r Registry
+			r.Register("ok", FromHealthChecker(fakeHealthChecker(true)), CheckerOptions{
+				Criticality: CriticalityLiveness,
+			})
+			r.Register("failing", failing, CheckerOptions{
+				Criticality: c.criticality,
+			})
+			for probe, want := range c.want {
+				report := r.Check(context.Background(), probe)
+				if report.Healthy != want {
+					t.Errorf("probe %d: expected healthy %v, got %v", probe, want, report.Healthy)
+				}
+				if len(report.Results) != 2 {
+					t.Fatalf("Expected 2 results, got %#v", report.Results)
+				}
+				failed, ok := report.Results[0], report.Results[1]
+				if failed.Name != "failing" || !errors.Is(failed.Err, errDown) || failed.Error != "down" {
+					t.Errorf("Unexpected result of failing checker: %#v", failed)
+				}
+				if ok.Name != "ok" || !ok.Healthy() {
+					t.Errorf("Unexpected result of ok checker: %#v", ok)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistryCache(t *testing.T) {
+	now := time.Unix(1000, 0)
+	var calls int64
+	check := func(context.Context) error {
+		atomic.AddInt64(&calls, 1)
+		return nil
+	}
+
+	r := Registry{
+		now: func() time.Time {
+			return now
+		},
+	}
+	r.Register("cached", check, CheckerOptions{CacheTTL: time.Second})
+	r.Register("uncached", check, CheckerOptions{CacheTTL: -1})
+
+	r.Check(context.Background(), readiness)
+	report := r.Check(context.Background(), readiness)
+	if got := atomic.LoadInt64(&calls); got != 3 {
+		t.Errorf("Expected 3 calls within ttl, got %d", got)
+	}
+	if cached, uncached := report.Results[0], report.Results[1]; !cached.Cached || uncached.Cached {
+		t.Errorf("Expected only the cached checker reported as cached, got %#v", report.Results)
+	}
+
+	now = now.Add(time.Second)
+	r.Check(context.Background(), readiness)
+	if got := atomic.LoadInt64(&calls); got != 5 {
+		t.Errorf("Expected 5 calls after ttl, got %d", got)
+	}
+}
+
+func TestRegistryTimeout(t *testing.T) {
+	block := make(chan struct{})
+	t.Cleanup(func() { close(block) })
+
+	var r Registry
+	r.Register(
+		"slow",
+		func(context.Context) error {
+			// Not honoring ctx.
+			<-block
+			return nil
+		},
+		CheckerOptions{
+			Criticality: CriticalityReadiness,
+			Timeout:     time.Millisecond * 10,
+		},
+	)
+
+	start := time.Now()
+	report := r.Check(context.Background(), readiness)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Check took too long: %v", elapsed)
+	}
+	if report.Healthy {
+		t.Error("Expected unhealthy report")
+	}
+	if err := report.Results[0].Err; !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestRegistryUnregister(t *testing.T) {
+	var r Registry
+	r.Register("failing", FromHealthChecker(fakeHealthChecker(false)), CheckerOptions{
+		Criticality: CriticalityReadiness,
+	})
+	if r.IsHealthy(context.Background(), readiness) {
+		t.Error("Expected unhealthy before unregister")
+	}
+	r.Unregister("failing")
+	if !r.IsHealthy(context.Background(), readiness) {
+		t.Error("Expected healthy after unregister")
+	}
+}
+
+func TestRegistryAdd(t *testing.T) {
+	var r Registry
+	remove := r.Add("dep", FromHealthChecker(fakeHealthChecker(false)), CheckerOptions{
+		Criticality: CriticalityReadiness,
+	})
+	if r.IsHealthy(context.Background(), readiness) {
+		t.Error("Expected unhealthy after add")
+	}
+	remove()
+	if !r.IsHealthy(context.Background(), readiness) {
+		t.Error("Expected healthy after remove")
+	}
+
+	// The remove function doesn't remove the checker replacing the one added.
+	remove = r.Add("dep", FromHealthChecker(fakeHealthChecker(true)), CheckerOptions{})
+	r.Register("dep", FromHealthChecker(fakeHealthChecker(false)), CheckerOptions{
+		Criticality: CriticalityReadiness,
+	})
+	remove()
+	if r.IsHealthy(context.Background(), readiness) {
+		t.Error("Expected the replacing checker kept")
+	}
+}
+
+func TestCriticalityUnmarshalText(t *testing.T) {
+	for _, c := range []Criticality{CriticalityNone, CriticalityReadiness, CriticalityLiveness} {
+		text, err := c.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Criticality
+		if err := got.UnmarshalText(text); err != nil {
+			t.Errorf("UnmarshalText(%q) returned %v", text, err)
+		}
+		if got != c {
+			t.Errorf("UnmarshalText(%q) expected %v, got %v", text, c, got)
+		}
+	}
+	var c Criticality
+	if err := c.UnmarshalText([]byte("fatal")); err == nil {
+		t.Error("Expected error for unknown criticality")
+	}
+}
+
+func TestRegistryProbes(t *testing.T) {
+	var r Registry
+	r.Register("warmup", FromHealthChecker(fakeHealthChecker(false)), CheckerOptions{
+		// Probes takes precedence over Criticality.
+		Criticality: CriticalityLiveness,
+		Probes:      []int64{startup},
+	})
+	for probe, want := range map[int64]bool{
+		readiness: true,
+		liveness:  true,
+		startup:   false,
+	} {
+		report := r.Check(context.Background(), probe)
+		if report.Healthy != want {
+			t.Errorf("probe %d: expected healthy %v, got %v", probe, want, report.Healthy)
+		}
+		if report.Results[0].Affecting == want {
+			t.Errorf("probe %d: expected affecting %v, got %v", probe, !want, report.Results[0].Affecting)
+		}
+		if report.Probe != ProbeString(probe) {
+			t.Errorf("probe %d: expected probe name %q, got %q", probe, ProbeString(probe), report.Probe)
+		}
+	}
+}
+
+func TestParseProbe(t *testing.T) {
+	for _, c := range []struct {
+		s    string
+		want int64
+		name string
+	}{
+		{s: "readiness", want: readiness, name: "readiness"},
+		{s: "LIVENESS", want: liveness, name: "liveness"},
+		{s: "3", want: startup, name: "startup"},
+		{s: "42", want: 42, name: "42"},
+	} {
+		probe, err := ParseProbe(c.s)
+		if err != nil {
+			t.Errorf("ParseProbe(%q) returned error: %v", c.s, err)
+		}
+		if probe != c.want {
+			t.Errorf("ParseProbe(%q) expected %d, got %d", c.s, c.want, probe)
+		}
+		if name := ProbeString(probe); name != c.name {
+			t.Errorf("ProbeString(%d) expected %q, got %q", probe, c.name, name)
+		}
+	}
+
+	if _, err := ParseProbe("foo"); err == nil {
+		t.Error("Expected error for unknown probe")
+	}
+}
@@ -0,0 +1,206 @@
+package healthbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/reddit/baseplate.go/errorsbp"
+)
+
+// ErrNotInitialized is the error reported by a StartupGate for the
+// StartupSignals not yet reported ready.
+var ErrNotInitialized = errors.New("healthbp: not initialized")
+
+// StartupCheckerName is the name baseplate.New registers the StartupGate of
+// the Baseplate into DefaultRegistry with.
+const StartupCheckerName = "startup"
+
+// StartupGate tracks the initialization of the long-lived dependencies of a
+// service (e.g. kafka consumers, or caches warmed up in the background).
+//
+// Every dependency required to be initialized before the service takes
+// traffic calls Require and reports the returned StartupSignal ready once it's
+// initialized.
+// Until all of them are ready, Check keeps failing the readiness probes,
+// and baseplate.Serve holds off serving (see baseplate.StartupGateProvider and
+// baseplate.Config.StartupTimeout).
+//
+// Only the dependencies initialized in the background need to require a
+// StartupSignal, the ones initialized synchronously (e.g. the secrets Store)
+// are already ready when their constructors return.
+// They take their StartupSignals in their configs,
+// e.g. kafkabp.ConsumerConfig, thriftbp.ClientPoolConfig (for the pool
+// warmed up in the background), experiments.Config and filewatcher.Config
+// (for the files loaded in the background).
+//
+// StartupGate is safe to be used concurrently.
+// The zero value is an empty StartupGate ready to use.
+type StartupGate struct {
+	lock    sync.Mutex
+	signals map[string]*StartupSignal
+
+	// changed is closed and replaced whenever a signal is reported.
+	changed chan struct{}
+}
+
+// StartupSignal is the initialization signal of a dependency,
+// returned by StartupGate.Require.
+//
+// A nil *StartupSignal is valid and all its methods are no-ops,
+// so the dependencies can report unconditionally.
+type StartupSignal struct {
+	gate *StartupGate
+	name string
+
+	// Guarded by the lock of gate.
+	ready bool
+	err   error
+}
+
+// Require registers a dependency named name required to be initialized,
+// and returns the StartupSignal for it to report.
+//
+// Requiring the same name again replaces the previous StartupSignal,
+// which no longer affects the gate.
+func (g *StartupGate) Require(name string) *StartupSignal {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.signals == nil {
+		g.signals = make(map[string]*StartupSignal)
+	}
+	s := &StartupSignal{
+		gate: g,
+		name: name,
+	}
+	g.signals[name] = s
+	g.notifyLocked()
+	return s
+}
+
+// Release removes the StartupSignal with name, if any,
+// so the gate no longer waits for it.
+func (g *StartupGate) Release(name string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	delete(g.signals, name)
+	g.notifyLocked()
+}
+
+// Ready reports the dependency initialized.
+//
+// It clears the error previously reported by Fail.
+func (s *StartupSignal) Ready() {
+	s.report(true, nil)
+}
+
+// Fail reports that the dependency failed to initialize with err,
+// which fails StartupGate.Wait immediately instead of waiting for the
+// deadline.
+//
+// It's only for the unrecoverable failures,
+// the dependencies still retrying should just keep the signal pending.
+func (s *StartupSignal) Fail(err error) {
+	if err == nil {
+		err = ErrNotInitialized
+	}
+	s.report(false, err)
+}
+
+func (s *StartupSignal) report(ready bool, err error) {
+	if s == nil {
+		return
+	}
+	g := s.gate
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	s.ready = ready
+	s.err = err
+	g.notifyLocked()
+}
+
+// notifyLocked wakes up the goroutines blocked in Wait.
+//
+// The lock must be held by the caller.
+func (g *StartupGate) notifyLocked() {
+	if g.changed != nil {
+		close(g.changed)
+		g.changed = nil
+	}
+}
+
+// state returns the channel closed on the next change,
+// whether any of the signals failed,
+// and the aggregated error of the signals not ready.
+func (g *StartupGate) state() (changed <-chan struct{}, failed bool, err error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	var batch errorsbp.Batch
+	for _, name := range g.pendingLocked() {
+		s := g.signals[name]
+		if s.err != nil {
+			failed = true
+			batch.Add(fmt.Errorf("healthbp: %q failed to initialize: %w", name, s.err))
+			continue
+		}
+		batch.Add(fmt.Errorf("%w: %q", ErrNotInitialized, name))
+	}
+
+	if g.changed == nil {
+		g.changed = make(chan struct{})
+	}
+	return g.changed, failed, batch.Compile()
+}
+
+// Pending returns the names of the StartupSignals not yet reported ready,
+// sorted.
+func (g *StartupGate) Pending() []string {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.pendingLocked()
+}
+
+func (g *StartupGate) pendingLocked() []string {
+	var names []string
+	for name, s := range g.signals {
+		if !s.ready {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check is a CheckFunc reporting the StartupSignals not yet ready.
+//
+// It returns nil once all of them are ready.
+func (g *StartupGate) Check(_ context.Context) error {
+	_, _, err := g.state()
+	return err
+}
+
+// Wait blocks until all the StartupSignals are ready,
+// any of them failed, or ctx is done.
+//
+// When it doesn't return nil,
+// the returned error is an errorsbp.Batch naming all the StartupSignals not
+// ready, and includes ctx.Err() when ctx is done.
+func (g *StartupGate) Wait(ctx context.Context) error {
+	for {
+		changed, failed, err := g.state()
+		if err == nil || failed {
+			return err
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			var batch errorsbp.Batch
+			batch.AddPrefix("healthbp: startup gate", ctx.Err())
+			batch.Add(err)
+			return batch.Compile()
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package healthbp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartupGateEmpty(t *testing.T) {
+	var g StartupGate
+	if err := g.Check(context.Background()); err != nil {
+		t.Errorf("Check returned %v", err)
+	}
+	if err := g.Wait(context.Background()); err != nil {
+		t.Errorf("Wait returned %v", err)
+	}
+}
+
+func TestStartupGateWait(t *testing.T) {
+	var g StartupGate
+	a := g.Require("a")
+	b := g.Require("b")
+
+	if got, want := g.Pending(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Pending got %q, want %q", got, want)
+	}
+	if err := g.Check(context.Background()); !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("Check expected %v, got %v", ErrNotInitialized, err)
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- g.Wait(context.Background())
+	}()
+
+	a.Ready()
+	select {
+	case err := <-errs:
+		t.Fatalf("Wait returned %v before b is ready", err)
+	case <-time.After(time.Millisecond * 10):
+	}
+	if got, want := g.Pending(), []string{"b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Pending got %q, want %q", got, want)
+	}
+
+	b.Ready()
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Errorf("Wait returned %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait didn't return after all signals are ready")
+	}
+	if err := g.Check(context.Background()); err != nil {
+		t.Errorf("Check returned %v", err)
+	}
+}
+
+func TestStartupGateTimeout(t *testing.T) {
+	var g StartupGate
+	g.Require("a").Ready()
+	g.Require("b")
+	g.Require("c")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	err := g.Wait(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("Expected %v, got %v", ErrNotInitialized, err)
+	}
+	if err != nil {
+		msg := err.Error()
+		for _, name := range []string{`"b"`, `"c"`} {
+			if !strings.Contains(msg, name) {
+				t.Errorf("Expected %s in the error, got %q", name, msg)
+			}
+		}
+		if strings.Contains(msg, `"a"`) {
+			t.Errorf("Expected no ready signal in the error, got %q", msg)
+		}
+	}
+}
+
+func TestStartupGateFail(t *testing.T) {
+	var g StartupGate
+	errFailed := errors.New("failed")
+	a := g.Require("a")
+	g.Require("b")
+	a.Fail(errFailed)
+
+	// Wait returns without waiting for b.
+	err := g.Wait(context.Background())
+	if !errors.Is(err, errFailed) {
+		t.Errorf("Expected %v, got %v", errFailed, err)
+	}
+
+	// Ready clears the failure.
+	a.Ready()
+	if got, want := g.Pending(), []string{"b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Pending got %q, want %q", got, want)
+	}
+}
+
+func TestStartupGateRelease(t *testing.T) {
+	var g StartupGate
+	g.Require("a")
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- g.Wait(context.Background())
+	}()
+	g.Release("a")
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Errorf("Wait returned %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait didn't return after the signal is released")
+	}
+}
+
+func TestStartupSignalNil(t *testing.T) {
+	var s *StartupSignal
+	s.Ready()
+	s.Fail(errors.New("failed"))
+}
+
+func TestStartupGateReadiness(t *testing.T) {
+	var g StartupGate
+	signal := g.Require("a")
+
+	var r Registry
+	r.Register(StartupCheckerName, g.Check, CheckerOptions{
+		Criticality: CriticalityReadiness,
+		CacheTTL:    -1,
+	})
+	if r.IsHealthy(context.Background(), readiness) {
+		t.Error("Expected readiness to fail before the signal is ready")
+	}
+	if !r.IsHealthy(context.Background(), liveness) {
+		t.Error("Expected liveness to pass before the signal is ready")
+	}
+	signal.Ready()
+	if !r.IsHealthy(context.Background(), readiness) {
+		t.Error("Expected readiness to pass after the signal is ready")
+	}
+}
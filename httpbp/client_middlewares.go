@@ -2,15 +2,20 @@ package httpbp
 
 import (
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"sync"
 	"sync/atomic"
 
 	"github.com/avast/retry-go"
 	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/reddit/baseplate.go/breakerbp"
+	"github.com/reddit/baseplate.go/clientpool"
 	"github.com/reddit/baseplate.go/retrybp"
 	"github.com/reddit/baseplate.go/tracing"
 )
@@ -37,6 +42,9 @@ func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
 // plus any additional client middleware passed into this function. Default
 // middlewares are: MonitorClient and Retries. ClientErrorWrapper is included
 // as transitive middleware through Retries.
+//
+// With config.ReportPoolStats, it returns an error if there's already a client
+// reporting pool stats with the same Slug not closed by CloseClient.
 func NewClient(config ClientConfig, middleware ...ClientMiddleware) (*http.Client, error) {
 	if err := config.Validate(); err != nil {
 		return nil, err
@@ -70,11 +78,62 @@ func NewClient(config ClientConfig, middleware ...ClientMiddleware) (*http.Clien
 	}
 	middleware = append(middleware, defaults...)
 
+	if config.ReportPoolStats {
+		stats := &poolStats{maxConns: int64(config.MaxConnections)}
+		// The same dialer http.Transport uses when DialContext is nil.
+		transport.DialContext = stats.dialContext((&net.Dialer{}).DialContext)
+		collector, err := clientpool.RegisterStats(PrometheusPoolProtocol, config.Slug, stats.Stats)
+		if err != nil {
+			return nil, fmt.Errorf("httpbp: failed to register prometheus pool stats for %q: %w", config.Slug, err)
+		}
+		// Make it the innermost one so every retry is tracked separately.
+		middleware = append(middleware, stats.middleware)
+		return &http.Client{
+			Transport: &statsTransport{
+				RoundTripper: WrapTransport(&transport, middleware...),
+				transport:    &transport,
+				collector:    collector,
+			},
+		}, nil
+	}
+
 	return &http.Client{
 		Transport: WrapTransport(&transport, middleware...),
 	}, nil
 }
 
+// CloseClient closes the idle connections of a client created by NewClient,
+// and unregisters its Prometheus pool metrics when ClientConfig.ReportPoolStats
+// is set, so that a new client with the same Slug can be created.
+//
+// The client should not be used after CloseClient is called.
+func CloseClient(client *http.Client) error {
+	if closer, ok := client.Transport.(io.Closer); ok {
+		return closer.Close()
+	}
+	client.CloseIdleConnections()
+	return nil
+}
+
+// statsTransport is the Transport of the clients reporting pool stats.
+type statsTransport struct {
+	http.RoundTripper
+
+	transport *http.Transport
+	collector prometheus.Collector
+}
+
+// CloseIdleConnections is called by http.Client.CloseIdleConnections.
+func (t *statsTransport) CloseIdleConnections() {
+	t.transport.CloseIdleConnections()
+}
+
+func (t *statsTransport) Close() error {
+	t.transport.CloseIdleConnections()
+	prometheus.Unregister(t.collector)
+	return nil
+}
+
 // WrapTransport takes a list of client middleware and wraps them around the
 // given transport. This is useful for using client middleware outside of this
 // package.
@@ -155,31 +214,67 @@ func CircuitBreaker(config breakerbp.Config) ClientMiddleware {
 				newBreaker = nil
 			}
 
-			var resp *http.Response
-			_, err := breaker.(*breakerbp.FailureRatioBreaker).Execute(func() (interface{}, error) {
-				r, err := next.RoundTrip(req)
-				if err != nil {
-					return nil, err
-				}
-				resp = r
-				// circuit break on any HTTP 5xx code
-				if resp.StatusCode >= http.StatusInternalServerError {
-					DrainAndClose(resp.Body)
-					return nil, ClientError{
-						Status:     resp.Status,
-						StatusCode: resp.StatusCode,
-					}
-				}
-				return nil, nil
-			})
-			return resp, err
+			return breakerRoundTrip(breaker.(*breakerbp.FailureRatioBreaker), next, req)
 		})
 	}
 }
 
+// CircuitBreakerGroup is a middleware like CircuitBreaker,
+// but with the breakers from group,
+// keyed by the key returned by key for each request
+// (e.g. BreakerKeyHost or BreakerKeyHostMethod).
+//
+// Unlike CircuitBreaker, the idle breakers are removed from the group.
+func CircuitBreakerGroup(group *breakerbp.BreakerGroup, key func(req *http.Request) string) ClientMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			breaker := group.Breaker(key(req))
+			return breakerRoundTrip(&breaker, next, req)
+		})
+	}
+}
+
+// BreakerKeyHost is the key function for CircuitBreakerGroup that keys the
+// breakers by the destination host.
+func BreakerKeyHost(req *http.Request) string {
+	return req.URL.Hostname()
+}
+
+// BreakerKeyHostMethod is the key function for CircuitBreakerGroup that keys
+// the breakers by the destination host and the HTTP method.
+func BreakerKeyHostMethod(req *http.Request) string {
+	return req.URL.Hostname() + " " + req.Method
+}
+
+func breakerRoundTrip(breaker *breakerbp.FailureRatioBreaker, next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	_, err := breaker.Execute(func() (interface{}, error) {
+		r, err := next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		resp = r
+		// circuit break on any HTTP 5xx code
+		if resp.StatusCode >= http.StatusInternalServerError {
+			DrainAndClose(resp.Body)
+			return nil, ClientError{
+				Status:     resp.Status,
+				StatusCode: resp.StatusCode,
+			}
+		}
+		return nil, nil
+	})
+	return resp, err
+}
+
 // Retries provides a retry middleware by ensuring certain HTTP responses are
 // wrapped in errors. Retries wraps the ClientErrorWrapper middleware, e.g. if
 // you are using Retries there is no need to also use ClientErrorWrapper.
+//
+// The retryOptions are the defaults of the client,
+// they can be overridden for a specific request by setting the options on the
+// context of the request via retrybp.WithOptions, e.g. to disable the retries
+// for an interactive path, or to retry more for a background job.
 func Retries(limit int, retryOptions ...retry.Option) ClientMiddleware {
 	if len(retryOptions) == 0 {
 		retryOptions = []retry.Option{retry.Attempts(1)}
@@ -224,6 +319,11 @@ func MaxConcurrency(maxConcurrency int64) ClientMiddleware {
 
 // MonitorClient is an HTTP client middleware that wraps HTTP requests in a
 // client span.
+//
+// It also propagates the client span to the server via the span headers
+// (X-Trace, X-Parent, X-Span, X-Flags and X-Sampled),
+// so that sampling decisions and the debug flag are honored by the downstream
+// services trusting them.
 func MonitorClient(slug string) ClientMiddleware {
 	return func(next http.RoundTripper) http.RoundTripper {
 		return roundTripperFunc(func(req *http.Request) (resp *http.Response, err error) {
@@ -241,7 +341,26 @@ func MonitorClient(slug string) ClientMiddleware {
 					Err: err,
 				}.Convert())
 			}()
-			return next.RoundTrip(req.WithContext(ctx))
+			req = req.Clone(ctx)
+			setSpanHeaders(req.Header, tracing.AsSpan(span))
+			return next.RoundTrip(req)
 		})
 	}
 }
+
+// setSpanHeaders sets the span headers for span onto h.
+func setSpanHeaders(h http.Header, span *tracing.Span) {
+	h.Set(TraceIDHeader, span.TraceID())
+	h.Set(SpanIDHeader, span.ID())
+	h.Set(SpanFlagsHeader, strconv.FormatInt(span.Flags(), 10))
+	if span.ParentID() != "" {
+		h.Set(ParentIDHeader, span.ParentID())
+	} else {
+		h.Del(ParentIDHeader)
+	}
+	if span.Sampled() {
+		h.Set(SpanSampledHeader, spanSampledTrue)
+	} else {
+		h.Del(SpanSampledHeader)
+	}
+}
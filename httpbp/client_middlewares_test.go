@@ -9,6 +9,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -19,6 +20,7 @@ import (
 
 	"github.com/reddit/baseplate.go/breakerbp"
 	"github.com/reddit/baseplate.go/mqsend"
+	"github.com/reddit/baseplate.go/retrybp"
 	"github.com/reddit/baseplate.go/tracing"
 )
 
@@ -190,6 +192,54 @@ func TestMonitorClient(t *testing.T) {
 	}
 }
 
+func TestMonitorClientSpanHeaders(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+	}))
+	defer server.Close()
+
+	sampled := false
+	ctx, parent := tracing.StartSpanFromHeaders(context.Background(), "server", tracing.Headers{
+		TraceID: "12345",
+		SpanID:  "23456",
+		Flags:   strconv.FormatInt(tracing.FlagMaskDebug, 10),
+		Sampled: &sampled,
+	})
+	defer parent.Stop(ctx, nil)
+
+	client := &http.Client{
+		Transport: MonitorClient("test")(http.DefaultTransport),
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(req.Header) != 0 {
+		t.Errorf("Expected original request headers untouched, got %v", req.Header)
+	}
+
+	h := <-headers
+	for k, v := range map[string]string{
+		TraceIDHeader:     "12345",
+		ParentIDHeader:    parent.ID(),
+		SpanFlagsHeader:   "1",
+		SpanSampledHeader: "1",
+	} {
+		if got := h.Get(k); got != v {
+			t.Errorf("Header %s: expected %q, got %q", k, v, got)
+		}
+	}
+	if h.Get(SpanIDHeader) == "" {
+		t.Errorf("Expected %s header to be set", SpanIDHeader)
+	}
+}
+
 func TestClientErrorWrapper(t *testing.T) {
 	t.Run("HTTP 200", func(t *testing.T) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -348,6 +398,57 @@ func TestRetry(t *testing.T) {
 			t.Errorf("expected %d, actual: %d", expected, attempts)
 		}
 	})
+
+	t.Run("per-request override", func(t *testing.T) {
+		var attempts int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&attempts, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		client := &http.Client{
+			Transport: Retries(
+				DefaultMaxErrorReadAhead,
+				retry.Attempts(3),
+			)(http.DefaultTransport),
+		}
+		for _, c := range []struct {
+			label    string
+			ctx      context.Context
+			expected int64
+		}{
+			{
+				label:    "default",
+				ctx:      context.Background(),
+				expected: 3,
+			},
+			{
+				label:    "no-retries",
+				ctx:      retrybp.WithOptions(context.Background(), retry.Attempts(1)),
+				expected: 1,
+			},
+			{
+				label:    "more-retries",
+				ctx:      retrybp.WithOptions(context.Background(), retry.Attempts(5)),
+				expected: 5,
+			},
+		} {
+			t.Run(c.label, func(t *testing.T) {
+				atomic.StoreInt64(&attempts, 0)
+				req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, server.URL, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := client.Do(req); err == nil {
+					t.Fatalf("expected error to be non-nil")
+				}
+				if actual := atomic.LoadInt64(&attempts); actual != c.expected {
+					t.Errorf("expected %d, actual: %d", c.expected, actual)
+				}
+			})
+		}
+	})
 }
 
 func TestMaxConcurrency(t *testing.T) {
@@ -417,3 +518,40 @@ func TestCircuitBreaker(t *testing.T) {
 		t.Errorf("Expected the third request to return %v, got %v", gobreaker.ErrOpenState, err)
 	}
 }
+
+func TestCircuitBreakerGroup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const code = http.StatusInternalServerError
+		w.WriteHeader(code)
+		io.WriteString(w, http.StatusText(code))
+	}))
+	defer server.Close()
+	group := breakerbp.NewBreakerGroup(breakerbp.GroupConfig{
+		Config: breakerbp.Config{
+			MinRequestsToTrip: 2,
+			FailureThreshold:  1,
+		},
+	})
+	client := server.Client()
+	client.Transport = CircuitBreakerGroup(group, BreakerKeyHostMethod)(client.Transport)
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Get(server.URL)
+		if !errors.As(err, new(ClientError)) {
+			t.Errorf("Expected GET request #%d to return ClientError, got %v", i, err)
+		}
+	}
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, gobreaker.ErrOpenState) {
+		t.Errorf("Expected the third GET request to return %v, got %v", gobreaker.ErrOpenState, err)
+	}
+
+	// The breaker of the other method is not tripped.
+	_, err = client.Post(server.URL, "text/plain", nil)
+	if !errors.As(err, new(ClientError)) {
+		t.Errorf("Expected POST request to return ClientError, got %v", err)
+	}
+	if n := group.Len(); n != 2 {
+		t.Errorf("Expected 2 breakers in the group, got %d", n)
+	}
+}
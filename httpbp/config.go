@@ -15,6 +15,12 @@ type ClientConfig struct {
 	MaxConnections    int               `yaml:"maxConnections"`
 	CircuitBreaker    *breakerbp.Config `yaml:"circuitBreaker"`
 	RetryOptions      []retry.Option
+
+	// ReportPoolStats registers the standardized Prometheus pool metrics
+	// (see clientpool.RegisterStats) of the client's connection pool,
+	// with "http" protocol and Slug as the pool name.
+	// They are unregistered by CloseClient.
+	ReportPoolStats bool `yaml:"reportPoolStats"`
 }
 
 // Validate checks ClientConfig for any missing or erroneous values.
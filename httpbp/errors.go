@@ -516,6 +516,18 @@ func TooManyRequests() *ErrorResponse {
 	)
 }
 
+// QuotaExceeded is for 429 responses when the client exceeded its quota
+// (as opposed to sending too many requests in a short period of time).
+//
+// This is used by the Quota middleware.
+func QuotaExceeded() *ErrorResponse {
+	return NewErrorResponse(
+		http.StatusTooManyRequests,
+		"QUOTA_EXCEEDED",
+		"The client has exceeded its quota.",
+	)
+}
+
 // LegalBlock is for 451 responses.
 //
 // This is appropriate for when the requested resource is unavailable for
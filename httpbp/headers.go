@@ -1,6 +1,7 @@
 package httpbp
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"net/http"
@@ -8,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/reddit/baseplate.go/ecinterface"
 	"github.com/reddit/baseplate.go/secrets"
 	"github.com/reddit/baseplate.go/signing"
 )
@@ -350,6 +352,46 @@ func headerMessage(h Headers) []byte {
 	return []byte(strings.Join(components, "|"))
 }
 
+// EdgeContextCarrier is the ecinterface.Carrier of the EdgeContextHeader HTTP
+// header, which takes care of the base64 encoding of the header.
+//
+// Get reads from Header, Set and Unset update Header and return ctx intact,
+// so Header should be the header of the incoming request for Get,
+// and the header of the outgoing request for Set and Unset.
+//
+// Note that the edge context from the HTTP headers should only be extracted
+// from trusted requests (see HeaderTrustHandler).
+type EdgeContextCarrier struct {
+	Header http.Header
+}
+
+var _ ecinterface.Carrier = EdgeContextCarrier{}
+
+// Get implements ecinterface.Carrier.
+func (c EdgeContextCarrier) Get(ctx context.Context) (string, error) {
+	value := c.Header.Get(EdgeContextHeader)
+	if value == "" {
+		return "", ecinterface.ErrNoHeader
+	}
+	header, err := decodeEdgeContextHeader(value)
+	if err != nil {
+		return "", err
+	}
+	return string(header), nil
+}
+
+// Set implements ecinterface.Carrier.
+func (c EdgeContextCarrier) Set(ctx context.Context, header string) context.Context {
+	c.Header.Set(EdgeContextHeader, encodeEdgeContextHeader([]byte(header)))
+	return ctx
+}
+
+// Unset implements ecinterface.Carrier.
+func (c EdgeContextCarrier) Unset(ctx context.Context) context.Context {
+	c.Header.Del(EdgeContextHeader)
+	return ctx
+}
+
 // encodeEdgeContextHeader does the appropriate base64 encoding from the raw
 // edge context header.
 func encodeEdgeContextHeader(raw []byte) string {
@@ -1,11 +1,14 @@
 package httpbp_test
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"net/http"
 	"testing"
 	"time"
 
+	"github.com/reddit/baseplate.go/ecinterface"
 	"github.com/reddit/baseplate.go/httpbp"
 	"github.com/reddit/baseplate.go/secrets"
 )
@@ -189,6 +192,38 @@ func TestNewEdgeContextHeaders(t *testing.T) {
 	}
 }
 
+func TestEdgeContextCarrier(t *testing.T) {
+	t.Parallel()
+
+	impl := ecinterface.Mock()
+	ctx, err := ecinterface.Extract(
+		context.Background(),
+		impl,
+		httpbp.EdgeContextCarrier{Header: getHeaders()},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header, _ := impl.ContextToHeader(ctx); header != edgeContext {
+		t.Errorf("Expected extracted header %q, got %q", edgeContext, header)
+	}
+
+	outgoing := make(http.Header)
+	ecinterface.Inject(ctx, impl, httpbp.EdgeContextCarrier{Header: outgoing})
+	if got := outgoing.Get(httpbp.EdgeContextHeader); got != b64EdgeContext {
+		t.Errorf("Expected injected header %q, got %q", b64EdgeContext, got)
+	}
+
+	ecinterface.Inject(context.Background(), impl, httpbp.EdgeContextCarrier{Header: outgoing})
+	if got := outgoing.Get(httpbp.EdgeContextHeader); got != "" {
+		t.Errorf("Expected header unset, got %q", got)
+	}
+
+	if _, err := (httpbp.EdgeContextCarrier{Header: outgoing}).Get(ctx); !errors.Is(err, ecinterface.ErrNoHeader) {
+		t.Errorf("Expected %v, got %v", ecinterface.ErrNoHeader, err)
+	}
+}
+
 func TestAlwaysTrustHeaders(t *testing.T) {
 	t.Parallel()
 
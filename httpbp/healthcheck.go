@@ -1,11 +1,14 @@
 package httpbp
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 
+	"github.com/reddit/baseplate.go/healthbp"
 	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
 )
 
@@ -60,3 +63,21 @@ func GetHealthCheckProbe(query url.Values) (int64, error) {
 		code,
 	)
 }
+
+// HealthCheckHandlerFunc returns a HandlerFunc serving the health check probes
+// from registry, with the probe parsed by GetHealthCheckProbe.
+//
+// It responds with the JSON encoded healthbp.Report,
+// with status code 200 when healthy and 503 otherwise.
+func HealthCheckHandlerFunc(registry *healthbp.Registry) HandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		// Unrecognized probes fallback to READINESS, ignore the error.
+		probe, _ := GetHealthCheckProbe(r.URL.Query())
+		report := registry.Check(ctx, probe)
+		resp := NewResponse(report)
+		if !report.Healthy {
+			resp = resp.WithCode(http.StatusServiceUnavailable)
+		}
+		return WriteJSON(w, resp)
+	}
+}
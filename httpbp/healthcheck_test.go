@@ -1,9 +1,15 @@
 package httpbp_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
+	"github.com/reddit/baseplate.go/healthbp"
 	"github.com/reddit/baseplate.go/httpbp"
 	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
 )
@@ -79,3 +85,65 @@ func TestGetHealthCheckProbe(t *testing.T) {
 		)
 	}
 }
+
+func TestHealthCheckHandlerFunc(t *testing.T) {
+	var registry healthbp.Registry
+	registry.Register(
+		"dependency",
+		func(context.Context) error {
+			return errors.New("down")
+		},
+		healthbp.CheckerOptions{
+			Criticality: healthbp.CriticalityReadiness,
+		},
+	)
+	handle := httpbp.HealthCheckHandlerFunc(&registry)
+
+	for _, c := range []struct {
+		query       string
+		wantCode    int
+		wantHealthy bool
+	}{
+		{
+			query:       "",
+			wantCode:    http.StatusServiceUnavailable,
+			wantHealthy: false,
+		},
+		{
+			query:       "?type=liveness",
+			wantCode:    http.StatusOK,
+			wantHealthy: true,
+		},
+	} {
+		t.Run(c.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/health"+c.query, nil)
+			if err := handle(r.Context(), w, r); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != c.wantCode {
+				t.Errorf("Expected code %d, got %d", c.wantCode, w.Code)
+			}
+			var report struct {
+				Healthy  bool `json:"healthy"`
+				Checkers []struct {
+					Name        string `json:"name"`
+					Criticality string `json:"criticality"`
+					Error       string `json:"error"`
+				} `json:"checkers"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+				t.Fatal(err)
+			}
+			if report.Healthy != c.wantHealthy {
+				t.Errorf("Expected healthy %v, got %v", c.wantHealthy, report.Healthy)
+			}
+			if len(report.Checkers) != 1 ||
+				report.Checkers[0].Name != "dependency" ||
+				report.Checkers[0].Criticality != "readiness" ||
+				report.Checkers[0].Error != "down" {
+				t.Errorf("Unexpected checkers: %+v", report.Checkers)
+			}
+		})
+	}
+}
@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/faults"
 	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/ratelimit"
 	"github.com/reddit/baseplate.go/tracing"
 )
 
@@ -16,6 +20,19 @@ import (
 // 405 - Method Not Allowed error.
 const AllowHeader = "Allow"
 
+// Headers set by the Quota middleware.
+const (
+	// QuotaLimitHeader is the max usage of the caller in the quota period.
+	QuotaLimitHeader = "X-Quota-Limit"
+
+	// QuotaRemainingHeader is the remaining quota of the caller.
+	QuotaRemainingHeader = "X-Quota-Remaining"
+
+	// QuotaResetHeader is the unix timestamp (in seconds) the quota of the
+	// caller resets.
+	QuotaResetHeader = "X-Quota-Reset"
+)
+
 const spanSampledTrue = "1"
 
 // Middleware wraps the given HandlerFunc and returns a new, wrapped, HandlerFunc.
@@ -105,7 +122,11 @@ func StartSpanFromTrustedRequest(
 		}
 	}
 
-	return tracing.StartSpanFromHeaders(ctx, name, spanHeaders)
+	ctx, span := tracing.StartSpanFromHeaders(ctx, name, spanHeaders)
+	log.SetSentryTags(ctx, map[string]string{
+		"http_method": r.Method,
+	})
+	return ctx, span
 }
 
 // InjectServerSpan returns a Middleware that will automatically wrap the
@@ -153,19 +174,10 @@ func InitializeEdgeContextFromTrustedRequest(
 		return ctx
 	}
 
-	header, err := decodeEdgeContextHeader(r.Header.Get(EdgeContextHeader))
-	if err != nil {
-		args.Logger.Log(ctx, "Error while parsing EdgeRequestContext: "+err.Error())
-		return ctx
-	}
-	if args.EdgeContextImpl == nil {
-		args.EdgeContextImpl = ecinterface.Get()
-	}
-	ctx, err = args.EdgeContextImpl.HeaderToContext(ctx, string(header))
+	ctx, err := ecinterface.Extract(ctx, args.EdgeContextImpl, EdgeContextCarrier{Header: r.Header})
 	if err != nil {
 		args.Logger.Log(ctx, "Error while parsing EdgeRequestContext: "+err.Error())
 	}
-
 	return ctx
 }
 
@@ -246,3 +258,137 @@ func SupportedMethods(method string, additional ...string) Middleware {
 		}
 	}
 }
+
+// RateLimit returns a middleware that rate limits the requests via limiter.
+//
+// key is used to get the rate limit key of the request (e.g. the client IP or
+// user id), it's prefixed by the name of the endpoint so that the limits of
+// different endpoints are separated.
+// If key is nil, all the requests to the same endpoint share the same limit.
+//
+// Returns a JSON 429 error response with "Retry-After" header set when the
+// request is rate limited.
+// When limiter returns an error, the error is logged and the request is
+// allowed (fail open).
+func RateLimit(limiter ratelimit.Limiter, key func(r *http.Request) string) Middleware {
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			k := name
+			if key != nil {
+				k += ":" + key(r)
+			}
+			result, err := ratelimit.Allow(ctx, limiter, k)
+			if err != nil {
+				log.C(ctx).Warnw(
+					"httpbp.RateLimit: limiter failed, allowing the request",
+					"err", err,
+					"key", k,
+				)
+				return next(ctx, w, r)
+			}
+			if !result.Allowed {
+				return JSONError(
+					TooManyRequests().Retryable(w, result.RetryAfter),
+					fmt.Errorf("httpbp.RateLimit: %q rate limited", k),
+				)
+			}
+			return next(ctx, w, r)
+		}
+	}
+}
+
+// Quota returns a middleware that consumes one unit of the quota of the caller
+// for each request.
+//
+// key is used to get the quota key of the request.
+// Unlike RateLimit, the key is not prefixed by the name of the endpoint,
+// so the quota is shared by all the endpoints using the same quota.
+// If key is nil, ratelimit.AuthenticatedKey is used to key the quota by the
+// authenticated caller from the edge context.
+// The requests with empty key (e.g. not authenticated) are not counted.
+//
+// The QuotaLimitHeader, QuotaRemainingHeader and QuotaResetHeader headers are
+// set on the response.
+// Returns a JSON 429 QUOTA_EXCEEDED error response with "Retry-After" header
+// set to when the quota resets when the quota is exceeded.
+// When quota returns an error, the error is logged and the request is
+// allowed (fail open).
+func Quota(quota ratelimit.Quota, key func(ctx context.Context, r *http.Request) string) Middleware {
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			var k string
+			if key != nil {
+				k = key(ctx, r)
+			} else {
+				k = ratelimit.AuthenticatedKey(ctx)
+			}
+			if k == "" {
+				return next(ctx, w, r)
+			}
+			result, err := quota.CheckAndConsume(ctx, k, 1)
+			if err != nil {
+				log.C(ctx).Warnw(
+					"httpbp.Quota: quota failed, allowing the request",
+					"err", err,
+					"key", k,
+				)
+				return next(ctx, w, r)
+			}
+			w.Header().Set(QuotaLimitHeader, strconv.FormatInt(result.Limit, 10))
+			w.Header().Set(QuotaRemainingHeader, strconv.FormatInt(result.Remaining, 10))
+			w.Header().Set(QuotaResetHeader, strconv.FormatInt(result.ResetAt.Unix(), 10))
+			if !result.Allowed {
+				return JSONError(
+					QuotaExceeded().Retryable(w, time.Until(result.ResetAt).Round(time.Second)),
+					result.Err(k),
+				)
+			}
+			return next(ctx, w, r)
+		}
+	}
+}
+
+// InjectFaults returns a middleware that injects the faults from injector into
+// the matching requests, for chaos experiments.
+//
+// The name of the endpoint is used as the method of the request to match the
+// rules, and the caller is read from the "User-Agent" header.
+// The fault delays the request first (cut short when the context is done).
+// Then, if the fault aborts, the handler panics with http.ErrAbortHandler,
+// so the server drops the connection without a response;
+// otherwise if the fault has an error code for HTTP,
+// a JSON error response of the status code with FAULT_INJECTED reason is
+// returned without calling the next handler.
+//
+// A nil injector never injects anything.
+func InjectFaults(injector *faults.Injector) Middleware {
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			fault, ok := injector.Match(faults.Request{
+				Protocol: faults.ProtocolHTTP,
+				Method:   name,
+				Caller:   r.Header.Get("User-Agent"),
+			})
+			if !ok {
+				return next(ctx, w, r)
+			}
+			if err := fault.Wait(ctx); err != nil {
+				return next(ctx, w, r)
+			}
+			if fault.Abort {
+				panic(http.ErrAbortHandler)
+			}
+			if fault.HasCode {
+				return JSONError(
+					NewErrorResponse(
+						fault.Code,
+						"FAULT_INJECTED",
+						fmt.Sprintf("fault injected by rule %q", fault.Rule),
+					),
+					fmt.Errorf("httpbp.InjectFaults: %q rejected by rule %q", name, fault.Rule),
+				)
+			}
+			return next(ctx, w, r)
+		}
+	}
+}
@@ -7,13 +7,17 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/faults"
 	"github.com/reddit/baseplate.go/httpbp"
 	"github.com/reddit/baseplate.go/log"
 	"github.com/reddit/baseplate.go/mqsend"
+	"github.com/reddit/baseplate.go/ratelimit"
 	"github.com/reddit/baseplate.go/tracing"
 )
 
@@ -304,3 +308,253 @@ func TestSupportedMethods(t *testing.T) {
 		)
 	}
 }
+
+type fakeLimiter struct {
+	result ratelimit.Result
+	err    error
+	keys   []string
+}
+
+func (l *fakeLimiter) AllowN(_ context.Context, key string, _ int64) (ratelimit.Result, error) {
+	l.keys = append(l.keys, key)
+	return l.result, l.err
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name        string
+		limiter     *fakeLimiter
+		errExpected bool
+	}{
+		{
+			name:    "allowed",
+			limiter: &fakeLimiter{result: ratelimit.Result{Allowed: true}},
+		},
+		{
+			name:        "limited",
+			limiter:     &fakeLimiter{result: ratelimit.Result{RetryAfter: time.Second * 2}},
+			errExpected: true,
+		},
+		{
+			name:    "fail-open",
+			limiter: &fakeLimiter{err: errors.New("redis down")},
+		},
+	}
+	for _, _c := range cases {
+		c := _c
+		t.Run(
+			c.name,
+			func(t *testing.T) {
+				req := newRequest(t, "")
+				handle := httpbp.Wrap(
+					"test",
+					newTestHandler(testHandlerPlan{}),
+					httpbp.RateLimit(c.limiter, func(r *http.Request) string {
+						return "client"
+					}),
+				)
+
+				w := httptest.NewRecorder()
+				err := handle(context.TODO(), w, req)
+				if len(c.limiter.keys) != 1 || c.limiter.keys[0] != "test:client" {
+					t.Errorf("Expected limiter called with %q, got %q", "test:client", c.limiter.keys)
+				}
+				if !c.errExpected {
+					if err != nil {
+						t.Fatalf("unexpected error %v", err)
+					}
+					return
+				}
+
+				var httpErr httpbp.HTTPError
+				if !errors.As(err, &httpErr) {
+					t.Fatalf("expected an HTTPError, got %v", err)
+				}
+				if httpErr.Response().Code != http.StatusTooManyRequests {
+					t.Errorf(
+						"wrong response code, expected %d, got %d",
+						http.StatusTooManyRequests,
+						httpErr.Response().Code,
+					)
+				}
+				if after := w.Header().Get(httpbp.RetryAfterHeader); after != "2" {
+					t.Errorf("expected %s header %q, got %q", httpbp.RetryAfterHeader, "2", after)
+				}
+			},
+		)
+	}
+}
+
+type fakeQuota struct {
+	result ratelimit.QuotaResult
+	err    error
+	keys   []string
+}
+
+func (q *fakeQuota) CheckAndConsume(_ context.Context, key string, _ int64) (ratelimit.QuotaResult, error) {
+	q.keys = append(q.keys, key)
+	return q.result, q.err
+}
+
+func TestQuota(t *testing.T) {
+	t.Parallel()
+
+	resetAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	cases := []struct {
+		name        string
+		quota       *fakeQuota
+		key         string
+		errExpected bool
+	}{
+		{
+			name: "allowed",
+			quota: &fakeQuota{result: ratelimit.QuotaResult{
+				Allowed:   true,
+				Limit:     10,
+				Remaining: 9,
+				ResetAt:   resetAt,
+			}},
+			key: "user",
+		},
+		{
+			name: "exceeded",
+			quota: &fakeQuota{result: ratelimit.QuotaResult{
+				Limit:   10,
+				ResetAt: resetAt,
+			}},
+			key:         "user",
+			errExpected: true,
+		},
+		{
+			name:  "fail-open",
+			quota: &fakeQuota{err: errors.New("redis down")},
+			key:   "user",
+		},
+		{
+			name:  "anonymous",
+			quota: &fakeQuota{},
+		},
+	}
+	for _, _c := range cases {
+		c := _c
+		t.Run(
+			c.name,
+			func(t *testing.T) {
+				req := newRequest(t, "")
+				handle := httpbp.Wrap(
+					"test",
+					newTestHandler(testHandlerPlan{}),
+					httpbp.Quota(c.quota, func(_ context.Context, r *http.Request) string {
+						return c.key
+					}),
+				)
+
+				w := httptest.NewRecorder()
+				err := handle(context.TODO(), w, req)
+				if c.key == "" {
+					if len(c.quota.keys) != 0 {
+						t.Errorf("Expected quota not called without key, got %q", c.quota.keys)
+					}
+				} else if len(c.quota.keys) != 1 || c.quota.keys[0] != c.key {
+					t.Errorf("Expected quota called with %q, got %q", c.key, c.quota.keys)
+				}
+				if c.quota.result.Limit > 0 {
+					if got := w.Header().Get(httpbp.QuotaLimitHeader); got != "10" {
+						t.Errorf("expected %s header %q, got %q", httpbp.QuotaLimitHeader, "10", got)
+					}
+					if got, expected := w.Header().Get(httpbp.QuotaResetHeader), strconv.FormatInt(resetAt.Unix(), 10); got != expected {
+						t.Errorf("expected %s header %q, got %q", httpbp.QuotaResetHeader, expected, got)
+					}
+				}
+				if !c.errExpected {
+					if err != nil {
+						t.Fatalf("unexpected error %v", err)
+					}
+					return
+				}
+
+				var httpErr httpbp.HTTPError
+				if !errors.As(err, &httpErr) {
+					t.Fatalf("expected an HTTPError, got %v", err)
+				}
+				if httpErr.Response().Code != http.StatusTooManyRequests {
+					t.Errorf(
+						"wrong response code, expected %d, got %d",
+						http.StatusTooManyRequests,
+						httpErr.Response().Code,
+					)
+				}
+				if !errors.As(err, new(*ratelimit.QuotaExceededError)) {
+					t.Errorf("expected a QuotaExceededError, got %v", err)
+				}
+				if w.Header().Get(httpbp.RetryAfterHeader) == "" {
+					t.Errorf("expected %s header to be set", httpbp.RetryAfterHeader)
+				}
+			},
+		)
+	}
+}
+
+func TestInjectFaults(t *testing.T) {
+	t.Parallel()
+
+	injector, err := faults.NewInjector(faults.Config{
+		Rules: []faults.Rule{
+			{
+				Name:       "abort",
+				Callers:    []string{"abort*"},
+				Percentage: 100,
+				Abort:      true,
+			},
+			{
+				Name:       "error",
+				Methods:    []string{"test"},
+				Callers:    []string{"frontend"},
+				Percentage: 100,
+				Codes:      map[faults.Protocol]int{faults.ProtocolHTTP: http.StatusServiceUnavailable},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle := httpbp.Wrap("test", newTestHandler(testHandlerPlan{}), httpbp.InjectFaults(injector))
+
+	t.Run("no-match", func(t *testing.T) {
+		req := newRequest(t, "")
+		req.Header.Set("User-Agent", "backend")
+		if err := handle(context.TODO(), httptest.NewRecorder(), req); err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		req := newRequest(t, "")
+		req.Header.Set("User-Agent", "frontend")
+		err := handle(context.TODO(), httptest.NewRecorder(), req)
+		var httpErr httpbp.HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("expected an HTTPError, got %v", err)
+		}
+		if httpErr.Response().Code != http.StatusServiceUnavailable {
+			t.Errorf(
+				"wrong response code, expected %d, got %d",
+				http.StatusServiceUnavailable,
+				httpErr.Response().Code,
+			)
+		}
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		req := newRequest(t, "")
+		req.Header.Set("User-Agent", "abort")
+		defer func() {
+			if r := recover(); r != http.ErrAbortHandler {
+				t.Errorf("expected panic with http.ErrAbortHandler, got %v", r)
+			}
+		}()
+		handle(context.TODO(), httptest.NewRecorder(), req)
+	})
+}
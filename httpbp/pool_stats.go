@@ -0,0 +1,141 @@
+package httpbp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+
+	"github.com/reddit/baseplate.go/clientpool"
+)
+
+// PrometheusPoolProtocol is the protocol label value of the standardized
+// Prometheus pool metrics reported by the clients created by NewClient.
+const PrometheusPoolProtocol = "http"
+
+// poolStats tracks the connection pool stats of an http.Transport.
+//
+// http.Transport doesn't expose the states of its connection pool,
+// so they are approximated by:
+//
+// - open connections: tracked by the wrapped DialContext.
+//
+// - active connections: requests got a connection and haven't closed the
+// response body yet.
+//
+// - idle connections: open connections that are not active.
+//
+// - waiters: requests waiting for a connection (including dialing a new one).
+//
+// - exhaustions: requests started to wait for a connection while all the
+// allowed connections (maxConns) are active.
+type poolStats struct {
+	// accessed atomically, keep it first for 64-bit alignment.
+	exhaustions uint64
+
+	open    int64
+	active  int64
+	waiters int64
+
+	maxConns int64
+}
+
+// Stats returns the stats in clientpool.Stats format.
+func (s *poolStats) Stats() clientpool.Stats {
+	open := atomic.LoadInt64(&s.open)
+	active := atomic.LoadInt64(&s.active)
+	idle := open - active
+	if idle < 0 {
+		idle = 0
+	}
+	return clientpool.Stats{
+		Active:      active,
+		Idle:        idle,
+		Waiters:     atomic.LoadInt64(&s.waiters),
+		Exhaustions: atomic.LoadUint64(&s.exhaustions),
+	}
+}
+
+// dialContext wraps dial to track the open connections.
+func (s *poolStats) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		atomic.AddInt64(&s.open, 1)
+		return &trackedConn{Conn: conn, stats: s}, nil
+	}
+}
+
+// middleware tracks the active connections and waiters of the requests.
+//
+// It should be the innermost middleware so that every retry is tracked
+// separately.
+func (s *poolStats) middleware(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		var waiting, got int32
+		release := func() {
+			if atomic.CompareAndSwapInt32(&waiting, 1, 0) {
+				atomic.AddInt64(&s.waiters, -1)
+			}
+			if atomic.CompareAndSwapInt32(&got, 1, 0) {
+				atomic.AddInt64(&s.active, -1)
+			}
+		}
+		trace := &httptrace.ClientTrace{
+			GetConn: func(string) {
+				if s.maxConns > 0 && atomic.LoadInt64(&s.active) >= s.maxConns {
+					atomic.AddUint64(&s.exhaustions, 1)
+				}
+				if atomic.CompareAndSwapInt32(&waiting, 0, 1) {
+					atomic.AddInt64(&s.waiters, 1)
+				}
+			},
+			GotConn: func(httptrace.GotConnInfo) {
+				if atomic.CompareAndSwapInt32(&waiting, 1, 0) {
+					atomic.AddInt64(&s.waiters, -1)
+				}
+				if atomic.CompareAndSwapInt32(&got, 0, 1) {
+					atomic.AddInt64(&s.active, 1)
+				}
+			},
+		}
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+		resp, err := next.RoundTrip(req)
+		if err != nil || resp.Body == nil {
+			release()
+			return resp, err
+		}
+		resp.Body = &trackedBody{ReadCloser: resp.Body, release: release}
+		return resp, nil
+	})
+}
+
+type trackedConn struct {
+	net.Conn
+
+	stats *poolStats
+	once  sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() {
+		atomic.AddInt64(&c.stats.open, -1)
+	})
+	return c.Conn.Close()
+}
+
+type trackedBody struct {
+	io.ReadCloser
+
+	release func()
+}
+
+func (b *trackedBody) Close() error {
+	b.release()
+	return b.ReadCloser.Close()
+}
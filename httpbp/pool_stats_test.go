@@ -0,0 +1,85 @@
+package httpbp
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/reddit/baseplate.go/clientpool"
+)
+
+func TestPoolStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	stats := &poolStats{maxConns: 1}
+	transport := &http.Transport{
+		MaxConnsPerHost: 1,
+		DialContext:     stats.dialContext((&net.Dialer{}).DialContext),
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Transport: WrapTransport(transport, stats.middleware),
+	}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := clientpool.Stats{Active: 1}
+	if got := stats.Stats(); got != expected {
+		t.Errorf("Expected stats %+v before closing the body, got %+v", expected, got)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	expected = clientpool.Stats{Idle: 1}
+	if got := stats.Stats(); got != expected {
+		t.Errorf("Expected stats %+v after closing the body, got %+v", expected, got)
+	}
+
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if got := stats.Stats(); got != expected {
+		t.Errorf("Expected stats %+v after reusing the connection, got %+v", expected, got)
+	}
+
+	transport.CloseIdleConnections()
+	expected = clientpool.Stats{}
+	if got := stats.Stats(); got != expected {
+		t.Errorf("Expected stats %+v after closing idle connections, got %+v", expected, got)
+	}
+}
+
+func TestNewClientPoolStatsRegistration(t *testing.T) {
+	config := ClientConfig{
+		Slug:            "test-pool-stats-registration",
+		ReportPoolStats: true,
+	}
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient(config); err == nil {
+		t.Error("Expected error creating another client with the same slug")
+	}
+
+	if err := CloseClient(client); err != nil {
+		t.Fatal(err)
+	}
+	client, err = NewClient(config)
+	if err != nil {
+		t.Fatalf("Expected the slug to be available after CloseClient, got %v", err)
+	}
+	if err := CloseClient(client); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,227 @@
+package clientsecrets
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+// Config is the paths of the secrets in the Store, all optional.
+type Config struct {
+	// Credentials is the path of a credential secret.
+	Credentials string
+
+	// CACert is the path of a simple secret containing the PEM encoded CA
+	// certificates used to verify the server.
+	CACert string
+
+	// ClientCert and ClientKey are the paths of the simple secrets containing
+	// the PEM encoded client certificate and key for mutual TLS.
+	ClientCert string
+	ClientKey  string
+}
+
+// HasTLS returns true if any of the TLS material is configured.
+func (cfg Config) HasTLS() bool {
+	return cfg.CACert != "" || cfg.ClientCert != "" || cfg.ClientKey != ""
+}
+
+// Validate checks Config for any erroneous values.
+//
+// name is the name of the client package (e.g. "redisbp"),
+// used as the prefix of the error.
+func (cfg Config) Validate(name string) error {
+	if (cfg.ClientCert == "") != (cfg.ClientKey == "") {
+		return fmt.Errorf("%s: clientCert and clientKey must be both set or both empty", name)
+	}
+	return nil
+}
+
+// Material is the material loaded from the secrets Store.
+type Material struct {
+	Username string
+	Password string
+
+	// CAPool is nil when Config.CACert is empty.
+	CAPool *x509.CertPool
+
+	// Cert is nil when Config.ClientCert is empty.
+	Cert *tls.Certificate
+}
+
+// Load loads the Material configured by cfg from sec.
+//
+// name is the name of the client package (e.g. "redisbp"),
+// used as the prefix of the errors.
+func Load(name string, cfg Config, sec *secrets.Secrets) (*Material, error) {
+	var m Material
+	if cfg.Credentials != "" {
+		cred, err := sec.GetCredentialSecret(cfg.Credentials)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to get credentials %q: %w", name, cfg.Credentials, err)
+		}
+		m.Username = cred.Username
+		m.Password = cred.Password
+	}
+	if cfg.CACert != "" {
+		ca, err := sec.GetSimpleSecret(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to get ca cert %q: %w", name, cfg.CACert, err)
+		}
+		m.CAPool = x509.NewCertPool()
+		if !m.CAPool.AppendCertsFromPEM(ca.Value) {
+			return nil, fmt.Errorf("%s: no valid certificates in ca cert %q", name, cfg.CACert)
+		}
+	}
+	if cfg.ClientCert != "" {
+		cert, err := sec.GetSimpleSecret(cfg.ClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to get client cert %q: %w", name, cfg.ClientCert, err)
+		}
+		key, err := sec.GetSimpleSecret(cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to get client key %q: %w", name, cfg.ClientKey, err)
+		}
+		pair, err := tls.X509KeyPair(cert.Value, key.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid client cert/key pair: %w", name, err)
+		}
+		m.Cert = &pair
+	}
+	return &m, nil
+}
+
+// Provider keeps the latest Material loaded from the secrets Store,
+// to be used by the new connections of the clients.
+type Provider struct {
+	name     string
+	cfg      Config
+	store    *secrets.Store
+	id       secrets.MiddlewareID
+	material atomic.Value // *Material
+}
+
+var _ io.Closer = (*Provider)(nil)
+
+// NopCloser is the io.Closer to return in place of a Provider when no secrets
+// are configured.
+var NopCloser io.Closer = nopCloser{}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// New loads the Material from store,
+// and registers a middleware to the store to reload it on rotation.
+//
+// name is the name of the client package (e.g. "redisbp"),
+// used as the prefix of the errors and logs.
+//
+// The middleware is removed by Close, which should be called when the client
+// using the Provider is closed.
+//
+// It calls store.AddMiddleware, so it must not be called from within a
+// middleware of the same store.
+func New(name string, store *secrets.Store, cfg Config) (*Provider, error) {
+	if err := cfg.Validate(name); err != nil {
+		return nil, err
+	}
+	p := &Provider{
+		name:  name,
+		cfg:   cfg,
+		store: store,
+	}
+	var initErr error
+	p.id = store.AddMiddleware(func(next secrets.SecretHandlerFunc) secrets.SecretHandlerFunc {
+		return func(sec *secrets.Secrets) {
+			defer next(sec)
+			m, err := Load(name, cfg, sec)
+			if err != nil {
+				if p.material.Load() == nil {
+					initErr = err
+					return
+				}
+				log.Errorw(
+					name+": failed to reload rotated secrets, keep using the old ones",
+					"err", err,
+				)
+				return
+			}
+			p.material.Store(m)
+		}
+	})
+	if initErr != nil {
+		p.Close()
+		return nil, initErr
+	}
+	return p, nil
+}
+
+// Current returns the latest Material.
+func (p *Provider) Current() *Material {
+	return p.material.Load().(*Material)
+}
+
+// Credentials returns the latest username and password.
+func (p *Provider) Credentials() (username, password string) {
+	m := p.Current()
+	return m.Username, m.Password
+}
+
+// TLSConfig returns a tls.Config based on base (could be nil) that always uses
+// the latest TLS material on new connections.
+func (p *Provider) TLSConfig(base *tls.Config) *tls.Config {
+	var cfg *tls.Config
+	if base != nil {
+		cfg = base.Clone()
+	} else {
+		cfg = &tls.Config{}
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	if p.cfg.ClientCert != "" {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return p.Current().Cert, nil
+		}
+	}
+	if p.cfg.CACert != "" {
+		// RootCAs can't be changed after the tls.Config is used,
+		// so we do the verification ourselves with the latest CA pool instead.
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return fmt.Errorf("%s: no server certificate", p.name)
+			}
+			opts := x509.VerifyOptions{
+				Roots:         p.Current().CAPool,
+				DNSName:       cs.ServerName,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, cert := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
+		}
+	}
+	return cfg
+}
+
+// Close removes the middleware registered to the store by New,
+// so the Material is no longer reloaded on rotation.
+//
+// It's OK to call Close multiple times,
+// but it must not be called from within a middleware of the same store.
+// It always returns nil.
+func (p *Provider) Close() error {
+	p.store.RemoveMiddleware(p.id)
+	return nil
+}
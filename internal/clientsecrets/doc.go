@@ -0,0 +1,4 @@
+// Package clientsecrets loads the credentials and TLS material of the clients
+// (e.g. redisbp, kafkabp) from the secrets Store,
+// and keeps them up to date when the secrets rotate.
+package clientsecrets
@@ -0,0 +1,82 @@
+package directorywatcher
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/fsnotify.v1"
+)
+
+// AtomicDataDir is the name of the symlink to the directory of the current
+// version of the files written by the Kubernetes atomic writer,
+// see Config.AtomicWriter.
+const AtomicDataDir = "..data"
+
+// atomicFiles returns the logical paths of the files in the current version
+// of the directory written by the atomic writer, sorted.
+//
+// It returns no files if there's no version written yet.
+func (dw *DirectoryWatcher) atomicFiles() ([]string, error) {
+	data, err := filepath.EvalSymlinks(filepath.Join(dw.cfg.Path, AtomicDataDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	err = filepath.WalkDir(data, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(data, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.Join(dw.cfg.Path, rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// initAtomic records the files of the current version.
+func (dw *DirectoryWatcher) initAtomic() {
+	files, err := dw.atomicFiles()
+	if err != nil {
+		dw.reportError("failed to read existing files", err)
+	}
+	dw.atomicSnapshot = files
+}
+
+// handleAtomic translates the swap of the AtomicDataDir symlink into the
+// events of the logical files, other events are ignored.
+func (dw *DirectoryWatcher) handleAtomic(ev fsnotify.Event) {
+	if ev.Op&fsnotify.Create == 0 || ev.Name != filepath.Join(dw.cfg.Path, AtomicDataDir) {
+		return
+	}
+	files, err := dw.atomicFiles()
+	if err != nil {
+		dw.reportError("failed to read new version", err)
+		return
+	}
+	current := make(map[string]bool, len(files))
+	for _, path := range files {
+		current[path] = true
+		dw.dispatch(path, eventCreate)
+	}
+	for _, path := range dw.atomicSnapshot {
+		if !current[path] {
+			dw.dispatch(path, eventRemove)
+		}
+	}
+	dw.atomicSnapshot = files
+}
@@ -0,0 +1,93 @@
+package directorywatcher
+
+import (
+	"sort"
+	"time"
+)
+
+type eventKind int
+
+const (
+	eventCreate eventKind = iota
+	eventRemove
+)
+
+type pendingEvent struct {
+	path     string
+	kind     eventKind
+	deadline time.Time
+}
+
+// debouncer coalesces the events of the same path,
+// only the last event of a path is delivered after there's no new event of the
+// path for the interval.
+//
+// It's only used by the loop goroutine, so it's not thread-safe.
+type debouncer struct {
+	interval time.Duration
+	pending  map[string]pendingEvent
+	timer    *time.Timer
+}
+
+func newDebouncer(interval time.Duration) *debouncer {
+	return &debouncer{
+		interval: interval,
+		pending:  make(map[string]pendingEvent),
+	}
+}
+
+// add adds (or replaces) the pending event of the path.
+func (d *debouncer) add(path string, kind eventKind, now time.Time) {
+	d.pending[path] = pendingEvent{
+		path:     path,
+		kind:     kind,
+		deadline: now.Add(d.interval),
+	}
+	if d.timer == nil {
+		// The deadline of the new event is never earlier than the existing ones,
+		// so the timer only needs to be armed when there's none.
+		d.timer = time.NewTimer(d.interval)
+	}
+}
+
+// c returns the channel to receive from when the earliest pending event is
+// due, or nil if there are no pending events.
+func (d *debouncer) c() <-chan time.Time {
+	if d == nil || d.timer == nil {
+		return nil
+	}
+	return d.timer.C
+}
+
+// due removes and returns the pending events due at now, ordered by their
+// deadlines, and re-arms the timer for the rest.
+//
+// It must be called after receiving from c.
+func (d *debouncer) due(now time.Time) []pendingEvent {
+	d.timer = nil
+	var events []pendingEvent
+	var next time.Time
+	for path, ev := range d.pending {
+		if !ev.deadline.After(now) {
+			events = append(events, ev)
+			delete(d.pending, path)
+			continue
+		}
+		if next.IsZero() || ev.deadline.Before(next) {
+			next = ev.deadline
+		}
+	}
+	if !next.IsZero() {
+		d.timer = time.NewTimer(next.Sub(now))
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].deadline.Before(events[j].deadline)
+	})
+	return events
+}
+
+func (d *debouncer) stop() {
+	if d != nil && d.timer != nil {
+		d.timer.Stop()
+	}
+}
@@ -0,0 +1,454 @@
+package directorywatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/fsnotify.v1"
+
+	"github.com/reddit/baseplate.go/internal/fswatch"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// Config is the config used by New.
+type Config struct {
+	// The path to the directory to be watched, required.
+	Path string
+
+	// OnCreate is called with the path of the file created or written in the
+	// directory.
+	//
+	// Optional, nil means the events are ignored.
+	OnCreate func(path string)
+
+	// OnRemove is called with the path of the file removed or renamed away
+	// from the directory.
+	//
+	// Optional, nil means the events are ignored.
+	OnRemove func(path string)
+
+	// OnEvent is called with every event of the files in the directory as
+	// reported by the underlying file system watcher,
+	// for the consumers needing more details than OnCreate and OnRemove
+	// (e.g. to keep an audit trail of the changes).
+	//
+	// It's called before OnCreate and OnRemove of the same event,
+	// and it's not affected by EmitExisting, DebounceInterval and AtomicWriter
+	// (e.g. the events of the internal files of the atomic writer are reported
+	// as is), but Include, Exclude and MaxEventsPerSecond still apply.
+	//
+	// Optional, nil means the events are ignored.
+	OnEvent func(ev Event)
+
+	// IncludeChmod controls whether the Chmod operations (e.g. changing the
+	// permissions or the timestamps of a file) are reported to OnEvent,
+	// they are never reported to the other handlers.
+	IncludeChmod bool
+
+	// EmitExisting controls whether OnCreate is called for every file already
+	// in the directory when the watcher starts,
+	// before New returns and before any other events.
+	EmitExisting bool
+
+	// Recursive controls whether the subdirectories (and the new ones created
+	// later) are watched as well.
+	//
+	// With Recursive, the handlers are only called for the files,
+	// the files in a new subdirectory are reported via OnCreate,
+	// and the symlinks to directories are not followed.
+	Recursive bool
+
+	// DebounceInterval, when positive, coalesces the bursts of events of the
+	// same file (e.g. multiple writes, or a write followed by a rename),
+	// so that the handler of the last event is only called once after there's
+	// no new event of the file for the interval.
+	//
+	// The pending events are dropped when the watcher is stopped.
+	DebounceInterval time.Duration
+
+	// AtomicWriter is for the directories written by the Kubernetes atomic
+	// writer (e.g. ConfigMap, Secret and projected volumes, and the Vault CSI
+	// provider),
+	// where the files are updated by swapping the AtomicDataDir symlink to a
+	// new hidden directory instead of writing the visible files.
+	//
+	// With AtomicWriter, every swap is translated into OnCreate calls of all
+	// the logical files in the new version (e.g. "<Path>/foo" for
+	// "<Path>/..data/foo") and OnRemove calls of the ones no longer in it,
+	// the other events are ignored,
+	// and EmitExisting reports the logical files of the current version.
+	AtomicWriter bool
+
+	// Include and Exclude are the glob patterns (see filepath.Match) of the
+	// files to call the handlers for,
+	// e.g. Include: []string{"*.json"}, Exclude: []string{".*"}.
+	//
+	// The patterns with path separators are matched against the paths
+	// relative to Path (e.g. "sub/*.json"),
+	// the others are matched against the base names of the files.
+	//
+	// Optional, empty Include means all the files are included,
+	// and Exclude takes precedence over Include.
+	Include []string
+	Exclude []string
+
+	// PollInterval is the interval to poll the directory when fsnotify is not
+	// available, or not supported by the file system of the directory
+	// (e.g. NFS).
+	//
+	// Optional, <=0 means fswatch.DefaultPollInterval.
+	PollInterval time.Duration
+
+	// ForcePolling controls whether to always poll the directory instead of
+	// using fsnotify.
+	ForcePolling bool
+
+	// MaxEventsPerSecond, when positive, limits the events of the underlying
+	// file system watcher processed per second (with bursts up to one second
+	// worth of events), to protect the service from a runaway writer.
+	//
+	// The events over the limit are dropped and counted in the
+	// directorywatcher_dropped_events_total metric,
+	// so the handlers might miss some changes of the directory.
+	MaxEventsPerSecond float64
+
+	// MaxFileSize, when positive, is the maximum size of the files read by
+	// SnapshotWatcher.
+	//
+	// The larger files are kept out of the snapshot (or keep their previous
+	// contents), reported via OnError,
+	// and counted in the directorywatcher_dropped_events_total metric.
+	MaxFileSize int64
+
+	// OnError is called with the errors of the underlying file system watcher
+	// (e.g. the inotify queue overflowed),
+	// and the failures to watch the new subdirectories or to read the files,
+	// after which some changes of the directory might be missed.
+	//
+	// It's called in the same goroutine as the other handlers.
+	// Optional, the errors are also logged via Logger and kept for Err.
+	OnError func(err error)
+
+	// Optional. When non-nil, it will be used to log the errors returned by
+	// the underlying file system watcher.
+	Logger log.Wrapper
+}
+
+// validate checks the required fields and the patterns of the Config.
+func (cfg Config) validate() error {
+	if cfg.Path == "" {
+		return errors.New("directorywatcher: path is required")
+	}
+	return validatePatterns(cfg)
+}
+
+// withDefaults returns the Config with the nil handlers replaced by no-ops,
+// and the nil Logger replaced by log.NopWrapper.
+func (cfg Config) withDefaults() Config {
+	nop := func(string) {}
+	if cfg.OnCreate == nil {
+		cfg.OnCreate = nop
+	}
+	if cfg.OnRemove == nil {
+		cfg.OnRemove = nop
+	}
+	if cfg.OnEvent == nil {
+		cfg.OnEvent = func(Event) {}
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(error) {}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.NopWrapper
+	}
+	return cfg
+}
+
+// DirectoryWatcher watches a directory and calls the handlers of its Config on
+// the changes of the files in it.
+//
+// Only the direct children of the directory are watched unless
+// Config.Recursive is set,
+// and the files already in the directory when it's created are only reported
+// with Config.EmitExisting.
+type DirectoryWatcher struct {
+	cfg       Config
+	watcher   fswatch.Watcher
+	debouncer *debouncer    // nil without Config.DebounceInterval
+	limiter   *eventLimiter // nil without Config.MaxEventsPerSecond
+
+	// The logical files of the current version with Config.AtomicWriter.
+	atomicSnapshot []string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	ready  chan struct{}
+	done   chan struct{}
+
+	// The error closing the underlying watcher, only set before done is closed.
+	closeErr error
+
+	errLock sync.Mutex
+	lastErr error
+}
+
+var _ io.Closer = (*DirectoryWatcher)(nil)
+
+// New creates a DirectoryWatcher and starts watching the directory.
+//
+// The directory must exist when calling New,
+// and New fails if cfg.Path is empty.
+func New(ctx context.Context, cfg Config) (*DirectoryWatcher, error) {
+	dw, err := newDirectoryWatcher(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dw.start(ctx)
+	return dw, nil
+}
+
+// newDirectoryWatcher creates a DirectoryWatcher watching the directory,
+// without calling any handlers until start is called.
+func newDirectoryWatcher(cfg Config) (*DirectoryWatcher, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+	watcher := fswatch.New(fswatch.Config{
+		PollInterval: cfg.PollInterval,
+		ForcePolling: cfg.ForcePolling,
+	})
+	dw := &DirectoryWatcher{
+		cfg:     cfg,
+		watcher: watcher,
+		ready:   make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := dw.add(cfg.Path); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	if cfg.DebounceInterval > 0 {
+		dw.debouncer = newDebouncer(cfg.DebounceInterval)
+	}
+	dw.limiter = newEventLimiter(cfg.MaxEventsPerSecond, time.Now())
+	return dw, nil
+}
+
+func (dw *DirectoryWatcher) start(ctx context.Context) {
+	dw.ctx, dw.cancel = context.WithCancel(ctx)
+	go dw.loop()
+	// The existing files (if any) are reported in the loop goroutine,
+	// so the handlers are never called concurrently.
+	<-dw.ready
+}
+
+// Stop stops watching the directory without waiting for it to stop.
+//
+// Unlike Close, it's OK to call Stop from the handlers.
+// No more handlers are called after the handler being called (if any) returns.
+// It's OK to call Stop multiple times, and to call Close after Stop.
+func (dw *DirectoryWatcher) Stop() {
+	dw.cancel()
+}
+
+// Close stops watching the directory,
+// waits for the handler being called (if any) to return,
+// and returns the error closing the underlying file system watcher.
+//
+// It's OK to call Close multiple times (and concurrently),
+// all the calls wait for the watcher to stop and return the same error.
+// It's also OK to call Close after the context passed into New is canceled.
+//
+// Close must not be called from the handlers, as it would wait for the handler
+// calling it to return and deadlock. Use Stop there instead.
+func (dw *DirectoryWatcher) Close() error {
+	dw.cancel()
+	<-dw.done
+	return dw.closeErr
+}
+
+// add watches dir, and its subdirectories with Recursive.
+func (dw *DirectoryWatcher) add(dir string) error {
+	if !dw.cfg.Recursive {
+		if err := dw.watcher.Add(dir); err != nil {
+			return fmt.Errorf("directorywatcher: failed to watch %q: %w", dir, err)
+		}
+		return nil
+	}
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if err := dw.watcher.Add(path); err != nil {
+			return fmt.Errorf("directorywatcher: failed to watch %q: %w", path, err)
+		}
+		return nil
+	})
+}
+
+func (dw *DirectoryWatcher) loop() {
+	defer func() {
+		dw.debouncer.stop()
+		if err := dw.watcher.Close(); err != nil {
+			dw.closeErr = fmt.Errorf("directorywatcher: failed to close watcher: %w", err)
+		}
+		close(dw.done)
+	}()
+	switch {
+	case dw.cfg.AtomicWriter:
+		dw.initAtomic()
+		if dw.cfg.EmitExisting {
+			for _, path := range dw.atomicSnapshot {
+				dw.call(path, eventCreate)
+			}
+		}
+	case dw.cfg.EmitExisting:
+		dw.emitFiles(dw.cfg.Path, dw.call)
+	}
+	close(dw.ready)
+	for {
+		select {
+		case <-dw.ctx.Done():
+			return
+
+		case err := <-dw.watcher.Errors():
+			if dw.ctx.Err() != nil {
+				// Closed by a handler.
+				return
+			}
+			dw.reportError("watcher error", err)
+
+		case ev := <-dw.watcher.Events():
+			if dw.ctx.Err() != nil {
+				return
+			}
+			now := time.Now()
+			if !dw.limiter.allow(now) {
+				dw.drop(reasonRateLimited)
+				continue
+			}
+			dw.event(ev, now)
+			dw.handle(ev)
+
+		case now := <-dw.debouncer.c():
+			for _, ev := range dw.debouncer.due(now) {
+				dw.call(ev.path, ev.kind)
+			}
+		}
+	}
+}
+
+func (dw *DirectoryWatcher) handle(ev fsnotify.Event) {
+	if dw.cfg.AtomicWriter {
+		dw.handleAtomic(ev)
+		return
+	}
+	switch {
+	case ev.Op&(fsnotify.Create|fsnotify.Write) != 0:
+		if dw.cfg.Recursive && ev.Op&fsnotify.Create != 0 {
+			if lstatIsDir(ev.Name) {
+				// Watch the new subdirectory, and report the files created in it
+				// before it's watched.
+				if err := dw.add(ev.Name); err != nil {
+					dw.reportError("failed to watch new directory", err)
+				}
+				dw.emitFiles(ev.Name, dw.dispatch)
+				return
+			}
+		}
+		dw.dispatch(ev.Name, eventCreate)
+	case ev.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		dw.dispatch(ev.Name, eventRemove)
+	}
+}
+
+// dispatch calls the handler of the event, or defers it with DebounceInterval.
+func (dw *DirectoryWatcher) dispatch(path string, kind eventKind) {
+	if dw.debouncer != nil {
+		dw.debouncer.add(path, kind, time.Now())
+		return
+	}
+	dw.call(path, kind)
+}
+
+// call calls the handler of the event, if the file is included and the
+// watcher is not closed (e.g. by the previous handler).
+func (dw *DirectoryWatcher) call(path string, kind eventKind) {
+	if !dw.included(path) || dw.ctx.Err() != nil {
+		return
+	}
+	if kind == eventRemove {
+		dw.cfg.OnRemove(path)
+		return
+	}
+	dw.cfg.OnCreate(path)
+}
+
+// emitFiles calls emit with eventCreate for the files already in dir,
+// and in its subdirectories with Recursive.
+func (dw *DirectoryWatcher) emitFiles(dir string, emit func(string, eventKind)) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		dw.reportError("failed to read existing files", err)
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			if dw.cfg.Recursive {
+				dw.emitFiles(path, emit)
+			}
+			continue
+		}
+		emit(path, eventCreate)
+	}
+}
+
+// lstatIsDir returns true if path is a directory (not a symlink to one).
+func lstatIsDir(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.IsDir()
+}
+
+// drop counts a dropped event.
+func (dw *DirectoryWatcher) drop(reason string) {
+	droppedEventsCounter.With(prometheus.Labels{
+		PrometheusPathLabel:   dw.cfg.Path,
+		PrometheusReasonLabel: reason,
+	}).Inc()
+}
+
+// Err returns the last error reported to Config.OnError, or nil if there's
+// none.
+//
+// A non-nil error means some changes of the directory might have been missed,
+// e.g. the services could use it to mark themselves unhealthy.
+func (dw *DirectoryWatcher) Err() error {
+	dw.errLock.Lock()
+	defer dw.errLock.Unlock()
+	return dw.lastErr
+}
+
+// reportError logs the error, keeps it for Err, and calls OnError.
+func (dw *DirectoryWatcher) reportError(msg string, err error) {
+	err = fmt.Errorf("directorywatcher: %s: %w", msg, err)
+	dw.cfg.Logger.Log(context.Background(), err.Error())
+
+	dw.errLock.Lock()
+	dw.lastErr = err
+	dw.errLock.Unlock()
+
+	dw.cfg.OnError(err)
+}
@@ -0,0 +1,744 @@
+package directorywatcher_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/internal/directorywatcher"
+	"github.com/reddit/baseplate.go/log"
+)
+
+func TestDirectoryWatcher(t *testing.T) {
+	dir := t.TempDir()
+	created := make(chan string, 10)
+	removed := make(chan string, 10)
+	dw, err := directorywatcher.New(context.Background(), directorywatcher.Config{
+		Path: dir,
+		OnCreate: func(path string) {
+			created <- path
+		},
+		OnRemove: func(path string) {
+			removed <- path
+		},
+		Logger: log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dw.Stop()
+
+	path := filepath.Join(dir, "foo")
+	if err := os.WriteFile(path, []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-created:
+		if got != path {
+			t.Errorf("OnCreate got %q, want %q", got, path)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnCreate not called")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-removed:
+		if got != path {
+			t.Errorf("OnRemove got %q, want %q", got, path)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnRemove not called")
+	}
+}
+
+func TestNewNotExist(t *testing.T) {
+	_, err := directorywatcher.New(context.Background(), directorywatcher.Config{
+		Path: filepath.Join(t.TempDir(), "not-exist"),
+	})
+	if err == nil {
+		t.Error("expected error for non-existing directory")
+	}
+}
+
+func TestEmitExisting(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"bar", "foo"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var created []string
+	dw, err := directorywatcher.New(context.Background(), directorywatcher.Config{
+		Path: dir,
+		OnCreate: func(path string) {
+			created = append(created, path)
+		},
+		EmitExisting: true,
+		Logger:       log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Stop before reading created, the existing files are reported before New
+	// returns, and there are no other events.
+	dw.Stop()
+
+	expected := []string{filepath.Join(dir, "bar"), filepath.Join(dir, "foo")}
+	if !reflect.DeepEqual(created, expected) {
+		t.Errorf("OnCreate got %q, want %q", created, expected)
+	}
+}
+
+func TestRecursive(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "a", "b", "existing")
+	if err := os.MkdirAll(filepath.Dir(existing), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(existing, []byte("existing"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	created := make(chan string, 100)
+	dw, err := directorywatcher.New(context.Background(), directorywatcher.Config{
+		Path: dir,
+		OnCreate: func(path string) {
+			created <- path
+		},
+		EmitExisting: true,
+		Recursive:    true,
+		Logger:       log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dw.Stop()
+
+	expectCreated := func(t *testing.T, path string) {
+		t.Helper()
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case got := <-created:
+				if got == path {
+					return
+				}
+				if info, err := os.Stat(got); err == nil && info.IsDir() {
+					t.Errorf("OnCreate called for directory %q", got)
+				}
+			case <-timeout:
+				t.Fatalf("OnCreate not called for %q", path)
+			}
+		}
+	}
+	expectCreated(t, existing)
+
+	nested := filepath.Join(dir, "a", "b", "nested")
+	if err := os.WriteFile(nested, []byte("nested"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expectCreated(t, nested)
+
+	// The file created together with its new directory could be created before
+	// the directory is watched.
+	newDir := filepath.Join(dir, "c", "d")
+	if err := os.MkdirAll(newDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	inNewDir := filepath.Join(newDir, "file")
+	if err := os.WriteFile(inNewDir, []byte("file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expectCreated(t, inNewDir)
+}
+
+func TestDebounce(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	dir := t.TempDir()
+	var lock sync.Mutex
+	var events []string
+	dw, err := directorywatcher.New(context.Background(), directorywatcher.Config{
+		Path: dir,
+		OnCreate: func(path string) {
+			lock.Lock()
+			defer lock.Unlock()
+			events = append(events, "create "+filepath.Base(path))
+		},
+		OnRemove: func(path string) {
+			lock.Lock()
+			defer lock.Unlock()
+			events = append(events, "remove "+filepath.Base(path))
+		},
+		DebounceInterval: interval,
+		Logger:           log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dw.Stop()
+
+	foo := filepath.Join(dir, "foo")
+	for i := 0; i < 5; i++ {
+		if err := os.WriteFile(foo, []byte(strconv.Itoa(i)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Write a temp file then rename it, the temp file should only be reported
+	// as removed.
+	tmp := filepath.Join(dir, "bar.tmp")
+	if err := os.WriteFile(tmp, []byte("bar"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, "bar")); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(interval * 5)
+	lock.Lock()
+	defer lock.Unlock()
+	sort.Strings(events)
+	expected := []string{"create bar", "create foo", "remove bar.tmp"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected events %q, got %q", expected, events)
+	}
+}
+
+// writeAtomicVersion writes files into a new version directory of dir and
+// swaps the ..data symlink to it, the same way the Kubernetes atomic writer
+// does.
+func writeAtomicVersion(t *testing.T, dir, version string, files map[string]string) {
+	t.Helper()
+
+	for path, content := range files {
+		path = filepath.Join(dir, version, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tmp := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink(version, tmp); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, directorywatcher.AtomicDataDir)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAtomicWriter(t *testing.T) {
+	dir := t.TempDir()
+	writeAtomicVersion(t, dir, "..v1", map[string]string{
+		"foo":     "foo",
+		"sub/bar": "bar",
+	})
+
+	events := make(chan string, 100)
+	dw, err := directorywatcher.New(context.Background(), directorywatcher.Config{
+		Path: dir,
+		OnCreate: func(path string) {
+			rel, _ := filepath.Rel(dir, path)
+			events <- "create " + rel
+		},
+		OnRemove: func(path string) {
+			rel, _ := filepath.Rel(dir, path)
+			events <- "remove " + rel
+		},
+		EmitExisting: true,
+		AtomicWriter: true,
+		Logger:       log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dw.Stop()
+
+	expectEvents := func(t *testing.T, expected ...string) {
+		t.Helper()
+		var got []string
+		for range expected {
+			select {
+			case ev := <-events:
+				got = append(got, ev)
+			case <-time.After(time.Second):
+				t.Fatalf("Expected events %q, got %q", expected, got)
+			}
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected events %q, got %q", expected, got)
+		}
+	}
+	expectEvents(t, "create foo", "create sub/bar")
+
+	writeAtomicVersion(t, dir, "..v2", map[string]string{
+		"foo": "updated",
+		"baz": "baz",
+	})
+	expectEvents(t, "create baz", "create foo", "remove sub/bar")
+
+	select {
+	case ev := <-events:
+		t.Errorf("Unexpected event %q", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestIncludeExclude(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{
+		"foo.json",
+		".foo.json",
+		"foo.json.swp",
+		"bar.yaml",
+		"sub/bar.yaml",
+		"sub/baz.json",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var created []string
+	dw, err := directorywatcher.New(context.Background(), directorywatcher.Config{
+		Path: dir,
+		OnCreate: func(path string) {
+			rel, _ := filepath.Rel(dir, path)
+			created = append(created, rel)
+		},
+		EmitExisting: true,
+		Recursive:    true,
+		Include:      []string{"*.json", filepath.Join("sub", "*.yaml")},
+		Exclude:      []string{".*"},
+		Logger:       log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dw.Stop()
+
+	expected := []string{"foo.json", filepath.Join("sub", "bar.yaml"), filepath.Join("sub", "baz.json")}
+	if !reflect.DeepEqual(created, expected) {
+		t.Errorf("OnCreate got %q, want %q", created, expected)
+	}
+
+	_, err = directorywatcher.New(context.Background(), directorywatcher.Config{
+		Path:    dir,
+		Include: []string{"["},
+	})
+	if err == nil {
+		t.Error("Expected error for the invalid pattern")
+	}
+}
+
+func TestPolling(t *testing.T) {
+	dir := t.TempDir()
+	events := make(chan string, 10)
+	dw, err := directorywatcher.New(context.Background(), directorywatcher.Config{
+		Path: dir,
+		OnCreate: func(path string) {
+			events <- "create " + filepath.Base(path)
+		},
+		OnRemove: func(path string) {
+			events <- "remove " + filepath.Base(path)
+		},
+		PollInterval: time.Millisecond * 10,
+		ForcePolling: true,
+		Logger:       log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dw.Stop()
+
+	expectEvent := func(t *testing.T, expected string) {
+		t.Helper()
+		select {
+		case got := <-events:
+			if got != expected {
+				t.Errorf("Expected event %q, got %q", expected, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected event %q", expected)
+		}
+	}
+
+	path := filepath.Join(dir, "foo")
+	if err := os.WriteFile(path, []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, "create foo")
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, "remove foo")
+}
+
+func TestClose(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	var returned int64
+	var once sync.Once
+	dw, err := directorywatcher.New(ctx, directorywatcher.Config{
+		Path: dir,
+		OnCreate: func(path string) {
+			once.Do(func() { close(handling) })
+			<-release
+			atomic.StoreInt64(&returned, 1)
+		},
+		Logger: log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "foo"), []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-handling:
+	case <-time.After(time.Second):
+		t.Fatal("OnCreate not called")
+	}
+
+	// Close waits for the handler being called to return.
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		close(release)
+	}()
+	if err := dw.Close(); err != nil {
+		t.Errorf("Close returned %v", err)
+	}
+	if atomic.LoadInt64(&returned) != 1 {
+		t.Error("Close returned before the handler")
+	}
+
+	// The subsequent calls, concurrent or not, are no-op.
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := dw.Close(); err != nil {
+				t.Errorf("Close returned %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	cancel()
+	dw.Stop()
+}
+
+func TestStopFromHandler(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan struct{})
+	var calls int64
+	var dw *directorywatcher.DirectoryWatcher
+	ready := make(chan struct{})
+	dw, err := directorywatcher.New(ctx, directorywatcher.Config{
+		Path: dir,
+		OnCreate: func(path string) {
+			<-ready
+			if atomic.AddInt64(&calls, 1) == 1 {
+				dw.Stop()
+				close(stopped)
+			}
+		},
+		Logger: log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(ready)
+
+	if err := os.WriteFile(filepath.Join(dir, "foo"), []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop from handler deadlocked")
+	}
+
+	// Close from other goroutines still waits for the watcher to stop.
+	if err := dw.Close(); err != nil {
+		t.Errorf("Close returned %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bar"), []byte("bar"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 50)
+	if calls := atomic.LoadInt64(&calls); calls != 1 {
+		t.Errorf("Expected no handlers called after Stop, got %d calls", calls)
+	}
+}
+
+func TestOnError(t *testing.T) {
+	dir := t.TempDir()
+	// A symlink loop fails reading the version of the atomic writer.
+	if err := os.Symlink(directorywatcher.AtomicDataDir, filepath.Join(dir, directorywatcher.AtomicDataDir)); err != nil {
+		t.Fatal(err)
+	}
+	var errs []error
+	dw, err := directorywatcher.New(context.Background(), directorywatcher.Config{
+		Path:         dir,
+		AtomicWriter: true,
+		OnError: func(err error) {
+			errs = append(errs, err)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dw.Stop()
+
+	// The version is read before New returns.
+	if len(errs) != 1 {
+		t.Fatalf("Expected OnError to be called once, got %v", errs)
+	}
+	if got := dw.Err(); got != errs[0] {
+		t.Errorf("Err() got %v, want %v", got, errs[0])
+	}
+}
+
+func TestErrNil(t *testing.T) {
+	dw, err := directorywatcher.New(context.Background(), directorywatcher.Config{
+		Path:   t.TempDir(),
+		Logger: log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dw.Stop()
+	if err := dw.Err(); err != nil {
+		t.Errorf("Expected Err() to be nil, got %v", err)
+	}
+}
+
+func TestSnapshotWatcher(t *testing.T) {
+	dir := t.TempDir()
+	foo := filepath.Join(dir, "foo")
+	if err := os.WriteFile(foo, []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sw, err := directorywatcher.NewSnapshotWatcher(context.Background(), directorywatcher.Config{
+		Path:   dir,
+		Logger: log.TestWrapper(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Stop()
+
+	initial := sw.Get()
+	if want := map[string][]byte{foo: []byte("foo")}; !reflect.DeepEqual(initial.Files, want) {
+		t.Errorf("Initial files got %q, want %q", initial.Files, want)
+	}
+
+	waitFor := func(t *testing.T, want map[string][]byte) directorywatcher.Snapshot {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for {
+			snapshot := sw.Get()
+			if reflect.DeepEqual(snapshot.Files, want) {
+				return snapshot
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Files got %q, want %q", snapshot.Files, want)
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+	}
+
+	bar := filepath.Join(dir, "bar")
+	if err := os.WriteFile(bar, []byte("bar"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	added := waitFor(t, map[string][]byte{
+		foo: []byte("foo"),
+		bar: []byte("bar"),
+	})
+	if added.Generation <= initial.Generation {
+		t.Errorf("Generation not incremented from %d, got %d", initial.Generation, added.Generation)
+	}
+
+	if err := os.Remove(foo); err != nil {
+		t.Fatal(err)
+	}
+	removed := waitFor(t, map[string][]byte{bar: []byte("bar")})
+	if removed.Generation <= added.Generation {
+		t.Errorf("Generation not incremented from %d, got %d", added.Generation, removed.Generation)
+	}
+
+	// The previous snapshots are not modified.
+	if want := map[string][]byte{foo: []byte("foo")}; !reflect.DeepEqual(initial.Files, want) {
+		t.Errorf("Initial files modified to %q", initial.Files)
+	}
+}
+
+func TestSnapshotWatcherMaxFileSize(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small")
+	large := filepath.Join(dir, "large")
+	if err := os.WriteFile(small, []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(large, []byte("foobar"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []error
+	sw, err := directorywatcher.NewSnapshotWatcher(context.Background(), directorywatcher.Config{
+		Path:        dir,
+		MaxFileSize: 5,
+		OnError: func(err error) {
+			errs = append(errs, err)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Stop()
+
+	if got, want := sw.Get().Files, map[string][]byte{small: []byte("foo")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Files got %q, want %q", got, want)
+	}
+	if len(errs) != 1 {
+		t.Errorf("Expected OnError to be called once for the large file, got %v", errs)
+	}
+}
+
+func TestNilHandlers(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "existing"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A symlink loop makes the atomic writer report an error.
+	if err := os.Symlink(directorywatcher.AtomicDataDir, filepath.Join(dir, directorywatcher.AtomicDataDir)); err != nil {
+		t.Fatal(err)
+	}
+	dw, err := directorywatcher.New(context.Background(), directorywatcher.Config{
+		Path:         dir,
+		EmitExisting: true,
+		AtomicWriter: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dw.Err() == nil {
+		t.Error("Expected the error of the symlink loop")
+	}
+	dw.Stop()
+
+	dw, err = directorywatcher.New(context.Background(), directorywatcher.Config{
+		Path:         dir,
+		EmitExisting: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dw.Stop()
+	path := filepath.Join(dir, "foo")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	// Give the loop some time to handle the events.
+	time.Sleep(time.Millisecond * 50)
+}
+
+func TestEmptyPath(t *testing.T) {
+	_, err := directorywatcher.New(context.Background(), directorywatcher.Config{})
+	if err == nil {
+		t.Error("Expected error for empty path")
+	}
+}
+
+func TestOnEvent(t *testing.T) {
+	for _, includeChmod := range []bool{false, true} {
+		t.Run(strconv.FormatBool(includeChmod), func(t *testing.T) {
+			dir := t.TempDir()
+			events := make(chan directorywatcher.Event, 10)
+			path := filepath.Join(dir, "foo")
+			start := time.Now()
+			dw, err := directorywatcher.New(context.Background(), directorywatcher.Config{
+				Path: dir,
+				OnEvent: func(ev directorywatcher.Event) {
+					events <- ev
+				},
+				IncludeChmod: includeChmod,
+				Logger:       log.TestWrapper(t),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer dw.Stop()
+
+			var ops directorywatcher.Op
+			// fsnotify drops the events of the files already removed when
+			// they are read, so wait for every event before the next change.
+			waitFor := func(t *testing.T, op directorywatcher.Op) {
+				t.Helper()
+				timeout := time.After(time.Second)
+				for ops&op == 0 {
+					select {
+					case ev := <-events:
+						if ev.Path != path {
+							t.Errorf("Unexpected event path %q", ev.Path)
+						}
+						if ev.Time.Before(start) {
+							t.Errorf("Unexpected event time %v before %v", ev.Time, start)
+						}
+						ops |= ev.Op
+					case <-timeout:
+						t.Fatalf("%v event not received, got %v", op, ops)
+					}
+				}
+			}
+
+			if err := os.WriteFile(path, []byte("foo"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			waitFor(t, directorywatcher.OpCreate)
+			if err := os.Chmod(path, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if includeChmod {
+				waitFor(t, directorywatcher.OpChmod)
+			}
+			if err := os.Remove(path); err != nil {
+				t.Fatal(err)
+			}
+			waitFor(t, directorywatcher.OpRemove)
+			if !includeChmod && ops&directorywatcher.OpChmod != 0 {
+				t.Errorf("Unexpected chmod event, got %v", ops)
+			}
+		})
+	}
+}
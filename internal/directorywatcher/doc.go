@@ -0,0 +1,7 @@
+// Package directorywatcher watches a directory for the changes of the files in
+// it, and calls the configured handlers on them.
+//
+// It's the directory counterpart of filewatcher,
+// used by the secrets stores backed by a directory of files
+// (e.g. the Vault CSI provider).
+package directorywatcher
@@ -0,0 +1,58 @@
+package directorywatcher
+
+import (
+	"time"
+
+	"gopkg.in/fsnotify.v1"
+)
+
+// Op is the bitmask of the operations of an Event,
+// with the same values as fsnotify.Op.
+type Op uint32
+
+// The operations of an Event.
+const (
+	OpCreate Op = Op(fsnotify.Create)
+	OpWrite  Op = Op(fsnotify.Write)
+	OpRemove Op = Op(fsnotify.Remove)
+	OpRename Op = Op(fsnotify.Rename)
+	OpChmod  Op = Op(fsnotify.Chmod)
+)
+
+// String returns the names of the operations joined by "|",
+// e.g. "CREATE|WRITE".
+func (op Op) String() string {
+	return fsnotify.Op(op).String()
+}
+
+// Event is an event of a file in the watched directory,
+// passed to Config.OnEvent.
+type Event struct {
+	// Op is the operations of the event,
+	// which could have more than one bits set.
+	Op Op
+
+	// Path is the path of the file.
+	Path string
+
+	// Time is when the event was received from the underlying file system
+	// watcher.
+	Time time.Time
+}
+
+// event calls OnEvent with the event of the underlying watcher received at
+// now, if the file is included.
+func (dw *DirectoryWatcher) event(ev fsnotify.Event, now time.Time) {
+	op := Op(ev.Op)
+	if !dw.cfg.IncludeChmod {
+		op &^= OpChmod
+	}
+	if op == 0 || !dw.included(ev.Name) {
+		return
+	}
+	dw.cfg.OnEvent(Event{
+		Op:   op,
+		Path: ev.Name,
+		Time: now,
+	})
+}
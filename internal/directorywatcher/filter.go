@@ -0,0 +1,54 @@
+package directorywatcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// validatePatterns checks the Include and Exclude patterns of the Config.
+func validatePatterns(cfg Config) error {
+	for _, patterns := range [][]string{cfg.Include, cfg.Exclude} {
+		for _, pattern := range patterns {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("directorywatcher: invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// included returns true if the file of path passes the Include and Exclude
+// patterns.
+func (dw *DirectoryWatcher) included(path string) bool {
+	if len(dw.cfg.Include) == 0 && len(dw.cfg.Exclude) == 0 {
+		return true
+	}
+	rel, err := filepath.Rel(dw.cfg.Path, path)
+	if err != nil {
+		rel = path
+	}
+	if matchAny(dw.cfg.Exclude, rel) {
+		return false
+	}
+	return len(dw.cfg.Include) == 0 || matchAny(dw.cfg.Include, rel)
+}
+
+// matchAny returns true if rel matches any of the patterns.
+//
+// The patterns with path separators are matched against rel,
+// the others are matched against the base name of rel.
+func matchAny(patterns []string, rel string) bool {
+	base := filepath.Base(rel)
+	for _, pattern := range patterns {
+		name := base
+		if strings.ContainsRune(pattern, filepath.Separator) {
+			name = rel
+		}
+		// The patterns are validated in New.
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package directorywatcher
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// errFileTooLarge is the error reading a file larger than Config.MaxFileSize.
+var errFileTooLarge = errors.New("file size exceeds the limit")
+
+// eventLimiter is a token bucket limiting the events processed per second,
+// only accessed in the loop goroutine.
+type eventLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newEventLimiter creates an eventLimiter allowing rate events per second,
+// with bursts up to one second worth of events.
+//
+// It returns nil when rate <= 0, which allows all the events.
+func newEventLimiter(rate float64, now time.Time) *eventLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	burst := math.Max(1, math.Ceil(rate))
+	return &eventLimiter{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   now,
+	}
+}
+
+// allow returns true if an event is allowed at now.
+func (l *eventLimiter) allow(now time.Time) bool {
+	if l == nil {
+		return true
+	}
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = math.Min(l.burst, l.tokens+elapsed.Seconds()*l.rate)
+		l.last = now
+	}
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// readFile reads the file at path,
+// failing with errFileTooLarge if it's larger than limit when limit > 0.
+func readFile(path string, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return os.ReadFile(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > limit {
+		return nil, fmt.Errorf("%w: %d > %d", errFileTooLarge, info.Size(), limit)
+	}
+	// The file could still grow after Stat.
+	content, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("%w: > %d", errFileTooLarge, limit)
+	}
+	return content, nil
+}
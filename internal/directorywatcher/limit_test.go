@@ -0,0 +1,106 @@
+package directorywatcher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestEventLimiter(t *testing.T) {
+	start := time.Unix(1000, 0)
+	l := newEventLimiter(2, start)
+	for i := 0; i < 2; i++ {
+		if !l.allow(start) {
+			t.Errorf("Event #%d of the burst not allowed", i)
+		}
+	}
+	if l.allow(start) {
+		t.Error("Event over the burst allowed")
+	}
+	if !l.allow(start.Add(time.Millisecond * 500)) {
+		t.Error("Event not allowed after refill")
+	}
+	if l.allow(start.Add(time.Millisecond * 500)) {
+		t.Error("Event over the refilled tokens allowed")
+	}
+
+	unlimited := newEventLimiter(0, start)
+	for i := 0; i < 10; i++ {
+		if !unlimited.allow(start) {
+			t.Fatal("Event not allowed without limit")
+		}
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo")
+	if err := os.WriteFile(path, []byte("foobar"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		limit   int64
+		tooLong bool
+	}{
+		{limit: 0},
+		{limit: 6},
+		{limit: 5, tooLong: true},
+	} {
+		t.Run(strconv.FormatInt(c.limit, 10), func(t *testing.T) {
+			content, err := readFile(path, c.limit)
+			if c.tooLong {
+				if !errors.Is(err, errFileTooLarge) {
+					t.Errorf("Expected errFileTooLarge, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != "foobar" {
+				t.Errorf("Got %q, want %q", content, "foobar")
+			}
+		})
+	}
+}
+
+func TestDroppedEventsMetric(t *testing.T) {
+	dir := t.TempDir()
+	created := make(chan string, 10)
+	dw, err := New(context.Background(), Config{
+		Path: dir,
+		OnCreate: func(path string) {
+			created <- path
+		},
+		MaxEventsPerSecond: 0.001,
+		MaxFileSize:        1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dw.Stop()
+
+	// With the burst of 1 event, the write event following the create event is
+	// dropped.
+	if err := os.WriteFile(filepath.Join(dir, "foo"), []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-created:
+	case <-time.After(time.Second):
+		t.Fatal("OnCreate not called")
+	}
+	counter := droppedEventsCounter.WithLabelValues(dir, reasonRateLimited)
+	deadline := time.Now().Add(time.Second)
+	for testutil.ToFloat64(counter) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Dropped event not counted")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
@@ -0,0 +1,30 @@
+package directorywatcher
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus label names reported by directorywatcher.
+const (
+	// PrometheusPathLabel is the label name of the watched directories.
+	PrometheusPathLabel = "directorywatcher_path"
+
+	// PrometheusReasonLabel is the label name of the reasons the events are
+	// dropped.
+	PrometheusReasonLabel = "directorywatcher_reason"
+)
+
+// The values of PrometheusReasonLabel.
+const (
+	reasonRateLimited  = "rate_limited"
+	reasonFileTooLarge = "file_too_large"
+)
+
+var droppedEventsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "directorywatcher_dropped_events_total",
+	Help: "Total number of the events dropped by the directory watchers because of Config.MaxEventsPerSecond or Config.MaxFileSize",
+}, []string{
+	PrometheusPathLabel,
+	PrometheusReasonLabel,
+})
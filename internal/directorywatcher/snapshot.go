@@ -0,0 +1,122 @@
+package directorywatcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"sync/atomic"
+)
+
+// Snapshot is the contents of the files in the directory watched by a
+// SnapshotWatcher at some point.
+type Snapshot struct {
+	// Files are the contents of the files by their paths,
+	// which are the same paths passed to the handlers of Config.
+	//
+	// It's shared by all the callers of SnapshotWatcher.Get and must not be
+	// modified.
+	Files map[string][]byte
+
+	// Generation starts from 0 for the empty directory,
+	// and is incremented every time Files changes.
+	Generation uint64
+}
+
+// SnapshotWatcher is a DirectoryWatcher maintaining the contents of the files
+// in the directory,
+// for the consumers that need the whole directory instead of the changes.
+type SnapshotWatcher struct {
+	*DirectoryWatcher
+
+	onCreate func(path string)
+	onRemove func(path string)
+
+	// Only accessed by the handlers, which are never called concurrently.
+	files      map[string][]byte
+	generation uint64
+
+	snapshot atomic.Value // Snapshot
+}
+
+// NewSnapshotWatcher creates a SnapshotWatcher and starts watching the
+// directory.
+//
+// The files already in the directory are read before NewSnapshotWatcher
+// returns (cfg.EmitExisting is implied),
+// and every file created, written or removed afterwards is re-read before
+// calling the handlers of cfg (if any),
+// so Get always returns the snapshot with the change when they are called.
+//
+// The failures to read the files (including the ones larger than
+// cfg.MaxFileSize) are reported via cfg.OnError,
+// and the previous contents of those files are kept.
+//
+// The directory must exist when calling NewSnapshotWatcher.
+func NewSnapshotWatcher(ctx context.Context, cfg Config) (*SnapshotWatcher, error) {
+	cfg = cfg.withDefaults()
+	sw := &SnapshotWatcher{
+		onCreate: cfg.OnCreate,
+		onRemove: cfg.OnRemove,
+		files:    make(map[string][]byte),
+	}
+	sw.snapshot.Store(Snapshot{Files: map[string][]byte{}})
+
+	cfg.EmitExisting = true
+	cfg.OnCreate = sw.create
+	cfg.OnRemove = sw.remove
+	dw, err := newDirectoryWatcher(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sw.DirectoryWatcher = dw
+	dw.start(ctx)
+	return sw, nil
+}
+
+// Get returns the current Snapshot of the directory.
+func (sw *SnapshotWatcher) Get() Snapshot {
+	return sw.snapshot.Load().(Snapshot)
+}
+
+func (sw *SnapshotWatcher) create(path string) {
+	content, err := readFile(path, sw.cfg.MaxFileSize)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// Removed after the event, the event of the removal will follow.
+		return
+	case errors.Is(err, errFileTooLarge):
+		sw.drop(reasonFileTooLarge)
+		sw.reportError("failed to read "+path, err)
+		return
+	case err != nil:
+		sw.reportError("failed to read "+path, err)
+		return
+	}
+	if old, ok := sw.files[path]; !ok || !bytes.Equal(old, content) {
+		sw.files[path] = content
+		sw.publish()
+	}
+	sw.onCreate(path)
+}
+
+func (sw *SnapshotWatcher) remove(path string) {
+	if _, ok := sw.files[path]; ok {
+		delete(sw.files, path)
+		sw.publish()
+	}
+	sw.onRemove(path)
+}
+
+// publish stores a copy of the current files as the new Snapshot.
+func (sw *SnapshotWatcher) publish() {
+	sw.generation++
+	files := make(map[string][]byte, len(sw.files))
+	for path, content := range sw.files {
+		files[path] = content
+	}
+	sw.snapshot.Store(Snapshot{
+		Files:      files,
+		Generation: sw.generation,
+	})
+}
@@ -0,0 +1,7 @@
+// Package fswatch provides the file system watcher used by filewatcher and
+// directorywatcher.
+//
+// It's backed by fsnotify, and falls back to polling when fsnotify is not
+// available, or when the watched path is on a file system not supporting
+// inotify (e.g. NFS).
+package fswatch
@@ -0,0 +1,36 @@
+//go:build linux
+// +build linux
+
+package fswatch
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// The magic numbers of the file systems not supporting inotify for the changes
+// made by other hosts, from statfs(2).
+const (
+	nfsSuperMagic  = 0x6969
+	smbSuperMagic  = 0x517b
+	smb2SuperMagic = 0xfe534d42
+	cifsSuperMagic = 0xff534d42
+	fuseSuperMagic = 0x65735546
+)
+
+var unsupportedFileSystems = map[int64]bool{
+	nfsSuperMagic:  true,
+	smbSuperMagic:  true,
+	smb2SuperMagic: true,
+	cifsSuperMagic: true,
+	fuseSuperMagic: true,
+}
+
+// unsupportedFileSystem returns true if path is on a file system not
+// supporting inotify.
+func unsupportedFileSystem(path string) bool {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return false
+	}
+	return unsupportedFileSystems[int64(stat.Type)]
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package fswatch
+
+// unsupportedFileSystem always returns false on the platforms other than
+// linux, where the file system types are not detected.
+func unsupportedFileSystem(path string) bool {
+	return false
+}
@@ -0,0 +1,163 @@
+package fswatch
+
+import (
+	"sync"
+	"time"
+
+	"gopkg.in/fsnotify.v1"
+)
+
+// DefaultPollInterval is the default Config.PollInterval used when it's <=0.
+const DefaultPollInterval = time.Second
+
+// Config is the config used by New.
+type Config struct {
+	// PollInterval is the interval to poll the paths watched by polling.
+	//
+	// Optional, <=0 means DefaultPollInterval.
+	PollInterval time.Duration
+
+	// ForcePolling controls whether to always poll the paths instead of using
+	// fsnotify, for the file systems not supporting inotify but not detected
+	// automatically (e.g. some container overlays).
+	ForcePolling bool
+}
+
+// Watcher watches the paths added and reports their events.
+//
+// Like fsnotify, when the path added is a directory, the events of its direct
+// children are reported.
+type Watcher interface {
+	// Add starts watching the path.
+	Add(path string) error
+
+	// Events returns the channel of the events of the watched paths.
+	Events() <-chan fsnotify.Event
+
+	// Errors returns the channel of the errors of the underlying watchers.
+	Errors() <-chan error
+
+	// Close stops watching all the paths.
+	//
+	// It's OK to call Close multiple times.
+	// Calls after the first one are no-op and return nil.
+	Close() error
+}
+
+// watcher implements Watcher by fsnotify, and falls back to poller for the
+// paths not supported by fsnotify.
+type watcher struct {
+	cfg Config
+
+	events chan fsnotify.Event
+	errors chan error
+	done   chan struct{}
+	wg     sync.WaitGroup
+
+	lock   sync.Mutex
+	notify *fsnotify.Watcher // nil if fsnotify is not available
+	poll   *poller           // created on the first path to be polled
+	closed bool
+}
+
+// New creates a Watcher.
+//
+// When fsnotify is not available (e.g. inotify instances limit reached),
+// all the paths are polled.
+func New(cfg Config) Watcher {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultPollInterval
+	}
+	w := &watcher{
+		cfg:    cfg,
+		events: make(chan fsnotify.Event),
+		errors: make(chan error),
+		done:   make(chan struct{}),
+	}
+	if !cfg.ForcePolling {
+		if notify, err := fsnotify.NewWatcher(); err == nil {
+			w.notify = notify
+			w.wg.Add(1)
+			go w.forward()
+		}
+	}
+	return w
+}
+
+func (w *watcher) Add(path string) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return errClosed
+	}
+	if w.notify != nil && !unsupportedFileSystem(path) {
+		if err := w.notify.Add(path); err == nil {
+			return nil
+		}
+		// Fall back to polling, e.g. when the inotify watches limit is reached.
+	}
+	if w.poll == nil {
+		w.poll = newPoller(w.cfg.PollInterval, w.events, w.errors, w.done)
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			w.poll.loop()
+		}()
+	}
+	return w.poll.add(path)
+}
+
+func (w *watcher) Events() <-chan fsnotify.Event {
+	return w.events
+}
+
+func (w *watcher) Errors() <-chan error {
+	return w.errors
+}
+
+func (w *watcher) Close() error {
+	w.lock.Lock()
+	if w.closed {
+		w.lock.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.done)
+	w.lock.Unlock()
+
+	var err error
+	if w.notify != nil {
+		err = w.notify.Close()
+	}
+	w.wg.Wait()
+	return err
+}
+
+// forward forwards the events and errors of fsnotify.
+func (w *watcher) forward() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.done:
+			return
+		case ev, ok := <-w.notify.Events:
+			if !ok {
+				return
+			}
+			select {
+			case w.events <- ev:
+			case <-w.done:
+				return
+			}
+		case err, ok := <-w.notify.Errors:
+			if !ok {
+				return
+			}
+			select {
+			case w.errors <- err:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
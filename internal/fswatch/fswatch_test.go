@@ -0,0 +1,108 @@
+package fswatch_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/fsnotify.v1"
+
+	"github.com/reddit/baseplate.go/internal/fswatch"
+)
+
+func TestPolling(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing")
+	if err := os.WriteFile(existing, []byte("existing"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := fswatch.New(fswatch.Config{
+		PollInterval: time.Millisecond * 10,
+		ForcePolling: true,
+	})
+	defer w.Close()
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	expectEvent := func(t *testing.T, name string, op fsnotify.Op) {
+		t.Helper()
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case ev := <-w.Events():
+				if ev.Name == name && ev.Op == op {
+					return
+				}
+			case err := <-w.Errors():
+				t.Fatal(err)
+			case <-timeout:
+				t.Fatalf("Expected event %v of %q", op, name)
+			}
+		}
+	}
+
+	foo := filepath.Join(dir, "foo")
+	if err := os.WriteFile(foo, []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, foo, fsnotify.Create)
+
+	if err := os.WriteFile(existing, []byte("modified"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, existing, fsnotify.Write)
+
+	if err := os.Chmod(existing, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, existing, fsnotify.Chmod)
+
+	tmp := filepath.Join(dir, "tmp")
+	if err := os.WriteFile(tmp, []byte("replaced"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, foo); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, foo, fsnotify.Create)
+
+	if err := os.Remove(foo); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, foo, fsnotify.Remove)
+
+	if err := w.Close(); err != nil {
+		t.Errorf("Close returned %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("Second Close returned %v", err)
+	}
+	if err := w.Add(dir); err == nil {
+		t.Error("Expected Add to fail after Close")
+	}
+}
+
+func TestNotify(t *testing.T) {
+	dir := t.TempDir()
+	w := fswatch.New(fswatch.Config{})
+	defer w.Close()
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	foo := filepath.Join(dir, "foo")
+	if err := os.WriteFile(foo, []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-w.Events():
+		if ev.Name != foo {
+			t.Errorf("Expected event of %q, got %v", foo, ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("No event received")
+	}
+}
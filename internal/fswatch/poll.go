@@ -0,0 +1,211 @@
+package fswatch
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/fsnotify.v1"
+)
+
+var errClosed = errors.New("fswatch: watcher closed")
+
+// fileState is the state of a file compared by poller.
+type fileState struct {
+	info   fs.FileInfo // from os.Lstat
+	target fs.FileInfo // from os.Stat, nil if it's not a symlink or broken
+}
+
+// change returns the op of the change from prev to s like fsnotify,
+// or 0 if there's no change:
+// Create if the file is replaced, Write if its content changed,
+// and Chmod if only its mode changed.
+func (s fileState) change(prev fileState) fsnotify.Op {
+	if !os.SameFile(s.info, prev.info) {
+		return fsnotify.Create
+	}
+	if s.info.ModTime() != prev.info.ModTime() || s.info.Size() != prev.info.Size() {
+		return fsnotify.Write
+	}
+	if (s.target == nil) != (prev.target == nil) {
+		return fsnotify.Write
+	}
+	if s.target != nil && (!os.SameFile(s.target, prev.target) || s.target.ModTime() != prev.target.ModTime() || s.target.Size() != prev.target.Size()) {
+		return fsnotify.Write
+	}
+	if s.info.Mode() != prev.info.Mode() {
+		return fsnotify.Chmod
+	}
+	return 0
+}
+
+// poller watches the paths by comparing their modification times and sizes
+// periodically.
+type poller struct {
+	interval time.Duration
+	events   chan<- fsnotify.Event
+	errors   chan<- error
+	done     <-chan struct{}
+
+	lock  sync.Mutex
+	paths map[string]map[string]fileState // watched path -> file -> state
+}
+
+func newPoller(interval time.Duration, events chan<- fsnotify.Event, errors chan<- error, done <-chan struct{}) *poller {
+	return &poller{
+		interval: interval,
+		events:   events,
+		errors:   errors,
+		done:     done,
+		paths:    make(map[string]map[string]fileState),
+	}
+}
+
+func (p *poller) add(path string) error {
+	snapshot, err := p.snapshot(path)
+	if err != nil {
+		return err
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.paths[path] = snapshot
+	return nil
+}
+
+// snapshot returns the states of path, or its children if it's a directory.
+func (p *poller) snapshot(path string) (map[string]fileState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make(map[string]fileState)
+	if !info.IsDir() {
+		if state, ok := stat(path); ok {
+			snapshot[path] = state
+		}
+		return snapshot, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := filepath.Join(path, entry.Name())
+		if state, ok := stat(name); ok {
+			snapshot[name] = state
+		}
+	}
+	return snapshot, nil
+}
+
+// stat returns the state of the file, or false if it no longer exists.
+func stat(path string) (fileState, bool) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fileState{}, false
+	}
+	state := fileState{info: info}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		state.target, _ = os.Stat(path)
+	}
+	return state, true
+}
+
+func (p *poller) loop() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			if !p.poll() {
+				return
+			}
+		}
+	}
+}
+
+// poll compares the states of all the paths with their previous ones and
+// reports the events, returns false when the poller is closed.
+func (p *poller) poll() bool {
+	p.lock.Lock()
+	paths := make([]string, 0, len(p.paths))
+	for path := range p.paths {
+		paths = append(paths, path)
+	}
+	p.lock.Unlock()
+
+	for _, path := range paths {
+		snapshot, err := p.snapshot(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// The watched path itself is removed, stop watching it like
+				// fsnotify.
+				p.lock.Lock()
+				prev := p.paths[path]
+				delete(p.paths, path)
+				p.lock.Unlock()
+				for name := range prev {
+					if !p.send(fsnotify.Event{Name: name, Op: fsnotify.Remove}) {
+						return false
+					}
+				}
+				continue
+			}
+			if !p.sendError(err) {
+				return false
+			}
+			continue
+		}
+
+		p.lock.Lock()
+		prev := p.paths[path]
+		p.paths[path] = snapshot
+		p.lock.Unlock()
+
+		for name, state := range snapshot {
+			prevState, ok := prev[name]
+			if !ok {
+				if !p.send(fsnotify.Event{Name: name, Op: fsnotify.Create}) {
+					return false
+				}
+				continue
+			}
+			if op := state.change(prevState); op != 0 {
+				if !p.send(fsnotify.Event{Name: name, Op: op}) {
+					return false
+				}
+			}
+		}
+		for name := range prev {
+			if _, ok := snapshot[name]; !ok {
+				if !p.send(fsnotify.Event{Name: name, Op: fsnotify.Remove}) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func (p *poller) send(ev fsnotify.Event) bool {
+	select {
+	case p.events <- ev:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+func (p *poller) sendError(err error) bool {
+	select {
+	case p.errors <- err:
+		return true
+	case <-p.done:
+		return false
+	}
+}
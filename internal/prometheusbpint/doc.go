@@ -0,0 +1,3 @@
+// Package prometheusbpint provides the prometheus helpers shared by the
+// baseplate packages.
+package prometheusbpint
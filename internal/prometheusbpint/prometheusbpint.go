@@ -0,0 +1,17 @@
+package prometheusbpint
+
+// BoolString returns the label value of b, "true" or "false".
+func BoolString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// BoolFloat returns the gauge value of b, 1 or 0.
+func BoolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -3,9 +3,11 @@ package kafkabp
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Shopify/sarama"
 
+	"github.com/reddit/baseplate.go/healthbp"
 	"github.com/reddit/baseplate.go/log"
 )
 
@@ -27,6 +29,49 @@ var (
 
 	// ErrOffsetInvalid is thrown when an invalid offset is specified.
 	ErrOffsetInvalid = errors.New("kafkabp: Offset is invalid")
+
+	// ErrCompressionInvalid is thrown when an invalid compression is specified.
+	ErrCompressionInvalid = errors.New("kafkabp: Compression is invalid")
+
+	// ErrRequiredAcksInvalid is thrown when an invalid required acks is
+	// specified.
+	ErrRequiredAcksInvalid = errors.New("kafkabp: RequiredAcks is invalid")
+
+	// ErrCommitStrategyInvalid is thrown when an invalid commit strategy is
+	// specified.
+	ErrCommitStrategyInvalid = errors.New("kafkabp: CommitStrategy is invalid")
+)
+
+// Allowed CommitStrategy values
+const (
+	// CommitInterval commits the offsets of the processed messages
+	// periodically in background, every CommitInterval.
+	CommitInterval = "interval"
+
+	// CommitSync commits the offset of every message after it's processed,
+	// before processing the next message from the same partition.
+	CommitSync = "sync"
+
+	// CommitAsync triggers a commit in background after every message is
+	// processed, without waiting for it to finish.
+	// Concurrent triggers are coalesced into one commit.
+	CommitAsync = "async"
+)
+
+// Allowed Compression values
+const (
+	CompressionNone   = "none"
+	CompressionGZIP   = "gzip"
+	CompressionSnappy = "snappy"
+	CompressionLZ4    = "lz4"
+	CompressionZSTD   = "zstd"
+)
+
+// Allowed RequiredAcks values
+const (
+	RequiredAcksNone  = "none"
+	RequiredAcksLocal = "local"
+	RequiredAcksAll   = "all"
 )
 
 // ConsumerConfig can be used to configure a kafkabp Consumer.
@@ -99,6 +144,77 @@ type ConsumerConfig struct {
 	// or it might make things worse.
 	// You are advised to test before using non-empty rack id in production.
 	RackID RackIDFunc `yaml:"rackID"`
+
+	// Optional. Defaults to "interval". Valid values are "interval", "sync" and
+	// "async".
+	//
+	// Only used when GroupID is non-empty.
+	//
+	// With all the strategies, the offset of a message is only marked to be
+	// committed after the ConsumeMessageFunc returns,
+	// and all the marked offsets are committed when the partitions are revoked
+	// on rebalances, so no messages are lost as long as they are processed
+	// synchronously in the ConsumeMessageFunc (at-least-once).
+	CommitStrategy string `yaml:"commitStrategy"`
+
+	// Optional. The interval to commit the offsets with "interval"
+	// CommitStrategy. Defaults to 1s.
+	CommitInterval time.Duration `yaml:"commitInterval"`
+
+	// Optional. The callbacks to be notified on rebalances.
+	//
+	// Only used when GroupID is non-empty.
+	RebalanceListener RebalanceListener `yaml:"-"`
+
+	// Optional. The signal reported ready once the consumer starts consuming
+	// (with GroupID, once the partitions are assigned),
+	// usually required from the StartupGate of the Baseplate,
+	// so the service only takes traffic after the consumer started.
+	StartupSignal *healthbp.StartupSignal `yaml:"-"`
+
+	// Optional. The criticality of the checker of the consumer registered into
+	// healthbp.DefaultRegistry, named HealthCheckerPrefix + Topic,
+	// which reports healthbp.ErrUnhealthy after Consume returns.
+	//
+	// Defaults to healthbp.CriticalityNone, which only reports the health of
+	// the consumer without failing any probes.
+	// The checker is removed when the consumer is closed.
+	HealthCriticality healthbp.Criticality `yaml:"healthCriticality"`
+
+	// Optional. The max number of partitions processing messages concurrently.
+	//
+	// The messages from each partition are always processed in order by a
+	// dedicated goroutine, and the messages from different partitions are
+	// processed concurrently.
+	// Defaults to 0, which means all the assigned partitions are processed
+	// concurrently.
+	MaxConcurrency int `yaml:"maxConcurrency"`
+
+	// Optional. The number of the fetched messages buffered for each partition
+	// waiting to be processed.
+	//
+	// When the buffer of a partition is full,
+	// fetching from the partition is paused until the processing catches up
+	// (backpressure).
+	// Defaults to 256.
+	PartitionBufferSize int `yaml:"partitionBufferSize"`
+
+	// Optional. The interval to query the offsets of the assigned partitions
+	// and report the consumer lag as Prometheus metrics:
+	//
+	// - kafka_consumer_lag: the number of messages not yet committed (with
+	// GroupID) or processed (without GroupID).
+	//
+	// - kafka_consumer_last_commit_age_seconds: the time since the committed
+	// offset was last seen changing (only with GroupID). It starts from 0 when
+	// the consumer starts, and is only meaningful with a non-zero lag.
+	//
+	// Defaults to 0, which disables the lag reporting.
+	LagReportInterval time.Duration `yaml:"lagReportInterval"`
+
+	// Optional. The SASL credentials and TLS material to be read from the
+	// secrets Store, they are only used by the *WithSecrets functions.
+	Secrets SecretsConfig `yaml:"secrets"`
 }
 
 // Since not all sarama's default config are zero values,
@@ -145,6 +261,10 @@ func (cfg *ConsumerConfig) NewSaramaConfig() (*sarama.Config, error) {
 		c.RackID = cfg.RackID()
 	}
 
+	if cfg.PartitionBufferSize > 0 {
+		c.ChannelBufferSize = cfg.PartitionBufferSize
+	}
+
 	if cfg.GroupID == "" {
 		var offset int64
 		switch cfg.Offset {
@@ -158,7 +278,172 @@ func (cfg *ConsumerConfig) NewSaramaConfig() (*sarama.Config, error) {
 			return nil, ErrOffsetInvalid
 		}
 		c.Consumer.Offsets.Initial = offset
+	} else {
+		switch cfg.CommitStrategy {
+		case "", CommitInterval:
+			c.Consumer.Offsets.AutoCommit.Enable = true
+			if cfg.CommitInterval > 0 {
+				c.Consumer.Offsets.AutoCommit.Interval = cfg.CommitInterval
+			}
+		case CommitSync, CommitAsync:
+			// The commits are handled by GroupConsumerHandler.
+			c.Consumer.Offsets.AutoCommit.Enable = false
+		default:
+			return nil, ErrCommitStrategyInvalid
+		}
+	}
+
+	return c, nil
+}
+
+// ProducerConfig can be used to configure a kafkabp Producer.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//     kafka:
+//       brokers:
+//         - 127.0.0.1:9090
+//         - 127.0.0.2:9090
+//       clientID: myclient
+//       version: 2.4.0
+//       batchSize: 100
+//       batchBytes: 1048576
+//       linger: 10ms
+//       compression: zstd
+//       requiredAcks: all
+type ProducerConfig struct {
+	// Required. Brokers specifies a slice of broker addresses.
+	Brokers []string `yaml:"brokers"`
+
+	// Required. ClientID is used by Kafka broker to identify the clients.
+	ClientID string `yaml:"clientID"`
+
+	// Optional. The version of the kafka broker this producer is connected to.
+	// In format of "0.10.2.0" or "2.4.0".
+	//
+	// When omitted, Sarama library would pick the oldest supported version in
+	// order to maintain maximum backward compatibility, but some of the newer
+	// features might be unavailable. For example, using "zstd" compression
+	// requires the version to be at least "2.1.0".
+	Version string `yaml:"version"`
+
+	// Optional. The number of messages to trigger a flush of the batch.
+	//
+	// Defaults to 0, which means the batches are only bounded by BatchBytes and
+	// Linger.
+	BatchSize int `yaml:"batchSize"`
+
+	// Optional. The total size of messages in bytes to trigger a flush of the
+	// batch.
+	//
+	// Defaults to 0, which means the batches are only bounded by BatchSize,
+	// Linger and the max request size.
+	BatchBytes int `yaml:"batchBytes"`
+
+	// Optional. The max time to wait for more messages before flushing the
+	// batch.
+	//
+	// Defaults to 0, which means the messages are sent as soon as possible.
+	Linger time.Duration `yaml:"linger"`
+
+	// Optional. Defaults to "none". Valid values are "none", "gzip", "snappy",
+	// "lz4" and "zstd".
+	Compression string `yaml:"compression"`
+
+	// Optional. Defaults to "local". Valid values are:
+	//
+	// - "none": don't wait for any response from the broker.
+	//
+	// - "local": wait for only the leader to commit the messages.
+	//
+	// - "all": wait for all in-sync replicas to commit the messages.
+	RequiredAcks string `yaml:"requiredAcks"`
+
+	// Optional. If non-nil, will be used to log the failed deliveries of the
+	// messages produced without a DeliveryReportFunc.
+	Logger log.Wrapper `yaml:"logger"`
+
+	// Optional. The function to set rack id for this kafka client.
+	//
+	// See ConsumerConfig.RackID for more details.
+	RackID RackIDFunc `yaml:"rackID"`
+
+	// Optional. The SASL credentials and TLS material to be read from the
+	// secrets Store, they are only used by the *WithSecrets functions.
+	Secrets SecretsConfig `yaml:"secrets"`
+}
+
+// NewSaramaConfig instantiates a sarama.Config with sane producer defaults
+// from sarama.NewConfig(), overwritten by values parsed from cfg.
+func (cfg *ProducerConfig) NewSaramaConfig() (*sarama.Config, error) {
+	// Validate input parameters.
+	if len(cfg.Brokers) == 0 {
+		return nil, ErrBrokersEmpty
 	}
 
+	if cfg.ClientID == "" {
+		return nil, ErrClientIDEmpty
+	}
+
+	version := defaultSaramaConfig.Version
+	if cfg.Version != "" {
+		var err error
+		version, err = sarama.ParseKafkaVersion(cfg.Version)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"kafkabp: ParseKafkaVersion error: %w",
+				err,
+			)
+		}
+	}
+
+	c := sarama.NewConfig()
+
+	// Both are needed to report the deliveries.
+	c.Producer.Return.Successes = true
+	c.Producer.Return.Errors = true
+
+	c.ClientID = cfg.ClientID
+	c.Version = version
+
+	if cfg.RackID != nil {
+		c.RackID = cfg.RackID()
+	}
+
+	c.Producer.Flush.Messages = cfg.BatchSize
+	c.Producer.Flush.Bytes = cfg.BatchBytes
+	c.Producer.Flush.Frequency = cfg.Linger
+
+	switch cfg.Compression {
+	case "", CompressionNone:
+		c.Producer.Compression = sarama.CompressionNone
+	case CompressionGZIP:
+		c.Producer.Compression = sarama.CompressionGZIP
+	case CompressionSnappy:
+		c.Producer.Compression = sarama.CompressionSnappy
+	case CompressionLZ4:
+		c.Producer.Compression = sarama.CompressionLZ4
+	case CompressionZSTD:
+		c.Producer.Compression = sarama.CompressionZSTD
+	default:
+		return nil, ErrCompressionInvalid
+	}
+
+	switch cfg.RequiredAcks {
+	case "", RequiredAcksLocal:
+		c.Producer.RequiredAcks = sarama.WaitForLocal
+	case RequiredAcksNone:
+		c.Producer.RequiredAcks = sarama.NoResponse
+	case RequiredAcksAll:
+		c.Producer.RequiredAcks = sarama.WaitForAll
+	default:
+		return nil, ErrRequiredAcksInvalid
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("kafkabp: invalid producer config: %w", err)
+	}
 	return c, nil
 }
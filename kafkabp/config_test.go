@@ -3,6 +3,9 @@ package kafkabp_test
 import (
 	"errors"
 	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
 
 	"github.com/reddit/baseplate.go/kafkabp"
 )
@@ -108,4 +111,155 @@ func TestConsumerConfig(t *testing.T) {
 			t.Errorf("expected sarama rack id to be empty, got %q", sc.ClientID)
 		}
 	})
+	t.Run("partition-buffer-size", func(t *testing.T) {
+		sc, err := cfg.NewSaramaConfig()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if sc.ChannelBufferSize != 256 {
+			t.Errorf("expected default channel buffer size 256, got %d", sc.ChannelBufferSize)
+		}
+
+		cfg.PartitionBufferSize = 10
+		sc, err = cfg.NewSaramaConfig()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if sc.ChannelBufferSize != 10 {
+			t.Errorf("expected channel buffer size 10, got %d", sc.ChannelBufferSize)
+		}
+	})
+}
+
+func TestProducerConfig(t *testing.T) {
+	var cfg kafkabp.ProducerConfig
+
+	t.Run("no-brokers", func(t *testing.T) {
+		sc, err := cfg.NewSaramaConfig()
+		if sc != nil {
+			t.Errorf("expected config to be nil, got %v", sc)
+		}
+		if !errors.Is(err, kafkabp.ErrBrokersEmpty) {
+			t.Errorf("expected error %v, got %v", kafkabp.ErrBrokersEmpty, err)
+		}
+	})
+	cfg.Brokers = []string{"127.0.0.1:9090", "127.0.0.2:9090"}
+
+	t.Run("no-client-id", func(t *testing.T) {
+		sc, err := cfg.NewSaramaConfig()
+		if sc != nil {
+			t.Errorf("expected config to be nil, got %v", sc)
+		}
+		if !errors.Is(err, kafkabp.ErrClientIDEmpty) {
+			t.Errorf("expected error %v, got %v", kafkabp.ErrClientIDEmpty, err)
+		}
+	})
+	cfg.ClientID = "i-am-unique"
+
+	t.Run("invalid-compression", func(t *testing.T) {
+		cfg.Compression = "fanciest"
+		sc, err := cfg.NewSaramaConfig()
+		if sc != nil {
+			t.Errorf("expected config to be nil, got %v", sc)
+		}
+		if !errors.Is(err, kafkabp.ErrCompressionInvalid) {
+			t.Errorf("expected error %v, got %v", kafkabp.ErrCompressionInvalid, err)
+		}
+	})
+	cfg.Compression = kafkabp.CompressionZSTD
+
+	t.Run("invalid-required-acks", func(t *testing.T) {
+		cfg.RequiredAcks = "some"
+		sc, err := cfg.NewSaramaConfig()
+		if sc != nil {
+			t.Errorf("expected config to be nil, got %v", sc)
+		}
+		if !errors.Is(err, kafkabp.ErrRequiredAcksInvalid) {
+			t.Errorf("expected error %v, got %v", kafkabp.ErrRequiredAcksInvalid, err)
+		}
+	})
+	cfg.RequiredAcks = kafkabp.RequiredAcksAll
+
+	t.Run("version-too-old-for-zstd", func(t *testing.T) {
+		cfg.Version = "0.10.2.0"
+		sc, err := cfg.NewSaramaConfig()
+		if sc != nil {
+			t.Errorf("expected config to be nil, got %v", sc)
+		}
+		if err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+	cfg.Version = "2.5.0"
+
+	cfg.BatchSize = 100
+	cfg.BatchBytes = 1 << 20
+	cfg.Linger = time.Millisecond * 10
+	t.Run("valid-config", func(t *testing.T) {
+		sc, err := cfg.NewSaramaConfig()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if sc.ClientID != cfg.ClientID {
+			t.Errorf("expected sarama client id to be %q, got %q", cfg.ClientID, sc.ClientID)
+		}
+		if sc.Producer.Compression != sarama.CompressionZSTD {
+			t.Errorf("expected zstd compression, got %v", sc.Producer.Compression)
+		}
+		if sc.Producer.RequiredAcks != sarama.WaitForAll {
+			t.Errorf("expected required acks %v, got %v", sarama.WaitForAll, sc.Producer.RequiredAcks)
+		}
+		if sc.Producer.Flush.Messages != cfg.BatchSize {
+			t.Errorf("expected flush messages %d, got %d", cfg.BatchSize, sc.Producer.Flush.Messages)
+		}
+		if sc.Producer.Flush.Bytes != cfg.BatchBytes {
+			t.Errorf("expected flush bytes %d, got %d", cfg.BatchBytes, sc.Producer.Flush.Bytes)
+		}
+		if sc.Producer.Flush.Frequency != cfg.Linger {
+			t.Errorf("expected flush frequency %v, got %v", cfg.Linger, sc.Producer.Flush.Frequency)
+		}
+		if !sc.Producer.Return.Successes || !sc.Producer.Return.Errors {
+			t.Error("expected both successes and errors to be returned")
+		}
+	})
+}
+
+func TestConsumerConfigCommitStrategy(t *testing.T) {
+	cfg := kafkabp.ConsumerConfig{
+		Brokers:  []string{"127.0.0.1:9090"},
+		Topic:    "test-topic",
+		ClientID: "i-am-unique",
+		GroupID:  "group",
+	}
+
+	for _, c := range []struct {
+		strategy   string
+		interval   time.Duration
+		autoCommit bool
+		err        error
+	}{
+		{strategy: "", autoCommit: true},
+		{strategy: kafkabp.CommitInterval, interval: time.Second * 5, autoCommit: true},
+		{strategy: kafkabp.CommitSync},
+		{strategy: kafkabp.CommitAsync},
+		{strategy: "sometimes", err: kafkabp.ErrCommitStrategyInvalid},
+	} {
+		t.Run(c.strategy, func(t *testing.T) {
+			cfg.CommitStrategy = c.strategy
+			cfg.CommitInterval = c.interval
+			sc, err := cfg.NewSaramaConfig()
+			if !errors.Is(err, c.err) {
+				t.Fatalf("expected error %v, got %v", c.err, err)
+			}
+			if c.err != nil {
+				return
+			}
+			if sc.Consumer.Offsets.AutoCommit.Enable != c.autoCommit {
+				t.Errorf("expected auto commit %v, got %v", c.autoCommit, sc.Consumer.Offsets.AutoCommit.Enable)
+			}
+			if c.interval > 0 && sc.Consumer.Offsets.AutoCommit.Interval != c.interval {
+				t.Errorf("expected auto commit interval %v, got %v", c.interval, sc.Consumer.Offsets.AutoCommit.Interval)
+			}
+		})
+	}
 }
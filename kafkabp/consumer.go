@@ -8,6 +8,7 @@ import (
 
 	"github.com/Shopify/sarama"
 
+	"github.com/reddit/baseplate.go/healthbp"
 	"github.com/reddit/baseplate.go/metricsbp"
 	"github.com/reddit/baseplate.go/tracing"
 )
@@ -52,6 +53,20 @@ type Consumer interface {
 
 	// IsHealthy returns false after Consume returns.
 	IsHealthy(ctx context.Context) bool
+
+	// Pause stops calling the ConsumeMessageFunc for new messages until Resume
+	// is called.
+	//
+	// The messages already passed into the ConsumeMessageFunc are not
+	// affected. The consumer stays in the group while paused, and the messages
+	// fetched in the meantime are buffered until the buffer is full.
+	Pause()
+
+	// Resume resumes calling the ConsumeMessageFunc after Pause.
+	Resume()
+
+	// IsPaused returns true after Pause is called and before Resume is called.
+	IsPaused() bool
 }
 
 // consumer implements a Kafka consumer.
@@ -67,6 +82,10 @@ type consumer struct {
 	consumeReturned int64
 	offset          int64
 
+	pauser  pauser
+	limiter partitionLimiter
+	lag     *lagMonitor
+
 	wg sync.WaitGroup
 }
 
@@ -88,7 +107,45 @@ func NewConsumer(cfg ConsumerConfig) (Consumer, error) {
 	if err != nil {
 		return nil, err
 	}
+	c, err := newConsumer(cfg, sc)
+	if err != nil {
+		return nil, err
+	}
+	return withHealthChecker(cfg, c), nil
+}
+
+// HealthCheckerPrefix is the prefix of the names of the checkers registered
+// into healthbp.DefaultRegistry by the consumers,
+// see ConsumerConfig.HealthCriticality.
+const HealthCheckerPrefix = "kafka-consumer:"
+
+// healthConsumer is a Consumer with its checker registered into
+// healthbp.DefaultRegistry, removed on Close.
+type healthConsumer struct {
+	Consumer
+
+	removeChecker func()
+}
+
+func withHealthChecker(cfg ConsumerConfig, c Consumer) Consumer {
+	return &healthConsumer{
+		Consumer: c,
+		removeChecker: healthbp.Add(
+			HealthCheckerPrefix+cfg.Topic,
+			healthbp.FromHealthChecker(c),
+			healthbp.CheckerOptions{
+				Criticality: cfg.HealthCriticality,
+			},
+		),
+	}
+}
+
+func (c *healthConsumer) Close() error {
+	c.removeChecker()
+	return c.Consumer.Close()
+}
 
+func newConsumer(cfg ConsumerConfig, sc *sarama.Config) (Consumer, error) {
 	switch {
 	default:
 		return newTopicConsumer(cfg, sc)
@@ -99,9 +156,10 @@ func NewConsumer(cfg ConsumerConfig) (Consumer, error) {
 
 func newTopicConsumer(cfg ConsumerConfig, sc *sarama.Config) (Consumer, error) {
 	kc := &consumer{
-		cfg:    cfg,
-		sc:     sc,
-		offset: sc.Consumer.Offsets.Initial,
+		cfg:     cfg,
+		sc:      sc,
+		offset:  sc.Consumer.Offsets.Initial,
+		limiter: newPartitionLimiter(cfg.MaxConcurrency),
 	}
 
 	// Initialize Sarama consumer and set atomic values.
@@ -109,6 +167,13 @@ func newTopicConsumer(cfg ConsumerConfig, sc *sarama.Config) (Consumer, error) {
 		return nil, err
 	}
 
+	lag, err := newConsumerLagMonitor(cfg, sc)
+	if err != nil {
+		kc.getConsumer().Close()
+		return nil, err
+	}
+	kc.lag = lag
+
 	return kc, nil
 }
 
@@ -171,6 +236,8 @@ func (kc *consumer) Close() error {
 	if !atomic.CompareAndSwapInt64(&kc.closed, 0, 1) {
 		return nil
 	}
+	// Unblock the paused consuming so that the messages can be drained.
+	kc.pauser.stop()
 
 	partitionConsumers := kc.getPartitionConsumers()
 	for _, pc := range partitionConsumers {
@@ -179,6 +246,12 @@ func (kc *consumer) Close() error {
 	}
 	// wait for the Consume function to return
 	kc.wg.Wait()
+	if err := kc.lag.close(); err != nil {
+		kc.cfg.Logger.Log(
+			context.Background(),
+			"kafkabp.consumer.Close: Error closing the lag monitor: "+err.Error(),
+		)
+	}
 	return kc.getConsumer().Close()
 }
 
@@ -202,6 +275,7 @@ func (kc *consumer) Consume(
 		// create a partition consumer for each partition
 		consumer := kc.getConsumer()
 		partitions := kc.getPartitions()
+		kc.lag.setPartitions(partitions)
 		partitionConsumers := make([]sarama.PartitionConsumer, 0, len(partitions))
 
 		for _, p := range partitions {
@@ -213,25 +287,23 @@ func (kc *consumer) Consume(
 
 			// consume partition consumer messages
 			wg.Add(1)
-			go func(pc sarama.PartitionConsumer) {
+			go func(p int32, pc sarama.PartitionConsumer) {
 				defer wg.Done()
-				for m := range pc.Messages() {
-					// Wrap in anonymous function for easier defer.
-					func() {
-						ctx := context.Background()
-						var span *tracing.Span
-						spanName := "consumer." + kc.cfg.Topic
-						ctx, span = tracing.StartTopLevelServerSpan(ctx, spanName)
-						defer func() {
-							span.FinishWithOptions(tracing.FinishOptions{
-								Ctx: ctx,
-							}.Convert())
-						}()
-
-						messagesFunc(ctx, m)
+				kc.limiter.processPartition(kc.cfg.Topic, p, pc.Messages(), nil, &kc.pauser, func(m *sarama.ConsumerMessage) {
+					ctx := context.Background()
+					var span *tracing.Span
+					spanName := "consumer." + kc.cfg.Topic
+					ctx, span = startConsumerSpan(ctx, spanName, m)
+					defer func() {
+						span.FinishWithOptions(tracing.FinishOptions{
+							Ctx: ctx,
+						}.Convert())
 					}()
-				}
-			}(partitionConsumer)
+
+					messagesFunc(ctx, m)
+					kc.lag.markProcessed(p, m.Offset)
+				})
+			}(p, partitionConsumer)
 
 			// consume partition consumer errors
 			wg.Add(1)
@@ -243,6 +315,7 @@ func (kc *consumer) Consume(
 			}(partitionConsumer)
 		}
 		kc.partitionConsumers.Store(partitionConsumers)
+		kc.cfg.StartupSignal.Ready()
 
 		wg.Wait()
 
@@ -265,3 +338,18 @@ func (kc *consumer) Consume(
 func (kc *consumer) IsHealthy(_ context.Context) bool {
 	return atomic.LoadInt64(&kc.consumeReturned) == 0
 }
+
+// Pause implements Consumer.
+func (kc *consumer) Pause() {
+	kc.pauser.pause()
+}
+
+// Resume implements Consumer.
+func (kc *consumer) Resume() {
+	kc.pauser.resume()
+}
+
+// IsPaused implements Consumer.
+func (kc *consumer) IsPaused() bool {
+	return kc.pauser.paused()
+}
@@ -0,0 +1,221 @@
+package kafkabp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Shopify/sarama"
+	opentracing "github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/internal/prometheusbpint"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/transport"
+)
+
+// ConsumerMiddleware wraps the ProcessMessageFunc of the messages of a topic,
+// like thrift.ProcessorMiddleware for thrift endpoints.
+type ConsumerMiddleware func(topic string, next ProcessMessageFunc) ProcessMessageFunc
+
+// WrapProcessMessageFunc wraps process with the given middlewares.
+//
+// Middlewares will be called in the order that they are defined:
+//
+//	1. middlewares[0]
+//	2. middlewares[1]
+//	...
+//	N. middlewares[n]
+//
+// The returned ProcessMessageFunc can be turned into a ConsumeMessageFunc by
+// WithDeadLetterQueue or LogProcessErrors.
+func WrapProcessMessageFunc(topic string, process ProcessMessageFunc, middlewares ...ConsumerMiddleware) ProcessMessageFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		process = middlewares[i](topic, process)
+	}
+	return process
+}
+
+// DefaultConsumerMiddlewaresArgs are the args to be passed into
+// DefaultConsumerMiddlewares function.
+type DefaultConsumerMiddlewaresArgs struct {
+	// The edge context implementation. Optional.
+	//
+	// If it's not set, the global one from ecinterface.Get will be used instead.
+	EdgeContextImpl ecinterface.Interface
+}
+
+// DefaultConsumerMiddlewares returns the default consumer middlewares that
+// should be used by a baseplate kafka consumer.
+//
+// The middlewares returned are:
+//
+// 1. ConsumerTracing
+//
+// 2. ConsumerEdgeContext
+//
+// 3. ConsumerPrometheus
+//
+// 4. ConsumerRecoverPanic
+func DefaultConsumerMiddlewares(args DefaultConsumerMiddlewaresArgs) []ConsumerMiddleware {
+	return []ConsumerMiddleware{
+		ConsumerTracing,
+		ConsumerEdgeContext(args.EdgeContextImpl),
+		ConsumerPrometheus,
+		ConsumerRecoverPanic,
+	}
+}
+
+// LogProcessErrors turns process into a ConsumeMessageFunc that logs the
+// errors returned by process via logger.
+//
+// Use WithDeadLetterQueue instead if the failed messages should be retried and
+// published to a dead-letter topic.
+func LogProcessErrors(logger log.Wrapper, process ProcessMessageFunc) ConsumeMessageFunc {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage) {
+		if err := process(ctx, msg); err != nil {
+			logger.Log(ctx, fmt.Sprintf(
+				"kafkabp: failed to process message of topic %q partition %d offset %d: %v",
+				msg.Topic,
+				msg.Partition,
+				msg.Offset,
+				err,
+			))
+		}
+	}
+}
+
+// ConsumerTracing is a ConsumerMiddleware that wraps the processing of each
+// message in a span.
+//
+// When there's no span in ctx, it starts a server span continuing the trace of
+// the producer span, from the tracing headers of the message set by Producer.
+// Otherwise (e.g. under the server span already started by Consumer.Consume
+// for each message), it starts a local child span of the span in ctx.
+//
+// The span is finished with the error returned by next.
+func ConsumerTracing(topic string, next ProcessMessageFunc) ProcessMessageFunc {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage) (err error) {
+		var span *tracing.Span
+		if opentracing.SpanFromContext(ctx) == nil {
+			ctx, span = startConsumerSpan(ctx, "consumer."+topic, msg)
+		} else {
+			var otSpan opentracing.Span
+			otSpan, ctx = opentracing.StartSpanFromContext(
+				ctx,
+				"process."+topic,
+				tracing.SpanTypeOption{Type: tracing.SpanTypeLocal},
+			)
+			span = tracing.AsSpan(otSpan)
+		}
+		defer func() {
+			span.FinishWithOptions(tracing.FinishOptions{
+				Ctx: ctx,
+				Err: err,
+			}.Convert())
+		}()
+		return next(ctx, msg)
+	}
+}
+
+// ConsumerEdgeContext returns a ConsumerMiddleware that injects the edge
+// request context from the transport.HeaderEdgeRequest header of the message
+// into ctx.
+//
+// If impl is nil, the global one from ecinterface.Get will be used instead.
+func ConsumerEdgeContext(impl ecinterface.Interface) ConsumerMiddleware {
+	return func(topic string, next ProcessMessageFunc) ProcessMessageFunc {
+		return func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+			if header, ok := messageHeader(msg, transport.HeaderEdgeRequest); ok {
+				if impl == nil {
+					impl = ecinterface.Get()
+				}
+				var err error
+				ctx, err = impl.HeaderToContext(ctx, header)
+				if err != nil {
+					log.C(ctx).Errorw(
+						"kafkabp.ConsumerEdgeContext: error while parsing edge request context",
+						"err", err,
+						"topic", topic,
+					)
+				} else {
+					ecinterface.SetSentryTags(ctx, impl)
+				}
+			}
+			return next(ctx, msg)
+		}
+	}
+}
+
+// ConsumerPrometheus is a ConsumerMiddleware that reports the number and the
+// latency of the processed messages to Prometheus.
+func ConsumerPrometheus(topic string, next ProcessMessageFunc) ProcessMessageFunc {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage) (err error) {
+		start := time.Now()
+		defer func() {
+			success := prometheusbpint.BoolString(err == nil)
+			consumerMessagesCounter.WithLabelValues(topic, success).Inc()
+			consumerLatencyHistogram.WithLabelValues(topic, success).Observe(time.Since(start).Seconds())
+		}()
+		return next(ctx, msg)
+	}
+}
+
+// ConsumerRecoverPanic is a ConsumerMiddleware that recovers from the panics
+// raised when processing the messages,
+// reports them to sentry and Prometheus,
+// and returns them as errors instead.
+func ConsumerRecoverPanic(topic string, next ProcessMessageFunc) ProcessMessageFunc {
+	return func(ctx context.Context, msg *sarama.ConsumerMessage) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				if asErr, ok := r.(error); ok {
+					err = asErr
+				} else {
+					err = fmt.Errorf("kafkabp: panic processing message of topic %q: %+v", topic, r)
+				}
+				log.ErrorWithSentry(
+					ctx,
+					"kafkabp: recovered from panic:",
+					err,
+					"topic", topic,
+					"partition", msg.Partition,
+					"offset", msg.Offset,
+				)
+				consumerPanicsCounter.WithLabelValues(topic).Inc()
+			}
+		}()
+		return next(ctx, msg)
+	}
+}
+
+// startConsumerSpan starts the server span of consuming msg,
+// continuing the trace of the producer span from the tracing headers of msg.
+func startConsumerSpan(ctx context.Context, name string, msg *sarama.ConsumerMessage) (context.Context, *tracing.Span) {
+	var headers tracing.Headers
+	if v, ok := messageHeader(msg, transport.HeaderTracingTrace); ok {
+		headers.TraceID = v
+	}
+	if v, ok := messageHeader(msg, transport.HeaderTracingSpan); ok {
+		headers.SpanID = v
+	}
+	if v, ok := messageHeader(msg, transport.HeaderTracingFlags); ok {
+		headers.Flags = v
+	}
+	if v, ok := messageHeader(msg, transport.HeaderTracingSampled); ok {
+		sampled := v == transport.HeaderTracingSampledTrue
+		headers.Sampled = &sampled
+	}
+	return tracing.StartSpanFromHeaders(ctx, name, headers)
+}
+
+// messageHeader returns the value of the last header of msg with key.
+func messageHeader(msg *sarama.ConsumerMessage, key string) (string, bool) {
+	for i := len(msg.Headers) - 1; i >= 0; i-- {
+		if h := msg.Headers[i]; h != nil && string(h.Key) == key {
+			return string(h.Value), true
+		}
+	}
+	return "", false
+}
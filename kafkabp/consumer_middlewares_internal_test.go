@@ -0,0 +1,54 @@
+package kafkabp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestConsumerPrometheusAndRecoverPanic(t *testing.T) {
+	const topic = "kafkabp-prometheus-test"
+	process := WrapProcessMessageFunc(
+		topic,
+		func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+			if msg.Offset%2 == 1 {
+				panic("odd offset")
+			}
+			return nil
+		},
+		ConsumerPrometheus,
+		ConsumerRecoverPanic,
+	)
+
+	success := consumerMessagesCounter.WithLabelValues(topic, "true")
+	failure := consumerMessagesCounter.WithLabelValues(topic, "false")
+	panics := consumerPanicsCounter.WithLabelValues(topic)
+	successBefore := testutil.ToFloat64(success)
+	failureBefore := testutil.ToFloat64(failure)
+	panicsBefore := testutil.ToFloat64(panics)
+
+	for offset := int64(0); offset < 4; offset++ {
+		err := process(context.Background(), &sarama.ConsumerMessage{
+			Topic:  topic,
+			Offset: offset,
+		})
+		if offset%2 == 1 && err == nil {
+			t.Errorf("Expected the panic at offset %d returned as error", offset)
+		}
+		if offset%2 == 0 && err != nil {
+			t.Errorf("Unexpected error at offset %d: %v", offset, err)
+		}
+	}
+
+	if diff := testutil.ToFloat64(success) - successBefore; diff != 2 {
+		t.Errorf("Expected success counter to increase by 2, got %v", diff)
+	}
+	if diff := testutil.ToFloat64(failure) - failureBefore; diff != 2 {
+		t.Errorf("Expected failure counter to increase by 2, got %v", diff)
+	}
+	if diff := testutil.ToFloat64(panics) - panicsBefore; diff != 2 {
+		t.Errorf("Expected panics counter to increase by 2, got %v", diff)
+	}
+}
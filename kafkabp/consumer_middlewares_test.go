@@ -0,0 +1,164 @@
+package kafkabp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/kafkabp"
+	"github.com/reddit/baseplate.go/mqsend"
+	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/transport"
+)
+
+const middlewaresTestTopic = "kafkabp-middlewares-test"
+
+func TestWrapProcessMessageFunc(t *testing.T) {
+	var order []string
+	middleware := func(label string) kafkabp.ConsumerMiddleware {
+		return func(topic string, next kafkabp.ProcessMessageFunc) kafkabp.ProcessMessageFunc {
+			if topic != middlewaresTestTopic {
+				t.Errorf("Expected topic %q, got %q", middlewaresTestTopic, topic)
+			}
+			return func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+				order = append(order, label)
+				return next(ctx, msg)
+			}
+		}
+	}
+	process := kafkabp.WrapProcessMessageFunc(
+		middlewaresTestTopic,
+		func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+			order = append(order, "process")
+			return nil
+		},
+		middleware("first"),
+		middleware("second"),
+	)
+	if err := process(context.Background(), &sarama.ConsumerMessage{}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"first", "second", "process"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, order)
+			break
+		}
+	}
+}
+
+func TestConsumerTracing(t *testing.T) {
+	defer func() {
+		tracing.CloseTracer()
+		tracing.InitGlobalTracer(tracing.Config{})
+	}()
+	mmq := mqsend.OpenMockMessageQueue(mqsend.MessageQueueConfig{
+		MaxQueueSize:   100,
+		MaxMessageSize: 1024,
+	})
+	logger, startFailing := tracing.TestWrapper(t)
+	tracing.InitGlobalTracer(tracing.Config{
+		SampleRate:               1,
+		MaxRecordTimeout:         time.Millisecond * 100,
+		Logger:                   logger,
+		TestOnlyMockMessageQueue: mmq,
+	})
+	startFailing()
+
+	const (
+		traceID = "1234"
+		spanID  = "5678"
+	)
+	msg := &sarama.ConsumerMessage{
+		Topic: middlewaresTestTopic,
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(transport.HeaderTracingTrace), Value: []byte(traceID)},
+			{Key: []byte(transport.HeaderTracingSpan), Value: []byte(spanID)},
+			{Key: []byte(transport.HeaderTracingSampled), Value: []byte(transport.HeaderTracingSampledTrue)},
+		},
+	}
+	process := kafkabp.WrapProcessMessageFunc(
+		middlewaresTestTopic,
+		func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+			return errors.New("error")
+		},
+		kafkabp.ConsumerTracing,
+	)
+	if err := process(context.Background(), msg); err == nil {
+		t.Error("Expected error, got nil")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	encoded, err := mmq.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var span tracing.ZipkinSpan
+	if err := json.Unmarshal(encoded, &span); err != nil {
+		t.Fatal(err)
+	}
+	if span.TraceID != traceID || span.ParentID != spanID {
+		t.Errorf(
+			"Expected span continuing trace %q parent %q, got trace %q parent %q",
+			traceID,
+			spanID,
+			span.TraceID,
+			span.ParentID,
+		)
+	}
+	if span.Name != "consumer."+middlewaresTestTopic {
+		t.Errorf("Unexpected span name %q", span.Name)
+	}
+	var hasError bool
+	for _, annotation := range span.BinaryAnnotations {
+		if annotation.Key == tracing.ZipkinBinaryAnnotationKeyError {
+			hasError = true
+		}
+	}
+	if !hasError {
+		t.Errorf("Expected error annotation, got %+v", span.BinaryAnnotations)
+	}
+}
+
+type edgeContextKey struct{}
+
+type fakeEdgeContext struct {
+	ecinterface.Interface
+}
+
+func (fakeEdgeContext) HeaderToContext(ctx context.Context, header string) (context.Context, error) {
+	return context.WithValue(ctx, edgeContextKey{}, header), nil
+}
+
+func TestConsumerEdgeContext(t *testing.T) {
+	const header = "edge-context"
+	msg := &sarama.ConsumerMessage{
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(transport.HeaderEdgeRequest), Value: []byte(header)},
+		},
+	}
+	var got interface{}
+	process := kafkabp.WrapProcessMessageFunc(
+		middlewaresTestTopic,
+		func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+			got = ctx.Value(edgeContextKey{})
+			return nil
+		},
+		kafkabp.ConsumerEdgeContext(fakeEdgeContext{}),
+	)
+	if err := process(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if got != header {
+		t.Errorf("Expected edge context %q injected, got %v", header, got)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/Shopify/sarama/mocks"
 
 	"github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/healthbp"
 )
 
 // Make sure that Consumer also implements baseplate.HealthChecker.
@@ -185,3 +186,82 @@ func containsMsg(msgs []*sarama.ConsumerMessage, msg *sarama.ConsumerMessage) bo
 	}
 	return false
 }
+
+func TestKafkaConsumer_Pause(t *testing.T) {
+	kc := getTestMockConsumer(t)
+	pc, pc1 := setupPartitionConsumers(t, kc)
+
+	kc.Pause()
+	if !kc.IsPaused() {
+		t.Error("expected consumer to be paused")
+	}
+
+	consumed := make(chan *sarama.ConsumerMessage, 2)
+	go func() {
+		kc.Consume(
+			func(_ context.Context, msg *sarama.ConsumerMessage) {
+				consumed <- msg
+			},
+			func(err error) {},
+		)
+	}()
+	pc.YieldMessage(getTestKafkaMessage("key1", "value1"))
+	pc1.YieldMessage(getTestKafkaMessage("key2", "value2"))
+
+	select {
+	case msg := <-consumed:
+		t.Fatalf("expected no messages consumed while paused, got %v", msg)
+	case <-time.After(time.Millisecond * 10):
+	}
+
+	kc.Resume()
+	if kc.IsPaused() {
+		t.Error("expected consumer to be resumed")
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-consumed:
+		case <-time.After(time.Second):
+			t.Fatal("expected messages consumed after resume")
+		}
+	}
+
+	// Close unblocks the paused consumer.
+	kc.Pause()
+	pc.ExpectMessagesDrainedOnClose()
+	pc.YieldMessage(getTestKafkaMessage("key3", "value3"))
+	kc.Close()
+}
+
+func TestHealthConsumer(t *testing.T) {
+	const name = HealthCheckerPrefix + "test-health-topic"
+	findResult := func() (healthbp.Result, bool) {
+		for _, result := range healthbp.Check(context.Background(), healthbp.ProbeLiveness).Results {
+			if result.Name == name {
+				return result, true
+			}
+		}
+		return healthbp.Result{}, false
+	}
+
+	fake := newFakeConsumer()
+	c := withHealthChecker(ConsumerConfig{
+		Topic:             "test-health-topic",
+		HealthCriticality: healthbp.CriticalityLiveness,
+	}, fake)
+	result, ok := findResult()
+	if !ok {
+		t.Fatalf("Expected checker %q registered", name)
+	}
+	if !result.Healthy() || !result.Affecting {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	c.Close()
+	if _, ok := findResult(); ok {
+		t.Errorf("Expected checker %q removed after Close", name)
+	}
+	if fake.IsHealthy(context.Background()) {
+		t.Error("Expected the underlying consumer closed")
+	}
+}
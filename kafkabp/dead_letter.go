@@ -0,0 +1,181 @@
+package kafkabp
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/avast/retry-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/reddit/baseplate.go/internal/prometheusbpint"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/retrybp"
+)
+
+// DefaultDeadLetterMaxAttempts is the default value of
+// DeadLetterConfig.MaxAttempts.
+const DefaultDeadLetterMaxAttempts = 3
+
+// ErrDeadLetterTopicEmpty is thrown when the dead-letter topic is empty.
+var ErrDeadLetterTopicEmpty = errors.New("kafkabp: dead-letter Topic is empty")
+
+// Headers added to the messages published to the dead-letter topic,
+// in addition to the original headers.
+const (
+	DeadLetterErrorHeader             = "DLQ-Error"
+	DeadLetterAttemptsHeader          = "DLQ-Attempts"
+	DeadLetterOriginalTopicHeader     = "DLQ-Original-Topic"
+	DeadLetterOriginalPartitionHeader = "DLQ-Original-Partition"
+	DeadLetterOriginalOffsetHeader    = "DLQ-Original-Offset"
+	DeadLetterFailedAtHeader          = "DLQ-Failed-At"
+)
+
+// PrometheusDeadLetterTopicLabel is the label name of the dead-letter topic
+// of the Prometheus metrics reported by WithDeadLetterQueue.
+const PrometheusDeadLetterTopicLabel = "kafka_dead_letter_topic"
+
+var deadLetterCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "kafka_dead_letter_messages_total",
+	Help: "Number of the messages published (or failed to be published) to the dead-letter topics",
+}, []string{
+	PrometheusTopicLabel,
+	PrometheusDeadLetterTopicLabel,
+	PrometheusSuccessLabel,
+})
+
+// ProcessMessageFunc is a function type for processing consumer messages that
+// could fail.
+//
+// See WithDeadLetterQueue for turning it into a ConsumeMessageFunc.
+type ProcessMessageFunc func(ctx context.Context, msg *sarama.ConsumerMessage) error
+
+// MessageProducer is the interface used to publish messages synchronously.
+//
+// It's implemented by *Producer.
+type MessageProducer interface {
+	ProduceSync(ctx context.Context, msg *sarama.ProducerMessage) error
+}
+
+var _ MessageProducer = (*Producer)(nil)
+
+// DeadLetterConfig configures the dead-letter queue of WithDeadLetterQueue.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//     deadLetter:
+//       topic: sample-topic-dlq
+//       maxAttempts: 5
+//       backoff: 100ms
+type DeadLetterConfig struct {
+	// Required. The topic to publish the failed messages to.
+	Topic string `yaml:"topic"`
+
+	// Optional. The max number of attempts to process a message before
+	// publishing it to the dead-letter topic.
+	// Defaults to DefaultDeadLetterMaxAttempts.
+	MaxAttempts int `yaml:"maxAttempts"`
+
+	// Optional. The initial delay between the attempts,
+	// which grows exponentially with jitter.
+	// Defaults to 1ms.
+	Backoff time.Duration `yaml:"backoff"`
+}
+
+// WithDeadLetterQueue returns a ConsumeMessageFunc that calls process for each
+// message, retrying up to cfg.MaxAttempts times when it returns an error.
+//
+// When all the attempts failed,
+// the message is published to cfg.Topic via producer with the original key,
+// value and headers, plus the DeadLetter*Header headers describing the failure,
+// and the consumer continues with the next message.
+//
+// If publishing to the dead-letter topic also fails,
+// the error is logged and the message is dropped.
+//
+// The "kafka_dead_letter_messages_total" Prometheus counter is reported,
+// labeled by the original topic, the dead-letter topic and whether the publish
+// succeeded.
+func WithDeadLetterQueue(cfg DeadLetterConfig, producer MessageProducer, process ProcessMessageFunc) (ConsumeMessageFunc, error) {
+	if cfg.Topic == "" {
+		return nil, ErrDeadLetterTopicEmpty
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultDeadLetterMaxAttempts
+	}
+	options := []retry.Option{
+		retry.Attempts(uint(cfg.MaxAttempts)),
+	}
+	if cfg.Backoff > 0 {
+		options = append(options, retry.Delay(cfg.Backoff))
+	}
+
+	return func(ctx context.Context, msg *sarama.ConsumerMessage) {
+		err := retrybp.Do(
+			ctx,
+			func() error {
+				return process(ctx, msg)
+			},
+			options...,
+		)
+		if err == nil {
+			return
+		}
+
+		dlqErr := producer.ProduceSync(ctx, newDeadLetterMessage(cfg, msg, err))
+		deadLetterCounter.WithLabelValues(
+			msg.Topic,
+			cfg.Topic,
+			prometheusbpint.BoolString(dlqErr == nil),
+		).Inc()
+		if dlqErr != nil {
+			log.C(ctx).Errorw(
+				"kafkabp: failed to publish message to dead-letter topic, dropping it",
+				"err", dlqErr,
+				"processErr", err,
+				"topic", msg.Topic,
+				"partition", msg.Partition,
+				"offset", msg.Offset,
+				"deadLetterTopic", cfg.Topic,
+			)
+		}
+	}, nil
+}
+
+func newDeadLetterMessage(cfg DeadLetterConfig, msg *sarama.ConsumerMessage, err error) *sarama.ProducerMessage {
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+6)
+	for _, h := range msg.Headers {
+		if h != nil {
+			headers = append(headers, *h)
+		}
+	}
+	add := func(key, value string) {
+		headers = append(headers, sarama.RecordHeader{
+			Key:   []byte(key),
+			Value: []byte(value),
+		})
+	}
+	add(DeadLetterErrorHeader, err.Error())
+	add(DeadLetterAttemptsHeader, strconv.Itoa(cfg.MaxAttempts))
+	add(DeadLetterOriginalTopicHeader, msg.Topic)
+	add(DeadLetterOriginalPartitionHeader, strconv.FormatInt(int64(msg.Partition), 10))
+	add(DeadLetterOriginalOffsetHeader, strconv.FormatInt(msg.Offset, 10))
+	add(DeadLetterFailedAtHeader, time.Now().UTC().Format(time.RFC3339Nano))
+
+	dlqMsg := &sarama.ProducerMessage{
+		Topic:   cfg.Topic,
+		Headers: headers,
+	}
+	if msg.Key != nil {
+		dlqMsg.Key = sarama.ByteEncoder(msg.Key)
+	}
+	if msg.Value != nil {
+		dlqMsg.Value = sarama.ByteEncoder(msg.Value)
+	}
+	return dlqMsg
+}
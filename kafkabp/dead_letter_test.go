@@ -0,0 +1,134 @@
+package kafkabp_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/reddit/baseplate.go/kafkabp"
+)
+
+type fakeProducer struct {
+	err      error
+	produced []*sarama.ProducerMessage
+}
+
+func (p *fakeProducer) ProduceSync(_ context.Context, msg *sarama.ProducerMessage) error {
+	p.produced = append(p.produced, msg)
+	return p.err
+}
+
+func TestWithDeadLetterQueue(t *testing.T) {
+	msg := &sarama.ConsumerMessage{
+		Topic:     "kafkabp-test",
+		Partition: 2,
+		Offset:    42,
+		Key:       []byte("key"),
+		Value:     []byte("value"),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte("foo"), Value: []byte("bar")},
+		},
+	}
+	cfg := kafkabp.DeadLetterConfig{
+		Topic:       "kafkabp-test-dlq",
+		MaxAttempts: 3,
+	}
+
+	t.Run("success", func(t *testing.T) {
+		var producer fakeProducer
+		var attempts int
+		consume, err := kafkabp.WithDeadLetterQueue(cfg, &producer, func(_ context.Context, _ *sarama.ConsumerMessage) error {
+			attempts++
+			if attempts < 2 {
+				return errors.New("transient")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		consume(context.Background(), msg)
+		if attempts != 2 {
+			t.Errorf("expected 2 attempts, got %d", attempts)
+		}
+		if len(producer.produced) != 0 {
+			t.Errorf("expected no dead letters, got %v", producer.produced)
+		}
+	})
+
+	t.Run("dead-letter", func(t *testing.T) {
+		var producer fakeProducer
+		var attempts int
+		consume, err := kafkabp.WithDeadLetterQueue(cfg, &producer, func(_ context.Context, _ *sarama.ConsumerMessage) error {
+			attempts++
+			return errors.New("permanent")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		consume(context.Background(), msg)
+		if attempts != cfg.MaxAttempts {
+			t.Errorf("expected %d attempts, got %d", cfg.MaxAttempts, attempts)
+		}
+		if len(producer.produced) != 1 {
+			t.Fatalf("expected 1 dead letter, got %v", producer.produced)
+		}
+
+		dlq := producer.produced[0]
+		if dlq.Topic != cfg.Topic {
+			t.Errorf("expected topic %q, got %q", cfg.Topic, dlq.Topic)
+		}
+		if key, _ := dlq.Key.Encode(); string(key) != "key" {
+			t.Errorf("expected key %q, got %q", "key", key)
+		}
+		if value, _ := dlq.Value.Encode(); string(value) != "value" {
+			t.Errorf("expected value %q, got %q", "value", value)
+		}
+		headers := make(map[string]string)
+		for _, h := range dlq.Headers {
+			headers[string(h.Key)] = string(h.Value)
+		}
+		for key, expected := range map[string]string{
+			"foo":                                     "bar",
+			kafkabp.DeadLetterAttemptsHeader:          "3",
+			kafkabp.DeadLetterOriginalTopicHeader:     "kafkabp-test",
+			kafkabp.DeadLetterOriginalPartitionHeader: "2",
+			kafkabp.DeadLetterOriginalOffsetHeader:    "42",
+		} {
+			if headers[key] != expected {
+				t.Errorf("expected header %q to be %q, got %q", key, expected, headers[key])
+			}
+		}
+		if !strings.Contains(headers[kafkabp.DeadLetterErrorHeader], "permanent") {
+			t.Errorf("expected error header to contain the error, got %q", headers[kafkabp.DeadLetterErrorHeader])
+		}
+		if headers[kafkabp.DeadLetterFailedAtHeader] == "" {
+			t.Error("expected failed at header to be set")
+		}
+	})
+
+	t.Run("dead-letter-failed", func(t *testing.T) {
+		producer := fakeProducer{err: errors.New("kafka error")}
+		consume, err := kafkabp.WithDeadLetterQueue(cfg, &producer, func(_ context.Context, _ *sarama.ConsumerMessage) error {
+			return errors.New("permanent")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Should not block or panic.
+		consume(context.Background(), msg)
+		if len(producer.produced) != 1 {
+			t.Errorf("expected 1 dead letter attempt, got %v", producer.produced)
+		}
+	})
+
+	t.Run("no-topic", func(t *testing.T) {
+		_, err := kafkabp.WithDeadLetterQueue(kafkabp.DeadLetterConfig{}, &fakeProducer{}, nil)
+		if !errors.Is(err, kafkabp.ErrDeadLetterTopicEmpty) {
+			t.Errorf("expected error %v, got %v", kafkabp.ErrDeadLetterTopicEmpty, err)
+		}
+	})
+}
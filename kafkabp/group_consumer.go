@@ -8,6 +8,7 @@ import (
 
 	"github.com/Shopify/sarama"
 
+	"github.com/reddit/baseplate.go/healthbp"
 	"github.com/reddit/baseplate.go/tracing"
 )
 
@@ -17,6 +18,10 @@ type groupConsumer struct {
 
 	wg sync.WaitGroup
 
+	pauser  pauser
+	limiter partitionLimiter
+	lag     *lagMonitor
+
 	consumeReturned int64
 	closed          int64
 }
@@ -27,9 +32,16 @@ func newGroupConsumer(cfg ConsumerConfig, sc *sarama.Config) (Consumer, error) {
 	if err != nil {
 		return nil, err
 	}
+	lag, err := newConsumerLagMonitor(cfg, sc)
+	if err != nil {
+		consumer.Close()
+		return nil, err
+	}
 	return &groupConsumer{
 		consumer: consumer,
 		cfg:      cfg,
+		limiter:  newPartitionLimiter(cfg.MaxConcurrency),
+		lag:      lag,
 	}, nil
 }
 
@@ -50,8 +62,14 @@ func (gc *groupConsumer) Consume(
 	}()
 
 	handler := GroupConsumerHandler{
-		Callback: messagesFunc,
-		Topic:    gc.cfg.Topic,
+		Callback:       messagesFunc,
+		Topic:          gc.cfg.Topic,
+		CommitStrategy: gc.cfg.CommitStrategy,
+		Listener:       gc.cfg.RebalanceListener,
+		pauser:         &gc.pauser,
+		limiter:        gc.limiter,
+		lag:            gc.lag,
+		startup:        gc.cfg.StartupSignal,
 	}
 
 	// gc.consumer.Consume returns when either:
@@ -73,10 +91,17 @@ func (gc *groupConsumer) Consume(
 // Close closes the consumer.
 func (gc *groupConsumer) Close() error {
 	atomic.StoreInt64(&gc.closed, 1)
+	gc.pauser.stop()
 
 	// wait for the Consume function to return
 	defer gc.wg.Wait()
 
+	if err := gc.lag.close(); err != nil {
+		gc.cfg.Logger.Log(
+			context.Background(),
+			"kafkabp.groupConsumer.Close: Error closing the lag monitor: "+err.Error(),
+		)
+	}
 	return gc.consumer.Close()
 }
 
@@ -84,6 +109,43 @@ func (gc *groupConsumer) IsHealthy(_ context.Context) bool {
 	return atomic.LoadInt64(&gc.consumeReturned) == 0
 }
 
+// Pause implements Consumer.
+func (gc *groupConsumer) Pause() {
+	gc.pauser.pause()
+}
+
+// Resume implements Consumer.
+func (gc *groupConsumer) Resume() {
+	gc.pauser.resume()
+}
+
+// IsPaused implements Consumer.
+func (gc *groupConsumer) IsPaused() bool {
+	return gc.pauser.paused()
+}
+
+// RebalanceListener is notified when the partitions are assigned to or revoked
+// from a group consumer.
+//
+// All the callbacks are optional.
+type RebalanceListener struct {
+	// OnAssigned is called at the beginning of every session (after a
+	// rebalance), with the partitions assigned to this consumer by topic,
+	// before any message from the session is consumed.
+	OnAssigned func(ctx context.Context, partitions map[string][]int32)
+
+	// OnRevoked is called at the end of every session (before the next
+	// rebalance), with the partitions to be revoked by topic,
+	// after the ConsumeMessageFunc returned for all the messages from the
+	// session.
+	//
+	// Services processing messages asynchronously should flush their in-flight
+	// work in this callback.
+	// With "sync" and "async" CommitStrategy,
+	// the offsets are committed after it returns.
+	OnRevoked func(ctx context.Context, partitions map[string][]int32)
+}
+
 // GroupConsumerHandler implements sarama.ConsumerGroupHandler.
 //
 // It's exported so that users of this library can write mocks to test their
@@ -91,42 +153,100 @@ func (gc *groupConsumer) IsHealthy(_ context.Context) bool {
 type GroupConsumerHandler struct {
 	Callback ConsumeMessageFunc
 	Topic    string
+
+	// Optional, see ConsumerConfig.CommitStrategy.
+	CommitStrategy string
+
+	// Optional, see ConsumerConfig.RebalanceListener.
+	Listener RebalanceListener
+
+	pauser  *pauser
+	limiter partitionLimiter
+	lag     *lagMonitor
+	startup *healthbp.StartupSignal
 }
 
 // Setup is run at the beginning of a new session, before ConsumeClaim.
-func (h GroupConsumerHandler) Setup(sarama.ConsumerGroupSession) error {
-	// Do nothing.
+func (h GroupConsumerHandler) Setup(session sarama.ConsumerGroupSession) error {
+	h.lag.setPartitions(session.Claims()[h.Topic])
+	if h.Listener.OnAssigned != nil {
+		h.Listener.OnAssigned(session.Context(), session.Claims())
+	}
+	h.startup.Ready()
 	return nil
 }
 
 // Cleanup is run at the end of a session,
 // once all ConsumeClaim goroutines have exited.
-func (h GroupConsumerHandler) Cleanup(sarama.ConsumerGroupSession) error {
-	// Do nothing.
+func (h GroupConsumerHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	if h.Listener.OnRevoked != nil {
+		// The session context is already canceled at this point.
+		h.Listener.OnRevoked(context.Background(), session.Claims())
+	}
+	switch h.CommitStrategy {
+	case CommitSync, CommitAsync:
+		session.Commit()
+	}
 	return nil
 }
 
 // ConsumeClaim starts a consumer loop of ConsumerGroupClaim's Messages() chan.
 func (h GroupConsumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	for m := range claim.Messages() {
-		// Wrap in anonymous function for easier defer.
-		func() {
-			ctx := context.Background()
-			var span *tracing.Span
-			spanName := "group-consumer." + h.Topic
-			ctx, span = tracing.StartTopLevelServerSpan(ctx, spanName)
-			defer func() {
-				span.FinishWithOptions(tracing.FinishOptions{
-					Ctx: ctx,
-				}.Convert())
-			}()
-
-			h.Callback(ctx, m)
-			session.MarkMessage(
-				m,
-				"", // metadata
-			)
+	commit := func() {}
+	switch h.CommitStrategy {
+	case CommitSync:
+		commit = session.Commit
+	case CommitAsync:
+		trigger := make(chan struct{}, 1)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for range trigger {
+				session.Commit()
+			}
+		}()
+		defer func() {
+			close(trigger)
+			<-done
 		}()
+		commit = func() {
+			select {
+			case trigger <- struct{}{}:
+			default:
+				// A commit is already pending, which will include this one.
+			}
+		}
 	}
+
+	// A false return means the session ended while waiting,
+	// the rest of the messages are left to the next session.
+	h.limiter.processPartition(
+		h.Topic,
+		claim.Partition(),
+		claim.Messages(),
+		session.Context().Done(),
+		h.pauser,
+		func(m *sarama.ConsumerMessage) {
+			// Wrap in anonymous function for easier defer.
+			func() {
+				ctx := context.Background()
+				var span *tracing.Span
+				spanName := "group-consumer." + h.Topic
+				ctx, span = startConsumerSpan(ctx, spanName, m)
+				defer func() {
+					span.FinishWithOptions(tracing.FinishOptions{
+						Ctx: ctx,
+					}.Convert())
+				}()
+
+				h.Callback(ctx, m)
+				session.MarkMessage(
+					m,
+					"", // metadata
+				)
+			}()
+			commit()
+		},
+	)
 	return nil
 }
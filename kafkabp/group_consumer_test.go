@@ -0,0 +1,336 @@
+package kafkabp
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/reddit/baseplate.go/healthbp"
+)
+
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+
+	ctx    context.Context
+	claims map[string][]int32
+
+	lock    sync.Mutex
+	marked  []int64
+	commits int
+	// committed is the number of marked offsets at each commit.
+	committed []int
+}
+
+func (s *fakeSession) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeSession) Claims() map[string][]int32 {
+	return s.claims
+}
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.marked = append(s.marked, msg.Offset)
+}
+
+func (s *fakeSession) Commit() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.commits++
+	s.committed = append(s.committed, len(s.marked))
+}
+
+type fakeClaim struct {
+	sarama.ConsumerGroupClaim
+
+	messages  chan *sarama.ConsumerMessage
+	partition int32
+}
+
+func (c fakeClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return c.messages
+}
+
+func (c fakeClaim) Partition() int32 {
+	return c.partition
+}
+
+func newFakeClaim(n int) fakeClaim {
+	c := fakeClaim{messages: make(chan *sarama.ConsumerMessage, n)}
+	for i := 0; i < n; i++ {
+		c.messages <- &sarama.ConsumerMessage{Offset: int64(i)}
+	}
+	close(c.messages)
+	return c
+}
+
+func TestGroupConsumerHandler_CommitStrategy(t *testing.T) {
+	for _, c := range []struct {
+		strategy string
+		check    func(t *testing.T, s *fakeSession)
+	}{
+		{
+			strategy: CommitInterval,
+			check: func(t *testing.T, s *fakeSession) {
+				if s.commits != 0 {
+					t.Errorf("expected no explicit commits, got %d", s.commits)
+				}
+			},
+		},
+		{
+			strategy: CommitSync,
+			check: func(t *testing.T, s *fakeSession) {
+				// One commit after each message, and one on Cleanup.
+				expected := []int{1, 2, 3, 3}
+				if len(s.committed) != len(expected) {
+					t.Fatalf("expected commits %v, got %v", expected, s.committed)
+				}
+				for i := range expected {
+					if s.committed[i] != expected[i] {
+						t.Errorf("expected commits %v, got %v", expected, s.committed)
+					}
+				}
+			},
+		},
+		{
+			strategy: CommitAsync,
+			check: func(t *testing.T, s *fakeSession) {
+				// Coalesced, but at least one in background and one on Cleanup,
+				// and the last one must include all the messages.
+				if s.commits < 2 || s.commits > 4 {
+					t.Errorf("expected 2-4 commits, got %d", s.commits)
+				}
+				if last := s.committed[len(s.committed)-1]; last != 3 {
+					t.Errorf("expected the last commit to include all 3 messages, got %d", last)
+				}
+			},
+		},
+	} {
+		t.Run(c.strategy, func(t *testing.T) {
+			session := &fakeSession{ctx: context.Background()}
+			var consumed int
+			h := GroupConsumerHandler{
+				Callback: func(_ context.Context, msg *sarama.ConsumerMessage) {
+					consumed++
+				},
+				Topic:          "kafkabp-test",
+				CommitStrategy: c.strategy,
+			}
+			if err := h.ConsumeClaim(session, newFakeClaim(3)); err != nil {
+				t.Fatal(err)
+			}
+			if err := h.Cleanup(session); err != nil {
+				t.Fatal(err)
+			}
+			if consumed != 3 || len(session.marked) != 3 {
+				t.Errorf("expected 3 messages consumed and marked, got %d and %d", consumed, len(session.marked))
+			}
+			c.check(t, session)
+		})
+	}
+}
+
+func TestGroupConsumerHandler_RebalanceListener(t *testing.T) {
+	claims := map[string][]int32{"kafkabp-test": {1, 2}}
+	session := &fakeSession{
+		ctx:    context.Background(),
+		claims: claims,
+	}
+	var events []string
+	h := GroupConsumerHandler{
+		Callback: func(_ context.Context, msg *sarama.ConsumerMessage) {
+			events = append(events, "consume")
+		},
+		Topic:          "kafkabp-test",
+		CommitStrategy: CommitSync,
+		Listener: RebalanceListener{
+			OnAssigned: func(_ context.Context, partitions map[string][]int32) {
+				if len(partitions["kafkabp-test"]) != 2 {
+					t.Errorf("expected assigned partitions %v, got %v", claims, partitions)
+				}
+				events = append(events, "assigned")
+			},
+			OnRevoked: func(_ context.Context, partitions map[string][]int32) {
+				if len(partitions["kafkabp-test"]) != 2 {
+					t.Errorf("expected revoked partitions %v, got %v", claims, partitions)
+				}
+				// Nothing is committed on Cleanup before OnRevoked returns.
+				if session.commits != 1 {
+					t.Errorf("expected 1 commit before OnRevoked, got %d", session.commits)
+				}
+				events = append(events, "revoked")
+			},
+		},
+	}
+
+	if err := h.Setup(session); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.ConsumeClaim(session, newFakeClaim(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Cleanup(session); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"assigned", "consume", "revoked"}
+	if len(events) != len(expected) {
+		t.Fatalf("expected events %v, got %v", expected, events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Errorf("expected events %v, got %v", expected, events)
+		}
+	}
+	if session.commits != 2 {
+		t.Errorf("expected 2 commits, got %d", session.commits)
+	}
+}
+
+func TestGroupConsumerHandler_StartupSignal(t *testing.T) {
+	var gate healthbp.StartupGate
+	h := GroupConsumerHandler{
+		Topic:   "kafkabp-test",
+		startup: gate.Require("kafka"),
+	}
+	if err := gate.Check(context.Background()); err == nil {
+		t.Error("expected the gate to fail before Setup")
+	}
+	session := &fakeSession{
+		ctx:    context.Background(),
+		claims: map[string][]int32{"kafkabp-test": {1}},
+	}
+	if err := h.Setup(session); err != nil {
+		t.Fatal(err)
+	}
+	if err := gate.Check(context.Background()); err != nil {
+		t.Errorf("expected the gate to pass after Setup, got %v", err)
+	}
+}
+
+func TestGroupConsumerHandler_Pause(t *testing.T) {
+	var p pauser
+	p.pause()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &fakeSession{ctx: ctx}
+	consumed := make(chan struct{}, 3)
+	h := GroupConsumerHandler{
+		Callback: func(_ context.Context, msg *sarama.ConsumerMessage) {
+			consumed <- struct{}{}
+		},
+		Topic:  "kafkabp-test",
+		pauser: &p,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ConsumeClaim(session, newFakeClaim(3))
+	}()
+
+	select {
+	case <-consumed:
+		t.Fatal("expected no messages consumed while paused")
+	case <-time.After(time.Millisecond * 10):
+	}
+
+	p.resume()
+	<-done
+	if len(consumed) != 3 {
+		t.Errorf("expected 3 messages consumed after resume, got %d", len(consumed))
+	}
+
+	// The session ending unblocks the paused consuming without consuming the
+	// remaining messages.
+	p.pause()
+	cancel()
+	if err := h.ConsumeClaim(session, newFakeClaim(3)); err != nil {
+		t.Fatal(err)
+	}
+	if len(session.marked) != 3 {
+		t.Errorf("expected no more messages marked, got %d", len(session.marked))
+	}
+}
+
+func TestGroupConsumerHandler_MaxConcurrency(t *testing.T) {
+	const (
+		partitions = 4
+		messages   = 20
+	)
+	for _, c := range []struct {
+		label          string
+		maxConcurrency int
+		expectedMax    int
+	}{
+		{label: "unlimited", maxConcurrency: 0, expectedMax: partitions},
+		{label: "limited", maxConcurrency: 2, expectedMax: 2},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			session := &fakeSession{ctx: context.Background()}
+
+			var lock sync.Mutex
+			var running, maxRunning int
+			offsets := make(map[int32][]int64)
+			h := GroupConsumerHandler{
+				Callback: func(_ context.Context, msg *sarama.ConsumerMessage) {
+					lock.Lock()
+					running++
+					if running > maxRunning {
+						maxRunning = running
+					}
+					offsets[msg.Partition] = append(offsets[msg.Partition], msg.Offset)
+					lock.Unlock()
+
+					time.Sleep(time.Millisecond)
+
+					lock.Lock()
+					running--
+					lock.Unlock()
+				},
+				Topic:   "kafkabp-test",
+				limiter: newPartitionLimiter(c.maxConcurrency),
+			}
+
+			var wg sync.WaitGroup
+			for p := int32(0); p < partitions; p++ {
+				claim := fakeClaim{
+					messages:  make(chan *sarama.ConsumerMessage, messages),
+					partition: p,
+				}
+				for i := 0; i < messages; i++ {
+					claim.messages <- &sarama.ConsumerMessage{
+						Partition: p,
+						Offset:    int64(i),
+					}
+				}
+				close(claim.messages)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					h.ConsumeClaim(session, claim)
+				}()
+			}
+			wg.Wait()
+
+			if maxRunning > c.expectedMax {
+				t.Errorf("expected at most %d messages processed concurrently, got %d", c.expectedMax, maxRunning)
+			}
+			for p := int32(0); p < partitions; p++ {
+				if len(offsets[p]) != messages {
+					t.Fatalf("expected %d messages from partition %d, got %v", messages, p, offsets[p])
+				}
+				for i, offset := range offsets[p] {
+					if offset != int64(i) {
+						t.Errorf("expected messages from partition %d in order, got %v", p, offsets[p])
+						break
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package kafkabp
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+// lagFetcher queries the offsets needed to calculate the consumer lag.
+type lagFetcher interface {
+	// highWaterMarks returns the offsets of the next messages to be produced to
+	// the partitions of topic.
+	highWaterMarks(topic string, partitions []int32) (map[int32]int64, error)
+
+	// committedOffsets returns the offsets committed by group to the partitions
+	// of topic.
+	//
+	// The partitions without any committed offset are omitted.
+	committedOffsets(group, topic string, partitions []int32) (map[int32]int64, error)
+
+	close() error
+}
+
+type saramaLagFetcher struct {
+	client sarama.Client
+	admin  sarama.ClusterAdmin
+}
+
+func newSaramaLagFetcher(brokers []string, sc *sarama.Config) (*saramaLagFetcher, error) {
+	client, err := sarama.NewClient(brokers, sc)
+	if err != nil {
+		return nil, err
+	}
+	// Closing the admin also closes the client.
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &saramaLagFetcher{
+		client: client,
+		admin:  admin,
+	}, nil
+}
+
+func (f *saramaLagFetcher) highWaterMarks(topic string, partitions []int32) (map[int32]int64, error) {
+	offsets := make(map[int32]int64, len(partitions))
+	for _, p := range partitions {
+		offset, err := f.client.GetOffset(topic, p, sarama.OffsetNewest)
+		if err != nil {
+			return nil, err
+		}
+		offsets[p] = offset
+	}
+	return offsets, nil
+}
+
+func (f *saramaLagFetcher) committedOffsets(group, topic string, partitions []int32) (map[int32]int64, error) {
+	resp, err := f.admin.ListConsumerGroupOffsets(group, map[string][]int32{
+		topic: partitions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Err != sarama.ErrNoError {
+		return nil, resp.Err
+	}
+	offsets := make(map[int32]int64, len(partitions))
+	for p, block := range resp.Blocks[topic] {
+		if block.Err != sarama.ErrNoError {
+			return nil, block.Err
+		}
+		if block.Offset >= 0 {
+			offsets[p] = block.Offset
+		}
+	}
+	return offsets, nil
+}
+
+func (f *saramaLagFetcher) close() error {
+	return f.admin.Close()
+}
+
+// partitionPosition is the position of the consumer on a partition.
+type partitionPosition struct {
+	// The offset of the next message to be consumed, -1 if unknown yet.
+	offset int64
+
+	// The last time offset changed, only used by group consumers.
+	changed time.Time
+}
+
+// lagMonitor periodically reports the lag of the consumer on the partitions
+// assigned to it.
+//
+// For group consumers the lag is calculated from the committed offsets,
+// and for topic consumers (which don't commit) from the processed ones.
+//
+// The nil value is a no-op.
+type lagMonitor struct {
+	topic    string
+	group    string
+	interval time.Duration
+	fetcher  lagFetcher
+	logger   log.Wrapper
+
+	now func() time.Time
+
+	lock      sync.Mutex
+	positions map[int32]*partitionPosition
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newLagMonitor(cfg ConsumerConfig, fetcher lagFetcher) *lagMonitor {
+	return &lagMonitor{
+		topic:     cfg.Topic,
+		group:     cfg.GroupID,
+		interval:  cfg.LagReportInterval,
+		fetcher:   fetcher,
+		logger:    cfg.Logger,
+		now:       time.Now,
+		positions: make(map[int32]*partitionPosition),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// newConsumerLagMonitor creates and starts a lagMonitor for the consumer with
+// cfg and sc, or returns nil if the lag reporting is disabled.
+func newConsumerLagMonitor(cfg ConsumerConfig, sc *sarama.Config) (*lagMonitor, error) {
+	if cfg.LagReportInterval <= 0 {
+		return nil, nil
+	}
+	fetcher, err := newSaramaLagFetcher(cfg.Brokers, sc)
+	if err != nil {
+		return nil, err
+	}
+	m := newLagMonitor(cfg, fetcher)
+	go m.run()
+	return m, nil
+}
+
+// setPartitions sets the partitions assigned to the consumer.
+func (m *lagMonitor) setPartitions(partitions []int32) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	positions := make(map[int32]*partitionPosition, len(partitions))
+	for _, p := range partitions {
+		if pos, ok := m.positions[p]; ok {
+			positions[p] = pos
+		} else {
+			positions[p] = &partitionPosition{offset: -1}
+		}
+	}
+	for p := range m.positions {
+		if _, ok := positions[p]; !ok {
+			m.deleteMetrics(p)
+		}
+	}
+	m.positions = positions
+}
+
+// markProcessed records that the message at offset on partition is
+// processed, only used by topic consumers.
+func (m *lagMonitor) markProcessed(partition int32, offset int64) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if pos, ok := m.positions[partition]; ok {
+		pos.offset = offset + 1
+	}
+}
+
+func (m *lagMonitor) run() {
+	defer close(m.done)
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			if err := m.report(); err != nil {
+				consumerLagErrorsCounter.WithLabelValues(m.topic, m.group).Inc()
+				m.logger.Log(
+					context.Background(),
+					"kafkabp: failed to query consumer lag: "+err.Error(),
+				)
+			}
+		}
+	}
+}
+
+// report queries the offsets and reports the lag of the assigned partitions.
+func (m *lagMonitor) report() error {
+	m.lock.Lock()
+	partitions := make([]int32, 0, len(m.positions))
+	for p := range m.positions {
+		partitions = append(partitions, p)
+	}
+	m.lock.Unlock()
+	if len(partitions) == 0 {
+		return nil
+	}
+
+	hwms, err := m.fetcher.highWaterMarks(m.topic, partitions)
+	if err != nil {
+		return err
+	}
+	var committed map[int32]int64
+	if m.group != "" {
+		committed, err = m.fetcher.committedOffsets(m.group, m.topic, partitions)
+		if err != nil {
+			return err
+		}
+	}
+
+	now := m.now()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for p, pos := range m.positions {
+		hwm, ok := hwms[p]
+		if !ok {
+			continue
+		}
+		labels := m.labels(p)
+		if m.group != "" {
+			offset, ok := committed[p]
+			if !ok {
+				continue
+			}
+			if offset != pos.offset {
+				pos.offset = offset
+				pos.changed = now
+			}
+			consumerLastCommitAgeGauge.WithLabelValues(labels...).Set(now.Sub(pos.changed).Seconds())
+		}
+		if pos.offset < 0 {
+			// Nothing processed yet on this partition.
+			continue
+		}
+		lag := hwm - pos.offset
+		if lag < 0 {
+			lag = 0
+		}
+		consumerLagGauge.WithLabelValues(labels...).Set(float64(lag))
+	}
+	return nil
+}
+
+func (m *lagMonitor) labels(partition int32) []string {
+	return []string{
+		m.topic,
+		strconv.FormatInt(int64(partition), 10),
+		m.group,
+	}
+}
+
+func (m *lagMonitor) deleteMetrics(partition int32) {
+	labels := m.labels(partition)
+	consumerLagGauge.DeleteLabelValues(labels...)
+	consumerLastCommitAgeGauge.DeleteLabelValues(labels...)
+}
+
+// close stops the reporting and removes the reported metrics.
+func (m *lagMonitor) close() error {
+	if m == nil {
+		return nil
+	}
+	close(m.stop)
+	<-m.done
+	m.setPartitions(nil)
+	return m.fetcher.close()
+}
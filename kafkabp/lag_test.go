@@ -0,0 +1,144 @@
+package kafkabp
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type fakeLagFetcher struct {
+	lock      sync.Mutex
+	hwms      map[int32]int64
+	committed map[int32]int64
+	err       error
+	closed    bool
+}
+
+func (f *fakeLagFetcher) highWaterMarks(_ string, _ []int32) (map[int32]int64, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.hwms, f.err
+}
+
+func (f *fakeLagFetcher) committedOffsets(_, _ string, _ []int32) (map[int32]int64, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.committed, f.err
+}
+
+func (f *fakeLagFetcher) close() error {
+	f.closed = true
+	return nil
+}
+
+func TestLagMonitorGroup(t *testing.T) {
+	const topic = "kafkabp-lag-group"
+	fetcher := &fakeLagFetcher{
+		hwms:      map[int32]int64{0: 100, 1: 50},
+		committed: map[int32]int64{0: 90},
+	}
+	m := newLagMonitor(ConsumerConfig{
+		Topic:   topic,
+		GroupID: "group",
+	}, fetcher)
+	now := time.Unix(1000, 0)
+	m.now = func() time.Time {
+		return now
+	}
+	m.setPartitions([]int32{0, 1})
+
+	if err := m.report(); err != nil {
+		t.Fatal(err)
+	}
+	if lag := testutil.ToFloat64(consumerLagGauge.WithLabelValues(topic, "0", "group")); lag != 10 {
+		t.Errorf("Expected lag 10 on partition 0, got %v", lag)
+	}
+	// Nothing committed on partition 1.
+	if n := testutil.CollectAndCount(consumerLagGauge); n != 1 {
+		t.Errorf("Expected lag reported for 1 partition, got %d", n)
+	}
+
+	// Not committed since the last report.
+	now = now.Add(time.Minute)
+	fetcher.hwms[0] = 120
+	if err := m.report(); err != nil {
+		t.Fatal(err)
+	}
+	if lag := testutil.ToFloat64(consumerLagGauge.WithLabelValues(topic, "0", "group")); lag != 30 {
+		t.Errorf("Expected lag 30 on partition 0, got %v", lag)
+	}
+	if age := testutil.ToFloat64(consumerLastCommitAgeGauge.WithLabelValues(topic, "0", "group")); age != 60 {
+		t.Errorf("Expected last commit age 60s on partition 0, got %v", age)
+	}
+
+	// Committed.
+	now = now.Add(time.Minute)
+	fetcher.committed[0] = 120
+	if err := m.report(); err != nil {
+		t.Fatal(err)
+	}
+	if lag := testutil.ToFloat64(consumerLagGauge.WithLabelValues(topic, "0", "group")); lag != 0 {
+		t.Errorf("Expected lag 0 on partition 0, got %v", lag)
+	}
+	if age := testutil.ToFloat64(consumerLastCommitAgeGauge.WithLabelValues(topic, "0", "group")); age != 0 {
+		t.Errorf("Expected last commit age 0s on partition 0, got %v", age)
+	}
+
+	// Revoked partitions are removed from the metrics.
+	m.setPartitions([]int32{1})
+	if n := testutil.CollectAndCount(consumerLagGauge); n != 0 {
+		t.Errorf("Expected the revoked partition removed from the metrics, got %d", n)
+	}
+
+	fetcher.err = errors.New("broker unavailable")
+	if err := m.report(); err == nil {
+		t.Error("Expected error, got nil")
+	}
+}
+
+func TestLagMonitorTopic(t *testing.T) {
+	const topic = "kafkabp-lag-topic"
+	fetcher := &fakeLagFetcher{
+		hwms: map[int32]int64{0: 100},
+	}
+	m := newLagMonitor(ConsumerConfig{
+		Topic:             topic,
+		LagReportInterval: time.Millisecond,
+	}, fetcher)
+	m.setPartitions([]int32{0})
+	m.markProcessed(0, 79)
+	// Not assigned.
+	m.markProcessed(1, 0)
+
+	go m.run()
+	time.Sleep(time.Millisecond * 10)
+
+	if lag := testutil.ToFloat64(consumerLagGauge.WithLabelValues(topic, "0", "")); lag != 20 {
+		t.Errorf("Expected lag 20 on partition 0, got %v", lag)
+	}
+	if n := testutil.CollectAndCount(consumerLastCommitAgeGauge); n != 0 {
+		t.Errorf("Expected no last commit age reported for topic consumers, got %d", n)
+	}
+
+	if err := m.close(); err != nil {
+		t.Fatal(err)
+	}
+	if !fetcher.closed {
+		t.Error("Expected the fetcher to be closed")
+	}
+	if n := testutil.CollectAndCount(consumerLagGauge); n != 0 {
+		t.Errorf("Expected the metrics removed on close, got %d", n)
+	}
+}
+
+func TestNilLagMonitor(t *testing.T) {
+	var m *lagMonitor
+	m.setPartitions([]int32{0})
+	m.markProcessed(0, 0)
+	if err := m.close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,81 @@
+package kafkabp
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// partitionLimiter bounds the number of partitions processing messages
+// concurrently.
+//
+// The nil value is unbounded.
+type partitionLimiter chan struct{}
+
+func newPartitionLimiter(n int) partitionLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return make(partitionLimiter, n)
+}
+
+// acquire blocks until a processing slot is available or done is closed.
+//
+// It returns false if it returns because of done.
+func (l partitionLimiter) acquire(done <-chan struct{}) bool {
+	if l == nil {
+		return true
+	}
+	select {
+	case l <- struct{}{}:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// release releases the slot taken by a successful acquire.
+func (l partitionLimiter) release() {
+	if l != nil {
+		<-l
+	}
+}
+
+// processPartition calls process for each message from messages in order,
+// waiting on p (could be nil) and l between the messages.
+//
+// It returns when messages is closed, or when done is closed while waiting.
+// It returns false in the later case.
+func (l partitionLimiter) processPartition(
+	topic string,
+	partition int32,
+	messages <-chan *sarama.ConsumerMessage,
+	done <-chan struct{},
+	p *pauser,
+	process func(*sarama.ConsumerMessage),
+) bool {
+	labels := []string{topic, strconv.FormatInt(int64(partition), 10)}
+	inFlight := consumerInFlightGauge.WithLabelValues(labels...)
+	defer consumerInFlightGauge.DeleteLabelValues(labels...)
+	wait := consumerConcurrencyWaitHistogram.WithLabelValues(topic)
+
+	for m := range messages {
+		// +1 for m itself.
+		inFlight.Set(float64(len(messages) + 1))
+
+		if p != nil && !p.wait(done) {
+			return false
+		}
+		start := time.Now()
+		if !l.acquire(done) {
+			return false
+		}
+		wait.Observe(time.Since(start).Seconds())
+
+		process(m)
+		l.release()
+		inFlight.Set(float64(len(messages)))
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package kafkabp
+
+import (
+	"sync"
+)
+
+// pauser blocks the consuming of the messages while paused.
+//
+// The zero value is ready to use and not paused.
+type pauser struct {
+	lock    sync.Mutex
+	resumed chan struct{} // non-nil while paused, closed on resume
+	stopped bool
+}
+
+// pause pauses the consuming until resume or stop is called.
+func (p *pauser) pause() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.resumed == nil && !p.stopped {
+		p.resumed = make(chan struct{})
+	}
+}
+
+// resume resumes the consuming.
+func (p *pauser) resume() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.resumed != nil {
+		close(p.resumed)
+		p.resumed = nil
+	}
+}
+
+// stop resumes the consuming, and makes future pause calls no-op.
+//
+// It's called on Close so that the messages can be drained.
+func (p *pauser) stop() {
+	p.lock.Lock()
+	p.stopped = true
+	p.lock.Unlock()
+	p.resume()
+}
+
+func (p *pauser) paused() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.resumed != nil
+}
+
+// wait blocks while paused, until resumed or done is closed.
+//
+// It returns false if it returns because of done.
+func (p *pauser) wait(done <-chan struct{}) bool {
+	p.lock.Lock()
+	resumed := p.resumed
+	p.lock.Unlock()
+	if resumed == nil {
+		return true
+	}
+	select {
+	case <-resumed:
+		return true
+	case <-done:
+		return false
+	}
+}
@@ -0,0 +1,314 @@
+package kafkabp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/internal/prometheusbpint"
+	"github.com/reddit/baseplate.go/tracing"
+	"github.com/reddit/baseplate.go/transport"
+)
+
+// ErrProducerClosed is returned by Producer when producing messages after
+// Close is called.
+var ErrProducerClosed = errors.New("kafkabp: producer is closed")
+
+// DeliveryReportFunc is called when a message produced by Producer is
+// delivered (err is nil), or failed to be delivered.
+//
+// It's called from the producer's background goroutine,
+// so the implementation should not block.
+type DeliveryReportFunc func(msg *sarama.ProducerMessage, err error)
+
+// Producer produces messages to Kafka asynchronously,
+// batching them according to the ProducerConfig.
+//
+// Every message produced creates a client span as the child of the span in the
+// context (if any), which is injected into the message headers (using the same
+// header names as Thrift, e.g. "Trace", "Span"),
+// and finished when the delivery report is received.
+//
+// The following Prometheus metrics are reported, labeled by the topic:
+//
+// - "kafka_producer_messages_total": counter of the delivered and failed
+// messages.
+//
+// - "kafka_producer_latency_seconds": histogram of the time from producing the
+// messages to receiving the delivery reports.
+//
+// - "kafka_producer_bytes_total": counter of the size of the keys and values of
+// the delivered messages.
+type Producer struct {
+	cfg      ProducerConfig
+	producer sarama.AsyncProducer
+
+	lock   sync.RWMutex
+	closed bool
+
+	wg sync.WaitGroup
+
+	// secrets is set by NewProducerWithSecrets, closed after the producer.
+	secrets io.Closer
+}
+
+// NewProducer creates a new Kafka producer.
+//
+// It's necessary to call Close on the producer to flush the buffered messages
+// before it goes out of scope.
+func NewProducer(cfg ProducerConfig) (*Producer, error) {
+	sc, err := cfg.NewSaramaConfig()
+	if err != nil {
+		return nil, err
+	}
+	return newProducerFromSaramaConfig(cfg, sc)
+}
+
+func newProducerFromSaramaConfig(cfg ProducerConfig, sc *sarama.Config) (*Producer, error) {
+	producer, err := sarama.NewAsyncProducer(cfg.Brokers, sc)
+	if err != nil {
+		return nil, err
+	}
+	return newProducer(cfg, producer), nil
+}
+
+func newProducer(cfg ProducerConfig, producer sarama.AsyncProducer) *Producer {
+	p := &Producer{
+		cfg:      cfg,
+		producer: producer,
+	}
+	p.start(producer)
+	return p
+}
+
+// start starts reporting the deliveries of producer.
+func (p *Producer) start(producer sarama.AsyncProducer) {
+	p.wg.Add(2)
+	go func() {
+		defer p.wg.Done()
+		for msg := range producer.Successes() {
+			p.report(msg, nil)
+		}
+	}()
+	go func() {
+		defer p.wg.Done()
+		for err := range producer.Errors() {
+			p.report(err.Msg, err.Err)
+		}
+	}()
+}
+
+// replace replaces the underlying sarama producer with producer,
+// e.g. to use the rotated PLAIN credentials (see NewProducerWithSecrets).
+//
+// The old one is closed after the messages in flight are reported.
+// When p is already closed, producer is closed instead.
+func (p *Producer) replace(producer sarama.AsyncProducer) {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		if err := producer.Close(); err != nil {
+			p.report(nil, err)
+		}
+		return
+	}
+	old := p.producer
+	p.producer = producer
+	p.start(producer)
+	p.lock.Unlock()
+
+	old.AsyncClose()
+}
+
+// producerMetadata replaces the Metadata of the messages in flight.
+type producerMetadata struct {
+	metadata interface{}
+	span     *tracing.Span
+	ctx      context.Context
+	start    time.Time
+	report   DeliveryReportFunc
+}
+
+// Produce sends msg to Kafka asynchronously.
+//
+// report is optional and will be called with the delivery result of msg.
+// When it's nil, failed deliveries are logged via ProducerConfig.Logger.
+//
+// It blocks when the producer's buffer is full, until msg is accepted or ctx
+// is done. The error is only non-nil when msg is not accepted,
+// in which case report is not called.
+//
+// msg must not be modified until it's reported.
+func (p *Producer) Produce(ctx context.Context, msg *sarama.ProducerMessage, report DeliveryReportFunc) error {
+	span, spanCtx := opentracing.StartSpanFromContext(
+		ctx,
+		"producer."+msg.Topic,
+		tracing.SpanTypeOption{Type: tracing.SpanTypeClient},
+	)
+	bpSpan := tracing.AsSpan(span)
+	setSpanHeaders(msg, bpSpan)
+	msg.Metadata = &producerMetadata{
+		metadata: msg.Metadata,
+		span:     bpSpan,
+		ctx:      spanCtx,
+		start:    time.Now(),
+		report:   report,
+	}
+
+	err := p.send(ctx, msg)
+	if err != nil {
+		msg.Metadata = msg.Metadata.(*producerMetadata).metadata
+		bpSpan.FinishWithOptions(tracing.FinishOptions{
+			Ctx: spanCtx,
+			Err: err,
+		}.Convert())
+	}
+	return err
+}
+
+func (p *Producer) send(ctx context.Context, msg *sarama.ProducerMessage) error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.closed {
+		return ErrProducerClosed
+	}
+	select {
+	case p.producer.Input() <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ProduceSync sends msg to Kafka and waits for its delivery.
+//
+// When ctx is done before the delivery, it returns ctx.Err(),
+// but msg might still be delivered.
+func (p *Producer) ProduceSync(ctx context.Context, msg *sarama.ProducerMessage) error {
+	result := make(chan error, 1)
+	if err := p.Produce(ctx, msg, func(_ *sarama.ProducerMessage, err error) {
+		result <- err
+	}); err != nil {
+		return err
+	}
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes the buffered messages and closes the producer.
+//
+// It waits until all the in-flight messages are reported.
+// Produce calls after Close return ErrProducerClosed.
+func (p *Producer) Close() error {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.lock.Unlock()
+
+	p.producer.AsyncClose()
+	p.wg.Wait()
+	if p.secrets != nil {
+		p.secrets.Close()
+	}
+	return nil
+}
+
+func (p *Producer) report(msg *sarama.ProducerMessage, err error) {
+	if msg == nil {
+		// Errors not attached to any messages, e.g. failing to close the
+		// underlying client.
+		if p.cfg.Logger != nil {
+			p.cfg.Logger.Log(
+				context.Background(),
+				"kafkabp.Producer: "+err.Error(),
+			)
+		}
+		return
+	}
+
+	md, ok := msg.Metadata.(*producerMetadata)
+	if !ok {
+		// Should not happen as all the messages are sent via Produce.
+		return
+	}
+	msg.Metadata = md.metadata
+
+	success := prometheusbpint.BoolString(err == nil)
+	producerMessagesCounter.WithLabelValues(msg.Topic, success).Inc()
+	producerLatencyHistogram.WithLabelValues(msg.Topic, success).Observe(time.Since(md.start).Seconds())
+	if err == nil {
+		producerBytesCounter.WithLabelValues(msg.Topic).Add(float64(messageSize(msg)))
+	}
+	md.span.FinishWithOptions(tracing.FinishOptions{
+		Ctx: md.ctx,
+		Err: err,
+	}.Convert())
+
+	if md.report != nil {
+		md.report(msg, err)
+		return
+	}
+	if err != nil && p.cfg.Logger != nil {
+		p.cfg.Logger.Log(
+			md.ctx,
+			"kafkabp.Producer: failed to deliver message to topic "+msg.Topic+": "+err.Error(),
+		)
+	}
+}
+
+func messageSize(msg *sarama.ProducerMessage) int {
+	var size int
+	if msg.Key != nil {
+		size += msg.Key.Length()
+	}
+	if msg.Value != nil {
+		size += msg.Value.Length()
+	}
+	return size
+}
+
+// setSpanHeaders sets the span headers for span onto msg,
+// replacing the existing ones.
+func setSpanHeaders(msg *sarama.ProducerMessage, span *tracing.Span) {
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+5)
+	for _, h := range msg.Headers {
+		switch string(h.Key) {
+		case transport.HeaderTracingTrace,
+			transport.HeaderTracingSpan,
+			transport.HeaderTracingParent,
+			transport.HeaderTracingSampled,
+			transport.HeaderTracingFlags:
+			continue
+		}
+		headers = append(headers, h)
+	}
+	add := func(key, value string) {
+		headers = append(headers, sarama.RecordHeader{
+			Key:   []byte(key),
+			Value: []byte(value),
+		})
+	}
+	add(transport.HeaderTracingTrace, span.TraceID())
+	add(transport.HeaderTracingSpan, span.ID())
+	add(transport.HeaderTracingFlags, strconv.FormatInt(span.Flags(), 10))
+	if span.ParentID() != "" {
+		add(transport.HeaderTracingParent, span.ParentID())
+	}
+	if span.Sampled() {
+		add(transport.HeaderTracingSampled, transport.HeaderTracingSampledTrue)
+	}
+	msg.Headers = headers
+}
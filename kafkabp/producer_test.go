@@ -0,0 +1,175 @@
+package kafkabp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+
+	"github.com/reddit/baseplate.go/transport"
+)
+
+func getTestMockProducer(t *testing.T) (*Producer, *mocks.AsyncProducer) {
+	t.Helper()
+
+	cfg := ProducerConfig{
+		Brokers:  []string{"127.0.0.1:9090", "127.0.0.2:9090"},
+		ClientID: "test-mock-producer",
+	}
+	sc, err := cfg.NewSaramaConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mp := mocks.NewAsyncProducer(t, sc)
+	return newProducer(cfg, mp), mp
+}
+
+func TestProducer_Produce(t *testing.T) {
+	p, mp := getTestMockProducer(t)
+	defer p.Close()
+
+	mp.ExpectInputAndSucceed()
+	const metadata = "metadata"
+	msg := &sarama.ProducerMessage{
+		Topic:    "kafkabp-test",
+		Key:      sarama.StringEncoder("key"),
+		Value:    sarama.StringEncoder("value"),
+		Metadata: metadata,
+	}
+	reported := make(chan error, 1)
+	if err := p.Produce(context.Background(), msg, func(m *sarama.ProducerMessage, err error) {
+		if m != msg {
+			t.Errorf("expected the produced message to be reported, got %v", m)
+		}
+		reported <- err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-reported; err != nil {
+		t.Errorf("expected delivery to succeed, got %v", err)
+	}
+	if msg.Metadata != metadata {
+		t.Errorf("expected metadata to be restored to %q, got %#v", metadata, msg.Metadata)
+	}
+
+	headers := make(map[string]string)
+	for _, h := range msg.Headers {
+		headers[string(h.Key)] = string(h.Value)
+	}
+	for _, key := range []string{
+		transport.HeaderTracingTrace,
+		transport.HeaderTracingSpan,
+		transport.HeaderTracingFlags,
+	} {
+		if headers[key] == "" {
+			t.Errorf("expected header %q to be set, got %v", key, headers)
+		}
+	}
+}
+
+func TestProducer_ProduceSync(t *testing.T) {
+	p, mp := getTestMockProducer(t)
+	defer p.Close()
+
+	mp.ExpectInputAndSucceed()
+	if err := p.ProduceSync(context.Background(), &sarama.ProducerMessage{
+		Topic: "kafkabp-test",
+		Value: sarama.StringEncoder("value"),
+	}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	kErr := errors.New("kafka error")
+	mp.ExpectInputAndFail(kErr)
+	if err := p.ProduceSync(context.Background(), &sarama.ProducerMessage{
+		Topic: "kafkabp-test",
+		Value: sarama.StringEncoder("value"),
+	}); !errors.Is(err, kErr) {
+		t.Errorf("expected error %v, got %v", kErr, err)
+	}
+}
+
+func TestProducer_Close(t *testing.T) {
+	p, mp := getTestMockProducer(t)
+
+	mp.ExpectInputAndSucceed()
+	mp.ExpectInputAndSucceed()
+	var reported int
+	for i := 0; i < 2; i++ {
+		if err := p.Produce(context.Background(), &sarama.ProducerMessage{
+			Topic: "kafkabp-test",
+			Value: sarama.StringEncoder("value"),
+		}, func(_ *sarama.ProducerMessage, err error) {
+			reported++
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Close waits for all the in-flight messages to be reported.
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if reported != 2 {
+		t.Errorf("expected 2 messages reported, got %d", reported)
+	}
+
+	if err := p.Produce(context.Background(), &sarama.ProducerMessage{
+		Topic: "kafkabp-test",
+	}, nil); !errors.Is(err, ErrProducerClosed) {
+		t.Errorf("expected error %v, got %v", ErrProducerClosed, err)
+	}
+}
+
+func TestProducer_replace(t *testing.T) {
+	p, oldMP := getTestMockProducer(t)
+
+	var lock sync.Mutex
+	var reported int
+	report := func(_ *sarama.ProducerMessage, err error) {
+		if err != nil {
+			t.Errorf("unexpected delivery error: %v", err)
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		reported++
+	}
+	produce := func(t *testing.T) {
+		t.Helper()
+		if err := p.Produce(context.Background(), &sarama.ProducerMessage{
+			Topic: "kafkabp-test",
+			Value: sarama.StringEncoder("value"),
+		}, report); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldMP.ExpectInputAndSucceed()
+	produce(t)
+
+	sc, err := p.cfg.NewSaramaConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newMP := mocks.NewAsyncProducer(t, sc)
+	newMP.ExpectInputAndSucceed()
+	p.replace(newMP)
+	produce(t)
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if reported != 2 {
+		t.Errorf("expected 2 messages reported, got %d", reported)
+	}
+
+	// Replacing after Close closes the new producer instead.
+	closedMP := mocks.NewAsyncProducer(t, nil)
+	p.replace(closedMP)
+	if p.producer != newMP {
+		t.Error("expected the producer not to be replaced after Close")
+	}
+}
@@ -0,0 +1,106 @@
+package kafkabp
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Label names of the Prometheus metrics reported by kafkabp.
+const (
+	PrometheusTopicLabel     = "kafka_topic"
+	PrometheusSuccessLabel   = "kafka_success"
+	PrometheusPartitionLabel = "kafka_partition"
+	PrometheusGroupLabel     = "kafka_group"
+)
+
+var (
+	producerMessagesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_producer_messages_total",
+		Help: "Number of the messages delivered (or failed to be delivered) by the producers",
+	}, []string{
+		PrometheusTopicLabel,
+		PrometheusSuccessLabel,
+	})
+
+	producerLatencyHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_producer_latency_seconds",
+		Help:    "Latency from producing the messages to receiving the delivery reports",
+		Buckets: prometheus.DefBuckets,
+	}, []string{
+		PrometheusTopicLabel,
+		PrometheusSuccessLabel,
+	})
+
+	producerBytesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_producer_bytes_total",
+		Help: "Total size of the keys and values of the messages delivered by the producers",
+	}, []string{
+		PrometheusTopicLabel,
+	})
+
+	consumerInFlightGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_in_flight_messages",
+		Help: "Number of the fetched messages waiting to be or being processed by the consumers",
+	}, []string{
+		PrometheusTopicLabel,
+		PrometheusPartitionLabel,
+	})
+
+	consumerConcurrencyWaitHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_consumer_concurrency_wait_seconds",
+		Help:    "Time the messages waited for a processing slot limited by ConsumerConfig.MaxConcurrency",
+		Buckets: prometheus.DefBuckets,
+	}, []string{
+		PrometheusTopicLabel,
+	})
+
+	consumerLagGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_lag",
+		Help: "Number of the messages in the partitions not yet committed (group consumers) or processed (topic consumers)",
+	}, []string{
+		PrometheusTopicLabel,
+		PrometheusPartitionLabel,
+		PrometheusGroupLabel,
+	})
+
+	consumerLastCommitAgeGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_last_commit_age_seconds",
+		Help: "Time since the committed offsets of the partitions were last seen changing",
+	}, []string{
+		PrometheusTopicLabel,
+		PrometheusPartitionLabel,
+		PrometheusGroupLabel,
+	})
+
+	consumerMessagesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_consumer_messages_total",
+		Help: "Number of the messages processed (or failed to be processed) by the ConsumerPrometheus middleware",
+	}, []string{
+		PrometheusTopicLabel,
+		PrometheusSuccessLabel,
+	})
+
+	consumerLatencyHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kafka_consumer_latency_seconds",
+		Help:    "Latency of processing the messages measured by the ConsumerPrometheus middleware",
+		Buckets: prometheus.DefBuckets,
+	}, []string{
+		PrometheusTopicLabel,
+		PrometheusSuccessLabel,
+	})
+
+	consumerPanicsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_consumer_panics_total",
+		Help: "Number of the panics recovered by the ConsumerRecoverPanic middleware",
+	}, []string{
+		PrometheusTopicLabel,
+	})
+
+	consumerLagErrorsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kafka_consumer_lag_query_errors_total",
+		Help: "Number of the failures querying the offsets to report the consumer lag",
+	}, []string{
+		PrometheusTopicLabel,
+		PrometheusGroupLabel,
+	})
+)
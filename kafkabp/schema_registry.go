@@ -0,0 +1,303 @@
+package kafkabp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SchemaType is the type of the schemas in the schema registry.
+type SchemaType string
+
+// Supported SchemaType values.
+const (
+	SchemaTypeAvro     SchemaType = "AVRO"
+	SchemaTypeProtobuf SchemaType = "PROTOBUF"
+	SchemaTypeJSON     SchemaType = "JSON"
+)
+
+// Allowed SubjectNameStrategy values
+const (
+	// SubjectNameTopic uses "<topic>-key" or "<topic>-value" as the subjects.
+	SubjectNameTopic = "topic"
+
+	// SubjectNameRecord uses the fully-qualified record names as the subjects.
+	SubjectNameRecord = "record"
+
+	// SubjectNameTopicRecord uses "<topic>-<record name>" as the subjects.
+	SubjectNameTopicRecord = "topic-record"
+)
+
+// SchemaRegistryDefaultTimeout is the default value of
+// SchemaRegistryConfig.Timeout.
+const SchemaRegistryDefaultTimeout = time.Second * 5
+
+var (
+	// ErrSchemaRegistryURLEmpty is thrown when the schema registry URL is
+	// empty.
+	ErrSchemaRegistryURLEmpty = errors.New("kafkabp: schema registry URL is empty")
+
+	// ErrSubjectNameStrategyInvalid is thrown when an invalid subject name
+	// strategy is specified.
+	ErrSubjectNameStrategyInvalid = errors.New("kafkabp: SubjectNameStrategy is invalid")
+
+	// ErrSchemaNotFound is returned by SchemaRegistry when the schema or the
+	// subject is not found in the registry.
+	ErrSchemaNotFound = errors.New("kafkabp: schema not found")
+)
+
+// SchemaRegistryConfig can be used to configure a SchemaRegistry.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//	schemaRegistry:
+//	  url: http://schema-registry:8081
+//	  timeout: 5s
+//	  subjectNameStrategy: topic
+//	  autoRegister: true
+type SchemaRegistryConfig struct {
+	// Required. The base URL of the Confluent-compatible schema registry.
+	URL string `yaml:"url"`
+
+	// Optional. The timeout of the HTTP requests to the schema registry.
+	// Defaults to SchemaRegistryDefaultTimeout.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// Optional. Defaults to "topic". Valid values are "topic", "record" and
+	// "topic-record".
+	//
+	// It's used by the Serializers to determine the subjects of the schemas.
+	SubjectNameStrategy string `yaml:"subjectNameStrategy"`
+
+	// Optional. When true, the Serializers register the schemas not yet in the
+	// registry. Otherwise they only look up the existing ones.
+	AutoRegister bool `yaml:"autoRegister"`
+}
+
+// Schema is a schema in the schema registry.
+type Schema struct {
+	ID     int
+	Type   SchemaType
+	Schema string
+}
+
+// SchemaRegistry is a client of a Confluent-compatible schema registry.
+//
+// The schemas are immutable in the registry,
+// so the results of all the lookups are cached forever.
+type SchemaRegistry struct {
+	cfg    SchemaRegistryConfig
+	client *http.Client
+
+	lock     sync.RWMutex
+	byID     map[int]Schema
+	bySchema map[subjectSchema]int
+}
+
+type subjectSchema struct {
+	subject    string
+	schemaType SchemaType
+	schema     string
+}
+
+// NewSchemaRegistry creates a SchemaRegistry.
+func NewSchemaRegistry(cfg SchemaRegistryConfig) (*SchemaRegistry, error) {
+	if cfg.URL == "" {
+		return nil, ErrSchemaRegistryURLEmpty
+	}
+	switch cfg.SubjectNameStrategy {
+	case "":
+		cfg.SubjectNameStrategy = SubjectNameTopic
+	case SubjectNameTopic, SubjectNameRecord, SubjectNameTopicRecord:
+	default:
+		return nil, ErrSubjectNameStrategyInvalid
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = SchemaRegistryDefaultTimeout
+	}
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	return &SchemaRegistry{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+		byID:     make(map[int]Schema),
+		bySchema: make(map[subjectSchema]int),
+	}, nil
+}
+
+// Subject returns the subject of the key (isKey is true) or value schema with
+// recordName on topic, according to the configured SubjectNameStrategy.
+func (r *SchemaRegistry) Subject(topic string, isKey bool, recordName string) string {
+	switch r.cfg.SubjectNameStrategy {
+	case SubjectNameRecord:
+		return recordName
+	case SubjectNameTopicRecord:
+		return topic + "-" + recordName
+	}
+	if isKey {
+		return topic + "-key"
+	}
+	return topic + "-value"
+}
+
+// SchemaByID returns the schema with id.
+func (r *SchemaRegistry) SchemaByID(ctx context.Context, id int) (Schema, error) {
+	r.lock.RLock()
+	schema, ok := r.byID[id]
+	r.lock.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	var resp schemaResponse
+	if err := r.do(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil, &resp); err != nil {
+		return Schema{}, fmt.Errorf("kafkabp: get schema %d: %w", id, err)
+	}
+	schema = Schema{
+		ID:     id,
+		Type:   resp.schemaType(),
+		Schema: resp.Schema,
+	}
+	r.lock.Lock()
+	r.byID[id] = schema
+	r.lock.Unlock()
+	return schema, nil
+}
+
+// SchemaID returns the id of schema under subject.
+//
+// When the schema is not registered under subject yet,
+// it registers it if register is true,
+// or returns ErrSchemaNotFound otherwise.
+func (r *SchemaRegistry) SchemaID(ctx context.Context, subject string, schemaType SchemaType, schema string, register bool) (int, error) {
+	key := subjectSchema{
+		subject:    subject,
+		schemaType: schemaType,
+		schema:     schema,
+	}
+	r.lock.RLock()
+	id, ok := r.bySchema[key]
+	r.lock.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	req := schemaRequest{
+		Schema: schema,
+	}
+	if schemaType != SchemaTypeAvro {
+		// AVRO is the default and omitted for compatibility with older
+		// registries.
+		req.SchemaType = string(schemaType)
+	}
+	var resp schemaResponse
+	path := "/subjects/" + url.PathEscape(subject)
+	if register {
+		path += "/versions"
+	}
+	if err := r.do(ctx, http.MethodPost, path, req, &resp); err != nil {
+		if register {
+			return 0, fmt.Errorf("kafkabp: register schema under subject %q: %w", subject, err)
+		}
+		return 0, fmt.Errorf("kafkabp: look up schema under subject %q: %w", subject, err)
+	}
+
+	r.lock.Lock()
+	r.bySchema[key] = resp.ID
+	r.byID[resp.ID] = Schema{
+		ID:     resp.ID,
+		Type:   schemaType,
+		Schema: schema,
+	}
+	r.lock.Unlock()
+	return resp.ID, nil
+}
+
+type schemaRequest struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+type schemaResponse struct {
+	ID         int    `json:"id"`
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType"`
+}
+
+func (resp schemaResponse) schemaType() SchemaType {
+	if resp.SchemaType == "" {
+		return SchemaTypeAvro
+	}
+	return SchemaType(resp.SchemaType)
+}
+
+type registryError struct {
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+// Error codes of the schema registry for the not found errors.
+const (
+	registrySubjectNotFound = 40401
+	registrySchemaNotFound  = 40403
+)
+
+const registryContentType = "application/vnd.schemaregistry.v1+json"
+
+func (r *SchemaRegistry) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.cfg.URL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", registryContentType)
+	if body != nil {
+		req.Header.Set("Content-Type", registryContentType)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	if resp.StatusCode >= 400 {
+		var regErr registryError
+		json.NewDecoder(resp.Body).Decode(&regErr)
+		if resp.StatusCode == http.StatusNotFound {
+			switch regErr.Code {
+			case registrySubjectNotFound, registrySchemaNotFound:
+				return fmt.Errorf("%w: %s", ErrSchemaNotFound, regErr.Message)
+			}
+		}
+		return fmt.Errorf(
+			"schema registry returned status code %d (error code %d): %s",
+			resp.StatusCode,
+			regErr.Code,
+			regErr.Message,
+		)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,172 @@
+package kafkabp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/reddit/baseplate.go/randbp"
+)
+
+// scramClient implements sarama.SCRAMClient (RFC 5802).
+//
+// The credentials are read from getCredentials on Begin instead of the ones
+// from sarama.Config, so that the rotated credentials are used on the new
+// connections.
+type scramClient struct {
+	hash           func() hash.Hash
+	getCredentials func() (username, password string)
+	nonce          func() (string, error)
+
+	username string
+	password string
+	authzID  string
+
+	step            int
+	clientNonce     string
+	clientFirstBare string
+	serverSignature []byte
+	done            bool
+}
+
+// newSCRAMClientGenerator returns the sarama.Config.Net.SASL.SCRAMClientGeneratorFunc
+// for mechanism.
+func newSCRAMClientGenerator(mechanism sarama.SASLMechanism, getCredentials func() (string, string)) func() sarama.SCRAMClient {
+	h := sha256.New
+	if mechanism == sarama.SASLTypeSCRAMSHA512 {
+		h = sha512.New
+	}
+	return func() sarama.SCRAMClient {
+		return &scramClient{
+			hash:           h,
+			getCredentials: getCredentials,
+			nonce:          scramNonce,
+		}
+	}
+}
+
+func scramNonce() (string, error) {
+	return randbp.SecureToken(24)
+}
+
+// scramEscaper escapes the usernames as required by RFC 5802.
+var scramEscaper = strings.NewReplacer("=", "=3D", ",", "=2C")
+
+// Begin implements sarama.SCRAMClient.
+func (c *scramClient) Begin(_, _, authzID string) error {
+	c.username, c.password = c.getCredentials()
+	c.authzID = authzID
+	c.step = 0
+	c.done = false
+	return nil
+}
+
+// Step implements sarama.SCRAMClient.
+func (c *scramClient) Step(challenge string) (string, error) {
+	c.step++
+	switch c.step {
+	case 1:
+		return c.clientFirst()
+	case 2:
+		return c.clientFinal(challenge)
+	case 3:
+		return "", c.verifyServerFinal(challenge)
+	default:
+		return "", errors.New("kafkabp: unexpected scram challenge after the exchange is done")
+	}
+}
+
+// Done implements sarama.SCRAMClient.
+func (c *scramClient) Done() bool {
+	return c.done
+}
+
+func (c *scramClient) gs2Header() string {
+	if c.authzID == "" {
+		return "n,,"
+	}
+	return "n,a=" + scramEscaper.Replace(c.authzID) + ","
+}
+
+func (c *scramClient) clientFirst() (string, error) {
+	nonce, err := c.nonce()
+	if err != nil {
+		return "", fmt.Errorf("kafkabp: failed to generate scram nonce: %w", err)
+	}
+	c.clientNonce = nonce
+	c.clientFirstBare = "n=" + scramEscaper.Replace(c.username) + ",r=" + nonce
+	return c.gs2Header() + c.clientFirstBare, nil
+}
+
+func parseSCRAMAttributes(msg string) map[string]string {
+	attrs := make(map[string]string)
+	for _, field := range strings.Split(msg, ",") {
+		if len(field) < 2 || field[1] != '=' {
+			continue
+		}
+		attrs[field[:1]] = field[2:]
+	}
+	return attrs
+}
+
+func (c *scramClient) clientFinal(serverFirst string) (string, error) {
+	attrs := parseSCRAMAttributes(serverFirst)
+	nonce := attrs["r"]
+	if !strings.HasPrefix(nonce, c.clientNonce) {
+		return "", errors.New("kafkabp: scram server nonce does not match the client nonce")
+	}
+	salt, err := base64.StdEncoding.DecodeString(attrs["s"])
+	if err != nil {
+		return "", fmt.Errorf("kafkabp: invalid scram salt: %w", err)
+	}
+	iterations, err := strconv.Atoi(attrs["i"])
+	if err != nil || iterations <= 0 {
+		return "", fmt.Errorf("kafkabp: invalid scram iteration count %q", attrs["i"])
+	}
+
+	salted := pbkdf2.Key([]byte(c.password), salt, iterations, c.hash().Size(), c.hash)
+	clientKey := c.hmac(salted, "Client Key")
+	h := c.hash()
+	h.Write(clientKey)
+	storedKey := h.Sum(nil)
+
+	withoutProof := "c=" + base64.StdEncoding.EncodeToString([]byte(c.gs2Header())) + ",r=" + nonce
+	authMessage := c.clientFirstBare + "," + serverFirst + "," + withoutProof
+	proof := c.hmac(storedKey, authMessage)
+	for i := range proof {
+		proof[i] ^= clientKey[i]
+	}
+	c.serverSignature = c.hmac(c.hmac(salted, "Server Key"), authMessage)
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+func (c *scramClient) verifyServerFinal(serverFinal string) error {
+	attrs := parseSCRAMAttributes(serverFinal)
+	if e, ok := attrs["e"]; ok {
+		return fmt.Errorf("kafkabp: scram authentication failed: %s", e)
+	}
+	signature, err := base64.StdEncoding.DecodeString(attrs["v"])
+	if err != nil {
+		return fmt.Errorf("kafkabp: invalid scram server signature: %w", err)
+	}
+	if !hmac.Equal(signature, c.serverSignature) {
+		return errors.New("kafkabp: scram server signature mismatch")
+	}
+	c.done = true
+	return nil
+}
+
+func (c *scramClient) hmac(key []byte, msg string) []byte {
+	mac := hmac.New(c.hash, key)
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,86 @@
+package kafkabp
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestSCRAMClient(t *testing.T) {
+	// Test vectors from RFC 7677.
+	const (
+		clientNonce = "rOprNGfwEbeRWgbNEkqO"
+		clientFirst = "n,,n=user,r=rOprNGfwEbeRWgbNEkqO"
+		serverFirst = "r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096"
+		clientFinal = "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ="
+		serverFinal = "v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="
+	)
+
+	newClient := func() *scramClient {
+		c := newSCRAMClientGenerator(sarama.SASLTypeSCRAMSHA256, func() (string, string) {
+			return "user", "pencil"
+		})().(*scramClient)
+		c.nonce = func() (string, error) {
+			return clientNonce, nil
+		}
+		// The credentials from sarama.Config are ignored.
+		if err := c.Begin("ignored", "ignored", ""); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	t.Run("success", func(t *testing.T) {
+		c := newClient()
+		for _, step := range []struct {
+			challenge, expected string
+		}{
+			{challenge: "", expected: clientFirst},
+			{challenge: serverFirst, expected: clientFinal},
+			{challenge: serverFinal, expected: ""},
+		} {
+			if c.Done() {
+				t.Fatal("Expected the exchange not done yet")
+			}
+			resp, err := c.Step(step.challenge)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp != step.expected {
+				t.Errorf("Expected response %q, got %q", step.expected, resp)
+			}
+		}
+		if !c.Done() {
+			t.Error("Expected the exchange to be done")
+		}
+	})
+
+	t.Run("wrong-server-signature", func(t *testing.T) {
+		c := newClient()
+		c.Step("")
+		c.Step(serverFirst)
+		if _, err := c.Step("v=AAAA"); err == nil {
+			t.Error("Expected error, got nil")
+		}
+		if c.Done() {
+			t.Error("Expected the exchange not done")
+		}
+	})
+
+	t.Run("server-error", func(t *testing.T) {
+		c := newClient()
+		c.Step("")
+		c.Step(serverFirst)
+		if _, err := c.Step("e=invalid-proof"); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+
+	t.Run("wrong-nonce", func(t *testing.T) {
+		c := newClient()
+		c.Step("")
+		if _, err := c.Step("r=foo,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096"); err == nil {
+			t.Error("Expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,466 @@
+package kafkabp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/reddit/baseplate.go/internal/clientsecrets"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+// Allowed SASLMechanism values
+const (
+	SASLMechanismPlain       = sarama.SASLTypePlaintext
+	SASLMechanismSCRAMSHA256 = sarama.SASLTypeSCRAMSHA256
+	SASLMechanismSCRAMSHA512 = sarama.SASLTypeSCRAMSHA512
+)
+
+var (
+	// ErrSASLMechanismInvalid is thrown when an invalid SASL mechanism is
+	// specified.
+	ErrSASLMechanismInvalid = errors.New("kafkabp: SASLMechanism is invalid")
+
+	// ErrSASLCredentialsEmpty is thrown when the SASL mechanism is specified
+	// without the credentials.
+	ErrSASLCredentialsEmpty = errors.New("kafkabp: SASLMechanism requires Credentials")
+)
+
+// SecretsConfig configures the SASL credentials and TLS material to be read
+// from the secrets Store,
+// by NewSaramaConfigWithSecrets, NewConsumerWithSecrets and
+// NewProducerWithSecrets.
+//
+// The rotated SCRAM credentials and TLS material are used by the new
+// connections without the need to rebuild the consumers/producers.
+//
+// The PLAIN credentials are read from the secrets Store only when the
+// sarama.Config is created, as sarama doesn't allow them to be changed
+// afterwards, so rotating them needs the consumers/producers to be rebuilt
+// (or the service to be restarted).
+// The ones created by NewConsumerWithSecrets and NewProducerWithSecrets are
+// rebuilt automatically when the credential secret changes.
+//
+// All the fields are optional.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//	kafka:
+//	  brokers:
+//	    - 127.0.0.1:9093
+//	  clientID: myclient
+//	  secrets:
+//	    saslMechanism: SCRAM-SHA-512
+//	    credentials: secret/myservice/kafka-credentials
+//	    tls: true
+//	    caCert: secret/myservice/kafka-ca
+type SecretsConfig struct {
+	// SASLMechanism is the SASL mechanism to authenticate with the brokers.
+	// Valid values are "PLAIN", "SCRAM-SHA-256" and "SCRAM-SHA-512".
+	//
+	// When it's empty, SASL is not used.
+	SASLMechanism string `yaml:"saslMechanism"`
+
+	// Credentials is the path of a credential secret used by SASL.
+	//
+	// Required when SASLMechanism is non-empty.
+	Credentials string `yaml:"credentials"`
+
+	// TLS enables TLS to connect to the brokers.
+	//
+	// It's implied when any of CACert, ClientCert and ClientKey is set.
+	TLS bool `yaml:"tls"`
+
+	// CACert is the path of a simple secret containing the PEM encoded CA
+	// certificates used to verify the brokers.
+	//
+	// Optional, the system CA pool is used when it's empty.
+	CACert string `yaml:"caCert"`
+
+	// ClientCert and ClientKey are the paths of the simple secrets containing
+	// the PEM encoded client certificate and key for mutual TLS.
+	//
+	// They must be both set or both empty.
+	ClientCert string `yaml:"clientCert"`
+	ClientKey  string `yaml:"clientKey"`
+}
+
+// IsEmpty returns true if none of the secrets are configured.
+func (cfg SecretsConfig) IsEmpty() bool {
+	return cfg == SecretsConfig{}
+}
+
+func (cfg SecretsConfig) hasTLS() bool {
+	return cfg.TLS || cfg.clientSecrets().HasTLS()
+}
+
+func (cfg SecretsConfig) clientSecrets() clientsecrets.Config {
+	return clientsecrets.Config{
+		Credentials: cfg.Credentials,
+		CACert:      cfg.CACert,
+		ClientCert:  cfg.ClientCert,
+		ClientKey:   cfg.ClientKey,
+	}
+}
+
+// Validate checks SecretsConfig for any erroneous values.
+func (cfg SecretsConfig) Validate() error {
+	switch cfg.SASLMechanism {
+	case "":
+	case SASLMechanismPlain, SASLMechanismSCRAMSHA256, SASLMechanismSCRAMSHA512:
+		if cfg.Credentials == "" {
+			return ErrSASLCredentialsEmpty
+		}
+	default:
+		return ErrSASLMechanismInvalid
+	}
+	return cfg.clientSecrets().Validate("kafkabp")
+}
+
+// secretsProvider applies the latest secrets from the secrets Store to the new
+// connections.
+type secretsProvider struct {
+	*clientsecrets.Provider
+
+	cfg SecretsConfig
+
+	// unsubscribe is set by onPlainRotation.
+	unsubscribe func()
+}
+
+// newSecretsProvider loads the secrets from store,
+// and registers a middleware to the store to reload them on rotation.
+func newSecretsProvider(store *secrets.Store, cfg SecretsConfig) (*secretsProvider, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	p, err := clientsecrets.New("kafkabp", store, cfg.clientSecrets())
+	if err != nil {
+		return nil, err
+	}
+	return &secretsProvider{
+		Provider: p,
+		cfg:      cfg,
+	}, nil
+}
+
+// apply applies the secrets to the sarama.Config.
+func (p *secretsProvider) apply(c *sarama.Config) {
+	if p.cfg.SASLMechanism != "" {
+		m := p.Current()
+		c.Net.SASL.Enable = true
+		c.Net.SASL.Handshake = true
+		c.Net.SASL.Mechanism = sarama.SASLMechanism(p.cfg.SASLMechanism)
+		// For SCRAM they are only used by sarama.Config.Validate,
+		// the actual credentials come from p on every new connection.
+		c.Net.SASL.User = m.Username
+		c.Net.SASL.Password = m.Password
+		if p.cfg.SASLMechanism != SASLMechanismPlain {
+			c.Net.SASL.Version = sarama.SASLHandshakeV1
+			c.Net.SASL.SCRAMClientGeneratorFunc = newSCRAMClientGenerator(
+				c.Net.SASL.Mechanism,
+				p.Credentials,
+			)
+		}
+	}
+	if p.cfg.hasTLS() {
+		c.Net.TLS.Enable = true
+		c.Net.TLS.Config = p.TLSConfig(nil)
+	}
+}
+
+// saramaConfig returns the sarama.Config from newConfig with the latest
+// secrets applied.
+func (p *secretsProvider) saramaConfig(newConfig func() (*sarama.Config, error)) (*sarama.Config, error) {
+	c, err := newConfig()
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return c, nil
+	}
+	p.apply(c)
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("kafkabp: invalid config with secrets: %w", err)
+	}
+	return c, nil
+}
+
+// onPlainRotation calls rebuild in a new goroutine every time the PLAIN
+// credentials change in store, until p is closed.
+//
+// The calls to rebuild are serialized.
+// It's a no-op with the other SASL mechanisms.
+func (p *secretsProvider) onPlainRotation(store *secrets.Store, rebuild func()) {
+	if p == nil || p.cfg.SASLMechanism != SASLMechanismPlain {
+		return
+	}
+	var lock sync.Mutex
+	p.unsubscribe = store.Subscribe(func(old, new *secrets.Secrets) {
+		for _, path := range secrets.ChangedPaths(old, new) {
+			if path == p.cfg.Credentials {
+				go func() {
+					lock.Lock()
+					defer lock.Unlock()
+					rebuild()
+				}()
+				return
+			}
+		}
+	})
+}
+
+// Close stops reloading the secrets on rotation and rebuilding on the PLAIN
+// credentials rotation.
+//
+// It's OK to call Close on a nil *secretsProvider.
+func (p *secretsProvider) Close() error {
+	if p == nil {
+		return nil
+	}
+	if p.unsubscribe != nil {
+		p.unsubscribe()
+	}
+	return p.Provider.Close()
+}
+
+// newSaramaConfigWithSecrets returns the sarama.Config from newConfig with the
+// secrets configured by cfg read from store.
+//
+// The returned *secretsProvider is nil when cfg is empty.
+func newSaramaConfigWithSecrets(newConfig func() (*sarama.Config, error), store *secrets.Store, cfg SecretsConfig) (*sarama.Config, *secretsProvider, error) {
+	if cfg.IsEmpty() {
+		c, err := newConfig()
+		return c, nil, err
+	}
+	p, err := newSecretsProvider(store, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := p.saramaConfig(newConfig)
+	if err != nil {
+		p.Close()
+		return nil, nil, err
+	}
+	return c, p, nil
+}
+
+// NewSaramaConfigWithSecrets is the same as NewSaramaConfig,
+// with the secrets configured by cfg.Secrets (see SecretsConfig) read from
+// store.
+//
+// The returned io.Closer stops the secrets from being reloaded on rotation,
+// it should be closed after closing the consumers/producers created with the
+// returned config.
+// The consumers/producers created with the returned config are not rebuilt on
+// the PLAIN credentials rotation,
+// use NewConsumerWithSecrets instead for that.
+//
+// It calls store.AddMiddleware, so it must not be called from within a
+// middleware of the same store.
+// The same applies to closing the returned io.Closer.
+func (cfg *ConsumerConfig) NewSaramaConfigWithSecrets(store *secrets.Store) (*sarama.Config, io.Closer, error) {
+	c, p, err := newSaramaConfigWithSecrets(cfg.NewSaramaConfig, store, cfg.Secrets)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p == nil {
+		return c, clientsecrets.NopCloser, nil
+	}
+	return c, p, nil
+}
+
+// NewSaramaConfigWithSecrets is the same as NewSaramaConfig,
+// with the secrets configured by cfg.Secrets (see SecretsConfig) read from
+// store.
+//
+// See ConsumerConfig.NewSaramaConfigWithSecrets for more details,
+// use NewProducerWithSecrets to rebuild the producer on the PLAIN credentials
+// rotation.
+func (cfg *ProducerConfig) NewSaramaConfigWithSecrets(store *secrets.Store) (*sarama.Config, io.Closer, error) {
+	c, p, err := newSaramaConfigWithSecrets(cfg.NewSaramaConfig, store, cfg.Secrets)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p == nil {
+		return c, clientsecrets.NopCloser, nil
+	}
+	return c, p, nil
+}
+
+// secretsConsumer is the Consumer returned by NewConsumerWithSecrets,
+// which replaces the underlying Consumer with a new one built by build on the
+// PLAIN credentials rotation.
+type secretsConsumer struct {
+	build   func() (Consumer, error)
+	secrets io.Closer
+
+	lock     sync.Mutex
+	consumer Consumer
+	paused   bool
+	closed   bool
+}
+
+// rebuild replaces the underlying Consumer with a new one,
+// the Consume call in progress, if any, switches to the new one.
+func (c *secretsConsumer) rebuild() {
+	consumer, err := c.build()
+	if err != nil {
+		log.Errorw(
+			"kafkabp: failed to rebuild the consumer with the rotated credentials, keep using the old one",
+			"err", err,
+		)
+		return
+	}
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		consumer.Close()
+		return
+	}
+	old := c.consumer
+	c.consumer = consumer
+	if c.paused {
+		consumer.Pause()
+	}
+	c.lock.Unlock()
+
+	old.Close()
+}
+
+func (c *secretsConsumer) current() Consumer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.consumer
+}
+
+// Consume implements Consumer.
+func (c *secretsConsumer) Consume(messagesFunc ConsumeMessageFunc, errorsFunc ConsumeErrorFunc) error {
+	for {
+		consumer := c.current()
+		err := consumer.Consume(messagesFunc, errorsFunc)
+
+		c.lock.Lock()
+		rebuilt := !c.closed && c.consumer != consumer
+		c.lock.Unlock()
+		if !rebuilt {
+			return err
+		}
+	}
+}
+
+// Close implements Consumer.
+func (c *secretsConsumer) Close() error {
+	c.lock.Lock()
+	c.closed = true
+	consumer := c.consumer
+	c.lock.Unlock()
+
+	err := consumer.Close()
+	c.secrets.Close()
+	return err
+}
+
+// IsHealthy implements Consumer.
+func (c *secretsConsumer) IsHealthy(ctx context.Context) bool {
+	return c.current().IsHealthy(ctx)
+}
+
+// Pause implements Consumer.
+func (c *secretsConsumer) Pause() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.paused = true
+	c.consumer.Pause()
+}
+
+// Resume implements Consumer.
+func (c *secretsConsumer) Resume() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.paused = false
+	c.consumer.Resume()
+}
+
+// IsPaused implements Consumer.
+func (c *secretsConsumer) IsPaused() bool {
+	return c.current().IsPaused()
+}
+
+// NewConsumerWithSecrets is the same as NewConsumer,
+// with the secrets configured by cfg.Secrets (see SecretsConfig) read from
+// store.
+//
+// The consumer is rebuilt on the PLAIN credentials rotation,
+// and the Consume call in progress switches to the new one.
+//
+// The secrets are no longer reloaded on rotation after the returned Consumer
+// is closed, so Close must not be called from within a middleware of store.
+func NewConsumerWithSecrets(cfg ConsumerConfig, store *secrets.Store) (Consumer, error) {
+	sc, p, err := newSaramaConfigWithSecrets(cfg.NewSaramaConfig, store, cfg.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := newConsumer(cfg, sc)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	c := &secretsConsumer{
+		build: func() (Consumer, error) {
+			sc, err := p.saramaConfig(cfg.NewSaramaConfig)
+			if err != nil {
+				return nil, err
+			}
+			return newConsumer(cfg, sc)
+		},
+		secrets:  p,
+		consumer: consumer,
+	}
+	p.onPlainRotation(store, c.rebuild)
+	return withHealthChecker(cfg, c), nil
+}
+
+// NewProducerWithSecrets is the same as NewProducer,
+// with the secrets configured by cfg.Secrets (see SecretsConfig) read from
+// store.
+//
+// The underlying sarama producer is rebuilt on the PLAIN credentials rotation,
+// the messages in flight are still reported.
+//
+// The secrets are no longer reloaded on rotation after the returned Producer
+// is closed, so Close must not be called from within a middleware of store.
+func NewProducerWithSecrets(cfg ProducerConfig, store *secrets.Store) (*Producer, error) {
+	sc, p, err := newSaramaConfigWithSecrets(cfg.NewSaramaConfig, store, cfg.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	producer, err := newProducerFromSaramaConfig(cfg, sc)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	producer.secrets = p
+	p.onPlainRotation(store, func() {
+		sc, err := p.saramaConfig(cfg.NewSaramaConfig)
+		if err == nil {
+			var ap sarama.AsyncProducer
+			if ap, err = sarama.NewAsyncProducer(cfg.Brokers, sc); err == nil {
+				producer.replace(ap)
+				return
+			}
+		}
+		log.Errorw(
+			"kafkabp: failed to rebuild the producer with the rotated credentials, keep using the old one",
+			"err", err,
+		)
+	})
+	return producer, nil
+}
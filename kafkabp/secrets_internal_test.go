@@ -0,0 +1,172 @@
+package kafkabp
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+// fakeConsumer is a Consumer with its Consume call blocking until Close.
+type fakeConsumer struct {
+	closed chan struct{}
+	once   sync.Once
+
+	lock   sync.Mutex
+	paused bool
+}
+
+func newFakeConsumer() *fakeConsumer {
+	return &fakeConsumer{closed: make(chan struct{})}
+}
+
+func (c *fakeConsumer) Close() error {
+	c.once.Do(func() {
+		close(c.closed)
+	})
+	return nil
+}
+
+func (c *fakeConsumer) Consume(ConsumeMessageFunc, ConsumeErrorFunc) error {
+	<-c.closed
+	return nil
+}
+
+func (c *fakeConsumer) IsHealthy(context.Context) bool {
+	select {
+	case <-c.closed:
+		return false
+	default:
+		return true
+	}
+}
+
+func (c *fakeConsumer) Pause() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.paused = true
+}
+
+func (c *fakeConsumer) Resume() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.paused = false
+}
+
+func (c *fakeConsumer) IsPaused() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.paused
+}
+
+func TestSecretsConsumerPlainRotation(t *testing.T) {
+	const credentialsPath = "secret/kafka/credentials"
+	store, err := secrets.NewTestStore(map[string]secrets.GenericSecret{
+		credentialsPath: {Type: "credential", Username: "old", Password: "password"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	cfg := SecretsConfig{
+		SASLMechanism: SASLMechanismPlain,
+		Credentials:   credentialsPath,
+	}
+	p, err := newSecretsProvider(store.Store, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	built := make(chan *fakeConsumer, 1)
+	newConfig := (&ConsumerConfig{
+		Brokers:  []string{"127.0.0.1:9093"},
+		Topic:    "test-topic",
+		ClientID: "i-am-unique",
+	}).NewSaramaConfig
+	first := newFakeConsumer()
+	c := &secretsConsumer{
+		build: func() (Consumer, error) {
+			sc, err := p.saramaConfig(newConfig)
+			if err != nil {
+				return nil, err
+			}
+			if sc.Net.SASL.User != "new" {
+				t.Errorf("Expected the rotated user %q, got %q", "new", sc.Net.SASL.User)
+			}
+			fc := newFakeConsumer()
+			built <- fc
+			return fc, nil
+		},
+		secrets:  p,
+		consumer: first,
+	}
+	p.onPlainRotation(store.Store, c.rebuild)
+
+	consumeReturned := make(chan error, 1)
+	go func() {
+		consumeReturned <- c.Consume(nil, nil)
+	}()
+	c.Pause()
+
+	// Changing the other secrets doesn't rebuild the consumer.
+	if err := store.Set("secret/kafka/other", secrets.GenericSecret{Type: "simple", Value: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(credentialsPath, secrets.GenericSecret{Type: "credential", Username: "new", Password: "password"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var second *fakeConsumer
+	select {
+	case second = <-built:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the consumer to be rebuilt")
+	}
+	select {
+	case <-first.closed:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the old consumer to be closed")
+	}
+	select {
+	case <-built:
+		t.Fatal("Expected only one rebuild")
+	default:
+	}
+	if !second.IsPaused() {
+		t.Error("Expected the pause to be applied to the new consumer")
+	}
+
+	// Consume switches to the new consumer, and returns after Close.
+	select {
+	case err := <-consumeReturned:
+		t.Fatalf("Expected Consume to keep running, returned %v", err)
+	default:
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-consumeReturned:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Consume to return after Close")
+	}
+	if second.IsHealthy(context.Background()) {
+		t.Error("Expected the new consumer to be closed")
+	}
+
+	// No more rebuilds after Close.
+	if err := store.Set(credentialsPath, secrets.GenericSecret{Type: "credential", Username: "newer", Password: "password"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-built:
+		t.Error("Expected no rebuilds after Close")
+	case <-time.After(time.Millisecond * 10):
+	}
+}
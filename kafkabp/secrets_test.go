@@ -0,0 +1,299 @@
+package kafkabp_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"github.com/reddit/baseplate.go/kafkabp"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+const (
+	credentialsPath = "secret/kafka/credentials"
+	caCertPath      = "secret/kafka/ca"
+	clientCertPath  = "secret/kafka/cert"
+	clientKeyPath   = "secret/kafka/key"
+)
+
+func credentialSecret(username, password string) secrets.GenericSecret {
+	return secrets.GenericSecret{
+		Type:     "credential",
+		Username: username,
+		Password: password,
+	}
+}
+
+// scramUsername starts a SCRAM exchange with sc and returns the username in
+// the client first message.
+func scramUsername(t *testing.T, sc *sarama.Config) string {
+	t.Helper()
+
+	client := sc.Net.SASL.SCRAMClientGeneratorFunc()
+	if err := client.Begin(sc.Net.SASL.User, sc.Net.SASL.Password, ""); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := client.Step("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range strings.Split(msg, ",") {
+		if strings.HasPrefix(field, "n=") {
+			return strings.TrimPrefix(field, "n=")
+		}
+	}
+	t.Fatalf("No username in the client first message %q", msg)
+	return ""
+}
+
+func TestConsumerConfigWithSecretsSCRAMRotation(t *testing.T) {
+	store, fw, err := secrets.NewTestSecrets(context.Background(), map[string]secrets.GenericSecret{
+		credentialsPath: credentialSecret("old", "password"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := kafkabp.ConsumerConfig{
+		Brokers:  []string{"127.0.0.1:9093"},
+		Topic:    "test-topic",
+		ClientID: "i-am-unique",
+		Secrets: kafkabp.SecretsConfig{
+			SASLMechanism: kafkabp.SASLMechanismSCRAMSHA512,
+			Credentials:   credentialsPath,
+		},
+	}
+	sc, closer, err := cfg.NewSaramaConfigWithSecrets(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.Net.SASL.Enable {
+		t.Error("Expected SASL to be enabled")
+	}
+	if sc.Net.SASL.Mechanism != sarama.SASLTypeSCRAMSHA512 {
+		t.Errorf("Expected mechanism %q, got %q", sarama.SASLTypeSCRAMSHA512, sc.Net.SASL.Mechanism)
+	}
+	if sc.Net.TLS.Enable {
+		t.Error("Expected TLS to be disabled")
+	}
+	if got := scramUsername(t, sc); got != "old" {
+		t.Errorf("Expected username %q, got %q", "old", got)
+	}
+
+	if err := secrets.UpdateTestSecrets(fw, map[string]secrets.GenericSecret{
+		credentialsPath: credentialSecret("new", "password"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 5)
+	if got := scramUsername(t, sc); got != "new" {
+		t.Errorf("Expected the rotated username %q, got %q", "new", got)
+	}
+
+	// Broken rotations are ignored.
+	if err := secrets.UpdateTestSecrets(fw, map[string]secrets.GenericSecret{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 5)
+	if got := scramUsername(t, sc); got != "new" {
+		t.Errorf("Expected the username %q to be kept, got %q", "new", got)
+	}
+
+	// No more rotations after closing.
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := secrets.UpdateTestSecrets(fw, map[string]secrets.GenericSecret{
+		credentialsPath: credentialSecret("newer", "password"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 5)
+	if got := scramUsername(t, sc); got != "new" {
+		t.Errorf("Expected the username %q after closing, got %q", "new", got)
+	}
+}
+
+func TestProducerConfigWithSecretsPlain(t *testing.T) {
+	store, err := secrets.NewTestStore(map[string]secrets.GenericSecret{
+		credentialsPath: credentialSecret("user", "password"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	cfg := kafkabp.ProducerConfig{
+		Brokers:  []string{"127.0.0.1:9093"},
+		ClientID: "i-am-unique",
+		Secrets: kafkabp.SecretsConfig{
+			SASLMechanism: kafkabp.SASLMechanismPlain,
+			Credentials:   credentialsPath,
+		},
+	}
+	sc, closer, err := cfg.NewSaramaConfigWithSecrets(store.Store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	if !sc.Net.SASL.Enable {
+		t.Error("Expected SASL to be enabled")
+	}
+	if sc.Net.SASL.Mechanism != sarama.SASLTypePlaintext {
+		t.Errorf("Expected mechanism %q, got %q", sarama.SASLTypePlaintext, sc.Net.SASL.Mechanism)
+	}
+	if sc.Net.SASL.User != "user" || sc.Net.SASL.Password != "password" {
+		t.Errorf("Expected the credentials to be set, got %q/%q", sc.Net.SASL.User, sc.Net.SASL.Password)
+	}
+	if sc.Net.SASL.SCRAMClientGeneratorFunc != nil {
+		t.Error("Expected no SCRAM client generator for PLAIN")
+	}
+}
+
+func TestConfigWithSecretsErrors(t *testing.T) {
+	store, _, err := secrets.NewTestSecrets(context.Background(), map[string]secrets.GenericSecret{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		label string
+		cfg   kafkabp.SecretsConfig
+		err   error
+	}{
+		{
+			label: "invalid-mechanism",
+			cfg:   kafkabp.SecretsConfig{SASLMechanism: "GSSAPI", Credentials: credentialsPath},
+			err:   kafkabp.ErrSASLMechanismInvalid,
+		},
+		{
+			label: "plain-without-credentials",
+			cfg:   kafkabp.SecretsConfig{SASLMechanism: kafkabp.SASLMechanismPlain},
+			err:   kafkabp.ErrSASLCredentialsEmpty,
+		},
+		{
+			label: "mechanism-without-credentials",
+			cfg:   kafkabp.SecretsConfig{SASLMechanism: kafkabp.SASLMechanismSCRAMSHA256},
+			err:   kafkabp.ErrSASLCredentialsEmpty,
+		},
+		{
+			label: "missing-credentials",
+			cfg:   kafkabp.SecretsConfig{SASLMechanism: kafkabp.SASLMechanismSCRAMSHA256, Credentials: credentialsPath},
+		},
+		{
+			label: "missing-ca",
+			cfg:   kafkabp.SecretsConfig{CACert: caCertPath},
+		},
+		{
+			label: "cert-without-key",
+			cfg:   kafkabp.SecretsConfig{ClientCert: clientCertPath},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			cfg := kafkabp.ProducerConfig{
+				Brokers:  []string{"127.0.0.1:9093"},
+				ClientID: "i-am-unique",
+				Secrets:  c.cfg,
+			}
+			_, _, err := cfg.NewSaramaConfigWithSecrets(store)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if c.err != nil && !errors.Is(err, c.err) {
+				t.Errorf("Expected error %v, got %v", c.err, err)
+			}
+		})
+	}
+}
+
+// generateCert generates a self-signed certificate,
+// returning its PEM encoded certificate and key.
+func generateCert(t *testing.T) (certPEM, keyPEM string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kafka"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestConsumerConfigWithSecretsClientCertRotation(t *testing.T) {
+	oldCert, oldKey := generateCert(t)
+	newCert, newKey := generateCert(t)
+	store, fw, err := secrets.NewTestSecrets(context.Background(), map[string]secrets.GenericSecret{
+		clientCertPath: {Type: "simple", Value: oldCert},
+		clientKeyPath:  {Type: "simple", Value: oldKey},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := kafkabp.ConsumerConfig{
+		Brokers:  []string{"127.0.0.1:9093"},
+		Topic:    "test-topic",
+		ClientID: "i-am-unique",
+		Secrets: kafkabp.SecretsConfig{
+			ClientCert: clientCertPath,
+			ClientKey:  clientKeyPath,
+		},
+	}
+	sc, closer, err := cfg.NewSaramaConfigWithSecrets(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	if !sc.Net.TLS.Enable {
+		t.Fatal("Expected TLS to be enabled")
+	}
+	if sc.Net.SASL.Enable {
+		t.Error("Expected SASL to be disabled")
+	}
+
+	checkCert := func(t *testing.T, expected string) {
+		t.Helper()
+		cert, err := sc.Net.TLS.Config.GetClientCertificate(&tls.CertificateRequestInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, _ := pem.Decode([]byte(expected))
+		if string(cert.Certificate[0]) != string(block.Bytes) {
+			t.Error("Expected the latest client certificate to be used")
+		}
+	}
+	checkCert(t, oldCert)
+
+	if err := secrets.UpdateTestSecrets(fw, map[string]secrets.GenericSecret{
+		clientCertPath: {Type: "simple", Value: newCert},
+		clientKeyPath:  {Type: "simple", Value: newKey},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 5)
+	checkCert(t, newCert)
+}
@@ -0,0 +1,291 @@
+package kafkabp
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrInvalidWireFormat is returned by Deserializer when the data is not in the
+// Confluent wire format (a zero magic byte followed by the 4-byte schema id).
+var ErrInvalidWireFormat = errors.New("kafkabp: invalid schema registry wire format")
+
+const (
+	wireMagicByte  = 0
+	wireHeaderSize = 1 + 4
+)
+
+// Codec marshals and unmarshals the values with the schemas of a SchemaType.
+type Codec interface {
+	// SchemaType returns the type of the schemas used by the codec.
+	SchemaType() SchemaType
+
+	// SchemaOf returns the schema of v, and its fully-qualified record name
+	// used by the "record" and "topic-record" subject name strategies.
+	SchemaOf(v interface{}) (schema string, recordName string, err error)
+
+	// Marshal encodes v with the schema returned by SchemaOf.
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal decodes data written with writer schema into v.
+	Unmarshal(data []byte, writer Schema, v interface{}) error
+}
+
+// Serializer serializes the values into the Confluent wire format,
+// with the schemas looked up (or registered) in the SchemaRegistry.
+type Serializer struct {
+	registry *SchemaRegistry
+	codec    Codec
+}
+
+// NewSerializer creates a Serializer.
+func NewSerializer(registry *SchemaRegistry, codec Codec) *Serializer {
+	return &Serializer{
+		registry: registry,
+		codec:    codec,
+	}
+}
+
+// Serialize serializes v as the key (isKey is true) or the value of a message
+// to topic.
+func (s *Serializer) Serialize(ctx context.Context, topic string, isKey bool, v interface{}) ([]byte, error) {
+	schema, recordName, err := s.codec.SchemaOf(v)
+	if err != nil {
+		return nil, err
+	}
+	id, err := s.registry.SchemaID(
+		ctx,
+		s.registry.Subject(topic, isKey, recordName),
+		s.codec.SchemaType(),
+		schema,
+		s.registry.cfg.AutoRegister,
+	)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := s.codec.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("kafkabp: marshal %s: %w", recordName, err)
+	}
+
+	data := make([]byte, wireHeaderSize, wireHeaderSize+len(payload))
+	data[0] = wireMagicByte
+	binary.BigEndian.PutUint32(data[1:wireHeaderSize], uint32(id))
+	return append(data, payload...), nil
+}
+
+// Deserializer deserializes the values in the Confluent wire format,
+// with the writer schemas fetched from the SchemaRegistry.
+type Deserializer struct {
+	registry *SchemaRegistry
+	codec    Codec
+}
+
+// NewDeserializer creates a Deserializer.
+func NewDeserializer(registry *SchemaRegistry, codec Codec) *Deserializer {
+	return &Deserializer{
+		registry: registry,
+		codec:    codec,
+	}
+}
+
+// Deserialize deserializes data into v.
+func (d *Deserializer) Deserialize(ctx context.Context, data []byte, v interface{}) error {
+	if len(data) < wireHeaderSize || data[0] != wireMagicByte {
+		return ErrInvalidWireFormat
+	}
+	id := int(binary.BigEndian.Uint32(data[1:wireHeaderSize]))
+	schema, err := d.registry.SchemaByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if schema.Type != d.codec.SchemaType() {
+		return fmt.Errorf(
+			"kafkabp: schema %d is of type %s, expected %s",
+			id,
+			schema.Type,
+			d.codec.SchemaType(),
+		)
+	}
+	if err := d.codec.Unmarshal(data[wireHeaderSize:], schema, v); err != nil {
+		return fmt.Errorf("kafkabp: unmarshal with schema %d: %w", id, err)
+	}
+	return nil
+}
+
+// JSONCodec is a Codec for JSON schemas using encoding/json.
+//
+// The values are not validated against the schemas.
+type JSONCodec struct {
+	// The JSON schema of the values.
+	Schema string
+
+	// The record name of the values used by the "record" and "topic-record"
+	// subject name strategies.
+	RecordName string
+}
+
+var _ Codec = JSONCodec{}
+
+// SchemaType implements Codec.
+func (JSONCodec) SchemaType() SchemaType {
+	return SchemaTypeJSON
+}
+
+// SchemaOf implements Codec.
+func (c JSONCodec) SchemaOf(interface{}) (schema string, recordName string, err error) {
+	return c.Schema, c.RecordName, nil
+}
+
+// Marshal implements Codec.
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements Codec.
+func (JSONCodec) Unmarshal(data []byte, _ Schema, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// ProtobufCodec is a Codec for Protobuf schemas.
+//
+// The values must be proto.Message.
+// The record names are the full names of the messages.
+type ProtobufCodec struct {
+	// The .proto definitions of the messages, by their full names.
+	Schemas map[string]string
+}
+
+var _ Codec = ProtobufCodec{}
+
+// SchemaType implements Codec.
+func (ProtobufCodec) SchemaType() SchemaType {
+	return SchemaTypeProtobuf
+}
+
+// SchemaOf implements Codec.
+func (c ProtobufCodec) SchemaOf(v interface{}) (schema string, recordName string, err error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return "", "", fmt.Errorf("kafkabp: %T is not a proto.Message", v)
+	}
+	recordName = string(msg.ProtoReflect().Descriptor().FullName())
+	schema, ok = c.Schemas[recordName]
+	if !ok {
+		return "", "", fmt.Errorf("kafkabp: no protobuf schema for %s", recordName)
+	}
+	return schema, recordName, nil
+}
+
+// Marshal implements Codec.
+//
+// The message indexes (the path of the message in the .proto file) are
+// prepended to the encoded message, as required by the Confluent wire format.
+func (ProtobufCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("kafkabp: %T is not a proto.Message", v)
+	}
+	data := appendMessageIndexes(nil, msg.ProtoReflect().Descriptor())
+	return proto.MarshalOptions{}.MarshalAppend(data, msg)
+}
+
+// Unmarshal implements Codec.
+func (ProtobufCodec) Unmarshal(data []byte, _ Schema, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("kafkabp: %T is not a proto.Message", v)
+	}
+	data, err := skipMessageIndexes(data)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+// appendMessageIndexes appends the zigzag varint encoded message indexes of
+// desc to data.
+func appendMessageIndexes(data []byte, desc protoreflect.MessageDescriptor) []byte {
+	var indexes []int
+	for d := protoreflect.Descriptor(desc); ; d = d.Parent() {
+		if _, ok := d.(protoreflect.MessageDescriptor); !ok {
+			break
+		}
+		indexes = append([]int{d.Index()}, indexes...)
+	}
+	if len(indexes) == 1 && indexes[0] == 0 {
+		// The common case of the first message is encoded as a single 0.
+		return append(data, 0)
+	}
+	buf := make([]byte, binary.MaxVarintLen64)
+	data = append(data, buf[:binary.PutVarint(buf, int64(len(indexes)))]...)
+	for _, i := range indexes {
+		data = append(data, buf[:binary.PutVarint(buf, int64(i))]...)
+	}
+	return data
+}
+
+// skipMessageIndexes returns data without the leading message indexes.
+func skipMessageIndexes(data []byte) ([]byte, error) {
+	n, size := binary.Varint(data)
+	if size <= 0 || n < 0 {
+		return nil, ErrInvalidWireFormat
+	}
+	data = data[size:]
+	for i := int64(0); i < n; i++ {
+		_, size = binary.Varint(data)
+		if size <= 0 {
+			return nil, ErrInvalidWireFormat
+		}
+		data = data[size:]
+	}
+	return data, nil
+}
+
+// AvroCodec is a Codec for Avro schemas.
+//
+// As baseplate.go does not depend on any Avro implementation,
+// the encoding and decoding are delegated to MarshalFunc and UnmarshalFunc,
+// which can be implemented by any Avro library.
+type AvroCodec struct {
+	// The Avro schema of the values.
+	Schema string
+
+	// The full name of the Avro record,
+	// used by the "record" and "topic-record" subject name strategies.
+	RecordName string
+
+	// MarshalFunc encodes v with schema.
+	MarshalFunc func(schema string, v interface{}) ([]byte, error)
+
+	// UnmarshalFunc decodes data written with writerSchema into v,
+	// resolving it against the reader schema when they differ.
+	UnmarshalFunc func(writerSchema string, data []byte, v interface{}) error
+}
+
+var _ Codec = AvroCodec{}
+
+// SchemaType implements Codec.
+func (AvroCodec) SchemaType() SchemaType {
+	return SchemaTypeAvro
+}
+
+// SchemaOf implements Codec.
+func (c AvroCodec) SchemaOf(interface{}) (schema string, recordName string, err error) {
+	return c.Schema, c.RecordName, nil
+}
+
+// Marshal implements Codec.
+func (c AvroCodec) Marshal(v interface{}) ([]byte, error) {
+	return c.MarshalFunc(c.Schema, v)
+}
+
+// Unmarshal implements Codec.
+func (c AvroCodec) Unmarshal(data []byte, writer Schema, v interface{}) error {
+	return c.UnmarshalFunc(writer.Schema, data, v)
+}
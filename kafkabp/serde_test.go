@@ -0,0 +1,281 @@
+package kafkabp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/reddit/baseplate.go/kafkabp"
+)
+
+// fakeRegistry implements the subset of the Confluent schema registry API
+// used by kafkabp.SchemaRegistry.
+type fakeRegistry struct {
+	url string
+
+	lock     sync.Mutex
+	schemas  []map[string]string // index is id-1
+	subjects map[string][]int
+	requests int
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.requests++
+
+	notFound := func(code int) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error_code": code,
+			"message":    "not found",
+		})
+	}
+
+	if id := strings.TrimPrefix(r.URL.Path, "/schemas/ids/"); id != r.URL.Path {
+		i, _ := strconv.Atoi(id)
+		if i <= 0 || i > len(f.schemas) {
+			notFound(40403)
+			return
+		}
+		json.NewEncoder(w).Encode(f.schemas[i-1])
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/subjects/")
+	register := strings.HasSuffix(path, "/versions")
+	subject := strings.TrimSuffix(path, "/versions")
+	var req map[string]string
+	json.NewDecoder(r.Body).Decode(&req)
+	for _, id := range f.subjects[subject] {
+		s := f.schemas[id-1]
+		if s["schema"] == req["schema"] && s["schemaType"] == req["schemaType"] {
+			json.NewEncoder(w).Encode(map[string]int{"id": id})
+			return
+		}
+	}
+	if !register {
+		if len(f.subjects[subject]) == 0 {
+			notFound(40401)
+		} else {
+			notFound(40403)
+		}
+		return
+	}
+	f.schemas = append(f.schemas, req)
+	id := len(f.schemas)
+	if f.subjects == nil {
+		f.subjects = make(map[string][]int)
+	}
+	f.subjects[subject] = append(f.subjects[subject], id)
+	json.NewEncoder(w).Encode(map[string]int{"id": id})
+}
+
+func newTestRegistry(t *testing.T, cfg kafkabp.SchemaRegistryConfig) (*kafkabp.SchemaRegistry, *fakeRegistry) {
+	t.Helper()
+
+	fake := new(fakeRegistry)
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	fake.url = server.URL
+	cfg.URL = server.URL
+	registry, err := kafkabp.NewSchemaRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry, fake
+}
+
+type testRecord struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestSerdeJSON(t *testing.T) {
+	registry, fake := newTestRegistry(t, kafkabp.SchemaRegistryConfig{
+		AutoRegister: true,
+	})
+	codec := kafkabp.JSONCodec{
+		Schema:     `{"type":"object"}`,
+		RecordName: "test.Record",
+	}
+	ctx := context.Background()
+
+	data, err := kafkabp.NewSerializer(registry, codec).Serialize(ctx, "topic", false, testRecord{Name: "foo", Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != 0 || data[4] != 1 {
+		t.Errorf("expected magic byte 0 and schema id 1, got %v", data[:5])
+	}
+	if fake.subjects["topic-value"] == nil {
+		t.Errorf("expected schema registered under topic-value, got %v", fake.subjects)
+	}
+
+	// The schema is cached.
+	requests := fake.requests
+	if _, err := kafkabp.NewSerializer(registry, codec).Serialize(ctx, "topic", false, testRecord{}); err != nil {
+		t.Fatal(err)
+	}
+	if fake.requests != requests {
+		t.Errorf("expected schema id to be cached, got %d more requests", fake.requests-requests)
+	}
+
+	// Use a new registry without cache to test fetching the schema by id.
+	reader, err := kafkabp.NewSchemaRegistry(kafkabp.SchemaRegistryConfig{URL: fake.url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record testRecord
+	if err := kafkabp.NewDeserializer(reader, codec).Deserialize(ctx, data, &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Name != "foo" || record.Count != 1 {
+		t.Errorf("unexpected record %+v", record)
+	}
+
+	// Schema type mismatch.
+	if err := kafkabp.NewDeserializer(reader, kafkabp.ProtobufCodec{}).Deserialize(ctx, data, &record); err == nil {
+		t.Error("expected error deserializing json with protobuf codec")
+	}
+	// Invalid wire format.
+	if err := kafkabp.NewDeserializer(reader, codec).Deserialize(ctx, []byte("{}"), &record); !errors.Is(err, kafkabp.ErrInvalidWireFormat) {
+		t.Errorf("expected error %v, got %v", kafkabp.ErrInvalidWireFormat, err)
+	}
+}
+
+func TestSerdeProtobuf(t *testing.T) {
+	registry, _ := newTestRegistry(t, kafkabp.SchemaRegistryConfig{
+		AutoRegister:        true,
+		SubjectNameStrategy: kafkabp.SubjectNameRecord,
+	})
+	codec := kafkabp.ProtobufCodec{
+		Schemas: map[string]string{
+			"google.protobuf.StringValue": "syntax = \"proto3\"; ...",
+		},
+	}
+	ctx := context.Background()
+
+	data, err := kafkabp.NewSerializer(registry, codec).Serialize(ctx, "topic", true, wrapperspb.String("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// StringValue is the 8th message in wrappers.proto,
+	// so the message indexes are [7] (zigzag encoded length 1 and index 7).
+	if data[5] != 2 || data[6] != 14 {
+		t.Errorf("expected message indexes [2 14], got %v", data[5:7])
+	}
+
+	var value wrapperspb.StringValue
+	if err := kafkabp.NewDeserializer(registry, codec).Deserialize(ctx, data, &value); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(&value, wrapperspb.String("foo")) {
+		t.Errorf("expected %v, got %v", wrapperspb.String("foo"), &value)
+	}
+
+	if _, err := kafkabp.NewSerializer(registry, codec).Serialize(ctx, "topic", true, wrapperspb.Int64(1)); err == nil {
+		t.Error("expected error serializing message without schema")
+	}
+	if _, err := kafkabp.NewSerializer(registry, codec).Serialize(ctx, "topic", true, "foo"); err == nil {
+		t.Error("expected error serializing non proto.Message")
+	}
+}
+
+func TestSerdeAvro(t *testing.T) {
+	registry, fake := newTestRegistry(t, kafkabp.SchemaRegistryConfig{
+		AutoRegister: true,
+	})
+	const schema = `{"type":"string"}`
+	codec := kafkabp.AvroCodec{
+		Schema:     schema,
+		RecordName: "string",
+		MarshalFunc: func(s string, v interface{}) ([]byte, error) {
+			if s != schema {
+				t.Errorf("expected schema %q, got %q", schema, s)
+			}
+			return []byte(v.(string)), nil
+		},
+		UnmarshalFunc: func(writer string, data []byte, v interface{}) error {
+			if writer != schema {
+				t.Errorf("expected writer schema %q, got %q", schema, writer)
+			}
+			*v.(*string) = string(data)
+			return nil
+		},
+	}
+	ctx := context.Background()
+
+	data, err := kafkabp.NewSerializer(registry, codec).Serialize(ctx, "topic", false, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := fake.schemas[0]; s["schemaType"] != "" {
+		t.Errorf("expected schemaType to be omitted for avro, got %q", s["schemaType"])
+	}
+	var value string
+	if err := kafkabp.NewDeserializer(registry, codec).Deserialize(ctx, data, &value); err != nil {
+		t.Fatal(err)
+	}
+	if value != "foo" {
+		t.Errorf("expected %q, got %q", "foo", value)
+	}
+}
+
+func TestSchemaRegistry(t *testing.T) {
+	t.Run("invalid-config", func(t *testing.T) {
+		if _, err := kafkabp.NewSchemaRegistry(kafkabp.SchemaRegistryConfig{}); !errors.Is(err, kafkabp.ErrSchemaRegistryURLEmpty) {
+			t.Errorf("expected error %v, got %v", kafkabp.ErrSchemaRegistryURLEmpty, err)
+		}
+		if _, err := kafkabp.NewSchemaRegistry(kafkabp.SchemaRegistryConfig{
+			URL:                 "http://localhost",
+			SubjectNameStrategy: "foo",
+		}); !errors.Is(err, kafkabp.ErrSubjectNameStrategyInvalid) {
+			t.Errorf("expected error %v, got %v", kafkabp.ErrSubjectNameStrategyInvalid, err)
+		}
+	})
+
+	t.Run("subject", func(t *testing.T) {
+		for _, c := range []struct {
+			strategy string
+			isKey    bool
+			expected string
+		}{
+			{strategy: kafkabp.SubjectNameTopic, isKey: true, expected: "topic-key"},
+			{strategy: kafkabp.SubjectNameTopic, expected: "topic-value"},
+			{strategy: kafkabp.SubjectNameRecord, expected: "test.Record"},
+			{strategy: kafkabp.SubjectNameTopicRecord, expected: "topic-test.Record"},
+		} {
+			registry, err := kafkabp.NewSchemaRegistry(kafkabp.SchemaRegistryConfig{
+				URL:                 "http://localhost",
+				SubjectNameStrategy: c.strategy,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := registry.Subject("topic", c.isKey, "test.Record"); got != c.expected {
+				t.Errorf("%s: expected subject %q, got %q", c.strategy, c.expected, got)
+			}
+		}
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		registry, _ := newTestRegistry(t, kafkabp.SchemaRegistryConfig{})
+		ctx := context.Background()
+		if _, err := registry.SchemaID(ctx, "topic-value", kafkabp.SchemaTypeJSON, "{}", false); !errors.Is(err, kafkabp.ErrSchemaNotFound) {
+			t.Errorf("expected error %v, got %v", kafkabp.ErrSchemaNotFound, err)
+		}
+		if _, err := registry.SchemaByID(ctx, 42); !errors.Is(err, kafkabp.ErrSchemaNotFound) {
+			t.Errorf("expected error %v, got %v", kafkabp.ErrSchemaNotFound, err)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package baseplate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/reddit/baseplate.go/errorsbp"
+)
+
+// LifecyclePhase is a phase in the lifecycle of a service run by Serve.
+type LifecyclePhase int
+
+// LifecyclePhase values, in the order they happen.
+const (
+	// PhasePostInit happens when Serve is called,
+	// after the Baseplate and the Server are created.
+	//
+	// It's usually used to establish the connections the service depends on,
+	// e.g. connecting the client pools.
+	PhasePostInit LifecyclePhase = iota
+
+	// PhasePreServe happens right before the Server starts to serve.
+	PhasePreServe
+
+	// PhasePreDrain happens when a shutdown signal is received,
+	// before the PreShutdown closers (e.g. a Drainer) are closed.
+	PhasePreDrain
+
+	// PhasePostShutdown happens after the Server and the PostShutdown closers
+	// are closed.
+	//
+	// It's usually used to flush the buffered data, e.g. the events.
+	PhasePostShutdown
+)
+
+func (p LifecyclePhase) String() string {
+	switch p {
+	default:
+		return fmt.Sprintf("LifecyclePhase(%d)", int(p))
+	case PhasePostInit:
+		return "PostInit"
+	case PhasePreServe:
+		return "PreServe"
+	case PhasePreDrain:
+		return "PreDrain"
+	case PhasePostShutdown:
+		return "PostShutdown"
+	}
+}
+
+// startup returns true if p happens before the Server starts to serve.
+func (p LifecyclePhase) startup() bool {
+	return p == PhasePostInit || p == PhasePreServe
+}
+
+// LifecycleHook is the callback registered into a LifecyclePhase.
+type LifecycleHook func(ctx context.Context) error
+
+type namedLifecycleHook struct {
+	name string
+	hook LifecycleHook
+}
+
+// Lifecycle is the registry of the LifecycleHooks of a Baseplate,
+// run by Serve.
+//
+// It's safe to be used concurrently.
+// The zero value is an empty Lifecycle ready to use.
+type Lifecycle struct {
+	lock  sync.Mutex
+	hooks map[LifecyclePhase][]namedLifecycleHook
+}
+
+// Register registers hook into phase.
+//
+// name is used to identify the hook in the errors.
+//
+// The hooks of PhasePostInit and PhasePreServe are run in the order they are
+// registered,
+// the hooks of PhasePreDrain and PhasePostShutdown are run in the reverse
+// order, like defer,
+// so that the libraries registering hooks in both startup and shutdown phases
+// are shut down in the reverse order they are started.
+func (l *Lifecycle) Register(phase LifecyclePhase, name string, hook LifecycleHook) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.hooks == nil {
+		l.hooks = make(map[LifecyclePhase][]namedLifecycleHook)
+	}
+	l.hooks[phase] = append(l.hooks[phase], namedLifecycleHook{
+		name: name,
+		hook: hook,
+	})
+}
+
+// Run runs all the hooks registered into phase.
+//
+// For PhasePostInit and PhasePreServe,
+// Run stops at the first hook returning an error and returns it.
+// For PhasePreDrain and PhasePostShutdown,
+// Run runs all the hooks and returns the errors in an errorsbp.Batch.
+//
+// Run is called by Serve and usually shouldn't be called directly.
+// It's nil-safe, a nil *Lifecycle has no hooks to run.
+func (l *Lifecycle) Run(ctx context.Context, phase LifecyclePhase) error {
+	if l == nil {
+		return nil
+	}
+
+	l.lock.Lock()
+	hooks := make([]namedLifecycleHook, len(l.hooks[phase]))
+	copy(hooks, l.hooks[phase])
+	l.lock.Unlock()
+
+	if phase.startup() {
+		for _, h := range hooks {
+			if err := h.hook(ctx); err != nil {
+				return fmt.Errorf("baseplate: %v hook %q failed: %w", phase, h.name, err)
+			}
+		}
+		return nil
+	}
+
+	var batch errorsbp.Batch
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		if err := h.hook(ctx); err != nil {
+			batch.Add(fmt.Errorf("baseplate: %v hook %q failed: %w", phase, h.name, err))
+		}
+	}
+	return batch.Compile()
+}
@@ -0,0 +1,303 @@
+package baseplate_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/batchcloser"
+	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/healthbp"
+)
+
+// lifecycleBaseplate is the Baseplate returned by NewTestBaseplate.
+type lifecycleBaseplate interface {
+	baseplate.Baseplate
+	baseplate.LifecycleProvider
+}
+
+// startupBaseplate is the Baseplate returned by NewTestBaseplate.
+type startupBaseplate interface {
+	lifecycleBaseplate
+	baseplate.StartupGateProvider
+}
+
+func TestLifecycleRun(t *testing.T) {
+	var called []string
+	hook := func(name string, err error) baseplate.LifecycleHook {
+		return func(context.Context) error {
+			called = append(called, name)
+			return err
+		}
+	}
+	errHook := errors.New("hook failed")
+
+	t.Run("startup", func(t *testing.T) {
+		called = nil
+		var l baseplate.Lifecycle
+		l.Register(baseplate.PhasePostInit, "a", hook("a", nil))
+		l.Register(baseplate.PhasePostInit, "b", hook("b", errHook))
+		l.Register(baseplate.PhasePostInit, "c", hook("c", nil))
+		l.Register(baseplate.PhasePreServe, "d", hook("d", nil))
+
+		err := l.Run(context.Background(), baseplate.PhasePostInit)
+		if !errors.Is(err, errHook) {
+			t.Errorf("Expected error %v, got %v", errHook, err)
+		}
+		if want := []string{"a", "b"}; !reflect.DeepEqual(called, want) {
+			t.Errorf("Expected hooks %v to be called, got %v", want, called)
+		}
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		called = nil
+		var l baseplate.Lifecycle
+		l.Register(baseplate.PhasePostShutdown, "a", hook("a", errHook))
+		l.Register(baseplate.PhasePostShutdown, "b", hook("b", nil))
+		l.Register(baseplate.PhasePostShutdown, "c", hook("c", errHook))
+
+		err := l.Run(context.Background(), baseplate.PhasePostShutdown)
+		if !errors.Is(err, errHook) {
+			t.Errorf("Expected error %v, got %v", errHook, err)
+		}
+		const want = `errorsbp.Batch: total 2 error(s) in this batch: baseplate: PostShutdown hook "c" failed: hook failed; baseplate: PostShutdown hook "a" failed: hook failed`
+		if err.Error() != want {
+			t.Errorf("Expected error %q, got %q", want, err.Error())
+		}
+		if want := []string{"c", "b", "a"}; !reflect.DeepEqual(called, want) {
+			t.Errorf("Expected hooks %v to be called, got %v", want, called)
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		var l *baseplate.Lifecycle
+		if err := l.Run(context.Background(), baseplate.PhasePostInit); err != nil {
+			t.Errorf("Expected nil error, got %v", err)
+		}
+	})
+}
+
+func TestServeLifecycle(t *testing.T) {
+	// Not parallel as it sends signals to the process.
+	store := newSecretsStore(t)
+	defer store.Close()
+
+	newBaseplate := func() lifecycleBaseplate {
+		return baseplate.NewTestBaseplate(baseplate.NewTestBaseplateArgs{
+			Config:          baseplate.Config{StopTimeout: testTimeout},
+			Store:           store,
+			EdgeContextImpl: ecinterface.Mock(),
+		}).(lifecycleBaseplate)
+	}
+
+	t.Run("order", func(t *testing.T) {
+		var called []string
+		record := func(name string) {
+			called = append(called, name)
+		}
+		hook := func(name string) baseplate.LifecycleHook {
+			return func(context.Context) error {
+				record(name)
+				return nil
+			}
+		}
+		closer := func(name string) io.Closer {
+			return batchcloser.Wrap(func() error {
+				record(name)
+				return nil
+			})
+		}
+
+		bp := newBaseplate()
+		for _, phase := range []baseplate.LifecyclePhase{
+			baseplate.PhasePostShutdown,
+			baseplate.PhasePreDrain,
+			baseplate.PhasePreServe,
+			baseplate.PhasePostInit,
+		} {
+			bp.Lifecycle().Register(phase, phase.String(), hook(phase.String()))
+		}
+
+		ch := make(chan error)
+		go func() {
+			ch <- baseplate.Serve(context.Background(), baseplate.ServeArgs{
+				Server:       newWaitServer(t, bp, time.Millisecond),
+				PreShutdown:  []io.Closer{closer("PreShutdown")},
+				PostShutdown: []io.Closer{closer("PostShutdown closer")},
+			})
+		}()
+
+		p, err := os.FindProcess(syscall.Getpid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond * 10)
+		p.Signal(os.Interrupt)
+		if err := <-ch; err != nil {
+			t.Errorf("Serve returned error: %v", err)
+		}
+
+		want := []string{
+			"PostInit",
+			"PreServe",
+			"PreDrain",
+			"PreShutdown",
+			"PostShutdown closer",
+			"PostShutdown",
+		}
+		if !reflect.DeepEqual(called, want) {
+			t.Errorf("Expected call order %v, got %v", want, called)
+		}
+	})
+
+	t.Run("startup-error", func(t *testing.T) {
+		errHook := errors.New("hook failed")
+		bp := newBaseplate()
+		bp.Lifecycle().Register(
+			baseplate.PhasePreServe,
+			"fail",
+			func(context.Context) error {
+				return errHook
+			},
+		)
+
+		server := &testServer{bp: bp}
+		// Serve of testServer would panic on the nil wg if called.
+		err := baseplate.Serve(context.Background(), baseplate.ServeArgs{
+			Server: server,
+		})
+		if !errors.Is(err, errHook) {
+			t.Errorf("Expected error %v, got %v", errHook, err)
+		}
+	})
+
+	t.Run("no-lifecycle", func(t *testing.T) {
+		// Only the methods of the Baseplate interface are promoted,
+		// so it doesn't implement LifecycleProvider.
+		bp := struct{ baseplate.Baseplate }{newBaseplate()}
+
+		ch := make(chan error)
+		go func() {
+			ch <- baseplate.Serve(context.Background(), baseplate.ServeArgs{
+				Server: newWaitServer(t, bp, time.Millisecond),
+			})
+		}()
+
+		p, err := os.FindProcess(syscall.Getpid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond * 10)
+		p.Signal(os.Interrupt)
+		if err := <-ch; err != nil {
+			t.Errorf("Serve returned error: %v", err)
+		}
+	})
+}
+
+func TestServeStartupGate(t *testing.T) {
+	t.Parallel()
+
+	store := newSecretsStore(t)
+	defer store.Close()
+
+	const name = "baseplate-test-dependency"
+	newBaseplate := func(timeout time.Duration) startupBaseplate {
+		return baseplate.NewTestBaseplate(baseplate.NewTestBaseplateArgs{
+			Config: baseplate.Config{
+				StopTimeout:    testTimeout,
+				StartupTimeout: timeout,
+			},
+			Store:           store,
+			EdgeContextImpl: ecinterface.Mock(),
+		}).(startupBaseplate)
+	}
+	// The PreServe hook stops Serve before the server starts.
+	errStop := errors.New("stop")
+
+	t.Run("ready", func(t *testing.T) {
+		bp := newBaseplate(-1)
+		signal := bp.StartupGate().Require(name)
+		bp.Lifecycle().Register(baseplate.PhasePostInit, "ready", func(context.Context) error {
+			go func() {
+				time.Sleep(time.Millisecond * 10)
+				signal.Ready()
+			}()
+			return nil
+		})
+		var pending []string
+		bp.Lifecycle().Register(baseplate.PhasePreServe, "stop", func(context.Context) error {
+			pending = bp.StartupGate().Pending()
+			return errStop
+		})
+
+		err := baseplate.Serve(context.Background(), baseplate.ServeArgs{
+			Server: &testServer{bp: bp},
+		})
+		if !errors.Is(err, errStop) {
+			t.Errorf("Expected error %v, got %v", errStop, err)
+		}
+		if len(pending) != 0 {
+			t.Errorf("Expected no pending dependencies before PreServe, got %q", pending)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		bp := newBaseplate(testTimeout)
+		bp.StartupGate().Require(name)
+		bp.Lifecycle().Register(baseplate.PhasePreServe, "stop", func(context.Context) error {
+			t.Error("PreServe hook called")
+			return errStop
+		})
+
+		// Serve of testServer would panic on the nil wg if called.
+		err := baseplate.Serve(context.Background(), baseplate.ServeArgs{
+			Server: &testServer{bp: bp},
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected error %v, got %v", context.DeadlineExceeded, err)
+		}
+		if !errors.Is(err, healthbp.ErrNotInitialized) {
+			t.Errorf("Expected error %v, got %v", healthbp.ErrNotInitialized, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected error to name %q, got %v", name, err)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		errFailed := errors.New("failed")
+		bp := newBaseplate(-1)
+		bp.StartupGate().Require(name).Fail(errFailed)
+
+		err := baseplate.Serve(context.Background(), baseplate.ServeArgs{
+			Server: &testServer{bp: bp},
+		})
+		if !errors.Is(err, errFailed) {
+			t.Errorf("Expected error %v, got %v", errFailed, err)
+		}
+	})
+
+	t.Run("isolated", func(t *testing.T) {
+		// The dependencies of another Baseplate don't hold off Serve.
+		newBaseplate(-1).StartupGate().Require(name)
+		bp := newBaseplate(testTimeout)
+		bp.Lifecycle().Register(baseplate.PhasePreServe, "stop", func(context.Context) error {
+			return errStop
+		})
+
+		err := baseplate.Serve(context.Background(), baseplate.ServeArgs{
+			Server: &testServer{bp: bp},
+		})
+		if !errors.Is(err, errStop) {
+			t.Errorf("Expected error %v, got %v", errStop, err)
+		}
+	})
+}
@@ -0,0 +1,296 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/reddit/baseplate.go/errorsbp"
+)
+
+// AuditLoggerName is the logger name of all the audit log entries.
+const AuditLoggerName = "audit"
+
+// AuditConfig is the config of the audit log channel.
+//
+// Can be deserialized from YAML.
+type AuditConfig struct {
+	// OutputPaths are the outputs of the audit logs,
+	// in the same format as zap.Config.OutputPaths
+	// (e.g. "stdout", "stderr", file paths),
+	// with additional support of sockets in URL format
+	// (e.g. "tcp://localhost:5170", "udp://localhost:5170", "unix:///path/to/sock").
+	//
+	// Optional. If empty, audit logs are written to stderr.
+	OutputPaths []string `yaml:"outputPaths"`
+
+	// OTLP is the optional config to also export the audit logs via OTLP,
+	// independent from the OTLP config of the global logger.
+	OTLP OTLPConfig `yaml:"otlp"`
+}
+
+// globalAuditLogger is used by Audit.
+//
+// Before InitAudit is called, it writes JSON to stderr.
+var globalAuditLogger = newAuditLogger(zapcore.NewCore(
+	zapcore.NewJSONEncoder(jsonConfig(DebugLevel).EncoderConfig),
+	zapcore.Lock(os.Stderr),
+	zapcore.DebugLevel,
+))
+
+func newAuditLogger(core zapcore.Core) *zap.SugaredLogger {
+	core = wrappedCore{Core: core}
+	core = redactingCore{Core: core, redactor: globalRedactor}
+	return zap.New(
+		core,
+		zap.AddCaller(),
+		zap.AddCallerSkip(1),
+	).Named(AuditLoggerName).Sugar()
+}
+
+// InitAudit initializes the audit log channel used by Audit.
+//
+// The audit log channel is independent from the global logger:
+// it always bypasses the log level (including the named levels) and sampling
+// of the global logger, and writes to its own outputs.
+// Redaction of the global logger is still applied.
+//
+// The io.Closer returned flushes the audit logs and closes the outputs.
+func InitAudit(cfg AuditConfig) (io.Closer, error) {
+	paths := cfg.OutputPaths
+	if len(paths) == 0 {
+		paths = []string{"stderr"}
+	}
+	paths, err := auditSinkPaths(paths)
+	if err != nil {
+		return nil, fmt.Errorf("log.InitAudit: failed to register socket outputs: %w", err)
+	}
+	sink, closeSink, err := zap.Open(paths...)
+	if err != nil {
+		return nil, fmt.Errorf("log.InitAudit: failed to open outputs: %w", err)
+	}
+	core := zapcore.NewCore(
+		zapcore.NewJSONEncoder(jsonConfig(DebugLevel).EncoderConfig),
+		sink,
+		zapcore.DebugLevel,
+	)
+	closer := &auditCloser{
+		sink:      sink,
+		closeSink: closeSink,
+		exporter:  nopCloser{},
+	}
+	if cfg.OTLP.Endpoint != "" {
+		exporter := newOTLPExporter(cfg.OTLP, http.DefaultClient)
+		core = zapcore.NewTee(core, &otlpCore{
+			LevelEnabler: zapcore.DebugLevel,
+			exporter:     exporter,
+		})
+		closer.exporter = exporter
+	}
+	globalAuditLogger = newAuditLogger(core)
+	return closer, nil
+}
+
+type auditCloser struct {
+	sink      zapcore.WriteSyncer
+	closeSink func()
+	exporter  io.Closer
+}
+
+func (c *auditCloser) Close() error {
+	var batch errorsbp.Batch
+	batch.Add(c.exporter.Close())
+	batch.Add(c.sink.Sync())
+	c.closeSink()
+	return batch.Compile()
+}
+
+// Audit logs a security/audit event (e.g. admin actions, permission denials,
+// secret access) to the audit log channel initialized by InitAudit.
+//
+// The variadic key-value pairs are treated as they are in With.
+// The trace id attached to ctx via Attach is also logged.
+//
+// Audit logs are never dropped by the log level or sampling.
+func Audit(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	logger := globalAuditLogger
+	if traceID, ok := ctx.Value(traceIDContextKey).(string); ok && traceID != "" {
+		logger = logger.With(zap.String(traceIDKey, traceID))
+	}
+	logger.Infow(msg, keysAndValues...)
+}
+
+// auditSchemePrefix is prepended to the schemes of the socket outputs in
+// AuditConfig.OutputPaths, to register the socket sinks to zap under private
+// schemes (e.g. "bpaudit+tcp") that don't conflict with the ones registered by
+// other packages.
+const auditSchemePrefix = "bpaudit+"
+
+// Timeouts of the socket outputs of the audit logs.
+const (
+	socketDialTimeout  = time.Second * 5
+	socketWriteTimeout = time.Second * 5
+)
+
+var socketSchemes = []string{"tcp", "udp", "unix"}
+
+var (
+	registerSocketSinksOnce sync.Once
+	registerSocketSinksErr  error
+)
+
+// auditSinkPaths returns paths with the socket outputs rewritten to the
+// private schemes, registering the socket sinks to zap on first use.
+func auditSinkPaths(paths []string) ([]string, error) {
+	rewritten := make([]string, len(paths))
+	for i, path := range paths {
+		rewritten[i] = path
+		u, err := url.Parse(path)
+		if err != nil || !isSocketScheme(u.Scheme) {
+			continue
+		}
+		registerSocketSinksOnce.Do(func() {
+			for _, scheme := range socketSchemes {
+				if err := zap.RegisterSink(auditSchemePrefix+scheme, newSocketSink); err != nil {
+					registerSocketSinksErr = err
+					return
+				}
+			}
+		})
+		if registerSocketSinksErr != nil {
+			return nil, registerSocketSinksErr
+		}
+		rewritten[i] = auditSchemePrefix + path
+	}
+	return rewritten, nil
+}
+
+func isSocketScheme(scheme string) bool {
+	for _, s := range socketSchemes {
+		if scheme == s {
+			return true
+		}
+	}
+	return false
+}
+
+// errSocketSinkClosed is returned by socketSink.Write after Close.
+var errSocketSinkClosed = errors.New("log: socket output closed")
+
+// socketSink is a zap.Sink writing to a socket,
+// reconnecting on write failures.
+type socketSink struct {
+	network string
+	address string
+
+	lock   sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+func newSocketSink(u *url.URL) (zap.Sink, error) {
+	s := &socketSink{
+		network: strings.TrimPrefix(u.Scheme, auditSchemePrefix),
+		address: u.Host,
+	}
+	if s.network == "unix" {
+		s.address = u.Path
+	}
+	if s.address == "" {
+		return nil, fmt.Errorf("log: empty address in socket output %q", u.String())
+	}
+	return s, nil
+}
+
+func (s *socketSink) Write(p []byte) (int, error) {
+	// Retry once with a new connection in case the old one is broken.
+	var err error
+	for i := 0; i < 2; i++ {
+		var conn net.Conn
+		conn, err = s.getConn()
+		if err != nil {
+			return 0, err
+		}
+		if err = conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout)); err == nil {
+			var n int
+			n, err = conn.Write(p)
+			if err == nil {
+				return n, nil
+			}
+		}
+		s.dropConn(conn)
+	}
+	return 0, err
+}
+
+// getConn returns the current connection,
+// or dials a new one without holding the lock.
+func (s *socketSink) getConn() (net.Conn, error) {
+	s.lock.Lock()
+	conn, closed := s.conn, s.closed
+	s.lock.Unlock()
+	if closed {
+		return nil, errSocketSinkClosed
+	}
+	if conn != nil {
+		return conn, nil
+	}
+
+	conn, err := net.DialTimeout(s.network, s.address, socketDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		conn.Close()
+		return nil, errSocketSinkClosed
+	}
+	if s.conn != nil {
+		// Dialed concurrently by another Write.
+		conn.Close()
+		return s.conn, nil
+	}
+	s.conn = conn
+	return conn, nil
+}
+
+// dropConn closes conn after a failed write,
+// so that the next Write dials a new one.
+func (s *socketSink) dropConn(conn net.Conn) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conn == conn {
+		s.conn = nil
+	}
+	conn.Close()
+}
+
+func (s *socketSink) Sync() error {
+	return nil
+}
+
+func (s *socketSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.closed = true
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
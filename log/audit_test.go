@@ -0,0 +1,159 @@
+package log
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestAudit(t *testing.T) {
+	InitFromConfig(Config{
+		Level: ErrorLevel,
+		Sampling: &SamplingConfig{
+			Initial:    1,
+			Thereafter: 100,
+			Tick:       time.Minute,
+		},
+	})
+	defer InitLoggerJSON(InfoLevel)
+	defer func(logger *zap.SugaredLogger) {
+		globalAuditLogger = logger
+	}(globalAuditLogger)
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	closer, err := InitAudit(AuditConfig{
+		OutputPaths: []string{path},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := Attach(context.Background(), AttachArgs{TraceID: "trace"})
+	const n = 5
+	for i := 0; i < n; i++ {
+		Audit(ctx, "secret accessed", "path", "secret/foo", "user", "admin")
+	}
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != n {
+		t.Fatalf("Expected %d audit logs regardless of level and sampling, got %d: %q", n, len(lines), data)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range map[string]interface{}{
+		"message":  "secret accessed",
+		"logger":   AuditLoggerName,
+		"level":    "INFO",
+		"path":     "secret/foo",
+		"user":     "admin",
+		traceIDKey: "trace",
+	} {
+		if entry[k] != v {
+			t.Errorf("Expected %q to be %#v, got %#v", k, v, entry[k])
+		}
+	}
+	if caller, _ := entry["caller"].(string); !strings.HasPrefix(caller, "log/audit_test.go:") {
+		t.Errorf("Expected caller to be the test, got %q", caller)
+	}
+}
+
+func TestAuditSocketOutput(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		received <- line
+	}()
+
+	defer func(logger *zap.SugaredLogger) {
+		globalAuditLogger = logger
+	}(globalAuditLogger)
+	closer, err := InitAudit(AuditConfig{
+		OutputPaths: []string{"tcp://" + ln.Addr().String()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+
+	Audit(context.Background(), "permission denied")
+	select {
+	case line := <-received:
+		if !strings.Contains(line, `"permission denied"`) {
+			t.Errorf("Unexpected audit log received: %q", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the audit log")
+	}
+}
+
+func TestAuditSocketSchemesNotRegistered(t *testing.T) {
+	defer func(logger *zap.SugaredLogger) {
+		globalAuditLogger = logger
+	}(globalAuditLogger)
+	closer, err := InitAudit(AuditConfig{
+		OutputPaths: []string{"tcp://localhost:5170"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	closer.Close()
+	// The socket sinks are registered under the private schemes,
+	// so the plain ones are still available to other packages.
+	if err := zap.RegisterSink("tcp", newSocketSink); err != nil {
+		t.Errorf("Expected the tcp scheme to be available, got %v", err)
+	}
+}
+
+func TestSocketSinkClosed(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	sink, err := newSocketSink(&url.URL{
+		Scheme: auditSchemePrefix + "tcp",
+		Host:   ln.Addr().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sink.Write([]byte("foo\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sink.Write([]byte("bar\n")); !errors.Is(err, errSocketSinkClosed) {
+		t.Errorf("Expected %v after Close, got %v", errSocketSinkClosed, err)
+	}
+}
@@ -1,11 +1,97 @@
 package log
 
+import (
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/reddit/baseplate.go/errorsbp"
+)
+
 // Config is the confuration struct for the log package.
 //
 // Can be deserialized from YAML.
 type Config struct {
 	// Level is the log level you want to set your service to.
 	Level Level `yaml:"level"`
+
+	// Levels are the levels for logger names, overriding Level.
+	//
+	// See SetNamedLevel for more details.
+	Levels map[string]Level `yaml:"levels"`
+
+	// LevelFile is the optional path to a file containing the levels to be used
+	// instead of Level and Levels, in the format described in LevelFileParser.
+	//
+	// When it's set, baseplate.New watches the file and changes the log level
+	// at runtime whenever the file is updated (see LevelFileParser).
+	// The file must exist when baseplate.New is called.
+	//
+	// InitFromConfig ignores it.
+	LevelFile string `yaml:"levelFile"`
+
+	// Schema is the optional output schema of the JSON logs,
+	// to map the log entries to a standard schema (e.g. ECSSchema or
+	// OTelSchema) so they can be ingested by the log backends without further
+	// transformations.
+	//
+	// When it's empty, DefaultSchema is used.
+	Schema Schema `yaml:"schema"`
+
+	// Sampling is the optional sampling config for the log entries.
+	//
+	// When it's nil, the log entries are not sampled.
+	Sampling *SamplingConfig `yaml:"sampling"`
+
+	// Redact is the config to redact sensitive data from logs.
+	Redact RedactConfig `yaml:"redact"`
+
+	// RecentLogs is the number of the most recent log entries to be kept in
+	// memory, regardless of their levels. 0 means disabled.
+	//
+	// See InitRecentLogs and RecentLogsHandler for more details.
+	RecentLogs int `yaml:"recentLogs"`
+
+	// OTLP is the config to export logs via OTLP.
+	//
+	// InitFromConfig ignores it, baseplate.New calls InitOTLP with it.
+	OTLP OTLPConfig `yaml:"otlp"`
+
+	// Audit is the config of the audit log channel used by Audit.
+	//
+	// InitFromConfig ignores it, baseplate.New calls InitAudit with it.
+	Audit AuditConfig `yaml:"audit"`
+}
+
+// ValidateConfig implements configbp.Validator.
+func (cfg Config) ValidateConfig() error {
+	var batch errorsbp.Batch
+	if cfg.Level != "" && !validLevel(cfg.Level) {
+		batch.Add(fmt.Errorf("level: unknown log level %q", cfg.Level))
+	}
+	names := make([]string, 0, len(cfg.Levels))
+	for name := range cfg.Levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if level := cfg.Levels[name]; !validLevel(level) {
+			batch.Add(fmt.Errorf("levels.%s: unknown log level %q", name, level))
+		}
+	}
+	if _, err := ParseSchema(string(cfg.Schema)); err != nil {
+		batch.Add(fmt.Errorf("schema: %w", err))
+	}
+	return batch.Compile()
+}
+
+func validLevel(level Level) bool {
+	switch level {
+	case NopLevel, DebugLevel, InfoLevel, WarnLevel, ErrorLevel, PanicLevel, FatalLevel:
+		return true
+	}
+	return false
 }
 
 // InitFromConfig initializes the log package using the given Config and JSON
@@ -14,6 +100,36 @@ func InitFromConfig(cfg Config) {
 	if cfg.Level == "" {
 		cfg.Level = InfoLevel
 	}
-	level := cfg.Level
-	InitLoggerJSON(level)
+	schema, schemaErr := ParseSchema(string(cfg.Schema))
+	zapCfg, schemaWrapper := schemaConfig(cfg.Level, schema)
+	var wrappers []func(zapcore.Core) zapcore.Core
+	if schemaWrapper != nil {
+		wrappers = append(wrappers, schemaWrapper)
+	}
+	// zap's own sampling is never used, see Config.Sampling.
+	zapCfg.Sampling = nil
+	if cfg.Sampling != nil {
+		sampling := *cfg.Sampling
+		wrappers = append(wrappers, func(core zapcore.Core) zapcore.Core {
+			return NewSamplingCore(core, sampling)
+		})
+	}
+	if err := initLogger(cfg.Level, zapCfg, wrappers...); err != nil {
+		// shouldn't happen, but just in case
+		panic(err)
+	}
+	if schemaErr != nil {
+		Errorw(
+			"Unknown log schema, using the default one",
+			"err", schemaErr,
+		)
+	}
+	SetNamedLevels(cfg.Levels)
+	InitRecentLogs(cfg.RecentLogs)
+	if err := InitRedaction(cfg.Redact); err != nil {
+		Errorw(
+			"Failed to init log redaction",
+			"err", err,
+		)
+	}
 }
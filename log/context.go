@@ -12,6 +12,12 @@ type contextKeyType struct{}
 
 var contextKey contextKeyType
 
+type traceIDContextKeyType struct{}
+
+// traceIDContextKey is the key to the trace id attached by Attach,
+// used by Audit.
+var traceIDContextKey traceIDContextKeyType
+
 // logger keys for attached data.
 const (
 	traceIDKey = "traceID"
@@ -29,6 +35,12 @@ const (
 type AttachArgs struct {
 	TraceID string
 
+	// SpanID and Request (the name of the request being handled, e.g. the
+	// thrift method or the http route name) are only attached to the sentry
+	// hub as "span_id" and "request" tags, not to the logger.
+	SpanID  string
+	Request string
+
 	AdditionalPairs map[string]interface{}
 }
 
@@ -45,14 +57,23 @@ func Attach(ctx context.Context, args AttachArgs) context.Context {
 		if args.TraceID != "" {
 			scope.SetTag("trace_id", args.TraceID)
 		}
+		if args.SpanID != "" {
+			scope.SetTag("span_id", args.SpanID)
+		}
+		if args.Request != "" {
+			scope.SetTag("request", args.Request)
+		}
 		for k, v := range args.AdditionalPairs {
 			scope.SetTag(k, fmt.Sprintf("%v", v))
 		}
 	})
 	ctx = context.WithValue(ctx, sentry.HubContextKey, hub)
+	if args.TraceID != "" {
+		ctx = context.WithValue(ctx, traceIDContextKey, args.TraceID)
+	}
 
 	// create and attach the logger
-	const additional = 1 // Number of non-AdditionalPairs fields in AttachArgs struct.
+	const additional = 1 // Number of non-AdditionalPairs fields attached to the logger.
 	kv := make([]interface{}, 0, len(args.AdditionalPairs)*2+additional)
 	if args.TraceID != "" {
 		kv = append(kv, zap.String(traceIDKey, args.TraceID))
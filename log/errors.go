@@ -0,0 +1,168 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/reddit/baseplate.go/errorsbp"
+)
+
+// ErrorKey is the key used by ErrorField.
+const ErrorKey = "error"
+
+// maxErrorChainDepth is the max number of errors in a chain to be logged by
+// ErrorField, to guard against cycles in the Unwrap chain.
+const maxErrorChainDepth = 32
+
+// codedError defines the interface of errors carrying an error code,
+// which is satisfied by thrift compiled baseplate.Error.
+type codedError interface {
+	error
+
+	IsSetCode() bool
+	GetCode() int32
+}
+
+// detailedError defines the interface of errors carrying details,
+// which is satisfied by thrift compiled baseplate.Error.
+type detailedError interface {
+	error
+
+	IsSetDetails() bool
+	GetDetails() map[string]string
+}
+
+// ErrorField returns a zap field for err that logs err as a structured object
+// under ErrorKey, instead of a flattened string.
+//
+// The object contains the following keys:
+//
+// - "message": err.Error().
+//
+// - "type": the Go type of err.
+//
+// - "code" and "details": when any error in the chain is a baseplate.Error
+// (or any error with GetCode/GetDetails methods) with them set.
+//
+// - "chain": the errors in the Unwrap chain of err (excluding err itself),
+// each with its own "message" and "type".
+//
+// - "errors": when err is an errorsbp.Batch,
+// all the errors inside the batch as structured objects.
+//
+// - "stacktrace": when any error in the chain has a StackTrace method
+// (e.g. errors created by github.com/pkg/errors),
+// the stack trace formatted by "%+v".
+func ErrorField(err error) zap.Field {
+	if err == nil {
+		return zap.Skip()
+	}
+	return zap.Object(ErrorKey, errorObject{err: err})
+}
+
+// ErrorWithChain logs a message at error level with err and some additional
+// context.
+//
+// err is logged via ErrorField,
+// and the variadic key-value pairs are treated as they are in With.
+func ErrorWithChain(ctx context.Context, msg string, err error, keysAndValues ...interface{}) {
+	keysAndValues = append(keysAndValues, ErrorField(err))
+	C(ctx).Errorw(msg, keysAndValues...)
+}
+
+// ErrorCtx logs err at error level with some additional context,
+// using err.Error() as the message.
+//
+// err is logged via ErrorField,
+// and the variadic key-value pairs are treated as they are in With.
+// It's a no-op when err is nil.
+func ErrorCtx(ctx context.Context, err error, keysAndValues ...interface{}) {
+	if err == nil {
+		return
+	}
+	keysAndValues = append(keysAndValues, ErrorField(err))
+	C(ctx).Errorw(err.Error(), keysAndValues...)
+}
+
+type errorObject struct {
+	err error
+}
+
+func (e errorObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("message", e.err.Error())
+	enc.AddString("type", fmt.Sprintf("%T", e.err))
+
+	var coded codedError
+	if errors.As(e.err, &coded) && coded.IsSetCode() {
+		enc.AddInt32("code", coded.GetCode())
+	}
+	var detailed detailedError
+	if errors.As(e.err, &detailed) && detailed.IsSetDetails() {
+		enc.AddObject("details", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			for k, v := range detailed.GetDetails() {
+				enc.AddString(k, v)
+			}
+			return nil
+		}))
+	}
+
+	var chain []error
+	for err := errors.Unwrap(e.err); err != nil && len(chain) < maxErrorChainDepth; err = errors.Unwrap(err) {
+		chain = append(chain, err)
+	}
+	if len(chain) > 0 {
+		enc.AddArray("chain", zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+			for _, err := range chain {
+				err := err
+				enc.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+					enc.AddString("message", err.Error())
+					enc.AddString("type", fmt.Sprintf("%T", err))
+					return nil
+				}))
+			}
+			return nil
+		}))
+	}
+
+	var batch errorsbp.Batch
+	if errors.As(e.err, &batch) {
+		if errs := batch.GetErrors(); len(errs) > 0 {
+			enc.AddArray("errors", zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+				for _, err := range errs {
+					enc.AppendObject(errorObject{err: err})
+				}
+				return nil
+			}))
+		}
+	}
+
+	for _, err := range append([]error{e.err}, chain...) {
+		if st, ok := stackTrace(err); ok {
+			enc.AddString("stacktrace", st)
+			break
+		}
+	}
+	return nil
+}
+
+// stackTrace returns the formatted stack trace from err's StackTrace method,
+// if it has one.
+//
+// Reflection is used here because the return types of StackTrace methods
+// are library specific.
+func stackTrace(err error) (string, bool) {
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return "", false
+	}
+	st := method.Call(nil)[0]
+	if st.Kind() == reflect.Slice && st.Len() == 0 {
+		return "", false
+	}
+	return fmt.Sprintf("%+v", st.Interface()), true
+}
@@ -0,0 +1,134 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/reddit/baseplate.go/errorsbp"
+	baseplatethrift "github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+)
+
+type stackTraceError struct{}
+
+func (stackTraceError) Error() string {
+	return "with stack"
+}
+
+func (stackTraceError) StackTrace() []string {
+	return []string{"frame1", "frame2"}
+}
+
+func logErrorField(t *testing.T, err error) map[string]interface{} {
+	t.Helper()
+	obs, logs := observer.New(zapcore.DebugLevel)
+	zap.New(obs).Error("msg", ErrorField(err))
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	obj, ok := entries[0].ContextMap()[ErrorKey].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected %q to be an object, got %#v", ErrorKey, entries[0].ContextMap())
+	}
+	return obj
+}
+
+func TestErrorField(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		obs, logs := observer.New(zapcore.DebugLevel)
+		zap.New(obs).Error("msg", ErrorField(nil))
+		if _, ok := logs.All()[0].ContextMap()[ErrorKey]; ok {
+			t.Error("Expected nil error to be skipped")
+		}
+	})
+
+	t.Run("chain", func(t *testing.T) {
+		code := int32(baseplatethrift.ErrorCode_TOO_MANY_REQUESTS)
+		bpErr := &baseplatethrift.Error{
+			Code:    &code,
+			Details: map[string]string{"foo": "bar"},
+		}
+		err := fmt.Errorf("outer: %w", fmt.Errorf("inner: %w", bpErr))
+		obj := logErrorField(t, err)
+
+		if obj["message"] != err.Error() {
+			t.Errorf("Unexpected message %v", obj["message"])
+		}
+		if obj["type"] != "*fmt.wrapError" {
+			t.Errorf("Unexpected type %v", obj["type"])
+		}
+		if obj["code"] != code {
+			t.Errorf("Expected code %d, got %#v", code, obj["code"])
+		}
+		if details, _ := obj["details"].(map[string]interface{}); details["foo"] != "bar" {
+			t.Errorf("Unexpected details %#v", obj["details"])
+		}
+		chain, _ := obj["chain"].([]interface{})
+		if len(chain) != 2 {
+			t.Fatalf("Expected chain of 2, got %#v", obj["chain"])
+		}
+		if last := chain[1].(map[string]interface{}); last["type"] != "*baseplate.Error" {
+			t.Errorf("Unexpected last error in chain %#v", last)
+		}
+		if _, ok := obj["stacktrace"]; ok {
+			t.Errorf("Expected no stacktrace, got %v", obj["stacktrace"])
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		var batch errorsbp.Batch
+		batch.Add(errors.New("foo"), stackTraceError{})
+		obj := logErrorField(t, batch)
+		errs, _ := obj["errors"].([]interface{})
+		if len(errs) != 2 {
+			t.Fatalf("Expected 2 errors, got %#v", obj["errors"])
+		}
+		if msg := errs[0].(map[string]interface{})["message"]; msg != "foo" {
+			t.Errorf("Unexpected first error message %v", msg)
+		}
+	})
+
+	t.Run("stacktrace", func(t *testing.T) {
+		obj := logErrorField(t, fmt.Errorf("wrapped: %w", stackTraceError{}))
+		if obj["stacktrace"] != "[frame1 frame2]" {
+			t.Errorf("Unexpected stacktrace %#v", obj["stacktrace"])
+		}
+	})
+}
+
+func TestErrorCtx(t *testing.T) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	ctx := context.WithValue(context.Background(), contextKey, zap.New(obs).Sugar())
+
+	ErrorCtx(ctx, nil)
+	if n := logs.Len(); n != 0 {
+		t.Fatalf("Expected nil error not to be logged, got %d entries", n)
+	}
+
+	err := fmt.Errorf("wrapped: %w", stackTraceError{})
+	ErrorCtx(ctx, err, "foo", "bar")
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Level != zapcore.ErrorLevel {
+		t.Errorf("Expected error level, got %v", entry.Level)
+	}
+	if entry.Message != err.Error() {
+		t.Errorf("Expected message %q, got %q", err.Error(), entry.Message)
+	}
+	fields := entry.ContextMap()
+	if fields["foo"] != "bar" {
+		t.Errorf("Expected foo=bar, got %#v", fields)
+	}
+	if obj, _ := fields[ErrorKey].(map[string]interface{}); obj["stacktrace"] != "[frame1 frame2]" {
+		t.Errorf("Unexpected error field %#v", fields[ErrorKey])
+	}
+}
@@ -0,0 +1,371 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// globalLevel is the level used by the global logger initialized by the Init*
+// functions.
+//
+// Unlike the level in the zap.Config passed into InitLoggerWithConfig,
+// it's kept across Init* calls so that SetLevel works on the current global
+// logger.
+var globalLevel = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+
+// namedLevels are the per logger name levels overriding globalLevel,
+// stored as map[string]zapcore.Level.
+var namedLevels atomic.Value
+
+func init() {
+	namedLevels.Store(map[string]zapcore.Level{})
+}
+
+// namedLevelsLock guards writes to namedLevels.
+var namedLevelsLock sync.Mutex
+
+// SetLevel changes the level of the global logger at runtime.
+//
+// It affects the global logger and all the loggers derived from it
+// (e.g. the ones attached to context objects via Attach).
+//
+// It has no effects if the global logger was initialized with NopLevel,
+// as in that case the global logger is a nop logger that has no level.
+func SetLevel(level Level) {
+	globalLevel.SetLevel(level.ToZapLevel())
+}
+
+// GetLevel returns the current level of the global logger.
+func GetLevel() Level {
+	return FromZapLevel(globalLevel.Level())
+}
+
+// SetNamedLevel overrides the level for the loggers with the given name at
+// runtime.
+//
+// Logger names are the ones set via zap.Logger.Named
+// (e.g. log.C(ctx).Named("thriftbp")), and nested names are joined by ".".
+// The override of a name also applies to all its nested names that don't have
+// their own overrides, e.g. the override of "thriftbp" also applies to
+// "thriftbp.client".
+//
+// Loggers without any matching overrides use the global level set by SetLevel.
+func SetNamedLevel(name string, level Level) {
+	updateNamedLevels(func(levels map[string]zapcore.Level) {
+		levels[name] = level.ToZapLevel()
+	})
+}
+
+// UnsetNamedLevel removes the override set by SetNamedLevel for the name.
+func UnsetNamedLevel(name string) {
+	updateNamedLevels(func(levels map[string]zapcore.Level) {
+		delete(levels, name)
+	})
+}
+
+// SetNamedLevels replaces all the overrides set by SetNamedLevel with levels.
+func SetNamedLevels(levels map[string]Level) {
+	updateNamedLevels(func(m map[string]zapcore.Level) {
+		for k := range m {
+			delete(m, k)
+		}
+		for k, v := range levels {
+			m[k] = v.ToZapLevel()
+		}
+	})
+}
+
+// GetNamedLevels returns a copy of all the overrides set by SetNamedLevel.
+func GetNamedLevels() map[string]Level {
+	levels := loadNamedLevels()
+	m := make(map[string]Level, len(levels))
+	for k, v := range levels {
+		m[k] = FromZapLevel(v)
+	}
+	return m
+}
+
+func loadNamedLevels() map[string]zapcore.Level {
+	return namedLevels.Load().(map[string]zapcore.Level)
+}
+
+func updateNamedLevels(f func(levels map[string]zapcore.Level)) {
+	namedLevelsLock.Lock()
+	defer namedLevelsLock.Unlock()
+
+	old := loadNamedLevels()
+	levels := make(map[string]zapcore.Level, len(old)+1)
+	for k, v := range old {
+		levels[k] = v
+	}
+	f(levels)
+	namedLevels.Store(levels)
+}
+
+// levelFor returns the effective level for the logger name.
+func levelFor(name string) zapcore.Level {
+	levels := loadNamedLevels()
+	if len(levels) > 0 {
+		for {
+			if level, ok := levels[name]; ok {
+				return level
+			}
+			i := strings.LastIndexByte(name, '.')
+			if i < 0 {
+				break
+			}
+			name = name[:i]
+		}
+	}
+	return globalLevel.Level()
+}
+
+// levelCore is the outermost core of the global logger,
+// deciding whether an entry is enabled by the global level and the named
+// levels.
+//
+// The cores wrapped by it should enable all levels.
+type levelCore struct {
+	zapcore.Core
+}
+
+func (c levelCore) Enabled(level zapcore.Level) bool {
+	// As the logger name is unknown here, it's enabled when any of the global
+	// and named levels enables it, and the actual check is done in Check.
+	if globalLevel.Enabled(level) {
+		return true
+	}
+	for _, l := range loadNamedLevels() {
+		if l.Enabled(level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return levelCore{Core: c.Core.With(fields)}
+}
+
+func (c levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level < zapcore.DPanicLevel && !levelFor(ent.LoggerName).Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// FromZapLevel converts a zapcore.Level back to Level.
+//
+// zapcore.DPanicLevel will be converted to PanicLevel,
+// and unknown levels will be converted to NopLevel.
+func FromZapLevel(l zapcore.Level) Level {
+	switch l {
+	default:
+		return NopLevel
+	case zapcore.DebugLevel:
+		return DebugLevel
+	case zapcore.InfoLevel:
+		return InfoLevel
+	case zapcore.WarnLevel:
+		return WarnLevel
+	case zapcore.ErrorLevel:
+		return ErrorLevel
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return PanicLevel
+	case zapcore.FatalLevel:
+		return FatalLevel
+	}
+}
+
+// ParseLevel parses a Level from its string form.
+//
+// It's case-insensitive, and returns an error if s is not a known Level.
+func ParseLevel(s string) (Level, error) {
+	switch l := Level(strings.ToLower(strings.TrimSpace(s))); l {
+	default:
+		return "", fmt.Errorf("log: unknown level %q", s)
+	case NopLevel, DebugLevel, InfoLevel, WarnLevel, ErrorLevel, PanicLevel, FatalLevel:
+		return l, nil
+	}
+}
+
+// LevelFileParser is a filewatcher.Parser implementation that reads levels
+// from the file and applies them via SetLevel and SetNamedLevels.
+//
+// The file contains levels separated by newlines or commas.
+// Each of them is either a bare level string (e.g. "debug"),
+// which is used as the global level,
+// or in the form of "name=level" (e.g. "thriftbp=warn"),
+// which is used as the level for the loggers with that name.
+// Whitespaces around them are ignored.
+// For example:
+//
+//     info
+//     thriftbp=debug, redisbp=warn
+//
+// If the file doesn't contain a global level,
+// the global level is left unchanged.
+// The named levels in the file replace all the previous named levels.
+//
+// The returned data is of type LevelSpec.
+//
+// It can be used with filewatcher to change the levels of the global logger by
+// updating a watched file, without the need of a redeploy.
+// See Config.LevelFile for the way to enable it from the config.
+func LevelFileParser(r io.Reader) (interface{}, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := ParseLevelSpec(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if spec.Level != "" {
+		SetLevel(spec.Level)
+	}
+	SetNamedLevels(spec.Names)
+	return spec, nil
+}
+
+// LevelSpec is the parsed result of ParseLevelSpec.
+type LevelSpec struct {
+	// The global level, empty if not specified.
+	Level Level
+
+	// The levels for logger names.
+	Names map[string]Level
+}
+
+// ParseLevelSpec parses levels in the format described in LevelFileParser.
+func ParseLevelSpec(s string) (LevelSpec, error) {
+	spec := LevelSpec{
+		Names: make(map[string]Level),
+	}
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == '\n' || r == ','
+	}) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		index := strings.Index(part, "=")
+		if index < 0 {
+			if spec.Level != "" {
+				return LevelSpec{}, fmt.Errorf("log: global level specified at least twice: %q", s)
+			}
+			level, err := ParseLevel(part)
+			if err != nil {
+				return LevelSpec{}, err
+			}
+			spec.Level = level
+			continue
+		}
+		name := strings.TrimSpace(part[:index])
+		if name == "" {
+			return LevelSpec{}, fmt.Errorf("log: empty logger name in %q", part)
+		}
+		if _, ok := spec.Names[name]; ok {
+			return LevelSpec{}, fmt.Errorf("log: level for logger name %q specified at least twice", name)
+		}
+		level, err := ParseLevel(part[index+1:])
+		if err != nil {
+			return LevelSpec{}, err
+		}
+		spec.Names[name] = level
+	}
+	if spec.Level == "" && len(spec.Names) == 0 {
+		return LevelSpec{}, fmt.Errorf("log: no levels found in %q", s)
+	}
+	return spec, nil
+}
+
+type levelPayload struct {
+	Level Level            `json:"level"`
+	Name  string           `json:"name,omitempty"`
+	Names map[string]Level `json:"names,omitempty"`
+}
+
+// LevelHandler returns an http.Handler to get or change the levels of the
+// global logger at runtime.
+//
+// GET requests return the current global level and the levels for logger
+// names (see SetNamedLevel) in JSON format, e.g.:
+//
+//     {"level":"info","names":{"thriftbp":"debug"}}
+//
+// PUT requests change the global level to the one in the request body, e.g.:
+//
+//     {"level":"debug"}
+//
+// When the request body also has a "name", the level for that logger name is
+// changed instead, e.g.:
+//
+//     {"level":"debug","name":"thriftbp"}
+//
+// The response of a successful PUT request is the same as GET requests.
+//
+// Other methods will get a 405 response.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			writeLevelError(w, http.StatusMethodNotAllowed, "only GET and PUT are supported")
+			return
+
+		case http.MethodGet:
+
+		case http.MethodPut:
+			var payload levelPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				writeLevelError(w, http.StatusBadRequest, "malformed request body: "+err.Error())
+				return
+			}
+			level, err := ParseLevel(string(payload.Level))
+			if err != nil {
+				writeLevelError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if payload.Name != "" {
+				SetNamedLevel(payload.Name, level)
+				Infow("log level changed", "level", level, "name", payload.Name)
+			} else {
+				SetLevel(level)
+				Infow("log level changed", "level", level)
+			}
+			Audit(
+				r.Context(),
+				"log level changed",
+				"level", level,
+				"name", payload.Name,
+				"remoteAddr", r.RemoteAddr,
+			)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(levelPayload{
+			Level: GetLevel(),
+			Names: GetNamedLevels(),
+		})
+	})
+}
+
+func writeLevelError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{
+		Error: msg,
+	})
+}
@@ -0,0 +1,233 @@
+package log_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+func TestSetLevel(t *testing.T) {
+	log.InitLoggerJSON(log.InfoLevel)
+	defer log.InitLoggerJSON(log.InfoLevel)
+
+	if got := log.GetLevel(); got != log.InfoLevel {
+		t.Errorf("Expected initial level %q, got %q", log.InfoLevel, got)
+	}
+	if log.C(context.Background()).Desugar().Core().Enabled(log.DebugLevel.ToZapLevel()) {
+		t.Error("Expected debug level to be disabled")
+	}
+
+	log.SetLevel(log.DebugLevel)
+	if got := log.GetLevel(); got != log.DebugLevel {
+		t.Errorf("Expected level %q, got %q", log.DebugLevel, got)
+	}
+	if !log.C(context.Background()).Desugar().Core().Enabled(log.DebugLevel.ToZapLevel()) {
+		t.Error("Expected debug level to be enabled")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, c := range []struct {
+		text     string
+		expected log.Level
+		err      bool
+	}{
+		{
+			text:     "debug",
+			expected: log.DebugLevel,
+		},
+		{
+			text:     " WARN\n",
+			expected: log.WarnLevel,
+		},
+		{
+			text:     "nop",
+			expected: log.NopLevel,
+		},
+		{
+			text: "verbose",
+			err:  true,
+		},
+		{
+			text: "",
+			err:  true,
+		},
+	} {
+		t.Run(c.text, func(t *testing.T) {
+			level, err := log.ParseLevel(c.text)
+			if c.err {
+				if err == nil {
+					t.Errorf("Expected error, got level %q", level)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if level != c.expected {
+				t.Errorf("Expected %q, got %q", c.expected, level)
+			}
+		})
+	}
+}
+
+func TestLevelFileParser(t *testing.T) {
+	log.InitLoggerJSON(log.InfoLevel)
+	defer log.InitLoggerJSON(log.InfoLevel)
+
+	defer log.SetNamedLevels(nil)
+
+	data, err := log.LevelFileParser(strings.NewReader("error\nthriftbp=debug, redisbp = warn\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := data.(log.LevelSpec)
+	if spec.Level != log.ErrorLevel {
+		t.Errorf("Expected parsed level %q, got %v", log.ErrorLevel, data)
+	}
+	expectedNames := map[string]log.Level{
+		"thriftbp": log.DebugLevel,
+		"redisbp":  log.WarnLevel,
+	}
+	if !reflect.DeepEqual(log.GetNamedLevels(), expectedNames) {
+		t.Errorf("Expected named levels %v, got %v", expectedNames, log.GetNamedLevels())
+	}
+	if got := log.GetLevel(); got != log.ErrorLevel {
+		t.Errorf("Expected level %q, got %q", log.ErrorLevel, got)
+	}
+
+	for _, content := range []string{
+		"fancy",
+		"",
+		"debug\ninfo",
+		"=debug",
+		"thriftbp=debug,thriftbp=info",
+		"thriftbp=fancy",
+	} {
+		if _, err := log.LevelFileParser(strings.NewReader(content)); err == nil {
+			t.Errorf("Expected error for %q", content)
+		}
+	}
+	if got := log.GetLevel(); got != log.ErrorLevel {
+		t.Errorf("Expected level to stay %q, got %q", log.ErrorLevel, got)
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	log.InitLoggerJSON(log.InfoLevel)
+	defer log.InitLoggerJSON(log.InfoLevel)
+	defer log.SetNamedLevels(nil)
+
+	handler := log.LevelHandler()
+	for _, c := range []struct {
+		label    string
+		method   string
+		body     string
+		code     int
+		expected log.Level
+	}{
+		{
+			label:    "get",
+			method:   http.MethodGet,
+			code:     http.StatusOK,
+			expected: log.InfoLevel,
+		},
+		{
+			label:    "put",
+			method:   http.MethodPut,
+			body:     `{"level":"debug"}`,
+			code:     http.StatusOK,
+			expected: log.DebugLevel,
+		},
+		{
+			label:    "put-unknown",
+			method:   http.MethodPut,
+			body:     `{"level":"fancy"}`,
+			code:     http.StatusBadRequest,
+			expected: log.DebugLevel,
+		},
+		{
+			label:    "put-malformed",
+			method:   http.MethodPut,
+			body:     `debug`,
+			code:     http.StatusBadRequest,
+			expected: log.DebugLevel,
+		},
+		{
+			label:    "put-named",
+			method:   http.MethodPut,
+			body:     `{"level":"warn","name":"thriftbp"}`,
+			code:     http.StatusOK,
+			expected: log.DebugLevel,
+		},
+		{
+			label:    "post",
+			method:   http.MethodPost,
+			body:     `{"level":"warn"}`,
+			code:     http.StatusMethodNotAllowed,
+			expected: log.DebugLevel,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, "/log/level", strings.NewReader(c.body))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != c.code {
+				t.Errorf("Expected code %d, got %d: %s", c.code, w.Code, w.Body.String())
+			}
+			if got := log.GetLevel(); got != c.expected {
+				t.Errorf("Expected level %q, got %q", c.expected, got)
+			}
+		})
+	}
+	if got := log.GetNamedLevels()["thriftbp"]; got != log.WarnLevel {
+		t.Errorf("Expected level %q for thriftbp, got %q", log.WarnLevel, got)
+	}
+}
+
+func TestNamedLevels(t *testing.T) {
+	log.InitLoggerJSON(log.InfoLevel)
+	defer log.InitLoggerJSON(log.InfoLevel)
+	defer log.SetNamedLevels(nil)
+
+	log.SetNamedLevels(map[string]log.Level{
+		"thriftbp":        log.DebugLevel,
+		"thriftbp.server": log.ErrorLevel,
+		"redisbp":         log.NopLevel,
+	})
+
+	root := log.C(context.Background())
+	for _, c := range []struct {
+		name     string
+		level    log.Level
+		expected bool
+	}{
+		{"", log.DebugLevel, false},
+		{"", log.InfoLevel, true},
+		{"thriftbp", log.DebugLevel, true},
+		{"thriftbp.client", log.DebugLevel, true},
+		{"thriftbp.server", log.WarnLevel, false},
+		{"thriftbp.server", log.ErrorLevel, true},
+		{"redisbp", log.ErrorLevel, false},
+		{"redisbpx", log.InfoLevel, true},
+	} {
+		logger := root.Desugar()
+		if c.name != "" {
+			logger = logger.Named(c.name)
+		}
+		got := logger.Check(c.level.ToZapLevel(), "msg") != nil
+		if got != c.expected {
+			t.Errorf("Expected %v for name %q at level %q, got %v", c.expected, c.name, c.level, got)
+		}
+	}
+
+	log.UnsetNamedLevel("thriftbp")
+	if logger := root.Desugar().Named("thriftbp"); logger.Check(log.DebugLevel.ToZapLevel(), "msg") != nil {
+		t.Error("Expected debug level disabled for thriftbp after UnsetNamedLevel")
+	}
+}
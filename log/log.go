@@ -15,7 +15,7 @@ var globalLogger = zap.New(
 	zapcore.NewCore(
 		zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()),
 		os.Stderr,
-		zapcore.DebugLevel,
+		globalLevel,
 	),
 	zap.Fields(
 		zap.Bool("pre_init", true),
@@ -116,6 +116,13 @@ func InitLogger(logLevel Level) {
 // The JSON format is also compatible with logdna's ingestion format:
 // https://docs.logdna.com/docs/ingestion
 func InitLoggerJSON(logLevel Level) {
+	if err := InitLoggerWithConfig(logLevel, jsonConfig(logLevel)); err != nil {
+		// shouldn't happen, but just in case
+		panic(err)
+	}
+}
+
+func jsonConfig(logLevel Level) zap.Config {
 	config := zap.NewProductionConfig()
 	config.Level = zap.NewAtomicLevelAt(logLevel.ToZapLevel())
 	config.Encoding = "json"
@@ -126,11 +133,7 @@ func InitLoggerJSON(logLevel Level) {
 	// json keys expected by logdna:
 	config.EncoderConfig.MessageKey = "message"
 	config.EncoderConfig.TimeKey = "timestamp"
-
-	if err := InitLoggerWithConfig(logLevel, config); err != nil {
-		// shouldn't happen, but just in case
-		panic(err)
-	}
+	return config
 }
 
 // InitLoggerWithConfig provides a quick way to start or replace the global
@@ -142,15 +145,34 @@ func InitLoggerJSON(logLevel Level) {
 // fields to strings, to prevent the loss of precision by json log ingester.
 // As a result, some of the cfg might get lost during this wrapping, namely
 // OutputPaths and ErrorOutputPaths.
+//
+// The Level in cfg will be replaced by the one backing SetLevel
+// (and SetNamedLevel), set to logLevel.
 func InitLoggerWithConfig(logLevel Level, cfg zap.Config) error {
+	return initLogger(logLevel, cfg)
+}
+
+// initLogger is the implementation of InitLoggerWithConfig with additional
+// core wrappers applied on top of the default one.
+func initLogger(logLevel Level, cfg zap.Config, wrappers ...func(zapcore.Core) zapcore.Core) error {
+	globalLevel.SetLevel(logLevel.ToZapLevel())
+	// The new global logger no longer writes into the previous RecentLogs.
+	globalRecentLogs.Store((*RecentLogs)(nil))
 	if logLevel == NopLevel {
 		globalLogger = zap.NewNop().Sugar()
 		return nil
 	}
+	// The actual level check is done by levelCore.
+	cfg.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
 	l, err := cfg.Build(
 		zap.AddCallerSkip(1),
 		zap.WrapCore(func(core zapcore.Core) zapcore.Core {
-			return wrappedCore{Core: core}
+			core = wrappedCore{Core: core}
+			core = redactingCore{Core: core, redactor: globalRedactor}
+			for _, wrapper := range wrappers {
+				core = wrapper(core)
+			}
+			return levelCore{Core: core}
 		}),
 	)
 	if err != nil {
@@ -0,0 +1,552 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Default values for OTLPConfig.
+const (
+	DefaultOTLPBatchSize     = 512
+	DefaultOTLPQueueSize     = 4096
+	DefaultOTLPFlushInterval = time.Second * 5
+	DefaultOTLPTimeout       = time.Second * 10
+)
+
+// otlpErrorLogInterval is the min interval between the logs of the export
+// errors.
+const otlpErrorLogInterval = time.Minute
+
+// OTLPConfig is the config to be passed into InitOTLP.
+//
+// Can be deserialized from YAML.
+type OTLPConfig struct {
+	// Endpoint is the full URL of the OTLP/HTTP logs endpoint of the collector,
+	// e.g. "http://localhost:4318/v1/logs".
+	//
+	// If empty, InitOTLP is a nop.
+	Endpoint string `yaml:"endpoint"`
+
+	// Headers are the additional HTTP headers sent with every export request.
+	Headers map[string]string `yaml:"headers"`
+
+	// ServiceName is reported as the "service.name" resource attribute.
+	ServiceName string `yaml:"serviceName"`
+
+	// ResourceAttributes are the additional resource attributes to be reported.
+	//
+	// "service.version" (from Version) and "k8s.pod.name" (from $POD_NAME or
+	// $HOSTNAME) are added automatically when not set here.
+	ResourceAttributes map[string]string `yaml:"resourceAttributes"`
+
+	// BatchSize is the max number of log records sent in one request.
+	//
+	// Optional. If <=0, DefaultOTLPBatchSize will be used instead.
+	BatchSize int `yaml:"batchSize"`
+
+	// QueueSize is the max number of log records buffered in memory waiting to
+	// be exported. Log records are dropped when the queue is full.
+	//
+	// The dropped log records, including the ones failed to export, are
+	// counted by the log_otlp_dropped_records_total counter.
+	//
+	// Optional. If <=0, DefaultOTLPQueueSize will be used instead.
+	QueueSize int `yaml:"queueSize"`
+
+	// FlushInterval is the max time a log record can stay in the queue before
+	// being exported.
+	//
+	// Optional. If <=0, DefaultOTLPFlushInterval will be used instead.
+	FlushInterval time.Duration `yaml:"flushInterval"`
+
+	// Timeout is the timeout of every export request.
+	//
+	// Optional. If <=0, DefaultOTLPTimeout will be used instead.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// InitOTLP adds OTLP log export to the global logger,
+// in addition to its current output.
+//
+// It should be called after the global logger is initialized by the Init*
+// functions, and loggers derived from the global logger before InitOTLP is
+// called are not affected.
+// The log records are batched and exported via OTLP/HTTP using the JSON
+// encoding, with the redaction of the global logger applied.
+// The export errors are logged by the global logger before InitOTLP is called,
+// at most once per minute.
+// When a logger has a trace ID attached via Attach,
+// the trace ID is also set on the exported log records.
+//
+// The io.Closer returned flushes the buffered log records and stops the
+// exporter.
+// If cfg.Endpoint is empty, it's a nop and the io.Closer returned is also nop.
+func InitOTLP(cfg OTLPConfig) (io.Closer, error) {
+	if cfg.Endpoint == "" {
+		return nopCloser{}, nil
+	}
+	exporter := newOTLPExporter(cfg, http.DefaultClient)
+	core := levelCore{
+		Core: redactingCore{
+			Core: &otlpCore{
+				LevelEnabler: zapcore.DebugLevel,
+				exporter:     exporter,
+			},
+			redactor: globalRedactor,
+		},
+	}
+	globalLogger = globalLogger.Desugar().WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(c, core)
+	})).Sugar()
+	return exporter, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// otlpCore is a zapcore.Core that sends log entries to an otlpExporter.
+type otlpCore struct {
+	zapcore.LevelEnabler
+
+	exporter *otlpExporter
+	fields   []zapcore.Field
+	traceID  string
+}
+
+func (c *otlpCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &otlpCore{
+		LevelEnabler: c.LevelEnabler,
+		exporter:     c.exporter,
+		fields:       make([]zapcore.Field, 0, len(c.fields)+len(fields)),
+		traceID:      c.traceID,
+	}
+	clone.fields = append(clone.fields, c.fields...)
+	for _, f := range fields {
+		if f.Key == traceIDKey && f.Type == zapcore.StringType {
+			clone.traceID = f.String
+			continue
+		}
+		clone.fields = append(clone.fields, f)
+	}
+	return clone
+}
+
+func (c *otlpCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *otlpCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	record := otlpLogRecord{
+		TimeUnixNano:   strconv.FormatInt(ent.Time.UnixNano(), 10),
+		SeverityNumber: otlpSeverityNumber(ent.Level),
+		SeverityText:   ent.Level.CapitalString(),
+		Body:           otlpValue{StringValue: &ent.Message},
+		Attributes:     otlpAttributes(enc.Fields),
+		TraceID:        otlpTraceID(c.traceID),
+	}
+	if ent.LoggerName != "" {
+		record.Attributes = append(record.Attributes, otlpStringAttribute("logger", ent.LoggerName))
+	}
+	if ent.Caller.Defined {
+		record.Attributes = append(record.Attributes, otlpStringAttribute("caller", ent.Caller.TrimmedPath()))
+	}
+	c.exporter.enqueue(record)
+	return nil
+}
+
+func (c *otlpCore) Sync() error {
+	return c.exporter.flush()
+}
+
+// otlpExporter batches and exports log records to an OTLP/HTTP endpoint.
+type otlpExporter struct {
+	cfg      OTLPConfig
+	client   *http.Client
+	resource otlpResource
+
+	queue   chan otlpLogRecord
+	flushCh chan chan error
+	done    chan struct{}
+
+	closeOnce sync.Once
+	stopped   chan struct{}
+
+	// The logger of the export errors, without OTLP export.
+	logger *zap.SugaredLogger
+	// Only accessed by loop.
+	lastErrorLog time.Time
+	failures     int
+}
+
+func newOTLPExporter(cfg OTLPConfig, client *http.Client) *otlpExporter {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultOTLPBatchSize
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultOTLPQueueSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultOTLPFlushInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultOTLPTimeout
+	}
+	e := &otlpExporter{
+		cfg:      cfg,
+		client:   client,
+		resource: otlpResourceFromConfig(cfg),
+		queue:    make(chan otlpLogRecord, cfg.QueueSize),
+		flushCh:  make(chan chan error),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		logger:   globalLogger,
+	}
+	go e.loop()
+	return e
+}
+
+func otlpResourceFromConfig(cfg OTLPConfig) otlpResource {
+	attrs := make(map[string]string, len(cfg.ResourceAttributes)+3)
+	if cfg.ServiceName != "" {
+		attrs["service.name"] = cfg.ServiceName
+	}
+	if Version != "" {
+		attrs["service.version"] = Version
+	}
+	if pod := os.Getenv("POD_NAME"); pod != "" {
+		attrs["k8s.pod.name"] = pod
+	} else if host, err := os.Hostname(); err == nil {
+		attrs["k8s.pod.name"] = host
+	}
+	for k, v := range cfg.ResourceAttributes {
+		attrs[k] = v
+	}
+	var resource otlpResource
+	for k, v := range attrs {
+		resource.Attributes = append(resource.Attributes, otlpStringAttribute(k, v))
+	}
+	return resource
+}
+
+func (e *otlpExporter) enqueue(record otlpLogRecord) {
+	select {
+	case <-e.done:
+	case e.queue <- record:
+	default:
+		// Queue is full, drop the record.
+		otlpDroppedCounter.WithLabelValues(otlpReasonQueueFull).Inc()
+	}
+}
+
+func (e *otlpExporter) loop() {
+	defer close(e.stopped)
+
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]otlpLogRecord, 0, e.cfg.BatchSize)
+	send := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := e.export(batch)
+		if err != nil {
+			e.exportFailed(len(batch), err)
+		}
+		batch = batch[:0]
+		return err
+	}
+	drain := func() error {
+		var err error
+		for {
+			select {
+			default:
+				if sendErr := send(); sendErr != nil {
+					err = sendErr
+				}
+				return err
+			case record := <-e.queue:
+				batch = append(batch, record)
+				if len(batch) >= e.cfg.BatchSize {
+					if sendErr := send(); sendErr != nil {
+						err = sendErr
+					}
+				}
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-e.done:
+			drain()
+			return
+		case ch := <-e.flushCh:
+			ch <- drain()
+		case <-ticker.C:
+			send()
+		case record := <-e.queue:
+			batch = append(batch, record)
+			if len(batch) >= e.cfg.BatchSize {
+				send()
+			}
+		}
+	}
+}
+
+// exportFailed counts the n log records failed to export as dropped,
+// and logs err unless another export error was logged in the last
+// otlpErrorLogInterval.
+func (e *otlpExporter) exportFailed(n int, err error) {
+	otlpDroppedCounter.WithLabelValues(otlpReasonExportFailed).Add(float64(n))
+	e.failures++
+	now := time.Now()
+	if now.Sub(e.lastErrorLog) < otlpErrorLogInterval {
+		return
+	}
+	e.logger.Errorw(
+		"Failed to export OTLP logs",
+		"err", err,
+		"failures", e.failures,
+	)
+	e.lastErrorLog = now
+	e.failures = 0
+}
+
+func (e *otlpExporter) export(records []otlpLogRecord) error {
+	payload := otlpExportRequest{
+		ResourceLogs: []otlpResourceLogs{{
+			Resource: e.resource,
+			ScopeLogs: []otlpScopeLogs{{
+				Scope: otlpScope{
+					Name: "github.com/reddit/baseplate.go/log",
+				},
+				LogRecords: records,
+			}},
+		}},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("log: failed to encode otlp logs: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("log: failed to create otlp request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("log: failed to export otlp logs: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("log: failed to export otlp logs: http status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// flush exports all the queued log records synchronously.
+func (e *otlpExporter) flush() error {
+	ch := make(chan error, 1)
+	select {
+	case <-e.stopped:
+		return nil
+	case e.flushCh <- ch:
+	}
+	return <-ch
+}
+
+// Close flushes the queued log records and stops the exporter.
+func (e *otlpExporter) Close() error {
+	err := e.flush()
+	e.closeOnce.Do(func() {
+		close(e.done)
+	})
+	<-e.stopped
+	return err
+}
+
+// otlpSeverityNumber maps zap levels to OTLP SeverityNumber.
+func otlpSeverityNumber(l zapcore.Level) int {
+	switch l {
+	default:
+		return 0
+	case zapcore.DebugLevel:
+		return 5
+	case zapcore.InfoLevel:
+		return 9
+	case zapcore.WarnLevel:
+		return 13
+	case zapcore.ErrorLevel:
+		return 17
+	case zapcore.DPanicLevel:
+		return 18
+	case zapcore.PanicLevel:
+		return 21
+	case zapcore.FatalLevel:
+		return 24
+	}
+}
+
+// otlpTraceID converts baseplate trace IDs to the 32-hex-digit OTLP form.
+//
+// Baseplate trace IDs are either 64-bit decimal or 64/128-bit hex.
+func otlpTraceID(id string) string {
+	switch len(id) {
+	case 0:
+		return ""
+	case 32:
+		return id
+	case 16:
+		if _, err := strconv.ParseUint(id, 16, 64); err == nil {
+			return strings.Repeat("0", 16) + id
+		}
+	}
+	if v, err := strconv.ParseUint(id, 10, 64); err == nil {
+		return fmt.Sprintf("%032x", v)
+	}
+	return ""
+}
+
+func otlpAttributes(fields map[string]interface{}) []otlpKeyValue {
+	attrs := make([]otlpKeyValue, 0, len(fields))
+	for k, v := range fields {
+		attrs = append(attrs, otlpKeyValue{
+			Key:   k,
+			Value: otlpAnyValue(v),
+		})
+	}
+	return attrs
+}
+
+func otlpStringAttribute(key, value string) otlpKeyValue {
+	return otlpKeyValue{
+		Key:   key,
+		Value: otlpValue{StringValue: &value},
+	}
+}
+
+func otlpAnyValue(v interface{}) otlpValue {
+	switch v := v.(type) {
+	case string:
+		return otlpValue{StringValue: &v}
+	case bool:
+		return otlpValue{BoolValue: &v}
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr:
+		s := fmt.Sprintf("%d", v)
+		return otlpValue{IntValue: &s}
+	case float32:
+		f := float64(v)
+		return otlpFloatValue(f)
+	case float64:
+		return otlpFloatValue(v)
+	case map[string]interface{}:
+		return otlpValue{KvlistValue: &otlpKeyValueList{Values: otlpAttributes(v)}}
+	case []interface{}:
+		values := make([]otlpValue, len(v))
+		for i, e := range v {
+			values[i] = otlpAnyValue(e)
+		}
+		return otlpValue{ArrayValue: &otlpArrayValue{Values: values}}
+	default:
+		s := fmt.Sprint(v)
+		return otlpValue{StringValue: &s}
+	}
+}
+
+func otlpFloatValue(f float64) otlpValue {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		// Not representable in JSON.
+		s := strconv.FormatFloat(f, 'g', -1, 64)
+		return otlpValue{StringValue: &s}
+	}
+	return otlpValue{DoubleValue: &f}
+}
+
+// JSON encoding of OTLP logs data model, see
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/logs/v1/logs.proto
+type otlpExportRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   string         `json:"timeUnixNano"`
+	SeverityNumber int            `json:"severityNumber"`
+	SeverityText   string         `json:"severityText"`
+	Body           otlpValue      `json:"body"`
+	Attributes     []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID        string         `json:"traceId,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string           `json:"stringValue,omitempty"`
+	BoolValue   *bool             `json:"boolValue,omitempty"`
+	IntValue    *string           `json:"intValue,omitempty"`
+	DoubleValue *float64          `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue   `json:"arrayValue,omitempty"`
+	KvlistValue *otlpKeyValueList `json:"kvlistValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpValue `json:"values"`
+}
+
+type otlpKeyValueList struct {
+	Values []otlpKeyValue `json:"values"`
+}
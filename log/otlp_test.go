@@ -0,0 +1,185 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestOTLPTraceID(t *testing.T) {
+	for _, c := range []struct {
+		id       string
+		expected string
+	}{
+		{"", ""},
+		{"1234", "000000000000000000000000000004d2"},
+		{"00000000000004d2", "000000000000000000000000000004d2"},
+		{"0123456789abcdef0123456789abcdef", "0123456789abcdef0123456789abcdef"},
+		{"not-an-id", ""},
+	} {
+		if got := otlpTraceID(c.id); got != c.expected {
+			t.Errorf("otlpTraceID(%q) expected %q, got %q", c.id, c.expected, got)
+		}
+	}
+}
+
+func TestInitOTLP(t *testing.T) {
+	var (
+		lock     sync.Mutex
+		requests []otlpExportRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected content type application/json, got %q", ct)
+		}
+		if auth := r.Header.Get("X-Auth"); auth != "token" {
+			t.Errorf("Expected X-Auth header, got %q", auth)
+		}
+		var req otlpExportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		requests = append(requests, req)
+	}))
+	defer server.Close()
+
+	InitLoggerJSON(InfoLevel)
+	defer InitLoggerJSON(InfoLevel)
+
+	closer, err := InitOTLP(OTLPConfig{
+		Endpoint:      server.URL,
+		Headers:       map[string]string{"X-Auth": "token"},
+		ServiceName:   "test-service",
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := Attach(context.Background(), AttachArgs{TraceID: "1234"})
+	C(ctx).Infow("hello", "key", "value", "n", 42)
+	C(ctx).Debug("filtered")
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Logging after close should not block or panic.
+	C(ctx).Info("after close")
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 export request, got %d", len(requests))
+	}
+	rl := requests[0].ResourceLogs[0]
+	var serviceName string
+	for _, attr := range rl.Resource.Attributes {
+		if attr.Key == "service.name" {
+			serviceName = *attr.Value.StringValue
+		}
+	}
+	if serviceName != "test-service" {
+		t.Errorf("Expected service.name %q, got %q", "test-service", serviceName)
+	}
+	records := rl.ScopeLogs[0].LogRecords
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 log record, got %#v", records)
+	}
+	record := records[0]
+	if *record.Body.StringValue != "hello" {
+		t.Errorf("Expected body %q, got %q", "hello", *record.Body.StringValue)
+	}
+	if record.SeverityNumber != 9 || record.SeverityText != "INFO" {
+		t.Errorf("Unexpected severity %d/%q", record.SeverityNumber, record.SeverityText)
+	}
+	if record.TraceID != "000000000000000000000000000004d2" {
+		t.Errorf("Unexpected trace id %q", record.TraceID)
+	}
+	attrs := make(map[string]otlpValue)
+	for _, attr := range record.Attributes {
+		attrs[attr.Key] = attr.Value
+	}
+	if v := attrs["key"].StringValue; v == nil || *v != "value" {
+		t.Errorf("Unexpected attribute key: %#v", attrs["key"])
+	}
+	// The int64 fields are converted to strings by wrappedCore in the tee
+	// before reaching otlpCore.
+	if v := attrs["n"].StringValue; v == nil || *v != "42" {
+		t.Errorf("Unexpected attribute n: %#v", attrs["n"])
+	}
+}
+
+func TestInitOTLPNop(t *testing.T) {
+	before := globalLogger
+	closer, err := InitOTLP(OTLPConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if globalLogger != before {
+		t.Error("Expected global logger unchanged with empty endpoint")
+	}
+	if err := closer.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOTLPExportFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	defer func(logger *zap.SugaredLogger) {
+		globalLogger = logger
+	}(globalLogger)
+	core, logs := observer.New(zapcore.DebugLevel)
+	globalLogger = zap.New(core).Sugar()
+
+	counter := otlpDroppedCounter.WithLabelValues(otlpReasonExportFailed)
+	before := testutil.ToFloat64(counter)
+	e := newOTLPExporter(OTLPConfig{
+		Endpoint:      server.URL,
+		FlushInterval: time.Hour,
+	}, server.Client())
+	defer e.Close()
+
+	for i := 0; i < 2; i++ {
+		e.enqueue(otlpLogRecord{})
+		e.enqueue(otlpLogRecord{})
+		if err := e.flush(); err == nil {
+			t.Error("Expected flush to return the export error")
+		}
+	}
+	if diff := testutil.ToFloat64(counter) - before; diff != 4 {
+		t.Errorf("Expected 4 records counted as failed, got %v", diff)
+	}
+	// The second failure within otlpErrorLogInterval is not logged.
+	if got := logs.FilterMessage("Failed to export OTLP logs").Len(); got != 1 {
+		t.Errorf("Expected the export error logged once, got %d", got)
+	}
+}
+
+func TestOTLPQueueFull(t *testing.T) {
+	counter := otlpDroppedCounter.WithLabelValues(otlpReasonQueueFull)
+	before := testutil.ToFloat64(counter)
+	// Without the loop consuming the queue.
+	e := &otlpExporter{
+		queue: make(chan otlpLogRecord, 1),
+		done:  make(chan struct{}),
+	}
+	e.enqueue(otlpLogRecord{})
+	e.enqueue(otlpLogRecord{})
+	if diff := testutil.ToFloat64(counter) - before; diff != 1 {
+		t.Errorf("Expected 1 record counted as dropped, got %v", diff)
+	}
+}
@@ -0,0 +1,25 @@
+package log
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus label names reported by log.
+const (
+	// PrometheusReasonLabel is the label name of the reasons the OTLP log
+	// records are dropped, either "queue_full" or "export_failed".
+	PrometheusReasonLabel = "log_otlp_reason"
+)
+
+const (
+	otlpReasonQueueFull    = "queue_full"
+	otlpReasonExportFailed = "export_failed"
+)
+
+var otlpDroppedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "log_otlp_dropped_records_total",
+	Help: "Total number of the log records dropped by the OTLP exporter because the queue is full or the export failed",
+}, []string{
+	PrometheusReasonLabel,
+})
@@ -0,0 +1,198 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RecentLogs is a bounded ring buffer of the most recent log entries,
+// encoded in JSON.
+//
+// It's safe to be used concurrently.
+type RecentLogs struct {
+	lock    sync.Mutex
+	entries []recentEntry
+	next    int
+	full    bool
+}
+
+type recentEntry struct {
+	level zapcore.Level
+	data  []byte
+}
+
+// NewRecentLogs creates a RecentLogs keeping the most recent size entries.
+//
+// size must be positive.
+func NewRecentLogs(size int) *RecentLogs {
+	return &RecentLogs{
+		entries: make([]recentEntry, size),
+	}
+}
+
+func (r *RecentLogs) add(level zapcore.Level, data []byte) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.entries[r.next] = recentEntry{
+		level: level,
+		data:  data,
+	}
+	r.next++
+	if r.next == len(r.entries) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// Entries returns the JSON encoded entries at level or above in the buffer,
+// from the oldest to the newest.
+func (r *RecentLogs) Entries(level Level) [][]byte {
+	zapLevel := level.ToZapLevel()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var ordered []recentEntry
+	if r.full {
+		ordered = append(ordered, r.entries[r.next:]...)
+	}
+	ordered = append(ordered, r.entries[:r.next]...)
+
+	result := make([][]byte, 0, len(ordered))
+	for _, e := range ordered {
+		if e.level >= zapLevel {
+			result = append(result, e.data)
+		}
+	}
+	return result
+}
+
+// Core returns a zapcore.Core that writes all the entries, regardless of their
+// levels, into r.
+func (r *RecentLogs) Core() zapcore.Core {
+	return recentLogsCore{
+		enc:    zapcore.NewJSONEncoder(jsonConfig(DebugLevel).EncoderConfig),
+		buffer: r,
+	}
+}
+
+type recentLogsCore struct {
+	enc    zapcore.Encoder
+	buffer *RecentLogs
+}
+
+func (recentLogsCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c recentLogsCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return recentLogsCore{
+		enc:    enc,
+		buffer: c.buffer,
+	}
+}
+
+func (c recentLogsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c recentLogsCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	c.buffer.add(ent.Level, bytes.TrimSpace(append([]byte(nil), buf.Bytes()...)))
+	return nil
+}
+
+func (recentLogsCore) Sync() error {
+	return nil
+}
+
+// globalRecentLogs is the RecentLogs used by the global logger,
+// stored as *RecentLogs.
+var globalRecentLogs atomic.Value
+
+// InitRecentLogs makes the global logger keep the most recent size log
+// entries in memory, at all levels,
+// even when they are below the level of the global logger.
+//
+// The recent entries can be retrieved via RecentLogsHandler.
+//
+// It should be called after the global logger is initialized by the Init*
+// functions, and loggers derived from the global logger before
+// InitRecentLogs is called are not affected.
+// InitFromConfig calls it when Config.RecentLogs is positive.
+//
+// Note that with it enabled, all the log entries are always constructed and
+// encoded, which makes logs below the level of the global logger no longer
+// nearly free.
+//
+// If size is not positive, it's a nop.
+func InitRecentLogs(size int) *RecentLogs {
+	if size <= 0 {
+		return nil
+	}
+	recent := NewRecentLogs(size)
+	core := redactingCore{
+		Core:     recent.Core(),
+		redactor: globalRedactor,
+	}
+	globalLogger = globalLogger.Desugar().WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(c, core)
+	})).Sugar()
+	globalRecentLogs.Store(recent)
+	return recent
+}
+
+// RecentLogsHandler returns an http.Handler that returns the recent log
+// entries kept by InitRecentLogs, as a JSON array from the oldest to the
+// newest.
+//
+// The optional "level" query parameter can be used to only return entries at
+// that level or above, e.g. "?level=warn".
+//
+// If InitRecentLogs was not called, it returns 404.
+func RecentLogsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recent, _ := globalRecentLogs.Load().(*RecentLogs)
+		if recent == nil {
+			http.Error(w, "recent logs not enabled", http.StatusNotFound)
+			return
+		}
+
+		level := DebugLevel
+		if s := r.URL.Query().Get("level"); s != "" {
+			var err error
+			level, err = ParseLevel(s)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		entries := recent.Entries(level)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Entries-Count", strconv.Itoa(len(entries)))
+		w.Write([]byte("["))
+		for i, e := range entries {
+			if i > 0 {
+				w.Write([]byte(","))
+			}
+			w.Write(e)
+		}
+		w.Write([]byte("]\n"))
+	})
+}
@@ -0,0 +1,64 @@
+package log_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+func TestRecentLogs(t *testing.T) {
+	log.InitLoggerJSON(log.ErrorLevel)
+	defer log.InitLoggerJSON(log.InfoLevel)
+
+	recent := log.InitRecentLogs(3)
+	log.Debugw("debug 1")
+	log.Infow("info 2", "key", "value")
+	log.Warnw("warn 3")
+	log.Errorw("error 4")
+
+	entries := recent.Entries(log.DebugLevel)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d: %q", len(entries), entries)
+	}
+	var first map[string]interface{}
+	if err := json.Unmarshal(entries[0], &first); err != nil {
+		t.Fatal(err)
+	}
+	if first["message"] != "info 2" || first["key"] != "value" {
+		t.Errorf("Unexpected oldest entry %s", entries[0])
+	}
+	if got := len(recent.Entries(log.WarnLevel)); got != 2 {
+		t.Errorf("Expected 2 entries at warn level or above, got %d", got)
+	}
+
+	for _, c := range []struct {
+		query    string
+		code     int
+		expected int
+	}{
+		{"", http.StatusOK, 3},
+		{"?level=error", http.StatusOK, 1},
+		{"?level=fancy", http.StatusBadRequest, 0},
+	} {
+		t.Run(c.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			log.RecentLogsHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/logs"+c.query, nil))
+			if w.Code != c.code {
+				t.Fatalf("Expected code %d, got %d", c.code, w.Code)
+			}
+			if c.code != http.StatusOK {
+				return
+			}
+			var result []map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatalf("Failed to decode %s: %v", w.Body.String(), err)
+			}
+			if len(result) != c.expected {
+				t.Errorf("Expected %d entries, got %d", c.expected, len(result))
+			}
+		})
+	}
+}
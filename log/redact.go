@@ -0,0 +1,309 @@
+package log
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// RedactedValue is the value used to replace redacted data in logs.
+const RedactedValue = "[REDACTED]"
+
+// MinRedactedValueLength is the minimal length of the values registered via
+// SetRedactedValues.
+//
+// Shorter values are ignored, as redacting them would likely redact a lot of
+// unrelated data.
+const MinRedactedValueLength = 6
+
+// Patterns that can be used in RedactConfig.Patterns or RedactPatterns.
+var (
+	// EmailPattern matches email addresses.
+	EmailPattern = regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`)
+
+	// BearerTokenPattern matches bearer tokens in authorization headers.
+	BearerTokenPattern = regexp.MustCompile(`(?i)bearer\s+[a-zA-Z0-9\-._~+/]+=*`)
+)
+
+// FieldProcessor is a hook to process log fields before they are written.
+//
+// It's called with every field attached to a log entry,
+// including the ones attached via With,
+// and the returned field will be written instead.
+// Implementations should return the field unchanged if they are not
+// interested in it.
+type FieldProcessor func(field zapcore.Field) zapcore.Field
+
+// RedactConfig defines the config of the redaction of the global logger.
+//
+// Can be deserialized from YAML.
+type RedactConfig struct {
+	// Fields are the names of the fields which values should always be
+	// redacted, case-insensitive.
+	Fields []string `yaml:"fields"`
+
+	// Patterns are the regular expressions to be redacted from log messages and
+	// string field values.
+	Patterns []string `yaml:"patterns"`
+
+	// When DefaultPatterns is true, EmailPattern and BearerTokenPattern are
+	// redacted in addition to Patterns.
+	DefaultPatterns bool `yaml:"defaultPatterns"`
+}
+
+// InitRedaction sets up the redaction of the global logger from cfg.
+//
+// It's called by InitFromConfig and only need to be called directly if you
+// initialize the global logger via other Init* functions.
+func InitRedaction(cfg RedactConfig) error {
+	patterns := make([]*regexp.Regexp, 0, len(cfg.Patterns)+2)
+	for _, p := range cfg.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("log: failed to compile redact pattern %q: %w", p, err)
+		}
+		patterns = append(patterns, re)
+	}
+	if cfg.DefaultPatterns {
+		patterns = append(patterns, EmailPattern, BearerTokenPattern)
+	}
+	processors := make([]FieldProcessor, 0, 2)
+	if len(cfg.Fields) > 0 {
+		processors = append(processors, RedactFields(cfg.Fields...))
+	}
+	if len(patterns) > 0 {
+		processors = append(processors, RedactPatterns(patterns...))
+	}
+	globalRedactor.setConfigProcessors(processors, patterns)
+	return nil
+}
+
+// AddFieldProcessors adds custom FieldProcessors to the global logger.
+//
+// They are called after the ones from RedactConfig, in the order added.
+func AddFieldProcessors(processors ...FieldProcessor) {
+	globalRedactor.addProcessors(processors...)
+}
+
+// SetRedactedValues registers literal values to be redacted from log
+// messages and string field values of the global logger.
+//
+// Values are grouped by owner,
+// every call replaces all the values previously registered by the same owner.
+// Values shorter than MinRedactedValueLength are ignored.
+//
+// This is the registry the secrets store uses to make sure the secrets it
+// loaded never appear in logs (see secrets.LogRedactionMiddleware).
+func SetRedactedValues(owner string, values []string) {
+	globalRedactor.setValues(owner, values)
+}
+
+// RedactFields returns a FieldProcessor that redacts the values of the fields
+// with the given names, case-insensitive.
+func RedactFields(names ...string) FieldProcessor {
+	m := make(map[string]bool, len(names))
+	for _, name := range names {
+		m[strings.ToLower(name)] = true
+	}
+	return func(field zapcore.Field) zapcore.Field {
+		if m[strings.ToLower(field.Key)] {
+			return redactedField(field.Key)
+		}
+		return field
+	}
+}
+
+// RedactPatterns returns a FieldProcessor that redacts the parts of string
+// field values matching any of the patterns.
+func RedactPatterns(patterns ...*regexp.Regexp) FieldProcessor {
+	return func(field zapcore.Field) zapcore.Field {
+		return redactFieldString(field, func(s string) string {
+			return redactPatterns(s, patterns)
+		})
+	}
+}
+
+func redactedField(key string) zapcore.Field {
+	return zapcore.Field{
+		Key:    key,
+		Type:   zapcore.StringType,
+		String: RedactedValue,
+	}
+}
+
+func redactPatterns(s string, patterns []*regexp.Regexp) string {
+	for _, re := range patterns {
+		s = re.ReplaceAllLiteralString(s, RedactedValue)
+	}
+	return s
+}
+
+// redactFieldString applies f to the string representation of the field,
+// for field types that are commonly used to carry free form strings.
+func redactFieldString(field zapcore.Field, f func(string) string) zapcore.Field {
+	var s string
+	switch field.Type {
+	default:
+		return field
+	case zapcore.StringType:
+		s = field.String
+	case zapcore.ByteStringType:
+		s = string(field.Interface.([]byte))
+	case zapcore.ErrorType:
+		if err, ok := field.Interface.(error); ok && err != nil {
+			s = err.Error()
+		}
+	case zapcore.StringerType:
+		if stringer, ok := field.Interface.(fmt.Stringer); ok && stringer != nil {
+			s = stringer.String()
+		}
+	}
+	redacted := f(s)
+	if redacted == s {
+		return field
+	}
+	return zapcore.Field{
+		Key:    field.Key,
+		Type:   zapcore.StringType,
+		String: redacted,
+	}
+}
+
+type redactorState struct {
+	configProcessors []FieldProcessor
+	customProcessors []FieldProcessor
+	patterns         []*regexp.Regexp
+	values           map[string][]string
+	replacer         *strings.Replacer
+}
+
+func (s *redactorState) enabled() bool {
+	return len(s.configProcessors) > 0 || len(s.customProcessors) > 0 || s.replacer != nil
+}
+
+type redactor struct {
+	lock  sync.Mutex // guards writes to state
+	state atomic.Value
+}
+
+var globalRedactor = newRedactor()
+
+func newRedactor() *redactor {
+	r := new(redactor)
+	r.state.Store(&redactorState{})
+	return r
+}
+
+func (r *redactor) load() *redactorState {
+	return r.state.Load().(*redactorState)
+}
+
+func (r *redactor) update(f func(s *redactorState)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	s := *r.load()
+	f(&s)
+	r.state.Store(&s)
+}
+
+func (r *redactor) setConfigProcessors(processors []FieldProcessor, patterns []*regexp.Regexp) {
+	r.update(func(s *redactorState) {
+		s.configProcessors = processors
+		s.patterns = patterns
+	})
+}
+
+func (r *redactor) addProcessors(processors ...FieldProcessor) {
+	r.update(func(s *redactorState) {
+		s.customProcessors = append(s.customProcessors[:len(s.customProcessors):len(s.customProcessors)], processors...)
+	})
+}
+
+func (r *redactor) setValues(owner string, values []string) {
+	r.update(func(s *redactorState) {
+		all := make(map[string][]string, len(s.values)+1)
+		for k, v := range s.values {
+			all[k] = v
+		}
+		filtered := make([]string, 0, len(values))
+		for _, v := range values {
+			if len(v) >= MinRedactedValueLength {
+				filtered = append(filtered, v)
+			}
+		}
+		all[owner] = filtered
+
+		var oldnew []string
+		for _, v := range all {
+			for _, value := range v {
+				oldnew = append(oldnew, value, RedactedValue)
+			}
+		}
+		s.values = all
+		s.replacer = nil
+		if len(oldnew) > 0 {
+			s.replacer = strings.NewReplacer(oldnew...)
+		}
+	})
+}
+
+func (s *redactorState) redactString(str string) string {
+	if s.replacer != nil {
+		str = s.replacer.Replace(str)
+	}
+	return str
+}
+
+func (s *redactorState) processFields(fields []zapcore.Field) []zapcore.Field {
+	for i, f := range fields {
+		for _, p := range s.configProcessors {
+			f = p(f)
+		}
+		for _, p := range s.customProcessors {
+			f = p(f)
+		}
+		if s.replacer != nil {
+			f = redactFieldString(f, s.redactString)
+		}
+		fields[i] = f
+	}
+	return fields
+}
+
+// redactingCore is a zapcore.Core applying the redactor to the message and
+// fields of all log entries.
+type redactingCore struct {
+	zapcore.Core
+
+	redactor *redactor
+}
+
+func (c redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	if s := c.redactor.load(); s.enabled() {
+		fields = s.processFields(fields)
+	}
+	return redactingCore{
+		Core:     c.Core.With(fields),
+		redactor: c.redactor,
+	}
+}
+
+func (c redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if s := c.redactor.load(); s.enabled() {
+		entry.Message = redactPatterns(s.redactString(entry.Message), s.patterns)
+		fields = s.processFields(fields)
+	}
+	return c.Core.Write(entry, fields)
+}
+
+func (c redactingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
@@ -0,0 +1,95 @@
+package log
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedactingCore(t *testing.T) {
+	r := newRedactor()
+	obs, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(redactingCore{Core: obs, redactor: r})
+
+	logger.Info("no redaction", zap.String("password", "hunter2"))
+	if got := logs.TakeAll()[0].ContextMap()["password"]; got != "hunter2" {
+		t.Errorf("Expected no redaction without config, got %v", got)
+	}
+
+	r.setConfigProcessors(
+		[]FieldProcessor{
+			RedactFields("Password"),
+			RedactPatterns(EmailPattern),
+		},
+		[]*regexp.Regexp{EmailPattern},
+	)
+	r.addProcessors(func(field zapcore.Field) zapcore.Field {
+		if field.Key == "custom" {
+			field.String = "processed"
+		}
+		return field
+	})
+	r.setValues("test", []string{"s3cr3t-value", "short"})
+
+	logger.With(zap.String("attached", "foo@example.com")).Info(
+		"user foo@example.com used s3cr3t-value",
+		zap.String("password", "hunter2"),
+		zap.Int("PASSWORD", 42),
+		zap.String("custom", "raw"),
+		zap.Error(errors.New("token s3cr3t-value rejected")),
+		zap.String("short", "short"),
+	)
+	entries := logs.TakeAll()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	const expectedMsg = "user [REDACTED] used [REDACTED]"
+	if entries[0].Message != expectedMsg {
+		t.Errorf("Expected message %q, got %q", expectedMsg, entries[0].Message)
+	}
+	fields := entries[0].ContextMap()
+	for k, v := range map[string]string{
+		"attached": RedactedValue,
+		"password": RedactedValue,
+		"PASSWORD": RedactedValue,
+		"custom":   "processed",
+		"error":    "token [REDACTED] rejected",
+		"short":    "short",
+	} {
+		if fields[k] != v {
+			t.Errorf("Expected field %q to be %q, got %v", k, v, fields[k])
+		}
+	}
+
+	r.setValues("test", nil)
+	logger.Info("s3cr3t-value")
+	if got := logs.TakeAll()[0].Message; got != "s3cr3t-value" {
+		t.Errorf("Expected values to be replaced, got message %q", got)
+	}
+}
+
+func TestInitRedaction(t *testing.T) {
+	defer InitRedaction(RedactConfig{})
+
+	if err := InitRedaction(RedactConfig{Patterns: []string{"("}}); err == nil {
+		t.Error("Expected error for malformed pattern")
+	}
+	if err := InitRedaction(RedactConfig{
+		Fields:          []string{"token"},
+		Patterns:        []string{`\d{3}-\d{4}`},
+		DefaultPatterns: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s := globalRedactor.load()
+	if len(s.configProcessors) != 2 {
+		t.Errorf("Expected 2 processors, got %d", len(s.configProcessors))
+	}
+	if len(s.patterns) != 3 {
+		t.Errorf("Expected 3 patterns, got %d", len(s.patterns))
+	}
+}
@@ -0,0 +1,212 @@
+package log
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultSamplingTick is the default SamplingConfig.Tick used when it's <= 0.
+const DefaultSamplingTick = time.Second
+
+// SuppressedLogMessage is the message of the summary log entry emitted by
+// the sampling core when some log entries were suppressed.
+const SuppressedLogMessage = "log entries suppressed by sampling"
+
+// SamplingConfig defines the config for log sampling.
+//
+// Log entries are sampled by their level and message.
+// Within every Tick, the first Initial entries with the same level and message
+// are logged, after that only every Thereafter-th entry is logged.
+//
+// When some entries are suppressed during a tick, a summary entry with the
+// message SuppressedLogMessage is logged at warn level when the tick ends
+// (or earlier, when the logger is synced), with the level and message of the
+// suppressed entries and the number of entries suppressed.
+// The summary entries only have the fields of the core passed into
+// NewSamplingCore, not the ones added by With.
+//
+// Can be deserialized from YAML.
+type SamplingConfig struct {
+	// Initial is the number of entries with the same level and message to be
+	// logged in every tick before sampling kicks in.
+	Initial int `yaml:"initial"`
+
+	// Thereafter is the sampling rate after Initial is reached.
+	//
+	// For example, 100 means only 1 in every 100 entries will be logged.
+	// When it's <= 0, all entries after Initial will be suppressed.
+	Thereafter int `yaml:"thereafter"`
+
+	// Tick is the duration of the sampling window.
+	//
+	// Optional. If <=0, DefaultSamplingTick will be used instead.
+	Tick time.Duration `yaml:"tick"`
+}
+
+type samplingKey struct {
+	level   zapcore.Level
+	message string
+}
+
+type samplingCounter struct {
+	count      int
+	suppressed int
+}
+
+// samplingState is shared by all the cores derived from the same sampling
+// core via With.
+type samplingState struct {
+	cfg  SamplingConfig
+	now  func() time.Time
+	root zapcore.Core
+
+	lock      sync.Mutex
+	tickStart time.Time
+	counters  map[samplingKey]*samplingCounter
+	// The timer to flush the summaries at the end of the tick,
+	// only set when there are suppressed entries not reported yet.
+	timer *time.Timer
+}
+
+// check returns whether an entry should be logged,
+// and the suppressed counts from the previous tick if the tick just rolled
+// over before the timer flushing them fired.
+func (s *samplingState) check(ent zapcore.Entry) (sampled bool, summary map[samplingKey]int) {
+	now := s.now()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if now.Sub(s.tickStart) >= s.cfg.Tick {
+		summary = s.takeSuppressed()
+		s.counters = make(map[samplingKey]*samplingCounter)
+		s.tickStart = now
+	}
+
+	key := samplingKey{
+		level:   ent.Level,
+		message: ent.Message,
+	}
+	counter := s.counters[key]
+	if counter == nil {
+		counter = new(samplingCounter)
+		s.counters[key] = counter
+	}
+	counter.count++
+	n := counter.count - s.cfg.Initial
+	if n <= 0 || (s.cfg.Thereafter > 0 && n%s.cfg.Thereafter == 0) {
+		return true, summary
+	}
+	counter.suppressed++
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.tickStart.Add(s.cfg.Tick).Sub(now), s.flush)
+	}
+	return false, summary
+}
+
+// takeSuppressed returns and resets the suppressed counts.
+//
+// It must be called with the lock held.
+func (s *samplingState) takeSuppressed() map[samplingKey]int {
+	var summary map[samplingKey]int
+	for key, counter := range s.counters {
+		if counter.suppressed > 0 {
+			if summary == nil {
+				summary = make(map[samplingKey]int)
+			}
+			summary[key] = counter.suppressed
+			counter.suppressed = 0
+		}
+	}
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	return summary
+}
+
+// flush writes the summaries of the entries suppressed so far.
+func (s *samplingState) flush() {
+	s.lock.Lock()
+	summary := s.takeSuppressed()
+	s.lock.Unlock()
+	s.writeSummaries(s.now(), summary)
+}
+
+// writeSummaries writes the summaries to the root core,
+// so they don't carry the fields of the core logging the suppressed entries.
+func (s *samplingState) writeSummaries(t time.Time, summary map[samplingKey]int) {
+	for key, suppressed := range summary {
+		ent := zapcore.Entry{
+			Level:   zapcore.WarnLevel,
+			Time:    t,
+			Message: SuppressedLogMessage,
+		}
+		if ce := s.root.Check(ent, nil); ce != nil {
+			ce.Write(
+				zap.String("sampledLevel", key.level.String()),
+				zap.String("sampledMessage", key.message),
+				zap.Int("suppressed", suppressed),
+			)
+		}
+	}
+}
+
+type samplingCore struct {
+	zapcore.Core
+
+	state *samplingState
+}
+
+// NewSamplingCore wraps a zapcore.Core with sampling.
+//
+// See SamplingConfig for more details.
+//
+// Entries at DPanic level and above are never sampled.
+func NewSamplingCore(core zapcore.Core, cfg SamplingConfig) zapcore.Core {
+	if cfg.Tick <= 0 {
+		cfg.Tick = DefaultSamplingTick
+	}
+	return samplingCore{
+		Core: core,
+		state: &samplingState{
+			cfg:      cfg,
+			now:      time.Now,
+			root:     core,
+			counters: make(map[samplingKey]*samplingCounter),
+		},
+	}
+}
+
+func (s samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return samplingCore{
+		Core:  s.Core.With(fields),
+		state: s.state,
+	}
+}
+
+func (s samplingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !s.Enabled(ent.Level) {
+		return ce
+	}
+	if ent.Level >= zapcore.DPanicLevel {
+		return s.Core.Check(ent, ce)
+	}
+
+	sampled, summary := s.state.check(ent)
+	s.state.writeSummaries(ent.Time, summary)
+	if !sampled {
+		return ce
+	}
+	return s.Core.Check(ent, ce)
+}
+
+// Sync writes the summaries of the entries suppressed so far before syncing
+// the underlying core.
+func (s samplingCore) Sync() error {
+	s.state.flush()
+	return s.Core.Sync()
+}
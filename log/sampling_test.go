@@ -0,0 +1,149 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSamplingCore(t *testing.T) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	core := NewSamplingCore(obs, SamplingConfig{
+		Initial:    2,
+		Thereafter: 3,
+		Tick:       time.Minute,
+	})
+	now := time.Unix(0, 0)
+	core.(samplingCore).state.now = func() time.Time {
+		return now
+	}
+	logger := zap.New(core).With(zap.String("key", "value"))
+
+	for i := 0; i < 10; i++ {
+		logger.Info("hot message")
+	}
+	logger.Warn("hot message")
+	logger.Info("other message")
+
+	// 10 "hot message": 1, 2 (initial), 5, 8 (thereafter).
+	// 1 warn "hot message" and 1 "other message" are counted separately.
+	if got := logs.FilterMessage("hot message").Len(); got != 5 {
+		t.Errorf("Expected 5 hot messages logged, got %d", got)
+	}
+	if got := logs.FilterMessage("other message").Len(); got != 1 {
+		t.Errorf("Expected 1 other message logged, got %d", got)
+	}
+	if got := logs.FilterMessage(SuppressedLogMessage).Len(); got != 0 {
+		t.Errorf("Expected no summary before the tick ends, got %d", got)
+	}
+	for _, entry := range logs.All() {
+		if entry.ContextMap()["key"] != "value" {
+			t.Errorf("Expected fields from With to be kept, got %#v", entry.ContextMap())
+		}
+	}
+
+	now = now.Add(time.Minute)
+	logger.Info("hot message")
+
+	summaries := logs.FilterMessage(SuppressedLogMessage).AllUntimed()
+	if len(summaries) != 1 {
+		t.Fatalf("Expected 1 summary, got %#v", summaries)
+	}
+	fields := summaries[0].ContextMap()
+	if fields["sampledMessage"] != "hot message" {
+		t.Errorf("Expected sampledMessage %q, got %#v", "hot message", fields)
+	}
+	if fields["sampledLevel"] != "info" {
+		t.Errorf("Expected sampledLevel %q, got %#v", "info", fields)
+	}
+	if fields["suppressed"] != int64(6) {
+		t.Errorf("Expected 6 suppressed, got %#v", fields)
+	}
+	if _, ok := fields["key"]; ok {
+		t.Errorf("Expected fields from With not in the summary, got %#v", fields)
+	}
+	if got := logs.FilterMessage("hot message").Len(); got != 6 {
+		t.Errorf("Expected hot message to be logged after the tick, got %d", got)
+	}
+}
+
+func TestSamplingCoreNoThereafter(t *testing.T) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(NewSamplingCore(obs, SamplingConfig{
+		Initial: 1,
+	}))
+	for i := 0; i < 10; i++ {
+		logger.Error("message")
+	}
+	if got := logs.Len(); got != 1 {
+		t.Errorf("Expected 1 entry logged, got %d", got)
+	}
+}
+
+func TestSamplingCoreFlushOnTick(t *testing.T) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(NewSamplingCore(obs, SamplingConfig{
+		Initial: 1,
+		Tick:    time.Millisecond * 10,
+	})).With(zap.String("key", "value"))
+	for i := 0; i < 3; i++ {
+		logger.Info("message")
+	}
+
+	// No more entries are logged, the summary is written by the timer.
+	deadline := time.Now().Add(time.Second)
+	for logs.FilterMessage(SuppressedLogMessage).Len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the summary to be written when the tick ends")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	summaries := logs.FilterMessage(SuppressedLogMessage).AllUntimed()
+	if len(summaries) != 1 {
+		t.Fatalf("Expected 1 summary, got %#v", summaries)
+	}
+	fields := summaries[0].ContextMap()
+	if fields["suppressed"] != int64(2) {
+		t.Errorf("Expected 2 suppressed, got %#v", fields)
+	}
+	if _, ok := fields["key"]; ok {
+		t.Errorf("Expected fields from With not in the summary, got %#v", fields)
+	}
+}
+
+func TestSamplingCoreSync(t *testing.T) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(NewSamplingCore(obs, SamplingConfig{
+		Initial: 1,
+		Tick:    time.Hour,
+	}))
+	for i := 0; i < 3; i++ {
+		logger.Info("message")
+	}
+	if err := logger.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	summaries := logs.FilterMessage(SuppressedLogMessage).AllUntimed()
+	if len(summaries) != 1 {
+		t.Fatalf("Expected 1 summary after Sync, got %#v", summaries)
+	}
+	if got := summaries[0].ContextMap()["suppressed"]; got != int64(2) {
+		t.Errorf("Expected 2 suppressed, got %v", got)
+	}
+
+	// The suppressed entries are only reported once.
+	if err := logger.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := logs.FilterMessage(SuppressedLogMessage).Len(); got != 1 {
+		t.Errorf("Expected no more summaries, got %d", got)
+	}
+	// Sampling still applies to the rest of the tick.
+	logger.Info("message")
+	if got := logs.FilterMessage("message").Len(); got != 1 {
+		t.Errorf("Expected 1 message logged, got %d", got)
+	}
+}
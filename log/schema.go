@@ -0,0 +1,169 @@
+package log
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Schema is the output schema of the JSON logs.
+type Schema string
+
+// Enums for Schema.
+const (
+	// DefaultSchema is the default JSON format used by InitLoggerJSON,
+	// which is compatible with logdna's ingestion format.
+	DefaultSchema Schema = ""
+
+	// ECSSchema maps the log entries to Elastic Common Schema:
+	// https://www.elastic.co/guide/en/ecs/current/ecs-log.html
+	ECSSchema Schema = "ecs"
+
+	// OTelSchema maps the log entries to OpenTelemetry log data model and
+	// semantic conventions:
+	// https://opentelemetry.io/docs/reference/specification/logs/data-model/
+	OTelSchema Schema = "otel"
+)
+
+// ECSVersion is the version of Elastic Common Schema used by ECSSchema,
+// reported as "ecs.version" in every log entry.
+const ECSVersion = "1.12.0"
+
+// ParseSchema parses s into a Schema.
+//
+// It's case insensitive.
+func ParseSchema(s string) (Schema, error) {
+	switch schema := Schema(strings.ToLower(strings.TrimSpace(s))); schema {
+	default:
+		return "", fmt.Errorf("log: unknown schema %q", s)
+	case DefaultSchema, ECSSchema, OTelSchema:
+		return schema, nil
+	}
+}
+
+// schemaKeys are the keys of the well-known fields in a Schema.
+type schemaKeys struct {
+	traceID string
+
+	// keys to write the caller as separated fields.
+	file     string
+	line     string
+	function string
+
+	// key to write the numerical severity, optional.
+	severityNumber string
+}
+
+var ecsKeys = schemaKeys{
+	traceID:  "trace.id",
+	file:     "log.origin.file.name",
+	line:     "log.origin.file.line",
+	function: "log.origin.function",
+}
+
+var otelKeys = schemaKeys{
+	traceID:        "trace_id",
+	file:           "code.filepath",
+	line:           "code.lineno",
+	function:       "code.function",
+	severityNumber: "severity_number",
+}
+
+// schemaConfig returns the zap config for JSON logs in schema,
+// and the core wrapper to rename the well-known fields.
+//
+// The core wrapper is nil for DefaultSchema.
+func schemaConfig(logLevel Level, schema Schema) (zap.Config, func(zapcore.Core) zapcore.Core) {
+	config := jsonConfig(logLevel)
+	var keys schemaKeys
+	switch schema {
+	default:
+		return config, nil
+	case ECSSchema:
+		keys = ecsKeys
+		config.EncoderConfig.TimeKey = "@timestamp"
+		config.EncoderConfig.LevelKey = "log.level"
+		config.EncoderConfig.EncodeLevel = zapcore.LowercaseLevelEncoder
+		config.EncoderConfig.NameKey = "log.logger"
+		config.EncoderConfig.MessageKey = "message"
+		config.EncoderConfig.StacktraceKey = "error.stack_trace"
+		config.InitialFields = map[string]interface{}{
+			"ecs.version": ECSVersion,
+		}
+	case OTelSchema:
+		keys = otelKeys
+		config.EncoderConfig.TimeKey = "timestamp"
+		config.EncoderConfig.LevelKey = "severity_text"
+		config.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		config.EncoderConfig.NameKey = "otel.scope.name"
+		config.EncoderConfig.MessageKey = "body"
+		config.EncoderConfig.StacktraceKey = "exception.stacktrace"
+	}
+	// The caller is written as separated fields by schemaCore instead.
+	config.EncoderConfig.CallerKey = ""
+	return config, func(core zapcore.Core) zapcore.Core {
+		return schemaCore{Core: core, keys: keys}
+	}
+}
+
+// schemaCore renames the well-known fields and adds the caller fields
+// according to the schema.
+type schemaCore struct {
+	zapcore.Core
+
+	keys schemaKeys
+}
+
+func (c schemaCore) With(fields []zapcore.Field) zapcore.Core {
+	return schemaCore{
+		Core: c.Core.With(c.renameFields(fields, 0)),
+		keys: c.keys,
+	}
+}
+
+func (c schemaCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c schemaCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	fields = c.renameFields(fields, 4)
+	if ent.Caller.Defined {
+		file := ent.Caller.TrimmedPath()
+		if i := strings.LastIndexByte(file, ':'); i >= 0 {
+			file = file[:i]
+		}
+		// Use int32 for numbers so they are not converted into strings by
+		// wrappedCore.
+		fields = append(
+			fields,
+			zap.String(c.keys.file, file),
+			zap.Int32(c.keys.line, int32(ent.Caller.Line)),
+		)
+		if fn := runtime.FuncForPC(ent.Caller.PC); fn != nil {
+			fields = append(fields, zap.String(c.keys.function, fn.Name()))
+		}
+	}
+	if c.keys.severityNumber != "" {
+		fields = append(fields, zap.Int32(c.keys.severityNumber, int32(otlpSeverityNumber(ent.Level))))
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// renameFields returns a copy of fields with the well-known keys renamed,
+// with extra capacity reserved for appending.
+func (c schemaCore) renameFields(fields []zapcore.Field, extra int) []zapcore.Field {
+	renamed := make([]zapcore.Field, len(fields), len(fields)+extra)
+	for i, f := range fields {
+		if f.Key == traceIDKey {
+			f.Key = c.keys.traceID
+		}
+		renamed[i] = f
+	}
+	return renamed
+}
@@ -0,0 +1,118 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseSchema(t *testing.T) {
+	for _, c := range []struct {
+		s        string
+		expected Schema
+		err      bool
+	}{
+		{s: "", expected: DefaultSchema},
+		{s: "ecs", expected: ECSSchema},
+		{s: " OTel ", expected: OTelSchema},
+		{s: "foo", err: true},
+	} {
+		t.Run(c.s, func(t *testing.T) {
+			schema, err := ParseSchema(c.s)
+			if c.err {
+				if err == nil {
+					t.Errorf("Expected error, got schema %q", schema)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if schema != c.expected {
+				t.Errorf("Expected %q, got %q", c.expected, schema)
+			}
+		})
+	}
+}
+
+func TestSchemaConfig(t *testing.T) {
+	for _, c := range []struct {
+		schema   Schema
+		expected map[string]interface{}
+		absent   []string
+	}{
+		{
+			schema: DefaultSchema,
+			expected: map[string]interface{}{
+				"level":   "INFO",
+				"message": "hello",
+				"logger":  "name",
+				"traceID": "trace",
+			},
+		},
+		{
+			schema: ECSSchema,
+			expected: map[string]interface{}{
+				"ecs.version":          ECSVersion,
+				"log.level":            "info",
+				"message":              "hello",
+				"log.logger":           "name",
+				"trace.id":             "trace",
+				"log.origin.file.name": "log/schema_test.go",
+			},
+			absent: []string{"traceID", "caller", "level", "timestamp"},
+		},
+		{
+			schema: OTelSchema,
+			expected: map[string]interface{}{
+				"severity_text":   "INFO",
+				"severity_number": float64(9),
+				"body":            "hello",
+				"otel.scope.name": "name",
+				"trace_id":        "trace",
+				"code.filepath":   "log/schema_test.go",
+			},
+			absent: []string{"traceID", "caller", "level", "message"},
+		},
+	} {
+		t.Run(string(c.schema), func(t *testing.T) {
+			cfg, wrapper := schemaConfig(InfoLevel, c.schema)
+			if (wrapper == nil) != (c.schema == DefaultSchema) {
+				t.Fatalf("Unexpected core wrapper for schema %q", c.schema)
+			}
+			var buf bytes.Buffer
+			var core zapcore.Core = zapcore.NewCore(
+				zapcore.NewJSONEncoder(cfg.EncoderConfig),
+				zapcore.AddSync(&buf),
+				zapcore.DebugLevel,
+			)
+			if wrapper != nil {
+				core = wrapper(core)
+			}
+			fields := make([]zap.Field, 0, len(cfg.InitialFields))
+			for k, v := range cfg.InitialFields {
+				fields = append(fields, zap.Any(k, v))
+			}
+			logger := zap.New(core, zap.AddCaller(), zap.Fields(fields...)).Named("name")
+			logger.With(zap.String(traceIDKey, "trace")).Info("hello")
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("Failed to decode %q: %v", buf.String(), err)
+			}
+			for k, v := range c.expected {
+				if entry[k] != v {
+					t.Errorf("Expected %q to be %#v, got %#v", k, v, entry[k])
+				}
+			}
+			for _, k := range c.absent {
+				if _, ok := entry[k]; ok {
+					t.Errorf("Expected %q to be absent, got %#v", k, entry)
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package log
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -54,6 +56,20 @@ type SentryConfig struct {
 
 	// BeforeSend is a callback modifier before emitting an event to Sentry.
 	BeforeSend func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event `yaml:"-"`
+
+	// Fingerprint is an optional hook to control how events are grouped in
+	// Sentry.
+	//
+	// It's called before BeforeSend.
+	// When it returns a non-empty fingerprint, the fingerprint of the event will
+	// be replaced by it.
+	// "{{ default }}" can be used in the fingerprint to extend the default
+	// grouping instead of replacing it.
+	// See https://docs.sentry.io/platforms/go/usage/sdk-fingerprinting/.
+	//
+	// To set the fingerprint for a single request,
+	// use SetSentryFingerprint instead.
+	Fingerprint func(event *sentry.Event, hint *sentry.EventHint) []string `yaml:"-"`
 }
 
 // InitSentry initializes sentry reporting.
@@ -76,6 +92,32 @@ func InitSentry(cfg SentryConfig) (io.Closer, error) {
 		sampleRate = *cfg.SampleRate
 	}
 
+	if err := sentry.Init(sentry.ClientOptions{
+		Dsn:          cfg.DSN,
+		SampleRate:   sampleRate,
+		ServerName:   cfg.ServerName,
+		Environment:  cfg.Environment,
+		IgnoreErrors: cfg.IgnoreErrors,
+		BeforeSend:   sentryBeforeSend(cfg),
+	}); err != nil {
+		return nil, err
+	}
+	if cfg.ServerName != "" {
+		if hostname, err := os.Hostname(); err == nil {
+			sentry.CurrentHub().ConfigureScope(func(scope *sentry.Scope) {
+				scope.SetTag("hostname", hostname)
+			})
+		}
+	}
+	if Version != "" {
+		sentry.CurrentHub().ConfigureScope(func(scope *sentry.Scope) {
+			scope.SetTag("version", Version)
+		})
+	}
+	return closer(cfg.FlushTimeout), nil
+}
+
+func sentryBeforeSend(cfg SentryConfig) func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
 	// Improve legibility of Sentry errors by using the error message as header
 	// instead of the error type and marking stack trace frame from
 	// baseplate.go as not in-app.
@@ -83,7 +125,7 @@ func InitSentry(cfg SentryConfig) (io.Closer, error) {
 		base   = "github.com/reddit/baseplate.go"
 		prefix = base + "/"
 	)
-	beforeSend := func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+	return func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
 		for i, exception := range event.Exception {
 			// Mark stacktraces from baseplate.go as not in-app.
 			if exception.Stacktrace != nil {
@@ -94,35 +136,66 @@ func InitSentry(cfg SentryConfig) (io.Closer, error) {
 				}
 			}
 		}
+		if cfg.Fingerprint != nil {
+			if fingerprint := cfg.Fingerprint(event, hint); len(fingerprint) > 0 {
+				event.Fingerprint = fingerprint
+			}
+		}
 		if cfg.BeforeSend != nil {
 			return cfg.BeforeSend(event, hint)
 		}
 		return event
 	}
+}
 
-	if err := sentry.Init(sentry.ClientOptions{
-		Dsn:          cfg.DSN,
-		SampleRate:   sampleRate,
-		ServerName:   cfg.ServerName,
-		Environment:  cfg.Environment,
-		IgnoreErrors: cfg.IgnoreErrors,
-		BeforeSend:   beforeSend,
-	}); err != nil {
-		return nil, err
+// SetSentryTags sets tags to the sentry hub attached to the context object,
+// so that they are reported with all the sentry events from the same request.
+//
+// It's a nop when there's no sentry hub attached to the context object
+// (see Attach), to avoid polluting the global hub with request specific tags.
+//
+// Tags are indexed and searchable in sentry,
+// so PII (e.g. user ids, device ids) should never be set as tags directly.
+// Use HashSentryTag to hash them first if they are needed for correlation.
+func SetSentryTags(ctx context.Context, tags map[string]string) {
+	if len(tags) == 0 {
+		return
 	}
-	if cfg.ServerName != "" {
-		if hostname, err := os.Hostname(); err == nil {
-			sentry.CurrentHub().ConfigureScope(func(scope *sentry.Scope) {
-				scope.SetTag("hostname", hostname)
-			})
-		}
+	hub := sentry.GetHubFromContext(ctx)
+	if hub == nil {
+		return
 	}
-	if Version != "" {
-		sentry.CurrentHub().ConfigureScope(func(scope *sentry.Scope) {
-			scope.SetTag("version", Version)
-		})
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTags(tags)
+	})
+}
+
+// SetSentryFingerprint sets the fingerprint of all the sentry events from the
+// sentry hub attached to the context object,
+// to control how they are grouped in sentry.
+//
+// Like SetSentryTags, it's a nop when there's no sentry hub attached to the
+// context object.
+// See SentryConfig.Fingerprint for the global version.
+func SetSentryFingerprint(ctx context.Context, fingerprint ...string) {
+	hub := sentry.GetHubFromContext(ctx)
+	if hub == nil {
+		return
 	}
-	return closer(cfg.FlushTimeout), nil
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetFingerprint(fingerprint)
+	})
+}
+
+// HashSentryTag returns a hashed version of value suitable to be used as a
+// sentry tag value,
+// for values that are useful for correlation but shouldn't be reported as-is
+// (e.g. device ids).
+//
+// The hash is deterministic, so the same value always produces the same tag.
+func HashSentryTag(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:8])
 }
 
 // SentryBeforeSendSwapExceptionTypeAndValue is a sentry.BeforeSend
@@ -3,6 +3,7 @@ package log
 import (
 	"context"
 	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/getsentry/sentry-go"
@@ -246,3 +247,116 @@ func TestSentryBeforeSend(t *testing.T) {
 
 	})
 }
+
+func TestSentryEnrichment(t *testing.T) {
+	var event *sentry.Event
+	closer, err := InitSentry(SentryConfig{
+		Fingerprint: func(event *sentry.Event, hint *sentry.EventHint) []string {
+			if event.Tags["request"] == "grouped" {
+				return []string{"{{ default }}", "grouped"}
+			}
+			return nil
+		},
+		BeforeSend: func(e *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			event = e
+			return e
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		closer.Close()
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		event = nil
+		ctx := Attach(context.Background(), AttachArgs{
+			TraceID: "trace",
+			SpanID:  "span",
+			Request: "method",
+		})
+		SetSentryTags(ctx, map[string]string{
+			"logged_in": "true",
+			"device_id": HashSentryTag("device"),
+		})
+		ErrorWithSentry(ctx, "test", errors.New("test"))
+
+		if event == nil {
+			t.Fatal("BeforeSend not called")
+		}
+		for k, want := range map[string]string{
+			"trace_id":  "trace",
+			"span_id":   "span",
+			"request":   "method",
+			"logged_in": "true",
+			"device_id": HashSentryTag("device"),
+		} {
+			if got := event.Tags[k]; got != want {
+				t.Errorf("tag %q got %q want %q", k, got, want)
+			}
+		}
+		if len(event.Fingerprint) != 0 {
+			t.Errorf("expected no fingerprint, got %q", event.Fingerprint)
+		}
+	})
+
+	t.Run("global-hub-untouched", func(t *testing.T) {
+		event = nil
+		SetSentryTags(context.Background(), map[string]string{
+			"foo": "bar",
+		})
+		ErrorWithSentry(context.Background(), "test", errors.New("test"))
+
+		if event == nil {
+			t.Fatal("BeforeSend not called")
+		}
+		if _, ok := event.Tags["foo"]; ok {
+			t.Errorf("Expected global hub not to have tag foo, got %#v", event.Tags)
+		}
+	})
+
+	t.Run("fingerprint-hook", func(t *testing.T) {
+		event = nil
+		ctx := Attach(context.Background(), AttachArgs{
+			Request: "grouped",
+		})
+		ErrorWithSentry(ctx, "test", errors.New("test"))
+
+		if event == nil {
+			t.Fatal("BeforeSend not called")
+		}
+		want := []string{"{{ default }}", "grouped"}
+		if !reflect.DeepEqual(event.Fingerprint, want) {
+			t.Errorf("fingerprint got %q want %q", event.Fingerprint, want)
+		}
+	})
+
+	t.Run("fingerprint-per-request", func(t *testing.T) {
+		event = nil
+		ctx := Attach(context.Background(), AttachArgs{})
+		SetSentryFingerprint(ctx, "foo")
+		ErrorWithSentry(ctx, "test", errors.New("test"))
+
+		if event == nil {
+			t.Fatal("BeforeSend not called")
+		}
+		want := []string{"foo"}
+		if !reflect.DeepEqual(event.Fingerprint, want) {
+			t.Errorf("fingerprint got %q want %q", event.Fingerprint, want)
+		}
+	})
+}
+
+func TestHashSentryTag(t *testing.T) {
+	a := HashSentryTag("foo")
+	if a == "foo" {
+		t.Error("HashSentryTag returned the value as-is")
+	}
+	if b := HashSentryTag("foo"); a != b {
+		t.Errorf("HashSentryTag not deterministic: %q vs %q", a, b)
+	}
+	if b := HashSentryTag("bar"); a == b {
+		t.Errorf("HashSentryTag returned the same hash %q for different values", a)
+	}
+}
@@ -177,7 +177,24 @@ func (w *Wrapper) UnmarshalText(text []byte) error {
 	return nil
 }
 
-var _ encoding.TextUnmarshaler = (*Wrapper)(nil)
+// MarshalText implements encoding.TextMarshaler.
+//
+// It makes the configs containing Wrapper possible to be encoded,
+// e.g. by the config dump of the admin server.
+// As the implementation can't be told from a Wrapper,
+// it encodes nil Wrapper to empty and others to "<custom>",
+// which can't be decoded back by UnmarshalText.
+func (w Wrapper) MarshalText() ([]byte, error) {
+	if w == nil {
+		return nil, nil
+	}
+	return []byte("<custom>"), nil
+}
+
+var (
+	_ encoding.TextUnmarshaler = (*Wrapper)(nil)
+	_ encoding.TextMarshaler   = Wrapper(nil)
+)
 
 // WrapToThriftLogger wraps a Wrapper into thrift.Logger.
 func WrapToThriftLogger(w Wrapper) thrift.Logger {
@@ -2,8 +2,11 @@ package metricsbp
 
 import (
 	"context"
+	"fmt"
 	"io"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/reddit/baseplate.go/log"
 	"github.com/reddit/baseplate.go/tracing"
 )
@@ -53,6 +56,27 @@ type Config struct {
 	//
 	// Optional, defaults to false.
 	RunSysStats bool `yaml:"runSysStats"`
+
+	// BridgeToPrometheus makes all the metrics created from the Statsd also
+	// exported as equivalent Prometheus metrics (see PrometheusBridge).
+	//
+	// It's meant to be used during the migration from statsd to Prometheus,
+	// so that the statsd sidecar can be turned off (by leaving Endpoint empty)
+	// before all the metricsbp call sites are migrated.
+	//
+	// When it's true, InitFromConfig registers the PrometheusBridge to
+	// prometheus.DefaultRegisterer.
+	//
+	// Optional, defaults to false.
+	BridgeToPrometheus bool `yaml:"bridgeToPrometheus"`
+}
+
+// ValidateConfig implements configbp.Validator.
+func (cfg Config) ValidateConfig() error {
+	if rate := cfg.HistogramSampleRate; rate != nil && (*rate < 0 || *rate > 1) {
+		return fmt.Errorf("histogramSampleRate: must be in [0, 1], got %v", *rate)
+	}
+	return nil
 }
 
 // InitFromConfig initializes the global metricsbp.M with the given context and
@@ -60,12 +84,19 @@ type Config struct {
 // your server exits.
 //
 // It also registers CreateServerSpanHook and ConcurrencyCreateServerSpanHook
-// with the global tracing hook registry.
+// with the global tracing hook registry,
+// and the PrometheusBridge with prometheus.DefaultRegisterer when
+// BridgeToPrometheus is true.
 func InitFromConfig(ctx context.Context, cfg Config) io.Closer {
 	M = NewStatsd(ctx, cfg)
 	tracing.RegisterCreateServerSpanHooks(CreateServerSpanHook{Metrics: M})
 	if cfg.RunSysStats {
 		M.RunSysStats()
 	}
+	if bridge := M.PrometheusBridge(); bridge != nil {
+		if err := prometheus.Register(bridge); err != nil {
+			log.Errorw("Failed to register metricsbp prometheus bridge", "err", err)
+		}
+	}
 	return M
 }
@@ -0,0 +1,323 @@
+package metricsbp
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultPrometheusHistogramBuckets are the default buckets used by the
+// Prometheus histograms bridged from Histogram and HistogramWithRate.
+//
+// Histograms bridged from Timing and TimingWithRate are in seconds and use
+// prometheus.DefBuckets instead.
+var DefaultPrometheusHistogramBuckets = prometheus.ExponentialBuckets(1, 2, 16)
+
+// prometheusHelp is the help string used by all bridged Prometheus metrics.
+const prometheusHelp = "Bridged from metricsbp."
+
+// unknownLabelValue is the label value used for dangling label keys,
+// same as what go-kit uses.
+const unknownLabelValue = "unknown"
+
+type bridgeKind int
+
+const (
+	bridgeCounter bridgeKind = iota
+	bridgeGauge
+	bridgeHistogram
+)
+
+// PrometheusBridge is a prometheus.Collector exporting the metrics created
+// from a Statsd as equivalent Prometheus metrics.
+//
+// It's created by NewStatsd when Config.BridgeToPrometheus is true,
+// and can be accessed via Statsd.PrometheusBridge.
+//
+// Metric names are converted into valid Prometheus names by replacing all
+// invalid characters (e.g. ".") with "_",
+// counters are suffixed with "_total",
+// and timings are converted into seconds and suffixed with "_seconds".
+// Tags (both from Config.Tags and With) are converted into labels.
+//
+// Sampled counters and histograms are scaled back by their reporting rates,
+// so the Prometheus metrics report the estimated actual values,
+// same as what statsd does.
+//
+// All the metrics are reported as "unchecked" metrics,
+// as the label names are not known until the metrics are used.
+type PrometheusBridge struct {
+	prefix      string
+	constLabels prometheus.Labels
+
+	lock   sync.Mutex
+	series map[string]*bridgeSeries
+}
+
+type bridgeSeries struct {
+	kind        bridgeKind
+	desc        *prometheus.Desc
+	labelValues []string
+
+	// for counters and gauges
+	value float64
+
+	// for histograms
+	buckets []float64
+	counts  []float64
+	count   float64
+	sum     float64
+}
+
+func newPrometheusBridge(prefix string, tags Tags) *PrometheusBridge {
+	constLabels := make(prometheus.Labels, len(tags))
+	for k, v := range tags {
+		constLabels[prometheusName(k)] = v
+	}
+	return &PrometheusBridge{
+		prefix:      prefix,
+		constLabels: constLabels,
+		series:      make(map[string]*bridgeSeries),
+	}
+}
+
+// Describe implements prometheus.Collector.
+//
+// It doesn't send any descriptions,
+// which makes PrometheusBridge an unchecked collector.
+func (b *PrometheusBridge) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (b *PrometheusBridge) Collect(ch chan<- prometheus.Metric) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for _, s := range b.series {
+		var (
+			m   prometheus.Metric
+			err error
+		)
+		switch s.kind {
+		case bridgeCounter:
+			m, err = prometheus.NewConstMetric(s.desc, prometheus.CounterValue, s.value, s.labelValues...)
+		case bridgeGauge:
+			m, err = prometheus.NewConstMetric(s.desc, prometheus.GaugeValue, s.value, s.labelValues...)
+		case bridgeHistogram:
+			buckets := make(map[float64]uint64, len(s.buckets))
+			for i, upper := range s.buckets {
+				buckets[upper] = uint64(s.counts[i])
+			}
+			m, err = prometheus.NewConstHistogram(s.desc, uint64(s.count), s.sum, buckets, s.labelValues...)
+		}
+		if err != nil {
+			m = prometheus.NewInvalidMetric(s.desc, err)
+		}
+		ch <- m
+	}
+}
+
+// update finds or creates the series, and calls f with it while holding the
+// lock.
+func (b *PrometheusBridge) update(kind bridgeKind, name string, buckets []float64, labelValues []string, f func(s *bridgeSeries)) {
+	names, values := splitLabelValues(labelValues)
+	var sb strings.Builder
+	sb.WriteString(name)
+	for i := range names {
+		sb.WriteByte(0)
+		sb.WriteString(names[i])
+		sb.WriteByte(0)
+		sb.WriteString(values[i])
+	}
+	key := sb.String()
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	s := b.series[key]
+	if s == nil {
+		s = &bridgeSeries{
+			kind:        kind,
+			desc:        prometheus.NewDesc(name, prometheusHelp, names, b.constLabels),
+			labelValues: values,
+		}
+		if kind == bridgeHistogram {
+			s.buckets = buckets
+			s.counts = make([]float64, len(buckets))
+		}
+		b.series[key] = s
+	}
+	f(s)
+}
+
+func (b *PrometheusBridge) counter(name string, scale float64) metrics.Counter {
+	return prometheusCounter{
+		bridge: b,
+		name:   prometheusName(b.prefix+name) + "_total",
+		scale:  scale,
+	}
+}
+
+func (b *PrometheusBridge) gauge(name string) metrics.Gauge {
+	return prometheusGauge{
+		bridge: b,
+		name:   prometheusName(b.prefix + name),
+	}
+}
+
+func (b *PrometheusBridge) histogram(name string, scale float64) metrics.Histogram {
+	return prometheusHistogram{
+		bridge:  b,
+		name:    prometheusName(b.prefix + name),
+		buckets: DefaultPrometheusHistogramBuckets,
+		unit:    1,
+		scale:   scale,
+	}
+}
+
+func (b *PrometheusBridge) timing(name string, scale float64) metrics.Histogram {
+	return prometheusHistogram{
+		bridge:  b,
+		name:    prometheusName(b.prefix+name) + "_seconds",
+		buckets: prometheus.DefBuckets,
+		// Timings are observed in milliseconds.
+		unit:  0.001,
+		scale: scale,
+	}
+}
+
+type prometheusCounter struct {
+	bridge      *PrometheusBridge
+	name        string
+	scale       float64
+	labelValues []string
+}
+
+func (c prometheusCounter) With(labelValues ...string) metrics.Counter {
+	c.labelValues = appendLabelValues(c.labelValues, labelValues)
+	return c
+}
+
+func (c prometheusCounter) Add(delta float64) {
+	c.bridge.update(bridgeCounter, c.name, nil, c.labelValues, func(s *bridgeSeries) {
+		s.value += delta * c.scale
+	})
+}
+
+type prometheusGauge struct {
+	bridge      *PrometheusBridge
+	name        string
+	labelValues []string
+}
+
+func (g prometheusGauge) With(labelValues ...string) metrics.Gauge {
+	g.labelValues = appendLabelValues(g.labelValues, labelValues)
+	return g
+}
+
+func (g prometheusGauge) Set(value float64) {
+	g.bridge.update(bridgeGauge, g.name, nil, g.labelValues, func(s *bridgeSeries) {
+		s.value = value
+	})
+}
+
+func (g prometheusGauge) Add(delta float64) {
+	g.bridge.update(bridgeGauge, g.name, nil, g.labelValues, func(s *bridgeSeries) {
+		s.value += delta
+	})
+}
+
+type prometheusHistogram struct {
+	bridge      *PrometheusBridge
+	name        string
+	buckets     []float64
+	unit        float64
+	scale       float64
+	labelValues []string
+}
+
+func (h prometheusHistogram) With(labelValues ...string) metrics.Histogram {
+	h.labelValues = appendLabelValues(h.labelValues, labelValues)
+	return h
+}
+
+func (h prometheusHistogram) Observe(value float64) {
+	value *= h.unit
+	h.bridge.update(bridgeHistogram, h.name, h.buckets, h.labelValues, func(s *bridgeSeries) {
+		for i, upper := range s.buckets {
+			if value <= upper {
+				s.counts[i] += h.scale
+			}
+		}
+		s.count += h.scale
+		s.sum += value * h.scale
+	})
+}
+
+// bridgeScale returns the scale to be applied to the bridged metrics reported
+// at rate.
+func bridgeScale(rate float64) float64 {
+	if rate <= 0 || rate >= 1 {
+		return 1
+	}
+	return 1 / rate
+}
+
+func appendLabelValues(old, labelValues []string) []string {
+	// Make sure we never modify the underlying array of old.
+	return append(old[:len(old):len(old)], labelValues...)
+}
+
+// splitLabelValues splits go-kit style label values
+// (alternating label names and values) into sorted label names and their
+// values.
+//
+// Later values of the same label name override the earlier ones.
+func splitLabelValues(labelValues []string) (names, values []string) {
+	if len(labelValues) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]string, (len(labelValues)+1)/2)
+	for i := 0; i < len(labelValues); i += 2 {
+		value := unknownLabelValue
+		if i+1 < len(labelValues) {
+			value = labelValues[i+1]
+		}
+		m[prometheusName(labelValues[i])] = value
+	}
+	names = make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	values = make([]string, len(names))
+	for i, k := range names {
+		values[i] = m[k]
+	}
+	return names, values
+}
+
+// prometheusName converts a statsd metric or tag name into a valid Prometheus
+// name.
+func prometheusName(name string) string {
+	var sb strings.Builder
+	sb.Grow(len(name) + 1)
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
+
+var _ prometheus.Collector = (*PrometheusBridge)(nil)
@@ -0,0 +1,162 @@
+package metricsbp_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+)
+
+func gatherBridge(t *testing.T, st *metricsbp.Statsd) map[string]*dto.MetricFamily {
+	t.Helper()
+
+	bridge := st.PrometheusBridge()
+	if bridge == nil {
+		t.Fatal("Expected non-nil PrometheusBridge")
+	}
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(bridge); err != nil {
+		t.Fatalf("Failed to register bridge: %v", err)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather: %v", err)
+	}
+	m := make(map[string]*dto.MetricFamily, len(families))
+	for _, f := range families {
+		m[f.GetName()] = f
+	}
+	return m
+}
+
+func labelsString(m *dto.Metric) string {
+	var sb strings.Builder
+	for _, l := range m.GetLabel() {
+		sb.WriteString(l.GetName())
+		sb.WriteString("=")
+		sb.WriteString(l.GetValue())
+		sb.WriteString(";")
+	}
+	return sb.String()
+}
+
+func TestPrometheusBridge(t *testing.T) {
+	st := metricsbp.NewStatsd(context.Background(), metricsbp.Config{
+		Namespace:          "my.service",
+		Tags:               metricsbp.Tags{"env": "test"},
+		BridgeToPrometheus: true,
+	})
+
+	st.Counter("foo.bar-count").Add(1)
+	st.Counter("foo.bar-count").With("success", "true").Add(2)
+	st.Counter("foo.bar-count").With("success", "true").Add(3)
+	st.CounterWithRate(metricsbp.RateArgs{
+		Name:             "sampled",
+		Rate:             1,
+		AlreadySampledAt: metricsbp.Float64Ptr(0.5),
+	}).Add(1)
+	st.Gauge("gauge").Set(5)
+	st.Gauge("gauge").Add(-1)
+	st.Histogram("histogram").Observe(3)
+	st.Timing("timing").Observe(20)
+
+	// Make sure the statsd side still works.
+	var buf bytes.Buffer
+	if _, err := st.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "my.service.foo.bar-count") {
+		t.Errorf("Expected statsd metrics to be kept, got %q", buf.String())
+	}
+
+	families := gatherBridge(t, st)
+
+	t.Run("counter", func(t *testing.T) {
+		f := families["my_service_foo_bar_count_total"]
+		if f == nil {
+			t.Fatalf("counter not found in %v", families)
+		}
+		if f.GetType() != dto.MetricType_COUNTER {
+			t.Errorf("Expected counter type, got %v", f.GetType())
+		}
+		values := make(map[string]float64)
+		for _, m := range f.GetMetric() {
+			values[labelsString(m)] = m.GetCounter().GetValue()
+		}
+		expected := map[string]float64{
+			"env=test;":              1,
+			"env=test;success=true;": 5,
+		}
+		if len(values) != len(expected) {
+			t.Errorf("Expected %v, got %v", expected, values)
+		}
+		for k, v := range expected {
+			if values[k] != v {
+				t.Errorf("%s: expected %v, got %v", k, v, values[k])
+			}
+		}
+	})
+
+	t.Run("sampled-counter", func(t *testing.T) {
+		f := families["my_service_sampled_total"]
+		if f == nil {
+			t.Fatalf("counter not found in %v", families)
+		}
+		if v := f.GetMetric()[0].GetCounter().GetValue(); v != 2 {
+			t.Errorf("Expected sampled counter to be scaled to 2, got %v", v)
+		}
+	})
+
+	t.Run("gauge", func(t *testing.T) {
+		f := families["my_service_gauge"]
+		if f == nil {
+			t.Fatalf("gauge not found in %v", families)
+		}
+		if v := f.GetMetric()[0].GetGauge().GetValue(); v != 4 {
+			t.Errorf("Expected gauge value 4, got %v", v)
+		}
+	})
+
+	t.Run("histogram", func(t *testing.T) {
+		f := families["my_service_histogram"]
+		if f == nil {
+			t.Fatalf("histogram not found in %v", families)
+		}
+		h := f.GetMetric()[0].GetHistogram()
+		if h.GetSampleCount() != 1 || h.GetSampleSum() != 3 {
+			t.Errorf("Unexpected histogram %v", h)
+		}
+	})
+
+	t.Run("timing", func(t *testing.T) {
+		f := families["my_service_timing_seconds"]
+		if f == nil {
+			t.Fatalf("timing not found in %v", families)
+		}
+		h := f.GetMetric()[0].GetHistogram()
+		if h.GetSampleCount() != 1 || h.GetSampleSum() != 0.02 {
+			t.Errorf("Unexpected histogram %v", h)
+		}
+		for _, b := range h.GetBucket() {
+			want := uint64(0)
+			if b.GetUpperBound() >= 0.02 {
+				want = 1
+			}
+			if b.GetCumulativeCount() != want {
+				t.Errorf("Bucket %v: expected %d, got %d", b.GetUpperBound(), want, b.GetCumulativeCount())
+			}
+		}
+	})
+}
+
+func TestPrometheusBridgeDisabled(t *testing.T) {
+	st := metricsbp.NewStatsd(context.Background(), metricsbp.Config{})
+	if bridge := st.PrometheusBridge(); bridge != nil {
+		t.Errorf("Expected nil PrometheusBridge, got %v", bridge)
+	}
+}
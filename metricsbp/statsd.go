@@ -10,6 +10,7 @@ import (
 
 	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/metrics/influxstatsd"
+	"github.com/go-kit/kit/metrics/multi"
 	"github.com/go-kit/kit/util/conn"
 
 	"github.com/reddit/baseplate.go/log"
@@ -88,6 +89,7 @@ type Statsd struct {
 	histogramSampleRate float64
 	writer              *bufferedWriter
 	wg                  sync.WaitGroup
+	bridge              *PrometheusBridge
 
 	activeRequests int64
 }
@@ -123,6 +125,9 @@ func NewStatsd(ctx context.Context, cfg Config) *Statsd {
 		histogramSampleRate: convertSampleRate(cfg.HistogramSampleRate),
 	}
 	st.ctx, st.cancel = context.WithCancel(ctx)
+	if cfg.BridgeToPrometheus {
+		st.bridge = newPrometheusBridge(prefix, cfg.Tags)
+	}
 
 	if cfg.Endpoint != "" {
 		if cfg.BufferSize == 0 {
@@ -221,7 +226,13 @@ func (st *Statsd) Counter(name string) metrics.Counter {
 // passed in instead of inherited from Config.
 func (st *Statsd) CounterWithRate(args RateArgs) metrics.Counter {
 	st = st.fallback()
-	counter := st.statsd.NewCounter(args.Name, args.ReportingRate())
+	var counter metrics.Counter = st.statsd.NewCounter(args.Name, args.ReportingRate())
+	if st.bridge != nil {
+		counter = multi.NewCounter(
+			counter,
+			st.bridge.counter(args.Name, bridgeScale(args.ReportingRate())),
+		)
+	}
 	if args.Rate >= 1 {
 		return counter
 	}
@@ -245,7 +256,13 @@ func (st *Statsd) Histogram(name string) metrics.Histogram {
 // unit, with sample rate passed in instead of inherited from Config.
 func (st *Statsd) HistogramWithRate(args RateArgs) metrics.Histogram {
 	st = st.fallback()
-	histogram := st.statsd.NewHistogram(args.Name, args.ReportingRate())
+	var histogram metrics.Histogram = st.statsd.NewHistogram(args.Name, args.ReportingRate())
+	if st.bridge != nil {
+		histogram = multi.NewHistogram(
+			histogram,
+			st.bridge.histogram(args.Name, bridgeScale(args.ReportingRate())),
+		)
+	}
 	if args.Rate >= 1 {
 		return histogram
 	}
@@ -269,7 +286,13 @@ func (st *Statsd) Timing(name string) metrics.Histogram {
 // the unit, with sample rate passed in instead of inherited from Config.
 func (st *Statsd) TimingWithRate(args RateArgs) metrics.Histogram {
 	st = st.fallback()
-	histogram := st.statsd.NewTiming(args.Name, args.ReportingRate())
+	var histogram metrics.Histogram = st.statsd.NewTiming(args.Name, args.ReportingRate())
+	if st.bridge != nil {
+		histogram = multi.NewHistogram(
+			histogram,
+			st.bridge.timing(args.Name, bridgeScale(args.ReportingRate())),
+		)
+	}
 	if args.Rate >= 1 {
 		return histogram
 	}
@@ -285,9 +308,19 @@ func (st *Statsd) TimingWithRate(args RateArgs) metrics.Histogram {
 // In most cases when you use a Gauge, you want to use RuntimeGauge instead.
 func (st *Statsd) Gauge(name string) metrics.Gauge {
 	st = st.fallback()
+	if st.bridge != nil {
+		return multi.NewGauge(st.statsd.NewGauge(name), st.bridge.gauge(name))
+	}
 	return st.statsd.NewGauge(name)
 }
 
+// PrometheusBridge returns the PrometheusBridge exporting the metrics created
+// from this Statsd to Prometheus,
+// or nil if Config.BridgeToPrometheus was false.
+func (st *Statsd) PrometheusBridge() *PrometheusBridge {
+	return st.fallback().bridge
+}
+
 func (st *Statsd) fallback() *Statsd {
 	if st == nil {
 		return M
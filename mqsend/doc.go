@@ -14,4 +14,8 @@
 // * Non-send operations (e.g. receive)
 //
 // If you need those features, this is not the package for you.
+//
+// On the platforms where posix message queues are not available,
+// the messages can be sent to the sidecars via a unix datagram socket,
+// UDP, or HTTP push instead, see Transport.
 package mqsend
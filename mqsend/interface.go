@@ -31,13 +31,18 @@ type MessageQueueConfig struct {
 
 	// The max size in bytes per message.
 	MaxMessageSize int64
+
+	// The Transport to use, optional, defaults to TransportPOSIX.
+	Transport TransportConfig
 }
 
-// OpenMessageQueue opens a named message queue.
+// OpenMessageQueue opens a named message queue,
+// using the Transport configured in cfg.
 //
-// On Linux systems this returns the real thing.
+// With TransportPOSIX (the default),
+// on Linux systems this returns the real thing.
 // On non-linux systems this just returns a mocked version,
 // see OpenMockMessageQueue.
 func OpenMessageQueue(cfg MessageQueueConfig) (MessageQueue, error) {
-	return openMessageQueue(cfg)
+	return openTransport(cfg)
 }
@@ -0,0 +1,249 @@
+package mqsend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Transport is the way messages are sent to the sidecars.
+type Transport string
+
+// Transport values.
+const (
+	// TransportPOSIX sends the messages to a posix message queue.
+	//
+	// It's only available on Linux,
+	// on other systems OpenMessageQueue returns a MockMessageQueue instead.
+	//
+	// This is the default Transport.
+	TransportPOSIX Transport = "posix"
+
+	// TransportUnixgram sends every message as a datagram to the unix socket
+	// at TransportConfig.Addr.
+	//
+	// It's available on the systems supporting unix datagram sockets
+	// (e.g. macOS and distroless containers, but not Windows).
+	TransportUnixgram Transport = "unixgram"
+
+	// TransportUDP sends every message as a UDP datagram to
+	// TransportConfig.Addr ("host:port").
+	//
+	// UDP never blocks, the messages are silently dropped by the kernel when
+	// the sidecar can't keep up, so it's only recommended for the environments
+	// where neither TransportPOSIX nor TransportUnixgram is available.
+	TransportUDP Transport = "udp"
+
+	// TransportHTTP pushes every message as the body of a POST request to
+	// TransportConfig.Addr (the URL),
+	// with the name of the queue in the MessageQueueHeader header.
+	//
+	// The sidecar should respond with 2xx when the message is accepted,
+	// 429 or 503 when it's full (reported as TimedOutError),
+	// and 413 when the message is too large
+	// (reported as MessageTooLargeError).
+	TransportHTTP Transport = "http"
+)
+
+// MessageQueueHeader is the HTTP header carrying the name of the queue used by
+// TransportHTTP.
+const MessageQueueHeader = "X-Baseplate-Message-Queue"
+
+// DefaultHTTPTimeout is the timeout of the requests sent by TransportHTTP
+// when the context object passed into Send has no deadline.
+const DefaultHTTPTimeout = time.Second
+
+// TransportConfig is the config to choose the Transport.
+//
+// Can be deserialized from YAML.
+type TransportConfig struct {
+	// Optional, defaults to TransportPOSIX.
+	Type Transport `yaml:"type"`
+
+	// The address of the sidecar, required by all the Transports other than
+	// TransportPOSIX.
+	Addr string `yaml:"addr"`
+}
+
+// ErrUnknownTransport is the error returned by OpenMessageQueue when the
+// Transport is not supported.
+var ErrUnknownTransport = errors.New("mqsend: unknown transport")
+
+func openTransport(cfg MessageQueueConfig) (MessageQueue, error) {
+	switch cfg.Transport.Type {
+	case "", TransportPOSIX:
+		return openMessageQueue(cfg)
+	case TransportUnixgram, TransportUDP:
+		if cfg.Transport.Addr == "" {
+			return nil, fmt.Errorf("mqsend: addr is required by transport %q", cfg.Transport.Type)
+		}
+		return &datagramQueue{
+			network: string(cfg.Transport.Type),
+			addr:    cfg.Transport.Addr,
+			maxSize: int(cfg.MaxMessageSize),
+		}, nil
+	case TransportHTTP:
+		if cfg.Transport.Addr == "" {
+			return nil, fmt.Errorf("mqsend: addr is required by transport %q", cfg.Transport.Type)
+		}
+		return &httpQueue{
+			name:    cfg.Name,
+			url:     cfg.Transport.Addr,
+			maxSize: int(cfg.MaxMessageSize),
+			client:  &http.Client{Timeout: DefaultHTTPTimeout},
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTransport, cfg.Transport.Type)
+	}
+}
+
+// datagramQueue implements TransportUnixgram and TransportUDP.
+type datagramQueue struct {
+	network string
+	addr    string
+	maxSize int
+
+	lock   sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+func (q *datagramQueue) Close() error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.closed = true
+	if q.conn == nil {
+		return nil
+	}
+	return q.conn.Close()
+}
+
+func (q *datagramQueue) Send(ctx context.Context, data []byte) error {
+	if len(data) > q.maxSize {
+		return MessageTooLargeError{
+			MessageSize: len(data),
+			MaxSize:     q.maxSize,
+		}
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.closed {
+		return net.ErrClosed
+	}
+	// The connection is established lazily,
+	// so the service doesn't depend on the sidecar to be up first.
+	if q.conn == nil {
+		conn, err := net.Dial(q.network, q.addr)
+		if err != nil {
+			return fmt.Errorf("mqsend: failed to connect to %s %q: %w", q.network, q.addr, err)
+		}
+		q.conn = conn
+	}
+
+	var err error
+	if deadline, ok := ctx.Deadline(); ok && deadline.After(time.Now()) {
+		if err = q.conn.SetWriteDeadline(deadline); err != nil {
+			return err
+		}
+		_, err = q.conn.Write(data)
+	} else {
+		err = writeNonBlocking(q.conn, data)
+	}
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.ENOBUFS):
+		return TimedOutError{
+			Cause: err,
+		}
+	case errors.Is(err, syscall.EMSGSIZE):
+		return MessageTooLargeError{
+			MessageSize: len(data),
+			MaxSize:     q.maxSize,
+			Cause:       err,
+		}
+	default:
+		// Reconnect on the next Send, in case the sidecar was restarted.
+		q.conn.Close()
+		q.conn = nil
+		return err
+	}
+}
+
+// httpQueue implements TransportHTTP.
+type httpQueue struct {
+	name    string
+	url     string
+	maxSize int
+	client  *http.Client
+}
+
+func (q *httpQueue) Close() error {
+	q.client.CloseIdleConnections()
+	return nil
+}
+
+func (q *httpQueue) Send(ctx context.Context, data []byte) error {
+	if len(data) > q.maxSize {
+		return MessageTooLargeError{
+			MessageSize: len(data),
+			MaxSize:     q.maxSize,
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && !deadline.After(time.Now()) {
+		// There's no non-blocking mode for HTTP,
+		// fail right away instead of sending a request that's bound to time out.
+		return TimedOutError{
+			Cause: context.DeadlineExceeded,
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, q.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set(MessageQueueHeader, q.name)
+	resp, err := q.client.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err) {
+			return TimedOutError{
+				Cause: err,
+			}
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusServiceUnavailable:
+		return TimedOutError{
+			Cause: fmt.Errorf("mqsend: sidecar responded with %s", resp.Status),
+		}
+	case resp.StatusCode == http.StatusRequestEntityTooLarge:
+		return MessageTooLargeError{
+			MessageSize: len(data),
+			MaxSize:     q.maxSize,
+		}
+	default:
+		return fmt.Errorf("mqsend: sidecar responded with %s", resp.Status)
+	}
+}
+
+var (
+	_ MessageQueue = (*datagramQueue)(nil)
+	_ MessageQueue = (*httpQueue)(nil)
+)
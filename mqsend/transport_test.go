@@ -0,0 +1,178 @@
+package mqsend_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/mqsend"
+)
+
+func TestOpenMessageQueueTransportErrors(t *testing.T) {
+	for _, c := range []struct {
+		label     string
+		transport mqsend.TransportConfig
+	}{
+		{
+			label:     "unknown",
+			transport: mqsend.TransportConfig{Type: "carrier-pigeon", Addr: "foo"},
+		},
+		{
+			label:     "unixgram-no-addr",
+			transport: mqsend.TransportConfig{Type: mqsend.TransportUnixgram},
+		},
+		{
+			label:     "http-no-addr",
+			transport: mqsend.TransportConfig{Type: mqsend.TransportHTTP},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			_, err := mqsend.OpenMessageQueue(mqsend.MessageQueueConfig{
+				Name:           "test",
+				MaxMessageSize: 10,
+				Transport:      c.transport,
+			})
+			if err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestUnixgramTransport(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix datagram sockets are not supported on windows")
+	}
+
+	const msg = "hello, world!"
+	const max = len(msg)
+
+	addr := filepath.Join(t.TempDir(), "sidecar.sock")
+	mq, err := mqsend.OpenMessageQueue(mqsend.MessageQueueConfig{
+		Name:           "test",
+		MaxMessageSize: int64(max),
+		Transport: mqsend.TransportConfig{
+			Type: mqsend.TransportUnixgram,
+			Addr: addr,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mq.Close()
+
+	t.Run("no-sidecar", func(t *testing.T) {
+		if err := mq.Send(context.Background(), []byte(msg)); err == nil {
+			t.Error("Expected error when the sidecar is not listening, got nil")
+		}
+	})
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	t.Run("message-too-large", func(t *testing.T) {
+		err := mq.Send(context.Background(), make([]byte, max+1))
+		if !errors.As(err, new(mqsend.MessageTooLargeError)) {
+			t.Errorf("Expected MessageTooLargeError, got %v", err)
+		}
+	})
+
+	t.Run("send", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := mq.Send(ctx, []byte(msg)); err != nil {
+			t.Fatalf("Send returned error: %v", err)
+		}
+		if err := mq.Send(context.Background(), []byte(msg)); err != nil {
+			t.Fatalf("Non-blocking Send returned error: %v", err)
+		}
+
+		buf := make([]byte, max*2)
+		for i := 0; i < 2; i++ {
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			n, err := conn.Read(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(buf[:n]); got != msg {
+				t.Errorf("Expected to receive %q, got %q", msg, got)
+			}
+		}
+	})
+}
+
+func TestHTTPTransport(t *testing.T) {
+	const msg = "hello, world!"
+	const max = len(msg)
+
+	received := make(chan string, 1)
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if name := r.Header.Get(mqsend.MessageQueueHeader); name != "traces-test" {
+			t.Errorf("Expected queue name %q, got %q", "traces-test", name)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if status/100 == 2 {
+			received <- string(body)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	mq, err := mqsend.OpenMessageQueue(mqsend.MessageQueueConfig{
+		Name:           "traces-test",
+		MaxMessageSize: int64(max),
+		Transport: mqsend.TransportConfig{
+			Type: mqsend.TransportHTTP,
+			Addr: server.URL,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mq.Close()
+
+	t.Run("message-too-large", func(t *testing.T) {
+		err := mq.Send(context.Background(), make([]byte, max+1))
+		if !errors.As(err, new(mqsend.MessageTooLargeError)) {
+			t.Errorf("Expected MessageTooLargeError, got %v", err)
+		}
+	})
+
+	t.Run("send", func(t *testing.T) {
+		status = http.StatusAccepted
+		if err := mq.Send(context.Background(), []byte(msg)); err != nil {
+			t.Fatalf("Send returned error: %v", err)
+		}
+		if got := <-received; got != msg {
+			t.Errorf("Expected to receive %q, got %q", msg, got)
+		}
+	})
+
+	t.Run("full", func(t *testing.T) {
+		status = http.StatusServiceUnavailable
+		err := mq.Send(context.Background(), []byte(msg))
+		if !errors.As(err, new(mqsend.TimedOutError)) {
+			t.Errorf("Expected TimedOutError, got %v", err)
+		}
+	})
+
+	t.Run("deadline-passed", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), -1)
+		defer cancel()
+		err := mq.Send(ctx, []byte(msg))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+}
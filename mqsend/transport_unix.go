@@ -0,0 +1,32 @@
+//go:build !windows
+// +build !windows
+
+package mqsend
+
+import (
+	"net"
+	"syscall"
+)
+
+// writeNonBlocking tries to write data to conn once,
+// and fails with EAGAIN instead of waiting when the socket is not writable.
+func writeNonBlocking(conn net.Conn, data []byte) error {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		_, err := conn.Write(data)
+		return err
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var writeErr error
+	if err := raw.Write(func(fd uintptr) bool {
+		_, writeErr = syscall.Write(int(fd), data)
+		// Always return true so it never waits for the socket to be writable.
+		return true
+	}); err != nil {
+		return err
+	}
+	return writeErr
+}
@@ -0,0 +1,17 @@
+//go:build windows
+// +build windows
+
+package mqsend
+
+import (
+	"net"
+)
+
+// writeNonBlocking writes data to conn.
+//
+// On windows only TransportUDP is supported by datagramQueue,
+// which never blocks.
+func writeNonBlocking(conn net.Conn, data []byte) error {
+	_, err := conn.Write(data)
+	return err
+}
@@ -0,0 +1,111 @@
+package baseplate
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/reddit/baseplate.go/adminbp"
+	"github.com/reddit/baseplate.go/errorsbp"
+)
+
+// ServeCloser is the minimal interface of the servers that can be served
+// together by NewMultiServer,
+// implemented by Server and *adminbp.Server.
+type ServeCloser interface {
+	// Serve should start the server and only return once it has stopped.
+	Serve() error
+
+	// Close should stop the server gracefully and only return after it has
+	// finished shutting down.
+	io.Closer
+}
+
+// ErrServerStopped is the error returned by the Serve of a MultiServer when
+// one of the servers stopped without error before Close is called.
+var ErrServerStopped = errors.New("baseplate: server stopped unexpectedly")
+
+// NewMultiServer returns a Server serving all the given servers together,
+// e.g. a thriftbp server and an httpbp server, to be run by Serve.
+//
+// Serve of the returned Server serves all the servers concurrently.
+// When any of them fails (or stops before Close is called),
+// all the others are closed and Serve returns the errors,
+// so that Serve shuts down the service instead of running it partially.
+//
+// Close of the returned Server closes the servers one by one,
+// in the order they are given,
+// so the servers handling the traffic should be put before the auxiliary ones
+// (e.g. the admin server serving the metrics),
+// to keep the latter available while the former are draining.
+// It's OK to call Close multiple times,
+// calls after the first one are no-ops returning nil.
+//
+// Please note that when Config.Admin.Addr is set,
+// New already started the admin server,
+// which is closed by the Close of the Baseplate.
+// To coordinate the admin server with the other servers instead,
+// leave Config.Admin.Addr empty and pass the server created by adminbp.New
+// to NewMultiServer.
+func NewMultiServer(bp Baseplate, servers ...ServeCloser) Server {
+	return &multiServer{
+		bp:      bp,
+		servers: servers,
+	}
+}
+
+type multiServer struct {
+	bp      Baseplate
+	servers []ServeCloser
+
+	closing   int32
+	closeOnce sync.Once
+}
+
+func (s *multiServer) Baseplate() Baseplate {
+	return s.bp
+}
+
+func (s *multiServer) Serve() error {
+	errs := make(chan error, len(s.servers))
+	for _, server := range s.servers {
+		go func(server ServeCloser) {
+			errs <- server.Serve()
+		}(server)
+	}
+
+	var batch errorsbp.Batch
+	for i := 0; i < len(s.servers); i++ {
+		err := <-errs
+		if atomic.LoadInt32(&s.closing) == 0 {
+			// The first server stopped before Close is called,
+			// close all the others so this Serve returns.
+			if err == nil {
+				err = ErrServerStopped
+			}
+			batch.Add(s.Close())
+		}
+		batch.Add(err)
+	}
+	return batch.Compile()
+}
+
+func (s *multiServer) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		atomic.StoreInt32(&s.closing, 1)
+		var batch errorsbp.Batch
+		for _, server := range s.servers {
+			batch.Add(server.Close())
+		}
+		err = batch.Compile()
+	})
+	return err
+}
+
+var (
+	_ Server      = (*multiServer)(nil)
+	_ ServeCloser = Server(nil)
+	_ ServeCloser = (*adminbp.Server)(nil)
+)
@@ -0,0 +1,188 @@
+package baseplate_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/ecinterface"
+)
+
+type fakeServeCloser struct {
+	name   string
+	closed *[]string
+	lock   *sync.Mutex
+
+	fail chan error
+	stop chan struct{}
+	once sync.Once
+}
+
+func newFakeServeCloser(name string, closed *[]string, lock *sync.Mutex) *fakeServeCloser {
+	return &fakeServeCloser{
+		name:   name,
+		closed: closed,
+		lock:   lock,
+		fail:   make(chan error, 1),
+		stop:   make(chan struct{}),
+	}
+}
+
+func (s *fakeServeCloser) Serve() error {
+	select {
+	case <-s.stop:
+		return nil
+	case err := <-s.fail:
+		return err
+	}
+}
+
+func (s *fakeServeCloser) Close() error {
+	s.once.Do(func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		*s.closed = append(*s.closed, s.name)
+		close(s.stop)
+	})
+	return nil
+}
+
+func TestMultiServer(t *testing.T) {
+	store := newSecretsStore(t)
+	defer store.Close()
+
+	bp := baseplate.NewTestBaseplate(baseplate.NewTestBaseplateArgs{
+		Config:          baseplate.Config{StopTimeout: testTimeout},
+		Store:           store,
+		EdgeContextImpl: ecinterface.Mock(),
+	}).(lifecycleBaseplate)
+
+	t.Run("close", func(t *testing.T) {
+		var closed []string
+		var lock sync.Mutex
+		server := baseplate.NewMultiServer(
+			bp,
+			newFakeServeCloser("thrift", &closed, &lock),
+			newFakeServeCloser("http", &closed, &lock),
+			newFakeServeCloser("admin", &closed, &lock),
+		)
+		if server.Baseplate() != bp {
+			t.Errorf("Expected Baseplate %v, got %v", bp, server.Baseplate())
+		}
+
+		ch := make(chan error)
+		go func() {
+			ch <- server.Serve()
+		}()
+		if err := server.Close(); err != nil {
+			t.Errorf("Close returned error: %v", err)
+		}
+		if err := server.Close(); err != nil {
+			t.Errorf("Second Close returned error: %v", err)
+		}
+		if err := <-ch; err != nil {
+			t.Errorf("Serve returned error: %v", err)
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		if want := []string{"thrift", "http", "admin"}; !reflect.DeepEqual(closed, want) {
+			t.Errorf("Expected close order %v, got %v", want, closed)
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		var closed []string
+		var lock sync.Mutex
+		thrift := newFakeServeCloser("thrift", &closed, &lock)
+		http := newFakeServeCloser("http", &closed, &lock)
+		server := baseplate.NewMultiServer(bp, thrift, http)
+
+		ch := make(chan error)
+		go func() {
+			ch <- server.Serve()
+		}()
+		errFail := errors.New("listen failed")
+		http.fail <- errFail
+
+		select {
+		case err := <-ch:
+			if !errors.Is(err, errFail) {
+				t.Errorf("Expected error %v, got %v", errFail, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Serve didn't return after a server failed")
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		if want := []string{"thrift", "http"}; !reflect.DeepEqual(closed, want) {
+			t.Errorf("Expected close order %v, got %v", want, closed)
+		}
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		var closed []string
+		var lock sync.Mutex
+		thrift := newFakeServeCloser("thrift", &closed, &lock)
+		http := newFakeServeCloser("http", &closed, &lock)
+		server := baseplate.NewMultiServer(bp, thrift, http)
+
+		ch := make(chan error)
+		go func() {
+			ch <- server.Serve()
+		}()
+		http.fail <- nil
+
+		select {
+		case err := <-ch:
+			if !errors.Is(err, baseplate.ErrServerStopped) {
+				t.Errorf("Expected error %v, got %v", baseplate.ErrServerStopped, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Serve didn't return after a server stopped")
+		}
+	})
+
+	t.Run("serve", func(t *testing.T) {
+		var closed []string
+		var lock sync.Mutex
+		thrift := newFakeServeCloser("thrift", &closed, &lock)
+		http := newFakeServeCloser("http", &closed, &lock)
+
+		var hookCalled bool
+		bp.Lifecycle().Register(
+			baseplate.PhasePostShutdown,
+			"test",
+			func(context.Context) error {
+				hookCalled = true
+				return nil
+			},
+		)
+
+		ch := make(chan error)
+		go func() {
+			ch <- baseplate.Serve(context.Background(), baseplate.ServeArgs{
+				Server: baseplate.NewMultiServer(bp, thrift, http),
+			})
+		}()
+		errFail := errors.New("listen failed")
+		thrift.fail <- errFail
+
+		select {
+		case err := <-ch:
+			if !errors.Is(err, errFail) {
+				t.Errorf("Expected error %v, got %v", errFail, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Serve didn't return after a server failed")
+		}
+		if !hookCalled {
+			t.Error("Expected PostShutdown hook to be called")
+		}
+	})
+}
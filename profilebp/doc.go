@@ -0,0 +1,17 @@
+// Package profilebp provides automatic profile capturing for pathological
+// requests and goroutine spikes.
+//
+// When enabled (see Config), it captures:
+//
+// - A short CPU profile when a server request is still running after
+// Config.LatencyThreshold.
+// The path of the profile is attached to the server span as the
+// TagKeyProfile tag and logged.
+//
+// - A goroutine profile when the number of goroutines exceeds
+// Config.GoroutineThreshold.
+//
+// Captured profiles are stored in Config.Dir,
+// and only the most recent Config.MaxProfiles profiles are kept.
+// They can be analyzed with "go tool pprof".
+package profilebp
@@ -0,0 +1,371 @@
+package profilebp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/reddit/baseplate.go/batchcloser"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// TagKeyProfile is the span tag key used to link the captured CPU profile to
+// the slow server span.
+const TagKeyProfile = "profile"
+
+// Default values used when the corresponding Config fields are not set.
+const (
+	DefaultMaxProfiles        = 10
+	DefaultCPUProfileDuration = time.Second * 5
+	DefaultCheckInterval      = time.Second * 10
+	DefaultCooldown           = time.Minute
+)
+
+// Profile kinds, used in the file names of the captured profiles.
+const (
+	KindCPU       = "cpu"
+	KindGoroutine = "goroutine"
+)
+
+const profileSuffix = ".pprof"
+
+// ErrCPUProfileBusy is the error returned by CaptureCPU when there's already
+// an ongoing CPU profile.
+var ErrCPUProfileBusy = errors.New("profilebp: cpu profile already in progress")
+
+// Config is the config of the profile capturing.
+//
+// Can be deserialized from YAML.
+type Config struct {
+	// Dir is the directory to store the captured profiles.
+	//
+	// When it's empty, profile capturing is disabled.
+	Dir string `yaml:"dir"`
+
+	// MaxProfiles is the max number of profiles to keep in Dir,
+	// older ones are deleted when new ones are captured.
+	//
+	// Optional, default to DefaultMaxProfiles.
+	MaxProfiles int `yaml:"maxProfiles"`
+
+	// When a server request is still running after LatencyThreshold,
+	// a CPU profile of CPUProfileDuration is captured.
+	//
+	// Optional, default to 0 (disabled).
+	LatencyThreshold time.Duration `yaml:"latencyThreshold"`
+
+	// Optional, default to DefaultCPUProfileDuration.
+	CPUProfileDuration time.Duration `yaml:"cpuProfileDuration"`
+
+	// When the number of goroutines exceeds GoroutineThreshold,
+	// a goroutine profile is captured.
+	// The number of goroutines is checked every CheckInterval.
+	//
+	// Optional, default to 0 (disabled).
+	GoroutineThreshold int `yaml:"goroutineThreshold"`
+
+	// Optional, default to DefaultCheckInterval.
+	CheckInterval time.Duration `yaml:"checkInterval"`
+
+	// Cooldown is the minimal interval between two automatically captured
+	// profiles of the same kind,
+	// to avoid the profiling itself making things worse.
+	//
+	// Optional, default to DefaultCooldown.
+	Cooldown time.Duration `yaml:"cooldown"`
+}
+
+func (c Config) withDefaults() Config {
+	if c.MaxProfiles <= 0 {
+		c.MaxProfiles = DefaultMaxProfiles
+	}
+	if c.CPUProfileDuration <= 0 {
+		c.CPUProfileDuration = DefaultCPUProfileDuration
+	}
+	if c.CheckInterval <= 0 {
+		c.CheckInterval = DefaultCheckInterval
+	}
+	if c.Cooldown <= 0 {
+		c.Cooldown = DefaultCooldown
+	}
+	return c
+}
+
+// Profiler captures profiles into a bounded directory.
+//
+// It's also a tracing.CreateServerSpanHook capturing CPU profiles for slow
+// server requests.
+type Profiler struct {
+	cfg Config
+
+	// guards the files in cfg.Dir
+	lock sync.Mutex
+
+	cpuBusy       int32
+	lastCPU       int64
+	lastGoroutine int64
+
+	// Set by Close. The Profiler stays registered as a
+	// tracing.CreateServerSpanHook after Close as the hooks can't be
+	// unregistered, but it no longer captures profiles.
+	closed int32
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a Profiler.
+//
+// It also starts a background goroutine to check the number of goroutines when
+// cfg.GoroutineThreshold is positive,
+// which will be stopped when ctx is canceled or Close is called.
+func New(ctx context.Context, cfg Config) (*Profiler, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("profilebp.New: empty dir")
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("profilebp.New: failed to create dir: %w", err)
+	}
+	p := &Profiler{
+		cfg: cfg.withDefaults(),
+	}
+	ctx, p.cancel = context.WithCancel(ctx)
+	if p.cfg.GoroutineThreshold > 0 {
+		p.wg.Add(1)
+		go p.watchGoroutines(ctx)
+	}
+	return p, nil
+}
+
+// InitFromConfig creates a Profiler from cfg,
+// and registers it as a tracing.CreateServerSpanHook when
+// cfg.LatencyThreshold is positive.
+//
+// When cfg.Dir is empty, it does nothing and returns a nop io.Closer.
+func InitFromConfig(ctx context.Context, cfg Config) (io.Closer, error) {
+	if cfg.Dir == "" {
+		return batchcloser.Wrap(func() error { return nil }), nil
+	}
+	p, err := New(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if p.cfg.LatencyThreshold > 0 {
+		tracing.RegisterCreateServerSpanHooks(p)
+	}
+	return p, nil
+}
+
+// Close stops the background goroutine started by New,
+// and stops capturing profiles for slow server requests.
+func (p *Profiler) Close() error {
+	atomic.StoreInt32(&p.closed, 1)
+	p.cancel()
+	p.wg.Wait()
+	return nil
+}
+
+// CaptureGoroutines captures a goroutine profile and returns its path.
+func (p *Profiler) CaptureGoroutines() (path string, err error) {
+	f, path, err := p.create(KindGoroutine)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil && closeErr != nil {
+			err = closeErr
+		}
+	}()
+	if err := pprof.Lookup("goroutine").WriteTo(f, 0); err != nil {
+		return "", fmt.Errorf("profilebp: failed to write goroutine profile: %w", err)
+	}
+	return path, nil
+}
+
+// CaptureCPU starts capturing a CPU profile of cfg.CPUProfileDuration in the
+// background, and returns its path immediately.
+//
+// The profile file is only complete after the duration.
+// If there's already an ongoing CPU profile
+// (including the ones not started by the Profiler, e.g. from pprof http
+// handlers), it returns an error.
+func (p *Profiler) CaptureCPU() (path string, err error) {
+	if !atomic.CompareAndSwapInt32(&p.cpuBusy, 0, 1) {
+		return "", ErrCPUProfileBusy
+	}
+	f, path, err := p.create(KindCPU)
+	if err != nil {
+		atomic.StoreInt32(&p.cpuBusy, 0)
+		return "", err
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		os.Remove(path)
+		atomic.StoreInt32(&p.cpuBusy, 0)
+		return "", fmt.Errorf("profilebp: failed to start cpu profile: %w", err)
+	}
+	time.AfterFunc(p.cfg.CPUProfileDuration, func() {
+		defer atomic.StoreInt32(&p.cpuBusy, 0)
+		pprof.StopCPUProfile()
+		if err := f.Close(); err != nil {
+			log.Errorw("profilebp: failed to close cpu profile", "err", err, "path", path)
+		}
+	})
+	return path, nil
+}
+
+// allow returns true if the cooldown since last has passed,
+// and updates last to now.
+func (p *Profiler) allow(last *int64) bool {
+	now := time.Now().UnixNano()
+	prev := atomic.LoadInt64(last)
+	if prev != 0 && time.Duration(now-prev) < p.cfg.Cooldown {
+		return false
+	}
+	return atomic.CompareAndSwapInt64(last, prev, now)
+}
+
+// create creates a new profile file of kind,
+// and deletes the oldest ones to keep at most cfg.MaxProfiles files.
+func (p *Profiler) create(kind string) (*os.File, string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if err := p.prune(p.cfg.MaxProfiles - 1); err != nil {
+		log.Errorw("profilebp: failed to delete old profiles", "err", err)
+	}
+	// Timestamp goes first so that the file names are sorted by time.
+	name := time.Now().UTC().Format("20060102T150405.000000000Z") + "-" + kind + profileSuffix
+	path := filepath.Join(p.cfg.Dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("profilebp: failed to create profile file: %w", err)
+	}
+	return f, path, nil
+}
+
+// prune deletes the oldest profiles in the directory to keep at most max of
+// them.
+//
+// It must be called with the lock held.
+func (p *Profiler) prune(max int) error {
+	entries, err := ioutil.ReadDir(p.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), profileSuffix) {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) <= max {
+		return nil
+	}
+	sort.Strings(names)
+	for _, name := range names[:len(names)-max] {
+		if err := os.Remove(filepath.Join(p.cfg.Dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Profiler) watchGoroutines(ctx context.Context) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n := runtime.NumGoroutine()
+			if n <= p.cfg.GoroutineThreshold || !p.allow(&p.lastGoroutine) {
+				continue
+			}
+			path, err := p.CaptureGoroutines()
+			if err != nil {
+				log.Errorw("profilebp: failed to capture goroutine profile", "err", err)
+				continue
+			}
+			log.Warnw(
+				"profilebp: goroutine count exceeded threshold, profile captured",
+				"goroutines", n,
+				"threshold", p.cfg.GoroutineThreshold,
+				"profile", path,
+			)
+		}
+	}
+}
+
+// OnCreateServerSpan implements tracing.CreateServerSpanHook.
+func (p *Profiler) OnCreateServerSpan(span *tracing.Span) error {
+	if atomic.LoadInt32(&p.closed) != 0 {
+		return nil
+	}
+	span.AddHooks(&latencyHook{profiler: p})
+	return nil
+}
+
+// latencyHook captures a CPU profile when the server span is still running
+// after the latency threshold.
+type latencyHook struct {
+	profiler *Profiler
+
+	timer *time.Timer
+	path  atomic.Value // string
+}
+
+func (h *latencyHook) OnPostStart(span *tracing.Span) error {
+	h.timer = time.AfterFunc(h.profiler.cfg.LatencyThreshold, func() {
+		if atomic.LoadInt32(&h.profiler.closed) != 0 || !h.profiler.allow(&h.profiler.lastCPU) {
+			return
+		}
+		path, err := h.profiler.CaptureCPU()
+		if err != nil {
+			if !errors.Is(err, ErrCPUProfileBusy) {
+				log.Errorw("profilebp: failed to capture cpu profile", "err", err)
+			}
+			return
+		}
+		h.path.Store(path)
+		log.Warnw(
+			"profilebp: slow request, capturing cpu profile",
+			"endpoint", span.Name(),
+			"traceID", span.TraceID(),
+			"threshold", h.profiler.cfg.LatencyThreshold,
+			"profile", path,
+		)
+	})
+	return nil
+}
+
+func (h *latencyHook) OnPreStop(span *tracing.Span, err error) error {
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	if path, _ := h.path.Load().(string); path != "" {
+		span.SetTag(TagKeyProfile, path)
+	}
+	return nil
+}
+
+var (
+	_ tracing.CreateServerSpanHook = (*Profiler)(nil)
+	_ tracing.StartStopSpanHook    = (*latencyHook)(nil)
+)
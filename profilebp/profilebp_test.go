@@ -0,0 +1,153 @@
+package profilebp_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/profilebp"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+func listProfiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestCaptureGoroutines(t *testing.T) {
+	dir := t.TempDir()
+	p, err := profilebp.New(context.Background(), profilebp.Config{
+		Dir:         dir,
+		MaxProfiles: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	var paths []string
+	for i := 0; i < 3; i++ {
+		path, err := p.CaptureGoroutines()
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	names := listProfiles(t, dir)
+	if len(names) != 2 {
+		t.Fatalf("Expected 2 profiles kept, got %v", names)
+	}
+	for i, name := range names {
+		if !strings.HasSuffix(paths[i+1], name) {
+			t.Errorf("Expected the newest profiles to be kept, got %v, all paths: %v", names, paths)
+		}
+		if !strings.Contains(name, profilebp.KindGoroutine) {
+			t.Errorf("Expected %q in file name %q", profilebp.KindGoroutine, name)
+		}
+	}
+}
+
+func TestCaptureCPU(t *testing.T) {
+	dir := t.TempDir()
+	p, err := profilebp.New(context.Background(), profilebp.Config{
+		Dir:                dir,
+		CPUProfileDuration: time.Millisecond * 50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	path, err := p.CaptureCPU()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(path, profilebp.KindCPU) {
+		t.Errorf("Expected %q in path %q", profilebp.KindCPU, path)
+	}
+	if _, err := p.CaptureCPU(); !errors.Is(err, profilebp.ErrCPUProfileBusy) {
+		t.Errorf("Expected ErrCPUProfileBusy, got %v", err)
+	}
+
+	time.Sleep(time.Millisecond * 200)
+	if _, err := p.CaptureCPU(); err != nil {
+		t.Errorf("Expected CaptureCPU to succeed after the previous one finished, got %v", err)
+	}
+	time.Sleep(time.Millisecond * 200)
+}
+
+func TestLatencyHook(t *testing.T) {
+	defer tracing.ResetHooks()
+
+	dir := t.TempDir()
+	closer, err := profilebp.InitFromConfig(context.Background(), profilebp.Config{
+		Dir:                dir,
+		LatencyThreshold:   time.Millisecond * 10,
+		CPUProfileDuration: time.Millisecond * 50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+
+	ctx, span := tracing.StartSpanFromHeaders(context.Background(), "fast", tracing.Headers{})
+	span.Stop(ctx, nil)
+	if names := listProfiles(t, dir); len(names) != 0 {
+		t.Errorf("Expected no profiles for fast request, got %v", names)
+	}
+
+	ctx, span = tracing.StartSpanFromHeaders(context.Background(), "slow", tracing.Headers{})
+	time.Sleep(time.Millisecond * 30)
+	span.Stop(ctx, nil)
+	if names := listProfiles(t, dir); len(names) != 1 {
+		t.Errorf("Expected 1 profile for slow request, got %v", names)
+	}
+	time.Sleep(time.Millisecond * 100)
+}
+
+func TestLatencyHookAfterClose(t *testing.T) {
+	defer tracing.ResetHooks()
+
+	dir := t.TempDir()
+	closer, err := profilebp.InitFromConfig(context.Background(), profilebp.Config{
+		Dir:                dir,
+		LatencyThreshold:   time.Millisecond * 10,
+		CPUProfileDuration: time.Millisecond * 50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, span := tracing.StartSpanFromHeaders(context.Background(), "slow", tracing.Headers{})
+	time.Sleep(time.Millisecond * 30)
+	span.Stop(ctx, nil)
+	if names := listProfiles(t, dir); len(names) != 0 {
+		t.Errorf("Expected no profiles after Close, got %v", names)
+	}
+}
+
+func TestInitFromConfigDisabled(t *testing.T) {
+	closer, err := profilebp.InitFromConfig(context.Background(), profilebp.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := closer.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -2,5 +2,9 @@
 //
 // 1. A thread-safe, properly seeded global *math/rand.Rand implementation.
 //
-// 2. Helper functions for common use cases.
+// 2. Helper functions for common use cases,
+// including weighted random selection (WeightedChoice) and reservoir sampling
+// (Reservoir).
+//
+// 3. crypto/rand backed helpers for security purposes (SecureToken, SecureIntn).
 package randbp
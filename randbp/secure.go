@@ -0,0 +1,70 @@
+package randbp
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidN is the error returned by SecureIntn when n <= 0.
+var ErrInvalidN = errors.New("randbp: n must be positive")
+
+// SecureBytes returns n bytes read from crypto/rand.
+//
+// Unlike R, it's suitable for security purposes.
+func SecureBytes(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if read, err := cryptoReader(buf); err != nil {
+		return nil, fmt.Errorf(
+			"randbp.SecureBytes: only read %d/%d bytes from crypto/rand: %w",
+			read,
+			n,
+			err,
+		)
+	}
+	return buf, nil
+}
+
+// SecureToken returns a token of n random bytes read from crypto/rand,
+// encoded with url safe base64 without padding.
+//
+// It's suitable to be used as nonces, session ids, etc.
+// The length of the returned string is ceil(n*4/3),
+// for example a 16-byte token is 22 characters long.
+func SecureToken(n int) (string, error) {
+	buf, err := SecureBytes(n)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// SecureIntn returns a uniformly distributed random int in [0, n) using
+// crypto/rand.
+//
+// Unlike the naive approach of taking the random number modulo n,
+// it rejects the values from the incomplete range at the top,
+// so the result is not biased towards the smaller numbers.
+//
+// It returns ErrInvalidN when n <= 0.
+func SecureIntn(n int) (int, error) {
+	if n <= 0 {
+		return 0, ErrInvalidN
+	}
+	bound := uint64(n)
+	// The largest multiple of bound that fits in uint64,
+	// values >= limit are rejected.
+	limit := math.MaxUint64 - math.MaxUint64%bound
+	buf := make([]byte, 8)
+	for {
+		if _, err := cryptoReader(buf); err != nil {
+			return 0, fmt.Errorf("randbp.SecureIntn: failed to read from crypto/rand: %w", err)
+		}
+		v := binary.LittleEndian.Uint64(buf)
+		if v < limit {
+			return int(v % bound), nil
+		}
+	}
+}
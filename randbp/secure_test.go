@@ -0,0 +1,99 @@
+package randbp
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestSecureToken(t *testing.T) {
+	token, err := SecureToken(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != 22 {
+		t.Errorf("Expected 22 characters, got %q", token)
+	}
+	if _, err := base64.RawURLEncoding.DecodeString(token); err != nil {
+		t.Errorf("Expected url safe base64 token, got %q: %v", token, err)
+	}
+
+	other, err := SecureToken(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == other {
+		t.Errorf("Expected different tokens, got %q twice", token)
+	}
+}
+
+func TestSecureIntn(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := SecureIntn(0); !errors.Is(err, ErrInvalidN) {
+			t.Errorf("Expected ErrInvalidN, got %v", err)
+		}
+	})
+
+	t.Run("range", func(t *testing.T) {
+		const n = 7
+		var seen [n]bool
+		for i := 0; i < 1000; i++ {
+			v, err := SecureIntn(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v < 0 || v >= n {
+				t.Fatalf("Expected value in [0, %d), got %d", n, v)
+			}
+			seen[v] = true
+		}
+		for v, ok := range seen {
+			if !ok {
+				t.Errorf("Expected %d to be returned at least once", v)
+			}
+		}
+	})
+
+	t.Run("rejection", func(t *testing.T) {
+		oldCryptoReader := cryptoReader
+		defer func() {
+			cryptoReader = oldCryptoReader
+		}()
+
+		// The first read is in the biased range at the top and must be rejected.
+		reads := [][]byte{
+			{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+			{5, 0, 0, 0, 0, 0, 0, 0},
+		}
+		cryptoReader = func(p []byte) (int, error) {
+			n := copy(p, reads[0])
+			reads = reads[1:]
+			return n, nil
+		}
+		v, err := SecureIntn(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != 2 {
+			t.Errorf("Expected 2, got %d", v)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		oldCryptoReader := cryptoReader
+		defer func() {
+			cryptoReader = oldCryptoReader
+		}()
+
+		errRead := errors.New("read failed")
+		cryptoReader = func(p []byte) (int, error) {
+			return 0, errRead
+		}
+		if _, err := SecureIntn(3); !errors.Is(err, errRead) {
+			t.Errorf("Expected %v, got %v", errRead, err)
+		}
+		if _, err := SecureToken(3); !errors.Is(err, errRead) {
+			t.Errorf("Expected %v, got %v", errRead, err)
+		}
+	})
+}
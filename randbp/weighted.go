@@ -0,0 +1,193 @@
+package randbp
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+// Errors returned by NewWeightedChoice.
+var (
+	ErrNoChoices      = errors.New("randbp: no choices")
+	ErrInvalidWeights = errors.New("randbp: weights must be non-negative finite numbers with a positive sum")
+	ErrWeightsLength  = errors.New("randbp: the number of weights doesn't match the number of choices")
+)
+
+// ErrInvalidSize is the error returned by NewReservoir when k is negative.
+var ErrInvalidSize = errors.New("randbp: reservoir size must be non-negative")
+
+// The common interface between *math/rand.Rand and randbp.Rand used by the
+// helpers picking random elements.
+type intFloater interface {
+	intner
+	Float64() float64
+}
+
+// WeightedChoice picks random elements from a fixed set of choices,
+// with the probability of each choice proportional to its weight.
+//
+// It uses the alias method,
+// so Pick takes O(1) time regardless of the number of choices,
+// after the O(n) setup in NewWeightedChoice.
+//
+// It's immutable after created, and safe for concurrent use as long as the rng
+// used is safe for concurrent use (e.g. R).
+type WeightedChoice[T any] struct {
+	choices []T
+	prob    []float64
+	alias   []int
+}
+
+// NewWeightedChoice creates a WeightedChoice.
+//
+// weights must have the same length as choices,
+// the weights must be non-negative and finite, with a positive sum.
+// The choices with 0 weight are never picked.
+func NewWeightedChoice[T any](choices []T, weights []float64) (*WeightedChoice[T], error) {
+	n := len(choices)
+	if n == 0 {
+		return nil, ErrNoChoices
+	}
+	if len(weights) != n {
+		return nil, ErrWeightsLength
+	}
+	var sum float64
+	for _, w := range weights {
+		if w < 0 || math.IsInf(w, 0) || math.IsNaN(w) {
+			return nil, ErrInvalidWeights
+		}
+		sum += w
+	}
+	if sum <= 0 || math.IsInf(sum, 0) {
+		return nil, ErrInvalidWeights
+	}
+
+	// Vose's alias method.
+	wc := &WeightedChoice[T]{
+		choices: append([]T(nil), choices...),
+		prob:    make([]float64, n),
+		alias:   make([]int, n),
+	}
+	scaled := make([]float64, n)
+	var small, large []int
+	// The index of the heaviest choice, the alias of the 0 weight leftovers.
+	heaviest := 0
+	for i, w := range weights {
+		if w > weights[heaviest] {
+			heaviest = i
+		}
+		scaled[i] = w * float64(n) / sum
+		if scaled[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+	for len(small) > 0 && len(large) > 0 {
+		s := small[len(small)-1]
+		small = small[:len(small)-1]
+		l := large[len(large)-1]
+		large = large[:len(large)-1]
+
+		wc.prob[s] = scaled[s]
+		wc.alias[s] = l
+		scaled[l] = scaled[l] + scaled[s] - 1
+		if scaled[l] < 1 {
+			small = append(small, l)
+		} else {
+			large = append(large, l)
+		}
+	}
+	// The leftovers are only off from 1 because of floating point errors,
+	// except for the 0 weight ones, which must never be picked.
+	for _, i := range large {
+		wc.prob[i] = 1
+	}
+	for _, i := range small {
+		if weights[i] == 0 {
+			wc.prob[i] = 0
+			wc.alias[i] = heaviest
+			continue
+		}
+		wc.prob[i] = 1
+	}
+	return wc, nil
+}
+
+// Pick picks a random element using R.
+func (wc *WeightedChoice[T]) Pick() T {
+	return wc.pick(R)
+}
+
+// PickWith picks a random element using r.
+//
+// It's useful in tests to get reproducible results with a seeded r.
+func (wc *WeightedChoice[T]) PickWith(r *rand.Rand) T {
+	return wc.pick(r)
+}
+
+func (wc *WeightedChoice[T]) pick(r intFloater) T {
+	i := r.Intn(len(wc.choices))
+	if r.Float64() < wc.prob[i] {
+		return wc.choices[i]
+	}
+	return wc.choices[wc.alias[i]]
+}
+
+// Reservoir keeps a uniform random sample of up to k elements from a stream of
+// unknown length, using O(k) memory.
+//
+// Every element added has the same probability to be in the sample.
+//
+// It's not safe for concurrent use.
+type Reservoir[T any] struct {
+	k       int
+	seen    int
+	samples []T
+	r       intner
+}
+
+// NewReservoir creates a Reservoir keeping up to k samples.
+//
+// k must be non-negative, otherwise ErrInvalidSize is returned.
+//
+// r is optional, when it's nil R will be used instead.
+func NewReservoir[T any](k int, r *rand.Rand) (*Reservoir[T], error) {
+	if k < 0 {
+		return nil, ErrInvalidSize
+	}
+	res := &Reservoir[T]{
+		k:       k,
+		samples: make([]T, 0, k),
+	}
+	if r != nil {
+		res.r = r
+	} else {
+		res.r = R
+	}
+	return res, nil
+}
+
+// Add adds an element from the stream.
+func (res *Reservoir[T]) Add(item T) {
+	res.seen++
+	if len(res.samples) < res.k {
+		res.samples = append(res.samples, item)
+		return
+	}
+	if i := res.r.Intn(res.seen); i < res.k {
+		res.samples[i] = item
+	}
+}
+
+// Seen returns the number of elements added.
+func (res *Reservoir[T]) Seen() int {
+	return res.seen
+}
+
+// Samples returns the current sample.
+//
+// The returned slice is a copy that's safe to be modified.
+func (res *Reservoir[T]) Samples() []T {
+	return append([]T(nil), res.samples...)
+}
@@ -0,0 +1,165 @@
+package randbp_test
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/reddit/baseplate.go/randbp"
+)
+
+func TestNewWeightedChoiceErrors(t *testing.T) {
+	for _, c := range []struct {
+		label   string
+		choices []string
+		weights []float64
+		err     error
+	}{
+		{
+			label: "empty",
+			err:   randbp.ErrNoChoices,
+		},
+		{
+			label:   "length",
+			choices: []string{"a", "b"},
+			weights: []float64{1},
+			err:     randbp.ErrWeightsLength,
+		},
+		{
+			label:   "negative",
+			choices: []string{"a", "b"},
+			weights: []float64{1, -1},
+			err:     randbp.ErrInvalidWeights,
+		},
+		{
+			label:   "zero-sum",
+			choices: []string{"a", "b"},
+			weights: []float64{0, 0},
+			err:     randbp.ErrInvalidWeights,
+		},
+		{
+			label:   "nan",
+			choices: []string{"a"},
+			weights: []float64{math.NaN()},
+			err:     randbp.ErrInvalidWeights,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			_, err := randbp.NewWeightedChoice(c.choices, c.weights)
+			if !errors.Is(err, c.err) {
+				t.Errorf("Expected %v, got %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestWeightedChoice(t *testing.T) {
+	choices := []string{"a", "b", "c", "never"}
+	weights := []float64{1, 2, 7, 0}
+	wc, err := randbp.NewWeightedChoice(choices, weights)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 100000
+	r := rand.New(rand.NewSource(1))
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		counts[wc.PickWith(r)]++
+	}
+	if counts["never"] != 0 {
+		t.Errorf("Expected 0 weight choice to never be picked, got %d", counts["never"])
+	}
+	for i, choice := range choices[:3] {
+		expected := weights[i] / 10
+		got := float64(counts[choice]) / n
+		if math.Abs(got-expected) > 0.01 {
+			t.Errorf("Expected %q to be picked with ratio %v, got %v", choice, expected, got)
+		}
+	}
+
+	// Make sure the global rng version works, too.
+	if got := wc.Pick(); got == "never" {
+		t.Errorf("Expected 0 weight choice to never be picked, got %q", got)
+	}
+}
+
+func TestWeightedChoiceZeroWeights(t *testing.T) {
+	choices := make([]int, 100)
+	weights := make([]float64, 100)
+	for i := range choices {
+		choices[i] = i
+		if i%10 == 0 {
+			weights[i] = 0.1 * float64(i+1)
+		}
+	}
+	wc, err := randbp.NewWeightedChoice(choices, weights)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100000; i++ {
+		if got := wc.PickWith(r); weights[got] == 0 {
+			t.Fatalf("Expected 0 weight choice to never be picked, got %d", got)
+		}
+	}
+}
+
+func TestReservoir(t *testing.T) {
+	t.Run("negative", func(t *testing.T) {
+		if _, err := randbp.NewReservoir[int](-1, nil); !errors.Is(err, randbp.ErrInvalidSize) {
+			t.Errorf("Expected %v, got %v", randbp.ErrInvalidSize, err)
+		}
+	})
+
+	t.Run("fewer", func(t *testing.T) {
+		res, err := randbp.NewReservoir[int](5, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			res.Add(i)
+		}
+		if got := res.Samples(); len(got) != 3 {
+			t.Errorf("Expected all 3 elements to be sampled, got %v", got)
+		}
+		if res.Seen() != 3 {
+			t.Errorf("Expected seen 3, got %d", res.Seen())
+		}
+	})
+
+	t.Run("uniform", func(t *testing.T) {
+		const (
+			k      = 2
+			stream = 10
+			rounds = 20000
+		)
+		r := rand.New(rand.NewSource(1))
+		counts := make([]int, stream)
+		for i := 0; i < rounds; i++ {
+			res, err := randbp.NewReservoir[int](k, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for j := 0; j < stream; j++ {
+				res.Add(j)
+			}
+			samples := res.Samples()
+			if len(samples) != k {
+				t.Fatalf("Expected %d samples, got %v", k, samples)
+			}
+			for _, s := range samples {
+				counts[s]++
+			}
+		}
+		expected := float64(k) / stream
+		for i, c := range counts {
+			got := float64(c) / rounds
+			if math.Abs(got-expected) > 0.02 {
+				t.Errorf("Expected %d to be sampled with ratio %v, got %v", i, expected, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// The benchmarks compare the CPU and memory cost of the algorithms,
+// with the limits set so that about half of the requests are rejected.
+//
+// The sliding window log allocates per allowed request up to Limit per key,
+// while the other algorithms allocate once per key.
+func BenchmarkLimiters(b *testing.B) {
+	const (
+		keys  = 1000
+		limit = 100
+	)
+	for _, c := range []struct {
+		label string
+		cfg   Config
+	}{
+		{
+			label: "token-bucket",
+			cfg: Config{
+				Algorithm: AlgorithmTokenBucket,
+				TokenBucket: TokenBucketConfig{
+					Rate:  limit,
+					Burst: limit,
+				},
+			},
+		},
+		{
+			label: "leaky-bucket",
+			cfg: Config{
+				Algorithm: AlgorithmLeakyBucket,
+				LeakyBucket: LeakyBucketConfig{
+					Rate:     limit,
+					Capacity: limit,
+				},
+			},
+		},
+		{
+			label: "sliding-window-log",
+			cfg: Config{
+				Algorithm: AlgorithmSlidingWindowLog,
+				SlidingWindow: SlidingWindowConfig{
+					Limit:  limit,
+					Window: time.Second,
+				},
+			},
+		},
+		{
+			label: "sliding-window-counter",
+			cfg: Config{
+				Algorithm: AlgorithmSlidingWindowCounter,
+				SlidingWindow: SlidingWindowConfig{
+					Limit:  limit,
+					Window: time.Second,
+				},
+			},
+		},
+	} {
+		b.Run(c.label, func(b *testing.B) {
+			limiter, err := New(c.cfg)
+			if err != nil {
+				b.Fatal(err)
+			}
+			keyNames := make([]string, keys)
+			for i := range keyNames {
+				keyNames[i] = strconv.Itoa(i)
+			}
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				var i int
+				for pb.Next() {
+					if _, err := Allow(ctx, limiter, keyNames[i%keys]); err != nil {
+						b.Error(err)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
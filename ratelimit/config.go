@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"fmt"
+
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+// Algorithm is the rate limiting algorithm of a Limiter created by New.
+type Algorithm string
+
+// Supported Algorithm values.
+//
+// See the documentation of the corresponding types for the tradeoffs between
+// them, and the benchmarks for their performance.
+const (
+	// AlgorithmTokenBucket is the default, see TokenBucket.
+	AlgorithmTokenBucket Algorithm = "token_bucket"
+	// See LeakyBucket.
+	AlgorithmLeakyBucket Algorithm = "leaky_bucket"
+	// See SlidingWindowLog.
+	AlgorithmSlidingWindowLog Algorithm = "sliding_window_log"
+	// See SlidingWindowCounter.
+	AlgorithmSlidingWindowCounter Algorithm = "sliding_window_counter"
+)
+
+// Config is the config to create an in-memory Limiter with New,
+// so that the algorithm can be selected per limit.
+//
+// Only the config of the selected Algorithm is used.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//	algorithm: sliding_window_counter
+//	slidingWindow:
+//	  limit: 100
+//	  window: 1m
+type Config struct {
+	// Optional. Defaults to AlgorithmTokenBucket.
+	Algorithm Algorithm `yaml:"algorithm"`
+
+	TokenBucket   TokenBucketConfig   `yaml:"tokenBucket"`
+	LeakyBucket   LeakyBucketConfig   `yaml:"leakyBucket"`
+	SlidingWindow SlidingWindowConfig `yaml:"slidingWindow"`
+
+	// Optional. When set it's used as the Clock of the selected Algorithm,
+	// unless the config of the Algorithm has its own Clock set.
+	Clock timebp.Clock `yaml:"-"`
+}
+
+// New creates an in-memory Limiter with the Algorithm selected in cfg.
+func New(cfg Config) (Limiter, error) {
+	if cfg.Clock != nil {
+		if cfg.TokenBucket.Clock == nil {
+			cfg.TokenBucket.Clock = cfg.Clock
+		}
+		if cfg.LeakyBucket.Clock == nil {
+			cfg.LeakyBucket.Clock = cfg.Clock
+		}
+		if cfg.SlidingWindow.Clock == nil {
+			cfg.SlidingWindow.Clock = cfg.Clock
+		}
+	}
+	switch cfg.Algorithm {
+	case "", AlgorithmTokenBucket:
+		return NewTokenBucket(cfg.TokenBucket)
+	case AlgorithmLeakyBucket:
+		return NewLeakyBucket(cfg.LeakyBucket)
+	case AlgorithmSlidingWindowLog:
+		return NewSlidingWindowLog(cfg.SlidingWindow)
+	case AlgorithmSlidingWindowCounter:
+		return NewSlidingWindowCounter(cfg.SlidingWindow)
+	default:
+		return nil, fmt.Errorf("ratelimit: unknown algorithm %q", cfg.Algorithm)
+	}
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+func TestNew(t *testing.T) {
+	cfg := Config{
+		TokenBucket: TokenBucketConfig{
+			Rate:  1,
+			Burst: 1,
+		},
+		LeakyBucket: LeakyBucketConfig{
+			Rate:     1,
+			Capacity: 1,
+		},
+		SlidingWindow: SlidingWindowConfig{
+			Limit:  1,
+			Window: time.Second,
+		},
+	}
+	for algorithm, expected := range map[Algorithm]string{
+		"":                            "*ratelimit.TokenBucket",
+		AlgorithmTokenBucket:          "*ratelimit.TokenBucket",
+		AlgorithmLeakyBucket:          "*ratelimit.LeakyBucket",
+		AlgorithmSlidingWindowLog:     "*ratelimit.SlidingWindowLog",
+		AlgorithmSlidingWindowCounter: "*ratelimit.SlidingWindowCounter",
+	} {
+		cfg.Algorithm = algorithm
+		limiter, err := New(cfg)
+		if err != nil {
+			t.Fatalf("%q: %v", algorithm, err)
+		}
+		if actual := fmt.Sprintf("%T", limiter); actual != expected {
+			t.Errorf("%q: Expected %s, got %s", algorithm, expected, actual)
+		}
+	}
+
+	cfg.Algorithm = "unknown"
+	if _, err := New(cfg); err == nil {
+		t.Error("Expected error for unknown algorithm, got nil")
+	}
+	if _, err := New(Config{Algorithm: AlgorithmLeakyBucket}); err == nil {
+		t.Error("Expected error for invalid config, got nil")
+	}
+}
+
+func TestNewClock(t *testing.T) {
+	clock := timebp.NewFakeClock(time.Now())
+	limiter, err := New(Config{
+		TokenBucket: TokenBucketConfig{
+			Rate:  1,
+			Burst: 1,
+		},
+		Clock: clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if result, err := limiter.AllowN(ctx, "key", 1); err != nil || !result.Allowed {
+		t.Fatalf("Expected first request to be allowed, got %+v, %v", result, err)
+	}
+	if result, err := limiter.AllowN(ctx, "key", 1); err != nil || result.Allowed {
+		t.Fatalf("Expected second request to be rejected, got %+v, %v", result, err)
+	}
+	clock.Advance(time.Second)
+	if result, err := limiter.AllowN(ctx, "key", 1); err != nil || !result.Allowed {
+		t.Errorf("Expected request to be allowed after refill, got %+v, %v", result, err)
+	}
+}
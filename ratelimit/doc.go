@@ -0,0 +1,18 @@
+// Package ratelimit provides the common interface of rate limiters,
+// and in-memory implementations of the token bucket, leaky bucket,
+// sliding window log and sliding window counter algorithms.
+//
+// The in-memory implementations only limit the requests inside the same
+// process.
+// To enforce the limits consistently across replicas,
+// use a distributed implementation instead (e.g. redisbp.RateLimiter).
+//
+// The Limiters can be used by the rate limiting middlewares in thriftbp,
+// httpbp and grpcbp.
+//
+// It also provides the common interface of quotas (Quota),
+// which track the usage of keys over long periods (e.g. daily API quotas per
+// user), with an in-memory implementation (MemoryQuota).
+// The Quotas can be used by the quota middlewares in thriftbp and httpbp,
+// keyed by the authenticated caller from the edge context by default.
+package ratelimit
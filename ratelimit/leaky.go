@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+// LeakyBucketConfig is the config of a leaky bucket limiter.
+//
+// Can be deserialized from YAML.
+type LeakyBucketConfig struct {
+	// Rate is the number of requests leaking out of the bucket per second.
+	Rate float64 `yaml:"rate"`
+
+	// Capacity is the max number of requests the bucket can hold.
+	Capacity int64 `yaml:"capacity"`
+
+	// Optional. The Clock to get the current time from,
+	// see TokenBucketConfig.Clock.
+	Clock timebp.Clock `yaml:"-"`
+}
+
+// Validate checks LeakyBucketConfig for any erroneous values.
+func (c LeakyBucketConfig) Validate() error {
+	if c.Rate <= 0 {
+		return errors.New("ratelimit: rate must be positive")
+	}
+	if c.Capacity <= 0 {
+		return errors.New("ratelimit: capacity must be positive")
+	}
+	return nil
+}
+
+// interval returns the time it takes a request to leak out of the bucket.
+func (c LeakyBucketConfig) interval() time.Duration {
+	return time.Duration(float64(time.Second) / c.Rate)
+}
+
+// LeakyBucket is an in-memory leaky bucket (as a meter) Limiter.
+//
+// Every allowed request adds to the level of the bucket of its key,
+// which leaks at Rate, and the requests overflowing Capacity are rejected.
+//
+// It's implemented as the generic cell rate algorithm (GCRA),
+// only keeping the time the bucket of each key becomes empty,
+// so it uses less memory per key than TokenBucket and no floating point
+// arithmetic.
+// It allows the same bursts as a TokenBucket with Burst equal to Capacity.
+type LeakyBucket struct {
+	cfg      LeakyBucketConfig
+	interval time.Duration
+	now      func() time.Time
+
+	lock sync.Mutex
+	// The theoretical arrival time, i.e. when the bucket becomes empty.
+	tats  map[string]time.Time
+	calls int
+}
+
+// NewLeakyBucket creates a LeakyBucket.
+func NewLeakyBucket(cfg LeakyBucketConfig) (*LeakyBucket, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &LeakyBucket{
+		cfg:      cfg,
+		interval: cfg.interval(),
+		now:      timebp.ClockOrDefault(cfg.Clock).Now,
+		tats:     make(map[string]time.Time),
+	}, nil
+}
+
+// AllowN implements Limiter.
+func (lb *LeakyBucket) AllowN(_ context.Context, key string, n int64) (Result, error) {
+	lb.lock.Lock()
+	defer lb.lock.Unlock()
+
+	now := lb.now()
+	lb.calls++
+	if lb.calls >= sweepInterval {
+		lb.calls = 0
+		lb.sweep(now)
+	}
+
+	tat := lb.tats[key]
+	if tat.Before(now) {
+		tat = now
+	}
+	limit := time.Duration(lb.cfg.Capacity) * lb.interval
+	newTAT := tat.Add(time.Duration(n) * lb.interval)
+	if excess := newTAT.Sub(now) - limit; excess > 0 {
+		return Result{
+			Remaining:  int64((limit - tat.Sub(now)) / lb.interval),
+			RetryAfter: excess,
+		}, nil
+	}
+	lb.tats[key] = newTAT
+	return Result{
+		Allowed:   true,
+		Remaining: int64((limit - newTAT.Sub(now)) / lb.interval),
+	}, nil
+}
+
+// sweep deletes the empty buckets.
+//
+// It must be called with the lock held.
+func (lb *LeakyBucket) sweep(now time.Time) {
+	for key, tat := range lb.tats {
+		if !tat.After(now) {
+			delete(lb.tats, key)
+		}
+	}
+}
+
+var _ Limiter = (*LeakyBucket)(nil)
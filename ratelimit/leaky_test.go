@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeakyBucket(t *testing.T) {
+	lb, err := NewLeakyBucket(LeakyBucketConfig{
+		Rate:     2,
+		Capacity: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	lb.now = func() time.Time {
+		return now
+	}
+
+	result := checkAllow(t, lb, "a", 1, true)
+	if result.Remaining != 1 {
+		t.Errorf("Expected Remaining 1, got %d", result.Remaining)
+	}
+	checkAllow(t, lb, "a", 1, true)
+	result = checkAllow(t, lb, "a", 1, false)
+	if result.RetryAfter != time.Second/2 {
+		t.Errorf("Expected RetryAfter %v, got %v", time.Second/2, result.RetryAfter)
+	}
+	result = checkAllow(t, lb, "a", 3, false)
+	if result.RetryAfter != time.Second*3/2 {
+		t.Errorf("Expected RetryAfter %v, got %v", time.Second*3/2, result.RetryAfter)
+	}
+	checkAllow(t, lb, "b", 2, true)
+
+	now = now.Add(time.Second / 2)
+	checkAllow(t, lb, "a", 1, true)
+	checkAllow(t, lb, "a", 1, false)
+
+	now = now.Add(time.Hour)
+	lb.sweep(now)
+	if len(lb.tats) != 0 {
+		t.Errorf("Expected empty buckets to be swept, got %v", lb.tats)
+	}
+}
+
+func TestLeakyBucketConfigValidate(t *testing.T) {
+	for _, cfg := range []LeakyBucketConfig{
+		{},
+		{Rate: 1},
+		{Capacity: 1},
+		{Rate: -1, Capacity: 1},
+	} {
+		if _, err := NewLeakyBucket(cfg); err == nil {
+			t.Errorf("Expected error for %+v, got nil", cfg)
+		}
+	}
+}
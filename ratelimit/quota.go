@@ -0,0 +1,201 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+// DefaultQuotaPeriod is the default value of QuotaConfig.Period.
+const DefaultQuotaPeriod = time.Hour * 24
+
+// QuotaConfig is the config of a quota.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//	limit: 10000
+//	period: 24h
+type QuotaConfig struct {
+	// Limit is the max usage of each key in a Period.
+	Limit int64 `yaml:"limit"`
+
+	// Optional. The length of the quota periods.
+	// The periods are aligned to the unix epoch,
+	// so the default (DefaultQuotaPeriod) resets at midnight UTC.
+	Period time.Duration `yaml:"period"`
+
+	// Optional. The Clock to get the current time from,
+	// see TokenBucketConfig.Clock.
+	Clock timebp.Clock `yaml:"-"`
+}
+
+// Validate checks QuotaConfig for any erroneous values.
+func (c QuotaConfig) Validate() error {
+	if c.Limit <= 0 {
+		return errors.New("ratelimit: limit must be positive")
+	}
+	if c.Period < 0 {
+		return errors.New("ratelimit: period must not be negative")
+	}
+	return nil
+}
+
+// WithDefaults returns the copy of c with the optional fields defaulted.
+func (c QuotaConfig) WithDefaults() QuotaConfig {
+	if c.Period == 0 {
+		c.Period = DefaultQuotaPeriod
+	}
+	return c
+}
+
+// PeriodStart returns the start of the period containing now.
+func (c QuotaConfig) PeriodStart(now time.Time) time.Time {
+	return time.Unix(0, now.UnixNano()-now.UnixNano()%int64(c.Period))
+}
+
+// QuotaResult is the result of a quota check.
+type QuotaResult struct {
+	// Allowed is true if the usage is allowed (and consumed).
+	Allowed bool
+
+	// Limit is the max usage of the key in the period.
+	Limit int64
+
+	// Remaining is the remaining quota of the key after this check.
+	Remaining int64
+
+	// ResetAt is the time the quota of the key resets.
+	ResetAt time.Time
+}
+
+// Err returns a *QuotaExceededError if the usage is not allowed,
+// or nil otherwise.
+func (r QuotaResult) Err(key string) error {
+	if r.Allowed {
+		return nil
+	}
+	return &QuotaExceededError{
+		Key:     key,
+		Limit:   r.Limit,
+		ResetAt: r.ResetAt,
+	}
+}
+
+// QuotaExceededError is the standardized error of a key exceeding its quota.
+type QuotaExceededError struct {
+	Key     string
+	Limit   int64
+	ResetAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf(
+		"ratelimit: quota of %q exceeded the limit of %d, resets at %s",
+		e.Key,
+		e.Limit,
+		e.ResetAt.UTC().Format(time.RFC3339),
+	)
+}
+
+// Quota defines the interface of a quota tracking the usage of keys over
+// long periods (e.g. daily API quotas per user).
+type Quota interface {
+	// CheckAndConsume checks whether the usage of n is within the remaining
+	// quota of key, and consumes it if it is.
+	CheckAndConsume(ctx context.Context, key string, n int64) (QuotaResult, error)
+}
+
+// MemoryQuota is an in-memory Quota.
+//
+// It only tracks the usage inside the same process,
+// which is lost on restarts.
+// For the quotas to be enforced across replicas and restarts,
+// use a distributed implementation instead (e.g. redisbp.Quota).
+type MemoryQuota struct {
+	cfg QuotaConfig
+	now func() time.Time
+
+	lock   sync.Mutex
+	usages map[string]*quotaUsage
+	calls  int
+}
+
+type quotaUsage struct {
+	start time.Time
+	used  int64
+}
+
+// NewMemoryQuota creates a MemoryQuota.
+func NewMemoryQuota(cfg QuotaConfig) (*MemoryQuota, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &MemoryQuota{
+		cfg:    cfg.WithDefaults(),
+		now:    timebp.ClockOrDefault(cfg.Clock).Now,
+		usages: make(map[string]*quotaUsage),
+	}, nil
+}
+
+// CheckAndConsume implements Quota.
+func (q *MemoryQuota) CheckAndConsume(_ context.Context, key string, n int64) (QuotaResult, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	now := q.now()
+	start := q.cfg.PeriodStart(now)
+	q.calls++
+	if q.calls >= sweepInterval {
+		q.calls = 0
+		q.sweep(start)
+	}
+
+	usage := q.usages[key]
+	if usage == nil || !usage.start.Equal(start) {
+		usage = &quotaUsage{start: start}
+		q.usages[key] = usage
+	}
+	result := QuotaResult{
+		Limit:   q.cfg.Limit,
+		ResetAt: start.Add(q.cfg.Period),
+	}
+	if usage.used+n > q.cfg.Limit {
+		result.Remaining = q.cfg.Limit - usage.used
+		return result, nil
+	}
+	usage.used += n
+	result.Allowed = true
+	result.Remaining = q.cfg.Limit - usage.used
+	return result, nil
+}
+
+// sweep deletes the usages of the previous periods.
+//
+// It must be called with the lock held.
+func (q *MemoryQuota) sweep(start time.Time) {
+	for key, usage := range q.usages {
+		if !usage.start.Equal(start) {
+			delete(q.usages, key)
+		}
+	}
+}
+
+// AuthenticatedKey returns the id of the authenticated caller from the edge
+// context attached to ctx, to be used as the key of the quota middlewares in
+// thriftbp and httpbp.
+//
+// It returns empty string when the request is not authenticated,
+// or the edgecontext implementation doesn't implement ecinterface.Identifier.
+func AuthenticatedKey(ctx context.Context) string {
+	id, _ := ecinterface.GetAuthenticatedID(ctx, ecinterface.Get())
+	return id
+}
+
+var _ Quota = (*MemoryQuota)(nil)
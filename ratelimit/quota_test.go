@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryQuota(t *testing.T) {
+	q, err := NewMemoryQuota(QuotaConfig{
+		Limit:  3,
+		Period: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2021, 1, 1, 10, 30, 0, 0, time.UTC)
+	q.now = func() time.Time {
+		return now
+	}
+	ctx := context.Background()
+	resetAt := time.Date(2021, 1, 1, 11, 0, 0, 0, time.UTC)
+
+	check := func(key string, n int64, allowed bool, remaining int64) {
+		t.Helper()
+		result, err := q.CheckAndConsume(ctx, key, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Allowed != allowed || result.Remaining != remaining || result.Limit != 3 {
+			t.Errorf("Expected allowed %v remaining %d, got %+v", allowed, remaining, result)
+		}
+		if !result.ResetAt.Equal(resetAt) {
+			t.Errorf("Expected reset at %v, got %v", resetAt, result.ResetAt)
+		}
+	}
+
+	check("user", 2, true, 1)
+	check("user", 2, false, 1)
+	check("other", 3, true, 0)
+	check("user", 1, true, 0)
+	check("user", 1, false, 0)
+
+	now = now.Add(time.Minute * 30)
+	resetAt = resetAt.Add(time.Hour)
+	check("user", 3, true, 0)
+}
+
+func TestMemoryQuotaDefaults(t *testing.T) {
+	q, err := NewMemoryQuota(QuotaConfig{
+		Limit: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2021, 1, 1, 10, 30, 0, 0, time.UTC)
+	q.now = func() time.Time {
+		return now
+	}
+	result, err := q.CheckAndConsume(context.Background(), "user", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC); !result.ResetAt.Equal(expected) {
+		t.Errorf("Expected the daily quota to reset at %v, got %v", expected, result.ResetAt)
+	}
+
+	if _, err := NewMemoryQuota(QuotaConfig{}); err == nil {
+		t.Error("Expected error from invalid config, got nil")
+	}
+}
+
+func TestQuotaResultErr(t *testing.T) {
+	resetAt := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+	if err := (QuotaResult{Allowed: true}).Err("user"); err != nil {
+		t.Errorf("Expected nil error when allowed, got %v", err)
+	}
+	err := QuotaResult{Limit: 10, ResetAt: resetAt}.Err("user")
+	var qe *QuotaExceededError
+	if !errors.As(err, &qe) {
+		t.Fatalf("Expected *QuotaExceededError, got %#v", err)
+	}
+	if qe.Key != "user" || qe.Limit != 10 || !qe.ResetAt.Equal(resetAt) {
+		t.Errorf("Unexpected error %+v", qe)
+	}
+	expected := `ratelimit: quota of "user" exceeded the limit of 10, resets at 2021-01-02T00:00:00Z`
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+}
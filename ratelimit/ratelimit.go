@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+// Result is the result of a rate limit check.
+type Result struct {
+	// Allowed is true if the request is allowed.
+	Allowed bool
+
+	// Remaining is the remaining capacity of the key after this request.
+	Remaining int64
+
+	// RetryAfter is the duration to wait before the request would be allowed,
+	// when it's not allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter defines the interface of a rate limiter.
+type Limiter interface {
+	// AllowN checks whether n requests of key are allowed at this moment,
+	// and consumes the capacity of key if they are.
+	AllowN(ctx context.Context, key string, n int64) (Result, error)
+}
+
+// Allow is the shortcut of limiter.AllowN(ctx, key, 1).
+func Allow(ctx context.Context, limiter Limiter, key string) (Result, error) {
+	return limiter.AllowN(ctx, key, 1)
+}
+
+// TokenBucketConfig is the config of a token bucket limiter.
+//
+// Can be deserialized from YAML.
+type TokenBucketConfig struct {
+	// Rate is the number of tokens added to the bucket per second.
+	Rate float64 `yaml:"rate"`
+
+	// Burst is the capacity of the bucket.
+	Burst int64 `yaml:"burst"`
+
+	// Optional. The Clock to get the current time from,
+	// defaults to timebp.SystemClock.
+	//
+	// It's for the tests to control the time with a timebp.FakeClock.
+	Clock timebp.Clock `yaml:"-"`
+}
+
+// Validate checks TokenBucketConfig for any erroneous values.
+func (c TokenBucketConfig) Validate() error {
+	if c.Rate <= 0 {
+		return errors.New("ratelimit: rate must be positive")
+	}
+	if c.Burst <= 0 {
+		return errors.New("ratelimit: burst must be positive")
+	}
+	return nil
+}
+
+// RetryAfter returns the duration needed to refill missing tokens.
+func (c TokenBucketConfig) RetryAfter(missing float64) time.Duration {
+	return time.Duration(math.Ceil(missing / c.Rate * float64(time.Second)))
+}
+
+// sweepInterval is the number of calls between two sweeps of the full buckets
+// in TokenBucket.
+const sweepInterval = 1024
+
+// TokenBucket is an in-memory token bucket Limiter.
+//
+// It keeps a separated bucket for each key.
+type TokenBucket struct {
+	cfg TokenBucketConfig
+	now func() time.Time
+
+	lock    sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a TokenBucket.
+func NewTokenBucket(cfg TokenBucketConfig) (*TokenBucket, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &TokenBucket{
+		cfg:     cfg,
+		now:     timebp.ClockOrDefault(cfg.Clock).Now,
+		buckets: make(map[string]*bucket),
+	}, nil
+}
+
+// AllowN implements Limiter.
+func (tb *TokenBucket) AllowN(_ context.Context, key string, n int64) (Result, error) {
+	tb.lock.Lock()
+	defer tb.lock.Unlock()
+
+	now := tb.now()
+	tb.calls++
+	if tb.calls >= sweepInterval {
+		tb.calls = 0
+		tb.sweep(now)
+	}
+
+	b := tb.buckets[key]
+	if b == nil {
+		b = &bucket{
+			tokens: float64(tb.cfg.Burst),
+			last:   now,
+		}
+		tb.buckets[key] = b
+	}
+	b.refill(now, tb.cfg)
+	if b.tokens < float64(n) {
+		return Result{
+			Remaining:  int64(b.tokens),
+			RetryAfter: tb.cfg.RetryAfter(float64(n) - b.tokens),
+		}, nil
+	}
+	b.tokens -= float64(n)
+	return Result{
+		Allowed:   true,
+		Remaining: int64(b.tokens),
+	}, nil
+}
+
+// sweep deletes the buckets that are full,
+// which is equivalent to not having them at all.
+//
+// It must be called with the lock held.
+func (tb *TokenBucket) sweep(now time.Time) {
+	for key, b := range tb.buckets {
+		b.refill(now, tb.cfg)
+		if b.tokens >= float64(tb.cfg.Burst) {
+			delete(tb.buckets, key)
+		}
+	}
+}
+
+func (b *bucket) refill(now time.Time, cfg TokenBucketConfig) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(float64(cfg.Burst), b.tokens+elapsed.Seconds()*cfg.Rate)
+		b.last = now
+	}
+}
+
+var _ Limiter = (*TokenBucket)(nil)
@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	tb, err := NewTokenBucket(TokenBucketConfig{
+		Rate:  2,
+		Burst: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	tb.now = func() time.Time {
+		return now
+	}
+	ctx := context.Background()
+
+	check := func(t *testing.T, key string, expected bool) Result {
+		t.Helper()
+		result, err := Allow(ctx, tb, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Allowed != expected {
+			t.Errorf("Expected allowed to be %v, got %+v", expected, result)
+		}
+		return result
+	}
+
+	check(t, "a", true)
+	check(t, "a", true)
+	result := check(t, "a", false)
+	if result.RetryAfter != time.Second/2 {
+		t.Errorf("Expected RetryAfter %v, got %v", time.Second/2, result.RetryAfter)
+	}
+	check(t, "b", true)
+
+	now = now.Add(time.Second / 2)
+	check(t, "a", true)
+	check(t, "a", false)
+
+	now = now.Add(time.Hour)
+	tb.sweep(now)
+	if len(tb.buckets) != 0 {
+		t.Errorf("Expected full buckets to be swept, got %v", tb.buckets)
+	}
+}
+
+func TestTokenBucketConfigValidate(t *testing.T) {
+	for _, cfg := range []TokenBucketConfig{
+		{},
+		{Rate: 1},
+		{Burst: 1},
+		{Rate: -1, Burst: 1},
+	} {
+		if _, err := NewTokenBucket(cfg); err == nil {
+			t.Errorf("Expected error for %+v, got nil", cfg)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+// SlidingWindowConfig is the config of the sliding window limiters.
+//
+// Can be deserialized from YAML.
+type SlidingWindowConfig struct {
+	// Limit is the max number of requests allowed in any Window.
+	Limit int64 `yaml:"limit"`
+
+	// Window is the length of the sliding window.
+	Window time.Duration `yaml:"window"`
+
+	// Optional. The Clock to get the current time from,
+	// see TokenBucketConfig.Clock.
+	Clock timebp.Clock `yaml:"-"`
+}
+
+// Validate checks SlidingWindowConfig for any erroneous values.
+func (c SlidingWindowConfig) Validate() error {
+	if c.Limit <= 0 {
+		return errors.New("ratelimit: limit must be positive")
+	}
+	if c.Window <= 0 {
+		return errors.New("ratelimit: window must be positive")
+	}
+	return nil
+}
+
+// SlidingWindowLog is an in-memory sliding window log Limiter.
+//
+// It records the time of every allowed request in the last Window for each
+// key, so it's exact, but uses O(Limit) memory per key.
+// Prefer SlidingWindowCounter for large limits.
+type SlidingWindowLog struct {
+	cfg SlidingWindowConfig
+	now func() time.Time
+
+	lock  sync.Mutex
+	logs  map[string]*windowLog
+	calls int
+}
+
+// windowLog is a ring buffer of the times of the allowed requests of a key,
+// oldest first.
+type windowLog struct {
+	times []time.Time
+	start int
+	size  int
+}
+
+// NewSlidingWindowLog creates a SlidingWindowLog.
+func NewSlidingWindowLog(cfg SlidingWindowConfig) (*SlidingWindowLog, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &SlidingWindowLog{
+		cfg:  cfg,
+		now:  timebp.ClockOrDefault(cfg.Clock).Now,
+		logs: make(map[string]*windowLog),
+	}, nil
+}
+
+// AllowN implements Limiter.
+func (l *SlidingWindowLog) AllowN(_ context.Context, key string, n int64) (Result, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	l.calls++
+	if l.calls >= sweepInterval {
+		l.calls = 0
+		l.sweep(now)
+	}
+
+	wl := l.logs[key]
+	if wl == nil {
+		wl = &windowLog{}
+		l.logs[key] = wl
+	}
+	wl.expire(now.Add(-l.cfg.Window))
+	remaining := l.cfg.Limit - int64(wl.size)
+	if n > remaining {
+		result := Result{
+			Remaining: remaining,
+		}
+		if n <= l.cfg.Limit {
+			// Wait until enough of the oldest requests leave the window.
+			result.RetryAfter = wl.at(int(n - remaining - 1)).Add(l.cfg.Window).Sub(now)
+		} else {
+			// Never allowed.
+			result.RetryAfter = l.cfg.Window
+		}
+		return result, nil
+	}
+	for i := int64(0); i < n; i++ {
+		wl.push(now, int(l.cfg.Limit))
+	}
+	return Result{
+		Allowed:   true,
+		Remaining: remaining - n,
+	}, nil
+}
+
+// sweep deletes the empty logs.
+//
+// It must be called with the lock held.
+func (l *SlidingWindowLog) sweep(now time.Time) {
+	cutoff := now.Add(-l.cfg.Window)
+	for key, wl := range l.logs {
+		wl.expire(cutoff)
+		if wl.size == 0 {
+			delete(l.logs, key)
+		}
+	}
+}
+
+func (wl *windowLog) at(i int) time.Time {
+	return wl.times[(wl.start+i)%len(wl.times)]
+}
+
+// expire removes the requests at or before cutoff.
+func (wl *windowLog) expire(cutoff time.Time) {
+	for wl.size > 0 && !wl.at(0).After(cutoff) {
+		wl.start = (wl.start + 1) % len(wl.times)
+		wl.size--
+	}
+}
+
+func (wl *windowLog) push(t time.Time, limit int) {
+	if wl.size == len(wl.times) {
+		// Grow the buffer lazily, up to limit.
+		capacity := len(wl.times) * 2
+		if capacity == 0 {
+			capacity = 1
+		}
+		if capacity > limit {
+			capacity = limit
+		}
+		times := make([]time.Time, capacity)
+		for i := 0; i < wl.size; i++ {
+			times[i] = wl.at(i)
+		}
+		wl.times = times
+		wl.start = 0
+	}
+	wl.times[(wl.start+wl.size)%len(wl.times)] = t
+	wl.size++
+}
+
+// SlidingWindowCounter is an in-memory sliding window counter Limiter.
+//
+// It only keeps the counts of the current and the previous fixed windows for
+// each key, and approximates the count of the sliding window by weighting the
+// previous count by its overlap with the sliding window.
+// It uses O(1) memory per key, but assumes the requests in the previous window
+// were evenly distributed, so it can be off when they were not.
+type SlidingWindowCounter struct {
+	cfg SlidingWindowConfig
+	now func() time.Time
+
+	lock     sync.Mutex
+	counters map[string]*windowCounter
+	calls    int
+}
+
+type windowCounter struct {
+	// The start of the current fixed window.
+	start    time.Time
+	current  int64
+	previous int64
+}
+
+// NewSlidingWindowCounter creates a SlidingWindowCounter.
+func NewSlidingWindowCounter(cfg SlidingWindowConfig) (*SlidingWindowCounter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &SlidingWindowCounter{
+		cfg:      cfg,
+		now:      timebp.ClockOrDefault(cfg.Clock).Now,
+		counters: make(map[string]*windowCounter),
+	}, nil
+}
+
+// AllowN implements Limiter.
+func (l *SlidingWindowCounter) AllowN(_ context.Context, key string, n int64) (Result, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	l.calls++
+	if l.calls >= sweepInterval {
+		l.calls = 0
+		l.sweep(now)
+	}
+
+	c := l.counters[key]
+	if c == nil {
+		c = &windowCounter{
+			start: now.Truncate(l.cfg.Window),
+		}
+		l.counters[key] = c
+	}
+	c.advance(now, l.cfg.Window)
+
+	// The fraction of the previous window still in the sliding window.
+	weight := 1 - float64(now.Sub(c.start))/float64(l.cfg.Window)
+	count := float64(c.previous)*weight + float64(c.current)
+	remaining := l.cfg.Limit - int64(count)
+	if float64(n) > float64(l.cfg.Limit)-count {
+		result := Result{
+			Remaining: remaining,
+		}
+		if remaining < 0 {
+			result.Remaining = 0
+		}
+		excess := count + float64(n) - float64(l.cfg.Limit)
+		if c.previous > 0 && excess <= float64(c.previous)*weight {
+			// Wait until enough of the previous window slides out.
+			result.RetryAfter = time.Duration(excess / float64(c.previous) * float64(l.cfg.Window))
+		} else {
+			// Wait until the next fixed window.
+			result.RetryAfter = c.start.Add(l.cfg.Window).Sub(now)
+		}
+		return result, nil
+	}
+	c.current += n
+	return Result{
+		Allowed:   true,
+		Remaining: l.cfg.Limit - int64(count) - n,
+	}, nil
+}
+
+// sweep deletes the counters that are out of the sliding window.
+//
+// It must be called with the lock held.
+func (l *SlidingWindowCounter) sweep(now time.Time) {
+	for key, c := range l.counters {
+		c.advance(now, l.cfg.Window)
+		if c.current == 0 && c.previous == 0 {
+			delete(l.counters, key)
+		}
+	}
+}
+
+// advance moves the fixed windows of c forward to the one containing now.
+func (c *windowCounter) advance(now time.Time, window time.Duration) {
+	elapsed := now.Sub(c.start)
+	if elapsed < window {
+		return
+	}
+	if elapsed < 2*window {
+		c.previous = c.current
+	} else {
+		c.previous = 0
+	}
+	c.current = 0
+	c.start = now.Truncate(window)
+}
+
+var (
+	_ Limiter = (*SlidingWindowLog)(nil)
+	_ Limiter = (*SlidingWindowCounter)(nil)
+)
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func checkAllow(t *testing.T, limiter Limiter, key string, n int64, expected bool) Result {
+	t.Helper()
+	result, err := limiter.AllowN(context.Background(), key, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed != expected {
+		t.Errorf("Expected allowed to be %v, got %+v", expected, result)
+	}
+	return result
+}
+
+func TestSlidingWindowLog(t *testing.T) {
+	l, err := NewSlidingWindowLog(SlidingWindowConfig{
+		Limit:  3,
+		Window: time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	l.now = func() time.Time {
+		return now
+	}
+
+	checkAllow(t, l, "a", 1, true)
+	now = now.Add(time.Second / 2)
+	checkAllow(t, l, "a", 2, true)
+	result := checkAllow(t, l, "a", 1, false)
+	if result.RetryAfter != time.Second/2 {
+		t.Errorf("Expected RetryAfter %v, got %v", time.Second/2, result.RetryAfter)
+	}
+	result = checkAllow(t, l, "a", 2, false)
+	if result.RetryAfter != time.Second {
+		t.Errorf("Expected RetryAfter %v, got %v", time.Second, result.RetryAfter)
+	}
+	checkAllow(t, l, "a", 4, false)
+	checkAllow(t, l, "b", 3, true)
+
+	// The first request leaves the window.
+	now = now.Add(time.Second / 2)
+	result = checkAllow(t, l, "a", 1, true)
+	if result.Remaining != 0 {
+		t.Errorf("Expected Remaining 0, got %d", result.Remaining)
+	}
+	checkAllow(t, l, "a", 1, false)
+
+	now = now.Add(time.Hour)
+	l.sweep(now)
+	if len(l.logs) != 0 {
+		t.Errorf("Expected empty logs to be swept, got %v", l.logs)
+	}
+}
+
+func TestSlidingWindowCounter(t *testing.T) {
+	l, err := NewSlidingWindowCounter(SlidingWindowConfig{
+		Limit:  10,
+		Window: time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Truncate(time.Second)
+	l.now = func() time.Time {
+		return now
+	}
+
+	checkAllow(t, l, "a", 10, true)
+	result := checkAllow(t, l, "a", 1, false)
+	if result.RetryAfter != time.Second {
+		t.Errorf("Expected RetryAfter %v, got %v", time.Second, result.RetryAfter)
+	}
+
+	// 75% into the next window, 25% of the previous count (2.5) is still in
+	// the sliding window.
+	now = now.Add(time.Second * 7 / 4)
+	result = checkAllow(t, l, "a", 7, true)
+	if result.Remaining != 1 {
+		t.Errorf("Expected Remaining 1, got %d", result.Remaining)
+	}
+	result = checkAllow(t, l, "a", 1, false)
+	// Another 5% of the previous window (0.5) needs to slide out.
+	if expected := time.Second / 20; result.RetryAfter != expected {
+		t.Errorf("Expected RetryAfter %v, got %v", expected, result.RetryAfter)
+	}
+	now = now.Add(time.Second / 20)
+	checkAllow(t, l, "a", 1, true)
+	checkAllow(t, l, "b", 10, true)
+
+	now = now.Add(time.Hour)
+	l.sweep(now)
+	if len(l.counters) != 0 {
+		t.Errorf("Expected empty counters to be swept, got %v", l.counters)
+	}
+}
+
+func TestSlidingWindowConfigValidate(t *testing.T) {
+	for _, cfg := range []SlidingWindowConfig{
+		{},
+		{Limit: 1},
+		{Window: time.Second},
+		{Limit: -1, Window: time.Second},
+	} {
+		if _, err := NewSlidingWindowLog(cfg); err == nil {
+			t.Errorf("Expected error for %+v, got nil", cfg)
+		}
+		if _, err := NewSlidingWindowCounter(cfg); err == nil {
+			t.Errorf("Expected error for %+v, got nil", cfg)
+		}
+	}
+}
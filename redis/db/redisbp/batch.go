@@ -0,0 +1,163 @@
+package redisbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-redis/redis/v8"
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// DefaultMaxBatchSize is the default value of BatcherConfig.MaxBatchSize.
+const DefaultMaxBatchSize = 1000
+
+// ErrBatchTooLarge is the error returned by Batcher when the number of
+// commands exceeds BatcherConfig.MaxBatchSize.
+var ErrBatchTooLarge = errors.New("redisbp: batch too large")
+
+// Tags set on the batch spans by Batcher.
+const (
+	BatchSizeTag   = "redis.batch.size"
+	BatchChunksTag = "redis.batch.chunks"
+)
+
+// BatcherConfig is the config of a Batcher.
+//
+// Can be deserialized from YAML.
+type BatcherConfig struct {
+	// MaxBatchSize is the max number of commands in a single pipeline or
+	// transaction.
+	//
+	// Optional, default to DefaultMaxBatchSize.
+	MaxBatchSize int `yaml:"maxBatchSize"`
+}
+
+// Batcher executes commands in pipelines and transactions,
+// with the number of commands in each of them bounded by
+// BatcherConfig.MaxBatchSize.
+//
+// When the client is created by NewMonitored*Client,
+// each pipeline or transaction produces a single client span,
+// annotated with the commands in it by SpanHook.
+//
+// The following metrics are reported with "name" tag:
+//
+// - "redis.batch.size": histogram of the number of commands in each pipeline or
+// transaction.
+//
+// - "redis.batch.rejected": counter of the batches rejected by ErrBatchTooLarge.
+type Batcher struct {
+	client redis.Cmdable
+	name   string
+	cfg    BatcherConfig
+
+	size     metrics.Histogram
+	rejected metrics.Counter
+}
+
+// NewBatcher creates a Batcher.
+//
+// name is used in the span names and metrics tags,
+// usually the same as the name of the client.
+func NewBatcher(client redis.Cmdable, name string, cfg BatcherConfig) *Batcher {
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = DefaultMaxBatchSize
+	}
+	tags := []string{"name", name}
+	return &Batcher{
+		client: client,
+		name:   name,
+		cfg:    cfg,
+
+		size:     metricsbp.M.Histogram("redis.batch.size").With(tags...),
+		rejected: metricsbp.M.Counter("redis.batch.rejected").With(tags...),
+	}
+}
+
+// Pipelined executes cmds in a single pipeline.
+//
+// It returns ErrBatchTooLarge without executing any of the commands when there
+// are more than MaxBatchSize commands.
+// Otherwise it returns the first failed command's error (including redis.Nil),
+// if any, and the result of each command can be read from cmds.
+//
+// The commands can be created via the redis.New*Cmd functions, e.g.:
+//
+//	get := redis.NewStringCmd(ctx, "get", "key")
+//	incr := redis.NewIntCmd(ctx, "incr", "counter")
+//	err := batcher.Pipelined(ctx, get, incr)
+func (b *Batcher) Pipelined(ctx context.Context, cmds ...redis.Cmder) error {
+	if err := b.checkSize(cmds); err != nil {
+		return err
+	}
+	return b.exec(ctx, b.client.Pipeline(), cmds)
+}
+
+// TxPipelined executes cmds in a single transaction (MULTI/EXEC).
+//
+// It has the same size limit as Pipelined.
+func (b *Batcher) TxPipelined(ctx context.Context, cmds ...redis.Cmder) error {
+	if err := b.checkSize(cmds); err != nil {
+		return err
+	}
+	return b.exec(ctx, b.client.TxPipeline(), cmds)
+}
+
+// PipelinedChunks executes cmds in as many pipelines as needed,
+// each of them containing at most MaxBatchSize commands.
+//
+// The pipelines are executed sequentially in a local span with the total
+// number of commands ("redis.batch.size" tag) and pipelines
+// ("redis.batch.chunks" tag) annotated.
+// It stops at the first failed pipeline and returns its error.
+func (b *Batcher) PipelinedChunks(ctx context.Context, cmds ...redis.Cmder) (err error) {
+	chunks := (len(cmds) + b.cfg.MaxBatchSize - 1) / b.cfg.MaxBatchSize
+	span, ctx := opentracing.StartSpanFromContext(ctx, b.name+".batch")
+	span.SetTag(BatchSizeTag, len(cmds))
+	span.SetTag(BatchChunksTag, chunks)
+	defer func() {
+		span.FinishWithOptions(tracing.FinishOptions{
+			Ctx: ctx,
+			Err: err,
+		}.Convert())
+	}()
+
+	for start := 0; start < len(cmds); start += b.cfg.MaxBatchSize {
+		end := start + b.cfg.MaxBatchSize
+		if end > len(cmds) {
+			end = len(cmds)
+		}
+		if err := b.exec(ctx, b.client.Pipeline(), cmds[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Batcher) checkSize(cmds []redis.Cmder) error {
+	if len(cmds) > b.cfg.MaxBatchSize {
+		b.rejected.Add(1)
+		return fmt.Errorf("%w: %d > %d", ErrBatchTooLarge, len(cmds), b.cfg.MaxBatchSize)
+	}
+	return nil
+}
+
+func (b *Batcher) exec(ctx context.Context, pipe redis.Pipeliner, cmds []redis.Cmder) error {
+	if len(cmds) == 0 {
+		return nil
+	}
+	for _, cmd := range cmds {
+		if err := pipe.Process(ctx, cmd); err != nil {
+			pipe.Discard()
+			return err
+		}
+	}
+	b.size.Observe(float64(len(cmds)))
+	_, err := pipe.Exec(ctx)
+	return err
+}
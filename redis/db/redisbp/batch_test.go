@@ -0,0 +1,81 @@
+package redisbp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/reddit/baseplate.go/redis/db/redisbp"
+)
+
+func TestBatcher(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	client := redisbp.NewMonitoredClient("redis", &redis.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	batcher := redisbp.NewBatcher(client, "redis", redisbp.BatcherConfig{
+		MaxBatchSize: 2,
+	})
+	ctx := context.Background()
+
+	t.Run("pipelined", func(t *testing.T) {
+		set := redis.NewStatusCmd(ctx, "set", "a", "1")
+		incr := redis.NewIntCmd(ctx, "incr", "a")
+		if err := batcher.Pipelined(ctx, set, incr); err != nil {
+			t.Fatal(err)
+		}
+		if incr.Val() != 2 {
+			t.Errorf("Expected incr to return 2, got %d", incr.Val())
+		}
+	})
+
+	t.Run("tx-pipelined", func(t *testing.T) {
+		incr := redis.NewIntCmd(ctx, "incr", "a")
+		get := redis.NewStringCmd(ctx, "get", "a")
+		if err := batcher.TxPipelined(ctx, incr, get); err != nil {
+			t.Fatal(err)
+		}
+		if get.Val() != "3" {
+			t.Errorf("Expected get to return %q, got %q", "3", get.Val())
+		}
+	})
+
+	t.Run("too-large", func(t *testing.T) {
+		cmds := []redis.Cmder{
+			redis.NewIntCmd(ctx, "incr", "b"),
+			redis.NewIntCmd(ctx, "incr", "b"),
+			redis.NewIntCmd(ctx, "incr", "b"),
+		}
+		if err := batcher.Pipelined(ctx, cmds...); !errors.Is(err, redisbp.ErrBatchTooLarge) {
+			t.Errorf("Expected ErrBatchTooLarge, got %v", err)
+		}
+		if err := batcher.TxPipelined(ctx, cmds...); !errors.Is(err, redisbp.ErrBatchTooLarge) {
+			t.Errorf("Expected ErrBatchTooLarge, got %v", err)
+		}
+		if s.Exists("b") {
+			t.Error("Expected no commands executed")
+		}
+	})
+
+	t.Run("chunks", func(t *testing.T) {
+		cmds := make([]redis.Cmder, 5)
+		for i := range cmds {
+			cmds[i] = redis.NewIntCmd(ctx, "incr", "c")
+		}
+		if err := batcher.PipelinedChunks(ctx, cmds...); err != nil {
+			t.Fatal(err)
+		}
+		for i, cmd := range cmds {
+			if got := cmd.(*redis.IntCmd).Val(); got != int64(i+1) {
+				t.Errorf("#%d: Expected %d, got %d", i, i+1, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,280 @@
+package redisbp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/randbp"
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+// DefaultCacheTTL is the default value of CacheConfig.TTL.
+const DefaultCacheTTL = time.Minute * 5
+
+// ErrCacheNotFound is the error Loaders return when the value does not exist.
+//
+// When negative caching is enabled,
+// it's cached for CacheConfig.NegativeTTL,
+// and Cache.Get returns it without calling the Loader again until then.
+var ErrCacheNotFound = errors.New("redisbp: cache value not found")
+
+// CacheConfig is the config of a Cache.
+//
+// Can be deserialized from YAML.
+type CacheConfig struct {
+	// TTL is the expiry of the cached values.
+	//
+	// Optional, default to DefaultCacheTTL.
+	TTL time.Duration `yaml:"ttl"`
+
+	// Jitter is applied to TTL (see randbp.JitterDuration) so that the values
+	// cached at the same time don't all expire at the same time.
+	//
+	// Optional, default to 0 (no jitter).
+	Jitter float64 `yaml:"jitter"`
+
+	// NegativeTTL is the expiry of ErrCacheNotFound returned by the Loaders.
+	//
+	// Optional, default to 0 (negative caching disabled).
+	NegativeTTL time.Duration `yaml:"negativeTTL"`
+
+	// StampedeProtection enables probabilistic early refresh of the cached
+	// values.
+	//
+	// When enabled, a Get could refresh the value before it expires,
+	// with higher probability when it's closer to the expiry or it took longer
+	// to load.
+	// This spreads out the loads across replicas instead of having all of them
+	// to load the same value when it expires.
+	StampedeProtection bool `yaml:"stampedeProtection"`
+
+	// KeyPrefix is prepended to the keys passed into Get.
+	//
+	// Optional.
+	KeyPrefix string `yaml:"keyPrefix"`
+
+	// Clock is used to decide whether the cached values expired and to time the
+	// Loaders.
+	//
+	// Optional, defaults to timebp.SystemClock.
+	Clock timebp.Clock `yaml:"-"`
+}
+
+// Loader loads the value when it's not in the cache.
+//
+// It should return ErrCacheNotFound (or an error wrapping it) when the value
+// does not exist.
+type Loader func(ctx context.Context) ([]byte, error)
+
+// Cache is a read-through cache backed by Redis.
+//
+// Concurrent misses of the same key inside the same process are collapsed into
+// a single call to the Loader.
+//
+// The following metrics are reported with "name" tag:
+//
+// - "redis.cache.hit": counter of the Gets served from the cache.
+//
+// - "redis.cache.miss": counter of the Gets that called the Loader.
+//
+// - "redis.cache.get": timing of the Gets.
+//
+// - "redis.cache.load": timing of the Loader calls.
+type Cache struct {
+	client redis.Cmdable
+	cfg    CacheConfig
+	clock  timebp.Clock
+
+	group singleflight.Group
+
+	hit  metrics.Counter
+	miss metrics.Counter
+	get  metrics.Histogram
+	load metrics.Histogram
+}
+
+// NewCache creates a Cache.
+//
+// name is used in the metrics tags,
+// usually the same as the name of the client.
+func NewCache(client redis.Cmdable, name string, cfg CacheConfig) *Cache {
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultCacheTTL
+	}
+	tags := []string{"name", name}
+	return &Cache{
+		client: client,
+		cfg:    cfg,
+		clock:  timebp.ClockOrDefault(cfg.Clock),
+
+		hit:  metricsbp.M.Counter("redis.cache.hit").With(tags...),
+		miss: metricsbp.M.Counter("redis.cache.miss").With(tags...),
+		get:  metricsbp.M.Timing("redis.cache.get").With(tags...),
+		load: metricsbp.M.Timing("redis.cache.load").With(tags...),
+	}
+}
+
+// Get returns the value of key from the cache,
+// or calls load to load it and stores it into the cache when it's not there.
+//
+// Redis errors are logged and treated as misses,
+// so the values are still loaded when Redis is unavailable.
+//
+// When there are concurrent misses of the same key,
+// only one of them calls load with its ctx,
+// and the others wait for its result until their own ctx is done.
+// A panic in load is returned as an error to all of them.
+func (c *Cache) Get(ctx context.Context, key string, load Loader) ([]byte, error) {
+	timer := metricsbp.NewTimer(c.get)
+	defer timer.ObserveDuration()
+
+	key = c.cfg.KeyPrefix + key
+	data, err := c.client.Get(ctx, key).Bytes()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.C(ctx).Warnw(
+			"redisbp.Cache: failed to get from redis",
+			"err", err,
+			"key", key,
+		)
+	}
+	if err == nil {
+		entry, decodeErr := decodeCacheEntry(data)
+		if decodeErr != nil {
+			log.C(ctx).Warnw(
+				"redisbp.Cache: failed to decode cached value",
+				"err", decodeErr,
+				"key", key,
+			)
+		} else if !c.shouldRefresh(entry, c.clock.Now()) {
+			c.hit.Add(1)
+			if entry.notFound {
+				return nil, ErrCacheNotFound
+			}
+			return entry.value, nil
+		}
+	}
+
+	c.miss.Add(1)
+	ch := c.group.DoChan(key, func() (_ interface{}, err error) {
+		defer func() {
+			// DoChan crashes the process on panics.
+			if r := recover(); r != nil {
+				err = fmt.Errorf("redisbp.Cache: loader panicked: %v", r)
+			}
+		}()
+		return c.loadAndSet(ctx, key, load)
+	})
+	select {
+	case result := <-ch:
+		value, _ := result.Val.([]byte)
+		return value, result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Delete deletes key from the cache.
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	if err := c.client.Del(ctx, c.cfg.KeyPrefix+key).Err(); err != nil {
+		return fmt.Errorf("redisbp.Cache: failed to delete %q: %w", key, err)
+	}
+	return nil
+}
+
+func (c *Cache) loadAndSet(ctx context.Context, key string, load Loader) ([]byte, error) {
+	start := c.clock.Now()
+	value, err := load(ctx)
+	delta := c.clock.Since(start)
+	c.load.Observe(delta.Seconds())
+
+	entry := cacheEntry{
+		value: value,
+		delta: delta,
+	}
+	ttl := randbp.JitterDuration(c.cfg.TTL, c.cfg.Jitter)
+	if err != nil {
+		if !errors.Is(err, ErrCacheNotFound) || c.cfg.NegativeTTL <= 0 {
+			return nil, err
+		}
+		entry.value = nil
+		entry.notFound = true
+		ttl = c.cfg.NegativeTTL
+	}
+	entry.expiry = start.Add(delta).Add(ttl)
+
+	if setErr := c.client.Set(ctx, key, entry.encode(), ttl).Err(); setErr != nil {
+		log.C(ctx).Warnw(
+			"redisbp.Cache: failed to set to redis",
+			"err", setErr,
+			"key", key,
+		)
+	}
+	return value, err
+}
+
+// shouldRefresh implements the probabilistic early expiration from
+// "Optimal Probabilistic Cache Stampede Prevention" (XFetch).
+func (c *Cache) shouldRefresh(entry cacheEntry, now time.Time) bool {
+	if !c.cfg.StampedeProtection || entry.notFound {
+		return false
+	}
+	early := time.Duration(-float64(entry.delta) * math.Log(1-randbp.R.Float64()))
+	return !now.Add(early).Before(entry.expiry)
+}
+
+const (
+	cacheEntryValue    byte = 'v'
+	cacheEntryNotFound byte = 'n'
+
+	// 1 byte of kind, 8 bytes of expiry and 4 bytes of delta.
+	cacheEntryHeaderSize = 1 + 8 + 4
+)
+
+// cacheEntry is the value stored in Redis by Cache.
+type cacheEntry struct {
+	notFound bool
+	expiry   time.Time
+	// delta is the time it took to load the value.
+	delta time.Duration
+	value []byte
+}
+
+func (e cacheEntry) encode() []byte {
+	data := make([]byte, cacheEntryHeaderSize+len(e.value))
+	data[0] = cacheEntryValue
+	if e.notFound {
+		data[0] = cacheEntryNotFound
+	}
+	binary.BigEndian.PutUint64(data[1:9], uint64(e.expiry.UnixNano()/int64(time.Millisecond)))
+	binary.BigEndian.PutUint32(data[9:13], uint32(e.delta.Milliseconds()))
+	copy(data[cacheEntryHeaderSize:], e.value)
+	return data
+}
+
+func decodeCacheEntry(data []byte) (cacheEntry, error) {
+	if len(data) < cacheEntryHeaderSize {
+		return cacheEntry{}, fmt.Errorf("redisbp: cache entry too short: %d", len(data))
+	}
+	var e cacheEntry
+	switch data[0] {
+	default:
+		return cacheEntry{}, fmt.Errorf("redisbp: unknown cache entry kind %q", data[0])
+	case cacheEntryValue:
+	case cacheEntryNotFound:
+		e.notFound = true
+	}
+	e.expiry = time.Unix(0, int64(binary.BigEndian.Uint64(data[1:9]))*int64(time.Millisecond))
+	e.delta = time.Duration(binary.BigEndian.Uint32(data[9:13])) * time.Millisecond
+	e.value = data[cacheEntryHeaderSize:]
+	return e, nil
+}
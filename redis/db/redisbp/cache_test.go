@@ -0,0 +1,226 @@
+package redisbp_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/reddit/baseplate.go/redis/db/redisbp"
+)
+
+func setupCache(t *testing.T, cfg redisbp.CacheConfig) (*miniredis.Miniredis, *redisbp.Cache) {
+	t.Helper()
+
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return s, redisbp.NewCache(client, "redis", cfg)
+}
+
+func TestCache(t *testing.T) {
+	s, cache := setupCache(t, redisbp.CacheConfig{
+		TTL:       time.Minute,
+		Jitter:    0.1,
+		KeyPrefix: "cache:",
+	})
+	ctx := context.Background()
+
+	var loads int
+	load := func(ctx context.Context) ([]byte, error) {
+		loads++
+		return []byte("value"), nil
+	}
+	for i := 0; i < 3; i++ {
+		value, err := cache.Get(ctx, "key", load)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(value) != "value" {
+			t.Errorf("Expected %q, got %q", "value", value)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("Expected 1 load, got %d", loads)
+	}
+	ttl := s.TTL("cache:key")
+	if ttl < time.Second*54 || ttl > time.Second*66 {
+		t.Errorf("Expected TTL of 1m +/- 10%%, got %v", ttl)
+	}
+
+	s.FastForward(time.Minute * 2)
+	if _, err := cache.Get(ctx, "key", load); err != nil {
+		t.Fatal(err)
+	}
+	if loads != 2 {
+		t.Errorf("Expected 2 loads after expiry, got %d", loads)
+	}
+
+	if err := cache.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Get(ctx, "key", load); err != nil {
+		t.Fatal(err)
+	}
+	if loads != 3 {
+		t.Errorf("Expected 3 loads after delete, got %d", loads)
+	}
+
+	loadErr := errors.New("load failed")
+	if _, err := cache.Get(ctx, "error", func(context.Context) ([]byte, error) {
+		return nil, loadErr
+	}); !errors.Is(err, loadErr) {
+		t.Errorf("Expected %v, got %v", loadErr, err)
+	}
+	if s.Exists("cache:error") {
+		t.Error("Expected errors not cached")
+	}
+}
+
+func TestCacheNegative(t *testing.T) {
+	s, cache := setupCache(t, redisbp.CacheConfig{
+		NegativeTTL: time.Second,
+	})
+	ctx := context.Background()
+
+	var loads int
+	load := func(ctx context.Context) ([]byte, error) {
+		loads++
+		return nil, redisbp.ErrCacheNotFound
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Get(ctx, "key", load); !errors.Is(err, redisbp.ErrCacheNotFound) {
+			t.Errorf("Expected ErrCacheNotFound, got %v", err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("Expected 1 load, got %d", loads)
+	}
+	if ttl := s.TTL("key"); ttl != time.Second {
+		t.Errorf("Expected TTL %v, got %v", time.Second, ttl)
+	}
+}
+
+func TestCacheSingleflight(t *testing.T) {
+	_, cache := setupCache(t, redisbp.CacheConfig{})
+	ctx := context.Background()
+
+	var loads int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return []byte("value"), nil
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			value, err := cache.Get(ctx, "key", load)
+			if err != nil {
+				t.Error(err)
+			}
+			if string(value) != "value" {
+				t.Errorf("Expected %q, got %q", "value", value)
+			}
+		}()
+	}
+	// Give all the goroutines a chance to miss before releasing the load.
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	wg.Wait()
+	if loads != 1 {
+		t.Errorf("Expected 1 load, got %d", loads)
+	}
+}
+
+func TestCacheSingleflightWaiterContext(t *testing.T) {
+	_, cache := setupCache(t, redisbp.CacheConfig{})
+
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	go cache.Get(context.Background(), "key", func(ctx context.Context) ([]byte, error) {
+		close(loading)
+		<-release
+		return []byte("value"), nil
+	})
+	<-loading
+
+	// The waiter returns when its own ctx is done,
+	// without waiting for the load in progress.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if _, err := cache.Get(ctx, "key", func(ctx context.Context) ([]byte, error) {
+		t.Error("Expected the load in progress to be shared")
+		return nil, nil
+	}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestCacheLoaderPanic(t *testing.T) {
+	_, cache := setupCache(t, redisbp.CacheConfig{})
+
+	value, err := cache.Get(context.Background(), "key", func(ctx context.Context) ([]byte, error) {
+		panic("oops")
+	})
+	if err == nil {
+		t.Errorf("Expected error from the panicked loader, got %q", value)
+	}
+}
+
+func TestCacheStampedeProtection(t *testing.T) {
+	_, cache := setupCache(t, redisbp.CacheConfig{
+		TTL:                time.Millisecond,
+		StampedeProtection: true,
+	})
+	ctx := context.Background()
+
+	var loads int
+	load := func(ctx context.Context) ([]byte, error) {
+		loads++
+		return []byte("value"), nil
+	}
+	if _, err := cache.Get(ctx, "key", load); err != nil {
+		t.Fatal(err)
+	}
+	// miniredis only expires keys on FastForward, so the key is still there,
+	// but it's past the expiry recorded in the entry.
+	time.Sleep(time.Millisecond * 5)
+	if _, err := cache.Get(ctx, "key", load); err != nil {
+		t.Fatal(err)
+	}
+	if loads != 2 {
+		t.Errorf("Expected the value to be refreshed, got %d loads", loads)
+	}
+}
+
+func TestCacheRedisDown(t *testing.T) {
+	s, cache := setupCache(t, redisbp.CacheConfig{})
+	s.Close()
+
+	value, err := cache.Get(context.Background(), "key", func(ctx context.Context) ([]byte, error) {
+		return []byte("value"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(value) != "value" {
+		t.Errorf("Expected %q, got %q", "value", value)
+	}
+}
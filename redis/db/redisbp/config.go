@@ -38,6 +38,8 @@ import (
 //	  dial: 1s
 //	  read: 100ms
 //	  write: 200ms
+//	 secrets:
+//	  credentials: secret/myservice/redis-credentials
 type ClientConfig struct {
 	// URL is passed to redis.ParseURL to initialize the client options.  This is
 	// a required field.
@@ -48,6 +50,10 @@ type ClientConfig struct {
 	Pool     PoolOptions    `yaml:"pool"`
 	Retries  RetryOptions   `yaml:"retries"`
 	Timeouts TimeoutOptions `yaml:"timeouts"`
+
+	// Secrets configures the credentials and TLS material to be read from the
+	// secrets Store, they are only used by the *WithSecrets constructors.
+	Secrets SecretsConfig `yaml:"secrets"`
 }
 
 // Options returns a redis.Options populated using the values from cfg.
@@ -98,6 +104,8 @@ func (cfg ClientConfig) Options() (*redis.Options, error) {
 //	  dial: 1s
 //	  read: 100ms
 //	  write: 200ms
+//	 secrets:
+//	  credentials: secret/myservice/redis-credentials
 type ClusterConfig struct {
 	// Addrs is the seed list of cluster nodes in the format "host:port". This is
 	// a required field.
@@ -108,6 +116,10 @@ type ClusterConfig struct {
 	Pool     PoolOptions    `yaml:"pool"`
 	Retries  RetryOptions   `yaml:"retries"`
 	Timeouts TimeoutOptions `yaml:"timeouts"`
+
+	// Secrets configures the credentials and TLS material to be read from the
+	// secrets Store, they are only used by the *WithSecrets constructors.
+	Secrets SecretsConfig `yaml:"secrets"`
 }
 
 // Options returns a redis.ClusterOptions populated using the values from cfg.
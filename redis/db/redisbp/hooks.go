@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/opentracing/opentracing-go"
@@ -12,6 +14,12 @@ import (
 	"github.com/reddit/baseplate.go/tracing"
 )
 
+// Tags set on the pipeline spans by SpanHook.
+const (
+	PipelineSizeTag     = "redis.pipeline.size"
+	PipelineCommandsTag = "redis.pipeline.commands"
+)
+
 // SpanHook is a redis.Hook for wrapping Redis commands and pipelines
 // in Client Spans and metrics.
 type SpanHook struct {
@@ -40,8 +48,17 @@ func (h SpanHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
 
 // BeforeProcessPipeline starts a client span before processing a Redis pipeline
 // and starts a timer to record how long the pipeline took.
+//
+// The span is annotated with the size of the pipeline ("redis.pipeline.size"
+// tag) and the number of each command in it ("redis.pipeline.commands" tag,
+// e.g. "get:2,set:1").
 func (h SpanHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
-	return h.startChildSpan(ctx, "pipeline"), nil
+	ctx = h.startChildSpan(ctx, "pipeline")
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span.SetTag(PipelineSizeTag, len(cmds))
+		span.SetTag(PipelineCommandsTag, summarizeCommands(cmds))
+	}
+	return ctx, nil
 }
 
 // AfterProcessPipeline ends the client span started by BeforeProcessPipeline,
@@ -60,6 +77,30 @@ func (h SpanHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder)
 	return nil
 }
 
+// summarizeCommands returns the number of each command in cmds,
+// in the order of their first appearance.
+func summarizeCommands(cmds []redis.Cmder) string {
+	var names []string
+	counts := make(map[string]int)
+	for _, cmd := range cmds {
+		name := cmd.Name()
+		if counts[name] == 0 {
+			names = append(names, name)
+		}
+		counts[name]++
+	}
+	var sb strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(name)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(counts[name]))
+	}
+	return sb.String()
+}
+
 func (h SpanHook) startChildSpan(ctx context.Context, cmdName string) context.Context {
 	name := fmt.Sprintf("%s.%s", h.ClientName, cmdName)
 	_, ctx = opentracing.StartSpanFromContext(
@@ -13,7 +13,9 @@ import (
 )
 
 func TestSpanHook(t *testing.T) {
-	ctx, _ := thriftbp.StartSpanFromThriftContext(context.Background(), "foo")
+	ctx, serverSpan := thriftbp.StartSpanFromThriftContext(context.Background(), "foo")
+	recorder := &tagsRecorder{tags: make(map[opentracing.Span]map[string]interface{})}
+	serverSpan.AddHooks(recorder)
 	hooks := redisbp.SpanHook{ClientName: "redis"}
 	statusCmd := redis.NewStatusCmd(ctx, "ping")
 	stringCmd := redis.NewStringCmd(ctx, "get", "1")
@@ -55,6 +57,10 @@ func TestSpanHook(t *testing.T) {
 			if name := tracing.AsSpan(activeSpan).Name(); name != "redis.pipeline" {
 				t.Fatalf("Incorrect span name %q", name)
 			}
+			if tags := recorder.tags[activeSpan]; tags[redisbp.PipelineSizeTag] != 2 ||
+				tags[redisbp.PipelineCommandsTag] != "ping:1,get:1" {
+				t.Errorf("Unexpected pipeline span tags: %v", tags)
+			}
 
 			if err = hooks.AfterProcessPipeline(ctx, cmds); err != nil {
 				t.Fatalf("Unexpected error: %s", err)
@@ -62,3 +68,20 @@ func TestSpanHook(t *testing.T) {
 		},
 	)
 }
+
+type tagsRecorder struct {
+	tags map[opentracing.Span]map[string]interface{}
+}
+
+func (r *tagsRecorder) OnCreateChild(parent, child *tracing.Span) error {
+	child.AddHooks(r)
+	return nil
+}
+
+func (r *tagsRecorder) OnSetTag(span *tracing.Span, key string, value interface{}) error {
+	if r.tags[span] == nil {
+		r.tags[span] = make(map[string]interface{})
+	}
+	r.tags[span][key] = value
+	return nil
+}
@@ -0,0 +1,334 @@
+package redisbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-redis/redis/v8"
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+	"github.com/reddit/baseplate.go/randbp"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// Default values for LockerConfig.
+const (
+	DefaultLockTTL           = time.Second * 30
+	DefaultLockRetryInterval = time.Millisecond * 50
+)
+
+// ErrLockNotAcquired is the error returned by Locker.TryLock when the lock is
+// held by someone else.
+var ErrLockNotAcquired = errors.New("redisbp: lock not acquired")
+
+// ErrLockNotHeld is the error returned by Lock.Unlock and Lock.Refresh when the
+// lock is no longer held, usually because it's expired.
+var ErrLockNotHeld = errors.New("redisbp: lock not held")
+
+// LockerConfig is the config of a Locker.
+//
+// Can be deserialized from YAML.
+type LockerConfig struct {
+	// TTL is the expiry of the locks,
+	// so that the locks held by crashed processes are released eventually.
+	//
+	// Optional, default to DefaultLockTTL.
+	TTL time.Duration `yaml:"ttl"`
+
+	// AutoRenew renews the locks every TTL/3 until they are unlocked.
+	//
+	// When the lock is no longer held, or it failed to be renewed for TTL,
+	// the lock is considered lost and Lock.Lost is closed.
+	AutoRenew bool `yaml:"autoRenew"`
+
+	// RetryInterval is the interval between the attempts in Locker.Lock.
+	//
+	// Optional, default to DefaultLockRetryInterval.
+	RetryInterval time.Duration `yaml:"retryInterval"`
+}
+
+// Locker is a distributed lock backed by Redis.
+//
+// The locks are single instance Redis locks (not Redlock),
+// with fencing tokens to guard the protected resources against the clients
+// that still believe they hold the lock after it's expired.
+//
+// Lock operations are wrapped in local spans,
+// and the following metrics are reported with "name" tag:
+//
+// - "redis.lock.acquired": counter of the locks acquired.
+//
+// - "redis.lock.contended": counter of the lock attempts failed because the
+// lock is held by someone else.
+//
+// - "redis.lock.lost": counter of the locks lost during auto renewal.
+//
+// - "redis.lock.wait": timing of Locker.Lock calls to acquire the locks.
+type Locker struct {
+	client redis.Scripter
+	name   string
+	cfg    LockerConfig
+
+	acquired  metrics.Counter
+	contended metrics.Counter
+	lost      metrics.Counter
+	wait      metrics.Histogram
+}
+
+// NewLocker creates a Locker.
+//
+// name is used in the span names and metrics tags,
+// usually the same as the name of the client.
+func NewLocker(client redis.Scripter, name string, cfg LockerConfig) *Locker {
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultLockTTL
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = DefaultLockRetryInterval
+	}
+	tags := []string{"name", name}
+	return &Locker{
+		client: client,
+		name:   name,
+		cfg:    cfg,
+
+		acquired:  metricsbp.M.Counter("redis.lock.acquired").With(tags...),
+		contended: metricsbp.M.Counter("redis.lock.contended").With(tags...),
+		lost:      metricsbp.M.Counter("redis.lock.lost").With(tags...),
+		wait:      metricsbp.M.Timing("redis.lock.wait").With(tags...),
+	}
+}
+
+// KEYS[1]: the lock key, KEYS[2]: the fencing token key.
+// ARGV[1]: the lock token, ARGV[2]: ttl in milliseconds.
+//
+// Returns the fencing token if acquired, 0 otherwise.
+var lockScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return 0
+`)
+
+// KEYS[1]: the lock key.
+// ARGV[1]: the lock token.
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// KEYS[1]: the lock key.
+// ARGV[1]: the lock token, ARGV[2]: ttl in milliseconds.
+var refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// fenceKey returns the key of the fencing token counter of key,
+// which is in the same hash slot as key in Redis cluster.
+func fenceKey(key string) string {
+	if strings.Contains(key, "{") {
+		// key already has a hash tag.
+		return key + ":fence"
+	}
+	return "{" + key + "}:fence"
+}
+
+// TryLock tries to acquire the lock of key once.
+//
+// It returns ErrLockNotAcquired when the lock is held by someone else.
+func (l *Locker) TryLock(ctx context.Context, key string) (_ *Lock, err error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, l.name+".lock.try")
+	defer func() {
+		span.FinishWithOptions(tracing.FinishOptions{
+			Ctx: ctx,
+			Err: err,
+		}.Convert())
+	}()
+	return l.tryLock(ctx, key)
+}
+
+// Lock acquires the lock of key,
+// retrying every RetryInterval until it's acquired or ctx is done.
+func (l *Locker) Lock(ctx context.Context, key string) (_ *Lock, err error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, l.name+".lock")
+	defer func() {
+		span.FinishWithOptions(tracing.FinishOptions{
+			Ctx: ctx,
+			Err: err,
+		}.Convert())
+	}()
+
+	timer := metricsbp.NewTimer(l.wait)
+	defer timer.ObserveDuration()
+
+	ticker := time.NewTicker(l.cfg.RetryInterval)
+	defer ticker.Stop()
+	for {
+		lock, err := l.tryLock(ctx, key)
+		if !errors.Is(err, ErrLockNotAcquired) {
+			return lock, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("redisbp: failed to acquire lock %q: %w", key, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *Locker) tryLock(ctx context.Context, key string) (*Lock, error) {
+	token := fmt.Sprintf("%016x%016x", randbp.R.Uint64(), randbp.R.Uint64())
+	fence, err := lockScript.Run(
+		ctx,
+		l.client,
+		[]string{key, fenceKey(key)},
+		token,
+		l.cfg.TTL.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return nil, fmt.Errorf("redisbp: failed to acquire lock %q: %w", key, err)
+	}
+	if fence == 0 {
+		l.contended.Add(1)
+		return nil, ErrLockNotAcquired
+	}
+	l.acquired.Add(1)
+
+	lock := &Lock{
+		locker: l,
+		key:    key,
+		token:  token,
+		fence:  fence,
+		lost:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if l.cfg.AutoRenew {
+		lock.wg.Add(1)
+		go lock.autoRenew()
+	}
+	return lock, nil
+}
+
+// Lock is a lock acquired by Locker.
+type Lock struct {
+	locker *Locker
+	key    string
+	token  string
+	fence  int64
+
+	lost     chan struct{}
+	done     chan struct{}
+	doneOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// Key returns the key of the lock.
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// FencingToken returns the fencing token of the lock.
+//
+// The fencing tokens of the same key are monotonically increasing.
+// The protected resources should reject the writes with a fencing token lower
+// than the ones they already saw.
+func (l *Lock) FencingToken() int64 {
+	return l.fence
+}
+
+// Lost returns a channel that's closed when the lock is lost during auto
+// renewal.
+//
+// It's never closed when LockerConfig.AutoRenew is false.
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Refresh extends the expiry of the lock to TTL from now.
+//
+// It returns ErrLockNotHeld if the lock is no longer held.
+func (l *Lock) Refresh(ctx context.Context) error {
+	n, err := refreshScript.Run(
+		ctx,
+		l.locker.client,
+		[]string{l.key},
+		l.token,
+		l.locker.cfg.TTL.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return fmt.Errorf("redisbp: failed to refresh lock %q: %w", l.key, err)
+	}
+	if n == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Unlock releases the lock and stops the auto renewal.
+//
+// It returns ErrLockNotHeld if the lock is no longer held.
+func (l *Lock) Unlock(ctx context.Context) (err error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, l.locker.name+".unlock")
+	defer func() {
+		span.FinishWithOptions(tracing.FinishOptions{
+			Ctx: ctx,
+			Err: err,
+		}.Convert())
+	}()
+
+	l.doneOnce.Do(func() {
+		close(l.done)
+	})
+	l.wg.Wait()
+
+	n, err := unlockScript.Run(ctx, l.locker.client, []string{l.key}, l.token).Int64()
+	if err != nil {
+		return fmt.Errorf("redisbp: failed to unlock %q: %w", l.key, err)
+	}
+	if n == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (l *Lock) autoRenew() {
+	defer l.wg.Done()
+
+	ttl := l.locker.cfg.TTL
+	interval := ttl / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastRenewed := time.Now()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			err := l.Refresh(ctx)
+			cancel()
+			if err == nil {
+				lastRenewed = time.Now()
+				continue
+			}
+			// Other errors are retried on the next tick,
+			// as the lock is still valid until its expiry.
+			if errors.Is(err, ErrLockNotHeld) || time.Since(lastRenewed) >= ttl {
+				l.locker.lost.Add(1)
+				close(l.lost)
+				return
+			}
+		}
+	}
+}
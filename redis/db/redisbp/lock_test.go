@@ -0,0 +1,108 @@
+package redisbp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/reddit/baseplate.go/redis/db/redisbp"
+)
+
+func TestLocker(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	locker := redisbp.NewLocker(client, "redis", redisbp.LockerConfig{
+		TTL:           time.Second,
+		RetryInterval: time.Millisecond,
+	})
+	ctx := context.Background()
+
+	lock, err := locker.TryLock(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := locker.TryLock(ctx, "key"); !errors.Is(err, redisbp.ErrLockNotAcquired) {
+		t.Errorf("Expected ErrLockNotAcquired, got %v", err)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+	defer cancel()
+	if _, err := locker.Lock(timeoutCtx, "key"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if err := lock.Refresh(ctx); err != nil {
+		t.Errorf("Refresh: %v", err)
+	}
+	if err := lock.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Unlock(ctx); !errors.Is(err, redisbp.ErrLockNotHeld) {
+		t.Errorf("Expected ErrLockNotHeld on second unlock, got %v", err)
+	}
+
+	lock2, err := locker.Lock(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock2.FencingToken() <= lock.FencingToken() {
+		t.Errorf(
+			"Expected fencing token to increase, got %d after %d",
+			lock2.FencingToken(),
+			lock.FencingToken(),
+		)
+	}
+
+	// Expire the lock.
+	s.FastForward(time.Second * 2)
+	if err := lock2.Refresh(ctx); !errors.Is(err, redisbp.ErrLockNotHeld) {
+		t.Errorf("Expected ErrLockNotHeld after expiry, got %v", err)
+	}
+	lock3, err := locker.TryLock(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock3.Unlock(ctx)
+	if err := lock2.Unlock(ctx); !errors.Is(err, redisbp.ErrLockNotHeld) {
+		t.Errorf("Expected ErrLockNotHeld unlocking an expired lock, got %v", err)
+	}
+}
+
+func TestLockerAutoRenew(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	locker := redisbp.NewLocker(client, "redis", redisbp.LockerConfig{
+		TTL:       time.Millisecond * 30,
+		AutoRenew: true,
+	})
+	ctx := context.Background()
+	lock, err := locker.TryLock(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Steal the lock, renewal should fail and report the lock as lost.
+	s.Set("key", "other")
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("Expected lock to be lost")
+	}
+	if err := lock.Unlock(ctx); !errors.Is(err, redisbp.ErrLockNotHeld) {
+		t.Errorf("Expected ErrLockNotHeld, got %v", err)
+	}
+}
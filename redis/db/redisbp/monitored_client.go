@@ -7,9 +7,16 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v8"
+
+	"github.com/reddit/baseplate.go/clientpool"
+	"github.com/reddit/baseplate.go/healthbp"
 	"github.com/reddit/baseplate.go/metricsbp"
 )
 
+// PrometheusPoolProtocol is the protocol label value of the standardized
+// Prometheus pool metrics reported by RegisterPoolStats.
+const PrometheusPoolProtocol = "redis"
+
 // ErrReplicationFactorFailed returns when the cluster client wait function returns replica reached count
 // that is less than desired replication factor
 var ErrReplicationFactorFailed = errors.New("redisbp: failed to meet the requested replication factor")
@@ -21,18 +28,21 @@ type PoolStatser interface {
 }
 
 // NewMonitoredClient creates a new *redis.Client object with a redisbp.SpanHook
-// attached that connects to a single Redis instance.
+// and a redisbp.PrometheusHook attached that connects to a single Redis
+// instance.
 func NewMonitoredClient(name string, opt *redis.Options) *redis.Client {
 	client := redis.NewClient(opt)
 	client.AddHook(SpanHook{ClientName: name})
+	client.AddHook(PrometheusHook{ClientName: name})
 	return client
 }
 
 // NewMonitoredFailoverClient creates a new failover *redis.Client using Redis
-// Sentinel with a redisbp.SpanHook attached.
+// Sentinel with a redisbp.SpanHook and a redisbp.PrometheusHook attached.
 func NewMonitoredFailoverClient(name string, opt *redis.FailoverOptions) *redis.Client {
 	client := redis.NewFailoverClient(opt)
 	client.AddHook(SpanHook{ClientName: name})
+	client.AddHook(PrometheusHook{ClientName: name})
 	return client
 }
 
@@ -73,14 +83,27 @@ func (cc *ClusterClient) Wait(ctx context.Context, args WaitArgs) (replicas int6
 }
 
 // NewMonitoredClusterClient creates a new *redis.ClusterClient object with a
-// redisbp.SpanHook attached.
+// redisbp.SpanHook and a redisbp.PrometheusHook attached.
 func NewMonitoredClusterClient(name string, opt *redis.ClusterOptions) *ClusterClient {
 	client := redis.NewClusterClient(opt)
 	client.AddHook(SpanHook{ClientName: name})
+	client.AddHook(PrometheusHook{ClientName: name})
 
 	return &ClusterClient{client}
 }
 
+// HealthCheck returns a healthbp.CheckFunc pinging the Redis server(s) of
+// client, to be registered into a healthbp.Registry.
+//
+// Unlike the thrift client pools and kafka consumers,
+// the Redis clients are not registered into healthbp.DefaultRegistry by their
+// constructors, as the checkers can't be removed when the clients are closed.
+func HealthCheck(client redis.Cmdable) healthbp.CheckFunc {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
 // MonitorPoolStats publishes stats for the underlying Redis client pool at the
 // rate defined by metricsbp.SysStatsTickerInterval using metricsbp.M.
 //
@@ -117,3 +140,36 @@ func MonitorPoolStats(ctx context.Context, client PoolStatser, name string, tags
 		}
 	}
 }
+
+// RegisterPoolStats registers the standardized Prometheus pool metrics
+// (see clientpool.RegisterStats) of the underlying Redis client pool,
+// with "redis" protocol and name as the pool name.
+//
+// The stats are converted via PoolStats.
+// The returned clientpool.StatsCollector can be used to unregister the metrics
+// via prometheus.Unregister when the client is closed.
+func RegisterPoolStats(client PoolStatser, name string) (*clientpool.StatsCollector, error) {
+	return clientpool.RegisterStats(PrometheusPoolProtocol, name, func() clientpool.Stats {
+		return PoolStats(client.PoolStats())
+	})
+}
+
+// PoolStats converts the stats of a Redis client pool into clientpool.Stats.
+//
+// redis.PoolStats doesn't expose the number of waiters, so Waiters is always 0.
+// Exhaustions is reported as the number of times a caller timed out waiting
+// for a connection from the full pool.
+func PoolStats(stats *redis.PoolStats) clientpool.Stats {
+	active := int64(stats.TotalConns) - int64(stats.IdleConns)
+	if active < 0 {
+		active = 0
+	}
+	return clientpool.Stats{
+		Active:      active,
+		Idle:        int64(stats.IdleConns),
+		Exhaustions: uint64(stats.Timeouts),
+		Hits:        uint64(stats.Hits),
+		Misses:      uint64(stats.Misses),
+		Stale:       uint64(stats.StaleConns),
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redis/v8"
 
+	"github.com/reddit/baseplate.go/clientpool"
 	"github.com/reddit/baseplate.go/mqsend"
 	"github.com/reddit/baseplate.go/redis/db/redisbp"
 	"github.com/reddit/baseplate.go/tracing"
@@ -75,3 +76,44 @@ func TestNewMonitoredClient(t *testing.T) {
 		t.Fatal("expected an error, got nil")
 	}
 }
+
+func TestPoolStats(t *testing.T) {
+	stats := redisbp.PoolStats(&redis.PoolStats{
+		Hits:       6,
+		Misses:     7,
+		Timeouts:   3,
+		TotalConns: 5,
+		IdleConns:  2,
+		StaleConns: 8,
+	})
+	expected := clientpool.Stats{
+		Active:      3,
+		Idle:        2,
+		Exhaustions: 3,
+		Hits:        6,
+		Misses:      7,
+		Stale:       8,
+	}
+	if stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	check := redisbp.HealthCheck(client)
+	if err := check(context.Background()); err != nil {
+		t.Errorf("Expected healthy, got %v", err)
+	}
+	s.Close()
+	if err := check(context.Background()); err == nil {
+		t.Error("Expected error after the server is closed")
+	}
+}
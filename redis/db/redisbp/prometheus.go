@@ -0,0 +1,319 @@
+package redisbp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/reddit/baseplate.go/internal/prometheusbpint"
+	"github.com/reddit/baseplate.go/randbp"
+)
+
+// Label names of the Prometheus metrics reported by redisbp.
+const (
+	PrometheusClientNameLabel = "redis_client_name"
+	PrometheusCommandLabel    = "redis_command"
+	PrometheusSuccessLabel    = "redis_success"
+	PrometheusErrorTypeLabel  = "redis_error_type"
+	PrometheusKeyPrefixLabel  = "redis_key_prefix"
+	PrometheusDirectionLabel  = "redis_direction"
+)
+
+// Values of PrometheusErrorTypeLabel other than the Redis server error
+// prefixes (e.g. "ERR", "WRONGTYPE").
+const (
+	ErrorTypeTimeout     = "timeout"
+	ErrorTypeCanceled    = "canceled"
+	ErrorTypeNetwork     = "network"
+	ErrorTypePoolTimeout = "pool_timeout"
+	ErrorTypeClosed      = "closed"
+	ErrorTypeOther       = "other"
+)
+
+// Values of PrometheusDirectionLabel.
+const (
+	DirectionRequest  = "request"
+	DirectionResponse = "response"
+)
+
+// pipelineCommand is the PrometheusCommandLabel value of the pipelines.
+const pipelineCommand = "pipeline"
+
+var (
+	latencyHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "redis_client_latency_seconds",
+		Help:    "Latency of the Redis commands and pipelines",
+		Buckets: prometheus.DefBuckets,
+	}, []string{
+		PrometheusClientNameLabel,
+		PrometheusCommandLabel,
+		PrometheusSuccessLabel,
+	})
+
+	errorsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "redis_client_errors_total",
+		Help: "Number of the failed Redis commands by error type",
+	}, []string{
+		PrometheusClientNameLabel,
+		PrometheusCommandLabel,
+		PrometheusErrorTypeLabel,
+	})
+
+	payloadSizeHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "redis_client_payload_size_bytes",
+		Help:    "Sampled payload sizes of the Redis commands by key prefix",
+		Buckets: prometheus.ExponentialBuckets(16, 4, 10),
+	}, []string{
+		PrometheusClientNameLabel,
+		PrometheusCommandLabel,
+		PrometheusKeyPrefixLabel,
+		PrometheusDirectionLabel,
+	})
+)
+
+type startTimeKey struct{}
+
+// PrometheusHook is a redis.Hook reporting the latency of the commands and
+// pipelines ("redis_client_latency_seconds" histogram) and the errors by type
+// ("redis_client_errors_total" counter) as Prometheus metrics,
+// labeled by ClientName.
+//
+// It's added to the clients created by NewMonitored*Client automatically.
+type PrometheusHook struct {
+	ClientName string
+}
+
+var _ redis.Hook = PrometheusHook{}
+
+// BeforeProcess implements redis.Hook.
+func (h PrometheusHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startTimeKey{}, time.Now()), nil
+}
+
+// AfterProcess implements redis.Hook.
+func (h PrometheusHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.observe(ctx, cmd.Name(), cmd.Err())
+	return nil
+}
+
+// BeforeProcessPipeline implements redis.Hook.
+func (h PrometheusHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startTimeKey{}, time.Now()), nil
+}
+
+// AfterProcessPipeline implements redis.Hook.
+//
+// The latency is reported for the whole pipeline with "pipeline" as the
+// command, while the errors are reported for each failed command.
+func (h PrometheusHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var failed error
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+			failed = err
+			errorsCounter.WithLabelValues(h.ClientName, cmd.Name(), ErrorType(err)).Inc()
+		}
+	}
+	if start, ok := ctx.Value(startTimeKey{}).(time.Time); ok {
+		latencyHistogram.WithLabelValues(
+			h.ClientName,
+			pipelineCommand,
+			prometheusbpint.BoolString(failed == nil),
+		).Observe(time.Since(start).Seconds())
+	}
+	return nil
+}
+
+func (h PrometheusHook) observe(ctx context.Context, command string, err error) {
+	if errors.Is(err, redis.Nil) {
+		err = nil
+	}
+	if start, ok := ctx.Value(startTimeKey{}).(time.Time); ok {
+		latencyHistogram.WithLabelValues(
+			h.ClientName,
+			command,
+			prometheusbpint.BoolString(err == nil),
+		).Observe(time.Since(start).Seconds())
+	}
+	if err != nil {
+		errorsCounter.WithLabelValues(h.ClientName, command, ErrorType(err)).Inc()
+	}
+}
+
+// ErrorType returns the type of err used as the PrometheusErrorTypeLabel.
+//
+// For errors returned by the Redis server,
+// it's the prefix of the error message (e.g. "ERR", "WRONGTYPE").
+// Otherwise it's one of the ErrorType* constants.
+func ErrorType(err error) string {
+	var redisErr redis.Error
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorTypeTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorTypeCanceled
+	case errors.Is(err, redis.ErrClosed):
+		return ErrorTypeClosed
+	case err.Error() == "redis: connection pool timeout":
+		// pool.ErrPoolTimeout is internal to go-redis.
+		return ErrorTypePoolTimeout
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return ErrorTypeTimeout
+		}
+		return ErrorTypeNetwork
+	case errors.As(err, &redisErr):
+		if prefix := strings.SplitN(redisErr.Error(), " ", 2)[0]; prefix != "" {
+			return prefix
+		}
+	}
+	return ErrorTypeOther
+}
+
+// PayloadSizeHook is a redis.Hook reporting the sampled payload sizes of the
+// commands ("redis_client_payload_size_bytes" histogram) as Prometheus metrics,
+// labeled by ClientName, the command, the matched key prefix and the direction
+// ("request" or "response").
+//
+// Only the commands with the first key matching one of the prefixes in
+// SampleRates are sampled, at the rate of the longest matched prefix.
+// The sizes are the total length of the string arguments for requests,
+// and the total length of the string values for responses.
+//
+// It's not added to the clients created by NewMonitored*Client,
+// and should be added explicitly via AddHook:
+//
+//	client.AddHook(redisbp.PayloadSizeHook{
+//		ClientName: "redis",
+//		SampleRates: map[string]float64{
+//			"user:": 0.01,
+//			"session:": 0.1,
+//		},
+//	})
+type PayloadSizeHook struct {
+	ClientName string
+
+	// SampleRates are the sample rates in [0, 1] keyed by key prefixes.
+	SampleRates map[string]float64
+}
+
+var _ redis.Hook = PayloadSizeHook{}
+
+// BeforeProcess implements redis.Hook.
+func (h PayloadSizeHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+// AfterProcess implements redis.Hook.
+func (h PayloadSizeHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.observe(cmd)
+	return nil
+}
+
+// BeforeProcessPipeline implements redis.Hook.
+func (h PayloadSizeHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+// AfterProcessPipeline implements redis.Hook.
+func (h PayloadSizeHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	for _, cmd := range cmds {
+		h.observe(cmd)
+	}
+	return nil
+}
+
+func (h PayloadSizeHook) observe(cmd redis.Cmder) {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return
+	}
+	key, ok := args[1].(string)
+	if !ok {
+		return
+	}
+	prefix, rate, ok := h.match(key)
+	if !ok || !h.sample(rate) {
+		return
+	}
+	name := cmd.Name()
+	payloadSizeHistogram.WithLabelValues(
+		h.ClientName,
+		name,
+		prefix,
+		DirectionRequest,
+	).Observe(float64(argsSize(args[1:])))
+	if cmd.Err() == nil {
+		payloadSizeHistogram.WithLabelValues(
+			h.ClientName,
+			name,
+			prefix,
+			DirectionResponse,
+		).Observe(float64(responseSize(cmd)))
+	}
+}
+
+// match returns the longest prefix in SampleRates matching key.
+func (h PayloadSizeHook) match(key string) (prefix string, rate float64, ok bool) {
+	for p, r := range h.SampleRates {
+		if strings.HasPrefix(key, p) && (!ok || len(p) > len(prefix)) {
+			prefix, rate, ok = p, r, true
+		}
+	}
+	return prefix, rate, ok
+}
+
+func (h PayloadSizeHook) sample(rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	return randbp.ShouldSampleWithRate(rate)
+}
+
+func argsSize(args []interface{}) int {
+	var size int
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			size += len(v)
+		case []byte:
+			size += len(v)
+		}
+	}
+	return size
+}
+
+func responseSize(cmd redis.Cmder) int {
+	switch c := cmd.(type) {
+	case *redis.StringCmd:
+		return len(c.Val())
+	case *redis.StringSliceCmd:
+		var size int
+		for _, v := range c.Val() {
+			size += len(v)
+		}
+		return size
+	case *redis.StringStringMapCmd:
+		var size int
+		for k, v := range c.Val() {
+			size += len(k) + len(v)
+		}
+		return size
+	case *redis.SliceCmd:
+		return argsSize(c.Val())
+	case *redis.Cmd:
+		if v, ok := c.Val().(string); ok {
+			return len(v)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,171 @@
+package redisbp_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/reddit/baseplate.go/redis/db/redisbp"
+)
+
+// findMetric returns the metric named name from the default gatherer with all
+// the given labels, or nil if it's not found.
+func findMetric(t *testing.T, name string, labels map[string]string) *dto.Metric {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range family.GetMetric() {
+			got := make(map[string]string)
+			for _, pair := range m.GetLabel() {
+				got[pair.GetName()] = pair.GetValue()
+			}
+			for k, v := range labels {
+				if got[k] != v {
+					continue metrics
+				}
+			}
+			return m
+		}
+	}
+	return nil
+}
+
+func TestPrometheusHook(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	const name = "prometheus-hook"
+	client := redisbp.NewMonitoredClient(name, &redis.Options{Addr: s.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	if err := client.Set(ctx, "key", "value", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Get(ctx, "missing").Err(); !errors.Is(err, redis.Nil) {
+		t.Fatalf("Expected redis.Nil, got %v", err)
+	}
+	if err := client.Incr(ctx, "key").Err(); err == nil {
+		t.Fatal("Expected error from incr on a non-integer value")
+	}
+	if _, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Get(ctx, "key")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		command string
+		success string
+	}{
+		{command: "set", success: "true"},
+		{command: "get", success: "true"},
+		{command: "incr", success: "false"},
+		{command: "pipeline", success: "true"},
+	} {
+		m := findMetric(t, "redis_client_latency_seconds", map[string]string{
+			redisbp.PrometheusClientNameLabel: name,
+			redisbp.PrometheusCommandLabel:    c.command,
+			redisbp.PrometheusSuccessLabel:    c.success,
+		})
+		if m == nil || m.GetHistogram().GetSampleCount() != 1 {
+			t.Errorf("Expected 1 latency sample for %q (success=%s), got %v", c.command, c.success, m)
+		}
+	}
+
+	m := findMetric(t, "redis_client_errors_total", map[string]string{
+		redisbp.PrometheusClientNameLabel: name,
+		redisbp.PrometheusCommandLabel:    "incr",
+		redisbp.PrometheusErrorTypeLabel:  "ERR",
+	})
+	if m == nil || m.GetCounter().GetValue() != 1 {
+		t.Errorf("Expected 1 ERR error for incr, got %v", m)
+	}
+}
+
+func TestErrorType(t *testing.T) {
+	for _, c := range []struct {
+		err      error
+		expected string
+	}{
+		{err: context.DeadlineExceeded, expected: redisbp.ErrorTypeTimeout},
+		{err: context.Canceled, expected: redisbp.ErrorTypeCanceled},
+		{err: redis.ErrClosed, expected: redisbp.ErrorTypeClosed},
+		{err: &net.OpError{Op: "dial", Err: errors.New("refused")}, expected: redisbp.ErrorTypeNetwork},
+		{err: errors.New("foo"), expected: redisbp.ErrorTypeOther},
+	} {
+		if got := redisbp.ErrorType(c.err); got != c.expected {
+			t.Errorf("ErrorType(%v) expected %q, got %q", c.err, c.expected, got)
+		}
+	}
+}
+
+func TestPayloadSizeHook(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	const name = "payload-size-hook"
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+	client.AddHook(redisbp.PayloadSizeHook{
+		ClientName: name,
+		SampleRates: map[string]float64{
+			"user:":        0,
+			"user:sample:": 1,
+		},
+	})
+	ctx := context.Background()
+
+	if err := client.Set(ctx, "user:sample:1", "value", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Get(ctx, "user:sample:1").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Set(ctx, "user:1", "value", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	labels := func(command, direction string) map[string]string {
+		return map[string]string{
+			redisbp.PrometheusClientNameLabel: name,
+			redisbp.PrometheusCommandLabel:    command,
+			redisbp.PrometheusKeyPrefixLabel:  "user:sample:",
+			redisbp.PrometheusDirectionLabel:  direction,
+		}
+	}
+	// "user:sample:1" + "value"
+	m := findMetric(t, "redis_client_payload_size_bytes", labels("set", redisbp.DirectionRequest))
+	if m == nil || m.GetHistogram().GetSampleCount() != 1 || m.GetHistogram().GetSampleSum() != 18 {
+		t.Errorf("Unexpected set request payload size: %v", m)
+	}
+	m = findMetric(t, "redis_client_payload_size_bytes", labels("get", redisbp.DirectionResponse))
+	if m == nil || m.GetHistogram().GetSampleSum() != 5 {
+		t.Errorf("Unexpected get response payload size: %v", m)
+	}
+	if m := findMetric(t, "redis_client_payload_size_bytes", map[string]string{
+		redisbp.PrometheusClientNameLabel: name,
+		redisbp.PrometheusKeyPrefixLabel:  "user:",
+	}); m != nil {
+		t.Errorf("Expected no samples with 0 rate, got %v", m)
+	}
+}
@@ -0,0 +1,110 @@
+package redisbp
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/reddit/baseplate.go/ratelimit"
+)
+
+// DefaultQuotaKeyPrefix is the default value of QuotaConfig.KeyPrefix.
+const DefaultQuotaKeyPrefix = "quota:"
+
+// QuotaConfig is the config of a Quota.
+//
+// Can be deserialized from YAML.
+type QuotaConfig struct {
+	ratelimit.QuotaConfig `yaml:",inline"`
+
+	// KeyPrefix is prepended to the keys passed into CheckAndConsume.
+	//
+	// Optional, default to DefaultQuotaKeyPrefix.
+	KeyPrefix string `yaml:"keyPrefix"`
+}
+
+// Quota is a ratelimit.Quota backed by Redis,
+// so that the quotas are enforced consistently across all the replicas using
+// the same Redis, and survive restarts.
+//
+// The usage of each key in each period is stored in a separate counter,
+// which expires at the end of the period.
+// The current time is taken from the client,
+// so the clocks of the replicas should be reasonably in sync.
+type Quota struct {
+	client redis.Scripter
+	cfg    QuotaConfig
+	now    func() time.Time
+}
+
+// NewQuota creates a Quota.
+func NewQuota(client redis.Scripter, cfg QuotaConfig) (*Quota, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg.QuotaConfig = cfg.WithDefaults()
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = DefaultQuotaKeyPrefix
+	}
+	return &Quota{
+		client: client,
+		cfg:    cfg,
+		now:    time.Now,
+	}, nil
+}
+
+// KEYS[1]: the counter key of the period.
+// ARGV[1]: limit, ARGV[2]: n, ARGV[3]: the end of the period in milliseconds.
+//
+// Returns {allowed (1 or 0), used}.
+var quotaScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local n = tonumber(ARGV[2])
+local used = tonumber(redis.call("GET", KEYS[1]) or "0")
+if used + n > limit then
+	return {0, used}
+end
+used = redis.call("INCRBY", KEYS[1], n)
+redis.call("PEXPIREAT", KEYS[1], ARGV[3])
+return {1, used}
+`)
+
+// CheckAndConsume implements ratelimit.Quota.
+func (q *Quota) CheckAndConsume(ctx context.Context, key string, n int64) (ratelimit.QuotaResult, error) {
+	start := q.cfg.PeriodStart(q.now())
+	resetAt := start.Add(q.cfg.Period)
+	result, err := quotaScript.Run(
+		ctx,
+		q.client,
+		[]string{q.cfg.KeyPrefix + key + ":" + strconv.FormatInt(start.Unix(), 10)},
+		q.cfg.Limit,
+		n,
+		resetAt.UnixNano()/int64(time.Millisecond),
+	).Result()
+	if err != nil {
+		return ratelimit.QuotaResult{}, fmt.Errorf("redisbp: quota %q: %w", key, err)
+	}
+	values, _ := result.([]interface{})
+	var ints [2]int64
+	if len(values) != len(ints) {
+		return ratelimit.QuotaResult{}, fmt.Errorf("redisbp: quota %q: unexpected script result %v", key, values)
+	}
+	for i, v := range values {
+		n, ok := v.(int64)
+		if !ok {
+			return ratelimit.QuotaResult{}, fmt.Errorf("redisbp: quota %q: unexpected script result %v", key, values)
+		}
+		ints[i] = n
+	}
+	return ratelimit.QuotaResult{
+		Allowed:   ints[0] == 1,
+		Limit:     q.cfg.Limit,
+		Remaining: q.cfg.Limit - ints[1],
+		ResetAt:   resetAt,
+	}, nil
+}
+
+var _ ratelimit.Quota = (*Quota)(nil)
@@ -0,0 +1,83 @@
+package redisbp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/reddit/baseplate.go/ratelimit"
+	"github.com/reddit/baseplate.go/redis/db/redisbp"
+)
+
+func TestQuota(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	cfg := redisbp.QuotaConfig{
+		QuotaConfig: ratelimit.QuotaConfig{
+			Limit: 5,
+		},
+	}
+	// Two quotas sharing the same redis share the same usages.
+	quotas := make([]*redisbp.Quota, 2)
+	for i := range quotas {
+		quotas[i], err = redisbp.NewQuota(client, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		result, err := quotas[i].CheckAndConsume(ctx, "user", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed {
+			t.Errorf("#%d: Expected allowed, got %+v", i, result)
+		}
+		if expected := int64(5 - 2*(i+1)); result.Remaining != expected {
+			t.Errorf("#%d: Expected remaining %d, got %d", i, expected, result.Remaining)
+		}
+	}
+	result, err := quotas[0].CheckAndConsume(ctx, "user", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed {
+		t.Errorf("Expected not allowed over the limit, got %+v", result)
+	}
+	if result.Remaining != 1 {
+		t.Errorf("Expected the rejected usage not consumed, got remaining %d", result.Remaining)
+	}
+	if result.ResetAt.IsZero() {
+		t.Error("Expected ResetAt to be set")
+	}
+	if len(s.Keys()) != 1 {
+		t.Errorf("Expected 1 key, got %v", s.Keys())
+	}
+	for _, key := range s.Keys() {
+		if s.TTL(key) <= 0 {
+			t.Errorf("Expected key %q to expire, got ttl %v", key, s.TTL(key))
+		}
+	}
+
+	result, err = quotas[1].CheckAndConsume(ctx, "user", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Allowed || result.Remaining != 0 {
+		t.Errorf("Expected the last unit allowed, got %+v", result)
+	}
+
+	if _, err := redisbp.NewQuota(client, redisbp.QuotaConfig{}); err == nil {
+		t.Error("Expected error from invalid config, got nil")
+	}
+}
@@ -0,0 +1,131 @@
+package redisbp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/reddit/baseplate.go/ratelimit"
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+// DefaultRateLimitKeyPrefix is the default value of
+// RateLimiterConfig.KeyPrefix.
+const DefaultRateLimitKeyPrefix = "ratelimit:"
+
+// RateLimiterConfig is the config of a RateLimiter.
+//
+// Can be deserialized from YAML.
+type RateLimiterConfig struct {
+	ratelimit.TokenBucketConfig `yaml:",inline"`
+
+	// KeyPrefix is prepended to the keys passed into AllowN.
+	//
+	// Optional, default to DefaultRateLimitKeyPrefix.
+	KeyPrefix string `yaml:"keyPrefix"`
+}
+
+// RateLimiter is a ratelimit.Limiter backed by Redis,
+// so that the limits are enforced consistently across all the replicas using
+// the same Redis.
+//
+// It implements a token bucket for each key via Lua scripts.
+// The current time is taken from the client
+// (TokenBucketConfig.Clock when set),
+// so the clocks of the replicas should be reasonably in sync.
+// Clock skews between replicas only affect the refill of the buckets,
+// tokens are never consumed twice.
+type RateLimiter struct {
+	client redis.Scripter
+	cfg    RateLimiterConfig
+	clock  timebp.Clock
+}
+
+// NewRateLimiter creates a RateLimiter.
+func NewRateLimiter(client redis.Scripter, cfg RateLimiterConfig) (*RateLimiter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = DefaultRateLimitKeyPrefix
+	}
+	return &RateLimiter{
+		client: client,
+		cfg:    cfg,
+		clock:  timebp.ClockOrDefault(cfg.Clock),
+	}, nil
+}
+
+// KEYS[1]: the bucket key.
+// ARGV[1]: rate (tokens per millisecond), ARGV[2]: burst,
+// ARGV[3]: now in milliseconds, ARGV[4]: n.
+//
+// Returns {allowed (1 or 0), remaining tokens, retry after in milliseconds}.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate)
+	ts = now
+end
+
+local allowed = 0
+local retry = 0
+if tokens >= n then
+	allowed = 1
+	tokens = tokens - n
+else
+	retry = math.ceil((n - tokens) / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(ts))
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate) + 1000)
+return {allowed, math.floor(tokens), retry}
+`)
+
+// AllowN implements ratelimit.Limiter.
+func (rl *RateLimiter) AllowN(ctx context.Context, key string, n int64) (ratelimit.Result, error) {
+	result, err := tokenBucketScript.Run(
+		ctx,
+		rl.client,
+		[]string{rl.cfg.KeyPrefix + key},
+		rl.cfg.Rate/float64(time.Second/time.Millisecond),
+		rl.cfg.Burst,
+		rl.clock.Now().UnixNano()/int64(time.Millisecond),
+		n,
+	).Result()
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("redisbp: rate limit %q: %w", key, err)
+	}
+	values, _ := result.([]interface{})
+	var ints [3]int64
+	if len(values) != len(ints) {
+		return ratelimit.Result{}, fmt.Errorf("redisbp: rate limit %q: unexpected script result %v", key, values)
+	}
+	for i, v := range values {
+		n, ok := v.(int64)
+		if !ok {
+			return ratelimit.Result{}, fmt.Errorf("redisbp: rate limit %q: unexpected script result %v", key, values)
+		}
+		ints[i] = n
+	}
+	return ratelimit.Result{
+		Allowed:    ints[0] == 1,
+		Remaining:  ints[1],
+		RetryAfter: time.Duration(ints[2]) * time.Millisecond,
+	}, nil
+}
+
+var _ ratelimit.Limiter = (*RateLimiter)(nil)
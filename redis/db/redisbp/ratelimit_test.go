@@ -0,0 +1,120 @@
+package redisbp_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/reddit/baseplate.go/ratelimit"
+	"github.com/reddit/baseplate.go/redis/db/redisbp"
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+func TestRateLimiter(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	cfg := redisbp.RateLimiterConfig{
+		TokenBucketConfig: ratelimit.TokenBucketConfig{
+			Rate:  0.1,
+			Burst: 3,
+		},
+	}
+	// Two limiters sharing the same redis share the same buckets.
+	limiters := make([]*redisbp.RateLimiter, 2)
+	for i := range limiters {
+		limiters[i], err = redisbp.NewRateLimiter(client, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		result, err := ratelimit.Allow(ctx, limiters[i%2], "key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed {
+			t.Errorf("#%d: Expected allowed, got %+v", i, result)
+		}
+		if result.Remaining != int64(2-i) {
+			t.Errorf("#%d: Expected remaining %d, got %d", i, 2-i, result.Remaining)
+		}
+	}
+	result, err := ratelimit.Allow(ctx, limiters[1], "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed {
+		t.Errorf("Expected not allowed after burst, got %+v", result)
+	}
+	if result.RetryAfter <= 0 {
+		t.Errorf("Expected positive RetryAfter, got %v", result.RetryAfter)
+	}
+	if !s.Exists(redisbp.DefaultRateLimitKeyPrefix + "key") {
+		t.Errorf("Expected key %q to exist, got %v", redisbp.DefaultRateLimitKeyPrefix+"key", s.Keys())
+	}
+
+	result, err = ratelimit.Allow(ctx, limiters[0], "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Allowed {
+		t.Errorf("Expected other key allowed, got %+v", result)
+	}
+
+	if _, err := redisbp.NewRateLimiter(client, redisbp.RateLimiterConfig{}); err == nil {
+		t.Error("Expected error from invalid config, got nil")
+	}
+}
+
+func TestRateLimiterClock(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+
+	clock := timebp.NewFakeClock(time.Now())
+	limiter, err := redisbp.NewRateLimiter(client, redisbp.RateLimiterConfig{
+		TokenBucketConfig: ratelimit.TokenBucketConfig{
+			Rate:  0.1,
+			Burst: 1,
+			Clock: clock,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	allow := func() bool {
+		t.Helper()
+		result, err := ratelimit.Allow(ctx, limiter, "key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Allowed
+	}
+	if !allow() {
+		t.Error("Expected the first call to be allowed")
+	}
+	if allow() {
+		t.Error("Expected not allowed after burst")
+	}
+	clock.Advance(10 * time.Second)
+	if !allow() {
+		t.Error("Expected allowed after the bucket refilled by the clock")
+	}
+}
@@ -0,0 +1,686 @@
+package redisbp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+// ReplicaPolicy is the policy used by ReplicaClient to route the read-only
+// commands.
+type ReplicaPolicy string
+
+// Supported ReplicaPolicy values.
+const (
+	// ReplicaPolicyRoundRobin routes the read-only commands to the available
+	// replicas in turns.
+	//
+	// When there's no available replica the commands fail with
+	// ErrNoReplicaAvailable.
+	ReplicaPolicyRoundRobin ReplicaPolicy = "round-robin"
+
+	// ReplicaPolicyNearest routes the read-only commands to the available
+	// replica with the lowest health check latency.
+	//
+	// When there's no available replica the commands fail with
+	// ErrNoReplicaAvailable.
+	ReplicaPolicyNearest ReplicaPolicy = "nearest"
+
+	// ReplicaPolicyFallbackToMaster routes the read-only commands to the
+	// available replicas in turns, same as ReplicaPolicyRoundRobin.
+	//
+	// When there's no available replica, or the command failed on the replica
+	// because of non-Redis errors (e.g. network errors),
+	// the commands are sent to the master instead.
+	ReplicaPolicyFallbackToMaster ReplicaPolicy = "fallback-to-master"
+)
+
+// DefaultReplicaHealthCheckInterval is the default value of
+// ReplicaOptions.HealthCheckInterval.
+const DefaultReplicaHealthCheckInterval = time.Second
+
+// ErrNoReplicaAvailable is the error returned by ReplicaClient when there's no
+// available replica to route the read-only commands to.
+var ErrNoReplicaAvailable = errors.New("redisbp: no replica available")
+
+// ReplicaTargetMaster is the PrometheusTargetLabel value of the master.
+//
+// The replicas use their addresses as the label values.
+const ReplicaTargetMaster = "master"
+
+// PrometheusTargetLabel is the label name of the target (master or replica)
+// of the Prometheus metrics reported by ReplicaClient.
+const PrometheusTargetLabel = "redis_target"
+
+var (
+	replicaRoutedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "redis_replica_routed_total",
+		Help: "Number of the commands routed to each target by ReplicaClient",
+	}, []string{
+		PrometheusClientNameLabel,
+		PrometheusTargetLabel,
+	})
+
+	replicaFallbacksCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "redis_replica_fallbacks_total",
+		Help: "Number of the read-only commands fell back to the master by ReplicaClient",
+	}, []string{
+		PrometheusClientNameLabel,
+	})
+
+	replicaAvailableGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "redis_replica_available",
+		Help: "Whether the replica is available for the read-only commands (1) or not (0)",
+	}, []string{
+		PrometheusClientNameLabel,
+		PrometheusTargetLabel,
+	})
+
+	replicaLatencyGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "redis_replica_latency_seconds",
+		Help: "Smoothed health check latency of the replica",
+	}, []string{
+		PrometheusClientNameLabel,
+		PrometheusTargetLabel,
+	})
+
+	replicaStalenessGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "redis_replica_staleness_seconds",
+		Help: "Seconds since the replica last heard from the master",
+	}, []string{
+		PrometheusClientNameLabel,
+		PrometheusTargetLabel,
+	})
+)
+
+// ReplicaOptions configures how ReplicaClient routes the read-only commands.
+//
+// Can be deserialized from YAML.
+type ReplicaOptions struct {
+	// Policy is the policy used to route the read-only commands.
+	//
+	// Optional, default to ReplicaPolicyRoundRobin.
+	Policy ReplicaPolicy `yaml:"policy"`
+
+	// MaxStaleness is the max replication staleness tolerated,
+	// as reported by the master_last_io_seconds_ago field of
+	// "INFO replication" on the replicas (so it only has the granularity of
+	// seconds).
+	//
+	// The replicas with a broken link to the master or exceeding MaxStaleness
+	// are considered unavailable until the next health check.
+	//
+	// Optional, default to 0 (staleness is not checked).
+	MaxStaleness time.Duration `yaml:"maxStaleness"`
+
+	// HealthCheckInterval is the interval of the background health checks
+	// (PING, and "INFO replication" when MaxStaleness is set) of the replicas.
+	//
+	// Optional, default to DefaultReplicaHealthCheckInterval.
+	HealthCheckInterval time.Duration `yaml:"healthCheckInterval"`
+}
+
+// Validate checks ReplicaOptions for any erroneous values.
+func (opt ReplicaOptions) Validate() error {
+	switch opt.Policy {
+	default:
+		return fmt.Errorf("redisbp: unknown replica policy %q", opt.Policy)
+	case "", ReplicaPolicyRoundRobin, ReplicaPolicyNearest, ReplicaPolicyFallbackToMaster:
+	}
+	if opt.MaxStaleness < 0 {
+		return fmt.Errorf("redisbp: negative maxStaleness %v", opt.MaxStaleness)
+	}
+	if opt.HealthCheckInterval < 0 {
+		return fmt.Errorf("redisbp: negative healthCheckInterval %v", opt.HealthCheckInterval)
+	}
+	return nil
+}
+
+// ReplicaConfig can be used to configure a ReplicaClient.
+//
+// Pool, Retries and Timeouts apply to both the master and the replicas.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//	redis:
+//	 url: redis://redis-master:6379
+//	 replicas:
+//	  - redis://redis-replica-1:6379
+//	  - redis://redis-replica-2:6379
+//	 routing:
+//	  policy: fallback-to-master
+//	  maxStaleness: 5s
+//	  healthCheckInterval: 1s
+//	 pool:
+//	  size: 10
+type ReplicaConfig struct {
+	// ClientConfig configures the master.
+	ClientConfig `yaml:",inline"`
+
+	// Replicas are the URLs of the replicas, parsed by redis.ParseURL.
+	Replicas []string `yaml:"replicas"`
+
+	Routing ReplicaOptions `yaml:"routing"`
+}
+
+// Options returns the redis.Options of the master and the replicas populated
+// using the values from cfg.
+func (cfg ReplicaConfig) Options() (master *redis.Options, replicas []*redis.Options, err error) {
+	master, err = cfg.ClientConfig.Options()
+	if err != nil {
+		return nil, nil, err
+	}
+	replicas = make([]*redis.Options, 0, len(cfg.Replicas))
+	for _, url := range cfg.Replicas {
+		replica, err := ClientConfig{
+			URL:      url,
+			Pool:     cfg.Pool,
+			Retries:  cfg.Retries,
+			Timeouts: cfg.Timeouts,
+		}.Options()
+		if err != nil {
+			return nil, nil, err
+		}
+		replicas = append(replicas, replica)
+	}
+	return master, replicas, nil
+}
+
+// ReplicaClient routes the read-only commands to the replicas and everything
+// else to the master.
+//
+// The commands executed via the embedded *redis.Client,
+// including the typed command methods (e.g. Get, Set), Process and Do,
+// are routed based on IsReadOnlyCommand:
+//
+//	value, err := client.Get(ctx, "key").Result() // routed to a replica
+//	err = client.Set(ctx, "key", value, 0).Err()  // sent to the master
+//
+// The pipelines, the transactions (including Watch) and the connections from
+// Conn always go to the master.
+//
+// The replicas are health checked in background,
+// and the unavailable ones are skipped until they recover.
+//
+// The following Prometheus metrics are reported, labeled by the client name:
+//
+// - "redis_replica_routed_total": counter of the commands routed to each target
+// (the replica address or "master").
+//
+// - "redis_replica_fallbacks_total": counter of the read-only commands fell back
+// to the master (only with ReplicaPolicyFallbackToMaster).
+//
+// - "redis_replica_available", "redis_replica_latency_seconds" and
+// "redis_replica_staleness_seconds": gauges of the health check results of each
+// replica.
+type ReplicaClient struct {
+	// Client is the master with the routing installed as a redis.Hook.
+	*redis.Client
+
+	// master is the master without the routing,
+	// sharing the connection pool with Client.
+	master *redis.Client
+
+	name     string
+	opt      ReplicaOptions
+	replicas []*replica
+	next     uint64
+
+	masterRouted prometheus.Counter
+	fallbacks    prometheus.Counter
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewMonitoredReplicaClient creates a ReplicaClient with all the clients to the
+// master and the replicas created by NewMonitoredClient.
+//
+// It runs the first health check of the replicas before returning,
+// and starts the background health checks.
+// Close must be called to stop them.
+func NewMonitoredReplicaClient(name string, master *redis.Options, replicas []*redis.Options, opt ReplicaOptions) (*ReplicaClient, error) {
+	if err := opt.Validate(); err != nil {
+		return nil, err
+	}
+	if opt.Policy == "" {
+		opt.Policy = ReplicaPolicyRoundRobin
+	}
+	if opt.HealthCheckInterval <= 0 {
+		opt.HealthCheckInterval = DefaultReplicaHealthCheckInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &ReplicaClient{
+		master: redis.NewClient(master),
+		name:   name,
+		opt:    opt,
+
+		masterRouted: replicaRoutedCounter.WithLabelValues(name, ReplicaTargetMaster),
+		fallbacks:    replicaFallbacksCounter.WithLabelValues(name),
+
+		cancel: cancel,
+	}
+	for _, options := range replicas {
+		c.replicas = append(c.replicas, &replica{
+			addr:   options.Addr,
+			client: NewMonitoredClient(name, options),
+
+			routed:    replicaRoutedCounter.WithLabelValues(name, options.Addr),
+			available: replicaAvailableGauge.WithLabelValues(name, options.Addr),
+			latency:   replicaLatencyGauge.WithLabelValues(name, options.Addr),
+			staleness: replicaStalenessGauge.WithLabelValues(name, options.Addr),
+		})
+	}
+
+	// The routing hook must be the first one on Client,
+	// so the hooks of the master are skipped for the commands routed to the
+	// replicas.
+	c.Client = c.master.WithContext(context.Background())
+	c.Client.AddHook(replicaRoutingHook{c: c})
+	for _, client := range []*redis.Client{c.master, c.Client} {
+		client.AddHook(SpanHook{ClientName: name})
+		client.AddHook(PrometheusHook{ClientName: name})
+	}
+
+	c.healthCheck(ctx)
+	c.wg.Add(1)
+	go c.healthCheckLoop(ctx)
+	return c, nil
+}
+
+// NewMonitoredReplicaClientFromConfig creates a ReplicaClient from cfg via
+// NewMonitoredReplicaClient.
+func NewMonitoredReplicaClientFromConfig(name string, cfg ReplicaConfig) (*ReplicaClient, error) {
+	master, replicas, err := cfg.Options()
+	if err != nil {
+		return nil, err
+	}
+	return NewMonitoredReplicaClient(name, master, replicas, cfg.Routing)
+}
+
+// Process executes cmd on the target chosen by the policy when cmd is
+// read-only, or on the master otherwise.
+func (c *ReplicaClient) Process(ctx context.Context, cmd redis.Cmder) error {
+	// The error returned by Client.Process is errRoutedToReplica when cmd is
+	// routed to a replica, the actual one is set to cmd by the hook.
+	_ = c.Client.Process(ctx, cmd)
+	return cmd.Err()
+}
+
+// Do creates a redis.Cmd with args and executes it via Process.
+func (c *ReplicaClient) Do(ctx context.Context, args ...interface{}) *redis.Cmd {
+	cmd := redis.NewCmd(ctx, args...)
+	_ = c.Process(ctx, cmd)
+	return cmd
+}
+
+// Watch is the same as redis.Client.Watch on the master,
+// all the commands in the transaction go to the master.
+func (c *ReplicaClient) Watch(ctx context.Context, fn func(*redis.Tx) error, keys ...string) error {
+	return c.master.Watch(ctx, fn, keys...)
+}
+
+// Replica returns the client of the replica chosen by the policy for the next
+// read-only command.
+//
+// It returns ErrNoReplicaAvailable when there's no available replica,
+// regardless of the policy.
+func (c *ReplicaClient) Replica() (*redis.Client, error) {
+	r := c.pick()
+	if r == nil {
+		return nil, ErrNoReplicaAvailable
+	}
+	return r.client, nil
+}
+
+// Close stops the background health checks,
+// and closes the clients to the master and all the replicas.
+func (c *ReplicaClient) Close() error {
+	c.cancel()
+	c.wg.Wait()
+
+	// Client shares the connection pool with master.
+	err := c.Client.Close()
+	for _, r := range c.replicas {
+		if closeErr := r.client.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// route executes cmd on the replica chosen by the policy when cmd is
+// read-only.
+//
+// handled is false when cmd should be sent to the master instead.
+func (c *ReplicaClient) route(ctx context.Context, cmd redis.Cmder) (handled bool, err error) {
+	if !IsReadOnlyCommand(cmd.Name()) {
+		return false, nil
+	}
+
+	r := c.pick()
+	if r == nil {
+		if c.opt.Policy != ReplicaPolicyFallbackToMaster {
+			return true, ErrNoReplicaAvailable
+		}
+		c.fallbacks.Inc()
+		return false, nil
+	}
+
+	r.routed.Inc()
+	err = r.client.Process(ctx, cmd)
+	if c.opt.Policy == ReplicaPolicyFallbackToMaster && shouldFallbackToMaster(ctx, err) {
+		// Take the replica out until the next successful health check.
+		r.setAvailable(false)
+		c.fallbacks.Inc()
+		cmd.SetErr(nil)
+		return false, nil
+	}
+	return true, err
+}
+
+// errRoutedToReplica is returned by replicaRoutingHook.BeforeProcess to skip
+// executing the command on the master.
+var errRoutedToReplica = errors.New("redisbp: command routed to replica")
+
+type replicaResultContextKey struct{}
+
+// replicaResult is the result of the command routed to a replica,
+// passed from BeforeProcess to AfterProcess via the context.
+type replicaResult struct {
+	err error
+}
+
+// replicaRoutingHook is the redis.Hook routing the read-only commands of
+// ReplicaClient.Client to the replicas.
+//
+// The commands routed to the replicas are skipped on the master by returning
+// errRoutedToReplica from BeforeProcess,
+// and the actual result is set back to the commands in AfterProcess.
+type replicaRoutingHook struct {
+	c *ReplicaClient
+}
+
+var _ redis.Hook = replicaRoutingHook{}
+
+// BeforeProcess implements redis.Hook.
+func (h replicaRoutingHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	handled, err := h.c.route(ctx, cmd)
+	if !handled {
+		h.c.masterRouted.Inc()
+		return ctx, nil
+	}
+	return context.WithValue(ctx, replicaResultContextKey{}, &replicaResult{err: err}), errRoutedToReplica
+}
+
+// AfterProcess implements redis.Hook.
+func (h replicaRoutingHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if result, ok := ctx.Value(replicaResultContextKey{}).(*replicaResult); ok {
+		cmd.SetErr(result.err)
+		return result.err
+	}
+	return nil
+}
+
+// BeforeProcessPipeline implements redis.Hook.
+func (replicaRoutingHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+// AfterProcessPipeline implements redis.Hook.
+func (replicaRoutingHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+// pick returns the available replica chosen by the policy,
+// or nil if there's none.
+func (c *ReplicaClient) pick() *replica {
+	available := make([]*replica, 0, len(c.replicas))
+	for _, r := range c.replicas {
+		if r.isAvailable() {
+			available = append(available, r)
+		}
+	}
+	if len(available) == 0 {
+		return nil
+	}
+
+	if c.opt.Policy == ReplicaPolicyNearest {
+		nearest := available[0]
+		for _, r := range available[1:] {
+			if r.getLatency() < nearest.getLatency() {
+				nearest = r
+			}
+		}
+		return nearest
+	}
+	next := atomic.AddUint64(&c.next, 1)
+	return available[next%uint64(len(available))]
+}
+
+func (c *ReplicaClient) healthCheckLoop(ctx context.Context) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.opt.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.healthCheck(ctx)
+		}
+	}
+}
+
+func (c *ReplicaClient) healthCheck(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, c.opt.HealthCheckInterval)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, r := range c.replicas {
+		wg.Add(1)
+		go func(r *replica) {
+			defer wg.Done()
+			if err := r.check(ctx, c.opt.MaxStaleness); err != nil && ctx.Err() == nil {
+				log.Debugw(
+					"redisbp: replica health check failed",
+					"name", c.name,
+					"replica", r.addr,
+					"err", err,
+				)
+			}
+		}(r)
+	}
+	wg.Wait()
+}
+
+// latencySmoothing is the weight of the latest health check latency in the
+// smoothed latency used by ReplicaPolicyNearest.
+const latencySmoothing = 0.2
+
+type replica struct {
+	addr   string
+	client *redis.Client
+
+	availableFlag int32 // 1 for available, accessed atomically
+	latencyNanos  int64 // smoothed latency, accessed atomically
+
+	routed    prometheus.Counter
+	available prometheus.Gauge
+	latency   prometheus.Gauge
+	staleness prometheus.Gauge
+}
+
+func (r *replica) isAvailable() bool {
+	return atomic.LoadInt32(&r.availableFlag) == 1
+}
+
+func (r *replica) setAvailable(available bool) {
+	if available {
+		atomic.StoreInt32(&r.availableFlag, 1)
+		r.available.Set(1)
+	} else {
+		atomic.StoreInt32(&r.availableFlag, 0)
+		r.available.Set(0)
+	}
+}
+
+func (r *replica) getLatency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&r.latencyNanos))
+}
+
+func (r *replica) check(ctx context.Context, maxStaleness time.Duration) (err error) {
+	defer func() {
+		r.setAvailable(err == nil)
+	}()
+
+	start := time.Now()
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return err
+	}
+	latency := time.Since(start)
+	if old := r.getLatency(); old > 0 {
+		latency = time.Duration(latencySmoothing*float64(latency) + (1-latencySmoothing)*float64(old))
+	}
+	atomic.StoreInt64(&r.latencyNanos, int64(latency))
+	r.latency.Set(latency.Seconds())
+
+	if maxStaleness <= 0 {
+		return nil
+	}
+	info, err := r.client.Info(ctx, "replication").Result()
+	if err != nil {
+		return err
+	}
+	staleness, err := parseReplicationStaleness(info)
+	if err != nil {
+		return err
+	}
+	r.staleness.Set(staleness.Seconds())
+	if staleness > maxStaleness {
+		return fmt.Errorf("redisbp: replica staleness %v exceeds %v", staleness, maxStaleness)
+	}
+	return nil
+}
+
+// parseReplicationStaleness parses the output of "INFO replication" on a
+// replica and returns the time since it last heard from the master.
+func parseReplicationStaleness(info string) (time.Duration, error) {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(info))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if kv := strings.SplitN(line, ":", 2); len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+	if role := fields["role"]; role != "slave" {
+		return 0, fmt.Errorf("redisbp: unexpected replication role %q", role)
+	}
+	if status := fields["master_link_status"]; status != "up" {
+		return 0, fmt.Errorf("redisbp: master link is %q", status)
+	}
+	seconds, err := strconv.ParseInt(fields["master_last_io_seconds_ago"], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("redisbp: invalid master_last_io_seconds_ago: %w", err)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// shouldFallbackToMaster returns true if the error from a replica is not from
+// the Redis server or the caller, so the command could succeed on the master.
+func shouldFallbackToMaster(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || errors.Is(err, redis.Nil) {
+		return false
+	}
+	var redisErr redis.Error
+	return !errors.As(err, &redisErr)
+}
+
+// readOnlyCommands are the commands IsReadOnlyCommand returns true for.
+var readOnlyCommands = map[string]bool{
+	"bitcount":             true,
+	"bitpos":               true,
+	"dbsize":               true,
+	"dump":                 true,
+	"exists":               true,
+	"geodist":              true,
+	"geohash":              true,
+	"geopos":               true,
+	"georadius_ro":         true,
+	"georadiusbymember_ro": true,
+	"get":                  true,
+	"getbit":               true,
+	"getrange":             true,
+	"hexists":              true,
+	"hget":                 true,
+	"hgetall":              true,
+	"hkeys":                true,
+	"hlen":                 true,
+	"hmget":                true,
+	"hscan":                true,
+	"hstrlen":              true,
+	"hvals":                true,
+	"keys":                 true,
+	"lindex":               true,
+	"llen":                 true,
+	"lrange":               true,
+	"mget":                 true,
+	"pfcount":              true,
+	"pttl":                 true,
+	"randomkey":            true,
+	"scan":                 true,
+	"scard":                true,
+	"sdiff":                true,
+	"sinter":               true,
+	"sismember":            true,
+	"smembers":             true,
+	"smismember":           true,
+	"srandmember":          true,
+	"sscan":                true,
+	"strlen":               true,
+	"sunion":               true,
+	"ttl":                  true,
+	"type":                 true,
+	"xlen":                 true,
+	"xrange":               true,
+	"xrevrange":            true,
+	"zcard":                true,
+	"zcount":               true,
+	"zlexcount":            true,
+	"zmscore":              true,
+	"zrange":               true,
+	"zrangebylex":          true,
+	"zrangebyscore":        true,
+	"zrank":                true,
+	"zrevrange":            true,
+	"zrevrangebylex":       true,
+	"zrevrangebyscore":     true,
+	"zrevrank":             true,
+	"zscan":                true,
+	"zscore":               true,
+}
+
+// IsReadOnlyCommand returns true if the command (case insensitive) only reads
+// data and can be routed to the replicas by ReplicaClient.
+func IsReadOnlyCommand(name string) bool {
+	return readOnlyCommands[strings.ToLower(name)]
+}
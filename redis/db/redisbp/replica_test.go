@@ -0,0 +1,321 @@
+package redisbp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/reddit/baseplate.go/redis/db/redisbp"
+)
+
+// startReplicaServers starts the master and n replicas,
+// with "key" set to "master", "replica0", "replica1", etc. respectively.
+func startReplicaServers(t *testing.T, n int) (master *miniredis.Miniredis, replicas []*miniredis.Miniredis, addrs []string) {
+	t.Helper()
+
+	start := func(value string) *miniredis.Miniredis {
+		s, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(s.Close)
+		s.Set("key", value)
+		return s
+	}
+	master = start("master")
+	for i := 0; i < n; i++ {
+		s := start("replica" + string(rune('0'+i)))
+		replicas = append(replicas, s)
+		addrs = append(addrs, s.Addr())
+	}
+	return master, replicas, addrs
+}
+
+// newReplicaClient creates a ReplicaClient with the replicas' addresses taken
+// before any of them are closed by the tests.
+func newReplicaClient(t *testing.T, master *miniredis.Miniredis, replicas []string, opt redisbp.ReplicaOptions) *redisbp.ReplicaClient {
+	t.Helper()
+
+	replicaOptions := make([]*redis.Options, 0, len(replicas))
+	for _, addr := range replicas {
+		replicaOptions = append(replicaOptions, &redis.Options{Addr: addr, MaxRetries: -1})
+	}
+	client, err := redisbp.NewMonitoredReplicaClient(
+		"redis",
+		&redis.Options{Addr: master.Addr()},
+		replicaOptions,
+		opt,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return client
+}
+
+func TestReplicaClientRoundRobin(t *testing.T) {
+	master, replicas, addrs := startReplicaServers(t, 2)
+	client := newReplicaClient(t, master, addrs, redisbp.ReplicaOptions{})
+	ctx := context.Background()
+
+	seen := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		value, err := client.Do(ctx, "get", "key").Text()
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[value]++
+	}
+	if seen["replica0"] != 2 || seen["replica1"] != 2 {
+		t.Errorf("Expected reads to be evenly distributed across replicas, got %v", seen)
+	}
+
+	// Writes go to the master.
+	if err := client.Do(ctx, "set", "key", "new").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := master.Get("key"); value != "new" {
+		t.Errorf("Expected write to go to master, master has %q", value)
+	}
+	for _, s := range replicas {
+		if value, _ := s.Get("key"); value == "new" {
+			t.Errorf("Expected write to not go to replica %s", s.Addr())
+		}
+	}
+}
+
+func TestReplicaClientTypedCommands(t *testing.T) {
+	master, replicas, addrs := startReplicaServers(t, 1)
+	client := newReplicaClient(t, master, addrs, redisbp.ReplicaOptions{})
+	ctx := context.Background()
+
+	// Typed read-only commands are routed to the replicas.
+	if value, err := client.Get(ctx, "key").Result(); err != nil || value != "replica0" {
+		t.Errorf("Expected (%q, nil) from replica, got (%q, %v)", "replica0", value, err)
+	}
+	if _, err := client.Get(ctx, "missing").Result(); !errors.Is(err, redis.Nil) {
+		t.Errorf("Expected %v from replica, got %v", redis.Nil, err)
+	}
+
+	// Typed writes go to the master.
+	if err := client.Set(ctx, "key", "new", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := master.Get("key"); value != "new" {
+		t.Errorf("Expected write to go to master, master has %q", value)
+	}
+	if value, _ := replicas[0].Get("key"); value == "new" {
+		t.Error("Expected write to not go to the replica")
+	}
+
+	// Pipelines and transactions go to the master.
+	var pipelined *redis.StringCmd
+	if _, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipelined = pipe.Get(ctx, "key")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if value := pipelined.Val(); value != "new" {
+		t.Errorf("Expected %q from master in pipeline, got %q", "new", value)
+	}
+	if err := client.Watch(ctx, func(tx *redis.Tx) error {
+		if value, err := tx.Get(ctx, "key").Result(); err != nil || value != "new" {
+			t.Errorf("Expected (%q, nil) from master in transaction, got (%q, %v)", "new", value, err)
+		}
+		return nil
+	}, "key"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplicaClientTypedCommandsNoReplica(t *testing.T) {
+	master, replicas, addrs := startReplicaServers(t, 1)
+	replicas[0].Close()
+	client := newReplicaClient(t, master, addrs, redisbp.ReplicaOptions{})
+
+	if err := client.Get(context.Background(), "key").Err(); !errors.Is(err, redisbp.ErrNoReplicaAvailable) {
+		t.Errorf("Expected %v, got %v", redisbp.ErrNoReplicaAvailable, err)
+	}
+}
+
+func TestReplicaClientNearest(t *testing.T) {
+	master, _, addrs := startReplicaServers(t, 2)
+	client := newReplicaClient(t, master, addrs, redisbp.ReplicaOptions{
+		Policy: redisbp.ReplicaPolicyNearest,
+	})
+	ctx := context.Background()
+
+	value, err := client.Do(ctx, "get", "key").Text()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "replica0" && value != "replica1" {
+		t.Errorf("Expected read from a replica, got %q", value)
+	}
+	for i := 0; i < 3; i++ {
+		if got, _ := client.Do(ctx, "get", "key").Text(); got != value {
+			t.Errorf("Expected reads to stick to the nearest replica %q, got %q", value, got)
+		}
+	}
+}
+
+func TestReplicaClientUnavailable(t *testing.T) {
+	for _, c := range []struct {
+		policy   redisbp.ReplicaPolicy
+		expected string
+		err      error
+	}{
+		{
+			policy: redisbp.ReplicaPolicyRoundRobin,
+			err:    redisbp.ErrNoReplicaAvailable,
+		},
+		{
+			policy:   redisbp.ReplicaPolicyFallbackToMaster,
+			expected: "master",
+		},
+	} {
+		t.Run(string(c.policy), func(t *testing.T) {
+			master, replicas, addrs := startReplicaServers(t, 1)
+			replicas[0].Close()
+			client := newReplicaClient(t, master, addrs, redisbp.ReplicaOptions{
+				Policy: c.policy,
+			})
+
+			value, err := client.Do(context.Background(), "get", "key").Text()
+			if !errors.Is(err, c.err) {
+				t.Errorf("Expected error %v, got %v", c.err, err)
+			}
+			if value != c.expected {
+				t.Errorf("Expected %q, got %q", c.expected, value)
+			}
+			if _, err := client.Replica(); !errors.Is(err, redisbp.ErrNoReplicaAvailable) {
+				t.Errorf("Expected ErrNoReplicaAvailable from Replica, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReplicaClientFallbackOnError(t *testing.T) {
+	master, replicas, addrs := startReplicaServers(t, 1)
+	client := newReplicaClient(t, master, addrs, redisbp.ReplicaOptions{
+		Policy:              redisbp.ReplicaPolicyFallbackToMaster,
+		HealthCheckInterval: time.Hour,
+	})
+	ctx := context.Background()
+
+	if value, _ := client.Do(ctx, "get", "key").Text(); value != "replica0" {
+		t.Errorf("Expected read from replica, got %q", value)
+	}
+	// Redis errors are not retried on the master.
+	replicas[0].SetError("ERR foo")
+	if err := client.Do(ctx, "get", "key").Err(); err == nil {
+		t.Error("Expected redis error from replica")
+	}
+	replicas[0].SetError("")
+
+	// Network errors are.
+	replicas[0].Close()
+	value, err := client.Do(ctx, "get", "key").Text()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "master" {
+		t.Errorf("Expected fallback to master, got %q", value)
+	}
+}
+
+func TestReplicaClientRecovery(t *testing.T) {
+	master, replicas, addrs := startReplicaServers(t, 1)
+	replicas[0].Close()
+	client := newReplicaClient(t, master, addrs, redisbp.ReplicaOptions{
+		HealthCheckInterval: time.Millisecond * 10,
+	})
+	ctx := context.Background()
+
+	if err := client.Do(ctx, "get", "key").Err(); !errors.Is(err, redisbp.ErrNoReplicaAvailable) {
+		t.Fatalf("Expected ErrNoReplicaAvailable, got %v", err)
+	}
+	if err := replicas[0].StartAddr(addrs[0]); err != nil {
+		t.Fatal(err)
+	}
+	replicas[0].Set("key", "replica0")
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		value, err := client.Do(ctx, "get", "key").Text()
+		if err == nil {
+			if value != "replica0" {
+				t.Errorf("Expected read from recovered replica, got %q", value)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Replica did not recover: %v", err)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestReplicaClientMaxStaleness(t *testing.T) {
+	// miniredis doesn't report itself as a replica in "INFO replication",
+	// so the staleness can't be determined and the replica is unavailable.
+	master, _, addrs := startReplicaServers(t, 1)
+	client := newReplicaClient(t, master, addrs, redisbp.ReplicaOptions{
+		Policy:       redisbp.ReplicaPolicyFallbackToMaster,
+		MaxStaleness: time.Second,
+	})
+
+	value, err := client.Do(context.Background(), "get", "key").Text()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "master" {
+		t.Errorf("Expected read from master, got %q", value)
+	}
+}
+
+func TestReplicaOptionsValidate(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		opt   redisbp.ReplicaOptions
+		valid bool
+	}{
+		{name: "default", valid: true},
+		{name: "nearest", opt: redisbp.ReplicaOptions{Policy: redisbp.ReplicaPolicyNearest}, valid: true},
+		{name: "unknown-policy", opt: redisbp.ReplicaOptions{Policy: "random"}},
+		{name: "negative-staleness", opt: redisbp.ReplicaOptions{MaxStaleness: -time.Second}},
+		{name: "negative-interval", opt: redisbp.ReplicaOptions{HealthCheckInterval: -time.Second}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.opt.Validate()
+			if c.valid && err != nil {
+				t.Errorf("Expected valid, got %v", err)
+			}
+			if !c.valid && err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestIsReadOnlyCommand(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"get":     true,
+		"GET":     true,
+		"hgetall": true,
+		"set":     false,
+		"eval":    false,
+		"incr":    false,
+	} {
+		if got := redisbp.IsReadOnlyCommand(name); got != expected {
+			t.Errorf("IsReadOnlyCommand(%q) expected %v, got %v", name, expected, got)
+		}
+	}
+}
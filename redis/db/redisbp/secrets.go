@@ -0,0 +1,331 @@
+package redisbp
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/reddit/baseplate.go/internal/clientsecrets"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+// SecretsConfig configures the Redis credentials and TLS material to be read
+// from the secrets Store,
+// by NewMonitoredClientWithSecrets and NewMonitoredClusterClientWithSecrets.
+//
+// When the secrets rotate in the Store, the new connections use the new
+// secrets without the need to rebuild the client.
+// When the credentials rotate, the existing connections are also closed on
+// their next use, and the commands are retried on new connections
+// (unless the retries are disabled, in which case the commands fail once per
+// existing connection).
+// The existing connections are not affected by the TLS material rotations,
+// they are replaced by new ones when closed by the server or on
+// Pool.MaxConnectionAge.
+//
+// All the fields are paths of the secrets in the Store, and are optional.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//	redis:
+//	 url: rediss://redis.example.com:6379
+//	 secrets:
+//	  credentials: secret/myservice/redis-credentials
+//	  caCert: secret/myservice/redis-ca
+//	  clientCert: secret/myservice/redis-client-cert
+//	  clientKey: secret/myservice/redis-client-key
+type SecretsConfig struct {
+	// Credentials is the path of a credential secret used to AUTH.
+	//
+	// When the username is empty, only the password is used (pre Redis 6 AUTH).
+	Credentials string `yaml:"credentials"`
+
+	// CACert is the path of a simple secret containing the PEM encoded CA
+	// certificates used to verify the server.
+	//
+	// Optional, the system CA pool is used when it's empty.
+	CACert string `yaml:"caCert"`
+
+	// ClientCert and ClientKey are the paths of the simple secrets containing
+	// the PEM encoded client certificate and key for mutual TLS.
+	//
+	// They must be both set or both empty.
+	ClientCert string `yaml:"clientCert"`
+	ClientKey  string `yaml:"clientKey"`
+}
+
+// IsEmpty returns true if none of the secrets are configured.
+func (cfg SecretsConfig) IsEmpty() bool {
+	return cfg == SecretsConfig{}
+}
+
+func (cfg SecretsConfig) clientSecrets() clientsecrets.Config {
+	return clientsecrets.Config{
+		Credentials: cfg.Credentials,
+		CACert:      cfg.CACert,
+		ClientCert:  cfg.ClientCert,
+		ClientKey:   cfg.ClientKey,
+	}
+}
+
+// Validate checks SecretsConfig for any erroneous values.
+func (cfg SecretsConfig) Validate() error {
+	return cfg.clientSecrets().Validate("redisbp")
+}
+
+// secretsProvider applies the latest secrets from the secrets Store to the new
+// connections.
+type secretsProvider struct {
+	*clientsecrets.Provider
+
+	cfg SecretsConfig
+
+	// generation is bumped on every credentials rotation, accessed atomically.
+	generation  uint64
+	unsubscribe func()
+}
+
+// newSecretsProvider loads the secrets from store,
+// and registers a middleware to the store to reload them on rotation.
+func newSecretsProvider(store *secrets.Store, cfg SecretsConfig) (*secretsProvider, error) {
+	provider, err := clientsecrets.New("redisbp", store, cfg.clientSecrets())
+	if err != nil {
+		return nil, err
+	}
+	p := &secretsProvider{
+		Provider: provider,
+		cfg:      cfg,
+	}
+	if cfg.Credentials != "" {
+		p.unsubscribe = store.Subscribe(func(old, new *secrets.Secrets) {
+			for _, path := range secrets.ChangedPaths(old, new) {
+				if path == cfg.Credentials {
+					atomic.AddUint64(&p.generation, 1)
+					return
+				}
+			}
+		})
+	}
+	return p, nil
+}
+
+// Close stops reloading the secrets on rotation.
+func (p *secretsProvider) Close() error {
+	if p.unsubscribe != nil {
+		p.unsubscribe()
+	}
+	return p.Provider.Close()
+}
+
+// onConnect returns the OnConnect function to AUTH (and SELECT db, as SELECT
+// requires AUTH first) the new connections with the latest credentials,
+// followed by next if it's non-nil.
+func (p *secretsProvider) onConnect(db int, next func(ctx context.Context, cn *redis.Conn) error) func(ctx context.Context, cn *redis.Conn) error {
+	return func(ctx context.Context, cn *redis.Conn) error {
+		username, password := p.Credentials()
+		if password != "" {
+			var err error
+			if username != "" {
+				err = cn.AuthACL(ctx, username, password).Err()
+			} else {
+				err = cn.Auth(ctx, password).Err()
+			}
+			if err != nil {
+				return fmt.Errorf("redisbp: failed to auth: %w", err)
+			}
+		}
+		if db > 0 {
+			if err := cn.Select(ctx, db).Err(); err != nil {
+				return fmt.Errorf("redisbp: failed to select db %d: %w", db, err)
+			}
+		}
+		if next != nil {
+			return next(ctx, cn)
+		}
+		return nil
+	}
+}
+
+// redisDialer is the type of redis.Options.Dialer.
+type redisDialer = func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dialer returns the Dialer wrapping the connections dialed by next,
+// or the default dialer of go-redis when next is nil,
+// to close them on their next use after the credentials rotate.
+func (p *secretsProvider) dialer(next redisDialer, dialTimeout time.Duration, tlsConfig *tls.Config) redisDialer {
+	if next == nil {
+		// Same as the default dialer of go-redis.
+		if dialTimeout == 0 {
+			dialTimeout = time.Second * 5
+		}
+		next = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			netDialer := &net.Dialer{
+				Timeout:   dialTimeout,
+				KeepAlive: time.Minute * 5,
+			}
+			if tlsConfig == nil {
+				return netDialer.DialContext(ctx, network, addr)
+			}
+			return tls.DialWithDialer(netDialer, network, addr, tlsConfig)
+		}
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		generation := atomic.LoadUint64(&p.generation)
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &rotatingConn{
+			Conn:       conn,
+			provider:   p,
+			generation: generation,
+		}, nil
+	}
+}
+
+// rotatingConn is a connection authenticated with the credentials of
+// generation.
+type rotatingConn struct {
+	net.Conn
+
+	provider   *secretsProvider
+	generation uint64
+}
+
+// Write closes the connection and returns io.EOF before writing anything when
+// the credentials rotated after the connection was dialed,
+// so that go-redis drops it and retries the command on a new one.
+func (c *rotatingConn) Write(b []byte) (int, error) {
+	if atomic.LoadUint64(&c.provider.generation) != c.generation {
+		c.Conn.Close()
+		return 0, io.EOF
+	}
+	return c.Conn.Write(b)
+}
+
+// applyOptions applies the secrets to the redis.Options.
+func (p *secretsProvider) applyOptions(options *redis.Options) {
+	if p.cfg.clientSecrets().HasTLS() {
+		options.TLSConfig = p.TLSConfig(options.TLSConfig)
+	}
+	if p.cfg.Credentials != "" {
+		options.Username = ""
+		options.Password = ""
+		options.OnConnect = p.onConnect(options.DB, options.OnConnect)
+		options.DB = 0
+		options.Dialer = p.dialer(options.Dialer, options.DialTimeout, options.TLSConfig)
+	}
+}
+
+// applyClusterOptions applies the secrets to the redis.ClusterOptions.
+func (p *secretsProvider) applyClusterOptions(options *redis.ClusterOptions) {
+	if p.cfg.clientSecrets().HasTLS() {
+		options.TLSConfig = p.TLSConfig(options.TLSConfig)
+	}
+	if p.cfg.Credentials != "" {
+		options.Username = ""
+		options.Password = ""
+		options.OnConnect = p.onConnect(0, options.OnConnect)
+		options.Dialer = p.dialer(options.Dialer, options.DialTimeout, options.TLSConfig)
+	}
+}
+
+// SecretsClient is the *redis.Client created by NewMonitoredClientWithSecrets.
+type SecretsClient struct {
+	*redis.Client
+
+	secrets io.Closer
+}
+
+// Close closes the client, and stops reloading the secrets on rotation.
+//
+// It must not be called from within a middleware of the secrets Store used to
+// create the client.
+func (c *SecretsClient) Close() error {
+	err := c.Client.Close()
+	c.secrets.Close()
+	return err
+}
+
+// NewMonitoredClientWithSecrets creates a client via NewMonitoredClient,
+// with the options from cfg and the secrets configured by cfg.Secrets
+// (see SecretsConfig) read from store.
+//
+// It calls store.AddMiddleware, so it must not be called from within a
+// middleware of the same store.
+func NewMonitoredClientWithSecrets(name string, cfg ClientConfig, store *secrets.Store) (*SecretsClient, error) {
+	options, err := cfg.Options()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Secrets.IsEmpty() {
+		return &SecretsClient{
+			Client:  NewMonitoredClient(name, options),
+			secrets: clientsecrets.NopCloser,
+		}, nil
+	}
+	p, err := newSecretsProvider(store, cfg.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	p.applyOptions(options)
+	return &SecretsClient{
+		Client:  NewMonitoredClient(name, options),
+		secrets: p,
+	}, nil
+}
+
+// SecretsClusterClient is the *ClusterClient created by
+// NewMonitoredClusterClientWithSecrets.
+type SecretsClusterClient struct {
+	*ClusterClient
+
+	secrets io.Closer
+}
+
+// Close closes the client, and stops reloading the secrets on rotation.
+//
+// It must not be called from within a middleware of the secrets Store used to
+// create the client.
+func (c *SecretsClusterClient) Close() error {
+	err := c.ClusterClient.Close()
+	c.secrets.Close()
+	return err
+}
+
+// NewMonitoredClusterClientWithSecrets creates a client via
+// NewMonitoredClusterClient,
+// with the options from cfg and the secrets configured by cfg.Secrets
+// (see SecretsConfig) read from store.
+//
+// It calls store.AddMiddleware, so it must not be called from within a
+// middleware of the same store.
+//
+// The client must not have ReadOnly set,
+// as READONLY command requires AUTH first.
+func NewMonitoredClusterClientWithSecrets(name string, cfg ClusterConfig, store *secrets.Store) (*SecretsClusterClient, error) {
+	options := cfg.Options()
+	if cfg.Secrets.IsEmpty() {
+		return &SecretsClusterClient{
+			ClusterClient: NewMonitoredClusterClient(name, options),
+			secrets:       clientsecrets.NopCloser,
+		}, nil
+	}
+	p, err := newSecretsProvider(store, cfg.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	p.applyClusterOptions(options)
+	return &SecretsClusterClient{
+		ClusterClient: NewMonitoredClusterClient(name, options),
+		secrets:       p,
+	}, nil
+}
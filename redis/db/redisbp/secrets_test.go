@@ -0,0 +1,222 @@
+package redisbp_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/reddit/baseplate.go/redis/db/redisbp"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+const (
+	credentialsPath = "secret/redis/credentials"
+	caCertPath      = "secret/redis/ca"
+)
+
+func credentialSecret(username, password string) secrets.GenericSecret {
+	return secrets.GenericSecret{
+		Type:     "credential",
+		Username: username,
+		Password: password,
+	}
+}
+
+func TestClientWithSecretsCredentialsRotation(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.RequireUserAuth("user", "old")
+
+	store, err := secrets.NewTestStore(map[string]secrets.GenericSecret{
+		credentialsPath: credentialSecret("user", "old"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	cfg := redisbp.ClientConfig{
+		URL: "redis://" + s.Addr() + "/1",
+		Secrets: redisbp.SecretsConfig{
+			Credentials: credentialsPath,
+		},
+	}
+	client, err := redisbp.NewMonitoredClientWithSecrets("redis", cfg, store.Store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.Set(ctx, "key", "value", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	s.Select(1)
+	if !s.Exists("key") {
+		t.Error("Expected the key to be set in db 1")
+	}
+	connections := s.TotalConnectionCount()
+
+	// Changing the other secrets doesn't affect the existing connections.
+	if err := store.Set(caCertPath, secrets.GenericSecret{Type: "simple", Value: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.TotalConnectionCount(); got != connections {
+		t.Errorf("Expected the existing connection to be reused, got %d new connections", got-connections)
+	}
+
+	// The existing connection is replaced by a new one with the rotated
+	// credentials.
+	s.RequireUserAuth("user", "new")
+	if err := store.Set(credentialsPath, credentialSecret("user", "new")); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Errorf("Expected the rotated credentials to be used, got %v", err)
+	}
+	if got := s.TotalConnectionCount(); got != connections+1 {
+		t.Errorf("Expected 1 new connection after the rotation, got %d", got-connections)
+	}
+	if got := s.CurrentConnectionCount(); got != 1 {
+		t.Errorf("Expected the old connection to be closed, got %d connections", got)
+	}
+
+	// Broken rotations are ignored.
+	if err := store.Delete(credentialsPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Errorf("Expected the old credentials to be kept, got %v", err)
+	}
+}
+
+func TestClientWithSecretsErrors(t *testing.T) {
+	store, err := secrets.NewTestStore(map[string]secrets.GenericSecret{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for _, c := range []struct {
+		label string
+		cfg   redisbp.SecretsConfig
+	}{
+		{
+			label: "missing-credentials",
+			cfg:   redisbp.SecretsConfig{Credentials: credentialsPath},
+		},
+		{
+			label: "missing-ca",
+			cfg:   redisbp.SecretsConfig{CACert: caCertPath},
+		},
+		{
+			label: "cert-without-key",
+			cfg:   redisbp.SecretsConfig{ClientCert: "secret/redis/cert"},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			cfg := redisbp.ClientConfig{
+				URL:     "redis://localhost:6379",
+				Secrets: c.cfg,
+			}
+			if _, err := redisbp.NewMonitoredClientWithSecrets("redis", cfg, store.Store); err == nil {
+				t.Error("Expected error, got nil")
+			}
+			clusterCfg := redisbp.ClusterConfig{
+				Addrs:   []string{"localhost:6379"},
+				Secrets: c.cfg,
+			}
+			if _, err := redisbp.NewMonitoredClusterClientWithSecrets("redis", clusterCfg, store.Store); err == nil {
+				t.Error("Expected error from cluster config, got nil")
+			}
+		})
+	}
+}
+
+// generateCert generates a self-signed certificate for 127.0.0.1,
+// returning the tls.Certificate and its PEM encoding.
+func generateCert(t *testing.T) (tls.Certificate, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "redis"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestClientWithSecretsTLS(t *testing.T) {
+	cert, certPEM := generateCert(t)
+	s, err := miniredis.RunTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	_, otherPEM := generateCert(t)
+	store, err := secrets.NewTestStore(map[string]secrets.GenericSecret{
+		caCertPath: {Type: "simple", Value: otherPEM},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	cfg := redisbp.ClientConfig{
+		URL: "rediss://" + s.Addr(),
+		Secrets: redisbp.SecretsConfig{
+			CACert: caCertPath,
+		},
+	}
+	client, err := redisbp.NewMonitoredClientWithSecrets("redis", cfg, store.Store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.Ping(ctx).Err(); err == nil {
+		t.Error("Expected verification error with the wrong CA, got nil")
+	}
+
+	if err := store.Set(caCertPath, secrets.GenericSecret{Type: "simple", Value: certPEM}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Errorf("Expected the rotated CA to be used, got %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package retrybp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/avast/retry-go"
+
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+// Default values of RetryBudgetConfig.
+const (
+	DefaultBudgetRatio               = 0.2
+	DefaultBudgetMinRetriesPerSecond = 10
+	DefaultBudgetWindow              = time.Second * 10
+)
+
+// budgetSlots is the number of slots the window of a RetryBudget is divided
+// into.
+const budgetSlots = 10
+
+// RetryBudgetConfig is the config of a RetryBudget.
+//
+// Can be deserialized from YAML.
+type RetryBudgetConfig struct {
+	// Name of the budget, used as the label of the metrics.
+	Name string `yaml:"name"`
+
+	// Optional. The max ratio of the retries to the requests in Window,
+	// e.g. 0.2 allows 1 retry for every 5 requests.
+	// Defaults to DefaultBudgetRatio.
+	Ratio float64 `yaml:"ratio"`
+
+	// Optional. The retries allowed regardless of Ratio,
+	// so that the clients sending few requests can still retry.
+	// Defaults to DefaultBudgetMinRetriesPerSecond.
+	//
+	// Set it to a negative value to only allow the retries within Ratio.
+	MinRetriesPerSecond float64 `yaml:"minRetriesPerSecond"`
+
+	// Optional. The sliding window the requests and retries are counted in.
+	// Defaults to DefaultBudgetWindow.
+	Window time.Duration `yaml:"window"`
+
+	// Optional. The Clock used to decide the current slot in Window,
+	// nil means timebp.SystemClock.
+	Clock timebp.Clock `yaml:"-"`
+}
+
+// RetryBudget limits the retries of all the Do calls sharing it to a ratio of
+// the requests (the calls) in the process,
+// so that the retries can't multiply the load on a downstream that is already
+// struggling.
+//
+// A RetryBudget is usually shared by all the call sites calling the same
+// downstream, via its Filters option.
+type RetryBudget struct {
+	cfg      RetryBudgetConfig
+	slotSize time.Duration
+	now      func() time.Time
+
+	lock     sync.Mutex
+	slots    [budgetSlots]budgetSlot
+	requests int64
+	retries  int64
+
+	metrics budgetMetrics
+}
+
+type budgetSlot struct {
+	// The index of the slot since the epoch,
+	// used to detect the slots left from the previous windows.
+	index    int64
+	requests int64
+	retries  int64
+}
+
+// NewRetryBudget creates a RetryBudget.
+func NewRetryBudget(cfg RetryBudgetConfig) *RetryBudget {
+	if cfg.Ratio <= 0 {
+		cfg.Ratio = DefaultBudgetRatio
+	}
+	if cfg.MinRetriesPerSecond == 0 {
+		cfg.MinRetriesPerSecond = DefaultBudgetMinRetriesPerSecond
+	}
+	if cfg.MinRetriesPerSecond < 0 {
+		cfg.MinRetriesPerSecond = 0
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultBudgetWindow
+	}
+	return &RetryBudget{
+		cfg:      cfg,
+		slotSize: cfg.Window / budgetSlots,
+		now:      timebp.ClockOrDefault(cfg.Clock).Now,
+		metrics:  newBudgetMetrics(cfg.Name),
+	}
+}
+
+// Filters is the same as retrybp.Filters,
+// but the retries are also limited by the budget.
+//
+// Each Do call using the returned option counts as a request,
+// and each retry the filters decided on is only made when the budget allows.
+func (b *RetryBudget) Filters(filters ...Filter) retry.Option {
+	retryIf := filtersRetryIf(filters)
+	return func(c *retry.Config) {
+		// The option is applied exactly once for each retry.Do call.
+		b.Deposit()
+		retry.RetryIf(func(err error) bool {
+			return retryIf(err) && b.TryWithdraw()
+		})(c)
+	}
+}
+
+// Deposit records a request.
+//
+// It's called by the option returned by Filters,
+// and only needs to be called directly when not using Filters.
+func (b *RetryBudget) Deposit() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.slot().requests++
+	b.requests++
+	b.metrics.requests.Inc()
+}
+
+// TryWithdraw returns true and records a retry if the budget allows one more
+// retry.
+//
+// It's called by the option returned by Filters,
+// and only needs to be called directly when not using Filters.
+func (b *RetryBudget) TryWithdraw() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	slot := b.slot()
+	allowed := float64(b.requests)*b.cfg.Ratio + b.cfg.MinRetriesPerSecond*b.cfg.Window.Seconds()
+	if float64(b.retries)+1 > allowed {
+		b.metrics.exhausted.Inc()
+		return false
+	}
+	slot.retries++
+	b.retries++
+	b.metrics.retries.Inc()
+	return true
+}
+
+// slot returns the slot of the current time,
+// after expiring the slots out of the window.
+//
+// It must be called with the lock held.
+func (b *RetryBudget) slot() *budgetSlot {
+	index := b.now().UnixNano() / int64(b.slotSize)
+	for i := range b.slots {
+		slot := &b.slots[i]
+		if slot.index <= index-budgetSlots {
+			b.requests -= slot.requests
+			b.retries -= slot.retries
+			*slot = budgetSlot{}
+		}
+	}
+	slot := &b.slots[index%budgetSlots]
+	slot.index = index
+	return slot
+}
@@ -0,0 +1,147 @@
+package retrybp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newTestBudget(cfg RetryBudgetConfig) (*RetryBudget, *time.Time) {
+	now := time.Unix(1000, 0)
+	b := NewRetryBudget(cfg)
+	b.now = func() time.Time {
+		return now
+	}
+	return b, &now
+}
+
+func TestRetryBudgetRatio(t *testing.T) {
+	b, _ := newTestBudget(RetryBudgetConfig{
+		Name:                "ratio",
+		Ratio:               0.5,
+		MinRetriesPerSecond: -1,
+	})
+
+	if b.TryWithdraw() {
+		t.Error("Expected no retries allowed without requests")
+	}
+	for i := 0; i < 4; i++ {
+		b.Deposit()
+	}
+	for i := 0; i < 2; i++ {
+		if !b.TryWithdraw() {
+			t.Errorf("Expected retry #%d to be allowed", i)
+		}
+	}
+	if b.TryWithdraw() {
+		t.Error("Expected the 3rd retry to be rejected")
+	}
+}
+
+func TestRetryBudgetMinRetries(t *testing.T) {
+	b, _ := newTestBudget(RetryBudgetConfig{
+		Name:                "min",
+		Ratio:               0.1,
+		MinRetriesPerSecond: 1,
+		Window:              time.Second * 2,
+	})
+
+	for i := 0; i < 2; i++ {
+		if !b.TryWithdraw() {
+			t.Errorf("Expected retry #%d to be allowed", i)
+		}
+	}
+	if b.TryWithdraw() {
+		t.Error("Expected the 3rd retry to be rejected")
+	}
+}
+
+func TestRetryBudgetWindow(t *testing.T) {
+	b, now := newTestBudget(RetryBudgetConfig{
+		Name:                "window",
+		Ratio:               1,
+		MinRetriesPerSecond: -1,
+		Window:              time.Second * 10,
+	})
+
+	b.Deposit()
+	if !b.TryWithdraw() {
+		t.Fatal("Expected the retry to be allowed")
+	}
+	if b.TryWithdraw() {
+		t.Fatal("Expected the retry to be rejected")
+	}
+
+	*now = now.Add(time.Second * 5)
+	b.Deposit()
+	*now = now.Add(time.Second * 5)
+	// The first request and retry slid out of the window,
+	// the second request is still in it.
+	if !b.TryWithdraw() {
+		t.Error("Expected the retry to be allowed after the window slid")
+	}
+	if b.TryWithdraw() {
+		t.Error("Expected the retry to be rejected")
+	}
+
+	*now = now.Add(time.Second * 10)
+	if b.TryWithdraw() {
+		t.Error("Expected the retry to be rejected after all requests slid out")
+	}
+}
+
+func TestRetryBudgetFilters(t *testing.T) {
+	const name = "filters"
+	b, _ := newTestBudget(RetryBudgetConfig{
+		Name:                name,
+		Ratio:               0.5,
+		MinRetriesPerSecond: -1,
+	})
+	alwaysRetry := func(_ error, _ retry.RetryIfFunc) bool {
+		return true
+	}
+	exhausted := budgetExhaustedCounter.WithLabelValues(name)
+	before := testutil.ToFloat64(exhausted)
+
+	var calls int
+	err := Do(
+		context.Background(),
+		func() error {
+			calls++
+			return errors.New("error")
+		},
+		retry.Attempts(5),
+		retry.Delay(0),
+		b.Filters(alwaysRetry),
+	)
+	if err == nil {
+		t.Error("Expected error, got nil")
+	}
+	// The request deposited half a retry, which isn't enough for one.
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+
+	calls = 0
+	Do(
+		context.Background(),
+		func() error {
+			calls++
+			return errors.New("error")
+		},
+		retry.Attempts(5),
+		retry.Delay(0),
+		b.Filters(alwaysRetry),
+	)
+	if calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+
+	if diff := testutil.ToFloat64(exhausted) - before; diff != 2 {
+		t.Errorf("Expected exhausted counter to increase by 2, got %v", diff)
+	}
+}
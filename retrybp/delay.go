@@ -54,6 +54,13 @@ type CappedExponentialBackoffArgs struct {
 	// If <=0, no random jitter will be added.
 	MaxJitter time.Duration
 
+	// When FullJitter is set to true,
+	// the delay before MaxJitter is a random value between 0 and the capped
+	// exponential delay ("full jitter"),
+	// so that the retries of the clients failed at the same time are spread
+	// out instead of synchronized.
+	FullJitter bool
+
 	// When IgnoreRetryAfterError is set to false (default),
 	// and the error caused the retry implements RetryAfterError,
 	// and the returned RetryAfterDuration > 0,
@@ -96,6 +103,9 @@ func cappedExponentialBackoffFunc(args CappedExponentialBackoffArgs) retry.Delay
 		if args.MaxDelay > 0 && delay > uint64(args.MaxDelay) {
 			delay = uint64(args.MaxDelay)
 		}
+		if args.FullJitter && delay > 0 {
+			delay = uint64(randbp.R.Int63n(int64(delay) + 1))
+		}
 
 		var rae RetryAfterError
 		if !args.IgnoreRetryAfterError && errors.As(err, &rae) {
@@ -146,3 +156,148 @@ func FixedDelayFunc(delay time.Duration) retry.DelayTypeFunc {
 		return delay
 	}
 }
+
+// DecorrelatedJitterBackoffArgs defines the args used in
+// DecorrelatedJitterBackoff retry option.
+//
+// All args are optional.
+type DecorrelatedJitterBackoffArgs struct {
+	// The initial (and the min) delay.
+	// If <=0, retry.DefaultDelay will be used.
+	// If retry.DefaultDelay <= 0, 1 nanosecond will be used.
+	InitialDelay time.Duration
+
+	// The cap of the delays. If <=0, the delays are only capped to not overflow.
+	MaxDelay time.Duration
+
+	// Same as CappedExponentialBackoffArgs.IgnoreRetryAfterError.
+	IgnoreRetryAfterError bool
+}
+
+// DecorrelatedJitterBackoff is a delay option implementing the "decorrelated
+// jitter" backoff from the AWS architecture blog
+// (https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/):
+//
+//     delay = min(MaxDelay, random_between(InitialDelay, previous_delay * 3))
+//
+// The delays grow roughly exponentially,
+// but unlike the exponential backoff with a bounded jitter,
+// the retries of the clients failed at the same time don't stay synchronized.
+//
+// The previous delay is tracked per retrybp.Do (or retry.Do) call.
+func DecorrelatedJitterBackoff(args DecorrelatedJitterBackoffArgs) retry.Option {
+	return func(c *retry.Config) {
+		// Create the stateful delay func for each call.
+		retry.DelayType(decorrelatedJitterBackoffFunc(args))(c)
+	}
+}
+
+func decorrelatedJitterBackoffFunc(args DecorrelatedJitterBackoffArgs) retry.DelayTypeFunc {
+	base := positiveDelay(args.InitialDelay)
+	maxDelay := args.MaxDelay
+	if maxDelay <= 0 || maxDelay > math.MaxInt64/3 {
+		// Make sure previous*3 never overflows.
+		maxDelay = math.MaxInt64 / 3
+	}
+	if base > maxDelay {
+		base = maxDelay
+	}
+
+	previous := base
+	return func(_ uint, err error, _ *retry.Config) time.Duration {
+		delay := base
+		if upper := previous * 3; upper > base {
+			delay += time.Duration(randbp.R.Int63n(int64(upper - base)))
+		}
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+		previous = delay
+		return retryAfterDelay(delay, err, args.IgnoreRetryAfterError)
+	}
+}
+
+// FibonacciBackoffArgs defines the args used in FibonacciBackoff retry option.
+//
+// All args are optional.
+type FibonacciBackoffArgs struct {
+	// The delay of the first retry.
+	// If <=0, retry.DefaultDelay will be used.
+	// If retry.DefaultDelay <= 0, 1 nanosecond will be used.
+	InitialDelay time.Duration
+
+	// The cap of the delays before MaxJitter.
+	// If <=0, the delays are only capped to not overflow.
+	MaxDelay time.Duration
+
+	// Max random jitter to be added to each retry delay.
+	// If <=0, no random jitter will be added.
+	MaxJitter time.Duration
+
+	// Same as CappedExponentialBackoffArgs.IgnoreRetryAfterError.
+	IgnoreRetryAfterError bool
+}
+
+// FibonacciBackoff is a delay option with the delays growing by the Fibonacci
+// sequence (InitialDelay * 1, 1, 2, 3, 5, 8, ...),
+// which grows slower than the exponential backoff.
+func FibonacciBackoff(args FibonacciBackoffArgs) retry.Option {
+	return retry.DelayType(fibonacciBackoffFunc(args))
+}
+
+func fibonacciBackoffFunc(args FibonacciBackoffArgs) retry.DelayTypeFunc {
+	base := positiveDelay(args.InitialDelay)
+	maxDelay := args.MaxDelay
+	if maxDelay <= 0 || maxDelay > math.MaxInt64-args.MaxJitter {
+		maxDelay = math.MaxInt64
+		if args.MaxJitter > 0 {
+			maxDelay -= args.MaxJitter
+		}
+	}
+
+	return func(n uint, err error, _ *retry.Config) time.Duration {
+		// Multiply by the (n+1)th Fibonacci number, stopping at maxDelay.
+		a, b := time.Duration(0), time.Duration(1)
+		delay := base
+		for i := uint(0); i < n && delay < maxDelay; i++ {
+			a, b = b, a+b
+			if b > maxDelay/base {
+				delay = maxDelay
+				break
+			}
+			delay = base * b
+		}
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+		delay = retryAfterDelay(delay, err, args.IgnoreRetryAfterError)
+		if args.MaxJitter > 0 && delay <= math.MaxInt64-args.MaxJitter {
+			delay += time.Duration(randbp.R.Int63n(int64(args.MaxJitter)))
+		}
+		return delay
+	}
+}
+
+// positiveDelay returns delay if it's positive,
+// otherwise retry.DefaultDelay, or 1 nanosecond if that's not positive either.
+func positiveDelay(delay time.Duration) time.Duration {
+	if delay <= 0 {
+		delay = retry.DefaultDelay
+	}
+	if delay <= 0 {
+		delay = 1
+	}
+	return delay
+}
+
+// retryAfterDelay returns the retry-after duration of err if it's longer than
+// delay, unless ignored.
+func retryAfterDelay(delay time.Duration, err error, ignore bool) time.Duration {
+	var rae RetryAfterError
+	if !ignore && errors.As(err, &rae) {
+		if minDelay := rae.RetryAfterDuration(); minDelay > delay {
+			return minDelay
+		}
+	}
+	return delay
+}
@@ -102,6 +102,7 @@ func TestCappedExponentialBackoff(t *testing.T) {
 		maxDelay    time.Duration
 		maxExponent int
 		maxJitter   time.Duration
+		fullJitter  bool
 		err         error
 		// The range of the expected result
 		min, max time.Duration
@@ -191,6 +192,32 @@ func TestCappedExponentialBackoff(t *testing.T) {
 			min:     time.Millisecond,
 			max:     time.Millisecond,
 		},
+		{
+			label:      "full-jitter",
+			n:          3,
+			initial:    time.Millisecond,
+			fullJitter: true,
+			min:        0,
+			max:        8 * time.Millisecond,
+		},
+		{
+			label:      "full-jitter-max-delay",
+			n:          9999,
+			initial:    time.Millisecond,
+			maxDelay:   time.Second,
+			fullJitter: true,
+			min:        0,
+			max:        time.Second,
+		},
+		{
+			label:      "full-jitter-retry-after",
+			n:          3,
+			initial:    time.Millisecond,
+			fullJitter: true,
+			err:        retryAfterError(time.Second),
+			min:        time.Second,
+			max:        time.Second,
+		},
 		{
 			label:   "negative-retry-after",
 			n:       0,
@@ -208,6 +235,7 @@ func TestCappedExponentialBackoff(t *testing.T) {
 					MaxDelay:     c.maxDelay,
 					MaxExponent:  c.maxExponent,
 					MaxJitter:    c.maxJitter,
+					FullJitter:   c.fullJitter,
 				})(c.n, c.err, nil)
 				if delay < c.min || delay > c.max {
 					t.Errorf("Delay %v not in range [%v, %v]", delay, c.min, c.max)
@@ -236,3 +264,116 @@ func TestCappedExponentialBackoffQuick(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestDecorrelatedJitterBackoff(t *testing.T) {
+	const (
+		initial  = time.Millisecond
+		maxDelay = time.Second
+	)
+	delayFunc := decorrelatedJitterBackoffFunc(DecorrelatedJitterBackoffArgs{
+		InitialDelay: initial,
+		MaxDelay:     maxDelay,
+	})
+	previous := initial
+	for n := uint(0); n < 100; n++ {
+		delay := delayFunc(n, nil, nil)
+		upper := previous * 3
+		if upper > maxDelay {
+			upper = maxDelay
+		}
+		if delay < initial || delay > upper {
+			t.Fatalf("Delay #%d %v not in range [%v, %v]", n, delay, initial, upper)
+		}
+		previous = delay
+	}
+
+	delay := delayFunc(0, retryAfterError(time.Minute), nil)
+	if delay != time.Minute {
+		t.Errorf("Expected retry-after delay %v, got %v", time.Minute, delay)
+	}
+}
+
+func TestDecorrelatedJitterBackoffQuick(t *testing.T) {
+	delayFunc := decorrelatedJitterBackoffFunc(DecorrelatedJitterBackoffArgs{
+		InitialDelay: time.Duration(math.MaxInt64),
+	})
+	f := func() bool {
+		delay := delayFunc(0, nil, nil)
+		if delay <= 0 {
+			t.Errorf("Delay result overflew: %v", delay)
+		}
+		return !t.Failed()
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFibonacciBackoff(t *testing.T) {
+	for _, c := range []struct {
+		label     string
+		n         uint
+		maxDelay  time.Duration
+		maxJitter time.Duration
+		err       error
+		min, max  time.Duration
+	}{
+		{
+			label: "first-try",
+			n:     0,
+			min:   time.Millisecond,
+			max:   time.Millisecond,
+		},
+		{
+			label: "second-try",
+			n:     1,
+			min:   time.Millisecond,
+			max:   time.Millisecond,
+		},
+		{
+			label: "sixth-try",
+			n:     5,
+			min:   8 * time.Millisecond,
+			max:   8 * time.Millisecond,
+		},
+		{
+			label:    "max-delay",
+			n:        9999,
+			maxDelay: time.Second,
+			min:      time.Second,
+			max:      time.Second,
+		},
+		{
+			label: "no-overflow",
+			n:     9999,
+			min:   time.Duration(math.MaxInt64),
+			max:   time.Duration(math.MaxInt64),
+		},
+		{
+			label:     "jitter",
+			n:         5,
+			maxJitter: time.Millisecond,
+			min:       8 * time.Millisecond,
+			max:       9 * time.Millisecond,
+		},
+		{
+			label:    "retry-after",
+			n:        0,
+			maxDelay: time.Millisecond,
+			err:      retryAfterError(time.Second),
+			min:      time.Second,
+			max:      time.Second,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			delay := fibonacciBackoffFunc(FibonacciBackoffArgs{
+				InitialDelay: time.Millisecond,
+				MaxDelay:     c.maxDelay,
+				MaxJitter:    c.maxJitter,
+			})(c.n, c.err, nil)
+			if delay < c.min || delay > c.max {
+				t.Errorf("Delay %v not in range [%v, %v]", delay, c.min, c.max)
+			}
+		})
+	}
+}
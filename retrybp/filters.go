@@ -7,6 +7,8 @@ import (
 
 	"github.com/avast/retry-go"
 	"github.com/sony/gobreaker"
+
+	"github.com/reddit/baseplate.go/errorsbp"
 )
 
 const (
@@ -34,11 +36,15 @@ func chain(current Filter, next retry.RetryIfFunc) retry.RetryIfFunc {
 // You should not use this with any other retry.RetryIf options as one will
 // override the other.
 func Filters(filters ...Filter) retry.Option {
+	return retry.RetryIf(filtersRetryIf(filters))
+}
+
+func filtersRetryIf(filters []Filter) retry.RetryIfFunc {
 	retryIf := fallback
 	for i := len(filters) - 1; i >= 0; i-- {
 		retryIf = chain(filters[i], retryIf)
 	}
-	return retry.RetryIf(retryIf)
+	return retryIf
 }
 
 // Filter is a function that is passed an error and attempts to determine
@@ -160,6 +166,34 @@ func BreakerErrorFilter(err error, next retry.RetryIfFunc) bool {
 	return next(err)
 }
 
+// RegistryFilter returns a Filter that decides based on the category of the
+// error classified by registry:
+//
+// - errorsbp.CategoryRetryable is retried.
+//
+// - errorsbp.CategoryClientFault and errorsbp.CategoryResourceExhausted are not
+// retried, the latter to not add more load to an already exhausted upstream.
+//
+// - Otherwise it defers to the next filter.
+//
+// If registry is nil, errorsbp.DefaultRegistry is used.
+//
+// It should usually come right after RetryableErrorFilter in the filter chain.
+func RegistryFilter(registry *errorsbp.Registry) Filter {
+	if registry == nil {
+		registry = errorsbp.DefaultRegistry
+	}
+	return func(err error, next retry.RetryIfFunc) bool {
+		switch registry.Classify(err) {
+		case errorsbp.CategoryRetryable:
+			return true
+		case errorsbp.CategoryClientFault, errorsbp.CategoryResourceExhausted:
+			return false
+		}
+		return next(err)
+	}
+}
+
 type retryableWrapper struct {
 	err       error
 	retryable int
@@ -10,6 +10,7 @@ import (
 	"github.com/avast/retry-go"
 
 	"github.com/reddit/baseplate.go/clientpool"
+	"github.com/reddit/baseplate.go/errorsbp"
 	"github.com/reddit/baseplate.go/retrybp"
 )
 
@@ -212,6 +213,80 @@ func TestPoolExhaustedFilter(t *testing.T) {
 	}
 }
 
+func TestRegistryFilter(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errRetryable = errors.New("retryable")
+		errClient    = errors.New("client")
+		errExhausted = errors.New("exhausted")
+		errServer    = errors.New("server")
+	)
+	var registry errorsbp.Registry
+	registry.RegisterError(errRetryable, errorsbp.CategoryRetryable)
+	registry.RegisterError(errClient, errorsbp.CategoryClientFault)
+	registry.RegisterError(errExhausted, errorsbp.CategoryResourceExhausted)
+	registry.RegisterError(errServer, errorsbp.CategoryServerFault)
+
+	cases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{
+			name:     "retryable",
+			err:      fmt.Errorf("wrapped: %w", errRetryable),
+			expected: maxAttempts,
+		},
+		{
+			name:     "client-fault",
+			err:      errClient,
+			expected: 1,
+		},
+		{
+			name:     "resource-exhausted",
+			err:      errExhausted,
+			expected: 1,
+		},
+		{
+			name:     "server-fault",
+			err:      errServer,
+			expected: maxAttempts,
+		},
+		{
+			name:     "unknown",
+			err:      errors.New("unknown"),
+			expected: maxAttempts,
+		},
+	}
+
+	for _, _c := range cases {
+		c := _c
+		t.Run(c.name, func(t *testing.T) {
+			counter := &counter{err: c.err}
+			retrybp.Do(
+				context.TODO(),
+				counter.call,
+				retry.Attempts(maxAttempts),
+				retry.Delay(0),
+				retry.DelayType(retry.FixedDelay),
+				retrybp.Filters(
+					retrybp.RegistryFilter(&registry),
+					// Defer to doFilter on no decision.
+					doFilter,
+				),
+			)
+			if counter.calls != c.expected {
+				t.Errorf(
+					"number of calls did not match, expected %v, got %v",
+					c.expected,
+					counter.calls,
+				)
+			}
+		})
+	}
+}
+
 func TestNetworkErrorFilter(t *testing.T) {
 	t.Parallel()
 
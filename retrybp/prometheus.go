@@ -0,0 +1,45 @@
+package retrybp
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// PrometheusBudgetLabel is the label name of the RetryBudget name in the
+// Prometheus metrics reported by retrybp.
+const PrometheusBudgetLabel = "retrybp_budget"
+
+var budgetLabels = []string{
+	PrometheusBudgetLabel,
+}
+
+var (
+	budgetRequestsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "retrybp_budget_requests_total",
+		Help: "Total number of requests counted by the retry budget",
+	}, budgetLabels)
+
+	budgetRetriesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "retrybp_budget_retries_total",
+		Help: "Total number of retries allowed by the retry budget",
+	}, budgetLabels)
+
+	budgetExhaustedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "retrybp_budget_exhausted_total",
+		Help: "Total number of retries rejected because the retry budget was exhausted",
+	}, budgetLabels)
+)
+
+type budgetMetrics struct {
+	requests  prometheus.Counter
+	retries   prometheus.Counter
+	exhausted prometheus.Counter
+}
+
+func newBudgetMetrics(name string) budgetMetrics {
+	return budgetMetrics{
+		requests:  budgetRequestsCounter.WithLabelValues(name),
+		retries:   budgetRetriesCounter.WithLabelValues(name),
+		exhausted: budgetExhaustedCounter.WithLabelValues(name),
+	}
+}
@@ -1,8 +1,11 @@
 package runtimebp
 
 import (
+	"errors"
 	"fmt"
 	"os"
+
+	"github.com/reddit/baseplate.go/errorsbp"
 )
 
 // Config is the configuration struct for the runtimebp package.
@@ -24,9 +27,55 @@ type Config struct {
 		// Defaults to 1 if not set.
 		Min int `yaml:"min"`
 	} `yaml:"numProcesses"`
+
+	// MemoryLimit can be used to set the Go memory limit (GOMEMLIMIT) based on
+	// the container memory limit.
+	//
+	// The GOMEMLIMIT environment variable, when set, takes precedence over it.
+	MemoryLimit struct {
+		// Disabled disables setting the Go memory limit.
+		Disabled bool `yaml:"disabled"`
+
+		// Ratio is the ratio of the container memory limit to set the Go memory
+		// limit to, in (0, 1].
+		//
+		// Defaults to DefaultMemoryLimitRatio if not set.
+		Ratio float64 `yaml:"ratio"`
+
+		// Bytes overrides the container memory limit detected by MemoryLimit,
+		// Ratio is not applied to it.
+		Bytes int64 `yaml:"bytes"`
+	} `yaml:"memoryLimit"`
+}
+
+// ValidateConfig implements configbp.Validator.
+func (cfg Config) ValidateConfig() error {
+	var batch errorsbp.Batch
+	if cfg.NumProcesses.Max < 0 {
+		batch.Add(fmt.Errorf("numProcesses.max: must not be negative, got %d", cfg.NumProcesses.Max))
+	}
+	if cfg.NumProcesses.Min < 0 {
+		batch.Add(fmt.Errorf("numProcesses.min: must not be negative, got %d", cfg.NumProcesses.Min))
+	}
+	if cfg.NumProcesses.Max > 0 && cfg.NumProcesses.Min > cfg.NumProcesses.Max {
+		batch.Add(fmt.Errorf(
+			"numProcesses.min: must not be greater than numProcesses.max (%d), got %d",
+			cfg.NumProcesses.Max,
+			cfg.NumProcesses.Min,
+		))
+	}
+	if cfg.MemoryLimit.Ratio < 0 || cfg.MemoryLimit.Ratio > 1 {
+		batch.Add(fmt.Errorf("memoryLimit.ratio: must be in [0, 1], got %v", cfg.MemoryLimit.Ratio))
+	}
+	if cfg.MemoryLimit.Bytes < 0 {
+		batch.Add(fmt.Errorf("memoryLimit.bytes: must not be negative, got %d", cfg.MemoryLimit.Bytes))
+	}
+	return batch.Compile()
 }
 
-// InitFromConfig sets GOMAXPROCS using the given config.
+// InitFromConfig sets GOMAXPROCS and the Go memory limit using the given
+// config and the detected container limits,
+// and prints the detected limits and the values set to stderr.
 func InitFromConfig(cfg Config) {
 	max := 64
 	min := 1
@@ -38,4 +87,27 @@ func InitFromConfig(cfg Config) {
 	}
 	prev, current := GOMAXPROCS(min, max)
 	fmt.Fprintf(os.Stderr, "GOMAXPROCS: %d %d\n", prev, current)
+
+	if cfg.MemoryLimit.Disabled {
+		return
+	}
+	var (
+		prevLimit, limit int64
+		err              error
+	)
+	if cfg.MemoryLimit.Bytes > 0 {
+		prevLimit, limit, err = SetMemoryLimit(cfg.MemoryLimit.Bytes)
+	} else {
+		prevLimit, limit, err = GOMEMLIMIT(cfg.MemoryLimit.Ratio)
+	}
+	switch {
+	case errors.Is(err, ErrNoMemoryLimit):
+		fmt.Fprintf(os.Stderr, "GOMEMLIMIT: no container memory limit detected\n")
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "GOMEMLIMIT: failed to set memory limit: %v\n", err)
+	case limit == 0:
+		fmt.Fprintf(os.Stderr, "GOMEMLIMIT: using the GOMEMLIMIT environment variable\n")
+	default:
+		fmt.Fprintf(os.Stderr, "GOMEMLIMIT: %d %d\n", prevLimit, limit)
+	}
 }
@@ -1,18 +1,30 @@
 package runtimebp
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
 )
 
+// cgroupRoot is the mount point of the cgroup sysfs.
+//
+// It's a variable for testing.
+var cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupV2NoLimit is the value in the cgroup v2 files meaning no limit.
+const cgroupV2NoLimit = "max"
+
 // NumCPU returns the number of CPUs assigned to this running container.
 //
 // This is the container aware version of runtime.NumCPU.
-// It reads from the cgroup sysfs values.
+// It reads from the cgroup sysfs values,
+// supporting both cgroup v2 (cpu.max) and cgroup v1 (cpu.cfs_quota_us and
+// cpu.cfs_period_us).
 //
 // If the current process is not running inside a container,
 // or for whatever reason we failed to read the cgroup sysfs values,
@@ -20,9 +32,58 @@ import (
 //
 // When fallback happens, it also prints the reason to stderr.
 func NumCPU() (n float64) {
-	const (
-		quotaPath  = "/sys/fs/cgroup/cpu/cpu.cfs_quota_us"
-		periodPath = "/sys/fs/cgroup/cpu/cpu.cfs_period_us"
+	n, err := numCPUCgroupV2()
+	if errors.Is(err, os.ErrNotExist) {
+		// Not cgroup v2.
+		return numCPUCgroupV1()
+	}
+	if err != nil || n <= 0 {
+		// Fallback and log to stderr.
+		fmt.Fprintf(
+			os.Stderr,
+			"NumCPU: falling back to use runtime.NumCPU(): %v, %v\n",
+			n,
+			err,
+		)
+		n = float64(runtime.NumCPU())
+	}
+	return n
+}
+
+// numCPUCgroupV2 reads the cpu.max file of cgroup v2,
+// in the format of "$MAX $PERIOD",
+// where $MAX could be "max" meaning no limit.
+func numCPUCgroupV2() (float64, error) {
+	path := filepath.Join(cgroupRoot, "cpu.max")
+	content, err := readCgroupFile(path, make([]byte, 1024))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(content)
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("runtimebp: unexpected content of %q: %q", path, content)
+	}
+	if fields[0] == cgroupV2NoLimit {
+		return 0, fmt.Errorf("runtimebp: no cpu limit in %q", path)
+	}
+	quota, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("runtimebp: failed to parse %q: %w", path, err)
+	}
+	period, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("runtimebp: failed to parse %q: %w", path, err)
+	}
+	if period <= 0 {
+		return 0, fmt.Errorf("runtimebp: invalid period in %q: %d", path, period)
+	}
+	return float64(quota) / float64(period), nil
+}
+
+func numCPUCgroupV1() (n float64) {
+	var (
+		quotaPath  = filepath.Join(cgroupRoot, "cpu", "cpu.cfs_quota_us")
+		periodPath = filepath.Join(cgroupRoot, "cpu", "cpu.cfs_period_us")
 	)
 
 	var err error
@@ -65,10 +126,8 @@ func NumCPU() (n float64) {
 //
 // - In k8s only request is set for cpu, not limit
 func numCPUSharesFallback() (n float64) {
-	const (
-		sharesPath  = "/sys/fs/cgroup/cpu/cpu.shares"
-		denominator = 1024
-	)
+	const denominator = 1024
+	sharesPath := filepath.Join(cgroupRoot, "cpu", "cpu.shares")
 
 	var err error
 	defer func() {
@@ -97,22 +156,32 @@ func numCPUSharesFallback() (n float64) {
 }
 
 func readNumberFromFile(path string, buf []byte) (float64, error) {
-	file, err := os.Open(path)
+	content, err := readCgroupFile(path, buf)
 	if err != nil {
-		return 0, fmt.Errorf("runtimebp: failed to open %q: %w", path, err)
+		return 0, err
 	}
-	defer file.Close()
 
-	n, err := file.Read(buf)
+	f, err := strconv.ParseInt(content, 10, 64)
 	if err != nil {
-		return 0, fmt.Errorf("runtimebp: failed to read %q: %w", path, err)
+		return 0, fmt.Errorf("runtimebp: failed to parse %q: %w", path, err)
 	}
+	return float64(f), nil
+}
 
-	f, err := strconv.ParseInt(strings.TrimSpace(string(buf[:n])), 10, 64)
+// readCgroupFile reads the content of the small file at path with buf,
+// with the surrounding spaces trimmed.
+func readCgroupFile(path string, buf []byte) (string, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return 0, fmt.Errorf("runtimebp: failed to parse %q: %w", path, err)
+		return "", fmt.Errorf("runtimebp: failed to open %q: %w", path, err)
 	}
-	return float64(f), nil
+	defer file.Close()
+
+	n, err := file.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("runtimebp: failed to read %q: %w", path, err)
+	}
+	return strings.TrimSpace(string(buf[:n])), nil
 }
 
 // MaxProcsFormula is the function to calculate GOMAXPROCS based on NumCPU value
@@ -3,6 +3,7 @@ package runtimebp
 import (
 	"math"
 	"os"
+	"runtime"
 	"testing"
 )
 
@@ -137,3 +138,52 @@ func TestBoundNtoMinMax(t *testing.T) {
 		)
 	}
 }
+
+func TestNumCPUCgroup(t *testing.T) {
+	for _, c := range []struct {
+		label string
+		files map[string]string
+		want  float64
+	}{
+		{
+			label: "v2",
+			files: map[string]string{
+				"cpu.max": "250000 100000\n",
+			},
+			want: 2.5,
+		},
+		{
+			label: "v1",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":  "150000\n",
+				"cpu/cpu.cfs_period_us": "100000\n",
+			},
+			want: 1.5,
+		},
+		{
+			label: "v1-shares",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":  "-1\n",
+				"cpu/cpu.cfs_period_us": "100000\n",
+				"cpu/cpu.shares":        "3072\n",
+			},
+			want: 3,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			fakeCgroup(t, c.files)
+			if n := NumCPU(); math.Abs(n-c.want) > 1e-5 {
+				t.Errorf("Expected %v, got %v", c.want, n)
+			}
+		})
+	}
+
+	t.Run("v2-no-limit", func(t *testing.T) {
+		fakeCgroup(t, map[string]string{
+			"cpu.max": "max 100000\n",
+		})
+		if n, want := NumCPU(), float64(runtime.NumCPU()); n != want {
+			t.Errorf("Expected runtime.NumCPU() %v, got %v", want, n)
+		}
+	})
+}
@@ -0,0 +1,12 @@
+//go:build go1.19
+// +build go1.19
+
+package runtimebp
+
+import (
+	"runtime/debug"
+)
+
+func setMemoryLimit(limit int64) (int64, error) {
+	return debug.SetMemoryLimit(limit), nil
+}
@@ -0,0 +1,12 @@
+//go:build !go1.19
+// +build !go1.19
+
+package runtimebp
+
+import (
+	"errors"
+)
+
+func setMemoryLimit(limit int64) (int64, error) {
+	return 0, errors.New("runtimebp: setting memory limit requires go1.19+")
+}
@@ -0,0 +1,110 @@
+package runtimebp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// DefaultMemoryLimitRatio is the default ratio of the container memory limit
+// GOMEMLIMIT sets the Go memory limit to,
+// leaving some headroom for the memory not managed by the Go runtime.
+const DefaultMemoryLimitRatio = 0.9
+
+// ErrNoMemoryLimit is the error returned by MemoryLimit when there's no memory
+// limit set on the container, or the process is not running inside a
+// container.
+var ErrNoMemoryLimit = errors.New("runtimebp: no memory limit")
+
+// cgroupV1NoLimit is the threshold in cgroup v1 memory.limit_in_bytes
+// meaning no limit.
+//
+// cgroup v1 reports the max int64 rounded down to the page size when there's
+// no limit.
+const cgroupV1NoLimit = 1 << 62
+
+// MemoryLimit returns the memory limit in bytes assigned to this running
+// container.
+//
+// It reads from the cgroup sysfs values,
+// supporting both cgroup v2 (memory.max) and cgroup v1
+// (memory.limit_in_bytes).
+//
+// It returns ErrNoMemoryLimit when there's no limit,
+// and other errors when it failed to read the cgroup sysfs values.
+func MemoryLimit() (int64, error) {
+	buf := make([]byte, 1024)
+
+	path := filepath.Join(cgroupRoot, "memory.max")
+	content, err := readCgroupFile(path, buf)
+	if err == nil {
+		// cgroup v2
+		if content == cgroupV2NoLimit {
+			return 0, ErrNoMemoryLimit
+		}
+		limit, err := strconv.ParseInt(content, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("runtimebp: failed to parse %q: %w", path, err)
+		}
+		return limit, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+
+	// cgroup v1
+	path = filepath.Join(cgroupRoot, "memory", "memory.limit_in_bytes")
+	content, err = readCgroupFile(path, buf)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, ErrNoMemoryLimit
+	}
+	if err != nil {
+		return 0, err
+	}
+	limit, err := strconv.ParseInt(content, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("runtimebp: failed to parse %q: %w", path, err)
+	}
+	if limit >= cgroupV1NoLimit {
+		return 0, ErrNoMemoryLimit
+	}
+	return limit, nil
+}
+
+// GOMEMLIMIT sets the Go memory limit (see runtime/debug.SetMemoryLimit) to
+// ratio of the container memory limit returned by MemoryLimit.
+//
+// When ratio is not in (0, 1], DefaultMemoryLimitRatio is used instead.
+//
+// It doesn't change the memory limit and returns 0 as newVal when the
+// GOMEMLIMIT environment variable is set,
+// which takes precedence over the container memory limit.
+func GOMEMLIMIT(ratio float64) (oldVal, newVal int64, err error) {
+	if os.Getenv("GOMEMLIMIT") != "" {
+		return 0, 0, nil
+	}
+	limit, err := MemoryLimit()
+	if err != nil {
+		return 0, 0, err
+	}
+	return SetMemoryLimit(int64(float64(limit) * memoryLimitRatio(ratio)))
+}
+
+// SetMemoryLimit sets the Go memory limit to limit bytes,
+// when it's supported by the go version (go1.19+).
+func SetMemoryLimit(limit int64) (oldVal, newVal int64, err error) {
+	oldVal, err = setMemoryLimit(limit)
+	if err != nil {
+		return 0, 0, err
+	}
+	return oldVal, limit, nil
+}
+
+func memoryLimitRatio(ratio float64) float64 {
+	if ratio <= 0 || ratio > 1 {
+		return DefaultMemoryLimitRatio
+	}
+	return ratio
+}
@@ -0,0 +1,132 @@
+package runtimebp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeCgroup points cgroupRoot to a temp dir with the given files for the
+// duration of the test.
+func fakeCgroup(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	orig := cgroupRoot
+	cgroupRoot = root
+	t.Cleanup(func() {
+		cgroupRoot = orig
+	})
+}
+
+func TestMemoryLimit(t *testing.T) {
+	for _, c := range []struct {
+		label string
+		files map[string]string
+		want  int64
+		err   error
+	}{
+		{
+			label: "v2",
+			files: map[string]string{
+				"memory.max": "1073741824\n",
+			},
+			want: 1 << 30,
+		},
+		{
+			label: "v2-no-limit",
+			files: map[string]string{
+				"memory.max": "max\n",
+			},
+			err: ErrNoMemoryLimit,
+		},
+		{
+			label: "v1",
+			files: map[string]string{
+				"memory/memory.limit_in_bytes": "536870912\n",
+			},
+			want: 1 << 29,
+		},
+		{
+			label: "v1-no-limit",
+			files: map[string]string{
+				"memory/memory.limit_in_bytes": "9223372036854771712\n",
+			},
+			err: ErrNoMemoryLimit,
+		},
+		{
+			label: "no-cgroup",
+			err:   ErrNoMemoryLimit,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			fakeCgroup(t, c.files)
+			limit, err := MemoryLimit()
+			if !errors.Is(err, c.err) {
+				t.Fatalf("Expected error %v, got %v", c.err, err)
+			}
+			if limit != c.want {
+				t.Errorf("Expected limit %d, got %d", c.want, limit)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		fakeCgroup(t, map[string]string{
+			"memory.max": "foo",
+		})
+		if _, err := MemoryLimit(); err == nil || errors.Is(err, ErrNoMemoryLimit) {
+			t.Errorf("Expected parse error, got %v", err)
+		}
+	})
+}
+
+func TestGOMEMLIMIT(t *testing.T) {
+	fakeCgroup(t, map[string]string{
+		"memory.max": "1000000\n",
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("GOMEMLIMIT", "1GiB")
+		_, newVal, err := GOMEMLIMIT(0.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if newVal != 0 {
+			t.Errorf("Expected the memory limit not set, got %d", newVal)
+		}
+	})
+
+	t.Run("ratio", func(t *testing.T) {
+		t.Setenv("GOMEMLIMIT", "")
+		oldVal, newVal, err := GOMEMLIMIT(0.5)
+		if err != nil {
+			t.Skipf("Setting memory limit not supported: %v", err)
+		}
+		t.Cleanup(func() {
+			SetMemoryLimit(oldVal)
+		})
+		if newVal != 500000 {
+			t.Errorf("Expected memory limit 500000, got %d", newVal)
+		}
+
+		_, newVal, err = GOMEMLIMIT(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if newVal != 900000 {
+			t.Errorf("Expected memory limit 900000 with default ratio, got %d", newVal)
+		}
+	})
+}
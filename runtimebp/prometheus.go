@@ -0,0 +1,29 @@
+package runtimebp
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus label names used by the ShutdownManager metrics.
+const (
+	PrometheusShutdownGroupLabel  = "runtimebp_shutdown_group"
+	PrometheusShutdownStepLabel   = "runtimebp_shutdown_step"
+	PrometheusShutdownResultLabel = "runtimebp_shutdown_result"
+)
+
+// Values of PrometheusShutdownResultLabel.
+const (
+	shutdownResultSuccess = "success"
+	shutdownResultError   = "error"
+	shutdownResultTimeout = "timeout"
+)
+
+var shutdownStepsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "runtimebp_shutdown_steps_total",
+	Help: "Total number of the steps run by runtimebp.ShutdownManager, by result",
+}, []string{
+	PrometheusShutdownGroupLabel,
+	PrometheusShutdownStepLabel,
+	PrometheusShutdownResultLabel,
+})
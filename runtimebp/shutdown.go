@@ -0,0 +1,243 @@
+package runtimebp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/reddit/baseplate.go/errorsbp"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// DefaultShutdownGroupTimeout is the timeout of a ShutdownGroup without
+// explicit timeout set via ShutdownManager.SetTimeout.
+const DefaultShutdownGroupTimeout = 10 * time.Second
+
+// ErrShutdownTimeout is the error reported for the shutdown steps that didn't
+// finish within the timeout of their ShutdownGroup.
+var ErrShutdownTimeout = errors.New("runtimebp: shutdown step timed out")
+
+// ShutdownGroup defines the order of the steps run by ShutdownManager.
+//
+// The groups are run in ascending order,
+// each group only starts after all the steps in the previous group finished
+// (or timed out).
+// The steps within the same group are run concurrently.
+//
+// The predefined groups cover the usual shutdown sequence of a service,
+// other values can be used to add groups in between
+// (e.g. ShutdownGroupDrain+1 runs after draining and before flushing).
+type ShutdownGroup int
+
+// Predefined ShutdownGroup values.
+const (
+	// ShutdownGroupStopAccepting is for the steps stopping new traffic from
+	// coming in, e.g. a Drainer failing the health checks.
+	ShutdownGroupStopAccepting ShutdownGroup = iota * 100
+
+	// ShutdownGroupDrain is for the steps waiting for the in-flight requests to
+	// finish, e.g. closing the servers.
+	ShutdownGroupDrain
+
+	// ShutdownGroupFlush is for the steps flushing the buffered data,
+	// e.g. the events and the spans.
+	ShutdownGroupFlush
+
+	// ShutdownGroupClose is for the steps releasing the resources,
+	// e.g. closing the client pools.
+	ShutdownGroupClose
+)
+
+func (g ShutdownGroup) String() string {
+	switch g {
+	default:
+		return fmt.Sprintf("ShutdownGroup(%d)", int(g))
+	case ShutdownGroupStopAccepting:
+		return "stop-accepting"
+	case ShutdownGroupDrain:
+		return "drain"
+	case ShutdownGroupFlush:
+		return "flush"
+	case ShutdownGroupClose:
+		return "close"
+	}
+}
+
+type shutdownStep struct {
+	name   string
+	closer io.Closer
+}
+
+// ShutdownManager runs the registered closers in the order of their
+// ShutdownGroups when the service is shutting down,
+// replacing the chain of defers in main.
+//
+// It's safe to be used concurrently.
+// The zero value is a ShutdownManager without any steps, ready to use.
+type ShutdownManager struct {
+	// Logger is used to log the steps that failed or timed out.
+	//
+	// Optional, nil means log.DefaultWrapper.
+	Logger log.Wrapper
+
+	lock     sync.Mutex
+	steps    map[ShutdownGroup][]shutdownStep
+	timeouts map[ShutdownGroup]time.Duration
+	once     sync.Once
+	err      error
+}
+
+// Register registers closer as a step named name into group.
+//
+// name is used in the logs, the errors and the metrics.
+// To register a function, wrap it with batchcloser.Wrap.
+func (m *ShutdownManager) Register(group ShutdownGroup, name string, closer io.Closer) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.steps == nil {
+		m.steps = make(map[ShutdownGroup][]shutdownStep)
+	}
+	m.steps[group] = append(m.steps[group], shutdownStep{
+		name:   name,
+		closer: closer,
+	})
+}
+
+// SetTimeout sets the timeout of group.
+//
+// When the steps in group didn't finish within the timeout,
+// they are reported with ErrShutdownTimeout and the shutdown moves on to the
+// next group.
+// <=0 means DefaultShutdownGroupTimeout.
+func (m *ShutdownManager) SetTimeout(group ShutdownGroup, timeout time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.timeouts == nil {
+		m.timeouts = make(map[ShutdownGroup]time.Duration)
+	}
+	m.timeouts[group] = timeout
+}
+
+// Shutdown runs all the registered steps group by group,
+// and returns the errors of the steps in an errorsbp.Batch.
+//
+// When ctx is done, the remaining steps of the current group are reported with
+// ErrShutdownTimeout and the following groups are skipped.
+//
+// Only the first call runs the steps,
+// the following calls return the same error without running them again.
+func (m *ShutdownManager) Shutdown(ctx context.Context) error {
+	m.once.Do(func() {
+		m.err = m.shutdown(ctx)
+	})
+	return m.err
+}
+
+// Run blocks until one of the shutdown signals (see HandleShutdown) is
+// received, then calls Shutdown.
+//
+// If ctx is done before any signal is received,
+// Run returns ctx.Err() without calling Shutdown.
+func (m *ShutdownManager) Run(ctx context.Context, signals ...os.Signal) error {
+	var received os.Signal
+	HandleShutdown(
+		ctx,
+		func(signal os.Signal) {
+			received = signal
+		},
+		signals...,
+	)
+	if received == nil {
+		return ctx.Err()
+	}
+	m.Logger.Log(context.Background(), fmt.Sprintf("runtimebp: received signal %v, shutting down", received))
+	return m.Shutdown(context.Background())
+}
+
+func (m *ShutdownManager) shutdown(ctx context.Context) error {
+	m.lock.Lock()
+	groups := make([]ShutdownGroup, 0, len(m.steps))
+	for group := range m.steps {
+		groups = append(groups, group)
+	}
+	steps := make(map[ShutdownGroup][]shutdownStep, len(m.steps))
+	for group, s := range m.steps {
+		steps[group] = append([]shutdownStep(nil), s...)
+	}
+	timeouts := make(map[ShutdownGroup]time.Duration, len(m.timeouts))
+	for group, timeout := range m.timeouts {
+		timeouts[group] = timeout
+	}
+	m.lock.Unlock()
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i] < groups[j]
+	})
+
+	var batch errorsbp.Batch
+	for _, group := range groups {
+		if ctx.Err() != nil {
+			m.Logger.Log(ctx, fmt.Sprintf("runtimebp: skipped shutdown group %v: %v", group, ctx.Err()))
+			continue
+		}
+		timeout := timeouts[group]
+		if timeout <= 0 {
+			timeout = DefaultShutdownGroupTimeout
+		}
+		batch.Add(m.runGroup(ctx, group, timeout, steps[group]))
+	}
+	return batch.Compile()
+}
+
+func (m *ShutdownManager) runGroup(ctx context.Context, group ShutdownGroup, timeout time.Duration, steps []shutdownStep) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	errs := make([]error, len(steps))
+	done := make([]chan struct{}, len(steps))
+	for i, step := range steps {
+		done[i] = make(chan struct{})
+		go func(i int, step shutdownStep) {
+			defer close(done[i])
+			errs[i] = step.closer.Close()
+		}(i, step)
+	}
+
+	var batch errorsbp.Batch
+	for i, step := range steps {
+		result := shutdownResultSuccess
+		var err error
+		select {
+		case <-done[i]:
+			err = errs[i]
+			if err != nil {
+				result = shutdownResultError
+			}
+		case <-ctx.Done():
+			// Still wait for the steps finished in the meantime,
+			// only report the ones actually stuck.
+			select {
+			case <-done[i]:
+				err = errs[i]
+				if err != nil {
+					result = shutdownResultError
+				}
+			default:
+				err = ErrShutdownTimeout
+				result = shutdownResultTimeout
+			}
+		}
+		shutdownStepsCounter.WithLabelValues(group.String(), step.name, result).Inc()
+		if err != nil {
+			err = fmt.Errorf("runtimebp: shutdown step %q in group %v failed: %w", step.name, group, err)
+			m.Logger.Log(ctx, err.Error())
+			batch.Add(err)
+		}
+	}
+	return batch.Compile()
+}
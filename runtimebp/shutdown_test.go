@@ -0,0 +1,111 @@
+package runtimebp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type recordCloser struct {
+	name  string
+	delay time.Duration
+	err   error
+
+	lock   *sync.Mutex
+	called *[]string
+}
+
+func (c recordCloser) Close() error {
+	time.Sleep(c.delay)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	*c.called = append(*c.called, c.name)
+	return c.err
+}
+
+func TestShutdownManager(t *testing.T) {
+	var lock sync.Mutex
+	var called []string
+	newCloser := func(name string, delay time.Duration, err error) recordCloser {
+		return recordCloser{
+			name:   name,
+			delay:  delay,
+			err:    err,
+			lock:   &lock,
+			called: &called,
+		}
+	}
+	errClose := errors.New("close failed")
+
+	var m ShutdownManager
+	m.Logger = func(context.Context, string) {}
+	m.Register(ShutdownGroupClose, "pool", newCloser("pool", 0, nil))
+	m.Register(ShutdownGroupFlush, "events", newCloser("events", 0, errClose))
+	m.Register(ShutdownGroupDrain, "stuck", newCloser("stuck", time.Millisecond*200, nil))
+	m.Register(ShutdownGroupDrain+1, "custom", newCloser("custom", 0, nil))
+	m.Register(ShutdownGroupStopAccepting, "drainer", newCloser("drainer", 0, nil))
+	m.SetTimeout(ShutdownGroupDrain, time.Millisecond*10)
+
+	timeouts := shutdownStepsCounter.WithLabelValues("drain", "stuck", shutdownResultTimeout)
+	before := testutil.ToFloat64(timeouts)
+
+	err := m.Shutdown(context.Background())
+	if !errors.Is(err, errClose) {
+		t.Errorf("Expected error %v, got %v", errClose, err)
+	}
+	if !errors.Is(err, ErrShutdownTimeout) {
+		t.Errorf("Expected error %v, got %v", ErrShutdownTimeout, err)
+	}
+	if diff := testutil.ToFloat64(timeouts) - before; diff != 1 {
+		t.Errorf("Expected timeout counter to increase by 1, got %v", diff)
+	}
+
+	lock.Lock()
+	want := []string{"drainer", "custom", "events", "pool"}
+	if !reflect.DeepEqual(called, want) {
+		t.Errorf("Expected steps %v, got %v", want, called)
+	}
+	lock.Unlock()
+
+	if second := m.Shutdown(context.Background()); !reflect.DeepEqual(second, err) {
+		t.Errorf("Expected second Shutdown to return %v, got %v", err, second)
+	}
+}
+
+func TestShutdownManagerCanceled(t *testing.T) {
+	var lock sync.Mutex
+	var called []string
+
+	var m ShutdownManager
+	m.Logger = func(context.Context, string) {}
+	m.Register(ShutdownGroupClose, "pool", recordCloser{
+		name:   "pool",
+		lock:   &lock,
+		called: &called,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.Shutdown(ctx); err != nil {
+		t.Errorf("Expected nil error, got %v", err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if len(called) != 0 {
+		t.Errorf("Expected no steps to run, got %v", called)
+	}
+}
+
+func TestShutdownManagerRun(t *testing.T) {
+	var m ShutdownManager
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := m.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error %v, got %v", context.DeadlineExceeded, err)
+	}
+}
@@ -2,28 +2,157 @@ package secrets
 
 import (
 	"context"
+	"crypto/cipher"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/reddit/baseplate.go/log"
 )
 
+// Supported Config.Provider values.
+const (
+	// ProviderVault reads the secrets from the secrets.json file written by the
+	// Vault fetcher sidecar.
+	ProviderVault = "vault"
+
+	// ProviderVaultCSI reads the secrets from the directory mounted by the
+	// Vault CSI provider, with one file per secret.
+	ProviderVaultCSI = "vault_csi"
+
+	// ProviderVaultAPI reads the secrets from the HTTP API of Vault directly,
+	// see VaultConfig.
+	ProviderVaultAPI = "vault_api"
+
+	// ProviderEnv reads the secrets from the environment variables,
+	// see EnvConfig.
+	//
+	// It's meant for the local development without the Vault fetcher sidecar.
+	ProviderEnv = "env"
+)
+
 // Config is the confuration struct for the secrets package.
 //
 // Can be deserialized from YAML.
 type Config struct {
 	// Path is the path to the secrets.json file file to load your service's
-	// secrets from.
+	// secrets from,
+	// or the directory the secrets are mounted to with ProviderVaultCSI.
+	//
+	// It's required unless the Provider is ProviderVaultAPI or ProviderEnv,
+	// or Paths is set with ProviderVault.
 	Path string `yaml:"path"`
+
+	// Paths are the additional secrets.json files (or glob patterns of them)
+	// with ProviderVault, merged after the one in Path with the later ones
+	// overriding the earlier ones, see NewMultiFileStore.
+	Paths []string `yaml:"paths"`
+
+	// Provider is where the secrets are read from,
+	// ProviderVault (default), ProviderVaultCSI, ProviderVaultAPI or
+	// ProviderEnv.
+	Provider string `yaml:"provider"`
+
+	// Encryption is the config of the secrets files encrypted at rest with
+	// ProviderVault, optional.
+	Encryption EncryptionConfig `yaml:"encryption"`
+
+	// Vault is the config of ProviderVaultAPI.
+	Vault VaultConfig `yaml:"vault"`
+
+	// Env is the config of ProviderEnv.
+	Env EnvConfig `yaml:"env"`
+
+	// RedactFromLogs controls whether to add LogRedactionMiddleware to the
+	// Store, so that the secret values are redacted from logs.
+	RedactFromLogs bool `yaml:"redactFromLogs"`
+}
+
+// ValidateConfig implements configbp.Validator.
+func (cfg Config) ValidateConfig() error {
+	provider := cfg.getProvider()
+	if cfg.Encryption.Enabled() && provider != ProviderVault {
+		return fmt.Errorf("encryption: not supported by provider %q", provider)
+	}
+	switch provider {
+	default:
+		return fmt.Errorf("provider: unknown provider %q", provider)
+	case ProviderVault:
+		if cfg.Path == "" && len(cfg.Paths) == 0 {
+			return errors.New("path: required field is missing")
+		}
+	case ProviderVaultCSI:
+		if cfg.Path == "" {
+			return errors.New("path: required field is missing")
+		}
+	case ProviderVaultAPI:
+		if err := cfg.Vault.Validate(); err != nil {
+			return fmt.Errorf("vault.%w", err)
+		}
+	case ProviderEnv:
+		if cfg.Env.Prefix == "" && len(cfg.Env.Mapping) == 0 {
+			return errors.New("env: either prefix or mapping is required")
+		}
+	}
+	return nil
+}
+
+func (cfg Config) getProvider() string {
+	if cfg.Provider == "" {
+		return ProviderVault
+	}
+	return cfg.Provider
 }
 
 // InitFromConfig returns a new *secrets.Store using the given context and config.
+//
+// The Store is created by NewStore (or NewMultiFileStore when Paths is set),
+// NewVaultCSIStore, NewVaultAPIStore or NewEnvStore,
+// depending on the Provider of the config.
 func InitFromConfig(ctx context.Context, cfg Config) (*Store, error) {
 	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
 	defer cancel()
 
-	store, err := NewStore(ctx, cfg.Path, log.ErrorWithSentryWrapper())
-	if err != nil {
-		return nil, err
+	var middlewares []SecretMiddleware
+	if cfg.RedactFromLogs {
+		middlewares = append(middlewares, LogRedactionMiddleware)
+	}
+	switch provider := cfg.getProvider(); provider {
+	default:
+		return nil, fmt.Errorf("secrets.InitFromConfig: unknown provider %q", provider)
+	case ProviderVault:
+		return newFileStoreFromConfig(ctx, cfg, middlewares...)
+	case ProviderVaultCSI:
+		return NewVaultCSIStore(ctx, cfg.Path, log.ErrorWithSentryWrapper(), middlewares...)
+	case ProviderVaultAPI:
+		return NewVaultAPIStore(ctx, cfg.Vault, log.ErrorWithSentryWrapper(), middlewares...)
+	case ProviderEnv:
+		return NewEnvStore(cfg.Env, middlewares...)
+	}
+}
+
+// newFileStoreFromConfig creates the Store of ProviderVault.
+func newFileStoreFromConfig(ctx context.Context, cfg Config, middlewares ...SecretMiddleware) (*Store, error) {
+	var aead cipher.AEAD
+	if cfg.Encryption.Enabled() {
+		key, err := cfg.Encryption.Key(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("secrets.InitFromConfig: %w", err)
+		}
+		if len(cfg.Paths) == 0 {
+			return NewEncryptedStore(ctx, cfg.Path, key, log.ErrorWithSentryWrapper(), middlewares...)
+		}
+		if aead, err = newAEAD(key); err != nil {
+			return nil, fmt.Errorf("secrets.InitFromConfig: %w", err)
+		}
+	}
+	if len(cfg.Paths) == 0 {
+		return NewStore(ctx, cfg.Path, log.ErrorWithSentryWrapper(), middlewares...)
+	}
+	var paths []string
+	if cfg.Path != "" {
+		paths = append(paths, cfg.Path)
 	}
-	return store, nil
+	paths = append(paths, cfg.Paths...)
+	return newMultiFileStore(ctx, paths, aead, log.ErrorWithSentryWrapper(), middlewares...)
 }
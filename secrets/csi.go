@@ -0,0 +1,225 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/internal/directorywatcher"
+	"github.com/reddit/baseplate.go/internal/limitopen"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// vaultResponse is the response of Vault reading a secret,
+// which is also the format of the files written by the Vault CSI provider.
+type vaultResponse struct {
+	Secret GenericSecret `json:"data"`
+}
+
+// csiReloadDelay is how long csiWatcher waits for the directory to settle
+// before reloading the secrets,
+// so that the several events of a single update by the atomic writer of the
+// CSI driver only cause one reload.
+const csiReloadDelay = 100 * time.Millisecond
+
+// csiWatcher implements filewatcher.FileWatcher for the directory mounted by
+// the Vault CSI provider.
+type csiWatcher struct {
+	dir     string
+	store   *Store
+	logger  log.Wrapper
+	data    atomic.Value // *Secrets
+	watcher *directorywatcher.DirectoryWatcher
+
+	// mu guards timer and stopped, and is held during the reloads.
+	mu      sync.Mutex
+	timer   *time.Timer
+	stopped bool
+}
+
+var _ filewatcher.FileWatcher = (*csiWatcher)(nil)
+
+// NewVaultCSIStore returns a new instance of Store reading the secrets from
+// the directory dir mounted by the Vault CSI provider,
+// where every secret is a file with the secret path as its path relative to
+// dir (e.g. "<dir>/secret/myservice/foo" for "secret/myservice/foo"),
+// and the directory is watched for changes ensuring secrets store will always
+// return up to date secrets.
+//
+// The Vault CSI provider doesn't provide Vault credentials,
+// so GetVault of the returned Store always returns an empty Vault.
+//
+// Context should come with a timeout otherwise this might block forever, i.e.
+// if dir never becomes available.
+func NewVaultCSIStore(ctx context.Context, dir string, logger log.Wrapper, middlewares ...SecretMiddleware) (*Store, error) {
+	store := newStore(middlewares...)
+	store.reloads = newReloadTracker(ProviderVaultCSI)
+
+	for {
+		_, err := os.Stat(dir)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("secrets.NewVaultCSIStore: context cancelled while waiting for directory %q to load. %w", dir, ctx.Err())
+		case <-time.After(filewatcher.InitialReadInterval):
+		}
+	}
+
+	w := &csiWatcher{
+		dir:    dir,
+		store:  store,
+		logger: logger,
+	}
+	if err := w.load(); err != nil {
+		return nil, err
+	}
+	watcher, err := directorywatcher.New(context.Background(), directorywatcher.Config{
+		Path:     dir,
+		OnCreate: w.scheduleReload,
+		OnRemove: w.scheduleReload,
+		Logger:   logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.watcher = watcher
+
+	store.watcher = w
+	trackers.register(store.reloads)
+	return store, nil
+}
+
+// scheduleReload reloads the secrets after csiReloadDelay,
+// postponing the pending reload if there's one.
+func (w *csiWatcher) scheduleReload(string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return
+	}
+	if w.timer == nil {
+		w.timer = time.AfterFunc(csiReloadDelay, w.reload)
+		return
+	}
+	w.timer.Reset(csiReloadDelay)
+}
+
+func (w *csiWatcher) reload() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return
+	}
+	if err := w.load(); err != nil {
+		w.logger.Log(context.Background(), "secrets: failed to reload vault csi secrets: "+err.Error())
+	}
+}
+
+// load reads all the secrets from the directory and replaces the current ones.
+func (w *csiWatcher) load() error {
+	secrets, err := w.read()
+	if err != nil {
+		w.store.reloads.failed()
+		return err
+	}
+	w.store.handle(secrets)
+	w.data.Store(secrets)
+	return nil
+}
+
+func (w *csiWatcher) read() (*Secrets, error) {
+	document := Document{
+		Secrets: make(map[string]GenericSecret),
+	}
+	if err := readCSIDirectory(w.dir, w.dir, document.Secrets); err != nil {
+		return nil, err
+	}
+	return newSecretsFromDocument(document)
+}
+
+// readCSIDirectory reads the secret files under dir into secrets recursively,
+// keyed by their paths relative to root.
+//
+// The entries starting with ".." are skipped,
+// as they are the internal files of the atomic writer of the CSI driver,
+// and the symlinks are followed.
+func readCSIDirectory(root, dir string, secrets map[string]GenericSecret) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "..") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if err := readCSIDirectory(root, path, secrets); err != nil {
+				return err
+			}
+			continue
+		}
+
+		secret, err := readCSIFile(path)
+		if err != nil {
+			return fmt.Errorf("secrets: failed to read vault csi file %q: %w", path, err)
+		}
+		key, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		secrets[filepath.ToSlash(key)] = secret
+	}
+	return nil
+}
+
+func readCSIFile(path string) (GenericSecret, error) {
+	f, err := limitopen.OpenWithLimit(
+		path,
+		filewatcher.DefaultMaxFileSize,
+		filewatcher.DefaultMaxFileSize*filewatcher.HardLimitMultiplier,
+	)
+	if err != nil {
+		return GenericSecret{}, err
+	}
+	defer f.Close()
+
+	var file vaultResponse
+	if err := json.NewDecoder(f).Decode(&file); err != nil {
+		return GenericSecret{}, err
+	}
+	return file.Secret, nil
+}
+
+func (w *csiWatcher) Get() interface{} {
+	return w.data.Load()
+}
+
+func (w *csiWatcher) Stop() {
+	w.watcher.Stop()
+
+	// Holding mu also waits for the reload in progress, if any, to finish.
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
@@ -0,0 +1,190 @@
+package secrets_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+const (
+	csiSimpleSecret = `{
+	"request_id": "4a6a1de0-5d3e-4a43-9e8a-4d6b1e55f13e",
+	"data": {
+		"type": "simple",
+		"value": "Y2RvVXhNMVdsTXJma3BDaHRGZ0dPYkVGSg==",
+		"encoding": "base64"
+	}
+}`
+
+	csiUpdatedSimpleSecret = `{
+	"data": {
+		"type": "simple",
+		"value": "dXBkYXRlZCBzZWNyZXQ=",
+		"encoding": "base64"
+	}
+}`
+
+	csiCredentialSecret = `{
+	"data": {
+		"type": "credential",
+		"username": "spez",
+		"password": "hunter2"
+	}
+}`
+)
+
+// writeCSIVersion writes files into a new version directory of dir and swaps
+// the ..data symlink to it, the same way the atomic writer of the CSI driver
+// does.
+func writeCSIVersion(t *testing.T, dir, version string, files map[string]string) {
+	t.Helper()
+
+	for path, content := range files {
+		path = filepath.Join(dir, version, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tmp := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink(version, tmp); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newCSIDirectory(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeCSIVersion(t, dir, "..2023_01_01_00_00_00.000000001", map[string]string{
+		"secret/myservice/some-api-key":              csiSimpleSecret,
+		"secret/myservice/some-database-credentials": csiCredentialSecret,
+	})
+	if err := os.Symlink(filepath.Join("..data", "secret"), filepath.Join(dir, "secret")); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestVaultCSIStore(t *testing.T) {
+	dir := newCSIDirectory(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var calls int64
+	store, err := secrets.NewVaultCSIStore(ctx, dir, log.TestWrapper(t), func(next secrets.SecretHandlerFunc) secrets.SecretHandlerFunc {
+		return func(sec *secrets.Secrets) {
+			atomic.AddInt64(&calls, 1)
+			next(sec)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if calls := atomic.LoadInt64(&calls); calls != 1 {
+		t.Errorf("expected middleware to be called once, got %d", calls)
+	}
+	secret, err := store.GetSimpleSecret("secret/myservice/some-api-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(secret.Value), "cdoUxM1WlMrfkpChtFgGObEFJ"; got != want {
+		t.Errorf("expected secret to be %q, got %q", want, got)
+	}
+	credential, err := store.GetCredentialSecret("secret/myservice/some-database-credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credential.Username != "spez" || credential.Password != "hunter2" {
+		t.Errorf("unexpected credential secret %+v", credential)
+	}
+
+	writeCSIVersion(t, dir, "..2023_01_01_00_00_00.000000002", map[string]string{
+		"secret/myservice/some-api-key": csiUpdatedSimpleSecret,
+	})
+	deadline := time.Now().Add(time.Second)
+	for {
+		secret, err = store.GetSimpleSecret("secret/myservice/some-api-key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(secret.Value) == "updated secret" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("secret not reloaded, got %q", secret.Value)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if _, err := store.GetCredentialSecret("secret/myservice/some-database-credentials"); err == nil {
+		t.Error("expected the removed secret to be gone after reload")
+	}
+	// All the events of the update are coalesced into a single reload.
+	if calls := atomic.LoadInt64(&calls); calls != 2 {
+		t.Errorf("expected middleware to be called twice, got %d", calls)
+	}
+}
+
+func TestVaultCSIStoreInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret", "myservice", "broken")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := secrets.NewVaultCSIStore(ctx, dir, log.TestWrapper(t))
+	if err == nil {
+		t.Fatal("expected error for the invalid file")
+	}
+	if !strings.Contains(err.Error(), path) {
+		t.Errorf("expected the error to contain the path %q, got %v", path, err)
+	}
+}
+
+func TestInitFromConfigProvider(t *testing.T) {
+	t.Run("vault_csi", func(t *testing.T) {
+		store, err := secrets.InitFromConfig(context.Background(), secrets.Config{
+			Path:     newCSIDirectory(t),
+			Provider: secrets.ProviderVaultCSI,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+		if _, err := store.GetSimpleSecret("secret/myservice/some-api-key"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		cfg := secrets.Config{
+			Path:     t.TempDir(),
+			Provider: "unknown",
+		}
+		if err := cfg.ValidateConfig(); err == nil {
+			t.Error("expected ValidateConfig to fail for unknown provider")
+		}
+		if _, err := secrets.InitFromConfig(context.Background(), cfg); err == nil {
+			t.Error("expected InitFromConfig to fail for unknown provider")
+		}
+	})
+}
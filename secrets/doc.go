@@ -2,4 +2,16 @@
 // reading them out of a JSON file with automatic refresh on change.
 //
 // Store should be used to instantiate and configure the secret fetcher.
+// The secrets split into multiple files can be merged into a single Store by
+// NewMultiFileStore,
+// and the secrets files encrypted at rest can be read by NewEncryptedStore.
+//
+// The secrets can also be read from the directory mounted by the Vault CSI
+// provider (NewVaultCSIStore), or from the HTTP API of Vault directly
+// (NewVaultAPIStore) instead, see Config.Provider.
+//
+// The stores reloading the secrets report the Prometheus metrics of the
+// reloads labeled by their providers: "secrets_loads_total" counts the
+// successful and failed loads, and "secrets_last_load_age_seconds" reports the
+// seconds since the last successful load, to alert on the secrets going stale.
 package secrets
@@ -3,6 +3,7 @@ package secrets
 import (
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 )
 
 // Encoding represents the Encoding used to encode a secret.
@@ -62,7 +63,7 @@ func (e Encoding) decodeValue(value string) (Secret, error) {
 	default:
 		data, err := base64.StdEncoding.DecodeString(value)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("invalid base64 value: %w", err)
 		}
 		return Secret(data), nil
 	}
@@ -0,0 +1,139 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// EncryptionConfig is the config of the secrets files encrypted at rest with
+// ProviderVault, see NewEncryptedStore for the format.
+//
+// Can be deserialized from YAML.
+type EncryptionConfig struct {
+	// KeyEnv is the environment variable to read the AES key from,
+	// in standard base64 encoding.
+	KeyEnv string `yaml:"keyEnv"`
+
+	// KeyFunc returns the AES key, e.g. by decrypting a data key with KMS.
+	// It takes precedence over KeyEnv.
+	//
+	// It can only be set in code.
+	KeyFunc func(ctx context.Context) ([]byte, error) `yaml:"-"`
+}
+
+// Enabled returns true if the secrets files are encrypted.
+func (cfg EncryptionConfig) Enabled() bool {
+	return cfg.KeyEnv != "" || cfg.KeyFunc != nil
+}
+
+// Key returns the AES key from KeyFunc or KeyEnv.
+func (cfg EncryptionConfig) Key(ctx context.Context) ([]byte, error) {
+	if cfg.KeyFunc != nil {
+		return cfg.KeyFunc(ctx)
+	}
+	value := os.Getenv(cfg.KeyEnv)
+	if value == "" {
+		return nil, fmt.Errorf("secrets: environment variable %q for the encryption key is not set", cfg.KeyEnv)
+	}
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: invalid encryption key in environment variable %q: %w", cfg.KeyEnv, err)
+	}
+	return key, nil
+}
+
+// ErrDecryption is returned when the secrets file failed to be decrypted,
+// e.g. with the wrong key or a corrupted file.
+var ErrDecryption = errors.New("secrets: failed to decrypt secrets file")
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptSecretsFile encrypts the content of a secrets.json file with AES-GCM
+// and the AES key (16, 24, or 32 bytes),
+// in the format read by NewEncryptedStore.
+func EncryptSecretsFile(key, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// decryptReader returns the reader of the decrypted content of r.
+func decryptReader(aead cipher.AEAD, r io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: file too short", ErrDecryption)
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryption, err)
+	}
+	return bytes.NewReader(plaintext), nil
+}
+
+// NewEncryptedStore is the same as NewStore,
+// but the secrets file is encrypted at rest with AES-GCM and the AES key
+// (16, 24, or 32 bytes),
+// for the deployments that can't keep the secrets file in memory (tmpfs).
+//
+// The encrypted file is the random nonce (12 bytes) followed by the sealed
+// content of the secrets.json file, as returned by EncryptSecretsFile.
+func NewEncryptedStore(ctx context.Context, path string, key []byte, logger log.Wrapper, middlewares ...SecretMiddleware) (*Store, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("secrets.NewEncryptedStore: %w", err)
+	}
+
+	store := newStore(middlewares...)
+	store.reloads = newReloadTracker(ProviderVault)
+
+	result, err := filewatcher.New(
+		ctx,
+		filewatcher.Config{
+			Path: path,
+			Parser: func(r io.Reader) (interface{}, error) {
+				decrypted, err := decryptReader(aead, r)
+				if err != nil {
+					store.reloads.failed()
+					return nil, err
+				}
+				return store.parser(decrypted)
+			},
+			Logger:        logger,
+			SkipUnchanged: true,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	store.watcher = result
+	trackers.register(store.reloads)
+	return store, nil
+}
@@ -0,0 +1,114 @@
+package secrets_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+func writeEncryptedSecretsFile(t *testing.T, path string, key []byte, content string) {
+	t.Helper()
+	encrypted, err := secrets.EncryptSecretsFile(key, []byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeSecretsFile(t, path, string(encrypted))
+}
+
+func TestEncryptedStore(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	path := filepath.Join(t.TempDir(), "secrets.json.enc")
+	writeEncryptedSecretsFile(t, path, key, specificationExample)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	store, err := secrets.NewEncryptedStore(ctx, path, key, log.TestWrapper(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	secret, err := store.GetSimpleSecret("secret/myservice/some-api-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(secret.Value), "cdoUxM1WlMrfkpChtFgGObEFJ"; got != want {
+		t.Errorf("Expected secret %q, got %q", want, got)
+	}
+
+	t.Run("wrong-key", func(t *testing.T) {
+		_, err := secrets.NewEncryptedStore(ctx, path, bytes.Repeat([]byte{2}, 32), log.TestWrapper(t))
+		if !errors.Is(err, secrets.ErrDecryption) {
+			t.Errorf("Expected ErrDecryption, got %v", err)
+		}
+	})
+
+	t.Run("plaintext", func(t *testing.T) {
+		plaintext := filepath.Join(t.TempDir(), "secrets.json")
+		writeSecretsFile(t, plaintext, specificationExample)
+		_, err := secrets.NewEncryptedStore(ctx, plaintext, key, log.TestWrapper(t))
+		if !errors.Is(err, secrets.ErrDecryption) {
+			t.Errorf("Expected ErrDecryption, got %v", err)
+		}
+	})
+
+	t.Run("invalid-key", func(t *testing.T) {
+		if _, err := secrets.NewEncryptedStore(ctx, path, []byte("short"), log.TestWrapper(t)); err == nil {
+			t.Error("Expected error for the invalid key size")
+		}
+	})
+}
+
+func TestEncryptedStoreFromConfig(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 16)
+	t.Setenv("BPTEST_SECRETS_KEY", base64.StdEncoding.EncodeToString(key))
+	dir := t.TempDir()
+	writeEncryptedSecretsFile(t, filepath.Join(dir, "a.json"), key, `{"secrets": {"secret/a": {"type": "simple", "value": "a"}}}`)
+	writeEncryptedSecretsFile(t, filepath.Join(dir, "b.json"), key, `{"secrets": {"secret/b": {"type": "simple", "value": "b"}}}`)
+
+	for label, cfg := range map[string]secrets.Config{
+		"single": {
+			Path:       filepath.Join(dir, "a.json"),
+			Encryption: secrets.EncryptionConfig{KeyEnv: "BPTEST_SECRETS_KEY"},
+		},
+		"multi": {
+			Paths: []string{filepath.Join(dir, "*.json")},
+			Encryption: secrets.EncryptionConfig{
+				KeyFunc: func(context.Context) ([]byte, error) {
+					return key, nil
+				},
+			},
+		},
+	} {
+		t.Run(label, func(t *testing.T) {
+			if err := cfg.ValidateConfig(); err != nil {
+				t.Fatal(err)
+			}
+			store, err := secrets.InitFromConfig(context.Background(), cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			if _, err := store.GetSimpleSecret("secret/a"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	t.Run("provider", func(t *testing.T) {
+		cfg := secrets.Config{
+			Provider:   secrets.ProviderVaultCSI,
+			Path:       dir,
+			Encryption: secrets.EncryptionConfig{KeyEnv: "BPTEST_SECRETS_KEY"},
+		}
+		if err := cfg.ValidateConfig(); err == nil {
+			t.Error("Expected ValidateConfig to fail for encryption with vault_csi")
+		}
+	})
+}
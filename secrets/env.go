@@ -0,0 +1,124 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+)
+
+// EnvConfig is the config of ProviderEnv,
+// which reads the secrets from the environment variables.
+//
+// The value of every environment variable is either a GenericSecret in JSON
+// (when it starts with "{"), e.g.
+//
+//	{"type": "credential", "username": "spez", "password": "hunter2"}
+//
+// or the raw value of a simple secret otherwise.
+//
+// Can be deserialized from YAML.
+type EnvConfig struct {
+	// Prefix is the prefix of the environment variables to be read as
+	// secrets.
+	//
+	// The secret paths are the rest of the variable names in lowercase,
+	// with "__" replaced by "/" and "_" replaced by "-",
+	// e.g. with prefix "SECRET_", "SECRET_SECRET__MYSERVICE__API_KEY" is read
+	// as "secret/myservice/api-key".
+	Prefix string `yaml:"prefix"`
+
+	// Mapping maps the secret paths to the names of the environment variables
+	// to read them from,
+	// for the paths that can't be expressed via Prefix.
+	//
+	// The mapped secrets take precedence over the ones read via Prefix.
+	Mapping map[string]string `yaml:"mapping"`
+}
+
+// staticWatcher is a filewatcher.FileWatcher of the data that never changes.
+type staticWatcher struct {
+	data interface{}
+}
+
+var _ filewatcher.FileWatcher = staticWatcher{}
+
+func (w staticWatcher) Get() interface{} {
+	return w.data
+}
+
+func (staticWatcher) Stop() {}
+
+// NewEnvStore returns a new instance of Store reading the secrets from the
+// environment variables configured by cfg.
+//
+// The environment variables are only read once,
+// and the Vault of the returned Store is always empty.
+//
+// It's meant for the local development without the Vault fetcher sidecar,
+// and should not be used to create production secrets.
+func NewEnvStore(cfg EnvConfig, middlewares ...SecretMiddleware) (*Store, error) {
+	document := Document{
+		Secrets: make(map[string]GenericSecret),
+	}
+	if cfg.Prefix != "" {
+		for _, env := range os.Environ() {
+			name, value, _ := strings.Cut(env, "=")
+			if !strings.HasPrefix(name, cfg.Prefix) || name == cfg.Prefix {
+				continue
+			}
+			path := envNameToPath(strings.TrimPrefix(name, cfg.Prefix))
+			secret, err := parseEnvSecret(value)
+			if err != nil {
+				return nil, fmt.Errorf("secrets.NewEnvStore: failed to parse %q: %w", name, err)
+			}
+			document.Secrets[path] = secret
+		}
+	}
+	for path, name := range cfg.Mapping {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("secrets.NewEnvStore: environment variable %q for %q is not set", name, path)
+		}
+		secret, err := parseEnvSecret(value)
+		if err != nil {
+			return nil, fmt.Errorf("secrets.NewEnvStore: failed to parse %q: %w", name, err)
+		}
+		document.Secrets[path] = secret
+	}
+
+	secrets, err := newSecretsFromDocument(document)
+	if err != nil {
+		return nil, err
+	}
+	store := newStore(middlewares...)
+	store.handle(secrets)
+	store.watcher = staticWatcher{data: secrets}
+	return store, nil
+}
+
+// envNameToPath converts the environment variable name (without the prefix)
+// into the secret path, see EnvConfig.Prefix.
+func envNameToPath(name string) string {
+	segments := strings.Split(strings.ToLower(name), "__")
+	for i, segment := range segments {
+		segments[i] = strings.ReplaceAll(segment, "_", "-")
+	}
+	return strings.Join(segments, "/")
+}
+
+func parseEnvSecret(value string) (GenericSecret, error) {
+	if !strings.HasPrefix(value, "{") {
+		return GenericSecret{
+			Type:  SimpleType,
+			Value: value,
+		}, nil
+	}
+	var secret GenericSecret
+	if err := json.Unmarshal([]byte(value), &secret); err != nil {
+		return GenericSecret{}, err
+	}
+	return secret, nil
+}
@@ -0,0 +1,91 @@
+package secrets_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+func TestEnvStore(t *testing.T) {
+	t.Setenv("BPTEST_SECRET__MYSERVICE__API_KEY", "foo")
+	t.Setenv("BPTEST_SECRET__MYSERVICE__DB", `{"type": "credential", "username": "spez", "password": "hunter2"}`)
+	t.Setenv("BPTEST_SIGNING_KEY", `{"type": "versioned", "current": "YmFy", "encoding": "base64"}`)
+	t.Setenv("BPTEST_MAPPED", "mapped")
+
+	store, err := secrets.InitFromConfig(context.Background(), secrets.Config{
+		Provider: secrets.ProviderEnv,
+		Env: secrets.EnvConfig{
+			Prefix: "BPTEST_",
+			Mapping: map[string]string{
+				"secret/myservice/api-key": "BPTEST_MAPPED",
+				"secret/other/path_with":   "BPTEST_MAPPED",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	simple, err := store.GetSimpleSecret("secret/myservice/api-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(simple.Value), "mapped"; got != want {
+		t.Errorf("expected the mapped value %q, got %q", want, got)
+	}
+	if _, err := store.GetSimpleSecret("secret/other/path_with"); err != nil {
+		t.Error(err)
+	}
+	credential, err := store.GetCredentialSecret("secret/myservice/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credential.Username != "spez" || credential.Password != "hunter2" {
+		t.Errorf("unexpected credential secret %+v", credential)
+	}
+	versioned, err := store.GetVersionedSecret("signing-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(versioned.Current), "bar"; got != want {
+		t.Errorf("expected versioned secret %q, got %q", want, got)
+	}
+}
+
+func TestEnvStoreErrors(t *testing.T) {
+	t.Run("missing-mapping", func(t *testing.T) {
+		_, err := secrets.NewEnvStore(secrets.EnvConfig{
+			Mapping: map[string]string{
+				"secret/myservice/api-key": "BPTEST_NOT_SET",
+			},
+		})
+		if err == nil {
+			t.Error("expected error for the unset environment variable")
+		}
+	})
+
+	t.Run("invalid-json", func(t *testing.T) {
+		t.Setenv("BPTEST_INVALID", "{")
+		_, err := secrets.NewEnvStore(secrets.EnvConfig{
+			Prefix: "BPTEST_",
+		})
+		if err == nil {
+			t.Error("expected error for the invalid JSON")
+		}
+	})
+
+	t.Run("config", func(t *testing.T) {
+		cfg := secrets.Config{
+			Provider: secrets.ProviderEnv,
+		}
+		if err := cfg.ValidateConfig(); err == nil {
+			t.Error("expected ValidateConfig to fail without prefix or mapping")
+		}
+		cfg.Env.Prefix = "BPTEST_"
+		if err := cfg.ValidateConfig(); err != nil {
+			t.Errorf("expected ValidateConfig to pass without path, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrExpired is the error reported by Store.CheckExpiration when some of the
+// secrets already expired.
+var ErrExpired = errors.New("secrets: expired")
+
+// Expiration returns when the secret at path expires,
+// or zero time if it never expires (or the expiration is unknown).
+//
+// It returns SecretNotFoundError if there's no secret at path.
+func (s *Secrets) Expiration(path string) (time.Time, error) {
+	if path == "" {
+		return time.Time{}, ErrEmptySecretKey
+	}
+	if secret, ok := s.simpleSecrets[path]; ok {
+		return secret.Expiration, nil
+	}
+	if secret, ok := s.versionedSecrets[path]; ok {
+		return secret.Expiration, nil
+	}
+	if secret, ok := s.credentialSecrets[path]; ok {
+		return secret.Expiration, nil
+	}
+	return time.Time{}, SecretNotFoundError(path)
+}
+
+// expirations returns the expirations of all the secrets expiring by their
+// paths.
+func (s *Secrets) expirations() map[string]time.Time {
+	expirations := make(map[string]time.Time)
+	if s == nil {
+		return expirations
+	}
+	for path := range s.entries() {
+		if expiration, _ := s.Expiration(path); !expiration.IsZero() {
+			expirations[path] = expiration
+		}
+	}
+	return expirations
+}
+
+// reportExpirations updates the secrets_expiration_timestamp_seconds gauges
+// from old to new secrets.
+func reportExpirations(old, new *Secrets) {
+	newExpirations := new.expirations()
+	for path := range old.expirations() {
+		if _, ok := newExpirations[path]; !ok {
+			expirationGauge.DeleteLabelValues(path)
+		}
+	}
+	for path, expiration := range newExpirations {
+		expirationGauge.WithLabelValues(path).Set(float64(expiration.UnixNano()) / float64(time.Second))
+	}
+}
+
+// TimeUntilExpiry loads secrets from watcher, and returns the time until the
+// secret at path expires,
+// which is negative if it already expired.
+//
+// ok is false if the secret never expires (or the expiration is unknown).
+func (s Store) TimeUntilExpiry(path string) (d time.Duration, ok bool, err error) {
+	expiration, err := s.getSecrets().Expiration(path)
+	if err != nil || expiration.IsZero() {
+		return 0, false, err
+	}
+	return time.Until(expiration), true, nil
+}
+
+// CheckExpiration returns an error wrapping ErrExpired naming the secrets
+// already expired, which usually means the secrets are no longer refreshed.
+//
+// It's a healthbp.CheckFunc,
+// registered into healthbp.DefaultRegistry by baseplate.New for its Store.
+func (s *Store) CheckExpiration(_ context.Context) error {
+	if s == nil {
+		return nil
+	}
+	now := time.Now()
+	var expired []string
+	for path, expiration := range s.getSecrets().expirations() {
+		if !expiration.After(now) {
+			expired = append(expired, path)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	sort.Strings(expired)
+	return fmt.Errorf("%w: %s", ErrExpired, strings.Join(expired, ", "))
+}
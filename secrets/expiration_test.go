@@ -0,0 +1,167 @@
+package secrets_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+func TestExpiration(t *testing.T) {
+	const document = `{
+	"secrets": {
+		"secret/myservice/simple": {
+			"type": "simple",
+			"value": "hunter2",
+			"expiration": "2030-01-02T03:04:05Z"
+		},
+		"secret/myservice/versioned": {
+			"type": "versioned",
+			"current": "hunter3",
+			"expiration": "2000-01-01T00:00:00Z"
+		},
+		"secret/myservice/db": {
+			"type": "credential",
+			"username": "spez",
+			"password": "hunter4",
+			"expiration": "2030-01-02T03:04:05+01:00"
+		},
+		"secret/myservice/forever": {
+			"type": "simple",
+			"value": "hunter5"
+		}
+	}
+}`
+	sec, err := secrets.NewSecrets(bytes.NewBufferString(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		path string
+		want time.Time
+	}{
+		{
+			path: "secret/myservice/simple",
+			want: time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			path: "secret/myservice/versioned",
+			want: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			path: "secret/myservice/db",
+			want: time.Date(2030, 1, 2, 2, 4, 5, 0, time.UTC),
+		},
+		{
+			path: "secret/myservice/forever",
+		},
+	} {
+		t.Run(c.path, func(t *testing.T) {
+			got, err := sec.Expiration(c.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(c.want) {
+				t.Errorf("Expected expiration %v, got %v", c.want, got)
+			}
+		})
+	}
+
+	if _, err := sec.Expiration("secret/myservice/not-found"); err == nil {
+		t.Error("Expected error for the secret not found")
+	}
+
+	simple, err := sec.GetSimpleSecret("secret/myservice/simple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := simple.AsVersioned().Expiration; !got.Equal(simple.Expiration) {
+		t.Errorf("Expected AsVersioned to keep the expiration %v, got %v", simple.Expiration, got)
+	}
+}
+
+func TestStoreTimeUntilExpiry(t *testing.T) {
+	expiration := time.Now().Add(time.Hour).Truncate(time.Second)
+	ts, err := secrets.NewTestStore(map[string]secrets.GenericSecret{
+		"secret/myservice/expiring": {
+			Type:       secrets.SimpleType,
+			Value:      "hunter2",
+			Expiration: expiration,
+		},
+		"secret/myservice/forever": {
+			Type:  secrets.SimpleType,
+			Value: "hunter3",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, ok, err := ts.TimeUntilExpiry("secret/myservice/expiring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || d <= 0 || d > time.Hour {
+		t.Errorf("Expected time until expiry within an hour, got %v, %v", d, ok)
+	}
+	if _, ok, err := ts.TimeUntilExpiry("secret/myservice/forever"); err != nil || ok {
+		t.Errorf("Expected no expiry, got %v, %v", ok, err)
+	}
+	if _, _, err := ts.TimeUntilExpiry("secret/myservice/not-found"); err == nil {
+		t.Error("Expected error for the secret not found")
+	}
+
+	for _, m := range ts.Metadata() {
+		switch m.Path {
+		case "secret/myservice/expiring":
+			if m.Expiration == nil || !m.Expiration.Equal(expiration) {
+				t.Errorf("Expected metadata expiration %v, got %v", expiration, m.Expiration)
+			}
+		case "secret/myservice/forever":
+			if m.Expiration != nil {
+				t.Errorf("Expected no metadata expiration, got %v", m.Expiration)
+			}
+		}
+	}
+}
+
+func TestStoreCheckExpiration(t *testing.T) {
+	ts, err := secrets.NewTestStore(map[string]secrets.GenericSecret{
+		"secret/myservice/expiring": {
+			Type:       secrets.SimpleType,
+			Value:      "hunter2",
+			Expiration: time.Now().Add(time.Hour),
+		},
+		"secret/myservice/forever": {
+			Type:  secrets.SimpleType,
+			Value: "hunter3",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+	if err := ts.CheckExpiration(context.Background()); err != nil {
+		t.Errorf("Expected no error before expiring, got %v", err)
+	}
+
+	if err := ts.Set("secret/myservice/expired", secrets.GenericSecret{
+		Type:       secrets.SimpleType,
+		Value:      "hunter4",
+		Expiration: time.Now().Add(-time.Second),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	err = ts.CheckExpiration(context.Background())
+	if !errors.Is(err, secrets.ErrExpired) {
+		t.Fatalf("Expected ErrExpired, got %v", err)
+	}
+	const want = "secrets: expired: secret/myservice/expired"
+	if err.Error() != want {
+		t.Errorf("Expected error %q, got %q", want, err.Error())
+	}
+}
@@ -0,0 +1,160 @@
+package secrets
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoPEMBlock is returned by the PEM accessors of Secret when there's no PEM
+// block of the expected type in the secret.
+var ErrNoPEMBlock = errors.New("secrets: no PEM block of the expected type found")
+
+// The accessors below interpret the value of a secret in common formats.
+//
+// They work on the value already decoded according to the encoding of the
+// secret in the secrets file, e.g. for a PEM certificate stored with "base64"
+// encoding, AsPEMCertificate should be used directly on the decoded value,
+// while AsBase64Bytes is for the secrets stored with "identity" encoding but
+// base64 encoded by the application.
+
+// AsBase64Bytes decodes the secret as a base64 string,
+// in standard encoding with or without padding.
+//
+// Leading and trailing whitespaces are ignored.
+func (s Secret) AsBase64Bytes() ([]byte, error) {
+	value := strings.TrimSpace(string(s))
+	if strings.HasSuffix(value, "=") {
+		return base64.StdEncoding.DecodeString(value)
+	}
+	return base64.RawStdEncoding.DecodeString(value)
+}
+
+// AsCSV parses the secret as a single line of comma-separated values,
+// e.g. a list of API keys, with the whitespaces around the values trimmed.
+//
+// An empty secret returns no values.
+func (s Secret) AsCSV() ([]string, error) {
+	if strings.TrimSpace(string(s)) == "" {
+		return nil, nil
+	}
+	r := csv.NewReader(strings.NewReader(string(s)))
+	r.TrimLeadingSpace = true
+	values, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("secrets: invalid csv value: %w", err)
+	}
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return values, nil
+}
+
+// AsPEMCertificates parses all the "CERTIFICATE" PEM blocks in the secret,
+// e.g. a certificate chain.
+//
+// It returns an error wrapping ErrNoPEMBlock if there's no such block.
+func (s Secret) AsPEMCertificates() ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(s)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("secrets: invalid certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrNoPEMBlock, "CERTIFICATE")
+	}
+	return certs, nil
+}
+
+// AsPEMCertificate parses the first "CERTIFICATE" PEM block in the secret.
+//
+// It returns an error wrapping ErrNoPEMBlock if there's no such block.
+func (s Secret) AsPEMCertificate() (*x509.Certificate, error) {
+	certs, err := s.AsPEMCertificates()
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+// AsCertPool parses all the "CERTIFICATE" PEM blocks in the secret into a new
+// x509.CertPool, e.g. for the CA certificates.
+func (s Secret) AsCertPool() (*x509.CertPool, error) {
+	certs, err := s.AsPEMCertificates()
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
+
+// AsPrivateKey parses the first private key PEM block in the secret,
+// in PKCS #1 ("RSA PRIVATE KEY"), SEC 1 ("EC PRIVATE KEY"),
+// or PKCS #8 ("PRIVATE KEY") form.
+//
+// The returned key is one of *rsa.PrivateKey, *ecdsa.PrivateKey and
+// ed25519.PrivateKey.
+// It returns an error wrapping ErrNoPEMBlock if there's no such block.
+func (s Secret) AsPrivateKey() (crypto.PrivateKey, error) {
+	rest := []byte(s)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, fmt.Errorf("%w: %q", ErrNoPEMBlock, "PRIVATE KEY")
+		}
+		var (
+			key crypto.PrivateKey
+			err error
+		)
+		switch block.Type {
+		default:
+			continue
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("secrets: invalid %s: %w", strings.ToLower(block.Type), err)
+		}
+		return key, nil
+	}
+}
+
+// AsRSAPrivateKey is the same as AsPrivateKey,
+// but returns an error if the key is not an RSA key.
+func (s Secret) AsRSAPrivateKey() (*rsa.PrivateKey, error) {
+	key, err := s.AsPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("secrets: expected rsa private key, got %T", key)
+	}
+	return rsaKey, nil
+}
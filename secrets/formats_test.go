@@ -0,0 +1,145 @@
+package secrets_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+func TestSecretAsBase64Bytes(t *testing.T) {
+	for _, c := range []struct {
+		value    string
+		expected string
+	}{
+		{value: "aHVudGVyMg==", expected: "hunter2"},
+		{value: "aHVudGVyMg", expected: "hunter2"},
+		{value: " aHVudGVyMg==\n", expected: "hunter2"},
+	} {
+		got, err := secrets.Secret(c.value).AsBase64Bytes()
+		if err != nil {
+			t.Errorf("%q: %v", c.value, err)
+			continue
+		}
+		if string(got) != c.expected {
+			t.Errorf("%q: expected %q, got %q", c.value, c.expected, got)
+		}
+	}
+	if _, err := secrets.Secret("not base64!").AsBase64Bytes(); err == nil {
+		t.Error("Expected error for invalid base64")
+	}
+}
+
+func TestSecretAsCSV(t *testing.T) {
+	for _, c := range []struct {
+		value    string
+		expected []string
+	}{
+		{value: "", expected: nil},
+		{value: "foo", expected: []string{"foo"}},
+		{value: "foo, bar ,baz\n", expected: []string{"foo", "bar", "baz"}},
+		{value: `foo,"bar,baz"`, expected: []string{"foo", "bar,baz"}},
+	} {
+		got, err := secrets.Secret(c.value).AsCSV()
+		if err != nil {
+			t.Errorf("%q: %v", c.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("%q: expected %q, got %q", c.value, c.expected, got)
+		}
+	}
+}
+
+func TestSecretAsPEM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &rsaKey.PublicKey, rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	pkcs1PEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	pkcs8DER, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8PEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8DER})
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER})
+
+	t.Run("certificate", func(t *testing.T) {
+		// The key before the certificate should be skipped.
+		secret := secrets.Secret(append(append([]byte(nil), pkcs1PEM...), certPEM...))
+		cert, err := secret.AsPEMCertificate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cert.Subject.CommonName != "test" {
+			t.Errorf("Expected common name %q, got %q", "test", cert.Subject.CommonName)
+		}
+		if _, err := secret.AsCertPool(); err != nil {
+			t.Error(err)
+		}
+		if _, err := secrets.Secret(pkcs1PEM).AsPEMCertificate(); !errors.Is(err, secrets.ErrNoPEMBlock) {
+			t.Errorf("Expected ErrNoPEMBlock, got %v", err)
+		}
+	})
+
+	t.Run("rsa", func(t *testing.T) {
+		for label, value := range map[string][]byte{
+			"pkcs1": pkcs1PEM,
+			"pkcs8": pkcs8PEM,
+		} {
+			key, err := secrets.Secret(value).AsRSAPrivateKey()
+			if err != nil {
+				t.Errorf("%s: %v", label, err)
+				continue
+			}
+			if !key.Equal(rsaKey) {
+				t.Errorf("%s: unexpected key", label)
+			}
+		}
+		if _, err := secrets.Secret(ecPEM).AsRSAPrivateKey(); err == nil {
+			t.Error("Expected error for ec key")
+		}
+		if _, err := secrets.Secret(certPEM).AsRSAPrivateKey(); !errors.Is(err, secrets.ErrNoPEMBlock) {
+			t.Errorf("Expected ErrNoPEMBlock, got %v", err)
+		}
+	})
+
+	t.Run("ec", func(t *testing.T) {
+		key, err := secrets.Secret(ecPEM).AsPrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k, ok := key.(*ecdsa.PrivateKey); !ok || !k.Equal(ecKey) {
+			t.Errorf("Unexpected key %T", key)
+		}
+	})
+}
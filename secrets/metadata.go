@@ -0,0 +1,95 @@
+package secrets
+
+import (
+	"sort"
+	"time"
+)
+
+// Versions of the versioned secrets reported in Metadata.
+const (
+	VersionCurrent  = "current"
+	VersionPrevious = "previous"
+	VersionNext     = "next"
+)
+
+// Metadata is the metadata of a secret, without its values.
+//
+// It's safe to be exposed for debugging, e.g. by the admin server.
+type Metadata struct {
+	// Path is the path of the secret.
+	Path string `json:"path"`
+
+	// Type is the type of the secret,
+	// one of SimpleType, VersionedType and CredentialType.
+	Type string `json:"type"`
+
+	// Versions are the non-empty versions of the secret for VersionedType,
+	// in the order of VersionCurrent, VersionPrevious and VersionNext.
+	Versions []string `json:"versions,omitempty"`
+
+	// History is the number of the historical versions of the secret for
+	// VersionedType, in addition to Versions.
+	History int `json:"history,omitempty"`
+
+	// Expiration is when the secret expires,
+	// nil if it never expires (or the expiration is unknown).
+	Expiration *time.Time `json:"expiration,omitempty"`
+}
+
+// Metadata returns the metadata of all the secrets, sorted by their paths.
+func (s *Secrets) Metadata() []Metadata {
+	metadata := make([]Metadata, 0, len(s.simpleSecrets)+len(s.versionedSecrets)+len(s.credentialSecrets))
+	for path, secret := range s.simpleSecrets {
+		metadata = append(metadata, Metadata{
+			Path:       path,
+			Type:       SimpleType,
+			Expiration: expirationPtr(secret.Expiration),
+		})
+	}
+	for path, secret := range s.versionedSecrets {
+		var versions []string
+		for _, v := range []struct {
+			name   string
+			secret Secret
+		}{
+			{name: VersionCurrent, secret: secret.Current},
+			{name: VersionPrevious, secret: secret.Previous},
+			{name: VersionNext, secret: secret.Next},
+		} {
+			if !v.secret.IsEmpty() {
+				versions = append(versions, v.name)
+			}
+		}
+		metadata = append(metadata, Metadata{
+			Path:       path,
+			Type:       VersionedType,
+			Versions:   versions,
+			History:    len(secret.History),
+			Expiration: expirationPtr(secret.Expiration),
+		})
+	}
+	for path, secret := range s.credentialSecrets {
+		metadata = append(metadata, Metadata{
+			Path:       path,
+			Type:       CredentialType,
+			Expiration: expirationPtr(secret.Expiration),
+		})
+	}
+	sort.Slice(metadata, func(i, j int) bool {
+		return metadata[i].Path < metadata[j].Path
+	})
+	return metadata
+}
+
+// Metadata loads secrets from watcher, and returns the metadata of all the
+// secrets, sorted by their paths.
+func (s Store) Metadata() []Metadata {
+	return s.getSecrets().Metadata()
+}
+
+func expirationPtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,71 @@
+package secrets_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+func TestMetadata(t *testing.T) {
+	store, _, err := secrets.NewTestSecrets(
+		context.Background(),
+		map[string]secrets.GenericSecret{
+			"secret/myservice/simple": {
+				Type:  secrets.SimpleType,
+				Value: "hunter2",
+			},
+			"secret/myservice/versioned": {
+				Type:    secrets.VersionedType,
+				Current: "hunter3",
+				Next:    "hunter4",
+			},
+			"secret/myservice/db": {
+				Type:     secrets.CredentialType,
+				Username: "spez",
+				Password: "hunter5",
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	metadata := store.Metadata()
+	want := []secrets.Metadata{
+		{
+			// Added by NewTestSecrets.
+			Path:     secrets.JWTPubKeyPath,
+			Type:     secrets.VersionedType,
+			Versions: []string{secrets.VersionCurrent},
+		},
+		{
+			Path: "secret/myservice/db",
+			Type: secrets.CredentialType,
+		},
+		{
+			Path: "secret/myservice/simple",
+			Type: secrets.SimpleType,
+		},
+		{
+			Path:     "secret/myservice/versioned",
+			Type:     secrets.VersionedType,
+			Versions: []string{secrets.VersionCurrent, secrets.VersionNext},
+		},
+	}
+	if !reflect.DeepEqual(metadata, want) {
+		t.Errorf("Expected %#v, got %#v", want, metadata)
+	}
+
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(encoded), "hunter") {
+		t.Errorf("Expected no secret values in metadata, got %s", encoded)
+	}
+}
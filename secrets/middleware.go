@@ -0,0 +1,137 @@
+package secrets
+
+import (
+	"sync"
+)
+
+// MiddlewareID identifies a middleware registered by Store.AddMiddleware,
+// to be removed by Store.RemoveMiddleware.
+type MiddlewareID uint64
+
+type registeredMiddleware struct {
+	id         MiddlewareID
+	middleware SecretMiddleware
+}
+
+// middlewareChain is the chain of the SecretMiddlewares of a Store.
+//
+// All the changes to the chain and the calls to it are serialized by lock,
+// so the middlewares always see the changes of the secrets in order.
+type middlewareChain struct {
+	lock        sync.Mutex
+	middlewares []registeredMiddleware
+	lastID      MiddlewareID
+	handler     SecretHandlerFunc
+	latest      *Secrets
+}
+
+func newMiddlewareChain(middlewares ...SecretMiddleware) *middlewareChain {
+	c := &middlewareChain{
+		handler: nopSecretHandlerFunc,
+	}
+	c.add(middlewares...)
+	c.build()
+	return c
+}
+
+// add must be called with lock held.
+func (c *middlewareChain) add(middlewares ...SecretMiddleware) MiddlewareID {
+	for _, m := range middlewares {
+		c.lastID++
+		c.middlewares = append(c.middlewares, registeredMiddleware{
+			id:         c.lastID,
+			middleware: m,
+		})
+	}
+	return c.lastID
+}
+
+// build rebuilds the handler from the middlewares, must be called with lock
+// held.
+//
+// The middlewares registered later wrap the ones registered earlier.
+func (c *middlewareChain) build() {
+	c.handler = nopSecretHandlerFunc
+	for _, m := range c.middlewares {
+		c.handler = m.middleware(c.handler)
+	}
+}
+
+// rerun calls the handler with the latest secrets, must be called with lock
+// held.
+func (c *middlewareChain) rerun() {
+	if c.latest != nil {
+		c.handler(c.latest)
+	}
+}
+
+func (c *middlewareChain) handle(secrets *Secrets) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.latest = secrets
+	c.handler(secrets)
+}
+
+// AddMiddlewares registers new middlewares to the store.
+//
+// Every AddMiddlewares call will cause all already registered middlewares to be
+// called again with the latest data.
+//
+// It's safe to be called concurrently, but it must not be called from within a
+// middleware of the same store.
+func (s *Store) AddMiddlewares(middlewares ...SecretMiddleware) {
+	s.middlewares.lock.Lock()
+	defer s.middlewares.lock.Unlock()
+	s.middlewares.add(middlewares...)
+	s.middlewares.build()
+	s.middlewares.rerun()
+}
+
+// AddMiddleware is the same as AddMiddlewares with a single middleware,
+// and returns the MiddlewareID to remove it via RemoveMiddleware.
+func (s *Store) AddMiddleware(middleware SecretMiddleware) MiddlewareID {
+	s.middlewares.lock.Lock()
+	defer s.middlewares.lock.Unlock()
+	id := s.middlewares.add(middleware)
+	s.middlewares.build()
+	s.middlewares.rerun()
+	return id
+}
+
+// RemoveMiddleware removes the middleware registered by AddMiddleware from the
+// store, and returns false if it's not registered (e.g. already removed).
+//
+// Unlike AddMiddlewares, the other middlewares are not called again.
+//
+// It's safe to be called concurrently, but it must not be called from within a
+// middleware of the same store.
+func (s *Store) RemoveMiddleware(id MiddlewareID) bool {
+	s.middlewares.lock.Lock()
+	defer s.middlewares.lock.Unlock()
+	for i, m := range s.middlewares.middlewares {
+		if m.id == id {
+			s.middlewares.middlewares = append(s.middlewares.middlewares[:i:i], s.middlewares.middlewares[i+1:]...)
+			s.middlewares.build()
+			return true
+		}
+	}
+	return false
+}
+
+// ReplaceMiddlewares replaces all the registered middlewares of the store,
+// including the ones passed into the constructor of the store,
+// with the given ones, and calls them with the latest data.
+//
+// It's useful for the long-running services to re-register the middlewares
+// when their components are hot-swapped.
+//
+// It's safe to be called concurrently, but it must not be called from within a
+// middleware of the same store.
+func (s *Store) ReplaceMiddlewares(middlewares ...SecretMiddleware) {
+	s.middlewares.lock.Lock()
+	defer s.middlewares.lock.Unlock()
+	s.middlewares.middlewares = nil
+	s.middlewares.add(middlewares...)
+	s.middlewares.build()
+	s.middlewares.rerun()
+}
@@ -0,0 +1,181 @@
+package secrets
+
+import (
+	"context"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// multiFileWatcher implements filewatcher.FileWatcher by watching multiple
+// secrets files and merging them.
+type multiFileWatcher struct {
+	store *Store
+	aead  cipher.AEAD  // nil if the files are not encrypted
+	data  atomic.Value // *Secrets
+
+	lock      sync.Mutex
+	documents []*Document
+	watchers  []filewatcher.FileWatcher
+}
+
+var _ filewatcher.FileWatcher = (*multiFileWatcher)(nil)
+
+// NewMultiFileStore returns a new instance of Store reading the secrets from
+// multiple secrets.json files merged in order,
+// where the secrets in the later files override the ones with the same paths
+// in the earlier files (and so does the non-empty vault section),
+// e.g. for the secrets split into multiple files by their owners.
+//
+// Every path could also be a glob pattern (see filepath.Match), which is
+// expanded in lexical order when NewMultiFileStore is called.
+// A pattern matching no files is an error,
+// while a plain path not available yet is waited for like NewStore.
+//
+// All the files are watched for changes,
+// and the secrets are merged again when any of them changes.
+//
+// Context should come with a timeout otherwise this might block forever, i.e.
+// if any of the paths never becomes available.
+func NewMultiFileStore(ctx context.Context, paths []string, logger log.Wrapper, middlewares ...SecretMiddleware) (*Store, error) {
+	return newMultiFileStore(ctx, paths, nil, logger, middlewares...)
+}
+
+// newMultiFileStore is NewMultiFileStore with the files encrypted with aead
+// when it's non-nil, see NewEncryptedStore.
+func newMultiFileStore(ctx context.Context, paths []string, aead cipher.AEAD, logger log.Wrapper, middlewares ...SecretMiddleware) (*Store, error) {
+	files, err := expandPaths(paths)
+	if err != nil {
+		return nil, fmt.Errorf("secrets.NewMultiFileStore: %w", err)
+	}
+
+	store := newStore(middlewares...)
+	store.reloads = newReloadTracker(ProviderVault)
+	w := &multiFileWatcher{
+		store:     store,
+		aead:      aead,
+		documents: make([]*Document, len(files)),
+	}
+	for i, file := range files {
+		result, err := filewatcher.New(
+			ctx,
+			filewatcher.Config{
+				Path:          file,
+				Parser:        w.parser(i, file),
+				Logger:        logger,
+				SkipUnchanged: true,
+			},
+		)
+		if err != nil {
+			w.Stop()
+			return nil, err
+		}
+		w.lock.Lock()
+		w.watchers = append(w.watchers, result)
+		w.lock.Unlock()
+	}
+
+	store.watcher = w
+	trackers.register(store.reloads)
+	return store, nil
+}
+
+// expandPaths expands the glob patterns in paths.
+func expandPaths(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no paths")
+	}
+	var files []string
+	for _, path := range paths {
+		if !strings.ContainsAny(path, `*?[\`) {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", path, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", path)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// parser returns the filewatcher.Parser of the i-th file.
+func (w *multiFileWatcher) parser(i int, path string) filewatcher.Parser {
+	return func(r io.Reader) (interface{}, error) {
+		if w.aead != nil {
+			decrypted, err := decryptReader(w.aead, r)
+			if err != nil {
+				w.store.reloads.failed()
+				return nil, fmt.Errorf("secrets: invalid secrets file %q: %w", path, err)
+			}
+			r = decrypted
+		}
+		document, err := parseSecretsFile(r)
+		if err != nil {
+			w.store.reloads.failed()
+			return nil, fmt.Errorf("secrets: invalid secrets file %q: %w", path, err)
+		}
+
+		w.lock.Lock()
+		defer w.lock.Unlock()
+		w.documents[i] = &document
+		if err := w.mergeLocked(); err != nil {
+			w.store.reloads.failed()
+			return nil, err
+		}
+		return &document, nil
+	}
+}
+
+// mergeLocked merges the documents of all the files and replaces the current
+// secrets, it's no-op until all the files are loaded.
+//
+// It must be called with lock held.
+func (w *multiFileWatcher) mergeLocked() error {
+	merged := Document{
+		Secrets: make(map[string]GenericSecret),
+	}
+	for _, document := range w.documents {
+		if document == nil {
+			return nil
+		}
+		for path, secret := range document.Secrets {
+			merged.Secrets[path] = secret
+		}
+		if document.Vault != (Vault{}) {
+			merged.Vault = document.Vault
+		}
+	}
+	secrets, err := newSecretsFromDocument(merged)
+	if err != nil {
+		return err
+	}
+	w.store.handle(secrets)
+	w.data.Store(secrets)
+	return nil
+}
+
+func (w *multiFileWatcher) Get() interface{} {
+	return w.data.Load()
+}
+
+func (w *multiFileWatcher) Stop() {
+	w.lock.Lock()
+	watchers := w.watchers
+	w.lock.Unlock()
+	for _, watcher := range watchers {
+		watcher.Stop()
+	}
+}
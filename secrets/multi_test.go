@@ -0,0 +1,108 @@
+package secrets_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+func writeSecretsFile(t *testing.T, path, content string) {
+	t.Helper()
+	// Write to a temp file then rename, to avoid the watcher reading a partial
+	// file.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMultiFileStore(t *testing.T) {
+	dir := t.TempDir()
+	platform := filepath.Join(dir, "10-platform.json")
+	product := filepath.Join(dir, "20-product.json")
+	writeSecretsFile(t, platform, `{
+	"secrets": {
+		"secret/shared": {"type": "simple", "value": "platform"},
+		"secret/platform": {"type": "simple", "value": "platform"}
+	},
+	"vault": {"url": "vault.example.com", "token": "token"}
+}`)
+	writeSecretsFile(t, product, `{
+	"secrets": {
+		"secret/shared": {"type": "simple", "value": "product"},
+		"secret/product": {"type": "simple", "value": "product"}
+	}
+}`)
+
+	store, err := secrets.InitFromConfig(context.Background(), secrets.Config{
+		Paths: []string{filepath.Join(dir, "*.json")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	expect := func(t *testing.T, path, expected string) {
+		t.Helper()
+		secret, err := store.GetSimpleSecret(path)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			return
+		}
+		if string(secret.Value) != expected {
+			t.Errorf("%s: expected %q, got %q", path, expected, secret.Value)
+		}
+	}
+	expect(t, "secret/shared", "product")
+	expect(t, "secret/platform", "platform")
+	expect(t, "secret/product", "product")
+	if v, _ := store.GetVault(); v.URL != "vault.example.com" {
+		t.Errorf("Expected the vault from the platform file, got %+v", v)
+	}
+
+	writeSecretsFile(t, product, `{
+	"secrets": {
+		"secret/product": {"type": "simple", "value": "updated"}
+	}
+}`)
+	deadline := time.Now().Add(time.Second)
+	for {
+		secret, err := store.GetSimpleSecret("secret/product")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(secret.Value) == "updated" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("secrets not reloaded, got %q", secret.Value)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	expect(t, "secret/shared", "platform")
+}
+
+func TestMultiFileStoreErrors(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	if _, err := secrets.NewMultiFileStore(ctx, []string{filepath.Join(dir, "*.json")}, log.TestWrapper(t)); err == nil {
+		t.Error("Expected error for the pattern matching no files")
+	}
+
+	valid := filepath.Join(dir, "valid.json")
+	invalid := filepath.Join(dir, "invalid.json")
+	writeSecretsFile(t, valid, `{"secrets": {}}`)
+	writeSecretsFile(t, invalid, `{"secrets": {"secret/foo": {"type": "unknown"}}}`)
+	if _, err := secrets.NewMultiFileStore(ctx, []string{valid, invalid}, log.TestWrapper(t)); err == nil {
+		t.Error("Expected error for the invalid file")
+	}
+}
@@ -0,0 +1,69 @@
+package secrets
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus label names reported by secrets.
+const (
+	// PrometheusPathLabel is the label name of the secret paths.
+	PrometheusPathLabel = "secrets_path"
+
+	// PrometheusProviderLabel is the label name of the providers of the
+	// stores, see Config.Provider.
+	PrometheusProviderLabel = "secrets_provider"
+
+	// PrometheusSuccessLabel is the label name of whether the (re)loads
+	// succeeded.
+	PrometheusSuccessLabel = "secrets_success"
+)
+
+var (
+	expirationGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "secrets_expiration_timestamp_seconds",
+		Help: "The unix timestamp the secret expires at, only reported for the secrets with an expiration",
+	}, []string{
+		PrometheusPathLabel,
+	})
+
+	loadsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "secrets_loads_total",
+		Help: "Total number of the initial loads and reloads of the secrets, including the ones failed to read or parse",
+	}, []string{
+		PrometheusProviderLabel,
+		PrometheusSuccessLabel,
+	})
+)
+
+var lastLoadAgeDesc = prometheus.NewDesc(
+	"secrets_last_load_age_seconds",
+	"Seconds since the last successful load of the secrets, the maximum of all the open stores of the provider",
+	[]string{PrometheusProviderLabel},
+	nil,
+)
+
+// lastLoadAgeCollector is a prometheus.Collector reporting the seconds since
+// the last successful loads of the registered reloadTrackers.
+type lastLoadAgeCollector struct{}
+
+var _ prometheus.Collector = lastLoadAgeCollector{}
+
+func init() {
+	prometheus.MustRegister(lastLoadAgeCollector{})
+}
+
+// Describe implements prometheus.Collector.
+func (lastLoadAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- lastLoadAgeDesc
+}
+
+// Collect implements prometheus.Collector.
+func (lastLoadAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	for provider, age := range trackers.maxAges(now) {
+		ch <- prometheus.MustNewConstMetric(lastLoadAgeDesc, prometheus.GaugeValue, age.Seconds(), provider)
+	}
+}
@@ -0,0 +1,42 @@
+package secrets
+
+import (
+	"github.com/reddit/baseplate.go/log"
+)
+
+// LogRedactionOwner is the owner name used by LogRedactionMiddleware to
+// register the secret values via log.SetRedactedValues.
+const LogRedactionOwner = "secrets"
+
+// LogRedactionMiddleware is a SecretMiddleware that registers all the secret
+// values (and the vault token) to the redaction registry of the log package,
+// so that they are redacted from the logs of the global logger.
+//
+// The registered values are replaced every time the secrets are reloaded,
+// so rotated secrets are still redacted.
+func LogRedactionMiddleware(next SecretHandlerFunc) SecretHandlerFunc {
+	return func(sec *Secrets) {
+		log.SetRedactedValues(LogRedactionOwner, sec.allValues())
+		next(sec)
+	}
+}
+
+// allValues returns all the secret values in sec as strings.
+func (s *Secrets) allValues() []string {
+	values := make([]string, 0, len(s.simpleSecrets)+len(s.versionedSecrets)*3+len(s.credentialSecrets)+1)
+	for _, secret := range s.simpleSecrets {
+		values = append(values, string(secret.Value))
+	}
+	for _, secret := range s.versionedSecrets {
+		for _, v := range secret.GetAllVersions() {
+			values = append(values, string(v))
+		}
+	}
+	for _, secret := range s.credentialSecrets {
+		values = append(values, secret.Password)
+	}
+	if s.vault.Token != "" {
+		values = append(values, s.vault.Token)
+	}
+	return values
+}
@@ -0,0 +1,55 @@
+package secrets_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+func TestLogRedactionMiddleware(t *testing.T) {
+	defer log.SetRedactedValues(secrets.LogRedactionOwner, nil)
+
+	const password = "correct-horse-battery-staple"
+	_, _, err := secrets.NewTestSecrets(
+		context.Background(),
+		map[string]secrets.GenericSecret{
+			"secret/myservice/db": {
+				Type:     secrets.CredentialType,
+				Username: "spez",
+				Password: password,
+			},
+		},
+		secrets.LogRedactionMiddleware,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "log")
+	cfg := zap.NewProductionConfig()
+	cfg.OutputPaths = []string{path}
+	if err := log.InitLoggerWithConfig(log.InfoLevel, cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer log.InitLoggerJSON(log.InfoLevel)
+
+	log.Info("connecting with " + password)
+	log.Sync()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), password) {
+		t.Errorf("Expected password to be redacted, got %s", content)
+	}
+	if !strings.Contains(string(content), "connecting with "+log.RedactedValue) {
+		t.Errorf("Expected redacted message, got %s", content)
+	}
+}
@@ -0,0 +1,101 @@
+package secrets
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// reloadTracker reports the metrics of the loads of a Store reloading the
+// secrets from its provider.
+//
+// A nil *reloadTracker reports nothing, which is used by the stores never
+// reloading, e.g. the ones from NewEnvStore and NewTestStore.
+type reloadTracker struct {
+	provider string
+	last     int64 // unix nanoseconds of the last successful load, atomic
+}
+
+func newReloadTracker(provider string) *reloadTracker {
+	return &reloadTracker{provider: provider}
+}
+
+func (t *reloadTracker) succeeded() {
+	if t == nil {
+		return
+	}
+	atomic.StoreInt64(&t.last, time.Now().UnixNano())
+	loadsCounter.With(t.labels(true)).Inc()
+}
+
+func (t *reloadTracker) failed() {
+	if t == nil {
+		return
+	}
+	loadsCounter.With(t.labels(false)).Inc()
+}
+
+func (t *reloadTracker) labels(success bool) map[string]string {
+	return map[string]string{
+		PrometheusProviderLabel: t.provider,
+		PrometheusSuccessLabel:  strconv.FormatBool(success),
+	}
+}
+
+// age returns the duration since the last successful load,
+// or false if it never succeeded.
+func (t *reloadTracker) age(now time.Time) (time.Duration, bool) {
+	last := atomic.LoadInt64(&t.last)
+	if last == 0 {
+		return 0, false
+	}
+	return now.Sub(time.Unix(0, last)), true
+}
+
+// reloadTrackers are the reloadTrackers of the open stores.
+type reloadTrackers struct {
+	lock     sync.Mutex
+	trackers map[*reloadTracker]struct{}
+}
+
+var trackers reloadTrackers
+
+func (ts *reloadTrackers) register(t *reloadTracker) {
+	if t == nil {
+		return
+	}
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	if ts.trackers == nil {
+		ts.trackers = make(map[*reloadTracker]struct{})
+	}
+	ts.trackers[t] = struct{}{}
+}
+
+func (ts *reloadTrackers) unregister(t *reloadTracker) {
+	if t == nil {
+		return
+	}
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	delete(ts.trackers, t)
+}
+
+// maxAges returns the maximum ages of the registered trackers by their
+// providers.
+func (ts *reloadTrackers) maxAges(now time.Time) map[string]time.Duration {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	ages := make(map[string]time.Duration)
+	for t := range ts.trackers {
+		age, ok := t.age(now)
+		if !ok {
+			continue
+		}
+		if max, ok := ages[t.provider]; !ok || age > max {
+			ages[t.provider] = age
+		}
+	}
+	return ages
+}
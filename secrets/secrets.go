@@ -1,9 +1,9 @@
 package secrets
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/reddit/baseplate.go/errorsbp"
 )
@@ -79,6 +79,10 @@ func (s *Secrets) GetCredentialSecret(path string) (CredentialSecret, error) {
 // SimpleSecret represent basic secrets.
 type SimpleSecret struct {
 	Value Secret
+
+	// Expiration is when the secret expires,
+	// zero means it never expires (or the expiration is unknown).
+	Expiration time.Time
 }
 
 // Returns a new instance of SimpleSecret based on a
@@ -91,7 +95,8 @@ func newSimpleSecret(secret *GenericSecret) (SimpleSecret, error) {
 		return result, err
 	}
 	return SimpleSecret{
-		Value: value,
+		Value:      value,
+		Expiration: secret.Expiration,
 	}, nil
 }
 
@@ -100,7 +105,10 @@ func newSimpleSecret(secret *GenericSecret) (SimpleSecret, error) {
 // The Value of the SimpleSecret will be set as the Current value on the
 // VersionedSecret.
 func (s SimpleSecret) AsVersioned() VersionedSecret {
-	return VersionedSecret{Current: s.Value}
+	return VersionedSecret{
+		Current:    s.Value,
+		Expiration: s.Expiration,
+	}
 }
 
 // VersionedSecret represent secrets like signing keys that can be rotated
@@ -114,10 +122,20 @@ func (s SimpleSecret) AsVersioned() VersionedSecret {
 // secret respectively. These MAY be used by applications to give a grace
 // period for cryptographic tokens generated during a rotation, but SHOULD NOT
 // be used to generate new cryptographic tokens.
+//
+// The history field contains the versions older than the previous one, from
+// the newest to the oldest, for the rotation windows longer than a single
+// rotation. Like previous, they SHOULD NOT be used to generate new
+// cryptographic tokens.
 type VersionedSecret struct {
 	Current  Secret
 	Previous Secret
 	Next     Secret
+	History  []Secret
+
+	// Expiration is when the current version expires,
+	// zero means it never expires (or the expiration is unknown).
+	Expiration time.Time
 }
 
 // Returns a new instance of VersionedSecret based on a
@@ -142,10 +160,22 @@ func newVersionedSecret(secret *GenericSecret) (VersionedSecret, error) {
 	if err != nil {
 		return result, err
 	}
+	var history []Secret
+	for _, h := range secret.History {
+		historySecret, err := secret.Encoding.decodeValue(h)
+		if err != nil {
+			return result, err
+		}
+		if !historySecret.IsEmpty() {
+			history = append(history, historySecret)
+		}
+	}
 	return VersionedSecret{
-		Current:  currentSecret,
-		Previous: previousSecret,
-		Next:     nextSecret,
+		Current:    currentSecret,
+		Previous:   previousSecret,
+		Next:       nextSecret,
+		History:    history,
+		Expiration: secret.Expiration,
 	}, nil
 }
 
@@ -162,20 +192,41 @@ func (v *VersionedSecret) GetAll() []Secret {
 	return allVersions
 }
 
+// GetAllVersions returns all versions that are not empty in the following
+// order: current, previous, next, and then the history from the newest to the
+// oldest.
+//
+// Unlike GetAll, it includes the history, so it should be used to verify the
+// cryptographic tokens generated during long rotation windows.
+func (v *VersionedSecret) GetAllVersions() []Secret {
+	allVersions := v.GetAll()
+	for _, h := range v.History {
+		if !h.IsEmpty() {
+			allVersions = append(allVersions, h)
+		}
+	}
+	return allVersions
+}
+
 // CredentialSecret represent represent username/password pairs as a single
 // secret in vault. Note that usernames are not generally considered secret,
 // but they are tied to passwords.
 type CredentialSecret struct {
 	Username string
 	Password string
+
+	// Expiration is when the credential expires,
+	// zero means it never expires (or the expiration is unknown).
+	Expiration time.Time
 }
 
 // NewCredentialSecret returns a new instance of CredentialSecret based on a
 // GenericSecret from Document.
 func newCredentialSecret(secret *GenericSecret) (CredentialSecret, error) {
 	return CredentialSecret{
-		Username: secret.Username,
-		Password: secret.Password,
+		Username:   secret.Username,
+		Password:   secret.Password,
+		Expiration: secret.Expiration,
 	}, nil
 }
 
@@ -217,7 +268,7 @@ func (s *Document) Validate() error {
 }
 
 func notOnlySimpleSecret(secret GenericSecret) bool {
-	return secret.Current != "" || secret.Previous != "" || secret.Next != "" || secret.Username != "" || secret.Password != ""
+	return secret.Current != "" || secret.Previous != "" || secret.Next != "" || len(secret.History) > 0 || secret.Username != "" || secret.Password != ""
 }
 
 func notOnlyVersionedSecret(secret GenericSecret) bool {
@@ -225,7 +276,7 @@ func notOnlyVersionedSecret(secret GenericSecret) bool {
 }
 
 func notOnlyCredentialSecret(secret GenericSecret) bool {
-	return secret.Value != "" || secret.Current != "" || secret.Previous != "" || secret.Next != ""
+	return secret.Value != "" || secret.Current != "" || secret.Previous != "" || secret.Next != "" || len(secret.History) > 0
 }
 
 // GenericSecret is a placeholder to fit all types of secrets when parsing the
@@ -239,8 +290,16 @@ type GenericSecret struct {
 	Previous string `json:"previous"`
 	Next     string `json:"next"`
 
+	// History is optional for VersionedType secrets, the versions older than
+	// Previous, from the newest to the oldest.
+	History []string `json:"history"`
+
 	Username string `json:"username"`
 	Password string `json:"password"`
+
+	// Expiration is optional, in RFC 3339 format,
+	// e.g. "2006-01-02T15:04:05Z".
+	Expiration time.Time `json:"expiration"`
 }
 
 // Vault provides authentication credentials so that applications can directly
@@ -251,50 +310,73 @@ type Vault struct {
 }
 
 // NewSecrets parses and validates the secret JSON provided by the reader.
+//
+// The errors of the malformed secrets are reported as ValidationErrors with
+// their locations in the JSON, see ValidateSecretsFile.
 func NewSecrets(r io.Reader) (*Secrets, error) {
-	var secretsDocument Document
-	err := json.NewDecoder(r).Decode(&secretsDocument)
+	secretsDocument, err := parseSecretsFile(r)
 	if err != nil {
 		return nil, err
 	}
+	return newSecretsFromDocument(secretsDocument)
+}
 
-	err = secretsDocument.Validate()
+// newSecretsFromDocument validates the Document and builds the Secrets from it.
+func newSecretsFromDocument(secretsDocument Document) (*Secrets, error) {
+	err := secretsDocument.Validate()
 	if err != nil {
 		return nil, err
 	}
-	secrets := &Secrets{
+	secrets := newEmptySecrets()
+	secrets.vault = secretsDocument.Vault
+	for key, secret := range secretsDocument.Secrets {
+		if err := secrets.add(key, secret); err != nil {
+			return nil, &ValidationError{
+				Path: key,
+				Err:  err,
+			}
+		}
+	}
+	return secrets, nil
+}
+
+func newEmptySecrets() *Secrets {
+	return &Secrets{
 		simpleSecrets:     make(map[string]SimpleSecret),
 		versionedSecrets:  make(map[string]VersionedSecret),
 		credentialSecrets: make(map[string]CredentialSecret),
-		vault:             secretsDocument.Vault,
 	}
-	for key, secret := range secretsDocument.Secrets {
-		switch secret.Type {
-		case "simple":
-			simple, err := newSimpleSecret(&secret)
-			if err != nil {
-				return nil, err
-			}
-			secrets.simpleSecrets[key] = simple
-		case "versioned":
-			versioned, err := newVersionedSecret(&secret)
-			if err != nil {
-				return nil, err
-			}
-			secrets.versionedSecrets[key] = versioned
-		case "credential":
-			credential, err := newCredentialSecret(&secret)
-			if err != nil {
-				return nil, err
-			}
-			secrets.credentialSecrets[key] = credential
-		default:
-			return nil, fmt.Errorf(
-				"secrets.NewSecrets: encountered unknown secret type %q for secret %q",
-				secret.Type,
-				key,
-			)
+}
+
+// add decodes the secret and adds it to s with path.
+func (s *Secrets) add(path string, secret GenericSecret) error {
+	switch secret.Type {
+	case SimpleType:
+		simple, err := newSimpleSecret(&secret)
+		if err != nil {
+			return err
+		}
+		s.simpleSecrets[path] = simple
+	case VersionedType:
+		versioned, err := newVersionedSecret(&secret)
+		if err != nil {
+			return err
 		}
+		s.versionedSecrets[path] = versioned
+	case CredentialType:
+		credential, err := newCredentialSecret(&secret)
+		if err != nil {
+			return err
+		}
+		s.credentialSecrets[path] = credential
+	default:
+		return fmt.Errorf(
+			"unknown secret type %q, expected %q, %q or %q",
+			secret.Type,
+			SimpleType,
+			VersionedType,
+			CredentialType,
+		)
 	}
-	return secrets, nil
+	return nil
 }
@@ -64,6 +64,51 @@ func TestNewSecrets(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "history",
+			input: `
+					{
+						"secrets": {
+							"secret/myservice/signing-key": {
+								"type": "versioned",
+								"current": "Zm9v",
+								"previous": "YmFy",
+								"history": ["YmF6", "", "cXV4"],
+								"encoding": "base64"
+							}
+						}
+					}
+			`,
+			expected: &Secrets{
+				simpleSecrets: make(map[string]SimpleSecret),
+				versionedSecrets: map[string]VersionedSecret{
+					"secret/myservice/signing-key": {
+						Current:  Secret("foo"),
+						Previous: Secret("bar"),
+						History:  []Secret{Secret("baz"), Secret("qux")},
+					},
+				},
+				credentialSecrets: make(map[string]CredentialSecret),
+			},
+		},
+		{
+			name: "history on simple secret",
+			input: `
+					{
+						"secrets": {
+							"secret/myservice/some-api-key": {
+								"type": "simple",
+								"value": "hunter2",
+								"history": ["hunter1"]
+							}
+						}
+					}
+			`,
+			expectedError: TooManyFieldsError{
+				SecretType: SimpleType,
+				Key:        "secret/myservice/some-api-key",
+			},
+		},
 		{
 			name:  "empty",
 			input: `{}`,
@@ -118,3 +163,25 @@ func TestNewSecrets(t *testing.T) {
 		})
 	}
 }
+
+func TestVersionedSecretGetAllVersions(t *testing.T) {
+	secret := VersionedSecret{
+		Current:  Secret("current"),
+		Previous: Secret("previous"),
+		Next:     Secret("next"),
+		History:  []Secret{Secret("older"), nil, Secret("oldest")},
+	}
+	expected := []Secret{
+		Secret("current"),
+		Secret("previous"),
+		Secret("next"),
+		Secret("older"),
+		Secret("oldest"),
+	}
+	if got := secret.GetAllVersions(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected GetAllVersions to return %q, got %q", expected, got)
+	}
+	if got := secret.GetAll(); len(got) != 3 {
+		t.Errorf("Expected GetAll to return 3 versions without the history, got %q", got)
+	}
+}
@@ -28,27 +28,38 @@ func nopSecretHandlerFunc(sec *Secrets) {}
 type Store struct {
 	watcher filewatcher.FileWatcher
 
-	secretHandlerFunc SecretHandlerFunc
+	middlewares *middlewareChain
+	subscribers *subscribers
+	reloads     *reloadTracker
+}
+
+func newStore(middlewares ...SecretMiddleware) *Store {
+	return &Store{
+		middlewares: newMiddlewareChain(middlewares...),
+		subscribers: new(subscribers),
+	}
 }
 
 // NewStore returns a new instance of Store by configuring it
 // with a filewatcher to watch the file in path for changes ensuring secrets
 // store will always return up to date secrets.
 //
+// The file is only reloaded when its content changed,
+// so touching the file without changing it doesn't call the middlewares.
+//
 // Context should come with a timeout otherwise this might block forever, i.e.
 // if the path never becomes available.
 func NewStore(ctx context.Context, path string, logger log.Wrapper, middlewares ...SecretMiddleware) (*Store, error) {
-	store := &Store{
-		secretHandlerFunc: nopSecretHandlerFunc,
-	}
-	store.secretHandler(middlewares...)
+	store := newStore(middlewares...)
+	store.reloads = newReloadTracker(ProviderVault)
 
 	result, err := filewatcher.New(
 		ctx,
 		filewatcher.Config{
-			Path:   path,
-			Parser: store.parser,
-			Logger: logger,
+			Path:          path,
+			Parser:        store.parser,
+			Logger:        logger,
+			SkipUnchanged: true,
 		},
 	)
 	if err != nil {
@@ -56,25 +67,29 @@ func NewStore(ctx context.Context, path string, logger log.Wrapper, middlewares
 	}
 
 	store.watcher = result
+	trackers.register(store.reloads)
 	return store, nil
 }
 
 func (s *Store) parser(r io.Reader) (interface{}, error) {
 	secrets, err := NewSecrets(r)
 	if err != nil {
+		s.reloads.failed()
 		return nil, err
 	}
 
-	s.secretHandlerFunc(secrets)
+	s.handle(secrets)
 
 	return secrets, nil
 }
 
-// secretHandler creates the middleware chain.
-func (s *Store) secretHandler(middlewares ...SecretMiddleware) {
-	for _, m := range middlewares {
-		s.secretHandlerFunc = m(s.secretHandlerFunc)
-	}
+// handle calls the middlewares and the subscribers with the newly loaded
+// secrets, and updates the metrics of them and of the reload.
+func (s *Store) handle(secrets *Secrets) {
+	s.middlewares.handle(secrets)
+	old := s.subscribers.notify(secrets)
+	reportExpirations(old, secrets)
+	s.reloads.succeeded()
 }
 
 func (s *Store) getSecrets() *Secrets {
@@ -91,20 +106,10 @@ func (s *Store) getSecrets() *Secrets {
 // Close doesn't return non-nil errors, but implements io.Closer.
 func (s *Store) Close() error {
 	s.watcher.Stop()
+	trackers.unregister(s.reloads)
 	return nil
 }
 
-// AddMiddlewares registers new middlewares to the store.
-//
-// Every AddMiddlewares call will cause all already registered middlewares to be
-// called again with the latest data.
-//
-// AddMiddlewares call is not thread-safe, it should not be called concurrently.
-func (s *Store) AddMiddlewares(middlewares ...SecretMiddleware) {
-	s.secretHandler(middlewares...)
-	s.secretHandlerFunc(s.getSecrets())
-}
-
 // GetSimpleSecret loads secrets from watcher, and fetches a simple secret from secrets
 func (s Store) GetSimpleSecret(path string) (SimpleSecret, error) {
 	return s.getSecrets().GetSimpleSecret(path)
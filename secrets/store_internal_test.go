@@ -3,7 +3,11 @@ package secrets
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
 	"github.com/reddit/baseplate.go/log"
 )
@@ -77,3 +81,74 @@ func TestNewStore(t *testing.T) {
 		)
 	}
 }
+
+func TestReportExpirations(t *testing.T) {
+	const path = "secret/test-report-expirations"
+	expiration := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	old, err := newSecretsFromDocument(Document{
+		Secrets: map[string]GenericSecret{
+			path: {
+				Type:       SimpleType,
+				Value:      "hunter2",
+				Expiration: expiration,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reportExpirations(nil, old)
+	if got, want := testutil.ToFloat64(expirationGauge.WithLabelValues(path)), float64(expiration.Unix()); got != want {
+		t.Errorf("Expected expiration gauge %v, got %v", want, got)
+	}
+
+	new, err := newSecretsFromDocument(Document{
+		Secrets: map[string]GenericSecret{
+			path: {
+				Type:  SimpleType,
+				Value: "hunter3",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reportExpirations(old, new)
+	if expirationGauge.DeleteLabelValues(path) {
+		t.Error("Expected the expiration gauge to be removed when the secret no longer expires")
+	}
+}
+
+func TestReloadMetrics(t *testing.T) {
+	const provider = "test-reload-metrics"
+	store := newStore()
+	store.reloads = newReloadTracker(provider)
+	failed := loadsCounter.With(store.reloads.labels(false))
+	succeeded := loadsCounter.With(store.reloads.labels(true))
+	failedBefore := testutil.ToFloat64(failed)
+	succeededBefore := testutil.ToFloat64(succeeded)
+
+	if _, err := store.parser(strings.NewReader("{")); err == nil {
+		t.Fatal("Expected parser to fail on invalid JSON")
+	}
+	if _, err := store.parser(strings.NewReader(`{"secrets": {}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(failed) - failedBefore; got != 1 {
+		t.Errorf("Expected 1 failed load, got %v", got)
+	}
+	if got := testutil.ToFloat64(succeeded) - succeededBefore; got != 1 {
+		t.Errorf("Expected 1 successful load, got %v", got)
+	}
+
+	trackers.register(store.reloads)
+	age, ok := trackers.maxAges(time.Now().Add(time.Minute))[provider]
+	if !ok || age < time.Minute {
+		t.Errorf("Expected last load age of at least 1m, got %v, %v", age, ok)
+	}
+	trackers.unregister(store.reloads)
+	if _, ok := trackers.maxAges(time.Now())[provider]; ok {
+		t.Error("Expected no last load age after unregistering")
+	}
+}
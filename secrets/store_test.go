@@ -3,7 +3,11 @@ package secrets_test
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -422,3 +426,125 @@ func TestAddMiddleware(t *testing.T) {
 		)
 	}
 }
+
+func TestStoreRemoveAndReplaceMiddlewares(t *testing.T) {
+	var lock sync.Mutex
+	calls := make(map[string]int)
+	counting := func(name string) secrets.SecretMiddleware {
+		return func(next secrets.SecretHandlerFunc) secrets.SecretHandlerFunc {
+			return func(sec *secrets.Secrets) {
+				lock.Lock()
+				calls[name]++
+				lock.Unlock()
+				next(sec)
+			}
+		}
+	}
+	expectCalls := func(t *testing.T, expected map[string]int) {
+		t.Helper()
+		lock.Lock()
+		defer lock.Unlock()
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("Expected middleware calls %v, got %v", expected, calls)
+		}
+	}
+
+	store, err := secrets.NewTestStore(nil, counting("initial"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	expectCalls(t, map[string]int{"initial": 1})
+
+	id := store.AddMiddleware(counting("added"))
+	expectCalls(t, map[string]int{"initial": 2, "added": 1})
+
+	if !store.RemoveMiddleware(id) {
+		t.Error("Expected RemoveMiddleware to return true for the added middleware")
+	}
+	if store.RemoveMiddleware(id) {
+		t.Error("Expected RemoveMiddleware to return false for the removed middleware")
+	}
+	if err := store.Set("secret/myservice/foo", secrets.GenericSecret{Type: secrets.SimpleType, Value: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	expectCalls(t, map[string]int{"initial": 3, "added": 1})
+
+	store.ReplaceMiddlewares(counting("replaced"))
+	expectCalls(t, map[string]int{"initial": 3, "added": 1, "replaced": 1})
+	if err := store.Delete("secret/myservice/foo"); err != nil {
+		t.Fatal(err)
+	}
+	expectCalls(t, map[string]int{"initial": 3, "added": 1, "replaced": 2})
+}
+
+func TestStoreMiddlewaresConcurrency(t *testing.T) {
+	store, err := secrets.NewTestStore(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	nop := func(next secrets.SecretHandlerFunc) secrets.SecretHandlerFunc {
+		return next
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			store.RemoveMiddleware(store.AddMiddleware(nop))
+			store.ReplaceMiddlewares(nop, nop)
+		}()
+		go func() {
+			defer wg.Done()
+			if err := store.Set("secret/myservice/foo", secrets.GenericSecret{Type: secrets.SimpleType, Value: "foo"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestStoreSkipsUnchangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	writeSecretsFile(t, path, specificationExample)
+
+	var calls int64
+	store, err := secrets.NewStore(context.Background(), path, log.TestWrapper(t), func(next secrets.SecretHandlerFunc) secrets.SecretHandlerFunc {
+		return func(sec *secrets.Secrets) {
+			atomic.AddInt64(&calls, 1)
+			next(sec)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// Rewrite the file with the same content, then with a different one.
+	writeSecretsFile(t, path, specificationExample)
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		t.Fatal(err)
+	}
+	writeSecretsFile(t, path, strings.Replace(specificationExample, "hunter2", "hunter3", 1))
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		secret, err := store.GetCredentialSecret("secret/myservice/some-database-credentials")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret.Password == "hunter3" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("secrets not reloaded")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if got := atomic.LoadInt64(&calls); got != 2 {
+		t.Errorf("Expected the middleware to be called twice, got %d", got)
+	}
+}
@@ -0,0 +1,135 @@
+package secrets
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// ChangeFunc is the callback registered by Store.Subscribe,
+// called with the Secrets before and after a reload.
+type ChangeFunc func(old, new *Secrets)
+
+type subscription struct {
+	f ChangeFunc
+}
+
+// subscribers are the ChangeFuncs subscribed to a Store.
+type subscribers struct {
+	lock  sync.Mutex
+	last  *Secrets
+	subs  []*subscription
+	calls sync.Mutex
+}
+
+// notify records sec as the latest Secrets,
+// calls the subscribers with the previous and the new ones,
+// and returns the previous one.
+func (s *subscribers) notify(sec *Secrets) *Secrets {
+	// Serialize the calls so the subscribers always see the changes in order.
+	s.calls.Lock()
+	defer s.calls.Unlock()
+
+	s.lock.Lock()
+	old := s.last
+	s.last = sec
+	subs := append([]*subscription(nil), s.subs...)
+	s.lock.Unlock()
+
+	if old == nil {
+		// The initial load.
+		return nil
+	}
+	for _, sub := range subs {
+		sub.f(old, sec)
+	}
+	return old
+}
+
+func (s *subscribers) add(f ChangeFunc) (unsubscribe func()) {
+	sub := &subscription{f: f}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.subs = append(s.subs, sub)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.lock.Lock()
+			defer s.lock.Unlock()
+			for i, existing := range s.subs {
+				if existing == sub {
+					s.subs = append(s.subs[:i:i], s.subs[i+1:]...)
+					return
+				}
+			}
+		})
+	}
+}
+
+// Subscribe registers f to be called every time the secrets are reloaded,
+// with the Secrets before and after the reload,
+// and returns the function to unsubscribe f.
+//
+// f is not called for the initial load of the Store,
+// and it's called on every reload even if nothing changed,
+// use ChangedPaths to find out the secrets actually changed, e.g. to only
+// reconnect the long-lived connections when their credentials changed:
+//
+//	unsubscribe := store.Subscribe(func(old, new *secrets.Secrets) {
+//		for _, path := range secrets.ChangedPaths(old, new) {
+//			if path == dbCredentialsPath {
+//				reconnect(new)
+//				return
+//			}
+//		}
+//	})
+//	defer unsubscribe()
+//
+// f is called synchronously in the reloading goroutine after the middlewares,
+// so it should not block,
+// and the getters of the Store might still return the old secrets when it's
+// called, use new instead.
+func (s *Store) Subscribe(f ChangeFunc) (unsubscribe func()) {
+	return s.subscribers.add(f)
+}
+
+// ChangedPaths returns the paths of the secrets added, removed, or changed
+// (including their types) from old to new, sorted.
+//
+// A nil *Secrets is treated as no secrets.
+func ChangedPaths(old, new *Secrets) []string {
+	oldEntries := old.entries()
+	newEntries := new.entries()
+	var paths []string
+	for path, value := range oldEntries {
+		if newValue, ok := newEntries[path]; !ok || !reflect.DeepEqual(value, newValue) {
+			paths = append(paths, path)
+		}
+	}
+	for path := range newEntries {
+		if _, ok := oldEntries[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// entries returns all the secrets by their paths.
+func (s *Secrets) entries() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+	entries := make(map[string]interface{}, len(s.simpleSecrets)+len(s.versionedSecrets)+len(s.credentialSecrets))
+	for path, secret := range s.simpleSecrets {
+		entries[path] = secret
+	}
+	for path, secret := range s.versionedSecrets {
+		entries[path] = secret
+	}
+	for path, secret := range s.credentialSecrets {
+		entries[path] = secret
+	}
+	return entries
+}
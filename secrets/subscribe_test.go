@@ -0,0 +1,125 @@
+package secrets_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+func TestSubscribe(t *testing.T) {
+	raw := map[string]secrets.GenericSecret{
+		"secret/myservice/db": {
+			Type:     secrets.CredentialType,
+			Username: "spez",
+			Password: "hunter2",
+		},
+		"secret/myservice/api-key": {
+			Type:  secrets.SimpleType,
+			Value: "foo",
+		},
+	}
+	store, fw, err := secrets.NewTestSecrets(context.Background(), raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var changes [][]string
+	var password string
+	unsubscribe := store.Subscribe(func(old, new *secrets.Secrets) {
+		changes = append(changes, secrets.ChangedPaths(old, new))
+		credential, err := new.GetCredentialSecret("secret/myservice/db")
+		if err != nil {
+			t.Error(err)
+		}
+		password = credential.Password
+	})
+	if len(changes) != 0 {
+		t.Fatalf("expected no calls on subscribe, got %q", changes)
+	}
+
+	// Reload without changes.
+	if err := secrets.UpdateTestSecrets(fw, raw); err != nil {
+		t.Fatal(err)
+	}
+	// Rotate the credential and add a new secret.
+	raw["secret/myservice/db"] = secrets.GenericSecret{
+		Type:     secrets.CredentialType,
+		Username: "spez",
+		Password: "hunter3",
+	}
+	raw["secret/myservice/new"] = secrets.GenericSecret{
+		Type:  secrets.SimpleType,
+		Value: "bar",
+	}
+	if err := secrets.UpdateTestSecrets(fw, raw); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		nil,
+		{"secret/myservice/db", "secret/myservice/new"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("expected changes %q, got %q", want, changes)
+	}
+	if password != "hunter3" {
+		t.Errorf("expected the new password in the callback, got %q", password)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	delete(raw, "secret/myservice/new")
+	if err := secrets.UpdateTestSecrets(fw, raw); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != len(want) {
+		t.Errorf("expected no calls after unsubscribe, got %q", changes[len(want):])
+	}
+}
+
+func TestChangedPaths(t *testing.T) {
+	newSecrets := func(raw map[string]secrets.GenericSecret) *secrets.Secrets {
+		t.Helper()
+		store, _, err := secrets.NewTestSecrets(context.Background(), raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sec *secrets.Secrets
+		store.AddMiddlewares(func(next secrets.SecretHandlerFunc) secrets.SecretHandlerFunc {
+			return func(s *secrets.Secrets) {
+				sec = s
+				next(s)
+			}
+		})
+		return sec
+	}
+
+	old := newSecrets(map[string]secrets.GenericSecret{
+		"removed":   {Type: secrets.SimpleType, Value: "foo"},
+		"unchanged": {Type: secrets.SimpleType, Value: "foo"},
+		"changed":   {Type: secrets.VersionedType, Current: "foo"},
+		"retyped":   {Type: secrets.SimpleType, Value: "foo"},
+	})
+	new := newSecrets(map[string]secrets.GenericSecret{
+		"added":     {Type: secrets.SimpleType, Value: "foo"},
+		"unchanged": {Type: secrets.SimpleType, Value: "foo"},
+		"changed":   {Type: secrets.VersionedType, Current: "bar", Previous: "foo"},
+		"retyped":   {Type: secrets.VersionedType, Current: "foo"},
+	})
+
+	want := []string{"added", "changed", "removed", "retyped"}
+	if got := secrets.ChangedPaths(old, new); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got := secrets.ChangedPaths(old, old); len(got) != 0 {
+		t.Errorf("expected no changes, got %q", got)
+	}
+	if got := secrets.ChangedPaths(nil, nil); len(got) != 0 {
+		t.Errorf("expected no changes, got %q", got)
+	}
+	if got := secrets.ChangedPaths(nil, new); len(got) != 5 {
+		// The 4 secrets plus the default JWTPubKeyPath.
+		t.Errorf("expected all secrets to be added, got %q", got)
+	}
+}
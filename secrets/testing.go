@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"sync"
+	"sync/atomic"
 
 	"github.com/reddit/baseplate.go/filewatcher"
 )
@@ -76,10 +78,7 @@ func NewTestSecrets(ctx context.Context, raw map[string]GenericSecret, middlewar
 		return nil, nil, err
 	}
 
-	store := &Store{
-		secretHandlerFunc: nopSecretHandlerFunc,
-	}
-	store.secretHandler(middlewares...)
+	store := newStore(middlewares...)
 
 	watcher, err := filewatcher.NewMockFilewatcher(&buf, store.parser)
 	if err != nil {
@@ -107,3 +106,115 @@ func UpdateTestSecrets(fw *filewatcher.MockFileWatcher, raw map[string]GenericSe
 	}
 	return fw.Update(&buf)
 }
+
+// TestStore is an in-memory Store for tests, returned by NewTestStore.
+//
+// Its secrets can be changed at runtime via Set, Delete and Update,
+// which run the middlewares and the subscribers of the Store synchronously,
+// without the JSON encoding or the file system involved.
+//
+// It's safe for concurrent use.
+type TestStore struct {
+	// Store is the Store backed by the in-memory secrets,
+	// to be passed to the code under test.
+	*Store
+
+	lock    sync.Mutex
+	raw     map[string]GenericSecret
+	watcher *memWatcher
+}
+
+// memWatcher is the filewatcher.FileWatcher of the secrets of a TestStore.
+type memWatcher struct {
+	data atomic.Value // *Secrets
+}
+
+func (w *memWatcher) Get() interface{} {
+	return w.data.Load()
+}
+
+func (w *memWatcher) Stop() {}
+
+// NewTestStore returns a TestStore initialized with the raw map of key to
+// GenericSecrets.
+//
+// This is provided to aid in testing and should not be used to create
+// production secrets.
+//
+// Like NewTestSecrets, if you do not provide a value for the key defined by
+// JWTPubKeyPath, then we will add a default secret for you.
+func NewTestStore(raw map[string]GenericSecret, middlewares ...SecretMiddleware) (*TestStore, error) {
+	ts := &TestStore{
+		Store:   newStore(middlewares...),
+		watcher: new(memWatcher),
+	}
+	ts.Store.watcher = ts.watcher
+	if err := ts.Update(raw); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// Update replaces all the secrets with the raw map of key to GenericSecrets.
+//
+// Like NewTestSecrets, if you do not provide a value for the key defined by
+// JWTPubKeyPath, then we will add a default secret for you.
+func (ts *TestStore) Update(raw map[string]GenericSecret) error {
+	clone := make(map[string]GenericSecret, len(raw))
+	for k, v := range raw {
+		clone[k] = v
+	}
+
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	return ts.loadLocked(clone)
+}
+
+// Set adds or replaces the secret at path.
+func (ts *TestStore) Set(path string, secret GenericSecret) error {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+
+	clone := ts.cloneLocked()
+	clone[path] = secret
+	return ts.loadLocked(clone)
+}
+
+// Delete removes the secret at path.
+//
+// Deleting JWTPubKeyPath restores the default secret for it.
+func (ts *TestStore) Delete(path string) error {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+
+	clone := ts.cloneLocked()
+	delete(clone, path)
+	return ts.loadLocked(clone)
+}
+
+func (ts *TestStore) cloneLocked() map[string]GenericSecret {
+	clone := make(map[string]GenericSecret, len(ts.raw))
+	for k, v := range ts.raw {
+		clone[k] = v
+	}
+	return clone
+}
+
+// loadLocked builds the secrets from raw and runs the middlewares and
+// subscribers with them.
+//
+// raw is owned by ts after the call.
+func (ts *TestStore) loadLocked(raw map[string]GenericSecret) error {
+	document, err := testDocument(raw)
+	if err != nil {
+		return err
+	}
+	secrets, err := newSecretsFromDocument(document)
+	if err != nil {
+		return err
+	}
+	ts.raw = raw
+	ts.Store.handle(secrets)
+	ts.watcher.data.Store(secrets)
+	return nil
+}
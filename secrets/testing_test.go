@@ -281,3 +281,75 @@ func TestUpdateTestSecrets(t *testing.T) {
 		)
 	}
 }
+
+func TestNewTestStore(t *testing.T) {
+	t.Parallel()
+
+	const path = "secret/myservice/api-key"
+	var middlewareCalls int
+	ts, err := secrets.NewTestStore(
+		map[string]secrets.GenericSecret{
+			path: {
+				Type:  secrets.SimpleType,
+				Value: "foo",
+			},
+		},
+		func(next secrets.SecretHandlerFunc) secrets.SecretHandlerFunc {
+			return func(sec *secrets.Secrets) {
+				middlewareCalls++
+				next(sec)
+			}
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if middlewareCalls != 1 {
+		t.Errorf("Expected middleware to be called once, got %d", middlewareCalls)
+	}
+	if _, err := ts.GetVersionedSecret(secrets.JWTPubKeyPath); err != nil {
+		t.Errorf("Expected the default %q secret: %v", secrets.JWTPubKeyPath, err)
+	}
+
+	var changed []string
+	ts.Subscribe(func(old, new *secrets.Secrets) {
+		changed = append(changed, secrets.ChangedPaths(old, new)...)
+	})
+
+	if err := ts.Set(path, secrets.GenericSecret{
+		Type:  secrets.SimpleType,
+		Value: "bar",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := ts.GetSimpleSecret(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(secret.Value), "bar"; got != want {
+		t.Errorf("Expected %q after Set, got %q", want, got)
+	}
+	if middlewareCalls != 2 {
+		t.Errorf("Expected middleware to be called on Set, got %d calls", middlewareCalls)
+	}
+
+	if err := ts.Delete(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.Store.GetSimpleSecret(path); err == nil {
+		t.Error("Expected the secret to be gone after Delete")
+	}
+	if got, want := fmt.Sprint(changed), fmt.Sprint([]string{path, path}); got != want {
+		t.Errorf("Expected changed paths %s, got %s", want, got)
+	}
+
+	if err := ts.Set(path, secrets.GenericSecret{
+		Type:  "unknown",
+		Value: "bar",
+	}); err == nil {
+		t.Error("Expected error for the invalid secret")
+	}
+	if _, err := ts.GetSimpleSecret(path); err == nil {
+		t.Error("Expected the invalid secret not to be applied")
+	}
+}
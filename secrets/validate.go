@@ -0,0 +1,204 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/reddit/baseplate.go/errorsbp"
+)
+
+// maxContextLength is the max length of ValidationError.Context.
+const maxContextLength = 80
+
+// ValidationError is the error of a malformed secrets file,
+// with the location of the error in the file when it's known.
+type ValidationError struct {
+	// Path is the path of the malformed secret,
+	// empty if the error is not specific to a secret (e.g. invalid JSON).
+	Path string
+
+	// Line and Column are the 1-based location of the error in the file,
+	// 0 if unknown.
+	//
+	// For the errors specific to a secret, it's the location of its path.
+	Line   int
+	Column int
+
+	// Context is the content of the line of the error, truncated.
+	Context string
+
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("secrets: ")
+	if e.Line > 0 {
+		fmt.Fprintf(&sb, "line %d, column %d: ", e.Line, e.Column)
+	}
+	if e.Path != "" {
+		fmt.Fprintf(&sb, "secret %q: ", e.Path)
+	}
+	sb.WriteString(strings.TrimPrefix(e.Err.Error(), "secrets: "))
+	if e.Context != "" {
+		fmt.Fprintf(&sb, " (near %q)", e.Context)
+	}
+	return sb.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateSecretsFile validates the secrets file (e.g. secrets.json) read from
+// r, and returns all the errors found in it,
+// so it can be used to check the secrets files in CI before deploying them.
+//
+// The errors of the malformed secrets or JSON are ValidationErrors with their
+// locations in the file,
+// and the secrets with unexpected fields for their types are reported as
+// TooManyFieldsErrors.
+// When there are multiple errors, they are returned as an errorsbp.Batch.
+func ValidateSecretsFile(r io.Reader) error {
+	_, err := parseSecretsFile(r)
+	return err
+}
+
+// parseSecretsFile parses the Document from r and validates all the secrets in
+// it.
+func parseSecretsFile(r io.Reader) (Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Document{}, err
+	}
+	var raw struct {
+		Secrets map[string]json.RawMessage `json:"secrets"`
+		Vault   Vault                      `json:"vault"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Document{}, newValidationError(data, "", jsonErrorOffset(err), err)
+	}
+
+	offsets := secretOffsets(data)
+	offset := func(path string) int64 {
+		if offset, ok := offsets[path]; ok {
+			return offset
+		}
+		return -1
+	}
+	paths := make([]string, 0, len(raw.Secrets))
+	for path := range raw.Secrets {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	document := Document{
+		Secrets: make(map[string]GenericSecret, len(raw.Secrets)),
+		Vault:   raw.Vault,
+	}
+	scratch := newEmptySecrets()
+	var batch errorsbp.Batch
+	for _, path := range paths {
+		var secret GenericSecret
+		if err := json.Unmarshal(raw.Secrets[path], &secret); err != nil {
+			batch.Add(newValidationError(data, path, offset(path), err))
+			continue
+		}
+		document.Secrets[path] = secret
+		if err := scratch.add(path, secret); err != nil {
+			batch.Add(newValidationError(data, path, offset(path), err))
+		}
+	}
+	batch.Add(document.Validate())
+	return document, batch.Compile()
+}
+
+// newValidationError creates a ValidationError located at offset of data,
+// offset <0 means unknown.
+func newValidationError(data []byte, path string, offset int64, err error) *ValidationError {
+	ve := &ValidationError{
+		Path: path,
+		Err:  err,
+	}
+	if offset < 0 || offset > int64(len(data)) {
+		return ve
+	}
+	before := data[:offset]
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	ve.Line = bytes.Count(before, []byte{'\n'}) + 1
+	ve.Column = len(before) - lineStart + 1
+	line := data[lineStart:]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	line = bytes.TrimSpace(line)
+	if len(line) > maxContextLength {
+		line = line[:maxContextLength]
+	}
+	ve.Context = string(line)
+	return ve
+}
+
+// jsonErrorOffset returns the offset of the error returned by json.Unmarshal,
+// or -1 if it's unknown.
+func jsonErrorOffset(err error) int64 {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return syntaxErr.Offset
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return typeErr.Offset
+	}
+	return -1
+}
+
+// secretOffsets returns the offsets of the paths of the secrets in data,
+// which must be valid JSON.
+func secretOffsets(data []byte) map[string]int64 {
+	offsets := make(map[string]int64)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return offsets
+	}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return offsets
+		}
+		if key != "secrets" {
+			var skip json.RawMessage
+			if err := decoder.Decode(&skip); err != nil {
+				return offsets
+			}
+			continue
+		}
+		if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+			return offsets
+		}
+		for decoder.More() {
+			offset := decoder.InputOffset()
+			path, err := decoder.Token()
+			if err != nil {
+				return offsets
+			}
+			if s, ok := path.(string); ok {
+				// offset is the end of the previous token,
+				// skip the separators and whitespaces to the path.
+				offsets[s] = offset + int64(bytes.IndexByte(data[offset:], '"'))
+			}
+			var skip json.RawMessage
+			if err := decoder.Decode(&skip); err != nil {
+				return offsets
+			}
+		}
+		return offsets
+	}
+	return offsets
+}
@@ -0,0 +1,99 @@
+package secrets_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/reddit/baseplate.go/errorsbp"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+func TestValidateSecretsFile(t *testing.T) {
+	if err := secrets.ValidateSecretsFile(strings.NewReader(specificationExample)); err != nil {
+		t.Errorf("Expected the specification example to be valid, got %v", err)
+	}
+
+	t.Run("syntax", func(t *testing.T) {
+		const input = `{
+	"secrets": {
+		"secret/myservice/some-api-key": {
+			"type": "simple",
+			"value": "hunter2",
+		}
+	}
+}`
+		err := secrets.ValidateSecretsFile(strings.NewReader(input))
+		var ve *secrets.ValidationError
+		if !errors.As(err, &ve) {
+			t.Fatalf("Expected ValidationError, got %v", err)
+		}
+		if ve.Line != 6 || ve.Path != "" {
+			t.Errorf("Expected the error at line 6 without path, got %#v", ve)
+		}
+		if ve.Context != "}" {
+			t.Errorf("Expected context %q, got %q", "}", ve.Context)
+		}
+	})
+
+	t.Run("secrets", func(t *testing.T) {
+		const input = `{
+	"secrets": {
+		"secret/myservice/bad-encoding": {"type": "simple", "value": "foo", "encoding": "hex"},
+		"secret/myservice/bad-base64": {
+			"type": "versioned",
+			"current": "not base64!",
+			"encoding": "base64"
+		},
+		"secret/myservice/bad-type": {"type": "complex", "value": "foo"},
+		"secret/myservice/too-many-fields": {"type": "credential", "username": "spez", "password": "hunter2", "value": "foo"},
+		"secret/myservice/good": {"type": "simple", "value": "foo"}
+	}
+}`
+		err := secrets.ValidateSecretsFile(strings.NewReader(input))
+		var batch errorsbp.Batch
+		if !errors.As(err, &batch) {
+			t.Fatalf("Expected errorsbp.Batch, got %v", err)
+		}
+		if got := len(batch.GetErrors()); got != 4 {
+			t.Fatalf("Expected 4 errors, got %d: %v", got, err)
+		}
+
+		lines := map[string]int{
+			"secret/myservice/bad-base64":   4,
+			"secret/myservice/bad-encoding": 3,
+			"secret/myservice/bad-type":     9,
+		}
+		for _, e := range batch.GetErrors() {
+			var ve *secrets.ValidationError
+			if errors.As(e, &ve) {
+				if want, ok := lines[ve.Path]; !ok || ve.Line != want {
+					t.Errorf("Unexpected error %v, expected line %d", ve, want)
+				}
+				if !strings.Contains(ve.Error(), ve.Path) {
+					t.Errorf("Expected the error message to contain the path, got %q", ve.Error())
+				}
+				delete(lines, ve.Path)
+				continue
+			}
+			var tooMany secrets.TooManyFieldsError
+			if !errors.As(e, &tooMany) || tooMany.Key != "secret/myservice/too-many-fields" {
+				t.Errorf("Unexpected error %v", e)
+			}
+		}
+		if len(lines) != 0 {
+			t.Errorf("Missing errors for %v", lines)
+		}
+	})
+
+	t.Run("new-secrets", func(t *testing.T) {
+		_, err := secrets.NewSecrets(strings.NewReader(`{"secrets": {"secret/myservice/foo": {"type": "simple", "value": "foo", "encoding": "hex"}}}`))
+		if !errors.Is(err, secrets.ErrInvalidEncoding) {
+			t.Errorf("Expected ErrInvalidEncoding, got %v", err)
+		}
+		var ve *secrets.ValidationError
+		if !errors.As(err, &ve) || ve.Path != "secret/myservice/foo" || ve.Line != 1 {
+			t.Errorf("Expected ValidationError for the secret, got %#v", err)
+		}
+	})
+}
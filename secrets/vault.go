@@ -0,0 +1,271 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/reddit/baseplate.go/filewatcher"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// Default values of VaultConfig.
+const (
+	DefaultVaultReloadInterval = time.Minute
+	DefaultVaultTimeout        = 10 * time.Second
+)
+
+// VaultTokenEnv is the environment variable the Vault token is read from when
+// VaultConfig.TokenPath is empty.
+const VaultTokenEnv = "VAULT_TOKEN"
+
+// VaultConfig is the config of ProviderVaultAPI,
+// which reads the secrets from the HTTP API of Vault directly.
+//
+// The secrets are read from the KV (version 1) secrets engine,
+// in the same format as the Vault fetcher sidecar reads them.
+//
+// Can be deserialized from YAML.
+type VaultConfig struct {
+	// URL is the address of Vault, e.g. "https://vault.example.com:8200",
+	// required.
+	URL string `yaml:"url"`
+
+	// TokenPath is the path to the file to read the Vault token from,
+	// which is re-read on every reload so the token can be rotated by an agent.
+	//
+	// Optional, when it's empty the token is read from the VaultTokenEnv
+	// environment variable.
+	TokenPath string `yaml:"tokenPath"`
+
+	// Paths are the paths of the secrets to read, required.
+	Paths []string `yaml:"paths"`
+
+	// ReloadInterval is the interval to re-read the secrets,
+	// <=0 means DefaultVaultReloadInterval.
+	ReloadInterval time.Duration `yaml:"reloadInterval"`
+
+	// RenewInterval is the interval to renew the lease of the token,
+	// <=0 means the token is never renewed.
+	RenewInterval time.Duration `yaml:"renewInterval"`
+
+	// Timeout is the timeout of every request to Vault,
+	// <=0 means DefaultVaultTimeout.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// HTTPClient is used to make the requests to Vault,
+	// nil means a new http.Client with Timeout.
+	HTTPClient *http.Client `yaml:"-"`
+}
+
+// Validate validates the VaultConfig.
+func (cfg VaultConfig) Validate() error {
+	if cfg.URL == "" {
+		return errors.New("url: required field is missing")
+	}
+	if len(cfg.Paths) == 0 {
+		return errors.New("paths: required field is missing")
+	}
+	return nil
+}
+
+// vaultWatcher implements filewatcher.FileWatcher by reading the secrets from
+// Vault periodically.
+type vaultWatcher struct {
+	cfg    VaultConfig
+	store  *Store
+	logger log.Wrapper
+	data   atomic.Value // *Secrets
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var _ filewatcher.FileWatcher = (*vaultWatcher)(nil)
+
+// NewVaultAPIStore returns a new instance of Store reading the secrets from
+// the HTTP API of Vault directly,
+// for the services running without the Vault fetcher sidecar.
+//
+// The secrets are re-read every cfg.ReloadInterval,
+// and the failures to reload or to renew the token are logged via logger,
+// with the previously read secrets kept.
+// GetVault of the returned Store returns the URL and the token used.
+//
+// The secrets are read for the first time with ctx before returning,
+// and it's necessary to call Close on the returned Store when it's no longer
+// used.
+func NewVaultAPIStore(ctx context.Context, cfg VaultConfig, logger log.Wrapper, middlewares ...SecretMiddleware) (*Store, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("secrets.NewVaultAPIStore: %w", err)
+	}
+	if cfg.ReloadInterval <= 0 {
+		cfg.ReloadInterval = DefaultVaultReloadInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultVaultTimeout
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: cfg.Timeout}
+	}
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+
+	store := newStore(middlewares...)
+	store.reloads = newReloadTracker(ProviderVaultAPI)
+	w := &vaultWatcher{
+		cfg:    cfg,
+		store:  store,
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+	if err := w.load(ctx); err != nil {
+		return nil, err
+	}
+
+	var loopCtx context.Context
+	loopCtx, w.cancel = context.WithCancel(context.Background())
+	go w.loop(loopCtx)
+
+	store.watcher = w
+	trackers.register(store.reloads)
+	return store, nil
+}
+
+func (w *vaultWatcher) loop(ctx context.Context) {
+	defer close(w.done)
+
+	reload := time.NewTicker(w.cfg.ReloadInterval)
+	defer reload.Stop()
+	var renew <-chan time.Time
+	if w.cfg.RenewInterval > 0 {
+		ticker := time.NewTicker(w.cfg.RenewInterval)
+		defer ticker.Stop()
+		renew = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reload.C:
+			if err := w.load(ctx); err != nil && ctx.Err() == nil {
+				w.logger.Log(ctx, "secrets: failed to reload secrets from vault: "+err.Error())
+			}
+		case <-renew:
+			if err := w.renew(ctx); err != nil && ctx.Err() == nil {
+				w.logger.Log(ctx, "secrets: failed to renew vault token: "+err.Error())
+			}
+		}
+	}
+}
+
+// load reads all the secrets from Vault and replaces the current ones.
+func (w *vaultWatcher) load(ctx context.Context) error {
+	secrets, err := w.read(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.store.reloads.failed()
+		}
+		return err
+	}
+	w.store.handle(secrets)
+	w.data.Store(secrets)
+	return nil
+}
+
+func (w *vaultWatcher) read(ctx context.Context) (*Secrets, error) {
+	token, err := w.token()
+	if err != nil {
+		return nil, err
+	}
+	document := Document{
+		Secrets: make(map[string]GenericSecret, len(w.cfg.Paths)),
+		Vault: Vault{
+			URL:   w.cfg.URL,
+			Token: token,
+		},
+	}
+	for _, path := range w.cfg.Paths {
+		var resp vaultResponse
+		if err := w.do(ctx, http.MethodGet, path, token, &resp); err != nil {
+			return nil, err
+		}
+		document.Secrets[path] = resp.Secret
+	}
+	return newSecretsFromDocument(document)
+}
+
+func (w *vaultWatcher) renew(ctx context.Context) error {
+	token, err := w.token()
+	if err != nil {
+		return err
+	}
+	return w.do(ctx, http.MethodPost, "auth/token/renew-self", token, nil)
+}
+
+func (w *vaultWatcher) token() (string, error) {
+	if w.cfg.TokenPath == "" {
+		token := os.Getenv(VaultTokenEnv)
+		if token == "" {
+			return "", fmt.Errorf("secrets: environment variable %q for the vault token is not set", VaultTokenEnv)
+		}
+		return token, nil
+	}
+	token, err := os.ReadFile(w.cfg.TokenPath)
+	if err != nil {
+		return "", fmt.Errorf("secrets: failed to read vault token: %w", err)
+	}
+	return string(bytes.TrimSpace(token)), nil
+}
+
+// do sends the request to the path of the Vault API,
+// and decodes the response into v when it's non-nil.
+func (w *vaultWatcher) do(ctx context.Context, method, path, token string, v interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.Timeout)
+	defer cancel()
+
+	url := w.cfg.URL + "/v1/" + strings.TrimPrefix(path, "/")
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	resp, err := w.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("secrets: vault request for %q failed: %w", path, err)
+	}
+	defer func() {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("secrets: vault request for %q failed with status %q", path, resp.Status)
+	}
+	if v == nil {
+		return nil
+	}
+	body := io.LimitReader(resp.Body, filewatcher.DefaultMaxFileSize*filewatcher.HardLimitMultiplier)
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		return fmt.Errorf("secrets: failed to decode vault response for %q: %w", path, err)
+	}
+	return nil
+}
+
+func (w *vaultWatcher) Get() interface{} {
+	return w.data.Load()
+}
+
+// Stop stops reloading the secrets, and waits for the in-flight reload to
+// finish.
+func (w *vaultWatcher) Stop() {
+	w.cancel()
+	<-w.done
+}
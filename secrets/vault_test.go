@@ -0,0 +1,177 @@
+package secrets_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+const testVaultToken = "test-token"
+
+// fakeVault is a fake Vault server serving the KV secrets.
+type fakeVault struct {
+	lock    sync.Mutex
+	secrets map[string]string
+
+	renewals int64
+}
+
+func (v *fakeVault) set(path, body string) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.secrets[path] = body
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Vault-Token") != testVaultToken {
+		http.Error(w, "permission denied", http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodPost && r.URL.Path == "/v1/auth/token/renew-self" {
+		atomic.AddInt64(&v.renewals, 1)
+		return
+	}
+	v.lock.Lock()
+	body, ok := v.secrets[r.URL.Path]
+	v.lock.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Write([]byte(body))
+}
+
+func newFakeVault(t *testing.T) (*fakeVault, *httptest.Server) {
+	t.Helper()
+
+	vault := &fakeVault{
+		secrets: map[string]string{
+			"/v1/secret/myservice/some-api-key":              csiSimpleSecret,
+			"/v1/secret/myservice/some-database-credentials": csiCredentialSecret,
+		},
+	}
+	server := httptest.NewServer(vault)
+	t.Cleanup(server.Close)
+	return vault, server
+}
+
+func TestVaultAPIStore(t *testing.T) {
+	vault, server := newFakeVault(t)
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte(testVaultToken+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := secrets.NewVaultAPIStore(
+		context.Background(),
+		secrets.VaultConfig{
+			URL:       server.URL + "/",
+			TokenPath: tokenPath,
+			Paths: []string{
+				"secret/myservice/some-api-key",
+				"secret/myservice/some-database-credentials",
+			},
+			ReloadInterval: time.Millisecond * 10,
+			RenewInterval:  time.Millisecond * 10,
+		},
+		log.TestWrapper(t),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	secret, err := store.GetSimpleSecret("secret/myservice/some-api-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(secret.Value), "cdoUxM1WlMrfkpChtFgGObEFJ"; got != want {
+		t.Errorf("expected secret to be %q, got %q", want, got)
+	}
+	credential, err := store.GetCredentialSecret("secret/myservice/some-database-credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credential.Username != "spez" || credential.Password != "hunter2" {
+		t.Errorf("unexpected credential secret %+v", credential)
+	}
+	v, _ := store.GetVault()
+	if v.URL != server.URL || v.Token != testVaultToken {
+		t.Errorf("unexpected vault %+v", v)
+	}
+
+	vault.set("/v1/secret/myservice/some-api-key", csiUpdatedSimpleSecret)
+	deadline := time.Now().Add(time.Second)
+	for {
+		secret, err = store.GetSimpleSecret("secret/myservice/some-api-key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(secret.Value) == "updated secret" && atomic.LoadInt64(&vault.renewals) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("secret not reloaded or token not renewed, got %q with %d renewals", secret.Value, atomic.LoadInt64(&vault.renewals))
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestVaultAPIStoreErrors(t *testing.T) {
+	_, server := newFakeVault(t)
+
+	t.Run("not-found", func(t *testing.T) {
+		t.Setenv(secrets.VaultTokenEnv, testVaultToken)
+		_, err := secrets.NewVaultAPIStore(
+			context.Background(),
+			secrets.VaultConfig{
+				URL:   server.URL,
+				Paths: []string{"secret/myservice/not-found"},
+			},
+			log.TestWrapper(t),
+		)
+		if err == nil {
+			t.Error("expected error for the secret not found")
+		}
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		t.Setenv(secrets.VaultTokenEnv, "invalid")
+		_, err := secrets.NewVaultAPIStore(
+			context.Background(),
+			secrets.VaultConfig{
+				URL:   server.URL,
+				Paths: []string{"secret/myservice/some-api-key"},
+			},
+			log.TestWrapper(t),
+		)
+		if err == nil {
+			t.Error("expected error for the invalid token")
+		}
+	})
+
+	t.Run("config", func(t *testing.T) {
+		cfg := secrets.Config{
+			Provider: secrets.ProviderVaultAPI,
+			Vault: secrets.VaultConfig{
+				URL: server.URL,
+			},
+		}
+		if err := cfg.ValidateConfig(); err == nil {
+			t.Error("expected ValidateConfig to fail without paths")
+		}
+		cfg.Vault.Paths = []string{"secret/myservice/some-api-key"}
+		if err := cfg.ValidateConfig(); err != nil {
+			t.Errorf("expected ValidateConfig to pass, got %v", err)
+		}
+	})
+}
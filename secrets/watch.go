@@ -0,0 +1,83 @@
+package secrets
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+)
+
+// Watched is a value derived from a simple secret of a Store,
+// cached until the secret changes.
+//
+// It's created by Watch.
+type Watched[T any] struct {
+	store *Store
+	path  string
+	parse func(SimpleSecret) (T, error)
+
+	lock  sync.Mutex
+	cache atomic.Value // *watchedEntry[T]
+}
+
+type watchedEntry[T any] struct {
+	secret Secret
+	value  T
+	err    error
+}
+
+// Watch returns a Watched of the simple secret of path in store,
+// which derives the value via parse (e.g. parsing a private key from the
+// secret), and caches it until the secret changes.
+//
+// The value is derived lazily, on the first Get call after the secret changes,
+// so Watch itself never fails, and it doesn't need to be stopped.
+//
+// The errors returned by parse are cached as well,
+// so an invalid secret is not parsed again until it changes.
+//
+// Example:
+//
+//	key := secrets.Watch(store, "secret/myservice/signing-key", func(s secrets.SimpleSecret) (*rsa.PrivateKey, error) {
+//		return s.Value.AsRSAPrivateKey()
+//	})
+//
+//	// In the hot path:
+//	privateKey, err := key.Get()
+func Watch[T any](store *Store, path string, parse func(SimpleSecret) (T, error)) *Watched[T] {
+	return &Watched[T]{
+		store: store,
+		path:  path,
+		parse: parse,
+	}
+}
+
+// Get returns the value derived from the current version of the secret,
+// or the error of getting the secret from the Store or of parse.
+func (w *Watched[T]) Get() (T, error) {
+	secret, err := w.store.GetSimpleSecret(w.path)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if entry := w.load(); entry != nil && bytes.Equal(entry.secret, secret.Value) {
+		return entry.value, entry.err
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	// Check again in case another goroutine already parsed it.
+	if entry := w.load(); entry != nil && bytes.Equal(entry.secret, secret.Value) {
+		return entry.value, entry.err
+	}
+	entry := &watchedEntry[T]{
+		secret: secret.Value,
+	}
+	entry.value, entry.err = w.parse(secret)
+	w.cache.Store(entry)
+	return entry.value, entry.err
+}
+
+func (w *Watched[T]) load() *watchedEntry[T] {
+	entry, _ := w.cache.Load().(*watchedEntry[T])
+	return entry
+}
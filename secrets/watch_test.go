@@ -0,0 +1,84 @@
+package secrets_test
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+func TestWatch(t *testing.T) {
+	const path = "secret/myservice/number"
+	store, err := secrets.NewTestStore(map[string]secrets.GenericSecret{
+		path: {Type: secrets.SimpleType, Value: "1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	var lock sync.Mutex
+	var parses int
+	number := secrets.Watch(store.Store, path, func(s secrets.SimpleSecret) (int, error) {
+		lock.Lock()
+		parses++
+		lock.Unlock()
+		return strconv.Atoi(string(s.Value))
+	})
+	expect := func(t *testing.T, expectedValue, expectedParses int) {
+		t.Helper()
+		v, err := number.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != expectedValue {
+			t.Errorf("Expected value %d, got %d", expectedValue, v)
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		if parses != expectedParses {
+			t.Errorf("Expected %d parses, got %d", expectedParses, parses)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			number.Get()
+		}()
+	}
+	wg.Wait()
+	expect(t, 1, 1)
+
+	if err := store.Set(path, secrets.GenericSecret{Type: secrets.SimpleType, Value: "2"}); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, 2, 2)
+	expect(t, 2, 2)
+
+	if err := store.Set(path, secrets.GenericSecret{Type: secrets.SimpleType, Value: "NaN"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := number.Get(); err == nil {
+			t.Error("Expected error for the invalid number")
+		}
+	}
+	lock.Lock()
+	if parses != 3 {
+		t.Errorf("Expected the parse error to be cached, got %d parses", parses)
+	}
+	lock.Unlock()
+
+	if err := store.Delete(path); err != nil {
+		t.Fatal(err)
+	}
+	var notFound secrets.SecretNotFoundError
+	if _, err := number.Get(); !errors.As(err, &notFound) {
+		t.Errorf("Expected SecretNotFoundError, got %v", err)
+	}
+}
@@ -27,6 +27,8 @@ type SignArgs struct {
 	// Note that V1 only defined second precision,
 	// any sub-second precision in ExpiresIn or ExpiresAt will be dropped and
 	// rounded down.
+	//
+	// V2: Same as V1.
 	ExpiresAt time.Time
 	ExpiresIn time.Duration
 }
@@ -27,46 +27,78 @@ const (
 
 type v1 struct{}
 
+// headerV1 is the header of v1 signatures, also used by v2.
 type headerV1 struct {
 	Version    Version
 	_          [2]byte // padding
 	Expiration uint32
 }
 
-func (v1) Sign(args SignArgs) (sig string, err error) {
-	key := args.Secret.Current
-	if key.IsEmpty() {
-		err = errors.New("signing: empty key")
-		return
-	}
-
+// encodeHeaderV1 returns the raw header of version with the expiration
+// calculated from args.
+func encodeHeaderV1(version Version, args SignArgs) ([]byte, error) {
 	now := time.Now()
 	expiration := args.ExpiresAt
 	if expiration.IsZero() {
 		expiration = now.Add(args.ExpiresIn)
 	}
 	if expiration.Before(now) {
-		err = errors.New("signing: already expired")
-		return
+		return nil, errors.New("signing: already expired")
 	}
 
 	header := bytes.NewBuffer(make([]byte, 0, V1HeaderLength))
-	err = binary.Write(
+	err := binary.Write(
 		header,
 		binary.LittleEndian,
 		headerV1{
-			Version:    1,
+			Version:    version,
 			Expiration: uint32(expiration.Unix()),
 		},
 	)
 	if err != nil {
-		return
+		return nil, err
+	}
+	return header.Bytes(), nil
+}
+
+// verifyHeaderV1 verifies the version and the expiration of the header of the
+// raw signature.
+func verifyHeaderV1(version Version, rawSig []byte, now time.Time) error {
+	var header headerV1
+	if err := binary.Read(bytes.NewReader(rawSig), binary.LittleEndian, &header); err != nil {
+		return VerifyError{
+			Cause: err,
+		}
+	}
+	if header.Version != version {
+		return VerifyError{
+			Reason: VerifyErrorReasonUnknownVersion,
+			Data:   header.Version,
+		}
+	}
+	if now.Unix() > int64(header.Expiration) {
+		return VerifyError{
+			Reason: VerifyErrorReasonExpired,
+		}
+	}
+	return nil
+}
+
+func (v1) Sign(args SignArgs) (string, error) {
+	key := args.Secret.Current
+	if key.IsEmpty() {
+		return "", errors.New("signing: empty key")
+	}
+
+	header, err := encodeHeaderV1(1, args)
+	if err != nil {
+		return "", err
 	}
 
 	raw := make([]byte, V1SignatureRawLength)
-	copy(raw, header.Bytes())
+	copy(raw, header)
 	mac := hmac.New(sha256.New, []byte(key))
-	mac.Write(header.Bytes())
+	mac.Write(header)
 	mac.Write(args.Message)
 	copy(raw[V1HeaderLength:], mac.Sum(nil))
 	return base64.URLEncoding.EncodeToString(raw), nil
@@ -87,7 +119,7 @@ func (v1) Verify(message []byte, signature string, secret secrets.VersionedSecre
 		}
 	}
 
-	return v1Verify(message, buf, secret.GetAll(), time.Now())
+	return v1Verify(message, buf, secret.GetAllVersions(), time.Now())
 }
 
 func v1Verify(
@@ -102,22 +134,8 @@ func v1Verify(
 		}
 	}
 
-	var header headerV1
-	if err := binary.Read(bytes.NewReader(rawSig), binary.LittleEndian, &header); err != nil {
-		return VerifyError{
-			Cause: err,
-		}
-	}
-	if header.Version != 1 {
-		return VerifyError{
-			Reason: VerifyErrorReasonUnknownVersion,
-			Data:   header.Version,
-		}
-	}
-	if now.Unix() > int64(header.Expiration) {
-		return VerifyError{
-			Reason: VerifyErrorReasonExpired,
-		}
+	if err := verifyHeaderV1(1, rawSig, now); err != nil {
+		return err
 	}
 
 	for _, key := range keys {
@@ -137,6 +137,21 @@ func TestV1(t *testing.T) {
 					},
 				)
 
+				t.Run(
+					"key-history",
+					func(t *testing.T) {
+						rotating := secrets.VersionedSecret{
+							Current:  invalidSecret.Current,
+							Previous: invalidSecret.Current,
+							History:  []secrets.Secret{invalidSecret.Current, secret.Current},
+						}
+						err := verify(msg, validSig, rotating)
+						if err != nil {
+							t.Errorf("Expected nil error, got %v", err)
+						}
+					},
+				)
+
 				t.Run(
 					"unrecognized-version",
 					func(t *testing.T) {
@@ -145,7 +160,7 @@ func TestV1(t *testing.T) {
 							t.Fatal(err)
 						}
 						// Change the version byte.
-						rawSig[0] = 2
+						rawSig[0] = 0xff
 						sig := base64.URLEncoding.EncodeToString(rawSig)
 						err = verify(msg, sig, invalidSecret)
 						if !errors.As(err, &e) {
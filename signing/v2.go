@@ -0,0 +1,174 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+// V2 implementation.
+//
+// V2 signs the messages with Ed25519 instead of HMAC-SHA256,
+// so the services only verifying the signatures don't need to hold the keys
+// able to sign them (see VerifyEd25519).
+//
+// The secrets used by V2 are the Ed25519 private keys,
+// either the ed25519.SeedSize bytes seeds or the ed25519.PrivateKeySize bytes
+// private keys.
+// V2.Verify derives the public keys from them.
+var V2 Interface = v2{}
+
+// Fixed lengths regarding v2 signatures.
+const (
+	// The length of the raw, pre-base64-encoding message header.
+	V2HeaderLength = V1HeaderLength
+	// The length of the raw, pre-base64-encoding signature.
+	V2SignatureRawLength = V2HeaderLength + ed25519.SignatureSize
+	// The length of the base64 encoded signature.
+	V2SignatureLength = (V2SignatureRawLength + 2) / 3 * 4
+)
+
+type v2 struct{}
+
+// ed25519PrivateKey converts key into an ed25519.PrivateKey.
+func ed25519PrivateKey(key secrets.Secret) (ed25519.PrivateKey, error) {
+	switch len(key) {
+	default:
+		return nil, fmt.Errorf(
+			"signing: invalid ed25519 key length %d, expected %d or %d",
+			len(key),
+			ed25519.SeedSize,
+			ed25519.PrivateKeySize,
+		)
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed([]byte(key)), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(key), nil
+	}
+}
+
+func (v2) Sign(args SignArgs) (string, error) {
+	key := args.Secret.Current
+	if key.IsEmpty() {
+		return "", errors.New("signing: empty key")
+	}
+	privateKey, err := ed25519PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	header, err := encodeHeaderV1(2, args)
+	if err != nil {
+		return "", err
+	}
+
+	raw := make([]byte, V2SignatureRawLength)
+	copy(raw, header)
+	copy(raw[V2HeaderLength:], ed25519.Sign(privateKey, v2SignedMessage(header, args.Message)))
+	return base64.URLEncoding.EncodeToString(raw), nil
+}
+
+func (v2) Verify(message []byte, signature string, secret secrets.VersionedSecret) error {
+	return verifyV2(message, signature, func(rawSig []byte, now time.Time) error {
+		return v2Verify(message, rawSig, secret.GetAllVersions(), now)
+	})
+}
+
+// VerifyEd25519 verifies a V2 signature against the public keys.
+//
+// It's for the services that only verify the signatures,
+// so they only need the public keys instead of the private keys required by
+// V2.Verify.
+// When rotating the keys, pass in the public keys of all the versions
+// (current, previous and next) so the in-flight signatures are still accepted.
+//
+// signature should be urlsafe base64 encoded signature, instead of the raw
+// one.
+//
+// If this function returns an error, it will be in the type of VerifyError.
+func VerifyEd25519(message []byte, signature string, keys ...ed25519.PublicKey) error {
+	return verifyV2(message, signature, func(rawSig []byte, now time.Time) error {
+		return v2VerifyPublicKeys(message, rawSig, keys, now)
+	})
+}
+
+func verifyV2(message []byte, signature string, verify func(rawSig []byte, now time.Time) error) error {
+	if len(signature) != V2SignatureLength {
+		return VerifyError{
+			Data: "signature length mismatch",
+		}
+	}
+
+	buf, err := base64.URLEncoding.DecodeString(signature)
+	if err != nil {
+		return VerifyError{
+			Cause:  err,
+			Reason: VerifyErrorReasonBase64,
+		}
+	}
+
+	return verify(buf, time.Now())
+}
+
+func v2Verify(
+	message []byte,
+	rawSig []byte,
+	keys []secrets.Secret,
+	now time.Time,
+) error {
+	publicKeys := make([]ed25519.PublicKey, 0, len(keys))
+	for _, key := range keys {
+		if key.IsEmpty() {
+			continue
+		}
+		privateKey, err := ed25519PrivateKey(key)
+		if err != nil {
+			// Invalid keys can't match any signatures.
+			continue
+		}
+		publicKeys = append(publicKeys, privateKey.Public().(ed25519.PublicKey))
+	}
+	return v2VerifyPublicKeys(message, rawSig, publicKeys, now)
+}
+
+func v2VerifyPublicKeys(
+	message []byte,
+	rawSig []byte,
+	keys []ed25519.PublicKey,
+	now time.Time,
+) error {
+	if len(rawSig) != V2SignatureRawLength {
+		return VerifyError{
+			Data: "signature length mismatch",
+		}
+	}
+
+	if err := verifyHeaderV1(2, rawSig, now); err != nil {
+		return err
+	}
+
+	header := rawSig[:V2HeaderLength]
+	for _, key := range keys {
+		if len(key) != ed25519.PublicKeySize {
+			continue
+		}
+		if ed25519.Verify(key, v2SignedMessage(header, message), rawSig[V2HeaderLength:]) {
+			return nil
+		}
+	}
+	return VerifyError{
+		Reason: VerifyErrorReasonMismatch,
+	}
+}
+
+// v2SignedMessage returns the message actually signed by v2,
+// which is the header followed by the message.
+func v2SignedMessage(header, message []byte) []byte {
+	signed := make([]byte, 0, len(header)+len(message))
+	signed = append(signed, header...)
+	return append(signed, message...)
+}
@@ -0,0 +1,172 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+func TestV2(t *testing.T) {
+	var e VerifyError
+
+	msg := []byte("Hello, world!")
+	seed := strings.Repeat("a", ed25519.SeedSize)
+	secret := secrets.VersionedSecret{Current: secrets.Secret(seed)}
+	invalidSecret := secrets.VersionedSecret{Current: secrets.Secret(strings.Repeat("b", ed25519.SeedSize))}
+	expiration := time.Now().Add(time.Hour * 24)
+
+	validSig, err := V2.Sign(SignArgs{
+		Message:   msg,
+		Secret:    secret,
+		ExpiresAt: expiration,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validSig) != V2SignatureLength {
+		t.Fatalf("Expected signature length %d, got %d", V2SignatureLength, len(validSig))
+	}
+
+	t.Run("private-key", func(t *testing.T) {
+		// Ed25519 signatures are deterministic,
+		// signing with the full private key should get the same signature.
+		privateKey := ed25519.NewKeyFromSeed([]byte(seed))
+		sig, err := V2.Sign(SignArgs{
+			Message:   msg,
+			Secret:    secrets.VersionedSecret{Current: secrets.Secret(privateKey)},
+			ExpiresAt: expiration,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig != validSig {
+			t.Errorf("Expected signature %q, got %q", validSig, sig)
+		}
+	})
+
+	t.Run("invalid-key", func(t *testing.T) {
+		_, err := V2.Sign(SignArgs{
+			Message:   msg,
+			Secret:    secrets.VersionedSecret{Current: secrets.Secret("hunter2")},
+			ExpiresIn: time.Hour,
+		})
+		if err == nil {
+			t.Error("Expected error for invalid key length")
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		rawSig, err := base64.URLEncoding.DecodeString(validSig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = v2Verify(msg, rawSig, secret.GetAll(), expiration.Add(time.Second))
+		if !errors.As(err, &e) {
+			t.Errorf("Expected VerifyError, got %v", err)
+		}
+		if e.Reason != VerifyErrorReasonExpired {
+			t.Errorf("Expected VerifyError with reason expired, got %v", e)
+		}
+	})
+
+	publicKey := ed25519.NewKeyFromSeed([]byte(seed)).Public().(ed25519.PublicKey)
+	invalidPublicKey := ed25519.NewKeyFromSeed([]byte(invalidSecret.Current)).Public().(ed25519.PublicKey)
+	verifyFuncs := map[string]func(sig string, valid bool) error{
+		"V2.Verify": func(sig string, valid bool) error {
+			if valid {
+				return V2.Verify(msg, sig, secret)
+			}
+			return V2.Verify(msg, sig, invalidSecret)
+		},
+		"Verify": func(sig string, valid bool) error {
+			if valid {
+				return Verify(msg, sig, secret)
+			}
+			return Verify(msg, sig, invalidSecret)
+		},
+		"VerifyEd25519": func(sig string, valid bool) error {
+			if valid {
+				return VerifyEd25519(msg, sig, publicKey)
+			}
+			return VerifyEd25519(msg, sig, invalidPublicKey)
+		},
+	}
+
+	for label, verify := range verifyFuncs {
+		t.Run(label, func(t *testing.T) {
+			if err := verify(validSig, true); err != nil {
+				t.Errorf("Expected nil error, got %v", err)
+			}
+
+			t.Run("length-mismatch", func(t *testing.T) {
+				sig := validSig[:V2SignatureLength-4]
+				if err := verify(sig, true); !errors.As(err, &e) {
+					t.Errorf("Expected VerifyError, got %v", err)
+				}
+			})
+
+			t.Run("mismatch", func(t *testing.T) {
+				err := verify(validSig, false)
+				if !errors.As(err, &e) {
+					t.Errorf("Expected VerifyError, got %v", err)
+				}
+				if e.Reason != VerifyErrorReasonMismatch {
+					t.Errorf("Expected VerifyError with reason mismatch, got %v", e)
+				}
+			})
+
+			t.Run("tampered", func(t *testing.T) {
+				rawSig, err := base64.URLEncoding.DecodeString(validSig)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// Extend the expiration.
+				rawSig[3]++
+				err = verify(base64.URLEncoding.EncodeToString(rawSig), true)
+				if !errors.As(err, &e) {
+					t.Errorf("Expected VerifyError, got %v", err)
+				}
+				if e.Reason != VerifyErrorReasonMismatch {
+					t.Errorf("Expected VerifyError with reason mismatch, got %v", e)
+				}
+			})
+		})
+	}
+
+	t.Run("key-rotation", func(t *testing.T) {
+		for label, rotating := range map[string]secrets.VersionedSecret{
+			"previous": {
+				Current:  invalidSecret.Current,
+				Previous: secret.Current,
+			},
+			"next": {
+				Current: invalidSecret.Current,
+				Next:    secret.Current,
+			},
+			"history": {
+				Current:  invalidSecret.Current,
+				Previous: invalidSecret.Current,
+				History:  []secrets.Secret{invalidSecret.Current, secret.Current},
+			},
+		} {
+			if err := Verify(msg, validSig, rotating); err != nil {
+				t.Errorf("%s: Expected nil error, got %v", label, err)
+			}
+		}
+		if err := VerifyEd25519(msg, validSig, invalidPublicKey, publicKey); err != nil {
+			t.Errorf("VerifyEd25519: Expected nil error, got %v", err)
+		}
+	})
+}
+
+func TestVerifyEmpty(t *testing.T) {
+	var e VerifyError
+	if err := Verify(nil, "", secrets.VersionedSecret{}); !errors.As(err, &e) {
+		t.Errorf("Expected VerifyError, got %v", err)
+	}
+}
@@ -10,6 +10,9 @@ import (
 var latest = V1
 
 // Sign calls the latest implementation's Sign function.
+//
+// The latest implementation is still V1,
+// use V2.Sign directly to sign with Ed25519 keys.
 func Sign(args SignArgs) (string, error) {
 	return latest.Sign(args)
 }
@@ -19,6 +22,7 @@ type internalVerifyFunc func([]byte, []byte, []secrets.Secret, time.Time) error
 // versions is the map from known versions to their implementations.
 var versions = map[Version]internalVerifyFunc{
 	1: v1Verify,
+	2: v2Verify,
 }
 
 // Verify auto chooses the correct version and verifies the signature with the
@@ -26,6 +30,9 @@ var versions = map[Version]internalVerifyFunc{
 //
 // Unrecognized versions will be rejected.
 //
+// All the versions of secret (current, previous and next) are tried,
+// so signatures signed by any of them are accepted during key rotations.
+//
 // signature should be urlsafe base64 encoded signature, instead of the raw
 // one.
 //
@@ -38,6 +45,11 @@ func Verify(message []byte, signature string, secret secrets.VersionedSecret) er
 			Reason: VerifyErrorReasonBase64,
 		}
 	}
+	if len(buf) == 0 {
+		return VerifyError{
+			Data: "signature length mismatch",
+		}
+	}
 
 	v := Version(buf[0])
 	verify, ok := versions[v]
@@ -48,5 +60,5 @@ func Verify(message []byte, signature string, secret secrets.VersionedSecret) er
 		}
 	}
 
-	return verify(message, buf, secret.GetAll(), time.Now())
+	return verify(message, buf, secret.GetAllVersions(), time.Now())
 }
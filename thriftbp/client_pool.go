@@ -11,11 +11,13 @@ import (
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/avast/retry-go"
 	"github.com/go-kit/kit/metrics"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/reddit/baseplate.go/breakerbp"
 	"github.com/reddit/baseplate.go/clientpool"
 	"github.com/reddit/baseplate.go/ecinterface"
 	"github.com/reddit/baseplate.go/errorsbp"
+	"github.com/reddit/baseplate.go/healthbp"
 	"github.com/reddit/baseplate.go/log"
 	"github.com/reddit/baseplate.go/metricsbp"
 )
@@ -24,6 +26,15 @@ import (
 // ClientPoolConfig.PoolGaugeInterval <= 0.
 const DefaultPoolGaugeInterval = time.Second * 10
 
+// PrometheusPoolProtocol is the protocol label value of the standardized
+// Prometheus pool metrics reported by ClientPool.
+const PrometheusPoolProtocol = "thrift"
+
+// HealthCheckerPrefix is the prefix of the names of the checkers registered
+// into healthbp.DefaultRegistry by the client pools,
+// see ClientPoolConfig.HealthCriticality.
+const HealthCheckerPrefix = "thrift-client-pool:"
+
 // PoolError is returned by ClientPool.TClient.Call when it fails to get a
 // client from its pool.
 type PoolError struct {
@@ -69,6 +80,18 @@ type ClientPoolConfig struct {
 	// pool can maintain.
 	MaxConnections int `yaml:"maxConnections"`
 
+	// PoolWaitTimeout is the max time a call waits for a connection to be
+	// released when the pool is exhausted (MaxConnections are all in use),
+	// after which the call fails with a PoolError wrapping
+	// clientpool.ErrExhausted.
+	//
+	// The call never waits longer than the deadline of its context.
+	// To prioritize some calls over the others when waiting,
+	// use clientpool.WithPriority on the context.
+	//
+	// Optional, <=0 means the call fails right away without waiting.
+	PoolWaitTimeout time.Duration `yaml:"poolWaitTimeout"`
+
 	// MaxConnectionAge is the maximum duration that a pooled connection will be
 	// kept before closing in favor of a new one.
 	//
@@ -140,7 +163,7 @@ type ClientPoolConfig struct {
 	DefaultRetryOptions []retry.Option
 
 	// ReportPoolStats signals to the ClientPool that it should report
-	// statistics on the underlying clientpool.Pool in a background
+	// statistics on the underlying clientpool.TypedPool in a background
 	// goroutine.  If this is set to false, the reporting goroutine will
 	// not be started and it will not report pool stats.
 	//
@@ -152,6 +175,10 @@ type ClientPoolConfig struct {
 	//
 	// The reporting goroutine is cancelled when the global metrics client
 	// context is Done.
+	//
+	// It also registers the standardized Prometheus pool metrics
+	// (see clientpool.RegisterStats) with "thrift" protocol and ServiceSlug as
+	// the pool name, which are unregistered when the ClientPool is closed.
 	ReportPoolStats bool `yaml:"reportPoolStats"`
 
 	// PoolGaugeInterval indicates how often we should update the active
@@ -183,6 +210,26 @@ type ClientPoolConfig struct {
 	InitialConnectionsFallback       bool        `yaml:"initialConnectionsFallback"`
 	InitialConnectionsFallbackLogger log.Wrapper `yaml:"initialConnectionsFallbackLogger"`
 
+	// Optional. The signal reported ready once the InitialConnections are
+	// opened, usually required from the StartupGate of the Baseplate,
+	// so the service only takes traffic after the pool is warmed up.
+	//
+	// When the pool falls back to 0 initial connections
+	// (see InitialConnectionsFallback), the InitialConnections are opened in
+	// the background until they succeed or the pool is closed,
+	// with the failures logged with InitialConnectionsFallbackLogger,
+	// and the signal is reported ready afterwards.
+	StartupSignal *healthbp.StartupSignal `yaml:"-"`
+
+	// Optional. The criticality of the checker of the pool registered into
+	// healthbp.DefaultRegistry, named HealthCheckerPrefix + ServiceSlug,
+	// which reports clientpool.ErrExhausted when the pool is exhausted.
+	//
+	// Defaults to healthbp.CriticalityNone, which only reports the health of
+	// the pool without failing any probes.
+	// The checker is removed when the pool is closed.
+	HealthCriticality healthbp.Criticality `yaml:"healthCriticality"`
+
 	// When BreakerConfig is non-nil,
 	// a breakerbp.FailureRatioBreaker will be created for the pool,
 	// and its middleware will be set for the pool.
@@ -249,6 +296,30 @@ func (c BaseplateClientPoolConfig) Validate() error {
 	return batch.Compile()
 }
 
+// warmUpPoolRetryInterval is the interval between the attempts of
+// warmUpPool.
+const warmUpPoolRetryInterval = time.Second
+
+// warmUpPool opens the InitialConnections of cfg into pool in the background
+// after the InitialConnectionsFallback, and reports cfg.StartupSignal ready
+// once they are opened or the pool is closed.
+func warmUpPool(pool *clientpool.TypedPool[*ttlClient], cfg ClientPoolConfig) {
+	for {
+		err := pool.WarmUp(cfg.InitialConnections)
+		if err == nil {
+			cfg.StartupSignal.Ready()
+			return
+		}
+		cfg.InitialConnectionsFallbackLogger.Log(context.Background(), fmt.Sprintf(
+			"thriftbp: error warming up thrift clientpool for %q, retrying in %v: %v",
+			cfg.ServiceSlug,
+			warmUpPoolRetryInterval,
+			err,
+		))
+		time.Sleep(warmUpPoolRetryInterval)
+	}
+}
+
 // Client is a client object that implements both the clientpool.Client and
 // thrift.TCLient interfaces.
 //
@@ -313,7 +384,7 @@ type ClientPool interface {
 	// It also increases ServiceSlug+".pool-release-error" counter.
 	TClient() thrift.TClient
 
-	// Passthrough APIs from clientpool.Pool:
+	// Passthrough APIs from clientpool.TypedPool:
 	io.Closer
 	IsExhausted() bool
 }
@@ -396,7 +467,7 @@ func newClientPool(
 	if cfg.MaxConnectionAgeJitter != nil {
 		jitter = *cfg.MaxConnectionAgeJitter
 	}
-	opener := func() (clientpool.Client, error) {
+	opener := func() (*ttlClient, error) {
 		return newClient(
 			tConfig,
 			cfg.ServiceSlug,
@@ -407,20 +478,19 @@ func newClientPool(
 			proto,
 		)
 	}
-	pool, err := clientpool.NewChannelPool(
-		cfg.InitialConnections,
-		cfg.MaxConnections,
-		opener,
-	)
+	poolCfg := clientpool.TypedPoolConfig{
+		InitialClients: cfg.InitialConnections,
+		MaxClients:     cfg.MaxConnections,
+		MaxWait:        cfg.PoolWaitTimeout,
+	}
+	pool, err := clientpool.NewTypedPool(poolCfg, opener)
+	warm := err == nil
 	if err != nil {
 		if cfg.InitialConnectionsFallback {
 			// do the InitialConnectionsFallback
 			var fallbackErr error
-			pool, fallbackErr = clientpool.NewChannelPool(
-				0, // initialClients
-				cfg.MaxConnections,
-				opener,
-			)
+			poolCfg.InitialClients = 0
+			pool, fallbackErr = clientpool.NewTypedPool(poolCfg, opener)
 			if fallbackErr == nil {
 				cfg.InitialConnectionsFallbackLogger.Log(context.Background(), fmt.Sprintf(
 					"thriftbp: error initializing thrift clientpool for %q but fallback to 0 initial connections worked. Original error: %v",
@@ -436,13 +506,21 @@ func newClientPool(
 			}
 		}
 		if err != nil {
-			return nil, fmt.Errorf(
+			err = fmt.Errorf(
 				"thriftbp: error initializing thrift clientpool for %q: %w",
 				cfg.ServiceSlug,
 				err,
 			)
+			cfg.StartupSignal.Fail(err)
+			return nil, err
 		}
 	}
+	if warm {
+		cfg.StartupSignal.Ready()
+	} else if cfg.StartupSignal != nil {
+		go warmUpPool(pool, cfg)
+	}
+	var statsCollector *clientpool.StatsCollector
 	if cfg.ReportPoolStats {
 		go reportPoolStats(
 			metricsbp.M.Ctx(),
@@ -451,13 +529,38 @@ func newClientPool(
 			cfg.PoolGaugeInterval,
 			tags,
 		)
+		statsCollector, err = clientpool.RegisterStats(
+			PrometheusPoolProtocol,
+			cfg.ServiceSlug,
+			pool.Stats,
+		)
+		if err != nil {
+			log.Warnw(
+				"thriftbp: failed to register prometheus pool stats",
+				"err", err,
+				"slug", cfg.ServiceSlug,
+			)
+		}
 	}
 
 	// create the base clientPool, this is not ready for use.
 	pooledClient := &clientPool{
-		Pool: pool,
-
-		slug: cfg.ServiceSlug,
+		pool: pool,
+
+		slug:           cfg.ServiceSlug,
+		statsCollector: statsCollector,
+		removeChecker: healthbp.Add(
+			HealthCheckerPrefix+cfg.ServiceSlug,
+			func(context.Context) error {
+				if pool.IsExhausted() {
+					return clientpool.ErrExhausted
+				}
+				return nil
+			},
+			healthbp.CheckerOptions{
+				Criticality: cfg.HealthCriticality,
+			},
+		),
 
 		poolExhaustedCounter: metricsbp.M.Counter(
 			cfg.ServiceSlug + ".pool-exhausted",
@@ -504,7 +607,13 @@ func newClient(
 	}, maxConnectionAge, maxConnectionAgeJitter, slug, tags)
 }
 
-func reportPoolStats(ctx context.Context, prefix string, pool clientpool.Pool, tickerDuration time.Duration, tags []string) {
+// poolCounter is the subset of clientpool.TypedPool used by reportPoolStats.
+type poolCounter interface {
+	NumActiveClients() int32
+	NumAllocated() int32
+}
+
+func reportPoolStats(ctx context.Context, prefix string, pool poolCounter, tickerDuration time.Duration, tags []string) {
 	activeGauge := metricsbp.M.RuntimeGauge(prefix + ".pool-active-connections").With(tags...)
 	allocatedGauge := metricsbp.M.RuntimeGauge(prefix + ".pool-allocated-clients").With(tags...)
 
@@ -525,9 +634,11 @@ func reportPoolStats(ctx context.Context, prefix string, pool clientpool.Pool, t
 }
 
 type clientPool struct {
-	clientpool.Pool
+	pool *clientpool.TypedPool[*ttlClient]
 
-	slug string
+	slug           string
+	statsCollector *clientpool.StatsCollector
+	removeChecker  func()
 
 	poolExhaustedCounter         metrics.Counter
 	releaseErrorCounter          metrics.Counter
@@ -536,6 +647,21 @@ type clientPool struct {
 	wrappedClient thrift.TClient
 }
 
+// Close closes the underlying clientpool.TypedPool,
+// and unregisters the Prometheus pool stats and the health checker.
+func (p *clientPool) Close() error {
+	if p.statsCollector != nil {
+		prometheus.Unregister(p.statsCollector)
+	}
+	p.removeChecker()
+	return p.pool.Close()
+}
+
+// IsExhausted returns true when all the connections of the pool are in use.
+func (p *clientPool) IsExhausted() bool {
+	return p.pool.IsExhausted()
+}
+
 func (p *clientPool) TClient() thrift.TClient {
 	// A clientPool needs to be set up properly before it can be used,
 	// specifically use p.wrapCalls to set up p.wrappedClient before using it.
@@ -557,14 +683,14 @@ func (p *clientPool) wrapCalls(middlewares ...thrift.ClientMiddleware) {
 	}, middlewares...)
 }
 
-// pooledCall gets a Client from the inner clientpool.Pool and "Calls" it,
+// pooledCall gets a Client from the inner clientpool.TypedPool and "Calls" it,
 // returning the result and releasing the client back to the pool afterwards.
 //
 // This is not called directly, but is rather the inner "Call" wrapped by
 // wrapCalls, so it runs after all of the middleware.
 func (p *clientPool) pooledCall(ctx context.Context, method string, args, result thrift.TStruct) (_ thrift.ResponseMeta, err error) {
-	var client Client
-	client, err = p.getClient()
+	var client *ttlClient
+	client, err = p.getClient(ctx)
 	if err != nil {
 		return thrift.ResponseMeta{}, PoolError{Cause: err}
 	}
@@ -586,8 +712,8 @@ func (p *clientPool) pooledCall(ctx context.Context, method string, args, result
 	return client.Call(ctx, method, args, result)
 }
 
-func (p *clientPool) getClient() (Client, error) {
-	c, err := p.Pool.Get()
+func (p *clientPool) getClient(ctx context.Context) (*ttlClient, error) {
+	c, err := p.pool.Get(ctx)
 	if err != nil {
 		if errors.Is(err, clientpool.ErrExhausted) {
 			p.poolExhaustedCounter.Add(1)
@@ -599,11 +725,11 @@ func (p *clientPool) getClient() (Client, error) {
 		)
 		return nil, err
 	}
-	return c.(Client), nil
+	return c, nil
 }
 
-func (p *clientPool) releaseClient(c Client) {
-	if err := p.Pool.Release(c); err != nil {
+func (p *clientPool) releaseClient(c *ttlClient) {
+	if err := p.pool.Release(c); err != nil {
 		log.Errorw(
 			"Failed to release client back to pool",
 			"pool", p.slug,
@@ -11,6 +11,7 @@ import (
 	"github.com/apache/thrift/lib/go/thrift"
 
 	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/healthbp"
 	"github.com/reddit/baseplate.go/thriftbp"
 )
 
@@ -54,6 +55,51 @@ func TestNewBaseplateClientPool(t *testing.T) {
 	}
 }
 
+func TestClientPoolHealthChecker(t *testing.T) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	const name = thriftbp.HealthCheckerPrefix + "test-health"
+	findResult := func() (healthbp.Result, bool) {
+		for _, result := range healthbp.Check(context.Background(), healthbp.ProbeReadiness).Results {
+			if result.Name == name {
+				return result, true
+			}
+		}
+		return healthbp.Result{}, false
+	}
+
+	pool, err := thriftbp.NewBaseplateClientPool(
+		thriftbp.ClientPoolConfig{
+			Addr:              ln.Addr().String(),
+			EdgeContextImpl:   ecinterface.Mock(),
+			ServiceSlug:       "test-health",
+			MaxConnections:    5,
+			ConnectTimeout:    time.Millisecond * 5,
+			SocketTimeout:     time.Millisecond * 15,
+			HealthCriticality: healthbp.CriticalityReadiness,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, ok := findResult()
+	if !ok {
+		t.Fatalf("Expected checker %q registered", name)
+	}
+	if !result.Healthy() || result.Criticality != healthbp.CriticalityReadiness {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	pool.Close()
+	if _, ok := findResult(); ok {
+		t.Errorf("Expected checker %q removed after Close", name)
+	}
+}
+
 func TestCustomClientPool(t *testing.T) {
 	ln, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -126,3 +172,54 @@ func TestInitialConnectionsFallback(t *testing.T) {
 		t.Error("InitialConnectionsFallbackLogger not called")
 	}
 }
+
+func TestInitialConnectionsFallbackStartupSignal(t *testing.T) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var counter uint64
+	addrGen := func() (string, error) {
+		if atomic.AddUint64(&counter, 1) > 2 {
+			return ln.Addr().String(), nil
+		}
+		// The initial connection and the first warm up attempt fail.
+		return "", errors.New("error")
+	}
+
+	gate := new(healthbp.StartupGate)
+	cfg := thriftbp.ClientPoolConfig{
+		ServiceSlug:                      "test",
+		EdgeContextImpl:                  ecinterface.Mock(),
+		Addr:                             ":9090",
+		InitialConnections:               2,
+		MaxConnections:                   5,
+		ConnectTimeout:                   time.Millisecond * 5,
+		SocketTimeout:                    time.Millisecond * 15,
+		InitialConnectionsFallback:       true,
+		InitialConnectionsFallbackLogger: func(_ context.Context, msg string) { t.Log(msg) },
+		StartupSignal:                    gate.Require("thrift"),
+	}
+	factory := thrift.NewTBinaryProtocolFactoryConf(cfg.ToTConfiguration())
+
+	pool, err := thriftbp.NewCustomClientPool(cfg, addrGen, factory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	if err := gate.Check(context.Background()); err == nil {
+		t.Error("Expected the startup signal to be pending before the pool is warmed up")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := gate.Wait(ctx); err != nil {
+		t.Fatalf("Expected the startup signal to be ready after the pool is warmed up, got %v", err)
+	}
+	// 2 failures, then the 2 initial connections.
+	if n := atomic.LoadUint64(&counter); n != 4 {
+		t.Errorf("Expected 4 connection attempts, got %d", n)
+	}
+}
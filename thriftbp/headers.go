@@ -20,18 +20,38 @@ var HeadersToForward = []string{
 	transport.HeaderTracingFlags,
 }
 
+// EdgeContextCarrier is the ecinterface.Carrier of the "Edge-Request" THeader.
+//
+// Get reads the header from the thrift headers of the incoming request,
+// Set and Unset update the headers of the outgoing Thrift calls.
+type EdgeContextCarrier struct{}
+
+var _ ecinterface.Carrier = EdgeContextCarrier{}
+
+// Get implements ecinterface.Carrier.
+func (EdgeContextCarrier) Get(ctx context.Context) (string, error) {
+	header, ok := thrift.GetHeader(ctx, transport.HeaderEdgeRequest)
+	if !ok {
+		return "", ecinterface.ErrNoHeader
+	}
+	return header, nil
+}
+
+// Set implements ecinterface.Carrier.
+func (EdgeContextCarrier) Set(ctx context.Context, header string) context.Context {
+	return AddClientHeader(ctx, transport.HeaderEdgeRequest, header)
+}
+
+// Unset implements ecinterface.Carrier.
+func (EdgeContextCarrier) Unset(ctx context.Context) context.Context {
+	return thrift.UnsetHeader(ctx, transport.HeaderEdgeRequest)
+}
+
 // AttachEdgeRequestContext returns a context that has the header of the edge
 // context attached to ctx object set to forward using the "Edge-Request" header
 // on any Thrift calls made with that context object.
 func AttachEdgeRequestContext(ctx context.Context, ecImpl ecinterface.Interface) context.Context {
-	if ecImpl == nil {
-		ecImpl = ecinterface.Get()
-	}
-	header, ok := ecImpl.ContextToHeader(ctx)
-	if !ok {
-		return thrift.UnsetHeader(ctx, transport.HeaderEdgeRequest)
-	}
-	return AddClientHeader(ctx, transport.HeaderEdgeRequest, header)
+	return ecinterface.Inject(ctx, ecImpl, EdgeContextCarrier{})
 }
 
 // AddClientHeader adds a key-value pair to thrift client's headers.
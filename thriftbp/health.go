@@ -0,0 +1,52 @@
+package thriftbp
+
+import (
+	"context"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/healthbp"
+	baseplatethrift "github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+)
+
+// IsHealthyMethod is the name of the is_healthy endpoint defined in
+// baseplate.thrift.
+const IsHealthyMethod = "is_healthy"
+
+// HealthCheck is a thrift.ProcessorMiddleware serving the is_healthy endpoint
+// from registry, instead of the IsHealthy implementation of the handler.
+//
+// The probe of the request (readiness when not set) is passed into
+// registry.IsHealthy,
+// see healthbp.Registry.Check for the checkers affecting each probe.
+// All the other endpoints are passed to the next TProcessorFunction untouched.
+//
+// A nil registry means healthbp.DefaultRegistry.
+func HealthCheck(registry *healthbp.Registry) thrift.ProcessorMiddleware {
+	if registry == nil {
+		registry = healthbp.DefaultRegistry
+	}
+	isHealthy, _ := baseplatethrift.NewBaseplateServiceV2Processor(
+		registryHandler{registry: registry},
+	).GetProcessorFunction(IsHealthyMethod)
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		if name != IsHealthyMethod {
+			return next
+		}
+		return isHealthy
+	}
+}
+
+// registryHandler implements baseplate.BaseplateServiceV2 with a
+// healthbp.Registry.
+type registryHandler struct {
+	registry *healthbp.Registry
+}
+
+func (h registryHandler) IsHealthy(ctx context.Context, req *baseplatethrift.IsHealthyRequest) (bool, error) {
+	probe := healthbp.ProbeReadiness
+	if req != nil && req.IsSetProbe() {
+		probe = int64(req.GetProbe())
+	}
+	return h.registry.IsHealthy(ctx, probe), nil
+}
@@ -0,0 +1,94 @@
+package thriftbp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/healthbp"
+	baseplatethrift "github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+func TestHealthCheck(t *testing.T) {
+	registry := new(healthbp.Registry)
+	registry.Register(
+		"dependency",
+		func(context.Context) error {
+			return errors.New("down")
+		},
+		healthbp.CheckerOptions{
+			Criticality: healthbp.CriticalityReadiness,
+		},
+	)
+	var nextCalls int
+	next := thrift.WrappedTProcessorFunction{
+		Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			nextCalls++
+			return true, nil
+		},
+	}
+	middleware := thriftbp.HealthCheck(registry)
+	middleware("other", next).Process(context.Background(), 1, nil, nil)
+	if nextCalls != 1 {
+		t.Error("Expected the other endpoints to be passed to the next TProcessorFunction")
+	}
+	nextCalls = 0
+	process := middleware(thriftbp.IsHealthyMethod, next)
+	defer func() {
+		if nextCalls != 0 {
+			t.Error("Expected the next TProcessorFunction not to be called for is_healthy")
+		}
+	}()
+
+	for _, c := range []struct {
+		label    string
+		request  *baseplatethrift.IsHealthyRequest
+		expected bool
+	}{
+		{
+			label: "readiness",
+			request: &baseplatethrift.IsHealthyRequest{
+				Probe: baseplatethrift.IsHealthyProbePtr(baseplatethrift.IsHealthyProbe_READINESS),
+			},
+			expected: false,
+		},
+		{
+			label: "liveness",
+			request: &baseplatethrift.IsHealthyRequest{
+				Probe: baseplatethrift.IsHealthyProbePtr(baseplatethrift.IsHealthyProbe_LIVENESS),
+			},
+			expected: true,
+		},
+		{
+			label:    "unset",
+			request:  &baseplatethrift.IsHealthyRequest{},
+			expected: false,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			ctx := context.Background()
+			in := thrift.NewTBinaryProtocolConf(thrift.NewTMemoryBuffer(), nil)
+			out := thrift.NewTBinaryProtocolConf(thrift.NewTMemoryBuffer(), nil)
+			args := baseplatethrift.BaseplateServiceV2IsHealthyArgs{Request: c.request}
+			if err := args.Write(ctx, in); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := process.Process(ctx, 1, in, out); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, _, err := out.ReadMessageBegin(ctx); err != nil {
+				t.Fatal(err)
+			}
+			var result baseplatethrift.BaseplateServiceV2IsHealthyResult
+			if err := result.Read(ctx, out); err != nil {
+				t.Fatal(err)
+			}
+			if got := result.GetSuccess(); got != c.expected {
+				t.Errorf("Expected %v, got %v", c.expected, got)
+			}
+		})
+	}
+}
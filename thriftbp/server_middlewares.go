@@ -9,12 +9,15 @@ import (
 
 	"github.com/apache/thrift/lib/go/thrift"
 
+	"github.com/reddit/baseplate.go/breakerbp"
 	"github.com/reddit/baseplate.go/ecinterface"
 	"github.com/reddit/baseplate.go/errorsbp"
+	"github.com/reddit/baseplate.go/faults"
 	"github.com/reddit/baseplate.go/iobp"
 	"github.com/reddit/baseplate.go/log"
 	"github.com/reddit/baseplate.go/metricsbp"
 	"github.com/reddit/baseplate.go/randbp"
+	"github.com/reddit/baseplate.go/ratelimit"
 	"github.com/reddit/baseplate.go/tracing"
 	"github.com/reddit/baseplate.go/transport"
 )
@@ -165,12 +168,7 @@ func InjectServerSpan(suppressor errorsbp.Suppressor) thrift.ProcessorMiddleware
 // headers set on the context onto the context and configures Thrift to forward
 // the edge requent context header on any Thrift calls made by the server.
 func InitializeEdgeContext(ctx context.Context, impl ecinterface.Interface) context.Context {
-	header, ok := thrift.GetHeader(ctx, transport.HeaderEdgeRequest)
-	if !ok {
-		return ctx
-	}
-
-	ctx, err := impl.HeaderToContext(ctx, header)
+	ctx, err := ecinterface.Extract(ctx, impl, EdgeContextCarrier{})
 	if err != nil {
 		log.Error("Error while parsing EdgeRequestContext: " + err.Error())
 	}
@@ -380,3 +378,220 @@ func RecoverPanic(name string, next thrift.TProcessorFunction) thrift.TProcessor
 		},
 	}
 }
+
+// RateLimit returns a ProcessorMiddleware that rate limits the requests via
+// limiter.
+//
+// key is used to get the rate limit key of the request (e.g. the caller from
+// the edge context), it's prefixed by the name of the endpoint so that the
+// limits of different endpoints are separated.
+// If key is nil, all the requests to the same endpoint share the same limit.
+//
+// When the request is rate limited,
+// the request is not passed to the next TProcessorFunction,
+// and a TApplicationException is written back to the client,
+// as the baseplate.Error declared in the IDL can only be written by the
+// compiled processor of the endpoint.
+// When limiter returns an error, the error is logged and the request is
+// allowed (fail open).
+func RateLimit(limiter ratelimit.Limiter, key func(ctx context.Context) string) thrift.ProcessorMiddleware {
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				k := name
+				if key != nil {
+					k += ":" + key(ctx)
+				}
+				result, err := ratelimit.Allow(ctx, limiter, k)
+				if err != nil {
+					log.C(ctx).Warnw(
+						"thriftbp.RateLimit: limiter failed, allowing the request",
+						"err", err,
+						"key", k,
+					)
+					return next.Process(ctx, seqID, in, out)
+				}
+				if result.Allowed {
+					return next.Process(ctx, seqID, in, out)
+				}
+				return rejectRequest(ctx, name, seqID, in, out, thrift.NewTApplicationException(
+					thrift.UNKNOWN_APPLICATION_EXCEPTION,
+					fmt.Sprintf(
+						"TOO_MANY_REQUESTS: %q rate limited, retry after %v",
+						k,
+						result.RetryAfter,
+					),
+				))
+			},
+		}
+	}
+}
+
+// Quota returns a ProcessorMiddleware that consumes one unit of the quota of
+// the caller for each request.
+//
+// key is used to get the quota key of the request.
+// Unlike RateLimit, the key is not prefixed by the name of the endpoint,
+// so the quota is shared by all the endpoints using the same quota.
+// If key is nil, ratelimit.AuthenticatedKey is used to key the quota by the
+// authenticated caller from the edge context.
+// The requests with empty key (e.g. not authenticated) are not counted.
+//
+// When the quota is exceeded,
+// the request is not passed to the next TProcessorFunction,
+// and a TApplicationException with the message starting with "QUOTA_EXCEEDED:"
+// and containing the time the quota resets is written back to the client,
+// the same way as RateLimit.
+// When quota returns an error, the error is logged and the request is
+// allowed (fail open).
+func Quota(quota ratelimit.Quota, key func(ctx context.Context) string) thrift.ProcessorMiddleware {
+	if key == nil {
+		key = ratelimit.AuthenticatedKey
+	}
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				k := key(ctx)
+				if k == "" {
+					return next.Process(ctx, seqID, in, out)
+				}
+				result, err := quota.CheckAndConsume(ctx, k, 1)
+				if err != nil {
+					log.C(ctx).Warnw(
+						"thriftbp.Quota: quota failed, allowing the request",
+						"err", err,
+						"key", k,
+					)
+					return next.Process(ctx, seqID, in, out)
+				}
+				if result.Allowed {
+					return next.Process(ctx, seqID, in, out)
+				}
+				return rejectRequest(ctx, name, seqID, in, out, thrift.NewTApplicationException(
+					thrift.UNKNOWN_APPLICATION_EXCEPTION,
+					"QUOTA_EXCEEDED: "+result.Err(k).Error(),
+				))
+			},
+		}
+	}
+}
+
+// ConcurrencyLimit returns a ProcessorMiddleware that limits the concurrent
+// in-flight requests to all the endpoints of the server via limiter,
+// adjusting the limit based on the latency of the requests.
+//
+// When the limit is reached,
+// the request is not passed to the next TProcessorFunction,
+// and a TApplicationException is written back to the client,
+// the same way as RateLimit.
+func ConcurrencyLimit(limiter *breakerbp.AdaptiveLimiter) thrift.ProcessorMiddleware {
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				var ok bool
+				_, err := limiter.Execute(func() (interface{}, error) {
+					var exc thrift.TException
+					ok, exc = next.Process(ctx, seqID, in, out)
+					if exc != nil {
+						return nil, exc
+					}
+					return nil, nil
+				})
+				if errors.Is(err, breakerbp.ErrConcurrencyLimitExceeded) {
+					return rejectRequest(ctx, name, seqID, in, out, thrift.NewTApplicationException(
+						thrift.UNKNOWN_APPLICATION_EXCEPTION,
+						fmt.Sprintf("TOO_MANY_REQUESTS: %q concurrency limit exceeded", name),
+					))
+				}
+				if err != nil {
+					return ok, err.(thrift.TException)
+				}
+				return ok, nil
+			},
+		}
+	}
+}
+
+// InjectFaults returns a ProcessorMiddleware that injects the faults from
+// injector into the matching requests, for chaos experiments.
+//
+// The caller of the request is read from the "User-Agent" (HeaderUserAgent)
+// THeader.
+// The fault delays the request first (cut short when the context is done).
+// Then, if the fault aborts, the connection is closed without a response;
+// otherwise if the fault has an error code for thrift,
+// the request is not passed to the next TProcessorFunction,
+// and a TApplicationException of the error code with the message starting
+// with "FAULT_INJECTED:" is written back to the client,
+// the same way as RateLimit.
+//
+// A nil injector never injects anything.
+func InjectFaults(injector *faults.Injector) thrift.ProcessorMiddleware {
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				caller, _ := thrift.GetHeader(ctx, transport.HeaderUserAgent)
+				fault, ok := injector.Match(faults.Request{
+					Protocol: faults.ProtocolThrift,
+					Method:   name,
+					Caller:   caller,
+				})
+				if !ok {
+					return next.Process(ctx, seqID, in, out)
+				}
+				if err := fault.Wait(ctx); err != nil {
+					// Let the next TProcessorFunction handle the canceled request.
+					return next.Process(ctx, seqID, in, out)
+				}
+				if fault.Abort {
+					// Returning a TTransportException makes the server close the
+					// connection.
+					return false, thrift.NewTTransportException(
+						thrift.UNKNOWN_TRANSPORT_EXCEPTION,
+						fmt.Sprintf("thriftbp.InjectFaults: connection aborted by rule %q", fault.Rule),
+					)
+				}
+				if fault.HasCode {
+					return rejectRequest(ctx, name, seqID, in, out, thrift.NewTApplicationException(
+						int32(fault.Code),
+						fmt.Sprintf("FAULT_INJECTED: %q by rule %q", name, fault.Rule),
+					))
+				}
+				return next.Process(ctx, seqID, in, out)
+			},
+		}
+	}
+}
+
+// rejectRequest skips the request in and writes exc back to out,
+// the same way the compiled processors handle unknown methods.
+//
+// It returns ok as true so that the server keeps the connection open for the
+// following requests.
+func rejectRequest(
+	ctx context.Context,
+	name string,
+	seqID int32,
+	in, out thrift.TProtocol,
+	exc thrift.TApplicationException,
+) (bool, thrift.TException) {
+	if err := in.Skip(ctx, thrift.STRUCT); err != nil {
+		return false, thrift.WrapTException(err)
+	}
+	if err := in.ReadMessageEnd(ctx); err != nil {
+		return false, thrift.WrapTException(err)
+	}
+	if err := out.WriteMessageBegin(ctx, name, thrift.EXCEPTION, seqID); err != nil {
+		return false, thrift.WrapTException(err)
+	}
+	if err := exc.Write(ctx, out); err != nil {
+		return false, thrift.WrapTException(err)
+	}
+	if err := out.WriteMessageEnd(ctx); err != nil {
+		return false, thrift.WrapTException(err)
+	}
+	if err := out.Flush(ctx); err != nil {
+		return false, thrift.WrapTException(err)
+	}
+	return true, exc
+}
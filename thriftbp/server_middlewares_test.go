@@ -4,13 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 
+	"github.com/reddit/baseplate.go/breakerbp"
 	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/faults"
 	"github.com/reddit/baseplate.go/mqsend"
+	"github.com/reddit/baseplate.go/ratelimit"
 	"github.com/reddit/baseplate.go/thriftbp"
 	"github.com/reddit/baseplate.go/thriftbp/thrifttest"
 	"github.com/reddit/baseplate.go/tracing"
@@ -332,3 +336,406 @@ func TestPanicMiddleware(t *testing.T) {
 		}
 	})
 }
+
+type fakeLimiter struct {
+	result ratelimit.Result
+	err    error
+	keys   []string
+}
+
+func (l *fakeLimiter) AllowN(_ context.Context, key string, _ int64) (ratelimit.Result, error) {
+	l.keys = append(l.keys, key)
+	return l.result, l.err
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	const name = "test"
+	ctx := context.Background()
+	newRequest := func(t *testing.T) thrift.TProtocol {
+		t.Helper()
+		buf := thrift.NewTMemoryBuffer()
+		proto := thrift.NewTBinaryProtocolConf(buf, nil)
+		// Write an empty args struct as the request body.
+		if err := proto.WriteStructBegin(ctx, "args"); err != nil {
+			t.Fatal(err)
+		}
+		if err := proto.WriteFieldStop(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := proto.WriteStructEnd(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := proto.WriteMessageEnd(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return proto
+	}
+
+	limiter := breakerbp.NewAdaptiveLimiter(breakerbp.AdaptiveLimiterConfig{
+		Name:         "test-thriftbp",
+		InitialLimit: 1,
+		MaxLimit:     1,
+	})
+	var wrapped thrift.TProcessorFunction
+	var calls int
+	var innerProto thrift.TProtocol
+	var innerOK bool
+	var innerErr thrift.TException
+	next := thrift.WrappedTProcessorFunction{
+		Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			calls++
+			if calls == 1 {
+				// A request arriving while this one is in flight.
+				innerProto = newRequest(t)
+				innerOK, innerErr = wrapped.Process(ctx, 2, innerProto, innerProto)
+			}
+			return true, nil
+		},
+	}
+	wrapped = thriftbp.ConcurrencyLimit(limiter)(name, next)
+
+	ok, err := wrapped.Process(ctx, 1, newRequest(t), thrift.NewTBinaryProtocolConf(thrift.NewTMemoryBuffer(), nil))
+	if !ok || err != nil {
+		t.Errorf("Expected (true, nil), got (%v, %v)", ok, err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected next to be called once, got %d", calls)
+	}
+	if !innerOK {
+		t.Error("Expected ok of the rejected request to be true, got false")
+	}
+	var tae thrift.TApplicationException
+	if !errors.As(innerErr, &tae) {
+		t.Fatalf("Expected TApplicationException, got %v", innerErr)
+	}
+	gotName, typeID, seqID, readErr := innerProto.ReadMessageBegin(ctx)
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	if gotName != name || typeID != thrift.EXCEPTION || seqID != 2 {
+		t.Errorf(
+			"Unexpected message begin: name=%q type=%v seqID=%d",
+			gotName,
+			typeID,
+			seqID,
+		)
+	}
+
+	// The limit is released after the request finished.
+	ok, err = wrapped.Process(ctx, 3, newRequest(t), thrift.NewTBinaryProtocolConf(thrift.NewTMemoryBuffer(), nil))
+	if !ok || err != nil {
+		t.Errorf("Expected (true, nil), got (%v, %v)", ok, err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected next to be called twice, got %d", calls)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	const name = "test"
+	key := func(ctx context.Context) string {
+		return "client"
+	}
+
+	for _, c := range []struct {
+		label   string
+		limiter *fakeLimiter
+		called  bool
+	}{
+		{
+			label:   "allowed",
+			limiter: &fakeLimiter{result: ratelimit.Result{Allowed: true}},
+			called:  true,
+		},
+		{
+			label:   "limited",
+			limiter: &fakeLimiter{result: ratelimit.Result{RetryAfter: time.Second}},
+		},
+		{
+			label:   "fail-open",
+			limiter: &fakeLimiter{err: errors.New("redis down")},
+			called:  true,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			ctx := context.Background()
+			buf := thrift.NewTMemoryBuffer()
+			proto := thrift.NewTBinaryProtocolConf(buf, nil)
+			// Write an empty args struct as the request body.
+			if err := proto.WriteStructBegin(ctx, "args"); err != nil {
+				t.Fatal(err)
+			}
+			if err := proto.WriteFieldStop(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if err := proto.WriteStructEnd(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if err := proto.WriteMessageEnd(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			var called bool
+			next := thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					called = true
+					return true, nil
+				},
+			}
+			wrapped := thriftbp.RateLimit(c.limiter, key)(name, next)
+			ok, err := wrapped.Process(ctx, 1, proto, proto)
+			if !ok {
+				t.Error("Expected ok to be true, got false")
+			}
+			if called != c.called {
+				t.Errorf("Expected next called to be %v, got %v", c.called, called)
+			}
+			if len(c.limiter.keys) != 1 || c.limiter.keys[0] != "test:client" {
+				t.Errorf("Expected limiter called with %q, got %q", "test:client", c.limiter.keys)
+			}
+			if c.called {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			var tae thrift.TApplicationException
+			if !errors.As(err, &tae) {
+				t.Fatalf("Expected TApplicationException, got %v", err)
+			}
+			gotName, typeID, seqID, readErr := proto.ReadMessageBegin(ctx)
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+			if gotName != name || typeID != thrift.EXCEPTION || seqID != 1 {
+				t.Errorf(
+					"Unexpected message begin: name=%q type=%v seqID=%d",
+					gotName,
+					typeID,
+					seqID,
+				)
+			}
+			exc := thrift.NewTApplicationException(thrift.UNKNOWN_APPLICATION_EXCEPTION, "")
+			if readErr := exc.Read(ctx, proto); readErr != nil {
+				t.Fatal(readErr)
+			}
+			if exc.Error() != tae.Error() {
+				t.Errorf("Expected exception %q written back, got %q", tae.Error(), exc.Error())
+			}
+		})
+	}
+}
+
+type fakeQuota struct {
+	result ratelimit.QuotaResult
+	err    error
+	keys   []string
+}
+
+func (q *fakeQuota) CheckAndConsume(_ context.Context, key string, _ int64) (ratelimit.QuotaResult, error) {
+	q.keys = append(q.keys, key)
+	return q.result, q.err
+}
+
+func TestQuota(t *testing.T) {
+	const name = "test"
+	resetAt := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	for _, c := range []struct {
+		label  string
+		quota  *fakeQuota
+		key    string
+		called bool
+	}{
+		{
+			label:  "allowed",
+			quota:  &fakeQuota{result: ratelimit.QuotaResult{Allowed: true}},
+			key:    "user",
+			called: true,
+		},
+		{
+			label: "exceeded",
+			quota: &fakeQuota{result: ratelimit.QuotaResult{Limit: 10, ResetAt: resetAt}},
+			key:   "user",
+		},
+		{
+			label:  "fail-open",
+			quota:  &fakeQuota{err: errors.New("redis down")},
+			key:    "user",
+			called: true,
+		},
+		{
+			label:  "anonymous",
+			quota:  &fakeQuota{},
+			called: true,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			ctx := context.Background()
+			buf := thrift.NewTMemoryBuffer()
+			proto := thrift.NewTBinaryProtocolConf(buf, nil)
+			// Write an empty args struct as the request body.
+			if err := proto.WriteStructBegin(ctx, "args"); err != nil {
+				t.Fatal(err)
+			}
+			if err := proto.WriteFieldStop(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if err := proto.WriteStructEnd(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if err := proto.WriteMessageEnd(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			var called bool
+			next := thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					called = true
+					return true, nil
+				},
+			}
+			key := func(ctx context.Context) string {
+				return c.key
+			}
+			wrapped := thriftbp.Quota(c.quota, key)(name, next)
+			ok, err := wrapped.Process(ctx, 1, proto, proto)
+			if !ok {
+				t.Error("Expected ok to be true, got false")
+			}
+			if called != c.called {
+				t.Errorf("Expected next called to be %v, got %v", c.called, called)
+			}
+			if c.key == "" {
+				if len(c.quota.keys) != 0 {
+					t.Errorf("Expected quota not called without key, got %q", c.quota.keys)
+				}
+			} else if len(c.quota.keys) != 1 || c.quota.keys[0] != c.key {
+				t.Errorf("Expected quota called with %q, got %q", c.key, c.quota.keys)
+			}
+			if c.called {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			var tae thrift.TApplicationException
+			if !errors.As(err, &tae) {
+				t.Fatalf("Expected TApplicationException, got %v", err)
+			}
+			if !strings.HasPrefix(tae.Error(), "QUOTA_EXCEEDED: ") || !strings.Contains(tae.Error(), "2021-01-02T00:00:00Z") {
+				t.Errorf("Unexpected exception message %q", tae.Error())
+			}
+			if _, typeID, _, readErr := proto.ReadMessageBegin(ctx); readErr != nil || typeID != thrift.EXCEPTION {
+				t.Errorf("Expected exception written back, got type %v, err %v", typeID, readErr)
+			}
+		})
+	}
+}
+
+func TestInjectFaults(t *testing.T) {
+	const name = "getUser"
+	injector, err := faults.NewInjector(faults.Config{
+		Rules: []faults.Rule{
+			{
+				Name:       "abort",
+				Callers:    []string{"abort-*"},
+				Percentage: 100,
+				Abort:      true,
+			},
+			{
+				Name:       "error",
+				Methods:    []string{"get*"},
+				Callers:    []string{"frontend"},
+				Percentage: 100,
+				Codes:      map[faults.Protocol]int{faults.ProtocolThrift: thrift.INTERNAL_ERROR},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		label  string
+		caller string
+		called bool
+		ok     bool
+		check  func(t *testing.T, err thrift.TException)
+	}{
+		{
+			label:  "no-match",
+			caller: "backend",
+			called: true,
+			ok:     true,
+		},
+		{
+			label:  "error",
+			caller: "frontend",
+			ok:     true,
+			check: func(t *testing.T, err thrift.TException) {
+				var appErr thrift.TApplicationException
+				if !errors.As(err, &appErr) {
+					t.Fatalf("Expected TApplicationException, got %v", err)
+				}
+				if appErr.TypeId() != thrift.INTERNAL_ERROR {
+					t.Errorf("Expected type %d, got %d", thrift.INTERNAL_ERROR, appErr.TypeId())
+				}
+				if !strings.HasPrefix(appErr.Error(), "FAULT_INJECTED:") {
+					t.Errorf("Expected FAULT_INJECTED message, got %q", appErr.Error())
+				}
+			},
+		},
+		{
+			label:  "abort",
+			caller: "abort-me",
+			check: func(t *testing.T, err thrift.TException) {
+				var transportErr thrift.TTransportException
+				if !errors.As(err, &transportErr) {
+					t.Errorf("Expected TTransportException, got %v", err)
+				}
+			},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			ctx := thrift.SetHeader(context.Background(), transport.HeaderUserAgent, c.caller)
+			buf := thrift.NewTMemoryBuffer()
+			proto := thrift.NewTBinaryProtocolConf(buf, nil)
+			// Write an empty args struct as the request body.
+			if err := proto.WriteStructBegin(ctx, "args"); err != nil {
+				t.Fatal(err)
+			}
+			if err := proto.WriteFieldStop(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if err := proto.WriteStructEnd(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if err := proto.WriteMessageEnd(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			var called bool
+			next := thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					called = true
+					return true, nil
+				},
+			}
+			ok, err := thriftbp.InjectFaults(injector)(name, next).Process(ctx, 1, proto, proto)
+			if ok != c.ok {
+				t.Errorf("Expected ok to be %v, got %v", c.ok, ok)
+			}
+			if called != c.called {
+				t.Errorf("Expected next called to be %v, got %v", c.called, called)
+			}
+			if c.check != nil {
+				c.check(t, err)
+			} else if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package timebp
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the interface of the time related functions,
+// so that the time dependent behaviors can be unit tested with a FakeClock.
+//
+// The libraries accepting a Clock (e.g. in their configs) treat nil as
+// SystemClock, see ClockOrDefault.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// Since returns the time elapsed since t.
+	Since(t time.Time) time.Duration
+
+	// After waits for the duration to elapse and then sends the current time on
+	// the returned channel.
+	After(d time.Duration) <-chan time.Time
+
+	// Sleep pauses the current goroutine for at least the duration d.
+	Sleep(d time.Duration)
+}
+
+// SystemClock is the Clock implemented with the functions from the time
+// package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (systemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// ClockOrDefault returns c, or SystemClock if c is nil.
+func ClockOrDefault(c Clock) Clock {
+	if c == nil {
+		return SystemClock
+	}
+	return c
+}
+
+// FakeClock is a Clock only moving forward when Advance or Set is called.
+//
+// It's safe for concurrent use.
+type FakeClock struct {
+	lock    sync.Mutex
+	now     time.Time
+	waiters []fakeClockWaiter
+}
+
+type fakeClockWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// Make sure FakeClock implements Clock.
+var _ Clock = (*FakeClock)(nil)
+
+// NewFakeClock creates a FakeClock starting at now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now: now,
+	}
+}
+
+// Now returns the current time of the FakeClock.
+func (c *FakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// Since returns the time elapsed since t according to the FakeClock.
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// After returns a channel receiving the time after the FakeClock is advanced
+// by d.
+//
+// When d <= 0 the channel receives the current time right away.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeClockWaiter{
+		at: c.now.Add(d),
+		ch: ch,
+	})
+	return ch
+}
+
+// Sleep blocks until the FakeClock is advanced by d.
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Advance moves the FakeClock forward by d,
+// and wakes up the After and Sleep calls due by then.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.setLocked(c.now.Add(d))
+}
+
+// Set sets the current time of the FakeClock,
+// and wakes up the After and Sleep calls due by then.
+//
+// Setting the FakeClock backwards doesn't wake up anything.
+func (c *FakeClock) Set(now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.setLocked(now)
+}
+
+func (c *FakeClock) setLocked(now time.Time) {
+	c.now = now
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(now) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.ch <- now
+	}
+	c.waiters = waiters
+}
+
+// NumWaiters returns the number of the pending After and Sleep calls.
+//
+// It's useful in tests to wait for the code under test to start waiting before
+// calling Advance.
+func (c *FakeClock) NumWaiters() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.waiters)
+}
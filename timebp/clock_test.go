@@ -0,0 +1,73 @@
+package timebp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+func TestClockOrDefault(t *testing.T) {
+	if c := timebp.ClockOrDefault(nil); c != timebp.SystemClock {
+		t.Errorf("Expected SystemClock, got %#v", c)
+	}
+	fake := timebp.NewFakeClock(time.Now())
+	if c := timebp.ClockOrDefault(fake); c != fake {
+		t.Errorf("Expected %#v, got %#v", fake, c)
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := timebp.NewFakeClock(start)
+
+	if now := clock.Now(); !now.Equal(start) {
+		t.Errorf("Expected now %v, got %v", start, now)
+	}
+
+	select {
+	case <-clock.After(0):
+	default:
+		t.Error("Expected After(0) to fire right away")
+	}
+
+	ch := clock.After(time.Minute)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		clock.Sleep(time.Hour)
+	}()
+	for clock.NumWaiters() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.Advance(time.Second * 59)
+	select {
+	case <-ch:
+		t.Error("Expected After(time.Minute) not to fire after 59s")
+	default:
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case got := <-ch:
+		if expected := start.Add(time.Minute); !got.Equal(expected) {
+			t.Errorf("Expected After to send %v, got %v", expected, got)
+		}
+	default:
+		t.Error("Expected After(time.Minute) to fire after 1m")
+	}
+	if elapsed := clock.Since(start); elapsed != time.Minute {
+		t.Errorf("Expected Since to return 1m, got %v", elapsed)
+	}
+
+	clock.Set(start.Add(time.Hour))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Expected Sleep(time.Hour) to return after 1h")
+	}
+	if n := clock.NumWaiters(); n != 0 {
+		t.Errorf("Expected no waiters left, got %d", n)
+	}
+}
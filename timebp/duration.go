@@ -0,0 +1,223 @@
+package timebp
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	_ json.Marshaler           = Duration(0)
+	_ json.Unmarshaler         = (*Duration)(nil)
+	_ encoding.TextMarshaler   = Duration(0)
+	_ encoding.TextUnmarshaler = (*Duration)(nil)
+)
+
+// Duration is a time.Duration to be used in the configs,
+// with human friendly parsing from YAML, JSON, and text.
+//
+// It accepts the following formats:
+//
+// 1. The strings accepted by time.ParseDuration, e.g. "1m30s" or "1.5h".
+//
+// 2. Additionally the "d" (24 hours) and "w" (7 days) units,
+// e.g. "1d12h" or "2w".
+//
+// 3. Numbers (either plain or quoted), as the number of seconds,
+// e.g. 30 or "0.5".
+//
+// It's encoded in the format of time.Duration.String.
+type Duration time.Duration
+
+// ParseDuration parses s in the formats accepted by Duration.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("timebp: invalid duration %q", s)
+	}
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		return secondsToDuration(s, seconds)
+	}
+
+	var sign time.Duration = 1
+	rest := s
+	switch rest[0] {
+	case '-':
+		sign = -1
+		rest = rest[1:]
+	case '+':
+		rest = rest[1:]
+	}
+	// A bare sign, or a sign followed by another one for time.ParseDuration.
+	if rest == "" || rest[0] == '-' || rest[0] == '+' {
+		return 0, fmt.Errorf("timebp: invalid duration %q", s)
+	}
+
+	// Take out the leading days and weeks, as time.ParseDuration doesn't support
+	// them.
+	var total time.Duration
+	for {
+		i := 0
+		for i < len(rest) && (rest[i] >= '0' && rest[i] <= '9' || rest[i] == '.') {
+			i++
+		}
+		if i == 0 || i >= len(rest) || (rest[i] != 'd' && rest[i] != 'w') {
+			break
+		}
+		n, err := strconv.ParseFloat(rest[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("timebp: invalid duration %q", s)
+		}
+		unit := 24 * time.Hour
+		if rest[i] == 'w' {
+			unit *= 7
+		}
+		if n*float64(unit) > math.MaxInt64 {
+			return 0, fmt.Errorf("timebp: duration %q out of range", s)
+		}
+		total += time.Duration(n * float64(unit))
+		rest = rest[i+1:]
+	}
+	if rest != "" {
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			return 0, fmt.Errorf("timebp: invalid duration %q", s)
+		}
+		total += d
+	}
+	return sign * total, nil
+}
+
+func secondsToDuration(s string, seconds float64) (time.Duration, error) {
+	ns := seconds * float64(time.Second)
+	if math.IsNaN(ns) || ns > math.MaxInt64 || ns < math.MinInt64 {
+		return 0, fmt.Errorf("timebp: duration %q out of range", s)
+	}
+	return time.Duration(ns), nil
+}
+
+func (d Duration) String() string {
+	return d.ToDuration().String()
+}
+
+// ToDuration converts Duration back to time.Duration.
+func (d Duration) ToDuration() time.Duration {
+	return time.Duration(d)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+//
+// Empty data is decoded as 0.
+func (d *Duration) UnmarshalText(data []byte) error {
+	if len(data) == 0 {
+		*d = 0
+		return nil
+	}
+	parsed, err := ParseDuration(string(data))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// It accepts both JSON strings and numbers (as seconds),
+// and null is decoded as 0.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		*d = 0
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+//
+// It accepts both YAML strings and numbers (as seconds).
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case nil:
+		*d = 0
+		return nil
+	case int:
+		*d = Duration(time.Duration(v) * time.Second)
+		return nil
+	case float64:
+		parsed, err := secondsToDuration(fmt.Sprint(v), v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+		return nil
+	case string:
+		return d.UnmarshalText([]byte(v))
+	default:
+		return fmt.Errorf("timebp: invalid duration %v", v)
+	}
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// DurationOutOfRangeError is the error returned by Duration.Validate.
+type DurationOutOfRangeError struct {
+	Duration time.Duration
+
+	// Max is 0 when there's no upper bound.
+	Min, Max time.Duration
+}
+
+func (e DurationOutOfRangeError) Error() string {
+	if e.Max <= 0 {
+		return fmt.Sprintf("timebp: duration %v must be at least %v", e.Duration, e.Min)
+	}
+	return fmt.Sprintf("timebp: duration %v must be in [%v, %v]", e.Duration, e.Min, e.Max)
+}
+
+// Validate checks that d is in the inclusive range of [min, max],
+// max <= 0 means there's no upper bound.
+//
+// It returns DurationOutOfRangeError when it's out of range.
+// It's usually called in the ValidateConfig of the configs
+// (see configbp.Validator), for example:
+//
+//	func (c Config) ValidateConfig() error {
+//		return c.Timeout.Validate(time.Millisecond, time.Minute)
+//	}
+func (d Duration) Validate(min, max time.Duration) error {
+	v := d.ToDuration()
+	if v < min || (max > 0 && v > max) {
+		return DurationOutOfRangeError{
+			Duration: v,
+			Min:      min,
+			Max:      max,
+		}
+	}
+	return nil
+}
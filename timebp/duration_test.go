@@ -0,0 +1,143 @@
+package timebp_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+func TestParseDuration(t *testing.T) {
+	for _, c := range []struct {
+		input    string
+		expected time.Duration
+		err      bool
+	}{
+		{input: "1m30s", expected: time.Second * 90},
+		{input: "1.5h", expected: time.Minute * 90},
+		{input: "1d", expected: time.Hour * 24},
+		{input: "1d12h", expected: time.Hour * 36},
+		{input: "2w1d", expected: time.Hour * 24 * 15},
+		{input: "-1d", expected: -time.Hour * 24},
+		{input: "30", expected: time.Second * 30},
+		{input: "0.5", expected: time.Millisecond * 500},
+		{input: " 10ms ", expected: time.Millisecond * 10},
+		{input: "", err: true},
+		{input: "-", err: true},
+		{input: "+", err: true},
+		{input: "--1h", err: true},
+		{input: "1x", err: true},
+		{input: "1h1d", err: true},
+		{input: "1000000w", err: true},
+	} {
+		t.Run(c.input, func(t *testing.T) {
+			d, err := timebp.ParseDuration(c.input)
+			if c.err {
+				if err == nil {
+					t.Errorf("Expected error, got %v", d)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d != c.expected {
+				t.Errorf("Expected %v, got %v", c.expected, d)
+			}
+		})
+	}
+}
+
+func TestDurationYAML(t *testing.T) {
+	var cfg struct {
+		A timebp.Duration `yaml:"a"`
+		B timebp.Duration `yaml:"b"`
+		C timebp.Duration `yaml:"c"`
+		D timebp.Duration `yaml:"d"`
+	}
+	const input = `
+a: 1d
+b: 30
+c: 0.25
+d: 1m
+`
+	if err := yaml.Unmarshal([]byte(input), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		got, expected time.Duration
+	}{
+		{cfg.A.ToDuration(), time.Hour * 24},
+		{cfg.B.ToDuration(), time.Second * 30},
+		{cfg.C.ToDuration(), time.Millisecond * 250},
+		{cfg.D.ToDuration(), time.Minute},
+	} {
+		if c.got != c.expected {
+			t.Errorf("Expected %v, got %v", c.expected, c.got)
+		}
+	}
+
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = "a: 24h0m0s\nb: 30s\nc: 250ms\nd: 1m0s\n"
+	if string(out) != expected {
+		t.Errorf("Expected yaml %q, got %q", expected, out)
+	}
+
+	if err := yaml.Unmarshal([]byte("a: foo"), &cfg); err == nil {
+		t.Error("Expected error for invalid duration, got nil")
+	}
+}
+
+func TestDurationJSON(t *testing.T) {
+	var v struct {
+		A timebp.Duration `json:"a"`
+		B timebp.Duration `json:"b"`
+	}
+	if err := json.Unmarshal([]byte(`{"a": "1h", "b": 1.5}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A.ToDuration() != time.Hour {
+		t.Errorf("Expected 1h, got %v", v.A)
+	}
+	if v.B.ToDuration() != time.Millisecond*1500 {
+		t.Errorf("Expected 1.5s, got %v", v.B)
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"a":"1h0m0s","b":"1.5s"}`; string(out) != expected {
+		t.Errorf("Expected json %s, got %s", expected, out)
+	}
+}
+
+func TestDurationValidate(t *testing.T) {
+	d := timebp.Duration(time.Second)
+	if err := d.Validate(time.Millisecond, time.Minute); err != nil {
+		t.Errorf("Expected nil error, got %v", err)
+	}
+	if err := d.Validate(time.Millisecond, 0); err != nil {
+		t.Errorf("Expected nil error without upper bound, got %v", err)
+	}
+
+	err := d.Validate(time.Minute, time.Hour)
+	var rangeErr timebp.DurationOutOfRangeError
+	if !errors.As(err, &rangeErr) {
+		t.Fatalf("Expected DurationOutOfRangeError, got %v", err)
+	}
+	const expected = "timebp: duration 1s must be in [1m0s, 1h0m0s]"
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, got %q", expected, err.Error())
+	}
+	if err := d.Validate(0, time.Millisecond); err == nil {
+		t.Error("Expected error when exceeding max, got nil")
+	}
+}
@@ -1,9 +1,11 @@
 package tracing
 
 import (
+	"fmt"
 	"io"
 	"time"
 
+	"github.com/reddit/baseplate.go/errorsbp"
 	"github.com/reddit/baseplate.go/log"
 	"github.com/reddit/baseplate.go/mqsend"
 )
@@ -22,10 +24,22 @@ type Config struct {
 	// except the ones with debug flag set.
 	//
 	// Please note that SampleRate only affect top level spans created inside this
-	// service. For most services the sample status will be inherited from the
+	// service, and spans from the headers without the sampled flag.
+	// For most services the sample status will be inherited from the
 	// headers from the client.
+	//
+	// The sampling decision is a deterministic function of the trace id and
+	// SampleRate (see ShouldSampleTraceID).
 	SampleRate float64 `yaml:"sampleRate"`
 
+	// Sampler is the optional config to compose the Sampler making the sampling
+	// decisions of the root spans, see SamplerConfig for the details.
+	//
+	// When it's set, SampleRate is ignored.
+	// When it's nil, a parentBased sampler with a ratio sampler at SampleRate
+	// as the root is used.
+	Sampler *SamplerConfig `yaml:"sampler"`
+
 	// Logger, if non-nil, will be used to log additional informations Record
 	// returned certain errors.
 	Logger log.Wrapper `yaml:"logger"`
@@ -58,6 +72,13 @@ type Config struct {
 	// This is only used when QueueName is non-empty.
 	MaxQueueSize int64 `yaml:"maxQueueSize"`
 
+	// Transport chooses how the spans are sent to the sidecar,
+	// for the platforms without posix message queues (e.g. macOS).
+	//
+	// Optional, defaults to posix message queue.
+	// This is only used when QueueName is non-empty.
+	Transport mqsend.TransportConfig `yaml:"transport"`
+
 	// If UseHex is set to true, when generating new trace/span IDs we will use
 	// hex instead of dec uint64.
 	//
@@ -76,6 +97,21 @@ type Config struct {
 	TestOnlyMockMessageQueue mqsend.MessageQueue `yaml:"-"`
 }
 
+// ValidateConfig implements configbp.Validator.
+func (cfg Config) ValidateConfig() error {
+	var batch errorsbp.Batch
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		batch.Add(fmt.Errorf("sampleRate: must be in [0, 1], got %v", cfg.SampleRate))
+	}
+	if cfg.MaxRecordTimeout < 0 {
+		batch.Add(fmt.Errorf("recordTimeout: must not be negative, got %v", cfg.MaxRecordTimeout))
+	}
+	if cfg.MaxQueueSize < 0 {
+		batch.Add(fmt.Errorf("maxQueueSize: must not be negative, got %d", cfg.MaxQueueSize))
+	}
+	return batch.Compile()
+}
+
 // InitFromConfig initializes the global tracer using the given Config and
 // also registers the ErrorReporterCreateServerSpanHook with the global span
 // hook registry.
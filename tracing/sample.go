@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"hash/fnv"
+	"math"
+	"strconv"
+)
+
+// ShouldSampleTraceID returns the sampling decision of traceID at rate.
+//
+// The decision is a deterministic function of traceID and rate,
+// so all the services in a call chain configured with the same rate make the
+// same decision for the same trace,
+// even when the sampled flag is not propagated in the headers.
+// With a lower rate the traces sampled are a subset of the ones sampled with
+// a higher rate.
+//
+// Same as other baseplate implementations,
+// traceID is parsed into its numeric value,
+// and it's sampled when the value is less than rate * math.MaxUint64.
+// The numeric value is the same whether the trace id is in decimal or hex,
+// so both formats get the same decision.
+// traceID is parsed as decimal when it only contains digits,
+// otherwise it's parsed as hex,
+// and only the lower 64 bits are used for 128-bit hex trace ids.
+// Trace ids that are neither use the FNV-1a hash of the string instead.
+//
+// rate should be in the range of [0, 1].
+// When rate <= 0 this function always returns false;
+// When rate >= 1 this function always returns true.
+func ShouldSampleTraceID(traceID string, rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	if rate >= 1 {
+		return true
+	}
+	return traceIDValue(traceID) < uint64(rate*math.MaxUint64)
+}
+
+// traceIDValue returns the numeric value of traceID used by
+// ShouldSampleTraceID.
+func traceIDValue(traceID string) uint64 {
+	if id, err := strconv.ParseUint(traceID, 10, 64); err == nil {
+		return id
+	}
+	hex := traceID
+	if n := len(hex); n > 16 && n <= 32 {
+		if _, err := strconv.ParseUint(hex[:n-16], 16, 64); err == nil {
+			hex = hex[n-16:]
+		}
+	}
+	if id, err := strconv.ParseUint(hex, 16, 64); err == nil {
+		return id
+	}
+	h := fnv.New64a()
+	h.Write([]byte(traceID))
+	return h.Sum64()
+}
@@ -0,0 +1,92 @@
+package tracing_test
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+func TestShouldSampleTraceID(t *testing.T) {
+	const n = 10000
+	r := rand.New(rand.NewSource(1))
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = strconv.FormatUint(r.Uint64(), 10)
+	}
+	for _, rate := range []float64{0, 0.1, 0.5, 1} {
+		t.Run(strconv.FormatFloat(rate, 'f', -1, 64), func(t *testing.T) {
+			var sampled int
+			for _, id := range ids {
+				decision := tracing.ShouldSampleTraceID(id, rate)
+				if decision != tracing.ShouldSampleTraceID(id, rate) {
+					t.Fatalf("Expected consistent decision for trace id %q", id)
+				}
+				if decision {
+					sampled++
+					// Traces sampled at rate must also be sampled at any higher rate.
+					if !tracing.ShouldSampleTraceID(id, math.Min(rate*2, 1)) {
+						t.Errorf("Trace id %q sampled at %v but not at %v", id, rate, rate*2)
+					}
+				}
+			}
+			if got := float64(sampled) / n; math.Abs(got-rate) > 0.02 {
+				t.Errorf("Expected sampled ratio around %v, got %v", rate, got)
+			}
+		})
+	}
+}
+
+func TestShouldSampleTraceIDKnownIDs(t *testing.T) {
+	for _, c := range []struct {
+		id       string
+		rate     float64
+		expected bool
+	}{
+		{id: "0", rate: 0.0001, expected: true},
+		{id: "18446744073709551615", rate: 0.9999, expected: false},
+		{id: "ffffffffffffffff", rate: 0.9999, expected: false},
+		{id: "9223372036854775807", rate: 0.5, expected: true},
+		{id: "7fffffffffffffff", rate: 0.5, expected: true},
+		{id: "9223372036854775818", rate: 0.5, expected: false},
+		{id: "800000000000000a", rate: 0.5, expected: false},
+		{id: "1844674407370955161", rate: 0.1, expected: true},
+		{id: "1999999999999a00", rate: 0.1, expected: false},
+		// 128-bit hex trace ids use the lower 64 bits.
+		{id: "ffffffffffffffff7fffffffffffffff", rate: 0.5, expected: true},
+		{id: "0000000000000000800000000000000a", rate: 0.5, expected: false},
+		// Trace ids that are not numeric use the hash of the string.
+		{id: "foo", rate: 0.5, expected: false},
+		{id: "bar", rate: 0.5, expected: true},
+	} {
+		if got := tracing.ShouldSampleTraceID(c.id, c.rate); got != c.expected {
+			t.Errorf("ShouldSampleTraceID(%q, %v) expected %v, got %v", c.id, c.rate, c.expected, got)
+		}
+	}
+}
+
+func TestStartSpanFromHeadersDeterministicSampling(t *testing.T) {
+	defer func() {
+		tracing.CloseTracer()
+		tracing.InitGlobalTracer(tracing.Config{})
+	}()
+	const rate = 0.5
+	if err := tracing.InitGlobalTracer(tracing.Config{
+		SampleRate: rate,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		id := strconv.Itoa(i)
+		_, span := tracing.StartSpanFromHeaders(context.Background(), "foo", tracing.Headers{
+			TraceID: id,
+		})
+		if expected := tracing.ShouldSampleTraceID(id, rate); span.Sampled() != expected {
+			t.Errorf("Expected sampled %v for trace id %q, got %v", expected, id, span.Sampled())
+		}
+	}
+}
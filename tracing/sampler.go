@@ -0,0 +1,257 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"sync"
+	"time"
+)
+
+// SamplingParameters are the parameters passed into Sampler to make the
+// sampling decision of a root span.
+type SamplingParameters struct {
+	// TraceID is the trace id of the span.
+	TraceID string
+
+	// Name is the name of the span (e.g. the endpoint).
+	Name string
+
+	// Type is the type of the span.
+	Type SpanType
+
+	// ParentSampled is the sampling decision from the upstream caller,
+	// it's nil when the span is a top level span,
+	// or the upstream caller didn't send the sampled flag.
+	ParentSampled *bool
+}
+
+// Sampler makes the sampling decisions of the root spans.
+//
+// Child spans inside the same process always inherit the decision of their
+// parents.
+type Sampler interface {
+	ShouldSample(params SamplingParameters) bool
+}
+
+// SamplerFunc is a function implementing Sampler.
+type SamplerFunc func(params SamplingParameters) bool
+
+// ShouldSample implements Sampler.
+func (f SamplerFunc) ShouldSample(params SamplingParameters) bool {
+	return f(params)
+}
+
+// AlwaysSample returns a Sampler that samples all the spans.
+func AlwaysSample() Sampler {
+	return SamplerFunc(func(SamplingParameters) bool {
+		return true
+	})
+}
+
+// NeverSample returns a Sampler that samples none of the spans.
+//
+// Please note that spans with the debug flag set are still sampled.
+func NeverSample() Sampler {
+	return SamplerFunc(func(SamplingParameters) bool {
+		return false
+	})
+}
+
+// TraceIDRatioSampler returns a Sampler that samples the spans based on their
+// trace ids via ShouldSampleTraceID.
+func TraceIDRatioSampler(rate float64) Sampler {
+	return SamplerFunc(func(params SamplingParameters) bool {
+		return ShouldSampleTraceID(params.TraceID, rate)
+	})
+}
+
+// ParentBasedSampler returns a Sampler that follows the decision of the
+// upstream caller when it's available,
+// and delegates to root otherwise.
+func ParentBasedSampler(root Sampler) Sampler {
+	return SamplerFunc(func(params SamplingParameters) bool {
+		if params.ParentSampled != nil {
+			return *params.ParentSampled
+		}
+		return root.ShouldSample(params)
+	})
+}
+
+// RateLimitingSampler returns a Sampler that samples at most perSecond spans
+// per second.
+//
+// perSecond can be fractional, e.g. 0.1 samples one span every 10 seconds.
+func RateLimitingSampler(perSecond float64) Sampler {
+	// Allow bursts of at most 1 second, but at least 1 span.
+	burst := math.Max(perSecond, 1)
+	return &rateLimitingSampler{
+		perSecond: perSecond,
+		burst:     burst,
+		tokens:    burst,
+		last:      time.Now(),
+		now:       time.Now,
+	}
+}
+
+type rateLimitingSampler struct {
+	perSecond float64
+	burst     float64
+
+	lock   sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func (s *rateLimitingSampler) ShouldSample(SamplingParameters) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	s.tokens += now.Sub(s.last).Seconds() * s.perSecond
+	if s.tokens > s.burst {
+		s.tokens = s.burst
+	}
+	s.last = now
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+// SamplerRule is a rule used by RuleSampler.
+type SamplerRule struct {
+	// Name is the pattern of the span names this rule applies to,
+	// in the syntax of path.Match (e.g. "is_healthy", "/v1/*").
+	Name string
+
+	// Sampler is the Sampler to be used for the matched spans.
+	Sampler Sampler
+}
+
+// RuleSampler returns a Sampler that uses the Sampler of the first matched
+// rule, and fallback when none of the rules matched.
+func RuleSampler(rules []SamplerRule, fallback Sampler) Sampler {
+	return SamplerFunc(func(params SamplingParameters) bool {
+		for _, rule := range rules {
+			if matched, _ := path.Match(rule.Name, params.Name); matched {
+				return rule.Sampler.ShouldSample(params)
+			}
+		}
+		return fallback.ShouldSample(params)
+	})
+}
+
+// Sampler types supported by SamplerConfig.
+const (
+	SamplerTypeAlways      = "always"
+	SamplerTypeNever       = "never"
+	SamplerTypeRatio       = "ratio"
+	SamplerTypeRateLimit   = "rateLimit"
+	SamplerTypeParentBased = "parentBased"
+	SamplerTypeRules       = "rules"
+)
+
+// SamplerConfig is the config to compose a Sampler.
+//
+// Can be deserialized from YAML.
+//
+// Example:
+//
+//     sampler:
+//       type: parentBased
+//       root:
+//         type: rules
+//         rules:
+//           - name: "is_healthy"
+//             sampler:
+//               type: never
+//         default:
+//           type: rateLimit
+//           rateLimit: 10
+type SamplerConfig struct {
+	// Type is the type of the Sampler, one of the SamplerType* constants.
+	Type string `yaml:"type"`
+
+	// Ratio is the rate used by SamplerTypeRatio, in [0, 1],
+	// see TraceIDRatioSampler.
+	Ratio float64 `yaml:"ratio"`
+
+	// RateLimit is the max number of spans sampled per second, used by
+	// SamplerTypeRateLimit, see RateLimitingSampler.
+	RateLimit float64 `yaml:"rateLimit"`
+
+	// Root is the Sampler used by SamplerTypeParentBased when there's no
+	// decision from the upstream caller, see ParentBasedSampler.
+	Root *SamplerConfig `yaml:"root"`
+
+	// Rules and Default are used by SamplerTypeRules, see RuleSampler.
+	Rules   []SamplerRuleConfig `yaml:"rules"`
+	Default *SamplerConfig      `yaml:"default"`
+}
+
+// SamplerRuleConfig is the config of a SamplerRule.
+//
+// Can be deserialized from YAML.
+type SamplerRuleConfig struct {
+	Name    string        `yaml:"name"`
+	Sampler SamplerConfig `yaml:"sampler"`
+}
+
+// Build builds the Sampler from the config.
+func (c SamplerConfig) Build() (Sampler, error) {
+	switch c.Type {
+	default:
+		return nil, fmt.Errorf("tracing: unknown sampler type %q", c.Type)
+	case SamplerTypeAlways:
+		return AlwaysSample(), nil
+	case SamplerTypeNever:
+		return NeverSample(), nil
+	case SamplerTypeRatio:
+		// Written this way to also reject NaN.
+		if !(c.Ratio >= 0 && c.Ratio <= 1) {
+			return nil, fmt.Errorf("tracing: ratio must be in [0, 1] for %q sampler, got %v", c.Type, c.Ratio)
+		}
+		return TraceIDRatioSampler(c.Ratio), nil
+	case SamplerTypeRateLimit:
+		if c.RateLimit <= 0 {
+			return nil, fmt.Errorf("tracing: rateLimit must be positive for %q sampler, got %v", c.Type, c.RateLimit)
+		}
+		return RateLimitingSampler(c.RateLimit), nil
+	case SamplerTypeParentBased:
+		if c.Root == nil {
+			return nil, errors.New("tracing: root is required for parentBased sampler")
+		}
+		root, err := c.Root.Build()
+		if err != nil {
+			return nil, err
+		}
+		return ParentBasedSampler(root), nil
+	case SamplerTypeRules:
+		if c.Default == nil {
+			return nil, errors.New("tracing: default is required for rules sampler")
+		}
+		rules := make([]SamplerRule, 0, len(c.Rules))
+		for _, rc := range c.Rules {
+			if _, err := path.Match(rc.Name, ""); err != nil {
+				return nil, fmt.Errorf("tracing: invalid sampler rule name %q: %w", rc.Name, err)
+			}
+			sampler, err := rc.Sampler.Build()
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, SamplerRule{
+				Name:    rc.Name,
+				Sampler: sampler,
+			})
+		}
+		fallback, err := c.Default.Build()
+		if err != nil {
+			return nil, err
+		}
+		return RuleSampler(rules, fallback), nil
+	}
+}
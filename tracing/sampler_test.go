@@ -0,0 +1,218 @@
+package tracing
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestSamplerConfig(t *testing.T) {
+	const cfgYAML = `
+type: parentBased
+root:
+  type: rules
+  rules:
+    - name: is_healthy
+      sampler:
+        type: never
+    - name: /v1/*
+      sampler:
+        type: always
+  default:
+    type: ratio
+    ratio: 0
+`
+	var cfg SamplerConfig
+	if err := yaml.Unmarshal([]byte(cfgYAML), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	sampler, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	yes, no := true, false
+	for _, c := range []struct {
+		label    string
+		params   SamplingParameters
+		expected bool
+	}{
+		{
+			label:    "parent-sampled",
+			params:   SamplingParameters{Name: "is_healthy", ParentSampled: &yes},
+			expected: true,
+		},
+		{
+			label:    "parent-not-sampled",
+			params:   SamplingParameters{Name: "/v1/foo", ParentSampled: &no},
+			expected: false,
+		},
+		{
+			label:    "rule-never",
+			params:   SamplingParameters{Name: "is_healthy"},
+			expected: false,
+		},
+		{
+			label:    "rule-always",
+			params:   SamplingParameters{Name: "/v1/foo"},
+			expected: true,
+		},
+		{
+			label:    "default",
+			params:   SamplingParameters{Name: "/v2/foo"},
+			expected: false,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			if got := sampler.ShouldSample(c.params); got != c.expected {
+				t.Errorf("Expected %v, got %v", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestSamplerConfigErrors(t *testing.T) {
+	for _, c := range []struct {
+		label string
+		cfg   SamplerConfig
+	}{
+		{label: "unknown-type", cfg: SamplerConfig{Type: "foo"}},
+		{label: "ratio-negative", cfg: SamplerConfig{Type: SamplerTypeRatio, Ratio: -0.1}},
+		{label: "ratio-above-one", cfg: SamplerConfig{Type: SamplerTypeRatio, Ratio: 1.5}},
+		{label: "ratio-nan", cfg: SamplerConfig{Type: SamplerTypeRatio, Ratio: math.NaN()}},
+		{label: "rate-limit", cfg: SamplerConfig{Type: SamplerTypeRateLimit}},
+		{label: "parent-based", cfg: SamplerConfig{Type: SamplerTypeParentBased}},
+		{label: "rules", cfg: SamplerConfig{Type: SamplerTypeRules}},
+		{
+			label: "rule-name",
+			cfg: SamplerConfig{
+				Type:    SamplerTypeRules,
+				Rules:   []SamplerRuleConfig{{Name: "[", Sampler: SamplerConfig{Type: SamplerTypeAlways}}},
+				Default: &SamplerConfig{Type: SamplerTypeNever},
+			},
+		},
+		{
+			label: "nested",
+			cfg: SamplerConfig{
+				Type: SamplerTypeParentBased,
+				Root: &SamplerConfig{Type: "foo"},
+			},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			if _, err := c.cfg.Build(); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestRateLimitingSampler(t *testing.T) {
+	sampler := RateLimitingSampler(2).(*rateLimitingSampler)
+	now := time.Now()
+	sampler.last = now
+	sampler.now = func() time.Time {
+		return now
+	}
+
+	var sampled int
+	for i := 0; i < 10; i++ {
+		if sampler.ShouldSample(SamplingParameters{}) {
+			sampled++
+		}
+	}
+	if sampled != 2 {
+		t.Errorf("Expected 2 sampled in the first second, got %d", sampled)
+	}
+
+	now = now.Add(time.Second / 2)
+	if !sampler.ShouldSample(SamplingParameters{}) {
+		t.Error("Expected 1 sampled after half a second")
+	}
+	if sampler.ShouldSample(SamplingParameters{}) {
+		t.Error("Expected only 1 sampled after half a second")
+	}
+}
+
+func TestRateLimitingSamplerFractional(t *testing.T) {
+	sampler := RateLimitingSampler(0.5).(*rateLimitingSampler)
+	now := time.Now()
+	sampler.last = now
+	sampler.now = func() time.Time {
+		return now
+	}
+
+	if !sampler.ShouldSample(SamplingParameters{}) {
+		t.Error("Expected the first span to be sampled")
+	}
+	if sampler.ShouldSample(SamplingParameters{}) {
+		t.Error("Expected only 1 sampled in the first second")
+	}
+
+	now = now.Add(time.Second)
+	if sampler.ShouldSample(SamplingParameters{}) {
+		t.Error("Expected none sampled after 1 second")
+	}
+
+	now = now.Add(time.Second)
+	if !sampler.ShouldSample(SamplingParameters{}) {
+		t.Error("Expected 1 sampled after 2 seconds")
+	}
+
+	now = now.Add(time.Minute)
+	var sampled int
+	for i := 0; i < 10; i++ {
+		if sampler.ShouldSample(SamplingParameters{}) {
+			sampled++
+		}
+	}
+	if sampled != 1 {
+		t.Errorf("Expected bursts of at most 1 sampled, got %d", sampled)
+	}
+}
+
+func TestTracerSampler(t *testing.T) {
+	defer func() {
+		CloseTracer()
+		InitGlobalTracer(Config{})
+	}()
+	if err := InitGlobalTracer(Config{
+		SampleRate: 1,
+		Sampler: &SamplerConfig{
+			Type: SamplerTypeRules,
+			Rules: []SamplerRuleConfig{
+				{Name: "is_healthy", Sampler: SamplerConfig{Type: SamplerTypeNever}},
+			},
+			Default: &SamplerConfig{Type: SamplerTypeAlways},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := StartTopLevelServerSpan(context.Background(), "is_healthy")
+	if span.Sampled() {
+		t.Error("Expected is_healthy not sampled")
+	}
+	_, span = StartTopLevelServerSpan(context.Background(), "foo")
+	if !span.Sampled() {
+		t.Error("Expected foo sampled")
+	}
+	// The sampler is not parent based, so the sampled header is ignored.
+	sampled := true
+	_, span = StartSpanFromHeaders(context.Background(), "is_healthy", Headers{
+		TraceID: "1234",
+		Sampled: &sampled,
+	})
+	if span.Sampled() {
+		t.Error("Expected is_healthy from headers not sampled")
+	}
+
+	if err := InitGlobalTracer(Config{
+		Sampler: &SamplerConfig{Type: "foo"},
+	}); err == nil {
+		t.Error("Expected error from invalid sampler config, got nil")
+	}
+}
@@ -141,8 +141,22 @@ func (s Span) Flags() int64 {
 }
 
 // Sampled returns if the current span is sampled.
+//
+// Spans with the debug flag set (see SetDebug and FlagMaskDebug) are always
+// sampled, so that the sampling decision is also forced on all the downstream
+// services they call.
 func (s Span) Sampled() bool {
-	return s.trace.sampled
+	return s.trace.shouldSample()
+}
+
+// Debug returns true if the debug flag is set on the current span.
+//
+// The debug flag is propagated to all the child spans and downstream services,
+// forcing the whole trace to be sampled.
+// It can be set by upstream callers via the flags header,
+// or explicitly via SetDebug.
+func (s Span) Debug() bool {
+	return s.trace.isDebugSet()
 }
 
 // StartTime the time that the span was started.
@@ -536,9 +550,14 @@ func (h Headers) ParseSampled() (sampled bool, ok bool) {
 // StartSpanFromHeaders creates a server span from the passed in Headers. If no
 // headers are set, then a new top-level server span will be created and returned.
 //
-// Please note that "Sampled" header is default to false according to baseplate
-// spec, so if the headers are incorrect, this span (and all its child-spans)
-// will never be sampled, unless debug flag was set explicitly later.
+// The sampling decision is made by the configured Sampler,
+// with the "Sampled" header as the decision from the upstream caller.
+// By default the decision from the upstream caller is followed,
+// and when the "Sampled" header is missing,
+// the decision is made by ShouldSampleTraceID with the trace id and the
+// configured SampleRate,
+// so it's consistent with the other services in the call chain configured
+// with the same rate.
 //
 // If any headers are missing or malformed, they will be ignored.
 // Malformed headers will be logged if InitGlobalTracer was last called with a
@@ -568,9 +587,15 @@ func StartSpanFromHeaders(ctx context.Context, name string, headers Headers) (co
 		span.trace.flags = flags
 	}
 
+	params := SamplingParameters{
+		TraceID: span.trace.traceID,
+		Name:    name,
+		Type:    SpanTypeServer,
+	}
 	if sampled, ok := headers.ParseSampled(); ok {
-		span.trace.sampled = sampled
+		params.ParentSampled = &sampled
 	}
+	span.trace.sampled = span.trace.tracer.shouldSample(params)
 
 	ctx = initRootSpan(ctx, span)
 
@@ -590,9 +615,14 @@ func StartSpanFromHeaders(ctx context.Context, name string, headers Headers) (co
 //
 // It also doesn't necessarily mean the span must be a server span.
 func initRootSpan(ctx context.Context, s *Span) context.Context {
-	ctx = log.Attach(ctx, log.AttachArgs{
+	args := log.AttachArgs{
 		TraceID: s.TraceID(),
-	})
+		SpanID:  s.ID(),
+	}
+	if s.spanType == SpanTypeServer {
+		args.Request = s.Name()
+	}
+	ctx = log.Attach(ctx, args)
 	s.hub = sentry.GetHubFromContext(ctx)
 	return ctx
 }
@@ -1,6 +1,7 @@
 package tracing
 
 import (
+	"context"
 	"math/rand"
 	"reflect"
 	"strings"
@@ -105,6 +106,33 @@ func TestDebugFlag(t *testing.T) {
 	)
 }
 
+func TestDebugFlagFromHeaders(t *testing.T) {
+	sampled := false
+	ctx, span := StartSpanFromHeaders(context.Background(), "test", Headers{
+		TraceID: "1",
+		SpanID:  "2",
+		Flags:   "1",
+		Sampled: &sampled,
+	})
+	if !span.Debug() {
+		t.Error("Expected span.Debug() to be true")
+	}
+	if !span.Sampled() {
+		t.Error("Expected span.Sampled() to be true when debug flag is set")
+	}
+
+	child := AsSpan(opentracing.StartSpan(
+		"child",
+		opentracing.ChildOf(opentracing.SpanFromContext(ctx).Context()),
+	))
+	if !child.Debug() {
+		t.Error("Expected child.Debug() to be true")
+	}
+	if !child.Sampled() {
+		t.Error("Expected child.Sampled() to be true when debug flag is set")
+	}
+}
+
 func TestDebugFlagQuick(t *testing.T) {
 	f := func(flags int64) bool {
 		span := AsSpan(opentracing.StartSpan("test"))
@@ -43,6 +43,7 @@ var globalTracer = Tracer{logger: log.NopWrapper}
 // A Tracer creates and manages spans.
 type Tracer struct {
 	sampleRate       float64
+	sampler          Sampler
 	recorder         mqsend.MessageQueue
 	logger           log.Wrapper
 	endpoint         ZipkinEndpointInfo
@@ -66,6 +67,7 @@ func InitGlobalTracer(cfg Config) error {
 			Name:           QueueNamePrefix + cfg.QueueName,
 			MaxQueueSize:   cfg.MaxQueueSize,
 			MaxMessageSize: MaxSpanSize,
+			Transport:      cfg.Transport,
 		})
 		if err != nil {
 			return err
@@ -76,6 +78,13 @@ func InitGlobalTracer(cfg Config) error {
 	}
 
 	tracer.sampleRate = cfg.SampleRate
+	if cfg.Sampler != nil {
+		sampler, err := cfg.Sampler.Build()
+		if err != nil {
+			return err
+		}
+		tracer.sampler = sampler
+	}
 	tracer.useHex = cfg.UseHex
 
 	logger := cfg.Logger
@@ -205,7 +214,11 @@ func (t *Tracer) StartSpan(operationName string, opts ...opentracing.StartSpanOp
 		parent.initChildSpan(span)
 	} else {
 		span.trace.traceID = t.newTraceID()
-		span.trace.sampled = randbp.ShouldSampleWithRate(t.sampleRate)
+		span.trace.sampled = t.shouldSample(SamplingParameters{
+			TraceID: span.trace.traceID,
+			Name:    operationName,
+			Type:    sso.Type,
+		})
 		initRootSpan(context.Background(), span)
 	}
 
@@ -236,6 +249,17 @@ func (t *Tracer) Extract(format interface{}, carrier interface{}) (opentracing.S
 	return nil, opentracing.ErrInvalidCarrier
 }
 
+// shouldSample makes the sampling decision of a root span.
+func (t *Tracer) shouldSample(params SamplingParameters) bool {
+	if t.sampler != nil {
+		return t.sampler.ShouldSample(params)
+	}
+	if params.ParentSampled != nil {
+		return *params.ParentSampled
+	}
+	return ShouldSampleTraceID(params.TraceID, t.sampleRate)
+}
+
 func (t *Tracer) newTraceID() string {
 	if t.useHex {
 		// For traces we just combine two 64-bit hex ids to get a 128-bit hex id.